@@ -19,6 +19,8 @@ OUTPUT_DIR="./bin"
 DOCKER_FILE="Dockerfile.builder"
 IMAGE_PREFIX="streamerbrainz-builder"
 VERSION="${VERSION:-1.0.0}"
+COMMIT="${COMMIT:-$(git rev-parse --short HEAD 2>/dev/null || echo unknown)}"
+BUILD_DATE="${BUILD_DATE:-$(date -u +%Y-%m-%dT%H:%M:%SZ)}"
 
 # Colors for output
 RED='\033[0;31m'
@@ -147,6 +149,9 @@ build_platform() {
         -f "${DOCKER_FILE}" \
         -t "${image_tag}" \
         --build-arg NO_UPX="${NO_UPX}" \
+        --build-arg VERSION="${VERSION}" \
+        --build-arg COMMIT="${COMMIT}" \
+        --build-arg BUILD_DATE="${BUILD_DATE}" \
         --load \
         . || {
             print_error "Build failed for ${platform}"
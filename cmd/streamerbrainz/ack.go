@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// ============================================================================
+// Event acknowledgements
+// ============================================================================
+// IPC and /webhooks/event clients used to get "ok" as soon as their event was
+// queued, whatever happened to it. Now they wrap it in AckedEvent and wait for
+// an EventAck on its reply channel:
+//
+//   - The zone router answers events it handles itself (zone selection, links,
+//     resync, ...) once handled, and passes the rest on to the zone loop.
+//   - The reducer rejects volume changes that are locked (calibration, test
+//     signal) or lose arbitration right away.
+//   - A volume or mute change that reaches CamillaDSP is answered when the
+//     first SetVolume / SetMute it causes is confirmed (a ramp is answered as it
+//     starts), or fails. Clamping to the volume limits is reported in the
+//     message ("clamped to -10.0 dB").
+//   - Anything else is answered once reduced.
+//
+// Pending acknowledgements live in DaemonState.Acks and time out after
+// ackTimeout if CamillaDSP never answers. The reply is a CmdAck, delivered by
+// the daemon loop without going through the effects worker.
+// ============================================================================
+
+const (
+	// ackTimeout bounds how long the reducer waits for CamillaDSP to confirm an
+	// acknowledged change.
+	ackTimeout = 3 * time.Second
+
+	// ackWaitTimeout bounds how long an ingress waits for the acknowledgement;
+	// past it the event counts as queued. It stays below the 5 s default
+	// timeout of pkg/client.
+	ackWaitTimeout = ackTimeout + time.Second
+)
+
+// EventAck is the outcome of an acknowledged event.
+type EventAck struct {
+	Err     string // empty on success
+	Message string // detail on success, e.g. "clamped to -10.0 dB"
+	DSP     bool   // Err came from CamillaDSP (failed or didn't answer)
+}
+
+// AckedEvent asks for the outcome of Event on Reply, which must be buffered.
+type AckedEvent struct {
+	Event Event
+	Reply chan<- EventAck
+}
+
+func (AckedEvent) eventMarker() {}
+
+// splitAck unwraps an AckedEvent (reply is nil for plain events).
+func splitAck(ev Event) (Event, chan<- EventAck) {
+	if a, ok := ev.(AckedEvent); ok {
+		return a.Event, a.Reply
+	}
+	return ev, nil
+}
+
+// CmdAck answers an acknowledged event.
+type CmdAck struct {
+	Reply chan<- EventAck
+	Ack   EventAck
+}
+
+func (CmdAck) commandMarker() {}
+func (c CmdAck) String() string {
+	return fmt.Sprintf("CmdAck(err=%q, message=%q)", c.Ack.Err, c.Ack.Message)
+}
+
+// PendingAck is an acknowledgement waiting for CamillaDSP.
+type PendingAck struct {
+	Reply    chan<- EventAck `json:"-"`
+	Mute     bool            // waits for a SetMute rather than a SetVolume
+	Message  string          // success detail
+	Deadline time.Time
+}
+
+// deliverAck sends an acknowledgement without blocking; the requester may
+// have given up.
+func deliverAck(reply chan<- EventAck, ack EventAck, logger *slog.Logger) {
+	select {
+	case reply <- ack:
+	default:
+		logger.Debug("event acknowledgement not delivered (requester gone)")
+	}
+}
+
+// ackCmd answers reply now (no command if nil).
+func ackCmd(reply chan<- EventAck, ack EventAck) []Command {
+	if reply == nil {
+		return nil
+	}
+	return []Command{CmdAck{Reply: reply, Ack: ack}}
+}
+
+// rejectedAck answers a volume change rejected by arbitration (see arbitration.go).
+func rejectedAck(b BroadcastControlRejected) EventAck {
+	return EventAck{Err: fmt.Sprintf("%s has control until %s", b.Holder, b.Until.Format(time.TimeOnly))}
+}
+
+// ackReduced answers, or starts waiting to answer, an event the reducer just
+// applied. requestedDB is the level asked for by an absolute set (nil if none).
+func ackReduced(s *DaemonState, e Event, reply chan<- EventAck, requestedDB *float64, at time.Time) []Command {
+	if reply == nil {
+		return nil
+	}
+	_, volume := volumeEventOrigin(e)
+	switch {
+	case volume && (s.VolumeCtrl.Ramping || s.Intent.DesiredVolume != nil):
+		target := s.VolumeCtrl.RampTarget
+		if !s.VolumeCtrl.Ramping {
+			target = *s.Intent.DesiredVolume
+		}
+		var msg string
+		if requestedDB != nil && absMB(target-mbFromDB(*requestedDB)) > 0 {
+			msg = fmt.Sprintf("clamped to %.1f dB", target.DB())
+		}
+		if !s.VolumeCtrl.Ramping && s.Camilla.VolumeKnown && absMB(target-s.Camilla.VolumeMB) < volumeUpdateThresholdMB {
+			// Already there: nothing will be sent.
+			return ackCmd(reply, EventAck{Message: msg})
+		}
+		if s.VolumeCtrl.Ramping && msg == "" {
+			msg = fmt.Sprintf("ramping to %.1f dB", target.DB())
+		}
+		s.Acks = append(s.Acks, PendingAck{Reply: reply, Message: msg, Deadline: at.Add(ackTimeout)})
+		return nil
+	case !volume && s.Intent.DesiredMute != nil:
+		s.Acks = append(s.Acks, PendingAck{Reply: reply, Mute: true, Deadline: at.Add(ackTimeout)})
+		return nil
+	}
+	return ackCmd(reply, EventAck{})
+}
+
+// resolveAcks answers the pending volume (mute = false) or mute acknowledgements
+// with ack, keeping the others.
+func resolveAcks(s *DaemonState, mute bool, ack EventAck) []Command {
+	var cmds []Command
+	kept := s.Acks[:0]
+	for _, p := range s.Acks {
+		if p.Mute != mute {
+			kept = append(kept, p)
+			continue
+		}
+		a := ack
+		if a.Err == "" {
+			a.Message = p.Message
+		}
+		cmds = append(cmds, CmdAck{Reply: p.Reply, Ack: a})
+	}
+	clear(s.Acks[len(kept):])
+	s.Acks = kept
+	return cmds
+}
+
+// expireAcks fails acknowledgements CamillaDSP didn't confirm in time.
+func expireAcks(s *DaemonState, now time.Time) []Command {
+	var cmds []Command
+	kept := s.Acks[:0]
+	for _, p := range s.Acks {
+		if now.Before(p.Deadline) {
+			kept = append(kept, p)
+			continue
+		}
+		cmds = append(cmds, CmdAck{Reply: p.Reply, Ack: EventAck{Err: "camilladsp did not confirm the change", DSP: true}})
+	}
+	clear(s.Acks[len(kept):])
+	s.Acks = kept
+	return cmds
+}
+
+// observationAcks resolves pending acknowledgements from a CamillaDSP result.
+func observationAcks(s *DaemonState, e Event) []Command {
+	if len(s.Acks) == 0 {
+		return nil
+	}
+	switch ev := e.(type) {
+	case CamillaVolumeObserved:
+		if ev.Confirmed {
+			return resolveAcks(s, false, EventAck{})
+		}
+	case CamillaMuteObserved:
+		if s.Intent.DesiredMute == nil && s.Camilla.MuteSent == nil {
+			return resolveAcks(s, true, EventAck{})
+		}
+	case CamillaCommandFailed:
+		msg := "camilladsp unreachable"
+		if c := ev.Class(); c == camillaErrProtocol || c == camillaErrResult {
+			msg = "camilladsp error"
+		}
+		if ev.Err != nil {
+			msg += ": " + ev.Err.Error()
+		}
+		switch ev.Command.(type) {
+		case CmdSetVolume:
+			return resolveAcks(s, false, EventAck{Err: msg, DSP: true})
+		case CmdSetMute:
+			return resolveAcks(s, true, EventAck{Err: msg, DSP: true})
+		}
+	}
+	return nil
+}
+
+// sendAcked queues ev as an AckedEvent and waits for its outcome. It returns
+// false if the queue is full; an outcome that takes longer than ackWaitTimeout
+// is reported as queued.
+func sendAcked(events chan<- Event, ev Event) (EventAck, bool) {
+	reply := make(chan EventAck, 1)
+	select {
+	case events <- AckedEvent{Event: ev, Reply: reply}:
+	default:
+		return EventAck{}, false
+	}
+	t := time.NewTimer(ackWaitTimeout)
+	defer t.Stop()
+	select {
+	case ack := <-reply:
+		return ack, true
+	case <-t.C:
+		return EventAck{Message: "queued; no result yet"}, true
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// acks returns the acknowledgements among cmds.
+func acks(cmds []Command) []EventAck {
+	var out []EventAck
+	for _, c := range cmds {
+		if a, ok := c.(CmdAck); ok {
+			out = append(out, a.Ack)
+		}
+	}
+	return out
+}
+
+func TestReduce_AckVolumeConfirmed(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: -10}
+	t0 := time.Unix(1000, 0)
+	s := &DaemonState{}
+	s.SetObservedVolume(-40, t0)
+	reply := make(chan EventAck, 1)
+
+	rr := Reduce(s, TimedEvent{At: t0, Event: AckedEvent{Event: SetVolumeAbsolute{Db: -3}, Reply: reply}}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if len(acks(rr.Commands)) != 0 || len(s.Acks) != 1 {
+		t.Fatalf("answered before CamillaDSP confirmed: %v", rr.Commands)
+	}
+	rr = Reduce(s, Tick{Now: t0.Add(10 * time.Millisecond), Dt: 0.01}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if countCommands[CmdSetVolume](rr.Commands) != 1 {
+		t.Fatalf("tick: %v", rr.Commands)
+	}
+	rr = Reduce(s, CamillaVolumeObserved{VolumeDB: -10, Confirmed: true, At: t0.Add(20 * time.Millisecond)}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if got := acks(rr.Commands); len(got) != 1 || got[0] != (EventAck{Message: "clamped to -10.0 dB"}) || len(s.Acks) != 0 {
+		t.Fatalf("acks = %+v", got)
+	}
+}
+
+func TestReduce_AckFailures(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0}
+	t0 := time.Unix(1000, 0)
+	s := &DaemonState{}
+	s.SetObservedVolume(-40, t0)
+	s.SetObservedMute(false, t0)
+	reply := make(chan EventAck, 4)
+	acked := func(ev Event, at time.Time) []Command {
+		return Reduce(s, TimedEvent{At: at, Event: AckedEvent{Event: ev, Reply: reply}}, cfg, RotaryConfig{}, ReducerPolicy{}).Commands
+	}
+
+	// A failed SetVolume fails the volume acknowledgement only.
+	acked(SetVolumeAbsolute{Db: -20}, t0)
+	acked(ToggleMute{}, t0)
+	rr := Reduce(s, CamillaCommandFailed{Command: CmdSetVolume{TargetDB: -20}, Err: errors.New("timeout"), At: t0}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if got := acks(rr.Commands); len(got) != 1 || !got[0].DSP || got[0].Err != "camilladsp unreachable: timeout" {
+		t.Fatalf("acks = %+v", got)
+	}
+
+	// The mute is flushed but never confirmed.
+	Reduce(s, Tick{Now: t0.Add(10 * time.Millisecond), Dt: 0.01}, cfg, RotaryConfig{}, ReducerPolicy{})
+	rr = Reduce(s, Tick{Now: t0.Add(ackTimeout), Dt: 0.01}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if got := acks(rr.Commands); len(got) != 1 || !got[0].DSP || len(s.Acks) != 0 {
+		t.Fatalf("acks after timeout = %+v", got)
+	}
+
+	// Locked volume changes are rejected right away; other events are just applied.
+	s.Calibration.Active = true
+	if got := acks(acked(VolumeStep{Steps: 1, DbPerStep: 1}, t0)); len(got) != 1 || got[0].Err == "" || got[0].DSP {
+		t.Fatalf("locked: %+v", got)
+	}
+	if got := acks(acked(MediaPlayPause{}, t0)); len(got) != 1 || got[0] != (EventAck{}) {
+		t.Fatalf("other event: %+v", got)
+	}
+}
+
+func TestZoneRouter_Acks(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan Event, 4)
+	main := make(chan Event, 4)
+	go runZoneRouter(ctx, events, []zoneRoute{{ID: "main", Events: main}}, "main", nil, nil, slog.New(slog.DiscardHandler))
+
+	// Zone events carry the reply on to the zone loop.
+	reply := make(chan EventAck, 1)
+	events <- AckedEvent{Event: ToggleMute{}, Reply: reply}
+	if a, ok := (<-main).(AckedEvent); !ok || a.Reply == nil {
+		t.Fatalf("zone got %#v", a)
+	}
+
+	// The router answers what it handles itself.
+	events <- AckedEvent{Event: SelectZone{Zone: "attic"}, Reply: reply}
+	if ack := <-reply; ack.Err != "unknown zone attic" {
+		t.Fatalf("select unknown zone: %+v", ack)
+	}
+	events <- AckedEvent{Event: ZonedEvent{Zone: "attic", Event: ToggleMute{}}, Reply: reply}
+	if ack := <-reply; ack.Err != "unknown zone attic" {
+		t.Fatalf("zoned event for unknown zone: %+v", ack)
+	}
+	events <- AckedEvent{Event: ResyncState{}, Reply: reply}
+	if ack := <-reply; ack != (EventAck{}) {
+		t.Fatalf("resync: %+v", ack)
+	}
+}
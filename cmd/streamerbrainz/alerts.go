@@ -0,0 +1,446 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// Fault alerts (ntfy / Pushover / webhook)
+// ============================================================================
+// Sends push notifications when something needs a human:
+//
+//   - dsp_disconnected:  CamillaDSP stays unreachable for after_sec.
+//   - input_device_down: an input device stays down for after_sec.
+//   - dsp_protocol_error: CamillaDSP sent a reply that couldn't be understood
+//     (see camilladsp_errors.go). One-shot: after_sec and notify_resolved don't
+//     apply, but the cooldown does.
+//
+// Each alert type has its own enable flag, delay, cooldown and channel list.
+// A fault that clears before after_sec never alerts; with notify_resolved a
+// follow-up is sent when an alerted fault clears.
+//
+// Channels:
+//   - ntfy:     POST <url>/<topic> with the message as body (ntfy.sh or self-hosted).
+//   - pushover: POST to the Pushover messages API (application token + user key).
+//   - webhook:  POST a JSON object {type, alert, key, title, message, resolved, ts},
+//               optionally HMAC-signed like outbound_webhooks.
+// ============================================================================
+
+// Alert types (config: alerts.<type>).
+const (
+	alertDSPDisconnected  = "dsp_disconnected"
+	alertInputDeviceDown  = "input_device_down"
+	alertDSPProtocolError = "dsp_protocol_error"
+)
+
+// Alert channel types (config: alerts.channels[].type).
+const (
+	alertChannelNtfy     = "ntfy"
+	alertChannelPushover = "pushover"
+	alertChannelWebhook  = "webhook"
+)
+
+const (
+	defaultNtfyURL     = "https://ntfy.sh"
+	defaultPushoverURL = "https://api.pushover.net/1/messages.json"
+
+	alertSendTimeout = 10 * time.Second
+	alertQueueSize   = 16
+)
+
+// alert is one notification to deliver.
+type alert struct {
+	Type     string
+	Key      string // zone or device the alert is about
+	Title    string
+	Message  string
+	Resolved bool
+	At       time.Time
+}
+
+// alertWebhookBody is the JSON body posted by webhook channels.
+type alertWebhookBody struct {
+	Type     string    `json:"type"` // always "alert"
+	Alert    string    `json:"alert"`
+	Key      string    `json:"key"`
+	Title    string    `json:"title"`
+	Message  string    `json:"message"`
+	Resolved bool      `json:"resolved"`
+	Ts       time.Time `json:"ts"`
+}
+
+// newAlertRequest builds the HTTP request delivering a to channel ch.
+func newAlertRequest(ctx context.Context, ch AlertChannelConfig, a alert) (*http.Request, error) {
+	var (
+		req *http.Request
+		err error
+	)
+	switch ch.Type {
+	case alertChannelNtfy:
+		base := ch.URL
+		if base == "" {
+			base = defaultNtfyURL
+		}
+		target := strings.TrimSuffix(base, "/") + "/" + url.PathEscape(ch.Topic)
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader(a.Message))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Title", a.Title)
+		if a.Resolved {
+			req.Header.Set("Tags", "white_check_mark")
+		} else {
+			req.Header.Set("Tags", "warning")
+			req.Header.Set("Priority", "high")
+		}
+		if ch.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+ch.Token)
+		}
+
+	case alertChannelPushover:
+		target := ch.URL
+		if target == "" {
+			target = defaultPushoverURL
+		}
+		form := url.Values{
+			"token":     {ch.Token},
+			"user":      {ch.User},
+			"title":     {a.Title},
+			"message":   {a.Message},
+			"timestamp": {fmt.Sprint(a.At.Unix())},
+		}
+		if !a.Resolved {
+			form.Set("priority", "1")
+		}
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	case alertChannelWebhook:
+		body, err := json.Marshal(alertWebhookBody{
+			Type:     "alert",
+			Alert:    a.Type,
+			Key:      a.Key,
+			Title:    a.Title,
+			Message:  a.Message,
+			Resolved: a.Resolved,
+			Ts:       a.At.UTC(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, ch.URL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if ch.Secret != "" {
+			req.Header.Set(outboundWebhookSignature, signWebhookBody(ch.Secret, body))
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown alert channel type %q", ch.Type)
+	}
+	req.Header.Set("User-Agent", "streamerbrainz/"+version)
+	return req, nil
+}
+
+// sendAlert delivers a to one channel.
+func sendAlert(ctx context.Context, client *http.Client, ch AlertChannelConfig, a alert) error {
+	req, err := newAlertRequest(ctx, ch, a)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// alertKey identifies one fault instance (e.g. dsp_disconnected for zone "living").
+type alertKey struct {
+	Type string
+	Key  string
+}
+
+// alertFault is a fault that is currently active.
+type alertFault struct {
+	since  time.Time
+	detail string
+	timer  *time.Timer // pending after_sec timer; nil once fired
+	sent   bool        // an alert was delivered for this fault
+}
+
+// alertManager tracks faults and decides when to notify. It is owned by a single
+// goroutine (runAlerts); timers report back through due.
+type alertManager struct {
+	rules    map[string]AlertRuleConfig
+	channels []AlertChannelConfig
+	faults   map[alertKey]*alertFault
+	lastSent map[alertKey]time.Time
+	due      chan alertKey
+	send     func(a alert, channels []AlertChannelConfig)
+}
+
+func newAlertManager(cfg AlertsConfig, send func(a alert, channels []AlertChannelConfig)) *alertManager {
+	return &alertManager{
+		rules: map[string]AlertRuleConfig{
+			alertDSPDisconnected:  cfg.DSPDisconnected,
+			alertInputDeviceDown:  cfg.InputDeviceDown,
+			alertDSPProtocolError: cfg.DSPProtocolError,
+		},
+		channels: cfg.Channels,
+		faults:   make(map[alertKey]*alertFault),
+		lastSent: make(map[alertKey]time.Time),
+		due:      make(chan alertKey, alertQueueSize),
+		send:     send,
+	}
+}
+
+// channelsFor returns the channels an alert type is delivered to.
+func (m *alertManager) channelsFor(typ string) []AlertChannelConfig {
+	names := m.rules[typ].Channels
+	if len(names) == 0 {
+		return m.channels
+	}
+	var out []AlertChannelConfig
+	for _, ch := range m.channels {
+		for _, n := range names {
+			if ch.name() == n {
+				out = append(out, ch)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// fault records that k became faulty and schedules its alert after after_sec.
+func (m *alertManager) fault(k alertKey, detail string, at time.Time) {
+	rule := m.rules[k.Type]
+	if !rule.Enabled {
+		return
+	}
+	if f, ok := m.faults[k]; ok {
+		f.detail = detail
+		return
+	}
+	f := &alertFault{since: at, detail: detail}
+	m.faults[k] = f
+	f.timer = time.AfterFunc(time.Duration(rule.AfterSec)*time.Second, func() {
+		select {
+		case m.due <- k:
+		default:
+		}
+	})
+}
+
+// clear records that k recovered, canceling a pending alert or sending a
+// resolved notification for a delivered one.
+func (m *alertManager) clear(k alertKey, at time.Time) {
+	f, ok := m.faults[k]
+	if !ok {
+		return
+	}
+	delete(m.faults, k)
+	if f.timer != nil {
+		f.timer.Stop()
+	}
+	if f.sent && m.rules[k.Type].NotifyResolved {
+		m.send(resolvedAlert(k, at.Sub(f.since), at), m.channelsFor(k.Type))
+	}
+}
+
+// fire sends the alert for k if the fault is still active and outside the cooldown.
+func (m *alertManager) fire(k alertKey, now time.Time) {
+	f, ok := m.faults[k]
+	if !ok || f.sent {
+		return
+	}
+	f.timer = nil
+	cooldown := time.Duration(m.rules[k.Type].CooldownSec) * time.Second
+	if last, ok := m.lastSent[k]; ok && now.Sub(last) < cooldown {
+		return
+	}
+	f.sent = true
+	m.lastSent[k] = now
+	m.send(faultAlert(k, f.detail, now.Sub(f.since), now), m.channelsFor(k.Type))
+}
+
+// notify sends a one-shot alert for k (an event rather than a lasting fault)
+// unless it is disabled or within the cooldown.
+func (m *alertManager) notify(k alertKey, detail string, at time.Time) {
+	rule := m.rules[k.Type]
+	if !rule.Enabled {
+		return
+	}
+	if last, ok := m.lastSent[k]; ok && at.Sub(last) < time.Duration(rule.CooldownSec)*time.Second {
+		return
+	}
+	m.lastSent[k] = at
+	m.send(faultAlert(k, detail, 0, at), m.channelsFor(k.Type))
+}
+
+// stop cancels all pending timers.
+func (m *alertManager) stop() {
+	for _, f := range m.faults {
+		if f.timer != nil {
+			f.timer.Stop()
+		}
+	}
+}
+
+// observe updates faults from a state broadcast.
+func (m *alertManager) observe(b StateBroadcast) {
+	switch ev := b.(type) {
+	case ZoneBroadcast:
+		switch c := ev.Broadcast.(type) {
+		case BroadcastDSPConnectionChanged:
+			k := alertKey{Type: alertDSPDisconnected, Key: ev.Zone}
+			if c.Connected {
+				m.clear(k, c.At)
+			} else {
+				m.fault(k, c.Error, c.At)
+			}
+		case BroadcastDSPCommandError:
+			if c.Class == camillaErrProtocol {
+				m.notify(alertKey{Type: alertDSPProtocolError, Key: ev.Zone}, c.Command+": "+c.Error, c.At)
+			}
+		}
+	case BroadcastDeviceDown:
+		m.fault(alertKey{Type: alertInputDeviceDown, Key: ev.Device}, ev.Reason, ev.At)
+	case BroadcastDeviceUp:
+		m.clear(alertKey{Type: alertInputDeviceDown, Key: ev.Device}, ev.At)
+	}
+}
+
+// faultAlert formats the notification for an active fault.
+func faultAlert(k alertKey, detail string, down time.Duration, at time.Time) alert {
+	a := alert{Type: k.Type, Key: k.Key, At: at}
+	switch k.Type {
+	case alertDSPDisconnected:
+		a.Title = "CamillaDSP unreachable"
+		a.Message = fmt.Sprintf("CamillaDSP (zone %s) has been unreachable for %s", k.Key, down.Round(time.Second))
+	case alertInputDeviceDown:
+		a.Title = "Input device down"
+		a.Message = fmt.Sprintf("Input device %s has been down for %s", k.Key, down.Round(time.Second))
+	case alertDSPProtocolError:
+		a.Title = "CamillaDSP protocol error"
+		a.Message = fmt.Sprintf("CamillaDSP (zone %s) sent a reply that couldn't be understood", k.Key)
+	}
+	if detail != "" {
+		a.Message += ": " + detail
+	}
+	return a
+}
+
+// resolvedAlert formats the notification sent when an alerted fault clears.
+func resolvedAlert(k alertKey, down time.Duration, at time.Time) alert {
+	a := alert{Type: k.Type, Key: k.Key, Resolved: true, At: at}
+	switch k.Type {
+	case alertDSPDisconnected:
+		a.Title = "CamillaDSP reachable again"
+		a.Message = fmt.Sprintf("CamillaDSP (zone %s) is reachable again after %s", k.Key, down.Round(time.Second))
+	case alertInputDeviceDown:
+		a.Title = "Input device back"
+		a.Message = fmt.Sprintf("Input device %s is back after %s", k.Key, down.Round(time.Second))
+	}
+	return a
+}
+
+// resolveAlertChannelSecrets returns a copy of channels with each token and
+// secret source replaced by the secret it names.
+func resolveAlertChannelSecrets(channels []AlertChannelConfig) ([]AlertChannelConfig, error) {
+	out := slices.Clone(channels)
+	for i := range out {
+		for _, f := range []struct {
+			key   string
+			field *string
+		}{{"token", &out[i].Token}, {"secret", &out[i].Secret}} {
+			if *f.field == "" {
+				continue
+			}
+			v, err := readSecret(*f.field)
+			if err != nil {
+				return nil, fmt.Errorf("alerts.channels[%d].%s: %w", i, f.key, err)
+			}
+			*f.field = v
+		}
+	}
+	return out, nil
+}
+
+// runAlerts watches the broadcast stream for faults and delivers alerts until
+// ctx is canceled or broadcasts is closed. cfg.Channels carry resolved secrets
+// (see resolveAlertChannelSecrets). Deliveries run one at a time off the
+// broadcast path so a slow notification service never delays fault tracking.
+func runAlerts(ctx context.Context, cfg AlertsConfig, broadcasts <-chan StateBroadcast, logger *slog.Logger) {
+	logger.Info("alerts enabled", "channels", len(cfg.Channels))
+
+	client := &http.Client{Timeout: alertSendTimeout}
+	type delivery struct {
+		alert    alert
+		channels []AlertChannelConfig
+	}
+	queue := make(chan delivery, alertQueueSize)
+	workerDone := make(chan struct{})
+	defer func() { <-workerDone }()
+	go func() {
+		defer close(workerDone)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case d := <-queue:
+				for _, ch := range d.channels {
+					if err := sendAlert(ctx, client, ch, d.alert); err != nil {
+						logger.Warn("alert delivery failed", "alert", d.alert.Type, "channel", ch.name(), "error", err)
+						continue
+					}
+					logger.Info("alert sent", "alert", d.alert.Type, "key", d.alert.Key, "resolved", d.alert.Resolved, "channel", ch.name())
+				}
+			}
+		}
+	}()
+
+	m := newAlertManager(cfg, func(a alert, channels []AlertChannelConfig) {
+		select {
+		case queue <- delivery{alert: a, channels: channels}:
+		default:
+			logger.Warn("alert queue full, dropping alert", "alert", a.Type, "key", a.Key)
+		}
+	})
+	defer m.stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case k := <-m.due:
+			m.fire(k, time.Now())
+		case b, ok := <-broadcasts:
+			if !ok {
+				return
+			}
+			m.observe(b)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewAlertRequest_Channels(t *testing.T) {
+	a := alert{Type: alertDSPDisconnected, Key: "main", Title: "CamillaDSP unreachable", Message: "down", At: time.Unix(1000, 0)}
+
+	req, err := newAlertRequest(context.Background(), AlertChannelConfig{Type: alertChannelNtfy, URL: "http://ntfy.local/", Topic: "sb", Token: "tk"}, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(req.Body)
+	if req.URL.String() != "http://ntfy.local/sb" || string(body) != "down" {
+		t.Fatalf("ntfy: got %s %q", req.URL, body)
+	}
+	if req.Header.Get("Title") != a.Title || req.Header.Get("Authorization") != "Bearer tk" || req.Header.Get("Priority") != "high" {
+		t.Fatalf("ntfy headers: %v", req.Header)
+	}
+
+	req, err = newAlertRequest(context.Background(), AlertChannelConfig{Type: alertChannelPushover, Token: "app", User: "usr"}, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(req.Body)
+	form, _ := url.ParseQuery(string(body))
+	if req.URL.String() != defaultPushoverURL || form.Get("token") != "app" || form.Get("user") != "usr" || form.Get("message") != "down" || form.Get("priority") != "1" {
+		t.Fatalf("pushover: got %s %v", req.URL, form)
+	}
+
+	req, err = newAlertRequest(context.Background(), AlertChannelConfig{Type: alertChannelWebhook, URL: "http://hook.local/x", Secret: "s"}, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(req.Body)
+	var got alertWebhookBody
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Type != "alert" || got.Alert != alertDSPDisconnected || got.Key != "main" || got.Resolved {
+		t.Fatalf("webhook body: %+v", got)
+	}
+	if req.Header.Get(outboundWebhookSignature) != signWebhookBody("s", body) {
+		t.Fatalf("webhook signature missing")
+	}
+}
+
+func TestSendAlert_ReportsHTTPErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad token", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	err := sendAlert(context.Background(), srv.Client(), AlertChannelConfig{Type: alertChannelWebhook, URL: srv.URL}, alert{Type: alertInputDeviceDown})
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected HTTP 401 error, got %v", err)
+	}
+}
+
+type sentAlert struct {
+	alert    alert
+	channels []AlertChannelConfig
+}
+
+func newTestAlertManager(cfg AlertsConfig) (*alertManager, *[]sentAlert) {
+	var sent []sentAlert
+	m := newAlertManager(cfg, func(a alert, channels []AlertChannelConfig) {
+		sent = append(sent, sentAlert{a, channels})
+	})
+	return m, &sent
+}
+
+func TestAlertManager_FiresAfterDelayAndResolves(t *testing.T) {
+	cfg := AlertsConfig{
+		Channels:        []AlertChannelConfig{{Name: "phone", Type: alertChannelNtfy, Topic: "a"}, {Name: "hook", Type: alertChannelWebhook, URL: "http://x"}},
+		DSPDisconnected: AlertRuleConfig{Enabled: true, NotifyResolved: true, Channels: []string{"phone"}},
+	}
+	m, sent := newTestAlertManager(cfg)
+	defer m.stop()
+	t0 := time.Unix(1000, 0)
+
+	m.observe(ZoneBroadcast{Zone: "main", Broadcast: BroadcastDSPConnectionChanged{Connected: false, Error: "refused", At: t0}})
+	var k alertKey
+	select {
+	case k = <-m.due:
+	case <-time.After(time.Second):
+		t.Fatal("alert timer did not fire")
+	}
+	m.fire(k, t0.Add(30*time.Second))
+	if len(*sent) != 1 {
+		t.Fatalf("expected 1 alert, got %d", len(*sent))
+	}
+	got := (*sent)[0]
+	if got.alert.Type != alertDSPDisconnected || got.alert.Key != "main" || !strings.Contains(got.alert.Message, "refused") {
+		t.Fatalf("unexpected alert %+v", got.alert)
+	}
+	if len(got.channels) != 1 || got.channels[0].Name != "phone" {
+		t.Fatalf("expected delivery to phone only, got %+v", got.channels)
+	}
+
+	m.observe(ZoneBroadcast{Zone: "main", Broadcast: BroadcastDSPConnectionChanged{Connected: true, At: t0.Add(40 * time.Second)}})
+	if len(*sent) != 2 || !(*sent)[1].alert.Resolved {
+		t.Fatalf("expected a resolved alert, got %+v", *sent)
+	}
+}
+
+func TestAlertManager_ClearBeforeDelayCancels(t *testing.T) {
+	cfg := AlertsConfig{
+		Channels:        []AlertChannelConfig{{Type: alertChannelNtfy, Topic: "a"}},
+		InputDeviceDown: AlertRuleConfig{Enabled: true, AfterSec: 60, NotifyResolved: true},
+	}
+	m, sent := newTestAlertManager(cfg)
+	defer m.stop()
+	t0 := time.Unix(1000, 0)
+
+	k := alertKey{Type: alertInputDeviceDown, Key: "/dev/input/event0"}
+	m.observe(BroadcastDeviceDown{Device: k.Key, Reason: "read error", At: t0})
+	m.observe(BroadcastDeviceUp{Device: k.Key, At: t0.Add(time.Second)})
+	m.fire(k, t0.Add(60*time.Second)) // a late timer must not alert for a cleared fault
+	if len(*sent) != 0 {
+		t.Fatalf("expected no alerts, got %+v", *sent)
+	}
+}
+
+func TestAlertManager_CooldownAndDisabledRules(t *testing.T) {
+	cfg := AlertsConfig{
+		Channels:        []AlertChannelConfig{{Type: alertChannelNtfy, Topic: "a"}},
+		InputDeviceDown: AlertRuleConfig{Enabled: true, CooldownSec: 600},
+	}
+	m, sent := newTestAlertManager(cfg)
+	defer m.stop()
+	t0 := time.Unix(1000, 0)
+	k := alertKey{Type: alertInputDeviceDown, Key: "/dev/input/event0"}
+
+	m.observe(BroadcastDeviceDown{Device: k.Key, At: t0})
+	m.fire(k, t0)
+	m.observe(BroadcastDeviceUp{Device: k.Key, At: t0.Add(time.Second)})
+	m.observe(BroadcastDeviceDown{Device: k.Key, At: t0.Add(2 * time.Second)})
+	m.fire(k, t0.Add(2*time.Second))
+	if len(*sent) != 1 {
+		t.Fatalf("expected the repeat to be suppressed by the cooldown, got %d alerts", len(*sent))
+	}
+
+	// dsp_disconnected is disabled in this config.
+	m.observe(ZoneBroadcast{Zone: "main", Broadcast: BroadcastDSPConnectionChanged{At: t0}})
+	if len(m.faults) != 1 {
+		t.Fatalf("expected disabled rule to be ignored, faults=%v", m.faults)
+	}
+}
+
+func TestResolveAlertChannelSecrets(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "alert-hook"), []byte("hmac-key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CREDENTIALS_DIRECTORY", dir)
+	t.Setenv("SB_TEST_PUSHOVER_TOKEN", "app-token\n")
+
+	channels := []AlertChannelConfig{
+		{Type: alertChannelPushover, Token: "env:SB_TEST_PUSHOVER_TOKEN", User: "u"},
+		{Type: alertChannelWebhook, URL: "http://hook.local", Secret: "credential:alert-hook"},
+		{Type: alertChannelNtfy, Topic: "sb"},
+	}
+	out, err := resolveAlertChannelSecrets(channels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out[0].Token != "app-token" || out[1].Secret != "hmac-key" || out[2].Token != "" || channels[0].Token != "env:SB_TEST_PUSHOVER_TOKEN" {
+		t.Fatalf("out = %+v", out)
+	}
+
+	channels[2].Token = "env:SB_TEST_UNSET_NTFY_TOKEN"
+	if _, err := resolveAlertChannelSecrets(channels); err == nil || !strings.Contains(err.Error(), "alerts.channels[2].token") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestConfigValidate_Alerts(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Alerts.Enabled = true
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("expected error for missing channels")
+	}
+	cfg.Alerts.Channels = []AlertChannelConfig{{Type: alertChannelPushover, Token: "t"}}
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("expected error for pushover without user")
+	}
+	cfg.Alerts.Channels[0].User = "u"
+	cfg.Alerts.InputDeviceDown.Channels = []string{"nope"}
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("expected error for unknown channel reference")
+	}
+	cfg.Alerts.InputDeviceDown.Channels = []string{"pushover"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg.Alerts.Channels[0].Token = "credential:"
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("expected error for a malformed token source")
+	}
+}
+
+func TestAlertManager_ProtocolErrorIsOneShot(t *testing.T) {
+	cfg := AlertsConfig{
+		Channels:         []AlertChannelConfig{{Type: alertChannelNtfy, Topic: "a"}},
+		DSPProtocolError: AlertRuleConfig{Enabled: true, CooldownSec: 600},
+	}
+	m, sent := newTestAlertManager(cfg)
+	defer m.stop()
+	t0 := time.Unix(1000, 0)
+
+	m.observe(ZoneBroadcast{Zone: "main", Broadcast: BroadcastDSPCommandError{Command: "GetVolume", Class: camillaErrResult, Error: "Error", At: t0}})
+	if len(*sent) != 0 {
+		t.Fatalf("dsp_error result alerted: %+v", *sent)
+	}
+	b := BroadcastDSPCommandError{Command: "GetVolume", Class: camillaErrProtocol, Error: "unexpected reply", At: t0}
+	m.observe(ZoneBroadcast{Zone: "main", Broadcast: b})
+	b.At = t0.Add(time.Minute)
+	m.observe(ZoneBroadcast{Zone: "main", Broadcast: b})
+	if len(*sent) != 1 || (*sent)[0].alert.Type != alertDSPProtocolError || !strings.Contains((*sent)[0].alert.Message, "GetVolume") {
+		t.Fatalf("expected one protocol alert within the cooldown, got %+v", *sent)
+	}
+	if len(m.faults) != 0 {
+		t.Fatalf("one-shot alert left a fault behind: %v", m.faults)
+	}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ============================================================================
+// alexa control protocol
+// ============================================================================
+// Answers Alexa Smart Home (payload version 3) directives for the Alexa.Speaker
+// interface, so "Alexa, set the hi-fi to 40 percent" becomes a
+// set_volume_percent. The skill's Lambda forwards each directive unchanged as a
+// POST to options.listen, with the shared options.token as a bearer token, and
+// returns the response body to Alexa:
+//
+//	Alexa.Discovery Discover    one SPEAKER endpoint per voice target
+//	Alexa.Speaker SetVolume     set_volume_percent
+//	Alexa.Speaker AdjustVolume  set_volume_percent (current + delta)
+//	Alexa.Speaker SetMute       set_mute
+//	Alexa ReportState           volume and muted
+//	Alexa.Authorization AcceptGrant  accepted (no proactive reporting)
+//
+// Responses carry the target's volume and muted properties. See docs/voice.md.
+// ============================================================================
+
+func init() {
+	registerControlProtocol("alexa", newAlexaProtocol)
+}
+
+// alexaOptions are the `options` of an alexa control protocol.
+type alexaOptions struct {
+	Listen       string `yaml:"listen"` // host:port for the directive endpoint
+	Token        string `yaml:"token"`  // secret reference for the bearer token
+	voiceOptions `yaml:",inline"`
+}
+
+// maxAlexaDirectiveBody bounds a directive's size.
+const maxAlexaDirectiveBody = 64 << 10
+
+type alexaProtocol struct {
+	opts   alexaOptions
+	logger *slog.Logger
+}
+
+func newAlexaProtocol(options *yaml.Node, logger *slog.Logger) (ControlProtocol, error) {
+	var opts alexaOptions
+	if err := decodeControlProtocolOptions(options, &opts); err != nil {
+		return nil, fmt.Errorf("alexa options: %w", err)
+	}
+	if _, _, err := net.SplitHostPort(opts.Listen); err != nil {
+		return nil, errors.New("alexa options.listen must be host:port")
+	}
+	if opts.Token == "" {
+		return nil, errors.New("alexa options.token is required")
+	}
+	if err := validateSecretRef(opts.Token); err != nil {
+		return nil, fmt.Errorf("alexa options.token: %w", err)
+	}
+	if err := opts.validate("alexa"); err != nil {
+		return nil, err
+	}
+	return &alexaProtocol{opts: opts, logger: logger}, nil
+}
+
+func (p *alexaProtocol) Start(ctx context.Context, events chan<- Event) error {
+	token, err := readSecret(p.opts.Token)
+	if err != nil {
+		return fmt.Errorf("alexa token: %w", err)
+	}
+	ln, err := net.Listen("tcp", p.opts.Listen)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", p.opts.Listen, err)
+	}
+	p.logger.Info("alexa control listening", "addr", ln.Addr().String())
+
+	h := &alexaHandler{token: token, targets: p.opts.targets(), events: events, logger: p.logger}
+	return serveHTTP(ctx, "alexa", ln, h)
+}
+
+// Notify is a no-op: state is reported in directive responses only.
+func (p *alexaProtocol) Notify(StateBroadcast) {}
+
+// alexaHeader is a directive or event header.
+type alexaHeader struct {
+	Namespace        string `json:"namespace"`
+	Name             string `json:"name"`
+	PayloadVersion   string `json:"payloadVersion"`
+	MessageID        string `json:"messageId"`
+	CorrelationToken string `json:"correlationToken,omitempty"`
+}
+
+// alexaEndpoint identifies the addressed device; Scope is echoed back.
+type alexaEndpoint struct {
+	Scope      json.RawMessage `json:"scope,omitempty"`
+	EndpointID string          `json:"endpointId"`
+}
+
+type alexaDirective struct {
+	Directive struct {
+		Header   alexaHeader     `json:"header"`
+		Endpoint *alexaEndpoint  `json:"endpoint,omitempty"`
+		Payload  json.RawMessage `json:"payload"`
+	} `json:"directive"`
+}
+
+type alexaProperty struct {
+	Namespace                 string    `json:"namespace"`
+	Name                      string    `json:"name"`
+	Value                     any       `json:"value"`
+	TimeOfSample              time.Time `json:"timeOfSample"`
+	UncertaintyInMilliseconds int       `json:"uncertaintyInMilliseconds"`
+}
+
+type alexaEvent struct {
+	Header   alexaHeader    `json:"header"`
+	Endpoint *alexaEndpoint `json:"endpoint,omitempty"`
+	Payload  any            `json:"payload"`
+}
+
+type alexaResponse struct {
+	Event   alexaEvent `json:"event"`
+	Context *struct {
+		Properties []alexaProperty `json:"properties"`
+	} `json:"context,omitempty"`
+}
+
+// alexaError is a directive failure reported as an Alexa ErrorResponse.
+type alexaError struct {
+	Type    string // e.g. NO_SUCH_ENDPOINT, INVALID_DIRECTIVE, ENDPOINT_UNREACHABLE
+	Message string
+}
+
+func (e *alexaError) Error() string { return e.Type + ": " + e.Message }
+
+type alexaHandler struct {
+	token   string
+	targets []voiceTarget
+	events  chan<- Event
+	logger  *slog.Logger
+	now     func() time.Time // for tests; nil = time.Now
+}
+
+func (h *alexaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !eventWebhookAuthorized(r, h.token) {
+		h.logger.Warn("alexa directive unauthorized", "remote_addr", r.RemoteAddr)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxAlexaDirectiveBody+1))
+	if err != nil || len(body) > maxAlexaDirectiveBody {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	var d alexaDirective
+	if err := json.Unmarshal(body, &d); err != nil || d.Directive.Header.Name == "" {
+		http.Error(w, "not an Alexa directive", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.handle(r.Context(), d)
+	if err != nil {
+		var ae *alexaError
+		if !errors.As(err, &ae) {
+			ae = &alexaError{Type: "INTERNAL_ERROR", Message: err.Error()}
+		}
+		h.logger.Warn("alexa directive failed", "namespace", d.Directive.Header.Namespace, "name", d.Directive.Header.Name, "error", err)
+		resp = alexaResponse{Event: alexaEvent{
+			Header:   h.header(d, "Alexa", "ErrorResponse"),
+			Endpoint: d.Directive.Endpoint,
+			Payload:  map[string]string{"type": ae.Type, "message": ae.Message},
+		}}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// handle answers one directive.
+func (h *alexaHandler) handle(ctx context.Context, d alexaDirective) (alexaResponse, error) {
+	hdr := d.Directive.Header
+	switch hdr.Namespace + " " + hdr.Name {
+	case "Alexa.Discovery Discover":
+		return alexaResponse{Event: alexaEvent{
+			Header:  h.header(d, "Alexa.Discovery", "Discover.Response"),
+			Payload: map[string]any{"endpoints": h.discoveryEndpoints()},
+		}}, nil
+	case "Alexa.Authorization AcceptGrant":
+		return alexaResponse{Event: alexaEvent{
+			Header:  h.header(d, "Alexa.Authorization", "AcceptGrant.Response"),
+			Payload: struct{}{},
+		}}, nil
+	}
+
+	target, err := h.target(d)
+	if err != nil {
+		return alexaResponse{}, err
+	}
+	state, err := currentVoiceState(ctx, h.events, target)
+	if err != nil {
+		return alexaResponse{}, &alexaError{Type: "ENDPOINT_UNREACHABLE", Message: err.Error()}
+	}
+
+	var ev Event
+	name := "Response"
+	switch hdr.Namespace + " " + hdr.Name {
+	case "Alexa ReportState":
+		name = "StateReport"
+	case "Alexa.Speaker SetVolume":
+		var p struct {
+			Volume *int `json:"volume"`
+		}
+		if err := json.Unmarshal(d.Directive.Payload, &p); err != nil || p.Volume == nil {
+			return alexaResponse{}, &alexaError{Type: "INVALID_DIRECTIVE", Message: "SetVolume needs payload.volume"}
+		}
+		state.Percent = min(max(*p.Volume, 0), 100)
+		ev = SetVolumePercent{Percent: float64(state.Percent), Origin: "alexa"}
+	case "Alexa.Speaker AdjustVolume":
+		var p struct {
+			Volume *int `json:"volume"`
+		}
+		if err := json.Unmarshal(d.Directive.Payload, &p); err != nil || p.Volume == nil {
+			return alexaResponse{}, &alexaError{Type: "INVALID_DIRECTIVE", Message: "AdjustVolume needs payload.volume"}
+		}
+		state.Percent = min(max(state.Percent+*p.Volume, 0), 100)
+		ev = SetVolumePercent{Percent: float64(state.Percent), Origin: "alexa"}
+	case "Alexa.Speaker SetMute":
+		var p struct {
+			Mute *bool `json:"mute"`
+		}
+		if err := json.Unmarshal(d.Directive.Payload, &p); err != nil || p.Mute == nil {
+			return alexaResponse{}, &alexaError{Type: "INVALID_DIRECTIVE", Message: "SetMute needs payload.mute"}
+		}
+		state.Muted = *p.Mute
+		ev = SetMute{Muted: state.Muted}
+	default:
+		return alexaResponse{}, &alexaError{Type: "INVALID_DIRECTIVE", Message: fmt.Sprintf("unsupported directive %s.%s", hdr.Namespace, hdr.Name)}
+	}
+	if ev != nil {
+		if err := sendVoiceEvent(h.events, target.event(ev)); err != nil {
+			return alexaResponse{}, &alexaError{Type: "ENDPOINT_BUSY", Message: err.Error()}
+		}
+		h.logger.Debug("alexa directive", "name", hdr.Name, "target", target.Name, "percent", state.Percent, "muted", state.Muted)
+	}
+
+	resp := alexaResponse{Event: alexaEvent{
+		Header:   h.header(d, "Alexa", name),
+		Endpoint: d.Directive.Endpoint,
+		Payload:  struct{}{},
+	}}
+	resp.Context = &struct {
+		Properties []alexaProperty `json:"properties"`
+	}{Properties: h.properties(state)}
+	return resp, nil
+}
+
+// target resolves the directive's endpoint.
+func (h *alexaHandler) target(d alexaDirective) (voiceTarget, error) {
+	if d.Directive.Endpoint == nil {
+		return voiceTarget{}, &alexaError{Type: "INVALID_DIRECTIVE", Message: "directive has no endpoint"}
+	}
+	for _, t := range h.targets {
+		if t.ID == d.Directive.Endpoint.EndpointID {
+			return t, nil
+		}
+	}
+	return voiceTarget{}, &alexaError{Type: "NO_SUCH_ENDPOINT", Message: fmt.Sprintf("unknown endpoint %q", d.Directive.Endpoint.EndpointID)}
+}
+
+func (h *alexaHandler) discoveryEndpoints() []map[string]any {
+	out := make([]map[string]any, 0, len(h.targets))
+	for _, t := range h.targets {
+		out = append(out, map[string]any{
+			"endpointId":        t.ID,
+			"manufacturerName":  "StreamerBrainz",
+			"friendlyName":      t.Name,
+			"description":       "StreamerBrainz volume control",
+			"displayCategories": []string{"SPEAKER"},
+			"capabilities": []map[string]any{
+				{"type": "AlexaInterface", "interface": "Alexa", "version": "3"},
+				{
+					"type": "AlexaInterface", "interface": "Alexa.Speaker", "version": "3",
+					"properties": map[string]any{
+						"supported":           []map[string]string{{"name": "volume"}, {"name": "muted"}},
+						"retrievable":         true,
+						"proactivelyReported": false,
+					},
+				},
+			},
+		})
+	}
+	return out
+}
+
+func (h *alexaHandler) properties(state voiceState) []alexaProperty {
+	now := time.Now
+	if h.now != nil {
+		now = h.now
+	}
+	at := now().UTC().Truncate(time.Second)
+	return []alexaProperty{
+		{Namespace: "Alexa.Speaker", Name: "volume", Value: state.Percent, TimeOfSample: at},
+		{Namespace: "Alexa.Speaker", Name: "muted", Value: state.Muted, TimeOfSample: at},
+	}
+}
+
+// header builds a response header for directive d.
+func (h *alexaHandler) header(d alexaDirective, namespace, name string) alexaHeader {
+	return alexaHeader{
+		Namespace:        namespace,
+		Name:             name,
+		PayloadVersion:   "3",
+		MessageID:        rand.Text(),
+		CorrelationToken: d.Directive.Header.CorrelationToken,
+	}
+}
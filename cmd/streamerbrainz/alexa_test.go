@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func postAlexaDirective(t *testing.T, h http.Handler, namespace, name, endpoint, payload string) (alexaResponse, map[string]any) {
+	t.Helper()
+	body := `{"directive":{"header":{"namespace":"` + namespace + `","name":"` + name + `","payloadVersion":"3","messageId":"m1","correlationToken":"c1"},` +
+		`"endpoint":{"endpointId":"` + endpoint + `"},"payload":` + payload + `}}`
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("%s: status %d", name, rec.Code)
+	}
+	var resp alexaResponse
+	var raw map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &raw); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+	return resp, raw
+}
+
+// alexaProperties returns the response's volume and muted properties.
+func alexaProperties(raw map[string]any) (volume float64, muted bool) {
+	ctx := raw["context"].(map[string]any)
+	for _, p := range ctx["properties"].([]any) {
+		p := p.(map[string]any)
+		switch p["name"] {
+		case "volume":
+			volume = p["value"].(float64)
+		case "muted":
+			muted = p["value"].(bool)
+		}
+	}
+	return volume, muted
+}
+
+func TestAlexa_Directives(t *testing.T) {
+	events := make(chan Event, 4)
+	defer close(events)
+	forwarded := make(chan Event, 4)
+	go serveSnapshots(events, StateSnapshot{
+		VolumeDB: -30, VolumeKnown: true, VolumePercent: 35.4,
+		Zones: []StateSnapshot{{Zone: "kitchen", VolumeKnown: true, VolumePercent: 20, Muted: true}},
+	}, forwarded)
+	opts := voiceOptions{Zones: map[string]string{"kitchen": "Kitchen"}}
+	h := &alexaHandler{token: "secret", targets: opts.targets(), events: events, logger: slog.Default()}
+
+	resp, raw := postAlexaDirective(t, h, "Alexa.Discovery", "Discover", "", `{}`)
+	endpoints := raw["event"].(map[string]any)["payload"].(map[string]any)["endpoints"].([]any)
+	if resp.Event.Header.Name != "Discover.Response" || len(endpoints) != 2 ||
+		endpoints[0].(map[string]any)["friendlyName"] != "Hi-Fi" || endpoints[1].(map[string]any)["endpointId"] != "streamerbrainz-kitchen" {
+		t.Fatalf("discovery: %+v", raw)
+	}
+
+	resp, raw = postAlexaDirective(t, h, "Alexa.Speaker", "SetVolume", "streamerbrainz", `{"volume":40}`)
+	if ev := <-forwarded; !reflect.DeepEqual(ev, SetVolumePercent{Percent: 40, Origin: "alexa"}) {
+		t.Fatalf("SetVolume: got %#v", ev)
+	}
+	if v, m := alexaProperties(raw); resp.Event.Header.Name != "Response" || resp.Event.Header.CorrelationToken != "c1" || v != 40 || m {
+		t.Fatalf("SetVolume response: %+v", raw)
+	}
+
+	_, raw = postAlexaDirective(t, h, "Alexa.Speaker", "AdjustVolume", "streamerbrainz-kitchen", `{"volume":-30,"volumeDefault":false}`)
+	if ev := <-forwarded; !reflect.DeepEqual(ev, ZonedEvent{Zone: "kitchen", Event: SetVolumePercent{Percent: 0, Origin: "alexa"}}) {
+		t.Fatalf("AdjustVolume: got %#v", ev)
+	}
+	if v, m := alexaProperties(raw); v != 0 || !m {
+		t.Fatalf("AdjustVolume response: %+v", raw)
+	}
+
+	postAlexaDirective(t, h, "Alexa.Speaker", "SetMute", "streamerbrainz", `{"mute":true}`)
+	if ev := <-forwarded; !reflect.DeepEqual(ev, SetMute{Muted: true}) {
+		t.Fatalf("SetMute: got %#v", ev)
+	}
+
+	resp, raw = postAlexaDirective(t, h, "Alexa", "ReportState", "streamerbrainz", `{}`)
+	if v, _ := alexaProperties(raw); resp.Event.Header.Name != "StateReport" || v != 35 {
+		t.Fatalf("ReportState: %+v", raw)
+	}
+
+	resp, _ = postAlexaDirective(t, h, "Alexa.Speaker", "SetVolume", "nope", `{"volume":1}`)
+	if resp.Event.Header.Name != "ErrorResponse" || resp.Event.Payload.(map[string]any)["type"] != "NO_SUCH_ENDPOINT" {
+		t.Fatalf("unknown endpoint: %+v", resp)
+	}
+	resp, _ = postAlexaDirective(t, h, "Alexa.PowerController", "TurnOn", "streamerbrainz", `{}`)
+	if resp.Event.Header.Name != "ErrorResponse" || resp.Event.Payload.(map[string]any)["type"] != "INVALID_DIRECTIVE" {
+		t.Fatalf("unsupported directive: %+v", resp)
+	}
+}
+
+func TestAlexa_Unauthorized(t *testing.T) {
+	h := &alexaHandler{token: "secret", logger: slog.Default()}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rec.Code)
+	}
+}
+
+func TestNewAlexaProtocol_ValidatesOptions(t *testing.T) {
+	good := decodeControlProtocolConfig(t, "type: alexa\noptions:\n  listen: 127.0.0.1:8091\n  token: env:ALEXA_TOKEN\n  name: Hi-Fi\n  zones: {kitchen: Kitchen}\n")
+	if _, err := newControlProtocol(good, slog.Default()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, src := range []string{
+		"type: alexa\noptions:\n  listen: 127.0.0.1:8091\n",
+		"type: alexa\noptions:\n  listen: 8091\n  token: env:T\n",
+		"type: alexa\noptions:\n  listen: 127.0.0.1:8091\n  token: env:T\n  zones: {kitchen: hi-fi}\n",
+	} {
+		if _, err := newControlProtocol(decodeControlProtocolConfig(t, src), slog.Default()); err == nil {
+			t.Errorf("expected error for %q", src)
+		}
+	}
+}
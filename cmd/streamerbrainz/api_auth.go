@@ -0,0 +1,113 @@
+package main
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ============================================================================
+// Control API authorization
+// ============================================================================
+// Endpoints that change state or expose internals take the same token as
+// POST /webhooks/event (webhooks.event.token_file), sent as
+// X-StreamerBrainz-Token or Authorization: Bearer. With api.port 0 they share
+// the webhooks listener, which binds every interface by default, so without
+// it anyone on the LAN could drive them.
+//
+// Without a token configured they are served only when the API listener is
+// bound to loopback (api.bind_address, or webhooks.bind_address with api.port
+// 0), e.g. behind a local reverse proxy; anywhere else they answer 403. The
+// CLI (tune, ctl tap) sends the token from the same config file.
+// ============================================================================
+
+// apiTokenHeader carries the control API token (Authorization: Bearer works too).
+const apiTokenHeader = "X-StreamerBrainz-Token"
+
+// apiAuth guards the protected control API endpoints.
+type apiAuth struct {
+	token    string // empty: none configured
+	loopback bool   // the API listener only accepts local connections
+	logger   *slog.Logger
+}
+
+// newAPIAuth reads the token configured for cfg and notes whether the API
+// listener is loopback-only.
+func newAPIAuth(cfg Config, logger *slog.Logger) (apiAuth, error) {
+	token, err := apiClientToken(cfg)
+	if err != nil {
+		return apiAuth{}, err
+	}
+	addr := cfg.API.ListenAddr()
+	if addr == "" {
+		addr = cfg.Webhooks.ListenAddr()
+	}
+	return apiAuth{token: token, loopback: isLoopbackListenAddr(addr), logger: logger}, nil
+}
+
+// open reports whether protected endpoints are reachable at all.
+func (a apiAuth) open() bool { return a.token != "" || a.loopback }
+
+// require serves h only to authorized requests (see above).
+func (a apiAuth) require(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case a.token != "":
+			if !eventWebhookAuthorized(r, a.token) {
+				a.logger.Warn("control api request unauthorized", "path", r.URL.Path, "remote_addr", r.RemoteAddr)
+				writeEventWebhookResponse(w, http.StatusUnauthorized, "unauthorized")
+				return
+			}
+		case !a.loopback:
+			writeEventWebhookResponse(w, http.StatusForbidden,
+				"set webhooks.event.token_file, or bind the API listener to loopback, to use this endpoint")
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// isLoopbackListenAddr reports whether a listener on addr (host:port) only
+// accepts local connections. An empty host binds every interface.
+func isLoopbackListenAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil || host == "" {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// apiClientToken reads the control API token from cfg ("" if none is set).
+func apiClientToken(cfg Config) (string, error) {
+	if cfg.Webhooks.Event.TokenFile == "" {
+		return "", nil
+	}
+	return readSecret(cfg.Webhooks.Event.TokenFile)
+}
+
+// apiTokenTransport adds the control API token to every request.
+type apiTokenTransport struct {
+	token string
+	base  http.RoundTripper
+}
+
+func (t apiTokenTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set(apiTokenHeader, t.token)
+	return t.base.RoundTrip(r)
+}
+
+// newAPIClient returns a client for the daemon's control API that sends token
+// (if set).
+func newAPIClient(token string, timeout time.Duration) *http.Client {
+	c := &http.Client{Timeout: timeout}
+	if token != "" {
+		c.Transport = apiTokenTransport{token: token, base: http.DefaultTransport}
+	}
+	return c
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAPIAuth_Require(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for _, tc := range []struct {
+		name   string
+		auth   apiAuth
+		header string
+		want   int
+	}{
+		{"token missing", apiAuth{token: "tok"}, "", http.StatusUnauthorized},
+		{"token wrong", apiAuth{token: "tok"}, "nope", http.StatusUnauthorized},
+		{"token", apiAuth{token: "tok"}, "tok", http.StatusOK},
+		{"token required on loopback too", apiAuth{token: "tok", loopback: true}, "", http.StatusUnauthorized},
+		{"no token, loopback", apiAuth{loopback: true}, "", http.StatusOK},
+		{"no token, exposed", apiAuth{}, "", http.StatusForbidden},
+	} {
+		tc.auth.logger = slog.New(slog.DiscardHandler)
+		req := httptest.NewRequest(http.MethodPost, "/jsonrpc", nil)
+		if tc.header != "" {
+			req.Header.Set(apiTokenHeader, tc.header)
+		}
+		rec := httptest.NewRecorder()
+		tc.auth.require(ok).ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s: got %d, want %d", tc.name, rec.Code, tc.want)
+		}
+	}
+}
+
+func TestNewAPIAuth(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	cfg := DefaultConfig()
+	a, err := newAPIAuth(cfg, logger)
+	if err != nil || a.open() {
+		t.Fatalf("default config: %+v, %v (want closed: all interfaces, no token)", a, err)
+	}
+
+	cfg.Webhooks.BindAddress = "127.0.0.1"
+	if a, _ := newAPIAuth(cfg, logger); !a.loopback {
+		t.Fatal("loopback webhooks listener not recognized")
+	}
+	// A dedicated API port decides, not the webhooks listener.
+	cfg.API.Port, cfg.API.BindAddress = 3002, ""
+	if a, _ := newAPIAuth(cfg, logger); a.loopback {
+		t.Fatal("api.port on all interfaces counted as loopback")
+	}
+
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Webhooks.Event.TokenFile = path
+	if a, err := newAPIAuth(cfg, logger); err != nil || a.token != "s3cret" {
+		t.Fatalf("token = %q, %v", a.token, err)
+	}
+}
+
+func TestIsLoopbackListenAddr(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"127.0.0.1:3001": true,
+		"[::1]:3001":     true,
+		"localhost:3001": true,
+		":3001":          false,
+		"0.0.0.0:3001":   false,
+		"10.0.0.2:3001":  false,
+	} {
+		if got := isLoopbackListenAddr(addr); got != want {
+			t.Errorf("isLoopbackListenAddr(%q) = %v", addr, got)
+		}
+	}
+}
+
+func TestNewAPIClient_SendsToken(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(apiTokenHeader)
+	}))
+	defer srv.Close()
+
+	resp, err := newAPIClient("tok", 0).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got != "tok" {
+		t.Fatalf("token header = %q", got)
+	}
+}
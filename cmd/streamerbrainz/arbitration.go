@@ -0,0 +1,77 @@
+package main
+
+import (
+	"slices"
+	"time"
+)
+
+// ============================================================================
+// Control arbitration
+// ============================================================================
+// Without arbitration every volume intent is applied in arrival order, so an
+// IR hold and a web slider drag interleaved in the same second fight each
+// other. arbitration.policy picks who wins while several sources are active:
+//
+//   - "none" (default): today's behaviour, every intent applies.
+//   - "physical": while a physical source (arbitration.physical_origins:
+//     IR holds, rotary encoders, local key presets) is moving the volume, and
+//     for lockout_ms after its last input, intents from every other origin
+//     are rejected.
+//   - "last_writer": the origin that changed the volume last owns it until
+//     lockout_ms pass without input from it; other origins are rejected.
+//
+// Origins are those of volume_origin.go. Holds and ramps keep their origin
+// active while they move, so a held key locks the volume for the whole hold.
+// A rejected intent is dropped by the reducer and reported as a
+// "control_rejected" broadcast, so a UI can snap its slider back instead of
+// showing a level that was never applied.
+// ============================================================================
+
+const (
+	arbitrationNone       = "none"
+	arbitrationPhysical   = "physical"
+	arbitrationLastWriter = "last_writer"
+)
+
+// defaultPhysicalOrigins are the origins treated as physical controls.
+var defaultPhysicalOrigins = []string{"ir", "rotary", "input"}
+
+// BroadcastControlRejected is emitted when arbitration drops a volume intent.
+type BroadcastControlRejected struct {
+	// Origin is the rejected source, Holder the source currently in control.
+	Origin string `json:"origin"`
+	Holder string `json:"holder"`
+	// Until is when Holder's lock ends if it sends nothing further.
+	Until time.Time `json:"until"`
+	At    time.Time `json:"at"`
+}
+
+func (BroadcastControlRejected) stateBroadcastMarker() {}
+
+// arbitrate reports whether a volume intent from origin at `at` must be
+// rejected under policy's arbitration settings, and the broadcast announcing it.
+func arbitrate(s *DaemonState, origin string, at time.Time, policy ReducerPolicy) (BroadcastControlRejected, bool) {
+	holder := s.VolumeOrigin
+	if at.IsZero() || holder.Origin == "" || holder.Origin == origin || policy.ArbitrationLockout <= 0 {
+		return BroadcastControlRejected{}, false
+	}
+	until := holder.At.Add(policy.ArbitrationLockout)
+	if !at.Before(until) {
+		return BroadcastControlRejected{}, false
+	}
+
+	switch policy.ArbitrationPolicy {
+	case arbitrationPhysical:
+		if !slices.Contains(policy.PhysicalOrigins, holder.Origin) || slices.Contains(policy.PhysicalOrigins, origin) {
+			return BroadcastControlRejected{}, false
+		}
+	case arbitrationLastWriter:
+		// Internal adjustments (calibration, limits) never hold the volume.
+		if holder.Origin == "calibration" || holder.Origin == "limits" {
+			return BroadcastControlRejected{}, false
+		}
+	default:
+		return BroadcastControlRejected{}, false
+	}
+	return BroadcastControlRejected{Origin: origin, Holder: holder.Origin, Until: until, At: at}, true
+}
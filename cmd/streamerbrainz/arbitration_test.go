@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReduce_ArbitrationPhysicalLocksOutNetwork(t *testing.T) {
+	cfg := VelocityConfig{
+		MinDB: -80,
+		MaxDB: 0,
+	}
+	policy := ReducerPolicy{
+		ArbitrationPolicy:  arbitrationPhysical,
+		ArbitrationLockout: time.Second,
+		PhysicalOrigins:    defaultPhysicalOrigins,
+	}
+	t0 := time.Unix(1000, 0).UTC()
+	s := &DaemonState{}
+	s.SetObservedVolume(-30, t0)
+
+	rr := Reduce(s, TimedEvent{At: t0, Event: VolumeHeld{Direction: 1}}, cfg, RotaryConfig{}, policy)
+	rr = Reduce(rr.State, Tick{Now: t0.Add(500 * time.Millisecond), Dt: 0.5}, cfg, RotaryConfig{}, policy)
+	rr = Reduce(rr.State, TimedEvent{At: t0.Add(600 * time.Millisecond), Event: VolumeRelease{}}, cfg, RotaryConfig{}, policy)
+	rr.State.ClearDesiredVolume()
+
+	// The lockout runs from the hold's last movement (the tick), not from its start.
+	rr = Reduce(rr.State, TimedEvent{At: t0.Add(1400 * time.Millisecond), Event: SetVolumeAbsolute{Db: -10, Origin: "webui"}}, cfg, RotaryConfig{}, policy)
+	if len(rr.Broadcasts) != 1 {
+		t.Fatalf("broadcasts = %v", rr.Broadcasts)
+	}
+	b, ok := rr.Broadcasts[0].(BroadcastControlRejected)
+	if !ok || b.Origin != "webui" || b.Holder != "ir" || !b.Until.Equal(t0.Add(1500*time.Millisecond)) {
+		t.Fatalf("rejection = %#v", rr.Broadcasts[0])
+	}
+	if _, ok := rr.State.GetDesiredVolume(); ok {
+		t.Fatal("rejected set was applied")
+	}
+
+	// Other physical sources are never locked out.
+	rr = Reduce(rr.State, TimedEvent{At: t0.Add(1400 * time.Millisecond), Event: RotaryTurn{Steps: 1}}, cfg, RotaryConfig{}, policy)
+	if len(rr.Broadcasts) != 0 {
+		t.Fatalf("rotary rejected: %v", rr.Broadcasts)
+	}
+
+	// Network sources never lock out physical ones, and get through once the lockout ends.
+	rr = Reduce(rr.State, TimedEvent{At: t0.Add(3 * time.Second), Event: SetVolumeAbsolute{Db: -10, Origin: "webui"}}, cfg, RotaryConfig{}, policy)
+	if len(rr.Broadcasts) != 0 {
+		t.Fatalf("set after lockout rejected: %v", rr.Broadcasts)
+	}
+	rr = Reduce(rr.State, TimedEvent{At: t0.Add(3100 * time.Millisecond), Event: VolumeStep{Steps: -1}}, cfg, RotaryConfig{}, policy)
+	if len(rr.Broadcasts) != 0 {
+		t.Fatalf("physical step rejected: %v", rr.Broadcasts)
+	}
+}
+
+func TestReduce_ArbitrationLastWriter(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0}
+	policy := ReducerPolicy{ArbitrationPolicy: arbitrationLastWriter, ArbitrationLockout: time.Second}
+	t0 := time.Unix(1000, 0).UTC()
+	s := &DaemonState{}
+
+	rr := Reduce(s, TimedEvent{At: t0, Event: SetVolumeAbsolute{Db: -20, Origin: "webui"}}, cfg, RotaryConfig{}, policy)
+	rr = Reduce(rr.State, TimedEvent{At: t0.Add(500 * time.Millisecond), Event: SetVolumeAbsolute{Db: -19, Origin: "webui"}}, cfg, RotaryConfig{}, policy)
+	if len(rr.Broadcasts) != 0 {
+		t.Fatalf("holder's own set rejected: %v", rr.Broadcasts)
+	}
+	rr = Reduce(rr.State, TimedEvent{At: t0.Add(time.Second), Event: VolumeStep{Steps: 1}}, cfg, RotaryConfig{}, policy)
+	if b, ok := rr.Broadcasts[0].(BroadcastControlRejected); len(rr.Broadcasts) != 1 || !ok || b.Holder != "webui" {
+		t.Fatalf("broadcasts = %v", rr.Broadcasts)
+	}
+	rr = Reduce(rr.State, TimedEvent{At: t0.Add(1600 * time.Millisecond), Event: VolumeStep{Steps: 1}}, cfg, RotaryConfig{}, policy)
+	if len(rr.Broadcasts) != 0 {
+		t.Fatalf("step after lockout rejected: %v", rr.Broadcasts)
+	}
+}
+
+func TestReduce_ArbitrationNoneAppliesEverything(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0}
+	policy := ReducerPolicy{ArbitrationPolicy: arbitrationNone, ArbitrationLockout: time.Second}
+	t0 := time.Unix(1000, 0).UTC()
+	rr := Reduce(&DaemonState{}, TimedEvent{At: t0, Event: VolumeStep{Steps: 1}}, cfg, RotaryConfig{}, policy)
+	rr = Reduce(rr.State, TimedEvent{At: t0, Event: SetVolumeAbsolute{Db: -10, Origin: "webui"}}, cfg, RotaryConfig{}, policy)
+	if v, ok := rr.State.GetDesiredVolume(); len(rr.Broadcasts) != 0 || !ok || v != -10 {
+		t.Fatalf("desired = %v, %v; broadcasts %v", v, ok, rr.Broadcasts)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+)
+
+// TeeBroadcasts copies every reducer-emitted StateBroadcast from src to each dst.
+//
+// The reducer publishes into a single channel; consumers (WS/SSE broadcaster, outbound
+// webhooks, ...) each get their own copy so one slow consumer can't starve the others.
+// Sends never block: if a destination is full, the broadcast is dropped for that
+// destination only. All destinations are closed when src is closed or ctx is canceled.
+func TeeBroadcasts(ctx context.Context, src <-chan StateBroadcast, logger *slog.Logger, dsts ...chan<- StateBroadcast) {
+	defer func() {
+		for _, d := range dsts {
+			close(d)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case b, ok := <-src:
+			if !ok {
+				return
+			}
+			for i, d := range dsts {
+				select {
+				case d <- b:
+				default:
+					logger.Warn("broadcast consumer queue full, dropping broadcast", "consumer", i)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import "time"
+
+// ============================================================================
+// Calibration (reference level) mode
+// ============================================================================
+// For measurement sessions (REW etc.): entering calibration mode sets the zone
+// to calibration.reference_db and locks out every volume change (holds, steps,
+// rotary in volume mode, absolute sets, output switches) until it is exited.
+// Mute still works.
+//
+// Enter/exit with a long press of calibration.long_press_key or a
+// calibration_mode event ({"enabled": true|false}; omit to toggle). On exit
+// the volume from before the session is restored (calibration.restore_on_exit).
+// ============================================================================
+
+// CalibrationMode enters or exits calibration mode. A nil Enabled toggles it.
+type CalibrationMode struct {
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
+func (CalibrationMode) eventMarker() {}
+
+// BroadcastCalibrationMode is emitted when calibration mode is entered or exited.
+type BroadcastCalibrationMode struct {
+	Active      bool      `json:"active"`
+	ReferenceDB float64   `json:"reference_db"`
+	At          time.Time `json:"at"`
+}
+
+func (BroadcastCalibrationMode) stateBroadcastMarker() {}
+
+// CalibrationState is the reducer-owned calibration mode state.
+type CalibrationState struct {
+	Active bool
+
+	// RestoreDB is the volume to return to on exit (nil if unknown when entering).
+	RestoreDB *float64
+}
+
+// calibrationKeyCodes maps calibration.long_press_key names to key codes.
+var calibrationKeyCodes = map[string]uint16{
+	"mute":       KEY_MUTE,
+	"audio":      KEY_AUDIO,
+	"play_pause": KEY_PLAYPAUSE,
+	"stop":       KEY_STOPCD,
+	"button":     BTN_0,
+}
+
+// reduceCalibration enters or exits calibration mode.
+func reduceCalibration(s *DaemonState, ev CalibrationMode, at time.Time, cfg VelocityConfig, policy ReducerPolicy) []StateBroadcast {
+	enable := !s.Calibration.Active
+	if ev.Enabled != nil {
+		enable = *ev.Enabled
+	}
+	if enable == s.Calibration.Active {
+		return nil
+	}
+
+	// Either way the level is set explicitly: stop any gesture in progress.
+	s.VolumeCtrl.HeldDirection = 0
+	s.VolumeCtrl.VelocityDBPerS = 0
+	s.VolumeCtrl.HoldBeganAt = time.Time{}
+	s.VolumeCtrl.Ramping = false
+	s.noteVolumeOrigin("calibration", at)
+
+	ref := clampVolumeDB(policy.CalibrationReferenceDB, cfg)
+	if enable {
+		s.Calibration.RestoreDB = nil
+		if v, ok := s.GetDesiredVolume(); ok {
+			s.Calibration.RestoreDB = &v
+		} else if s.Camilla.VolumeKnown {
+			v := s.Camilla.VolumeMB.DB()
+			s.Calibration.RestoreDB = &v
+		}
+		s.Calibration.Active = true
+		s.SetDesiredVolume(ref)
+		s.VolumeCtrl.TargetDB = ref
+	} else {
+		s.Calibration.Active = false
+		if policy.CalibrationRestore && s.Calibration.RestoreDB != nil {
+			v := clampVolumeDB(*s.Calibration.RestoreDB, cfg)
+			s.SetDesiredVolume(v)
+			s.VolumeCtrl.TargetDB = v
+		}
+		s.Calibration.RestoreDB = nil
+	}
+	return []StateBroadcast{BroadcastCalibrationMode{Active: enable, ReferenceDB: ref, At: at}}
+}
+
+// volumeLocked reports whether e would change the volume and must be dropped
+// because calibration mode is active or a test signal is playing.
+func volumeLocked(s *DaemonState, e Event) bool {
+	if !s.Calibration.Active && s.TestSignal.Channel == "" && !s.TestSignal.Pending {
+		return false
+	}
+	switch e.(type) {
+	case VolumeHeld, VolumeStep, SetVolumeAbsolute, OutputSelect:
+		return true
+	case RotaryTurn, RotaryTurnHiRes:
+		return s.encoderMode() == EncoderModeVolume
+	}
+	return false
+}
+
+// longPressDetector turns a long press of one key into a CalibrationMode toggle.
+// The key's normal action moves to its release (short presses only).
+type longPressDetector struct {
+	code    uint16
+	hold    time.Duration
+	pressAt time.Time
+	pressed bool
+	fired   bool
+}
+
+// handle processes ev for the configured key. It returns true if ev was consumed;
+// short is set when a short press ended and the key's normal action should run.
+func (l *longPressDetector) handle(ev inputEvent, events chan<- Event) (consumed, short bool) {
+	if l == nil || ev.Type != EV_KEY || ev.Code != l.code {
+		return false, false
+	}
+	switch ev.Value {
+	case evValuePress:
+		l.pressAt, l.pressed, l.fired = ev.time(), true, false
+	case evValueRepeat:
+		if l.pressed && !l.fired && ev.time().Sub(l.pressAt) >= l.hold {
+			l.fired = true
+			events <- CalibrationMode{}
+		}
+	case evValueRelease:
+		if !l.pressed {
+			return true, false
+		}
+		l.pressed = false
+		if l.fired {
+			return true, false
+		}
+		// Devices without autorepeat (e.g. a rotary push-button) report the hold on release.
+		if ev.time().Sub(l.pressAt) >= l.hold {
+			events <- CalibrationMode{}
+			return true, false
+		}
+		return true, true
+	}
+	return true, false
+}
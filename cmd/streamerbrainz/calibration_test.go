@@ -0,0 +1,86 @@
+package main
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestReduce_CalibrationModePinsAndRestoresVolume(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0}
+	policy := ReducerPolicy{CalibrationReferenceDB: -20, CalibrationRestore: true}
+	rotaryCfg := RotaryConfig{DbPerStep: 1}
+	t0 := time.Unix(1000, 0).UTC()
+
+	s := &DaemonState{}
+	s.SetObservedVolume(-35, t0)
+
+	rr := Reduce(s, TimedEvent{Event: CalibrationMode{}, At: t0}, cfg, rotaryCfg, policy)
+	if v, ok := rr.State.GetDesiredVolume(); !ok || v != -20 {
+		t.Fatalf("expected reference level -20, got %v (ok=%v)", v, ok)
+	}
+	if len(rr.Broadcasts) != 1 || !rr.Broadcasts[0].(BroadcastCalibrationMode).Active {
+		t.Fatalf("expected calibration_mode broadcast, got %#v", rr.Broadcasts)
+	}
+	rr = Reduce(rr.State, Tick{Now: t0.Add(10 * time.Millisecond), Dt: 0.01}, cfg, rotaryCfg, policy)
+	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: -20, At: t0.Add(10 * time.Millisecond)}, cfg, rotaryCfg, policy)
+
+	// Volume changes are locked out; mute is not.
+	for _, ev := range []Event{SetVolumeAbsolute{Db: -5}, VolumeStep{Steps: 3, DbPerStep: 1}, RotaryTurn{Steps: 2}} {
+		rr = Reduce(rr.State, ev, cfg, rotaryCfg, policy)
+		if _, ok := rr.State.GetDesiredVolume(); ok {
+			t.Fatalf("%T changed the volume during calibration", ev)
+		}
+	}
+	rr = Reduce(rr.State, ToggleMute{}, cfg, rotaryCfg, policy)
+	if rr.State.Intent.DesiredMute == nil {
+		t.Fatalf("expected mute to stay available during calibration")
+	}
+
+	off := false
+	rr = Reduce(rr.State, TimedEvent{Event: CalibrationMode{Enabled: &off}, At: t0.Add(time.Minute)}, cfg, rotaryCfg, policy)
+	if v, ok := rr.State.GetDesiredVolume(); !ok || v != -35 {
+		t.Fatalf("expected previous volume -35 restored, got %v (ok=%v)", v, ok)
+	}
+	if rr.State.Calibration.Active || len(rr.Broadcasts) != 1 || rr.Broadcasts[0].(BroadcastCalibrationMode).Active {
+		t.Fatalf("expected calibration mode to end, got %#v", rr.Broadcasts)
+	}
+}
+
+func TestInputDecoder_LongPressTogglesCalibration(t *testing.T) {
+	events := make(chan Event, 8)
+	d := newInputDecoder(events, inputDecoderConfig{LongPressKey: KEY_MUTE, LongPressHold: 2 * time.Second}, &Metrics{}, slog.New(slog.DiscardHandler))
+	key := func(value int32, sec int64) inputEvent {
+		return inputEvent{Sec: sec, Type: EV_KEY, Code: KEY_MUTE, Value: value}
+	}
+
+	// Short press: the normal action runs on release.
+	d.handle(key(evValuePress, 0))
+	if len(events) != 0 {
+		t.Fatalf("expected press to be deferred")
+	}
+	d.handle(key(evValueRelease, 0))
+	if ev := <-events; ev != (ToggleMute{}) {
+		t.Fatalf("expected ToggleMute on short press, got %#v", ev)
+	}
+
+	// Long press via autorepeat: one toggle, nothing on release.
+	d.handle(key(evValuePress, 10))
+	d.handle(key(evValueRepeat, 11))
+	d.handle(key(evValueRepeat, 12))
+	d.handle(key(evValueRepeat, 13))
+	d.handle(key(evValueRelease, 13))
+	if len(events) != 1 {
+		t.Fatalf("expected exactly one event, got %d", len(events))
+	}
+	if _, ok := (<-events).(CalibrationMode); !ok {
+		t.Fatalf("expected CalibrationMode on long press")
+	}
+
+	// Long press without autorepeat is detected on release.
+	d.handle(key(evValuePress, 20))
+	d.handle(key(evValueRelease, 23))
+	if _, ok := (<-events).(CalibrationMode); !ok {
+		t.Fatalf("expected CalibrationMode on long release")
+	}
+}
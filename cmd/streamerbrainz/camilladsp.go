@@ -1,10 +1,16 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"math"
 	"net/url"
+	"strconv"
 	"sync"
 	"time"
 
@@ -13,18 +19,26 @@ import (
 
 // CamillaDSPClientInterface defines the interface for CamillaDSP client operations
 // This allows for mocking in tests
+// Every call takes a ctx: canceling it aborts an in-flight read at once, and its
+// deadline (if sooner) replaces the command's own (see camilladsp_deadlines.go).
 type CamillaDSPClientInterface interface {
-	SetVolume(targetDB float64) (float64, error)
-	GetVolume() (float64, error)
+	SetVolume(ctx context.Context, targetDB float64) (float64, error)
+	GetVolume(ctx context.Context) (float64, error)
 
 	// Mute control (default fader/control "Main")
-	GetMute() (bool, error)
-	SetMute(mute bool) error
-	ToggleMute() (bool, error) // returns new mute state
+	GetMute(ctx context.Context) (bool, error)
+	SetMute(ctx context.Context, mute bool) error
 
 	// State/config helpers for initial daemon sync
-	GetConfigFilePath() (string, error)
-	GetState() (string, error)
+	GetConfigFilePath(ctx context.Context) (string, error)
+	GetState(ctx context.Context) (string, error)
+
+	// Aux faders (balance/sub encoder modes)
+	SetFaderVolume(ctx context.Context, fader int, targetDB float64) error
+
+	// Config switching (output select)
+	SetConfigFilePath(ctx context.Context, path string) error
+	Reload(ctx context.Context) error
 
 	Close() error
 }
@@ -34,33 +48,86 @@ type CamillaDSPClient struct {
 	mu          sync.Mutex
 	conn        *websocket.Conn
 	url         string
+	dial        CamillaDSPDialOptions
+	reconnect   camillaReconnect // see camilladsp_reconnect.go
 	logger      *slog.Logger
-	readTimeout time.Duration
+	readTimeout time.Duration // default read deadline (camilladsp.timeout_ms)
+
+	// timeouts overrides the read deadline per command (config:
+	// camilladsp.command_timeouts_ms; see camilladsp_deadlines.go).
+	timeouts map[string]time.Duration
+
+	// backgroundDial is set while the deferred startup connect runs (guarded by
+	// mu; see camilladsp_startup.go).
+	backgroundDial bool
+
+	// lastReply is when the connection last answered (guarded by mu; see
+	// camilladsp_keepalive.go).
+	lastReply time.Time
+
+	// Reused SetVolume request/response buffers (guarded by mu) so volume ramps
+	// don't allocate a JSON frame per step.
+	volFrame []byte
+	volResp  []byte
+
+	// pipelining lets the effects worker send several queued commands back to back
+	// and read the responses afterwards (config: camilladsp.pipeline).
+	pipelining bool
+
+	// monitor is the second connection used for meter polling (config:
+	// camilladsp.monitor_hz; nil if disabled). See camilladsp_monitor.go.
+	monitor *CamillaDSPClient
+
+	// tap, when on, records this connection's frames under tapZone (see
+	// camilladsp_tap.go; nil if not wired).
+	tap     *camillaTap
+	tapZone string
+
+	// version is the attached CamillaDSP's version (nil if unknown), detected on
+	// every connect; requires lists the settings that need gated features (see
+	// camilladsp_version.go). Both guarded by mu.
+	version  *camillaVersion
+	requires []camillaRequirement
 }
 
 // NewCamillaDSPClient creates a new CamillaDSP client and establishes initial connection
 func NewCamillaDSPClient(wsURL string, logger *slog.Logger, readTimeout int) (*CamillaDSPClient, error) {
-	// Validate URL
-	if _, err := url.Parse(wsURL); err != nil {
-		return nil, fmt.Errorf("invalid websocket URL: %w", err)
-	}
+	return NewCamillaDSPClientWithOptions(wsURL, CamillaDSPDialOptions{}, logger, readTimeout)
+}
 
-	client := &CamillaDSPClient{
-		url:         wsURL,
-		logger:      logger,
-		readTimeout: time.Duration(readTimeout) * time.Millisecond,
+// NewCamillaDSPClientWithOptions is NewCamillaDSPClient with TLS/header dial options
+// (see camilladsp_dial.go).
+func NewCamillaDSPClientWithOptions(wsURL string, dial CamillaDSPDialOptions, logger *slog.Logger, readTimeout int) (*CamillaDSPClient, error) {
+	client, err := newCamillaDSPClient(wsURL, dial, logger, readTimeout)
+	if err != nil {
+		return nil, err
 	}
 
 	// Establish initial connection with retry
-	if err := client.connectWithRetry(); err != nil {
+	if err := client.connectWithRetry(context.Background(), client.reconnect.forever); err != nil {
 		return nil, err
 	}
 
 	return client, nil
 }
 
+// newCamillaDSPClient creates a client without connecting it.
+func newCamillaDSPClient(wsURL string, dial CamillaDSPDialOptions, logger *slog.Logger, readTimeout int) (*CamillaDSPClient, error) {
+	// Validate URL
+	if _, err := url.Parse(wsURL); err != nil {
+		return nil, fmt.Errorf("invalid websocket URL: %w", err)
+	}
+	return &CamillaDSPClient{
+		url:         wsURL,
+		dial:        dial,
+		reconnect:   dial.Reconnect,
+		logger:      logger,
+		readTimeout: time.Duration(readTimeout) * time.Millisecond,
+	}, nil
+}
+
 // connect establishes a WebSocket connection to CamillaDSP
-func (c *CamillaDSPClient) connect() error {
+func (c *CamillaDSPClient) connect(ctx context.Context) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -76,49 +143,47 @@ func (c *CamillaDSPClient) connect() error {
 
 	d := websocket.Dialer{
 		HandshakeTimeout: 2 * time.Second,
+		TLSClientConfig:  c.dial.TLSConfig,
 	}
 
-	conn, _, err := d.Dial(u.String(), nil)
+	conn, _, err := d.DialContext(ctx, u.String(), c.dial.Header)
 	if err != nil {
 		return err
 	}
 
 	c.conn = conn
+	c.lastReply = time.Now()
 	return nil
 }
 
-// connectWithRetry attempts to connect with exponential backoff
-func (c *CamillaDSPClient) connectWithRetry() error {
-	var lastErr error
-	for attempt := 0; attempt < 10; attempt++ {
-		err := c.connect()
-		if err == nil {
-			c.logger.Info("connected to CamillaDSP", "url", c.url)
-			return nil
-		}
-		lastErr = err
-		c.logger.Warn("connection failed; retrying...", "error", err, "attempt", attempt+1)
-		time.Sleep(500 * time.Millisecond)
-	}
-	return fmt.Errorf("failed to connect after 10 attempts: %w", lastErr)
+// connected reports whether c currently has a live connection.
+func (c *CamillaDSPClient) connected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn != nil
 }
 
 // ensureConnected checks connection and reconnects if necessary
-func (c *CamillaDSPClient) ensureConnected() error {
+func (c *CamillaDSPClient) ensureConnected(ctx context.Context) error {
 	c.mu.Lock()
 	if c.conn != nil {
 		c.mu.Unlock()
 		return nil
 	}
+	if c.backgroundDial {
+		// The startup connect is still retrying (see camilladsp_startup.go).
+		c.mu.Unlock()
+		return errCamillaNotConnected
+	}
 	c.mu.Unlock()
 
 	c.logger.Warn("connection lost; reconnecting...")
-	return c.connectWithRetry()
+	return c.connectWithRetry(ctx, false)
 }
 
 // send sends a message to CamillaDSP (one-way, no response expected)
-func (c *CamillaDSPClient) send(v any) error {
-	if err := c.ensureConnected(); err != nil {
+func (c *CamillaDSPClient) send(ctx context.Context, v any) error {
+	if err := c.ensureConnected(ctx); err != nil {
 		return err
 	}
 
@@ -126,7 +191,7 @@ func (c *CamillaDSPClient) send(v any) error {
 	defer c.mu.Unlock()
 
 	if c.conn == nil {
-		return fmt.Errorf("no websocket connection")
+		return errCamillaNotConnected
 	}
 
 	payload, err := json.Marshal(v)
@@ -134,6 +199,7 @@ func (c *CamillaDSPClient) send(v any) error {
 		return fmt.Errorf("marshal command: %w", err)
 	}
 
+	c.tap.record(c.tapZone, tapOut, payload)
 	if err := c.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
 		c.conn = nil // Mark connection as broken
 		return err
@@ -142,44 +208,212 @@ func (c *CamillaDSPClient) send(v any) error {
 	return nil
 }
 
-// sendAndRead sends a message and waits for a response
-func (c *CamillaDSPClient) sendAndRead(v any, timeout time.Duration) ([]byte, error) {
-	if err := c.ensureConnected(); err != nil {
+// sendAndRead sends a message and waits for a response, within the read
+// deadline of its command.
+func (c *CamillaDSPClient) sendAndRead(ctx context.Context, v any) ([]byte, error) {
+	if err := c.ensureConnected(ctx); err != nil {
 		return nil, err
 	}
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if c.conn == nil {
-		return nil, fmt.Errorf("no websocket connection")
-	}
-
 	payload, err := json.Marshal(v)
 	if err != nil {
 		return nil, fmt.Errorf("marshal command: %w", err)
 	}
 
+	return c.roundTripLocked(ctx, payload, nil, c.timeoutFor(camillaRequestName(v)))
+}
+
+// roundTripLocked writes payload and reads one response message, appending it to dst.
+// c.mu must be held.
+func (c *CamillaDSPClient) roundTripLocked(ctx context.Context, payload, dst []byte, timeout time.Duration) (_ []byte, err error) {
+	if c.conn == nil {
+		return nil, errCamillaNotConnected
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	defer c.watchCtxLocked(ctx, &err)()
+
+	c.tap.record(c.tapZone, tapOut, payload)
 	if err := c.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
 		c.conn = nil // Mark connection as broken
 		return nil, err
 	}
 
-	// Set read deadline
-	c.conn.SetReadDeadline(time.Now().Add(timeout))
-	defer c.conn.SetReadDeadline(time.Time{})
+	c.conn.SetReadDeadline(readDeadline(ctx, timeout))
+	defer c.resetDeadlineLocked()
 
-	_, message, err := c.conn.ReadMessage()
+	_, r, err := c.conn.NextReader()
 	if err != nil {
 		c.conn = nil // Mark connection as broken
 		return nil, err
 	}
+	start := len(dst)
+	for {
+		if len(dst) == cap(dst) {
+			dst = append(dst, 0)[:len(dst)]
+		}
+		n, err := r.Read(dst[len(dst):cap(dst)])
+		dst = dst[:len(dst)+n]
+		if errors.Is(err, io.EOF) {
+			c.tap.record(c.tapZone, tapIn, dst[start:])
+			c.lastReply = time.Now()
+			return dst, nil
+		}
+		if err != nil {
+			c.conn = nil // Mark connection as broken
+			return nil, err
+		}
+	}
+}
+
+// Call sends an arbitrary request and returns the raw response (reconnecting if
+// needed), for tools that speak CamillaDSP commands directly (`streamerbrainz dsp`).
+func (c *CamillaDSPClient) Call(ctx context.Context, request any) ([]byte, error) {
+	return c.sendAndRead(ctx, request)
+}
+
+// Pipelining reports whether camilladsp.pipeline is enabled for this client.
+func (c *CamillaDSPClient) Pipelining() bool {
+	return c.pipelining
+}
+
+// Pipeline writes all requests back to back and then reads one response per request.
+// CamillaDSP handles the messages of a connection in order, so responses line up with
+// requests; this saves a network round trip per extra command when the DSP is remote.
+// CamillaDSP has no multi-command frame, so each request is still its own WS message.
+//
+// It returns the responses received so far; on error len(responses) < len(requests).
+func (c *CamillaDSPClient) Pipeline(ctx context.Context, requests []any) ([][]byte, error) {
+	if err := c.ensureConnected(ctx); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pipelineLocked(ctx, requests)
+}
+
+// pipelineLocked is Pipeline without reconnecting. c.mu must be held.
+func (c *CamillaDSPClient) pipelineLocked(ctx context.Context, requests []any) (responses [][]byte, err error) {
+	if c.conn == nil {
+		return nil, errCamillaNotConnected
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	defer c.watchCtxLocked(ctx, &err)()
+
+	for _, req := range requests {
+		payload, err := json.Marshal(req)
+		if err != nil {
+			return nil, fmt.Errorf("marshal command: %w", err)
+		}
+		c.tap.record(c.tapZone, tapOut, payload)
+		if err := c.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+			c.conn = nil // Mark connection as broken
+			return nil, err
+		}
+	}
+
+	// One deadline per response keeps the per-command timeout semantics.
+	defer c.resetDeadlineLocked()
+	responses = make([][]byte, 0, len(requests))
+	for _, req := range requests {
+		c.conn.SetReadDeadline(readDeadline(ctx, c.timeoutFor(camillaRequestName(req))))
+		_, message, err := c.conn.ReadMessage()
+		if err != nil {
+			c.conn = nil // Mark connection as broken
+			return responses, err
+		}
+		c.tap.record(c.tapZone, tapIn, message)
+		c.lastReply = time.Now()
+		responses = append(responses, message)
+	}
+	return responses, nil
+}
+
+// camillaReply is the common shape of CamillaDSP responses: {"<Command>": {"result", "value"}}.
+type camillaReply[T any] struct {
+	Result string `json:"result"`
+	Value  T      `json:"value"`
+}
+
+// parseCamillaReply decodes the response to command name. A result other than
+// "Ok" is returned as camillaResultError before the value is decoded (error
+// replies carry a message instead).
+func parseCamillaReply[T any](resp []byte, name string) (camillaReply[T], error) {
+	var m map[string]camillaReply[json.RawMessage]
+	if err := json.Unmarshal(resp, &m); err != nil {
+		return camillaReply[T]{}, fmt.Errorf("parse %s response: %w", name, err)
+	}
+	raw, ok := m[name]
+	if !ok {
+		return camillaReply[T]{}, fmt.Errorf("parse %s response: %w %.80s", name, errCamillaProtocol, resp)
+	}
+	if checkCamillaResult(name, raw.Result) != nil {
+		return camillaReply[T]{}, camillaResultError{Command: name, Result: raw.Result, Detail: replyDetail(raw.Value)}
+	}
+	r := camillaReply[T]{Result: raw.Result}
+	if len(raw.Value) > 0 {
+		if err := json.Unmarshal(raw.Value, &r.Value); err != nil {
+			return camillaReply[T]{}, fmt.Errorf("parse %s response: %w", name, err)
+		}
+	}
+	return r, nil
+}
+
+// replyDetail renders the value of an error reply (a JSON string is unquoted).
+func replyDetail(v json.RawMessage) string {
+	var s string
+	if json.Unmarshal(v, &s) == nil {
+		return s
+	}
+	return string(v)
+}
+
+// appendSetVolumeFrame appends the JSON command {"SetVolume":<db>} to b.
+func appendSetVolumeFrame(b []byte, db float64) []byte {
+	b = append(b, `{"SetVolume":`...)
+	b = strconv.AppendFloat(b, db, 'f', -1, 64)
+	return append(b, '}')
+}
+
+// checkCamillaReply returns an error unless resp is a successful reply to
+// command: camillaResultError for a result other than "Ok", a protocol error
+// for a reply that can't be parsed.
+func checkCamillaReply(command string, resp []byte) error {
+	if string(responseResult(resp)) == "Ok" {
+		return nil // the common case, without decoding
+	}
+	_, err := parseCamillaReply[json.RawMessage](resp, command)
+	return err
+}
 
-	return message, nil
+// responseResult returns the "result" value of a CamillaDSP response without decoding it
+// (e.g. `Ok` from {"SetVolume":{"result":"Ok"}}). It returns nil if absent.
+func responseResult(resp []byte) []byte {
+	const key = `"result":"`
+	i := bytes.Index(resp, []byte(key))
+	if i < 0 {
+		return nil
+	}
+	rest := resp[i+len(key):]
+	j := bytes.IndexByte(rest, '"')
+	if j < 0 {
+		return nil
+	}
+	return rest[:j]
 }
 
-// Close closes the WebSocket connection
+// Close closes the WebSocket connection (and the monitoring one, if open)
 func (c *CamillaDSPClient) Close() error {
+	if c.monitor != nil {
+		c.monitor.Close()
+	}
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	if c.conn != nil {
@@ -189,182 +423,194 @@ func (c *CamillaDSPClient) Close() error {
 	return nil
 }
 
-// SetVolume sends a SetVolume command to CamillaDSP and returns the target volume
-func (c *CamillaDSPClient) SetVolume(targetDB float64) (float64, error) {
-	cmd := map[string]any{"SetVolume": targetDB}
+// SetVolume sends a SetVolume command to CamillaDSP and returns the target volume.
+// It is on the ramp hot path, so the frame is built by hand into a reused buffer
+// and the response is scanned rather than decoded.
+func (c *CamillaDSPClient) SetVolume(ctx context.Context, targetDB float64) (float64, error) {
+	if math.IsNaN(targetDB) || math.IsInf(targetDB, 0) {
+		return 0, fmt.Errorf("set volume: invalid target %v", targetDB)
+	}
+	if err := c.ensureConnected(ctx); err != nil {
+		return 0, fmt.Errorf("set volume: %w", err)
+	}
 
-	response, err := c.sendAndRead(cmd, c.readTimeout)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.volFrame = appendSetVolumeFrame(c.volFrame[:0], targetDB)
+	response, err := c.roundTripLocked(ctx, c.volFrame, c.volResp[:0], c.timeoutFor("SetVolume"))
 	if err != nil {
 		return 0, fmt.Errorf("set volume: %w", err)
 	}
+	c.volResp = response
 
-	var setResp struct {
-		SetVolume struct {
-			Result string `json:"result"`
-		} `json:"SetVolume"`
+	// Only pay for the log record (and its boxed attrs) when debug logging is on.
+	if c.logger.Enabled(context.Background(), slog.LevelDebug) {
+		c.logger.Debug("SetVolume", "target_db", targetDB, "result", string(responseResult(response)))
 	}
-
-	if err := json.Unmarshal(response, &setResp); err != nil {
-		c.logger.Warn("failed to parse SetVolume response", "error", err)
-		return targetDB, nil // Assume success
+	if err := checkCamillaReply("SetVolume", response); err != nil {
+		return 0, err
 	}
 
-	c.logger.Debug("SetVolume", "target_db", targetDB, "result", setResp.SetVolume.Result)
-
 	return targetDB, nil
 }
 
 // GetVolume queries CamillaDSP for the current volume
-func (c *CamillaDSPClient) GetVolume() (float64, error) {
+func (c *CamillaDSPClient) GetVolume(ctx context.Context) (float64, error) {
 	cmd := "GetVolume"
 
-	response, err := c.sendAndRead(cmd, c.readTimeout)
+	response, err := c.sendAndRead(ctx, cmd)
 	if err != nil {
 		return 0, fmt.Errorf("get volume: %w", err)
 	}
 
-	var volResp struct {
-		GetVolume struct {
-			Result string  `json:"result"`
-			Value  float64 `json:"value"`
-		} `json:"GetVolume"`
-	}
-
-	if err := json.Unmarshal(response, &volResp); err != nil {
+	r, err := parseCamillaReply[float64](response, "GetVolume")
+	if err != nil {
 		c.logger.Warn("failed to parse GetVolume response", "error", err)
 		return 0, err
 	}
 
-	c.logger.Debug("GetVolume", "volume_db", volResp.GetVolume.Value)
+	c.logger.Debug("GetVolume", "volume_db", r.Value)
 
-	return volResp.GetVolume.Value, nil
+	return r.Value, nil
 }
 
 // GetMute queries CamillaDSP for the current mute state.
-func (c *CamillaDSPClient) GetMute() (bool, error) {
+func (c *CamillaDSPClient) GetMute(ctx context.Context) (bool, error) {
 	cmd := "GetMute"
 
-	response, err := c.sendAndRead(cmd, c.readTimeout)
+	response, err := c.sendAndRead(ctx, cmd)
 	if err != nil {
 		return false, fmt.Errorf("get mute: %w", err)
 	}
 
-	var muteResp struct {
-		GetMute struct {
-			Result string `json:"result"`
-			Value  bool   `json:"value"`
-		} `json:"GetMute"`
-	}
-
-	if err := json.Unmarshal(response, &muteResp); err != nil {
+	r, err := parseCamillaReply[bool](response, "GetMute")
+	if err != nil {
 		c.logger.Warn("failed to parse GetMute response", "error", err)
 		return false, err
 	}
 
-	c.logger.Debug("GetMute", "mute", muteResp.GetMute.Value)
+	c.logger.Debug("GetMute", "mute", r.Value)
 
-	return muteResp.GetMute.Value, nil
+	return r.Value, nil
 }
 
 // SetMute sets the mute state in CamillaDSP.
-func (c *CamillaDSPClient) SetMute(mute bool) error {
+func (c *CamillaDSPClient) SetMute(ctx context.Context, mute bool) error {
 	cmd := map[string]any{"SetMute": mute}
 
-	response, err := c.sendAndRead(cmd, c.readTimeout)
+	response, err := c.sendAndRead(ctx, cmd)
 	if err != nil {
 		return fmt.Errorf("set mute: %w", err)
 	}
 
-	var setResp struct {
-		SetMute struct {
-			Result string `json:"result"`
-		} `json:"SetMute"`
-	}
-
-	if err := json.Unmarshal(response, &setResp); err != nil {
-		c.logger.Warn("failed to parse SetMute response", "error", err)
-		return nil // Assume success if we can't parse the response
+	if err := checkCamillaReply("SetMute", response); err != nil {
+		c.logger.Warn("camilladsp rejected SetMute", "mute", mute, "error", err)
+		return err
 	}
 
-	c.logger.Debug("SetMute", "mute", mute, "result", setResp.SetMute.Result)
+	c.logger.Debug("SetMute", "mute", mute)
 
 	return nil
 }
 
-// ToggleMute sends a ToggleMute command to CamillaDSP and returns the new mute state.
-func (c *CamillaDSPClient) ToggleMute() (bool, error) {
-	cmd := "ToggleMute"
-
-	response, err := c.sendAndRead(cmd, c.readTimeout)
-	if err != nil {
-		return false, fmt.Errorf("toggle mute: %w", err)
+// SetFaderVolume sets the volume of the given fader (0 = Main, 1-4 = Aux1-4).
+// It returns errCamillaUnsupported, sending nothing, on CamillaDSP < 2.0.
+func (c *CamillaDSPClient) SetFaderVolume(ctx context.Context, fader int, targetDB float64) error {
+	if err := c.unsupported(camillaFaders); err != nil {
+		return err
 	}
+	cmd := map[string]any{"SetFaderVolume": []any{fader, targetDB}}
 
-	var toggleResp struct {
-		ToggleMute struct {
-			Result string `json:"result"`
-			Value  bool   `json:"value"`
-		} `json:"ToggleMute"`
+	response, err := c.sendAndRead(ctx, cmd)
+	if err != nil {
+		return fmt.Errorf("set fader volume: %w", err)
 	}
 
-	if err := json.Unmarshal(response, &toggleResp); err != nil {
-		c.logger.Warn("failed to parse ToggleMute response", "error", err)
-		return false, err
+	if err := checkCamillaReply("SetFaderVolume", response); err != nil {
+		c.logger.Warn("camilladsp rejected SetFaderVolume", "fader", fader, "target_db", targetDB, "error", err)
+		return err
 	}
 
-	c.logger.Debug("ToggleMute", "mute", toggleResp.ToggleMute.Value, "result", toggleResp.ToggleMute.Result)
+	c.logger.Debug("SetFaderVolume", "fader", fader, "target_db", targetDB)
 
-	return toggleResp.ToggleMute.Value, nil
+	return nil
 }
 
 // GetConfigFilePath queries CamillaDSP for the currently active config file path.
-func (c *CamillaDSPClient) GetConfigFilePath() (string, error) {
+func (c *CamillaDSPClient) GetConfigFilePath(ctx context.Context) (string, error) {
 	cmd := "GetConfigFilePath"
 
-	response, err := c.sendAndRead(cmd, c.readTimeout)
+	response, err := c.sendAndRead(ctx, cmd)
 	if err != nil {
 		return "", fmt.Errorf("get config file path: %w", err)
 	}
 
-	var resp struct {
-		GetConfigFilePath struct {
-			Result string `json:"result"`
-			Value  string `json:"value"`
-		} `json:"GetConfigFilePath"`
-	}
-
-	if err := json.Unmarshal(response, &resp); err != nil {
+	r, err := parseCamillaReply[string](response, "GetConfigFilePath")
+	if err != nil {
 		c.logger.Warn("failed to parse GetConfigFilePath response", "error", err)
 		return "", err
 	}
 
-	c.logger.Debug("GetConfigFilePath", "path", resp.GetConfigFilePath.Value, "result", resp.GetConfigFilePath.Result)
+	c.logger.Debug("GetConfigFilePath", "path", r.Value)
 
-	return resp.GetConfigFilePath.Value, nil
+	return r.Value, nil
 }
 
 // GetState queries CamillaDSP for the current processing state ("Running", "Paused", etc.).
-func (c *CamillaDSPClient) GetState() (string, error) {
+func (c *CamillaDSPClient) GetState(ctx context.Context) (string, error) {
 	cmd := "GetState"
 
-	response, err := c.sendAndRead(cmd, c.readTimeout)
+	response, err := c.sendAndRead(ctx, cmd)
 	if err != nil {
 		return "", fmt.Errorf("get state: %w", err)
 	}
 
-	var resp struct {
-		GetState struct {
-			Result string `json:"result"`
-			Value  string `json:"value"`
-		} `json:"GetState"`
-	}
-
-	if err := json.Unmarshal(response, &resp); err != nil {
+	r, err := parseCamillaReply[string](response, "GetState")
+	if err != nil {
 		c.logger.Warn("failed to parse GetState response", "error", err)
 		return "", err
 	}
 
-	c.logger.Debug("GetState", "state", resp.GetState.Value, "result", resp.GetState.Result)
+	c.logger.Debug("GetState", "state", r.Value)
+
+	return r.Value, nil
+}
+
+// SetConfigFilePath changes the config file path CamillaDSP loads on the next Reload.
+func (c *CamillaDSPClient) SetConfigFilePath(ctx context.Context, path string) error {
+	cmd := map[string]any{"SetConfigFilePath": path}
+
+	response, err := c.sendAndRead(ctx, cmd)
+	if err != nil {
+		return fmt.Errorf("set config file path: %w", err)
+	}
+
+	if err := checkCamillaReply("SetConfigFilePath", response); err != nil {
+		c.logger.Warn("camilladsp rejected SetConfigFilePath", "path", path, "error", err)
+		return err
+	}
+
+	c.logger.Debug("SetConfigFilePath", "path", path)
+
+	return nil
+}
+
+// Reload asks CamillaDSP to reload its config file.
+func (c *CamillaDSPClient) Reload(ctx context.Context) error {
+	cmd := "Reload"
 
-	return resp.GetState.Value, nil
+	response, err := c.sendAndRead(ctx, cmd)
+	if err != nil {
+		return fmt.Errorf("reload: %w", err)
+	}
+
+	if err := checkCamillaReply("Reload", response); err != nil {
+		c.logger.Warn("camilladsp rejected Reload", "error", err)
+		return err
+	}
+
+	c.logger.Debug("Reload")
+
+	return nil
 }
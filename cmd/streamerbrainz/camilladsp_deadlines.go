@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// ============================================================================
+// CamillaDSP client: contexts and per-command read deadlines
+// ============================================================================
+// Every client call takes a ctx. Canceling it (the daemon's ctx on shutdown)
+// makes an in-flight read return at once instead of running into its
+// deadline; the connection is then dropped, since the late reply would answer
+// the next request. A ctx deadline sooner than the command's own wins.
+//
+// Each command gets its own read deadline:
+//
+//   - camilladsp.command_timeouts_ms sets it by command name.
+//   - Otherwise SetVolume and SetFaderVolume (ramp and hold steps, superseded
+//     by the next one) wait at most camillaShortTimeout, and commands that load,
+//     serialize or validate a whole config, or enumerate devices, at least
+//     camillaLongTimeout.
+//   - Everything else uses camilladsp.timeout_ms.
+// ============================================================================
+
+const (
+	camillaShortTimeout = 250 * time.Millisecond
+	camillaLongTimeout  = 5 * time.Second
+)
+
+// camillaShortCommands / camillaLongCommands name the commands with built-in
+// deadlines (see above).
+var (
+	camillaShortCommands = map[string]bool{
+		"SetVolume":      true,
+		"SetFaderVolume": true,
+	}
+	camillaLongCommands = map[string]bool{
+		"Reload":                      true,
+		"GetConfig":                   true,
+		"GetConfigJson":               true,
+		"GetConfigYaml":               true,
+		"GetPreviousConfig":           true,
+		"SetConfig":                   true,
+		"SetConfigJson":               true,
+		"ValidateConfig":              true,
+		"GetAvailableCaptureDevices":  true,
+		"GetAvailablePlaybackDevices": true,
+	}
+)
+
+// camillaRequestName returns the command name of a CamillaDSP request ("GetVolume",
+// or the key of {"SetVolume": -20}); empty if it has none.
+func camillaRequestName(req any) string {
+	switch r := req.(type) {
+	case string:
+		return r
+	case map[string]any:
+		for name := range r {
+			return name
+		}
+	}
+	return ""
+}
+
+// commandTimeouts converts camilladsp.command_timeouts_ms (nil if unset).
+func (c CamillaDSPConfig) commandTimeouts() map[string]time.Duration {
+	if len(c.CommandTimeoutsMS) == 0 {
+		return nil
+	}
+	out := make(map[string]time.Duration, len(c.CommandTimeoutsMS))
+	for name, ms := range c.CommandTimeoutsMS {
+		out[name] = time.Duration(ms) * time.Millisecond
+	}
+	return out
+}
+
+// timeoutFor returns the read deadline of command name.
+func (c *CamillaDSPClient) timeoutFor(name string) time.Duration {
+	if d, ok := c.timeouts[name]; ok {
+		return d
+	}
+	switch {
+	case camillaShortCommands[name]:
+		return min(c.readTimeout, camillaShortTimeout)
+	case camillaLongCommands[name]:
+		return max(c.readTimeout, camillaLongTimeout)
+	}
+	return c.readTimeout
+}
+
+// readDeadline is now + timeout, or ctx's deadline if sooner.
+func readDeadline(ctx context.Context, timeout time.Duration) time.Time {
+	d := time.Now().Add(timeout)
+	if dl, ok := ctx.Deadline(); ok && dl.Before(d) {
+		return dl
+	}
+	return d
+}
+
+// watchCtxLocked makes reads on c.conn return once ctx is canceled, by moving
+// their deadline to now. The returned func ends the watch; if ctx was canceled
+// meanwhile it drops the connection and replaces *err with ctx's error (when
+// set). c.mu must be held throughout.
+func (c *CamillaDSPClient) watchCtxLocked(ctx context.Context, err *error) func() {
+	if ctx.Done() == nil {
+		return func() {}
+	}
+	conn := c.conn
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	return func() {
+		if stop() {
+			return
+		}
+		// The deadline was (or is about to be) moved: the connection can't be trusted.
+		conn.Close()
+		if c.conn == conn {
+			c.conn = nil
+		}
+		if *err != nil {
+			*err = ctx.Err()
+		}
+	}
+}
+
+// resetDeadlineLocked clears the read deadline, if still connected. c.mu must
+// be held.
+func (c *CamillaDSPClient) resetDeadlineLocked() {
+	if c.conn != nil {
+		c.conn.SetReadDeadline(time.Time{})
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newSilentCamillaDSP answers GetVersion only, leaving every other request
+// unanswered.
+func newSilentCamillaDSP(t *testing.T) string {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if string(msg) == `"GetVersion"` {
+				_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"GetVersion":{"result":"Ok","value":"2.0.3"}}`))
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func TestCamillaDSPClient_TimeoutFor(t *testing.T) {
+	c := &CamillaDSPClient{readTimeout: time.Second}
+	for name, want := range map[string]time.Duration{
+		"SetVolume":     camillaShortTimeout,
+		"GetVolume":     time.Second,
+		"GetConfigJson": camillaLongTimeout,
+		"":              time.Second,
+	} {
+		if got := c.timeoutFor(name); got != want {
+			t.Errorf("timeoutFor(%q) = %v, want %v", name, got, want)
+		}
+	}
+
+	// Short deadlines never exceed timeout_ms; overrides win.
+	c = &CamillaDSPClient{readTimeout: 100 * time.Millisecond}
+	c.timeouts = CamillaDSPConfig{CommandTimeoutsMS: map[string]int{"GetVolume": 2000}}.commandTimeouts()
+	if got := c.timeoutFor("SetVolume"); got != 100*time.Millisecond {
+		t.Errorf("SetVolume = %v", got)
+	}
+	if got := c.timeoutFor("GetVolume"); got != 2*time.Second {
+		t.Errorf("GetVolume override = %v", got)
+	}
+}
+
+func TestCamillaDSPClient_CancelAbortsRead(t *testing.T) {
+	client, err := NewCamillaDSPClient(newSilentCamillaDSP(t), slog.New(slog.DiscardHandler), 10000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	_, err = client.GetVolume(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Fatalf("cancel took %v", d)
+	}
+	if client.connected() {
+		t.Fatal("connection kept after an abandoned read")
+	}
+}
+
+func TestCamillaDSPClient_PerCommandDeadline(t *testing.T) {
+	client, err := NewCamillaDSPClient(newSilentCamillaDSP(t), slog.New(slog.DiscardHandler), 10000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	start := time.Now()
+	_, err = client.SetVolume(t.Context(), -20)
+	if d := time.Since(start); d > 2*time.Second {
+		t.Fatalf("SetVolume waited %v (timeout_ms is 10 s)", d)
+	}
+	if classifyCamillaError(err) != camillaErrTimeout {
+		t.Fatalf("err = %v, want a timeout", err)
+	}
+}
+
+func TestValidateCamillaDSP_CommandTimeouts(t *testing.T) {
+	c := DefaultConfig().CamillaDSP
+	c.CommandTimeoutsMS = map[string]int{"GetConfigJson": 0}
+	if err := validateCamillaDSP("camilladsp", c); err == nil {
+		t.Fatal("zero command timeout accepted")
+	}
+	c.CommandTimeoutsMS["GetConfigJson"] = 5000
+	if err := validateCamillaDSP("camilladsp", c); err != nil {
+		t.Fatal(err)
+	}
+}
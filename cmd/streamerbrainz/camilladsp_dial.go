@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// ============================================================================
+// CamillaDSP dial options (TLS, auth headers)
+// ============================================================================
+// CamillaDSP itself only speaks plain ws://, but its port is sometimes put
+// behind a TLS-terminating proxy (nginx, caddy) that may also want basic auth.
+// camilladsp.ws_url may then be wss://, with:
+//
+//   - ca_file: a PEM bundle trusted in addition to the system roots (private CA)
+//   - insecure_skip_verify: accept any certificate (self-signed, testing only)
+//   - username/password_file: HTTP basic auth on the WebSocket upgrade request
+//     (password_file is a secret source, see secrets.go)
+//   - headers: any other upgrade request headers (e.g. a proxy token)
+//
+// Zones inherit these from the top-level camilladsp block. Both the control
+// and the monitoring connection dial with them.
+// ============================================================================
+
+// CamillaDSPDialOptions configures how a CamillaDSPClient dials.
+type CamillaDSPDialOptions struct {
+	TLSConfig *tls.Config // nil uses the defaults (system roots)
+	Header    http.Header // sent with the upgrade request
+
+	// Reconnect paces connect attempts (see camilladsp_reconnect.go).
+	Reconnect camillaReconnect
+}
+
+// dialOptions builds the dial options for c, reading ca_file and password_file.
+func (c CamillaDSPConfig) dialOptions() (CamillaDSPDialOptions, error) {
+	opts := CamillaDSPDialOptions{Reconnect: c.reconnectPolicy()}
+	if c.CAFile != "" || c.InsecureSkipVerify {
+		tlsCfg := &tls.Config{InsecureSkipVerify: c.InsecureSkipVerify}
+		if c.CAFile != "" {
+			pem, err := os.ReadFile(c.CAFile)
+			if err != nil {
+				return opts, fmt.Errorf("read ca_file: %w", err)
+			}
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return opts, fmt.Errorf("ca_file %s: no PEM certificates found", c.CAFile)
+			}
+			tlsCfg.RootCAs = pool
+		}
+		opts.TLSConfig = tlsCfg
+	}
+
+	if len(c.Headers) > 0 || c.Username != "" {
+		opts.Header = http.Header{}
+		for k, v := range c.Headers {
+			opts.Header.Set(k, v)
+		}
+		if c.Username != "" {
+			password := c.Password
+			if c.PasswordFile != "" {
+				var err error
+				if password, err = readSecret(c.PasswordFile); err != nil {
+					return opts, fmt.Errorf("read password_file: %w", err)
+				}
+			}
+			opts.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(c.Username+":"+password)))
+		}
+	}
+	return opts, nil
+}
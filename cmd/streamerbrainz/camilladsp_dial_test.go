@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/pem"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// newTLSCamillaDSP serves GetVolume over wss://, answering only upgrade requests
+// with the given Authorization header. It returns the server and a PEM file with
+// its certificate.
+func newTLSCamillaDSP(t *testing.T, wantAuth string) (*httptest.Server, string) {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != wantAuth {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+			_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"GetVolume":{"result":"Ok","value":-12}}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return srv, caFile
+}
+
+func TestCamillaDSPClient_DialsWSSWithCAAndBasicAuth(t *testing.T) {
+	srv, caFile := newTLSCamillaDSP(t, "Basic YWRtaW46czNjcmV0") // admin:s3cret
+	cfg := CamillaDSPConfig{
+		WsURL:    "wss" + strings.TrimPrefix(srv.URL, "https"),
+		CAFile:   caFile,
+		Username: "admin",
+		Password: "s3cret",
+		Headers:  map[string]string{"X-Proxy-Token": "abc"},
+	}
+	dial, err := cfg.dialOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := dial.Header.Get("X-Proxy-Token"); got != "abc" {
+		t.Fatalf("header = %q", got)
+	}
+
+	client, err := NewCamillaDSPClientWithOptions(cfg.WsURL, dial, slog.New(slog.DiscardHandler), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if v, err := client.GetVolume(t.Context()); err != nil || v != -12 {
+		t.Fatalf("GetVolume = %v, %v", v, err)
+	}
+}
+
+func TestCamillaDSPClient_WSSRejectsUnknownCAAndBadAuth(t *testing.T) {
+	srv, caFile := newTLSCamillaDSP(t, "Basic YWRtaW46czNjcmV0")
+	wsURL := "wss" + strings.TrimPrefix(srv.URL, "https")
+	logger := slog.New(slog.DiscardHandler)
+
+	// System roots only: the test server's certificate is not trusted.
+	client := &CamillaDSPClient{url: wsURL, logger: logger}
+	if err := client.connect(t.Context()); err == nil {
+		t.Fatal("connected without trusting the server certificate")
+	}
+
+	// Trusted, but without credentials.
+	dial, err := CamillaDSPConfig{CAFile: caFile}.dialOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = &CamillaDSPClient{url: wsURL, dial: dial, logger: logger}
+	if err := client.connect(t.Context()); err == nil {
+		t.Fatal("connected without basic auth")
+	}
+
+	t.Setenv("SB_TEST_DSP_PASSWORD", "s3cret")
+	dial, err = CamillaDSPConfig{InsecureSkipVerify: true, Username: "admin", PasswordFile: "env:SB_TEST_DSP_PASSWORD"}.dialOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = &CamillaDSPClient{url: wsURL, dial: dial, logger: logger}
+	if err := client.connect(t.Context()); err != nil {
+		t.Fatalf("insecure_skip_verify: %v", err)
+	}
+	client.Close()
+}
+
+func TestCamillaDSPConfig_DialOptionsBadCAFile(t *testing.T) {
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{notPEM, filepath.Join(t.TempDir(), "missing.pem")} {
+		if _, err := (CamillaDSPConfig{CAFile: path}).dialOptions(); err == nil {
+			t.Fatalf("ca_file %s accepted", path)
+		}
+	}
+}
+
+func TestCamillaDSPConfig_DialOptionsPasswordFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	dial, err := CamillaDSPConfig{Username: "admin", PasswordFile: path}.dialOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := dial.Header.Get("Authorization"); got != "Basic YWRtaW46czNjcmV0" {
+		t.Fatalf("Authorization = %q", got)
+	}
+	if _, err := (CamillaDSPConfig{Username: "admin", PasswordFile: "env:SB_TEST_UNSET_PASSWORD"}).dialOptions(); err == nil {
+		t.Fatal("unset env: password accepted")
+	}
+}
+
+func TestValidateCamillaDSP_TLSOptions(t *testing.T) {
+	base := DefaultConfig().CamillaDSP
+	for name, tc := range map[string]struct {
+		mutate func(*CamillaDSPConfig)
+		ok     bool
+	}{
+		"wss":                    {func(c *CamillaDSPConfig) { c.WsURL = "wss://dsp.lan/camilla" }, true},
+		"wss with ca_file":       {func(c *CamillaDSPConfig) { c.WsURL = "wss://dsp.lan"; c.CAFile = "/etc/ca.pem" }, true},
+		"http scheme":            {func(c *CamillaDSPConfig) { c.WsURL = "http://dsp.lan:1234" }, false},
+		"ca_file on ws":          {func(c *CamillaDSPConfig) { c.CAFile = "/etc/ca.pem" }, false},
+		"insecure on ws":         {func(c *CamillaDSPConfig) { c.InsecureSkipVerify = true }, false},
+		"password, no username":  {func(c *CamillaDSPConfig) { c.Password = "x" }, false},
+		"basic auth on plain ws": {func(c *CamillaDSPConfig) { c.Username = "u"; c.Password = "p" }, true},
+		"password_file":          {func(c *CamillaDSPConfig) { c.Username = "u"; c.PasswordFile = "credential:dsp" }, true},
+		"password_file, no user": {func(c *CamillaDSPConfig) { c.PasswordFile = "env:DSP_PASSWORD" }, false},
+		"both passwords":         {func(c *CamillaDSPConfig) { c.Username = "u"; c.Password = "p"; c.PasswordFile = "env:P" }, false},
+		"bad password_file ref":  {func(c *CamillaDSPConfig) { c.Username = "u"; c.PasswordFile = "env:" }, false},
+	} {
+		c := base
+		tc.mutate(&c)
+		if err := validateCamillaDSP("camilladsp", c); (err == nil) != tc.ok {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// CamillaDSP command failures: classification and retry policy
+// ============================================================================
+// Every CamillaCommandFailed is classified (CamillaCommandFailed.Class), and the
+// reducer reacts per class:
+//
+//   - timeout: no reply within the read timeout. A GetVolume is retried with
+//     backoff (camillaRetryBase, doubling, up to camillaMaxRetries times) so
+//     the volume is learned without waiting for the next probe.
+//   - connection_lost: not connected, or the connection broke (and anything
+//     unrecognized). A failed SetVolume stops the hold or ramp and drops the
+//     pending level: by the time CamillaDSP is back it would be stale. The
+//     reachability probe (GetVolume) reads the actual level back.
+//   - protocol: the reply couldn't be understood (see errCamillaProtocol).
+//     Broadcast as dsp_command_error and alerted (alerts.dsp_protocol_error).
+//   - dsp_error: CamillaDSP answered with a result other than "Ok"
+//     (camillaResultError). Broadcast as dsp_command_error.
+//
+// Timeouts and lost connections mark CamillaDSP unreachable; protocol and
+// dsp_error failures prove it is answering, so they don't.
+// ============================================================================
+
+// camillaErrorClass classifies a failed CamillaDSP command.
+type camillaErrorClass string
+
+const (
+	camillaErrTimeout    camillaErrorClass = "timeout"
+	camillaErrConnection camillaErrorClass = "connection_lost"
+	camillaErrProtocol   camillaErrorClass = "protocol"
+	camillaErrResult     camillaErrorClass = "dsp_error"
+)
+
+const (
+	camillaRetryBase  = 250 * time.Millisecond
+	camillaMaxRetries = 3
+)
+
+var (
+	// errCamillaNotConnected is returned when no control connection is open.
+	errCamillaNotConnected = errors.New("no websocket connection")
+
+	// errCamillaProtocol wraps replies that don't match the command sent.
+	errCamillaProtocol = errors.New("unexpected reply")
+)
+
+// camillaResultError is a reply whose result isn't "Ok".
+type camillaResultError struct {
+	Command string
+	Result  string
+	Detail  string // the reply's value, if any (usually a message)
+}
+
+func (e camillaResultError) Error() string {
+	if e.Detail != "" {
+		return fmt.Sprintf("%s: camilladsp returned %s: %s", e.Command, e.Result, e.Detail)
+	}
+	return fmt.Sprintf("%s: camilladsp returned %s", e.Command, e.Result)
+}
+
+// checkCamillaResult returns camillaResultError for a result other than "Ok"
+// (an absent result is accepted, as from older CamillaDSP versions).
+func checkCamillaResult(command, result string) error {
+	if result == "" || result == "Ok" {
+		return nil
+	}
+	return camillaResultError{Command: command, Result: result}
+}
+
+// classifyCamillaError returns the class of a command failure. Errors that
+// aren't recognized count as connection_lost: the command didn't get through.
+func classifyCamillaError(err error) camillaErrorClass {
+	var (
+		resultErr camillaResultError
+		netErr    net.Error
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &resultErr):
+		return camillaErrResult
+	case errors.Is(err, os.ErrDeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return camillaErrTimeout
+	case errors.Is(err, errCamillaProtocol), errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return camillaErrProtocol
+	}
+	return camillaErrConnection
+}
+
+// Class classifies the failure.
+func (e CamillaCommandFailed) Class() camillaErrorClass {
+	return classifyCamillaError(e.Err)
+}
+
+// BroadcastDSPCommandError reports a command CamillaDSP answered but didn't
+// carry out (protocol and dsp_error failures).
+type BroadcastDSPCommandError struct {
+	Command string
+	Class   camillaErrorClass
+	Error   string
+	At      time.Time
+}
+
+func (BroadcastDSPCommandError) stateBroadcastMarker() {}
+
+// camillaCommandName names cmd for broadcasts ("CmdSetVolume" -> "SetVolume").
+func camillaCommandName(cmd Command) string {
+	if req, ok := camillaRequestFor(cmd); ok {
+		return camillaRequestName(req)
+	}
+	return strings.TrimPrefix(fmt.Sprintf("%T", cmd), "main.Cmd")
+}
+
+// reduceCommandFailed applies the per-class policy to a failed command. It
+// reports whether the failure means CamillaDSP is unreachable.
+func reduceCommandFailed(s *DaemonState, ev CamillaCommandFailed) (broadcasts []StateBroadcast, unreachable bool) {
+	class := ev.Class()
+	switch class {
+	case camillaErrTimeout:
+		if _, ok := ev.Command.(CmdGetVolume); ok && s.Camilla.VolumeRetries < camillaMaxRetries {
+			s.Camilla.VolumeRetryAt = ev.At.Add(camillaRetryBase << s.Camilla.VolumeRetries)
+			s.Camilla.VolumeRetries++
+		}
+		return nil, true
+
+	case camillaErrConnection:
+		if _, ok := ev.Command.(CmdSetVolume); ok {
+			dropStaleVolume(s)
+		}
+		return nil, true
+	}
+
+	msg := ""
+	if ev.Err != nil {
+		msg = ev.Err.Error()
+	}
+	return []StateBroadcast{BroadcastDSPCommandError{Command: camillaCommandName(ev.Command), Class: class, Error: msg, At: ev.At}}, false
+}
+
+// dropStaleVolume stops the hold or ramp whose SetVolume was lost with the
+// connection. A hold stays stopped until its key is released.
+func dropStaleVolume(s *DaemonState) {
+	if s.VolumeCtrl.HeldDirection != 0 {
+		s.VolumeCtrl.SuppressedDirection = s.VolumeCtrl.HeldDirection
+	}
+	s.VolumeCtrl.HeldDirection = 0
+	s.VolumeCtrl.HoldBeganAt = time.Time{}
+	s.VolumeCtrl.VelocityDBPerS = 0
+	s.VolumeCtrl.Ramping = false
+	s.VolumeCtrl.RampRateDBPerS = 0
+	s.Intent.DesiredVolume = nil
+}
+
+// volumeRetry returns the GetVolume retry due at now, if any.
+func volumeRetry(s *DaemonState, now time.Time) []Command {
+	if s.Camilla.VolumeRetryAt.IsZero() || now.Before(s.Camilla.VolumeRetryAt) {
+		return nil
+	}
+	s.Camilla.VolumeRetryAt = time.Time{}
+	return []Command{CmdGetVolume{}}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestClassifyCamillaError(t *testing.T) {
+	_, jsonErr := parseCamillaReply[float64]([]byte(`{"GetVolume":`), "GetVolume")
+	_, resultErr := parseCamillaReply[float64]([]byte(`{"GetVolume":{"result":"Error","value":0}}`), "GetVolume")
+	_, unexpected := parseCamillaReply[float64]([]byte(`{"GetMute":{"result":"Ok","value":false}}`), "GetVolume")
+	for _, tc := range []struct {
+		err  error
+		want camillaErrorClass
+	}{
+		{fmt.Errorf("read: %w", os.ErrDeadlineExceeded), camillaErrTimeout},
+		{errCamillaNotConnected, camillaErrConnection},
+		{errors.New("websocket: close 1006"), camillaErrConnection},
+		{jsonErr, camillaErrProtocol},
+		{unexpected, camillaErrProtocol},
+		{resultErr, camillaErrResult},
+	} {
+		if got := classifyCamillaError(tc.err); got != tc.want {
+			t.Errorf("classify(%v) = %s, want %s", tc.err, got, tc.want)
+		}
+	}
+	if err := checkCamillaResult("SetVolume", "Ok"); err != nil {
+		t.Errorf("Ok result: %v", err)
+	}
+}
+
+func TestReduce_GetVolumeTimeoutRetriesWithBackoff(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0}
+	t0 := time.Unix(1000, 0).UTC()
+	s := &DaemonState{}
+	timeout := CamillaCommandFailed{Command: CmdGetVolume{}, Err: os.ErrDeadlineExceeded}
+
+	var retries []time.Duration
+	at := t0
+	for el := time.Duration(0); el < 5*time.Second; el += 50 * time.Millisecond {
+		now := t0.Add(el)
+		if el == 0 {
+			timeout.At = now
+			Reduce(s, timeout, cfg, RotaryConfig{}, ReducerPolicy{})
+		}
+		rr := Reduce(s, Tick{Now: now, Dt: 0.05}, cfg, RotaryConfig{}, ReducerPolicy{})
+		if countCommands[CmdGetVolume](rr.Commands) > 0 && now.Sub(s.Camilla.ProbeAt) != 0 {
+			retries = append(retries, now.Sub(at))
+			at = now
+			timeout.At = now
+			Reduce(s, timeout, cfg, RotaryConfig{}, ReducerPolicy{})
+		}
+	}
+	want := []time.Duration{250 * time.Millisecond, 500 * time.Millisecond, time.Second}
+	if fmt.Sprint(retries) != fmt.Sprint(want) {
+		t.Fatalf("retries after %v, want %v", retries, want)
+	}
+	if !s.Camilla.Unreachable {
+		t.Fatal("timeout didn't mark CamillaDSP unreachable")
+	}
+
+	Reduce(s, CamillaVolumeObserved{VolumeDB: -30, At: t0.Add(5 * time.Second)}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if s.Camilla.VolumeRetries != 0 || !s.Camilla.VolumeRetryAt.IsZero() {
+		t.Fatalf("retries not reset: %+v", s.Camilla)
+	}
+}
+
+func TestReduce_ConnectionLostDropsStaleVolume(t *testing.T) {
+	cfg := VelocityConfig{Mode: VelocityModeConstant, VelMaxDBPerS: 5, AccelTime: 1, MinDB: -80, MaxDB: 0}
+	t0 := time.Unix(1000, 0).UTC()
+	s := &DaemonState{}
+	s.SetObservedVolume(-40, t0)
+	Reduce(s, TimedEvent{At: t0, Event: VolumeHeld{Direction: 1}}, cfg, RotaryConfig{}, ReducerPolicy{})
+	rr := Reduce(s, Tick{Now: t0.Add(100 * time.Millisecond), Dt: 0.1}, cfg, RotaryConfig{}, ReducerPolicy{})
+	var set CmdSetVolume
+	for _, c := range rr.Commands {
+		if v, ok := c.(CmdSetVolume); ok {
+			set = v
+		}
+	}
+
+	rr = Reduce(s, CamillaCommandFailed{Command: set, Err: errCamillaNotConnected, At: t0.Add(150 * time.Millisecond)}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if rr.State.VolumeCtrl.moving() || rr.State.Intent.DesiredVolume != nil || rr.State.VolumeCtrl.SuppressedDirection != 1 {
+		t.Fatalf("stale volume kept: %+v %+v", rr.State.VolumeCtrl, rr.State.Intent)
+	}
+	if !rr.State.Camilla.Unreachable {
+		t.Fatal("connection loss didn't mark CamillaDSP unreachable")
+	}
+	// The hold repeating while the key is still down doesn't resume it.
+	Reduce(s, TimedEvent{At: t0.Add(200 * time.Millisecond), Event: VolumeHeld{Direction: 1}}, cfg, RotaryConfig{}, ReducerPolicy{})
+	rr = Reduce(s, Tick{Now: t0.Add(300 * time.Millisecond), Dt: 0.1}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if n := countCommands[CmdSetVolume](rr.Commands); n != 0 {
+		t.Fatalf("sent %d stale SetVolume", n)
+	}
+}
+
+func TestReduce_ProtocolErrorBroadcastsWithoutUnreachable(t *testing.T) {
+	t0 := time.Unix(1000, 0).UTC()
+	s := &DaemonState{}
+	_, err := parseCamillaReply[bool]([]byte(`{"GetVolume":{"result":"Ok","value":-3}}`), "GetMute")
+	rr := Reduce(s, CamillaCommandFailed{Command: CmdGetMute{}, Err: err, At: t0}, VelocityConfig{}, RotaryConfig{}, ReducerPolicy{})
+	if rr.State.Camilla.Unreachable {
+		t.Fatal("protocol error marked CamillaDSP unreachable")
+	}
+	var got *BroadcastDSPCommandError
+	for _, b := range rr.Broadcasts {
+		if e, ok := b.(BroadcastDSPCommandError); ok {
+			got = &e
+		}
+		if _, ok := b.(BroadcastDSPConnectionChanged); ok {
+			t.Fatalf("unexpected %+v", b)
+		}
+	}
+	if got == nil || got.Command != "GetMute" || got.Class != camillaErrProtocol {
+		t.Fatalf("dsp_command_error = %+v", got)
+	}
+}
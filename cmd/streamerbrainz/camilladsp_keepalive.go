@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ============================================================================
+// CamillaDSP control connection keepalive
+// ============================================================================
+// A TCP path that dies silently (CamillaDSP host powered off, Wi-Fi gone) used
+// to be noticed only when the next command timed out, often a user's volume
+// change. With camilladsp.keepalive_sec set, a connection that has been idle
+// that long is pinged: a websocket ping followed by GetVersion. The client
+// only reads while waiting for a reply and CamillaDSP sends nothing unasked,
+// so the GetVersion reply is what ends the wait (the read consumes the pong on
+// the way). If it doesn't arrive within camillaPongWait (or keepalive_sec, if
+// shorter), the connection is dropped and re-dialed right away.
+//
+// The monitor connection (camilladsp.monitor_hz) needs no keepalive: it polls
+// several times a second.
+// ============================================================================
+
+const camillaPongWait = 5 * time.Second
+
+// pingLocked checks that c's connection answers within wait. c.mu must be held.
+func (c *CamillaDSPClient) pingLocked(ctx context.Context, wait time.Duration) (err error) {
+	if c.conn == nil {
+		return errCamillaNotConnected
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	defer c.watchCtxLocked(ctx, &err)()
+
+	conn := c.conn
+	if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+		c.conn = nil
+		return err
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(`"GetVersion"`)); err != nil {
+		c.conn = nil
+		return err
+	}
+	conn.SetReadDeadline(readDeadline(ctx, wait))
+	defer c.resetDeadlineLocked()
+	if _, _, err := conn.ReadMessage(); err != nil {
+		c.conn = nil
+		return err
+	}
+	c.lastReply = time.Now()
+	return nil
+}
+
+// keepalive pings c if it has been idle for interval, re-dialing on failure.
+func (c *CamillaDSPClient) keepalive(ctx context.Context, interval time.Duration, logger *slog.Logger) {
+	c.mu.Lock()
+	if c.conn == nil || time.Since(c.lastReply) < interval {
+		// Down (the next command re-dials) or recently proven alive.
+		c.mu.Unlock()
+		return
+	}
+	conn := c.conn
+	err := c.pingLocked(ctx, min(interval, camillaPongWait))
+	c.mu.Unlock()
+	if err == nil || ctx.Err() != nil {
+		return
+	}
+	conn.Close()
+	logger.Warn("camilladsp missed keepalive; reconnecting", "error", err)
+	if err := c.ensureConnected(ctx); err != nil && ctx.Err() == nil {
+		logger.Warn("camilladsp reconnect after missed keepalive failed", "error", err)
+	}
+}
+
+// runCamillaKeepalive pings client's control connection whenever it has been
+// idle for interval, until ctx is canceled.
+func runCamillaKeepalive(ctx context.Context, client *CamillaDSPClient, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			client.keepalive(ctx, interval, logger)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestCamillaDSPClient_KeepaliveReconnectsDeadConnection(t *testing.T) {
+	var conns, pings atomic.Int32
+	var silent atomic.Bool
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		id := conns.Add(1)
+		conn.SetPingHandler(func(data string) error {
+			pings.Add(1)
+			return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+			// The first connection goes quiet once told to, like a dead path.
+			if id == 1 && silent.Load() {
+				continue
+			}
+			_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"GetVersion":{"result":"Ok","value":"2.0.3"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewCamillaDSPClient("ws"+strings.TrimPrefix(srv.URL, "http"), slog.New(slog.DiscardHandler), 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	logger := slog.New(slog.DiscardHandler)
+
+	// Recently active: no ping.
+	client.keepalive(t.Context(), time.Hour, logger)
+	if pings.Load() != 0 {
+		t.Fatal("pinged a connection that just answered")
+	}
+
+	// Idle and alive: pinged, kept.
+	time.Sleep(150 * time.Millisecond)
+	client.keepalive(t.Context(), 100*time.Millisecond, logger)
+	if pings.Load() != 1 || conns.Load() != 1 {
+		t.Fatalf("pings=%d conns=%d", pings.Load(), conns.Load())
+	}
+
+	// Idle and dead: dropped and re-dialed without waiting for a command.
+	silent.Store(true)
+	time.Sleep(150 * time.Millisecond)
+	start := time.Now()
+	client.keepalive(t.Context(), 100*time.Millisecond, logger)
+	if d := time.Since(start); d > 2*time.Second {
+		t.Fatalf("keepalive took %v", d)
+	}
+	if conns.Load() != 2 || !client.connected() {
+		t.Fatalf("not re-dialed: conns=%d connected=%v", conns.Load(), client.connected())
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// ============================================================================
+// CamillaDSP monitoring connection
+// ============================================================================
+// Meters poll CamillaDSP several times a second. CamillaDSP answers the
+// requests of one connection in order, so over the control socket every poll
+// could sit in front of a user's volume command. With camilladsp.monitor_hz
+// set, a zone opens a second WebSocket used only for GetSignalLevels and
+// GetFaders (CamillaDSP 2.0+, see camilladsp_version.go); volume, mute and config commands keep the control connection to
+// themselves.
+//
+// Reconnects are supervised together: the monitor never re-dials while the
+// control connection is down (the control path already retries and reports
+// dsp_connection_changed), and re-dials with backoff once it is back, so a
+// restarting CamillaDSP sees one reconnect loop per zone rather than two.
+// ============================================================================
+
+const (
+	camillaMonitorMinBackoff = 500 * time.Millisecond
+	camillaMonitorMaxBackoff = 10 * time.Second
+)
+
+// CamillaSignalLevels is CamillaDSP's GetSignalLevels value (dBFS per channel).
+type CamillaSignalLevels struct {
+	PlaybackRMS  []float64 `json:"playback_rms"`
+	PlaybackPeak []float64 `json:"playback_peak"`
+	CaptureRMS   []float64 `json:"capture_rms"`
+	CapturePeak  []float64 `json:"capture_peak"`
+}
+
+// CamillaFader is one entry of CamillaDSP's GetFaders value (fader 0 is Main).
+type CamillaFader struct {
+	Volume float64 `json:"volume"`
+	Mute   bool    `json:"mute"`
+}
+
+// BroadcastSignalLevels carries one meter poll of a zone's CamillaDSP.
+type BroadcastSignalLevels struct {
+	Levels CamillaSignalLevels
+	Faders []CamillaFader
+	At     time.Time
+}
+
+func (BroadcastSignalLevels) stateBroadcastMarker() {}
+
+// openMonitor creates c's monitoring connection. A failed first dial is not an
+// error: runCamillaMonitor keeps retrying.
+func (c *CamillaDSPClient) openMonitor() {
+	c.monitor = &CamillaDSPClient{
+		url:         c.url,
+		dial:        c.dial,
+		logger:      c.logger.With("conn", "monitor"),
+		readTimeout: c.readTimeout,
+		timeouts:    c.timeouts,
+	}
+	if err := c.monitor.connect(context.Background()); err != nil {
+		c.logger.Warn("camilladsp monitor connection failed", "error", err)
+	}
+}
+
+// pollLevels reads signal levels and, with withFaders, faders in one pipelined
+// round trip, without reconnecting.
+func (c *CamillaDSPClient) pollLevels(ctx context.Context, withFaders bool) (CamillaSignalLevels, []CamillaFader, error) {
+	requests := []any{"GetSignalLevels"}
+	if withFaders {
+		requests = append(requests, "GetFaders")
+	}
+	c.mu.Lock()
+	responses, err := c.pipelineLocked(ctx, requests)
+	c.mu.Unlock()
+	if err != nil {
+		return CamillaSignalLevels{}, nil, err
+	}
+	levels, err := parseCamillaReply[CamillaSignalLevels](responses[0], "GetSignalLevels")
+	if err != nil {
+		return CamillaSignalLevels{}, nil, err
+	}
+	if !withFaders {
+		return levels.Value, nil, nil
+	}
+	faders, err := parseCamillaReply[[]CamillaFader](responses[1], "GetFaders")
+	if err != nil {
+		return CamillaSignalLevels{}, nil, err
+	}
+	return levels.Value, faders.Value, nil
+}
+
+// runCamillaMonitor polls client's monitoring connection hz times a second and
+// publishes each result until ctx is canceled. client must have been set up
+// with openMonitor.
+func runCamillaMonitor(ctx context.Context, client *CamillaDSPClient, hz int, publish func(StateBroadcast), logger *slog.Logger) {
+	mon := client.monitor
+	ticker := time.NewTicker(time.Second / time.Duration(hz))
+	defer ticker.Stop()
+
+	backoff := camillaMonitorMinBackoff
+	var nextDial time.Time
+	failing := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if !mon.connected() {
+				if !client.connected() || now.Before(nextDial) {
+					continue
+				}
+				if err := mon.connect(ctx); err != nil {
+					logger.Debug("camilladsp monitor reconnect failed", "error", err, "retry_in", backoff)
+					nextDial = now.Add(backoff)
+					backoff = min(backoff*2, camillaMonitorMaxBackoff)
+					continue
+				}
+				logger.Info("camilladsp monitor connected")
+				backoff = camillaMonitorMinBackoff
+			}
+
+			// The control connection knows the version (CamillaDSP < 2.0 has no faders).
+			levels, faders, err := mon.pollLevels(ctx, client.supports(camillaFaders))
+			if err != nil {
+				// Log transitions only; a DSP without meter support would
+				// otherwise log every poll.
+				if !failing {
+					logger.Warn("camilladsp level polling failed", "error", err)
+					failing = true
+				}
+				continue
+			}
+			if failing {
+				logger.Info("camilladsp level polling recovered")
+				failing = false
+			}
+			publish(BroadcastSignalLevels{Levels: levels, Faders: faders, At: now})
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeCamillaDSP answers GetVersion, GetVolume, GetSignalLevels and GetFaders and records
+// which connection (in dial order, from 0) each request arrived on.
+type fakeCamillaDSP struct {
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	conns    int
+	requests map[int][]string
+}
+
+func newFakeCamillaDSP(t *testing.T) *fakeCamillaDSP {
+	t.Helper()
+	return newFakeCamillaDSPVersion(t, "2.0.3")
+}
+
+// newFakeCamillaDSPVersion is newFakeCamillaDSP reporting the given version.
+func newFakeCamillaDSPVersion(t *testing.T, version string) *fakeCamillaDSP {
+	t.Helper()
+	f := &fakeCamillaDSP{requests: map[int][]string{}}
+	replies := map[string]string{
+		"GetVersion":      `{"GetVersion":{"result":"Ok","value":"` + version + `"}}`,
+		"GetMute":         `{"GetMute":{"result":"Ok","value":true}}`,
+		"GetVolume":       `{"GetVolume":{"result":"Ok","value":-20}}`,
+		"GetSignalLevels": `{"GetSignalLevels":{"result":"Ok","value":{"playback_rms":[-30,-31],"playback_peak":[-12,-13],"capture_rms":[-29],"capture_peak":[-11]}}}`,
+		"GetFaders":       `{"GetFaders":{"result":"Ok","value":[{"volume":-20,"mute":false},{"volume":-3,"mute":true}]}}`,
+	}
+	upgrader := websocket.Upgrader{}
+	f.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		id := f.conns
+		f.conns++
+		f.mu.Unlock()
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var name string
+			_ = json.Unmarshal(msg, &name)
+			f.mu.Lock()
+			f.requests[id] = append(f.requests[id], name)
+			f.mu.Unlock()
+			_ = conn.WriteMessage(websocket.TextMessage, []byte(replies[name]))
+		}
+	}))
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeCamillaDSP) url() string { return "ws" + strings.TrimPrefix(f.srv.URL, "http") }
+
+func (f *fakeCamillaDSP) connCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.conns
+}
+
+func (f *fakeCamillaDSP) requestsOn(conn int) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.requests[conn])
+}
+
+func startCamillaMonitor(t *testing.T, client *CamillaDSPClient) <-chan StateBroadcast {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	out := make(chan StateBroadcast, 16)
+	go runCamillaMonitor(ctx, client, 50, func(b StateBroadcast) {
+		select {
+		case out <- b:
+		default:
+		}
+	}, slog.New(slog.DiscardHandler))
+	return out
+}
+
+func waitSignalLevels(t *testing.T, out <-chan StateBroadcast) BroadcastSignalLevels {
+	t.Helper()
+	select {
+	case b := <-out:
+		return b.(BroadcastSignalLevels)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no signal levels published")
+		return BroadcastSignalLevels{}
+	}
+}
+
+func TestCamillaMonitor_PollsOnItsOwnConnection(t *testing.T) {
+	dsp := newFakeCamillaDSP(t)
+	client, err := NewCamillaDSPClient(dsp.url(), slog.New(slog.DiscardHandler), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.openMonitor()
+	out := startCamillaMonitor(t, client)
+
+	got := waitSignalLevels(t, out)
+	if !slices.Equal(got.Levels.PlaybackPeak, []float64{-12, -13}) || !slices.Equal(got.Levels.CaptureRMS, []float64{-29}) {
+		t.Fatalf("levels = %+v", got.Levels)
+	}
+	if !slices.Equal(got.Faders, []CamillaFader{{Volume: -20}, {Volume: -3, Mute: true}}) {
+		t.Fatalf("faders = %+v", got.Faders)
+	}
+
+	if v, err := client.GetVolume(t.Context()); err != nil || v != -20 {
+		t.Fatalf("GetVolume = %v, %v", v, err)
+	}
+	if reqs := dsp.requestsOn(0); !slices.Equal(reqs, []string{"GetVersion", "GetVolume"}) {
+		t.Fatalf("control connection requests = %v", reqs)
+	}
+	for _, name := range dsp.requestsOn(1) {
+		if name != "GetSignalLevels" && name != "GetFaders" {
+			t.Fatalf("monitor connection got %q", name)
+		}
+	}
+}
+
+func TestCamillaMonitor_RedialsOnlyAfterControlConnection(t *testing.T) {
+	dsp := newFakeCamillaDSP(t)
+	client, err := NewCamillaDSPClient(dsp.url(), slog.New(slog.DiscardHandler), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.openMonitor()
+
+	// Both connections drop (e.g. CamillaDSP restarted).
+	client.Close()
+	out := startCamillaMonitor(t, client)
+
+	time.Sleep(150 * time.Millisecond)
+	if n := dsp.connCount(); n != 2 {
+		t.Fatalf("connections = %d, monitor must not redial while control is down", n)
+	}
+	select {
+	case b := <-out:
+		t.Fatalf("published while disconnected: %#v", b)
+	default:
+	}
+
+	if err := client.ensureConnected(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	waitSignalLevels(t, out)
+	if n := dsp.connCount(); n != 4 {
+		t.Fatalf("connections = %d, want control and monitor redialed once each", n)
+	}
+}
+
+func TestValidateCamillaDSP_MonitorHz(t *testing.T) {
+	c := DefaultConfig().CamillaDSP
+	for _, hz := range []int{0, 10, maxMonitorHz} {
+		c.MonitorHz = hz
+		if err := validateCamillaDSP("camilladsp", c); err != nil {
+			t.Fatalf("monitor_hz %d: %v", hz, err)
+		}
+	}
+	for _, hz := range []int{-1, maxMonitorHz + 1} {
+		c.MonitorHz = hz
+		if err := validateCamillaDSP("camilladsp", c); err == nil {
+			t.Fatalf("monitor_hz %d accepted", hz)
+		}
+	}
+}
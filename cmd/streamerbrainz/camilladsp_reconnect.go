@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ============================================================================
+// CamillaDSP connect/reconnect pacing
+// ============================================================================
+// Every (re)connect makes up to camilladsp.reconnect.attempts dial attempts.
+// The first retry waits interval_ms; each later one waits backoff_factor times
+// longer, capped at max_backoff_ms (factor 1 = a fixed interval). Defaults are
+// 10 attempts 500 ms apart.
+//
+// The daemon's startup connect never gives up: it runs in the background, or
+// with reconnect.wait_at_startup blocks startup (see camilladsp_startup.go).
+// Reconnects later on stay bounded, since the effects worker is blocked while
+// they run. `dsp` and `doctor` never wait.
+// ============================================================================
+
+// camillaReconnect is a client's retry policy; the zero value is the default.
+type camillaReconnect struct {
+	attempts   int // per (re)connect (<= 0: defaultCamillaConnectAttempts)
+	interval   time.Duration
+	factor     float64
+	maxBackoff time.Duration
+
+	// forever makes the initial connect retry until ctx is canceled.
+	forever bool
+}
+
+// reconnectPolicy converts camilladsp.reconnect.
+func (c CamillaDSPConfig) reconnectPolicy() camillaReconnect {
+	r := c.Reconnect
+	return camillaReconnect{
+		attempts:   r.Attempts,
+		interval:   time.Duration(r.IntervalMS) * time.Millisecond,
+		factor:     r.BackoffFactor,
+		maxBackoff: time.Duration(r.MaxBackoffMS) * time.Millisecond,
+	}
+}
+
+// maxAttempts is the bounded attempt count of r.
+func (r camillaReconnect) maxAttempts() int {
+	if r.attempts <= 0 {
+		return defaultCamillaConnectAttempts
+	}
+	return r.attempts
+}
+
+// delay is the wait after failed attempt n (0-based).
+func (r camillaReconnect) delay(n int) time.Duration {
+	interval := r.interval
+	if interval <= 0 {
+		interval = defaultCamillaConnectIntervalMS * time.Millisecond
+	}
+	maxBackoff := max(r.maxBackoff, interval)
+	if r.maxBackoff <= 0 {
+		maxBackoff = max(defaultCamillaConnectMaxBackoffMS*time.Millisecond, interval)
+	}
+	d := float64(interval)
+	for range n {
+		if r.factor <= 1 || d >= float64(maxBackoff) {
+			break
+		}
+		d *= r.factor
+	}
+	return min(time.Duration(d), maxBackoff)
+}
+
+// connectWithRetry connects, retrying per c.reconnect. forever ignores the
+// attempt limit (only ctx ends it).
+func (c *CamillaDSPClient) connectWithRetry(ctx context.Context, forever bool) error {
+	attempts := c.reconnect.maxAttempts()
+	var lastErr error
+	for attempt := 0; forever || attempt < attempts; attempt++ {
+		err := c.connect(ctx)
+		if err == nil {
+			c.logger.Info("connected to CamillaDSP", "url", c.url)
+			c.detectVersion(ctx)
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		lastErr = err
+		if !forever && attempt == attempts-1 {
+			break
+		}
+		wait := c.reconnect.delay(attempt)
+		c.logger.Warn("connection failed; retrying...", "error", err, "attempt", attempt+1, "retry_in", wait)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	return fmt.Errorf("failed to connect after %d attempts: %w", attempts, lastErr)
+}
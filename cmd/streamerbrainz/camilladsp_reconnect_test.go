@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCamillaReconnect_Delay(t *testing.T) {
+	var def camillaReconnect
+	if def.maxAttempts() != 10 || def.delay(0) != 500*time.Millisecond || def.delay(5) != 500*time.Millisecond {
+		t.Fatalf("default policy: %d attempts, delays %v %v", def.maxAttempts(), def.delay(0), def.delay(5))
+	}
+
+	r := CamillaDSPConfig{Reconnect: CamillaDSPReconnectConfig{
+		Attempts: 3, IntervalMS: 100, BackoffFactor: 2, MaxBackoffMS: 500,
+	}}.reconnectPolicy()
+	for n, want := range []time.Duration{100, 200, 400, 500, 500} {
+		if got := r.delay(n); got != want*time.Millisecond {
+			t.Errorf("delay(%d) = %v, want %v", n, got, want*time.Millisecond)
+		}
+	}
+}
+
+func TestCamillaDSPClient_ConnectAttempts(t *testing.T) {
+	c := &CamillaDSPClient{
+		url:       "ws://127.0.0.1:1",
+		logger:    slog.New(slog.DiscardHandler),
+		reconnect: camillaReconnect{attempts: 2, interval: time.Millisecond},
+	}
+	err := c.connectWithRetry(t.Context(), false)
+	if err == nil || !strings.HasPrefix(err.Error(), "failed to connect after 2 attempts") {
+		t.Fatalf("err = %v", err)
+	}
+
+	// forever only ends with ctx.
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+	if err := c.connectWithRetry(ctx, true); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("forever: err = %v", err)
+	}
+}
+
+func TestValidateCamillaDSP_Reconnect(t *testing.T) {
+	for name, mod := range map[string]func(*CamillaDSPReconnectConfig){
+		"attempts":    func(r *CamillaDSPReconnectConfig) { r.Attempts = 0 },
+		"interval":    func(r *CamillaDSPReconnectConfig) { r.IntervalMS = 0 },
+		"factor":      func(r *CamillaDSPReconnectConfig) { r.BackoffFactor = 0.5 },
+		"max_backoff": func(r *CamillaDSPReconnectConfig) { r.MaxBackoffMS = 100 },
+	} {
+		c := DefaultConfig().CamillaDSP
+		mod(&c.Reconnect)
+		if err := validateCamillaDSP("camilladsp", c); err == nil {
+			t.Errorf("%s: invalid reconnect accepted", name)
+		}
+	}
+	if err := validateCamillaDSP("camilladsp", DefaultConfig().CamillaDSP); err != nil {
+		t.Fatal(err)
+	}
+}
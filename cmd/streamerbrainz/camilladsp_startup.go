@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+)
+
+// ============================================================================
+// Deferred CamillaDSP startup connect
+// ============================================================================
+// streamerbrainz may well start before camilladsp.service. Rather than exit
+// when the first connect attempt fails, the daemon starts with a disconnected
+// client and serves IPC, the API and WebSocket clients right away: the
+// bootstrap reads fail, so the zone is unreachable and volume_known is false.
+// The connect keeps retrying in the background (camilladsp.reconnect pacing,
+// without the attempt limit); meanwhile commands fail at once instead of
+// dialing too. Once connected, the zone resyncs (ResyncState) and the usual
+// recovery follows.
+//
+// camilladsp.reconnect.wait_at_startup restores the old behavior: block until
+// connected before serving anything.
+// ============================================================================
+
+// NewCamillaDSPClientDeferred creates a client with a single connect attempt;
+// if that fails, run connectInBackground to keep trying.
+func NewCamillaDSPClientDeferred(wsURL string, dial CamillaDSPDialOptions, logger *slog.Logger, readTimeout int) (*CamillaDSPClient, error) {
+	client, err := newCamillaDSPClient(wsURL, dial, logger, readTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.connect(context.Background()); err != nil {
+		logger.Warn("CamillaDSP not reachable yet; connecting in the background", "url", wsURL, "error", err)
+		client.backgroundDial = true
+		return client, nil
+	}
+	logger.Info("connected to CamillaDSP", "url", wsURL)
+	client.detectVersion(context.Background())
+	return client, nil
+}
+
+// connectInBackground connects c if the startup attempt failed, retrying until
+// ctx is canceled, then calls onConnect.
+func (c *CamillaDSPClient) connectInBackground(ctx context.Context, onConnect func()) {
+	c.mu.Lock()
+	pending := c.backgroundDial
+	c.mu.Unlock()
+	if !pending {
+		return
+	}
+	err := c.connectWithRetry(ctx, true)
+	c.mu.Lock()
+	c.backgroundDial = false
+	c.mu.Unlock()
+	if err == nil && onConnect != nil {
+		onConnect()
+	}
+}
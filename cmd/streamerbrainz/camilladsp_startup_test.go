@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestCamillaDSPClient_DeferredStartup(t *testing.T) {
+	// Reserve a port, then start CamillaDSP on it only after the client exists.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	dial := CamillaDSPDialOptions{Reconnect: camillaReconnect{interval: 20 * time.Millisecond, maxBackoff: 20 * time.Millisecond}}
+	client, err := NewCamillaDSPClientDeferred("ws://"+addr, dial, slog.New(slog.DiscardHandler), 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// Commands fail at once while the background connect is pending.
+	start := time.Now()
+	if _, err := client.GetVolume(t.Context()); !errors.Is(err, errCamillaNotConnected) {
+		t.Fatalf("err = %v, want errCamillaNotConnected", err)
+	}
+	if d := time.Since(start); d > 100*time.Millisecond {
+		t.Fatalf("GetVolume dialed for %v", d)
+	}
+
+	connected := make(chan struct{})
+	go client.connectInBackground(t.Context(), func() { close(connected) })
+
+	time.Sleep(50 * time.Millisecond)
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("port reused: %v", err)
+	}
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			switch string(msg) {
+			case `"GetVersion"`:
+				_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"GetVersion":{"result":"Ok","value":"2.0.3"}}`))
+			case `"GetVolume"`:
+				_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"GetVolume":{"result":"Ok","value":-20.0}}`))
+			}
+		}
+	}))
+	srv.Listener = ln
+	srv.Start()
+	defer srv.Close()
+
+	select {
+	case <-connected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("background connect never succeeded")
+	}
+	if v, err := client.GetVolume(t.Context()); err != nil || v != -20 {
+		t.Fatalf("GetVolume = %v, %v", v, err)
+	}
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ============================================================================
+// CamillaDSP protocol tap
+// ============================================================================
+// For diagnosing protocol mismatches with other CamillaDSP versions: while the
+// tap is on, every frame on each zone's control connection (commands sent and
+// responses read; not the monitor_hz meter connection) is
+//
+//   - appended to diagnostics.tap_file, one line per frame
+//     ("<RFC 3339 time> <zone> > <frame>" for outbound, "<" for inbound), and
+//   - streamed to state clients as transient "camilladsp_frame" messages.
+//
+// The tap is off at startup and turned on at runtime (`streamerbrainz ctl tap
+// on [duration]`, or PUT /api/v1/debug/tap). It turns itself off after the
+// requested duration (default camillaTapDefaultDuration) or when the file
+// reaches diagnostics.tap_max_mb; each start truncates the file. Frames are
+// queued to a writer goroutine so volume commands never wait on the file, and
+// dropped (counted) if it falls behind. Frames longer than camillaTapMaxFrame
+// bytes (e.g. GetConfig responses) are truncated.
+// ============================================================================
+
+const (
+	camillaTapDefaultDuration = 10 * time.Minute
+	camillaTapMaxDuration     = 24 * time.Hour
+	camillaTapMaxFrame        = 4096
+	camillaTapQueue           = 1024
+)
+
+// Tap directions.
+const (
+	tapOut = ">"
+	tapIn  = "<"
+)
+
+// tapFrame is one captured frame.
+type tapFrame struct {
+	At        time.Time
+	Zone      string
+	Direction string
+	Frame     string
+	Truncated bool
+}
+
+// BroadcastCamillaFrame streams one tapped frame to state clients.
+type BroadcastCamillaFrame struct {
+	Direction string // "out" or "in"
+	Frame     string
+	Truncated bool
+	At        time.Time
+}
+
+func (BroadcastCamillaFrame) stateBroadcastMarker() {}
+
+// tapStatus is the GET /api/v1/debug/tap response.
+type tapStatus struct {
+	Enabled bool      `json:"enabled"`
+	Until   time.Time `json:"until,omitzero"`
+	File    string    `json:"file,omitempty"`
+	Bytes   int64     `json:"bytes"`
+	Frames  int64     `json:"frames"`
+	Dropped int64     `json:"dropped"`
+}
+
+// camillaTap is shared by the CamillaDSP clients of all zones.
+type camillaTap struct {
+	path     string
+	maxBytes int64
+	publish  func(StateBroadcast) // nil: file only
+	logger   *slog.Logger
+
+	enabled atomic.Bool
+	queue   chan tapFrame
+	dropped atomic.Int64
+
+	mu     sync.Mutex
+	file   *os.File
+	w      *bufio.Writer
+	until  time.Time
+	bytes  int64
+	frames int64
+}
+
+func newCamillaTap(cfg DiagnosticsConfig, publish func(StateBroadcast), logger *slog.Logger) *camillaTap {
+	return &camillaTap{
+		path:     cfg.TapFile,
+		maxBytes: int64(cfg.TapMaxMB) << 20,
+		publish:  publish,
+		logger:   logger,
+		queue:    make(chan tapFrame, camillaTapQueue),
+	}
+}
+
+// record captures a frame if the tap is on. It is called with the client's
+// lock held, so it only copies and queues. Safe on a nil tap.
+func (t *camillaTap) record(zone, direction string, frame []byte) {
+	if t == nil || !t.enabled.Load() {
+		return
+	}
+	f := tapFrame{At: time.Now(), Zone: zone, Direction: direction}
+	if len(frame) > camillaTapMaxFrame {
+		frame, f.Truncated = frame[:camillaTapMaxFrame], true
+	}
+	f.Frame = string(frame)
+	select {
+	case t.queue <- f:
+	default:
+		t.dropped.Add(1)
+	}
+}
+
+// start turns the tap on for d (0 = camillaTapDefaultDuration), restarting the
+// file; a running tap is only extended.
+func (t *camillaTap) start(d time.Duration) error {
+	if d <= 0 {
+		d = camillaTapDefaultDuration
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.until = time.Now().Add(d)
+	if t.enabled.Load() {
+		return nil
+	}
+	if t.path != "" {
+		if err := os.MkdirAll(filepath.Dir(t.path), 0o700); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(t.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+		if err != nil {
+			return err
+		}
+		t.file, t.w = f, bufio.NewWriter(f)
+	}
+	t.bytes, t.frames = 0, 0
+	t.dropped.Store(0)
+	t.enabled.Store(true)
+	t.logger.Info("camilladsp protocol tap on", "file", t.path, "until", t.until.Format(time.RFC3339))
+	return nil
+}
+
+// stop turns the tap off and closes the file.
+func (t *camillaTap) stop(reason string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopLocked(reason)
+}
+
+func (t *camillaTap) stopLocked(reason string) {
+	if !t.enabled.Swap(false) {
+		return
+	}
+	if t.file != nil {
+		if err := t.w.Flush(); err != nil {
+			t.logger.Warn("camilladsp protocol tap: write failed", "file", t.path, "error", err)
+		}
+		t.file.Close()
+		t.file, t.w = nil, nil
+	}
+	t.until = time.Time{}
+	t.logger.Info("camilladsp protocol tap off", "reason", reason, "frames", t.frames, "bytes", t.bytes, "dropped", t.dropped.Load())
+}
+
+func (t *camillaTap) status() tapStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return tapStatus{
+		Enabled: t.enabled.Load(),
+		Until:   t.until,
+		File:    t.path,
+		Bytes:   t.bytes,
+		Frames:  t.frames,
+		Dropped: t.dropped.Load(),
+	}
+}
+
+// Run writes and publishes queued frames until ctx is canceled, and ends the
+// tap when its time is up.
+func (t *camillaTap) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			t.stop("shutdown")
+			return
+		case f := <-t.queue:
+			t.write(f)
+		case now := <-ticker.C:
+			t.mu.Lock()
+			if t.enabled.Load() && now.After(t.until) {
+				t.stopLocked("duration elapsed")
+			} else if t.w != nil {
+				_ = t.w.Flush()
+			}
+			t.mu.Unlock()
+		}
+	}
+}
+
+func (t *camillaTap) write(f tapFrame) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.enabled.Load() {
+		return
+	}
+	t.frames++
+	if t.w != nil {
+		line := fmt.Sprintf("%s %s %s %s", f.At.UTC().Format(time.RFC3339Nano), f.Zone, f.Direction, f.Frame)
+		if f.Truncated {
+			line += " [truncated]"
+		}
+		n, err := fmt.Fprintln(t.w, line)
+		t.bytes += int64(n)
+		if err != nil {
+			t.logger.Warn("camilladsp protocol tap: write failed", "file", t.path, "error", err)
+			t.stopLocked("write failed")
+			return
+		}
+		if t.bytes >= t.maxBytes {
+			t.stopLocked("tap_max_mb reached")
+			return
+		}
+	}
+	if t.publish != nil {
+		dir := "out"
+		if f.Direction == tapIn {
+			dir = "in"
+		}
+		t.publish(ZoneBroadcast{Zone: f.Zone, Broadcast: BroadcastCamillaFrame{Direction: dir, Frame: f.Frame, Truncated: f.Truncated, At: f.At}})
+	}
+}
+
+// tapRequest is the PUT /api/v1/debug/tap body.
+type tapRequest struct {
+	Enabled     bool `json:"enabled"`
+	DurationSec int  `json:"duration_sec,omitempty"` // 0 = 10 minutes
+}
+
+// tapHandler serves GET and PUT /api/v1/debug/tap.
+type tapHandler struct {
+	tap *camillaTap
+}
+
+func (h *tapHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req tapRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+			writeEventWebhookResponse(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+			return
+		}
+		d := time.Duration(req.DurationSec) * time.Second
+		if req.DurationSec < 0 || d > camillaTapMaxDuration {
+			writeEventWebhookResponse(w, http.StatusBadRequest, "duration_sec must be 0-86400")
+			return
+		}
+		if !req.Enabled {
+			h.tap.stop("requested")
+		} else if err := h.tap.start(d); err != nil {
+			writeEventWebhookResponse(w, http.StatusInternalServerError, "start tap: "+err.Error())
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeEventWebhookResponse(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(h.tap.status())
+}
+
+// ctlTap handles `ctl tap on [duration] | off | status` against baseURL, sending
+// the control API token (if set), and prints the resulting status to w.
+func ctlTap(baseURL, token string, args []string, w io.Writer) error {
+	method, body := http.MethodGet, io.Reader(nil)
+	switch {
+	case args[0] == "status" && len(args) == 1:
+	case args[0] == "off" && len(args) == 1:
+		method, body = http.MethodPut, strings.NewReader(`{"enabled":false}`)
+	case args[0] == "on":
+		req := tapRequest{Enabled: true}
+		if len(args) == 2 {
+			d, err := time.ParseDuration(args[1])
+			if err != nil || d < time.Second {
+				return fmt.Errorf("invalid duration %q (e.g. 30s, 10m)", args[1])
+			}
+			req.DurationSec = int(d / time.Second)
+		}
+		b, _ := json.Marshal(req)
+		method, body = http.MethodPut, bytes.NewReader(b)
+	default:
+		return fmt.Errorf("usage: ctl tap on [duration] | off | status")
+	}
+
+	req, err := http.NewRequest(method, baseURL+"/api/v1/debug/tap", body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := newAPIClient(token, debugDumpHTTPTimeout).Do(req)
+	if err != nil {
+		return fmt.Errorf("contact daemon (is it running?): %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return tuneHTTPError(resp)
+	}
+	var st tapStatus
+	if err := json.NewDecoder(resp.Body).Decode(&st); err != nil {
+		return fmt.Errorf("decode tap status: %w", err)
+	}
+	if !st.Enabled {
+		fmt.Fprintf(w, "tap off (last run: %d frames, %d bytes, %d dropped)\n", st.Frames, st.Bytes, st.Dropped)
+		return nil
+	}
+	dest := st.File
+	if dest == "" {
+		dest = "state stream only; set diagnostics.tap_file to log to a file"
+	}
+	fmt.Fprintf(w, "tap on until %s (%s): %d frames, %d bytes, %d dropped\n",
+		st.Until.Local().Format(time.DateTime), dest, st.Frames, st.Bytes, st.Dropped)
+	return nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestCamillaTap_RecordsControlFrames(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+			_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"SetVolume":{"result":"Ok"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "tap.log")
+	var published []StateBroadcast
+	tap := newCamillaTap(DiagnosticsConfig{TapFile: path, TapMaxMB: 1}, func(b StateBroadcast) { published = append(published, b) }, slog.New(slog.DiscardHandler))
+
+	client, err := NewCamillaDSPClient("ws"+strings.TrimPrefix(srv.URL, "http"), slog.New(slog.DiscardHandler), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.tap, client.tapZone = tap, "main"
+
+	if _, err := client.SetVolume(t.Context(), -30); err != nil { // tap off: nothing recorded
+		t.Fatal(err)
+	}
+	if err := tap.start(time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.SetVolume(t.Context(), -29.5); err != nil {
+		t.Fatal(err)
+	}
+	if len(tap.queue) != 2 {
+		t.Fatalf("queued %d frames, want 2", len(tap.queue))
+	}
+	tap.write(<-tap.queue)
+	tap.write(<-tap.queue)
+	if st := tap.status(); !st.Enabled || st.Frames != 2 {
+		t.Fatalf("status = %+v", st)
+	}
+	tap.stop("test")
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], ` main > {"SetVolume":-29.5}`) || !strings.HasSuffix(lines[1], ` main < {"SetVolume":{"result":"Ok"}}`) {
+		t.Fatalf("tap file:\n%s", b)
+	}
+
+	if len(published) != 2 {
+		t.Fatalf("published %d broadcasts, want 2", len(published))
+	}
+	zb := published[1].(ZoneBroadcast)
+	if f := zb.Broadcast.(BroadcastCamillaFrame); zb.Zone != "main" || f.Direction != "in" || f.Frame != `{"SetVolume":{"result":"Ok"}}` {
+		t.Fatalf("broadcast = %+v", zb)
+	}
+	if ev, ok := convertBroadcast(zb.Broadcast); !ok || ev.Type != "camilladsp_frame" {
+		t.Fatalf("convertBroadcast = %+v, %v", ev, ok)
+	}
+}
+
+func TestCamillaTap_StopsAtMaxSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tap.log")
+	tap := newCamillaTap(DiagnosticsConfig{TapFile: path, TapMaxMB: 1}, nil, slog.New(slog.DiscardHandler))
+	if err := tap.start(0); err != nil {
+		t.Fatal(err)
+	}
+	big := bytes.Repeat([]byte("x"), camillaTapMaxFrame+100)
+	for tap.enabled.Load() {
+		tap.record("main", tapIn, big)
+		tap.write(<-tap.queue)
+	}
+	st := tap.status()
+	if st.Bytes < 1<<20 || st.Bytes > 1<<20+2*camillaTapMaxFrame {
+		t.Fatalf("stopped at %d bytes", st.Bytes)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(b)) != st.Bytes || !strings.Contains(string(b[:camillaTapMaxFrame+200]), "[truncated]") {
+		t.Fatalf("file has %d bytes, status %d", len(b), st.Bytes)
+	}
+
+	// A new start truncates the file.
+	if err := tap.start(time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	tap.stop("test")
+	if fi, err := os.Stat(path); err != nil || fi.Size() != 0 {
+		t.Fatalf("file not truncated on restart: %v, %v", fi, err)
+	}
+}
+
+func TestCtlTap(t *testing.T) {
+	tap := newCamillaTap(DiagnosticsConfig{TapMaxMB: 1}, nil, slog.New(slog.DiscardHandler))
+	auth := apiAuth{token: "tok", logger: slog.New(slog.DiscardHandler)}
+	srv := httptest.NewServer(auth.require(&tapHandler{tap: tap}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	if err := ctlTap(srv.URL, "", []string{"status"}, &out); err == nil {
+		t.Fatal("tap status without the token succeeded")
+	}
+	if err := ctlTap(srv.URL, "tok", []string{"on", "30s"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if st := tap.status(); !st.Enabled || time.Until(st.Until) > 30*time.Second || !strings.HasPrefix(out.String(), "tap on until") {
+		t.Fatalf("after on: %+v, %q", st, out.String())
+	}
+
+	out.Reset()
+	if err := ctlTap(srv.URL, "tok", []string{"off"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if tap.status().Enabled || !strings.HasPrefix(out.String(), "tap off") {
+		t.Fatalf("after off: %q", out.String())
+	}
+
+	for _, args := range [][]string{{"on", "soon"}, {"status", "now"}, {"flip"}} {
+		if err := ctlTap(srv.URL, "tok", args, &out); err == nil {
+			t.Errorf("ctlTap(%q) succeeded", args)
+		}
+	}
+}
+
+func TestTapHandler_RejectsBadDuration(t *testing.T) {
+	h := &tapHandler{tap: newCamillaTap(DiagnosticsConfig{TapMaxMB: 1}, nil, slog.New(slog.DiscardHandler))}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/v1/debug/tap", strings.NewReader(`{"enabled":true,"duration_sec":-1}`)))
+	if rec.Code != http.StatusBadRequest || h.tap.status().Enabled {
+		t.Fatalf("code %d, enabled %v", rec.Code, h.tap.status().Enabled)
+	}
+}
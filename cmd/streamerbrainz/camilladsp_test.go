@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestAppendSetVolumeFrame_IsValidJSONAndAllocationFree(t *testing.T) {
+	for _, db := range []float64{-30, -12.5, 0, -0.25} {
+		frame := appendSetVolumeFrame(nil, db)
+		var got map[string]float64
+		if err := json.Unmarshal(frame, &got); err != nil {
+			t.Fatalf("frame %s: %v", frame, err)
+		}
+		if got["SetVolume"] != db {
+			t.Fatalf("frame %s: want %v", frame, db)
+		}
+	}
+
+	buf := make([]byte, 0, 64)
+	resp := []byte(`{"SetVolume":{"result":"Ok"}}`)
+	allocs := testing.AllocsPerRun(100, func() {
+		buf = appendSetVolumeFrame(buf[:0], -23.75)
+		_ = responseResult(resp)
+	})
+	if allocs != 0 {
+		t.Fatalf("expected no allocations, got %v", allocs)
+	}
+}
+
+func TestResponseResult(t *testing.T) {
+	if got := string(responseResult([]byte(`{"SetVolume":{"result":"Error"}}`))); got != "Error" {
+		t.Fatalf("got %q", got)
+	}
+	if got := responseResult([]byte(`{"SetVolume":{}}`)); got != nil {
+		t.Fatalf("expected nil, got %q", got)
+	}
+}
+
+func TestCamillaDSPClient_SetVolumeRoundTrip(t *testing.T) {
+	frames := make(chan string, 4)
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if string(msg) == `"GetVersion"` {
+				_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"GetVersion":{"result":"Ok","value":"2.0.3"}}`))
+				continue
+			}
+			frames <- string(msg)
+			_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"SetVolume":{"result":"Ok"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewCamillaDSPClient("ws"+strings.TrimPrefix(srv.URL, "http"), slog.New(slog.DiscardHandler), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	for _, db := range []float64{-30, -29.5} {
+		got, err := client.SetVolume(t.Context(), db)
+		if err != nil || got != db {
+			t.Fatalf("SetVolume(%v) = %v, %v", db, got, err)
+		}
+	}
+	for _, want := range []string{`{"SetVolume":-30}`, `{"SetVolume":-29.5}`} {
+		if got := <-frames; got != want {
+			t.Fatalf("frame: got %s, want %s", got, want)
+		}
+	}
+}
+
+func TestRunEffects_PipelinesQueuedCommands(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// Only answer once all three requests are in: a sequential client would time out.
+		var names []string
+		for len(names) < 3 {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var name string
+			if json.Unmarshal(msg, &name) != nil {
+				var obj map[string]json.RawMessage
+				_ = json.Unmarshal(msg, &obj)
+				for k := range obj {
+					name = k
+				}
+			}
+			if name == "GetVersion" {
+				_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"GetVersion":{"result":"Ok","value":"2.0.3"}}`))
+				continue
+			}
+			names = append(names, name)
+		}
+		replies := map[string]string{
+			"SetVolume": `{"SetVolume":{"result":"Ok"}}`,
+			"GetMute":   `{"GetMute":{"result":"Ok","value":true}}`,
+			"GetState":  `{"GetState":{"result":"Ok","value":"Running"}}`,
+		}
+		for _, name := range names {
+			_ = conn.WriteMessage(websocket.TextMessage, []byte(replies[name]))
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewCamillaDSPClient("ws"+strings.TrimPrefix(srv.URL, "http"), slog.New(slog.DiscardHandler), 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.pipelining = true
+
+	var got []Event
+	runEffects(t.Context(), client, []Command{CmdSetVolume{TargetDB: -20}, CmdGetMute{}, CmdGetState{}}, slog.New(slog.DiscardHandler), func(ev Event) {
+		got = append(got, ev)
+	})
+
+	if len(got) != 3 {
+		t.Fatalf("expected 3 events, got %d: %#v", len(got), got)
+	}
+	if v, ok := got[0].(CamillaVolumeObserved); !ok || v.VolumeDB != -20 {
+		t.Fatalf("event 0: %#v", got[0])
+	}
+	if m, ok := got[1].(CamillaMuteObserved); !ok || !m.Muted {
+		t.Fatalf("event 1: %#v", got[1])
+	}
+	if st, ok := got[2].(CamillaProcessingStateObserved); !ok || st.State != "Running" {
+		t.Fatalf("event 2: %#v", got[2])
+	}
+}
+
+func TestRunEffects_PipelineFailureFailsUnansweredCommands(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// Answer the version check and the first request, then drop the connection.
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"GetVersion":{"result":"Ok","value":"2.0.3"}}`))
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"GetVolume":{"result":"Ok","value":-31.5}}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewCamillaDSPClient("ws"+strings.TrimPrefix(srv.URL, "http"), slog.New(slog.DiscardHandler), 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.pipelining = true
+
+	var got []Event
+	runEffects(t.Context(), client, []Command{CmdGetVolume{}, CmdSetMute{Muted: true}}, slog.New(slog.DiscardHandler), func(ev Event) {
+		got = append(got, ev)
+	})
+
+	if len(got) != 2 {
+		t.Fatalf("expected 2 events, got %d: %#v", len(got), got)
+	}
+	if v, ok := got[0].(CamillaVolumeObserved); !ok || v.VolumeDB != -31.5 {
+		t.Fatalf("event 0: %#v", got[0])
+	}
+	if f, ok := got[1].(CamillaCommandFailed); !ok || f.Command != (CmdSetMute{Muted: true}) {
+		t.Fatalf("event 1: %#v", got[1])
+	}
+}
+
+func TestRunEffects_ErrorResultsFailCommands(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		replies := map[string]string{
+			"GetVersion": `{"GetVersion":{"result":"Ok","value":"2.0.3"}}`,
+			"SetMute":    `{"SetMute":{"result":"Error","value":"mute is locked"}}`,
+			"SetVolume":  `{"SetVolume":`,
+		}
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var name string
+			if json.Unmarshal(msg, &name) != nil {
+				var obj map[string]json.RawMessage
+				_ = json.Unmarshal(msg, &obj)
+				for k := range obj {
+					name = k
+				}
+			}
+			_ = conn.WriteMessage(websocket.TextMessage, []byte(replies[name]))
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewCamillaDSPClient("ws"+strings.TrimPrefix(srv.URL, "http"), slog.New(slog.DiscardHandler), 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var got []Event
+	runEffects(t.Context(), client, []Command{CmdSetMute{Muted: true}, CmdSetVolume{TargetDB: -20}}, slog.New(slog.DiscardHandler), func(ev Event) {
+		got = append(got, ev)
+	})
+	if len(got) != 2 {
+		t.Fatalf("expected 2 events, got %#v", got)
+	}
+	mute, ok := got[0].(CamillaCommandFailed)
+	if !ok || mute.Class() != camillaErrResult || !strings.Contains(mute.Err.Error(), "mute is locked") {
+		t.Fatalf("SetMute: %#v", got[0])
+	}
+	vol, ok := got[1].(CamillaCommandFailed)
+	if !ok || vol.Class() != camillaErrProtocol {
+		t.Fatalf("SetVolume: %#v", got[1])
+	}
+}
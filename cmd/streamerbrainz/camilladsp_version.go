@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// CamillaDSP version detection and feature gating
+// ============================================================================
+// Every (re)connect of a zone's control connection starts with GetVersion.
+// Commands the attached CamillaDSP doesn't have are then not sent at all:
+//
+//   - faders (2.0): SetFaderVolume and GetFaders. Balance/sub encoder modes
+//     and normalization skip their fader commands, and meters
+//     (camilladsp.monitor_hz) poll signal levels only.
+//
+// Volume ramps (absolute-set fades, fade-in) are plain SetVolume steps, which
+// every version supports, so they need no gating. CamillaDSP has no command
+// for a server-side ramp with a per-call duration (only the fixed
+// devices.volume_ramp_time smoothing), so there is no native ramp to detect.
+//
+// When configured settings need a feature the attached version lacks, one
+// warning per setting is logged at connect (again only if the version
+// changes). If GetVersion fails or answers something unparsable, everything is
+// assumed to be supported, as before detection existed.
+// ============================================================================
+
+// camillaFeature names a gated part of the CamillaDSP websocket API.
+type camillaFeature string
+
+const (
+	camillaFaders camillaFeature = "faders"
+)
+
+// camillaFeatureSince is the first CamillaDSP version with each feature.
+var camillaFeatureSince = map[camillaFeature]camillaVersion{
+	camillaFaders: {Major: 2},
+}
+
+// camillaVersion is a parsed CamillaDSP version ("2.0.3", "v3.0.0").
+type camillaVersion struct {
+	Major, Minor, Patch int
+}
+
+// parseCamillaVersion parses s, ignoring a leading "v" and any pre-release or
+// build suffix ("3.0.0-beta1").
+func parseCamillaVersion(s string) (camillaVersion, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(s, "-+ "); i >= 0 {
+		s = s[:i]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return camillaVersion{}, false
+	}
+	var nums [3]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return camillaVersion{}, false
+		}
+		nums[i] = n
+	}
+	return camillaVersion{Major: nums[0], Minor: nums[1], Patch: nums[2]}, true
+}
+
+func (v camillaVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// atLeast reports whether v >= w.
+func (v camillaVersion) atLeast(w camillaVersion) bool {
+	if v.Major != w.Major {
+		return v.Major > w.Major
+	}
+	if v.Minor != w.Minor {
+		return v.Minor > w.Minor
+	}
+	return v.Patch >= w.Patch
+}
+
+// supports reports whether CamillaDSP v has feature f.
+func (v camillaVersion) supports(f camillaFeature) bool {
+	since, ok := camillaFeatureSince[f]
+	return !ok || v.atLeast(since)
+}
+
+// camillaRequirement is a configured setting that needs a CamillaDSP feature.
+type camillaRequirement struct {
+	Feature camillaFeature
+	Setting string
+}
+
+// camillaRequirements lists the settings of cfg (for a zone using cd) that
+// need gated CamillaDSP features.
+func camillaRequirements(cfg Config, cd CamillaDSPConfig) []camillaRequirement {
+	var reqs []camillaRequirement
+	if len(cfg.Rotary.BalanceFaders) > 0 {
+		reqs = append(reqs, camillaRequirement{camillaFaders, "rotary.balance_faders"})
+	}
+	if cfg.Rotary.SubFader > 0 {
+		reqs = append(reqs, camillaRequirement{camillaFaders, "rotary.sub_fader"})
+	}
+	if cfg.Normalization.Enabled {
+		reqs = append(reqs, camillaRequirement{camillaFaders, "normalization"})
+	}
+	if cd.MonitorHz > 0 {
+		reqs = append(reqs, camillaRequirement{camillaFaders, "camilladsp.monitor_hz (fader readout)"})
+	}
+	return reqs
+}
+
+// errCamillaUnsupported is returned for commands the attached CamillaDSP
+// doesn't have; nothing was sent.
+type errCamillaUnsupported struct {
+	Feature camillaFeature
+	Version camillaVersion
+}
+
+func (e errCamillaUnsupported) Error() string {
+	return fmt.Sprintf("CamillaDSP %s has no %s support (needs %s)", e.Version, e.Feature, camillaFeatureSince[e.Feature])
+}
+
+// detectVersion asks the freshly connected CamillaDSP for its version, and
+// warns about configured settings it can't support if the version changed.
+func (c *CamillaDSPClient) detectVersion(ctx context.Context) {
+	var v camillaVersion
+	resp, err := c.sendAndRead(ctx, "GetVersion")
+	if err == nil {
+		var r camillaReply[string]
+		if r, err = parseCamillaReply[string](resp, "GetVersion"); err == nil {
+			var ok bool
+			if v, ok = parseCamillaVersion(r.Value); !ok {
+				err = fmt.Errorf("unrecognized version %q", r.Value)
+			}
+		}
+	}
+	if err != nil {
+		c.logger.Warn("camilladsp version unknown; assuming all features are supported", "error", err)
+		c.mu.Lock()
+		c.version = nil
+		c.mu.Unlock()
+		return
+	}
+
+	c.mu.Lock()
+	changed := c.version == nil || *c.version != v
+	c.version = &v
+	reqs := c.requires
+	c.mu.Unlock()
+	if !changed {
+		return
+	}
+	c.logger.Info("camilladsp version", "version", v.String())
+	c.warnUnsupported(v, reqs)
+}
+
+// setRequirements records the settings that need gated features and warns
+// about those the already detected version can't support.
+func (c *CamillaDSPClient) setRequirements(reqs []camillaRequirement) {
+	c.mu.Lock()
+	c.requires = reqs
+	v := c.version
+	c.mu.Unlock()
+	if v != nil {
+		c.warnUnsupported(*v, reqs)
+	}
+}
+
+func (c *CamillaDSPClient) warnUnsupported(v camillaVersion, reqs []camillaRequirement) {
+	for _, r := range reqs {
+		if !v.supports(r.Feature) {
+			c.logger.Warn("configured setting is not supported by the attached CamillaDSP; its commands are skipped",
+				"setting", r.Setting, "camilladsp_version", v.String(), "needs", camillaFeatureSince[r.Feature].String())
+		}
+	}
+}
+
+// supports reports whether the attached CamillaDSP has feature f (true while
+// the version is unknown).
+func (c *CamillaDSPClient) supports(f camillaFeature) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.version == nil || c.version.supports(f)
+}
+
+// unsupported returns errCamillaUnsupported if the attached CamillaDSP lacks f.
+func (c *CamillaDSPClient) unsupported(f camillaFeature) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.version == nil || c.version.supports(f) {
+		return nil
+	}
+	return errCamillaUnsupported{Feature: f, Version: *c.version}
+}
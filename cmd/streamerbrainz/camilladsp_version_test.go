@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+)
+
+func TestParseCamillaVersion(t *testing.T) {
+	cases := map[string]camillaVersion{
+		"2.0.3":        {2, 0, 3},
+		"v3.0.0":       {3, 0, 0},
+		"3.1.0-beta.1": {3, 1, 0},
+		"1.0":          {1, 0, 0},
+	}
+	for in, want := range cases {
+		if got, ok := parseCamillaVersion(in); !ok || got != want {
+			t.Errorf("parseCamillaVersion(%q) = %v, %v; want %v", in, got, ok, want)
+		}
+	}
+	for _, in := range []string{"", "dev", "2.x", "1.2.3.4"} {
+		if v, ok := parseCamillaVersion(in); ok {
+			t.Errorf("parseCamillaVersion(%q) = %v, want failure", in, v)
+		}
+	}
+
+	if (camillaVersion{1, 9, 9}).supports(camillaFaders) || !(camillaVersion{2, 0, 0}).supports(camillaFaders) {
+		t.Fatal("faders should need 2.0")
+	}
+}
+
+func TestCamillaRequirements(t *testing.T) {
+	cfg := DefaultConfig()
+	if reqs := camillaRequirements(cfg, cfg.CamillaDSP); len(reqs) != 0 {
+		t.Fatalf("defaults need %v", reqs)
+	}
+	cfg.Rotary.SubFader = 3
+	cfg.CamillaDSP.MonitorHz = 10
+	reqs := camillaRequirements(cfg, cfg.CamillaDSP)
+	if len(reqs) != 2 || reqs[0].Setting != "rotary.sub_fader" || reqs[1].Feature != camillaFaders {
+		t.Fatalf("requirements = %v", reqs)
+	}
+}
+
+func TestCamillaDSPClient_GatesFeaturesByVersion(t *testing.T) {
+	dsp := newFakeCamillaDSPVersion(t, "1.0.3")
+	client, err := NewCamillaDSPClient(dsp.url(), slog.New(slog.DiscardHandler), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if client.supports(camillaFaders) {
+		t.Fatal("1.0.3 reported as supporting faders")
+	}
+	err = client.SetFaderVolume(t.Context(), 1, -6)
+	if !errors.As(err, &errCamillaUnsupported{}) {
+		t.Fatalf("SetFaderVolume error = %v", err)
+	}
+	var events []Event
+	runEffect(t.Context(), client, CmdSetFaderVolume{Fader: 1, TargetDB: -6}, slog.New(slog.DiscardHandler), func(ev Event) { events = append(events, ev) })
+	if len(events) != 0 {
+		t.Fatalf("skipped fader command reported %v", events)
+	}
+
+	// Meters still poll signal levels, just not faders.
+	client.openMonitor()
+	got := waitSignalLevels(t, startCamillaMonitor(t, client))
+	if got.Faders != nil || len(got.Levels.PlaybackRMS) != 2 {
+		t.Fatalf("levels = %+v", got)
+	}
+	if slices.Contains(dsp.requestsOn(1), "GetFaders") {
+		t.Fatal("GetFaders sent to 1.0.3")
+	}
+}
+
+func TestCamillaDSPClient_UnknownVersionAssumesSupport(t *testing.T) {
+	dsp := newFakeCamillaDSPVersion(t, "dev")
+	client, err := NewCamillaDSPClient(dsp.url(), slog.New(slog.DiscardHandler), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if !client.supports(camillaFaders) {
+		t.Fatal("unknown version gated features")
+	}
+
+	// Reconnecting re-detects the version.
+	client.mu.Lock()
+	client.conn.Close()
+	client.conn = nil
+	client.mu.Unlock()
+	if err := client.ensureConnected(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if n := slices.Index(dsp.requestsOn(1), "GetVersion"); n != 0 {
+		t.Fatalf("reconnect requests = %v", dsp.requestsOn(1))
+	}
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ============================================================================
+// cast control protocol
+// ============================================================================
+// Watches a Chromecast / Cast audio device (or Cast group) that feeds the DSP,
+// e.g. over toslink, and optionally keeps its volume and mute in step with
+// CamillaDSP:
+//
+//	mirror: none       observe only (changes are logged)
+//	mirror: from_cast  Cast changes become set_volume_percent / set_mute (origin "cast")
+//	mirror: to_cast    daemon changes are sent to the device
+//	mirror: both       both directions
+//
+// The Cast level (0..1) is a volume percent, mapped like any other (see
+// volume_percent.go). A status the device merely reports on connect is
+// recorded, not mirrored; with to_cast/both the daemon's state is pushed to the
+// device instead. Echoes are suppressed by ignoring the daemon's own "cast"
+// changes and by only sending what differs from the device's last status.
+//
+// options.address is host[:port]; Cast groups listen on their own port (see
+// the group's _googlecast._tcp mDNS record). options.zone picks the zone to
+// mirror (default: the selected zone).
+// ============================================================================
+
+func init() {
+	registerControlProtocol("cast", newCastProtocol)
+}
+
+// Mirror directions.
+const (
+	castMirrorNone = "none"
+	castMirrorFrom = "from_cast"
+	castMirrorTo   = "to_cast"
+	castMirrorBoth = "both"
+)
+
+const (
+	castDefaultPort       = "8009"
+	castDialTimeout       = 5 * time.Second
+	castHeartbeatInterval = 5 * time.Second
+	castHeartbeatTimeout  = 3 * castHeartbeatInterval
+	castMaxBackoff        = 30 * time.Second
+
+	// castLevelTolerance is the smallest level difference treated as a change.
+	castLevelTolerance = 0.01
+)
+
+// Cast namespaces and endpoints.
+const (
+	castNSConnection = "urn:x-cast:com.google.cast.tp.connection"
+	castNSHeartbeat  = "urn:x-cast:com.google.cast.tp.heartbeat"
+	castNSReceiver   = "urn:x-cast:com.google.cast.receiver"
+	castSenderID     = "sender-streamerbrainz"
+	castReceiverID   = "receiver-0"
+)
+
+// castOptions are the `options` of a cast control protocol.
+type castOptions struct {
+	Address string `yaml:"address"` // host[:port] of the device or group (port 8009 by default)
+	Mirror  string `yaml:"mirror"`  // none | from_cast | to_cast | both (default none)
+	Zone    string `yaml:"zone"`    // zone to mirror (default: the selected zone)
+}
+
+type castProtocol struct {
+	opts   castOptions
+	addr   string
+	logger *slog.Logger
+	push   chan struct{} // daemon state changed; sync it to the device
+}
+
+func newCastProtocol(options *yaml.Node, logger *slog.Logger) (ControlProtocol, error) {
+	var opts castOptions
+	if err := decodeControlProtocolOptions(options, &opts); err != nil {
+		return nil, fmt.Errorf("cast options: %w", err)
+	}
+	if opts.Address == "" {
+		return nil, errors.New("cast options.address is required")
+	}
+	addr := opts.Address
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, castDefaultPort)
+	}
+	switch opts.Mirror {
+	case "":
+		opts.Mirror = castMirrorNone
+	case castMirrorNone, castMirrorFrom, castMirrorTo, castMirrorBoth:
+	default:
+		return nil, fmt.Errorf("cast options.mirror must be one of %s, %s, %s, %s", castMirrorNone, castMirrorFrom, castMirrorTo, castMirrorBoth)
+	}
+	return &castProtocol{opts: opts, addr: addr, logger: logger, push: make(chan struct{}, 1)}, nil
+}
+
+func (p *castProtocol) mirrorFrom() bool {
+	return p.opts.Mirror == castMirrorFrom || p.opts.Mirror == castMirrorBoth
+}
+
+func (p *castProtocol) mirrorTo() bool {
+	return p.opts.Mirror == castMirrorTo || p.opts.Mirror == castMirrorBoth
+}
+
+// Start keeps a connection to the device, reconnecting with backoff.
+func (p *castProtocol) Start(ctx context.Context, events chan<- Event) error {
+	backoff := time.Second
+	for {
+		conn, err := castDial(ctx, p.addr)
+		if err == nil {
+			p.logger.Info("cast device connected", "addr", p.addr, "mirror", p.opts.Mirror)
+			var synced bool
+			synced, err = p.session(ctx, conn, events)
+			if synced {
+				backoff = time.Second
+			}
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		p.logger.Warn("cast device unavailable; retrying", "addr", p.addr, "error", err, "retry_in", backoff)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, castMaxBackoff)
+	}
+}
+
+// Notify schedules a push of the daemon's state when mirroring to the device.
+func (p *castProtocol) Notify(b StateBroadcast) {
+	if !p.mirrorTo() {
+		return
+	}
+	zb, ok := b.(ZoneBroadcast)
+	if !ok || (p.opts.Zone != "" && zb.Zone != p.opts.Zone) {
+		return
+	}
+	switch ev := zb.Broadcast.(type) {
+	case BroadcastVolumeChanged:
+		if ev.Origin == "cast" {
+			return // our own change
+		}
+	case BroadcastMuteChanged:
+	case BroadcastZoneSelected:
+		if p.opts.Zone != "" {
+			return
+		}
+	default:
+		return
+	}
+	select {
+	case p.push <- struct{}{}:
+	default:
+	}
+}
+
+// castDial opens a TLS connection to a Cast device. Cast devices present
+// self-signed certificates, so the certificate is not verified.
+func castDial(ctx context.Context, addr string) (net.Conn, error) {
+	d := tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: castDialTimeout},
+		Config:    &tls.Config{InsecureSkipVerify: true},
+	}
+	return d.DialContext(ctx, "tcp", addr)
+}
+
+// castVolume is the volume part of a RECEIVER_STATUS.
+type castVolume struct {
+	Level *float64 `json:"level,omitempty"`
+	Muted *bool    `json:"muted,omitempty"`
+}
+
+// castSession is one connection's state.
+type castSession struct {
+	p         *castProtocol
+	w         io.Writer
+	events    chan<- Event
+	requestID int
+
+	known bool // a status has been received
+	level float64
+	muted bool
+}
+
+// session runs one connection until it fails or ctx is canceled. synced reports
+// whether the device answered with a status.
+func (p *castProtocol) session(ctx context.Context, conn io.ReadWriteCloser, events chan<- Event) (synced bool, err error) {
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+	defer conn.Close()
+
+	s := &castSession{p: p, w: conn, events: events}
+	if err := s.send(castNSConnection, castReceiverID, map[string]any{"type": "CONNECT"}); err != nil {
+		return false, err
+	}
+	if err := s.send(castNSReceiver, castReceiverID, s.request("GET_STATUS")); err != nil {
+		return false, err
+	}
+
+	msgs := make(chan castMessage, 16)
+	readErr := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			m, err := readCastMessage(conn)
+			if err != nil {
+				readErr <- err
+				return
+			}
+			select {
+			case msgs <- m:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(castHeartbeatInterval)
+	defer heartbeat.Stop()
+	lastRx := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return s.known, nil
+		case err := <-readErr:
+			if ctx.Err() != nil {
+				return s.known, nil
+			}
+			return s.known, err
+		case m := <-msgs:
+			lastRx = time.Now()
+			if err := s.handle(ctx, m); err != nil {
+				return s.known, err
+			}
+		case <-heartbeat.C:
+			if time.Since(lastRx) > castHeartbeatTimeout {
+				return s.known, errors.New("device stopped answering heartbeats")
+			}
+			if err := s.send(castNSHeartbeat, castReceiverID, map[string]any{"type": "PING"}); err != nil {
+				return s.known, err
+			}
+		case <-p.push:
+			if err := s.pushToCast(ctx); err != nil {
+				return s.known, err
+			}
+		}
+	}
+}
+
+func (s *castSession) request(typ string) map[string]any {
+	s.requestID++
+	return map[string]any{"type": typ, "requestId": s.requestID}
+}
+
+func (s *castSession) send(namespace, dest string, payload any) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return writeCastMessage(s.w, castMessage{SourceID: castSenderID, DestinationID: dest, Namespace: namespace, Payload: string(b)})
+}
+
+// handle processes one message from the device.
+func (s *castSession) handle(ctx context.Context, m castMessage) error {
+	var msg struct {
+		Type   string `json:"type"`
+		Status struct {
+			Volume castVolume `json:"volume"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal([]byte(m.Payload), &msg); err != nil {
+		s.p.logger.Debug("cast: unparseable message", "namespace", m.Namespace, "error", err)
+		return nil
+	}
+	switch m.Namespace + " " + msg.Type {
+	case castNSHeartbeat + " PING":
+		return s.send(castNSHeartbeat, m.SourceID, map[string]any{"type": "PONG"})
+	case castNSConnection + " CLOSE":
+		return errors.New("device closed the connection")
+	case castNSReceiver + " RECEIVER_STATUS":
+		return s.observe(ctx, msg.Status.Volume)
+	}
+	return nil
+}
+
+// observe records a status and mirrors changes to the daemon.
+func (s *castSession) observe(ctx context.Context, v castVolume) error {
+	level, muted := s.level, s.muted
+	if v.Level != nil {
+		level = *v.Level
+	}
+	if v.Muted != nil {
+		muted = *v.Muted
+	}
+	first := !s.known
+	levelChanged := math.Abs(level-s.level) >= castLevelTolerance
+	muteChanged := muted != s.muted
+	s.known, s.level, s.muted = true, level, muted
+
+	if first {
+		s.p.logger.Info("cast volume", "level", level, "muted", muted)
+		if s.p.mirrorTo() {
+			return s.pushToCast(ctx)
+		}
+		return nil
+	}
+	if !levelChanged && !muteChanged {
+		return nil
+	}
+	s.p.logger.Info("cast volume changed", "level", level, "muted", muted)
+	if !s.p.mirrorFrom() {
+		return nil
+	}
+	if levelChanged {
+		s.emit(ctx, SetVolumePercent{Percent: math.Round(level*1000) / 10, Origin: "cast"})
+	}
+	if muteChanged {
+		s.emit(ctx, SetMute{Muted: muted})
+	}
+	return nil
+}
+
+func (s *castSession) emit(ctx context.Context, ev Event) {
+	if s.p.opts.Zone != "" {
+		ev = ZonedEvent{Zone: s.p.opts.Zone, Event: ev}
+	}
+	select {
+	case s.events <- ev:
+	case <-ctx.Done():
+	}
+}
+
+// pushToCast sends the daemon's volume and mute where they differ from the device's.
+func (s *castSession) pushToCast(ctx context.Context) error {
+	if !s.known {
+		return nil // pushed once the device reports its status
+	}
+	snap, err := requestStateSnapshot(ctx, s.events, voiceSnapshotTimeout)
+	if err != nil {
+		s.p.logger.Debug("cast: state unavailable", "error", err)
+		return nil
+	}
+	zs, ok := zoneSnapshot(snap, s.p.opts.Zone)
+	if !ok {
+		return nil
+	}
+	if zs.VolumeKnown {
+		if level := zs.VolumePercent / 100; math.Abs(level-s.level) >= castLevelTolerance {
+			req := s.request("SET_VOLUME")
+			req["volume"] = castVolume{Level: &level}
+			if err := s.send(castNSReceiver, castReceiverID, req); err != nil {
+				return err
+			}
+			s.level = level
+		}
+	}
+	if zs.MuteKnown && zs.Muted != s.muted {
+		muted := zs.Muted
+		req := s.request("SET_VOLUME")
+		req["volume"] = castVolume{Muted: &muted}
+		if err := s.send(castNSReceiver, castReceiverID, req); err != nil {
+			return err
+		}
+		s.muted = muted
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ============================================================================
+// Cast v2 wire format
+// ============================================================================
+// Cast devices speak length-prefixed (4-byte big-endian) protobuf CastMessages
+// over TLS on port 8009. Only string payloads are used, so the few fields
+// needed are encoded by hand rather than pulling in a protobuf runtime:
+//
+//	message CastMessage {
+//	  required ProtocolVersion protocol_version = 1; // CASTV2_1_0 = 0
+//	  required string source_id = 2;
+//	  required string destination_id = 3;
+//	  required string namespace = 4;
+//	  required PayloadType payload_type = 5;          // STRING = 0
+//	  optional string payload_utf8 = 6;
+//	  optional bytes payload_binary = 7;
+//	}
+// ============================================================================
+
+// maxCastMessage bounds a received message (receivers cap theirs at 64 KiB).
+const maxCastMessage = 64 << 10
+
+// castMessage is a decoded CastMessage with a string payload.
+type castMessage struct {
+	SourceID      string
+	DestinationID string
+	Namespace     string
+	Payload       string
+}
+
+// marshal encodes m as a CastMessage.
+func (m castMessage) marshal() []byte {
+	b := []byte{1<<3 | 0, 0} // protocol_version = CASTV2_1_0
+	b = appendCastString(b, 2, m.SourceID)
+	b = appendCastString(b, 3, m.DestinationID)
+	b = appendCastString(b, 4, m.Namespace)
+	b = append(b, 5<<3|0, 0) // payload_type = STRING
+	return appendCastString(b, 6, m.Payload)
+}
+
+func appendCastString(b []byte, field int, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// unmarshalCastMessage decodes a CastMessage, skipping fields it doesn't use.
+func unmarshalCastMessage(b []byte) (castMessage, error) {
+	var m castMessage
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return m, errors.New("cast message: bad field key")
+		}
+		b = b[n:]
+		field, wire := key>>3, key&7
+		switch wire {
+		case 0: // varint
+			if _, n = binary.Uvarint(b); n <= 0 {
+				return m, errors.New("cast message: bad varint")
+			}
+			b = b[n:]
+		case 2: // length-delimited
+			l, n := binary.Uvarint(b)
+			if n <= 0 || l > uint64(len(b)-n) {
+				return m, errors.New("cast message: bad length")
+			}
+			v := string(b[n : n+int(l)])
+			b = b[n+int(l):]
+			switch field {
+			case 2:
+				m.SourceID = v
+			case 3:
+				m.DestinationID = v
+			case 4:
+				m.Namespace = v
+			case 6:
+				m.Payload = v
+			}
+		default:
+			return m, fmt.Errorf("cast message: unsupported wire type %d", wire)
+		}
+	}
+	return m, nil
+}
+
+// writeCastMessage writes one framed message.
+func writeCastMessage(w io.Writer, m castMessage) error {
+	body := m.marshal()
+	frame := binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(body)), uint32(len(body)))
+	_, err := w.Write(append(frame, body...))
+	return err
+}
+
+// readCastMessage reads one framed message.
+func readCastMessage(r io.Reader) (castMessage, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return castMessage{}, err
+	}
+	n := binary.BigEndian.Uint32(hdr[:])
+	if n > maxCastMessage {
+		return castMessage{}, fmt.Errorf("cast message too large (%d bytes)", n)
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return castMessage{}, err
+	}
+	return unmarshalCastMessage(body)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCastMessage_RoundTrip(t *testing.T) {
+	m := castMessage{SourceID: "sender-0", DestinationID: "receiver-0", Namespace: castNSReceiver, Payload: `{"type":"GET_STATUS","requestId":1}`}
+	got, err := unmarshalCastMessage(m.marshal())
+	if err != nil || got != m {
+		t.Fatalf("got %+v, %v", got, err)
+	}
+	if _, err := unmarshalCastMessage([]byte{0x1a, 0x10, 'x'}); err == nil {
+		t.Fatal("expected an error for a truncated field")
+	}
+}
+
+func TestNewCastProtocol_ValidatesOptions(t *testing.T) {
+	good := decodeControlProtocolConfig(t, "type: cast\noptions:\n  address: 192.168.1.50\n  mirror: both\n")
+	p, err := newControlProtocol(good, slog.Default())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if addr := p.(*castProtocol).addr; addr != "192.168.1.50:8009" {
+		t.Fatalf("addr = %q", addr)
+	}
+	for _, src := range []string{
+		"type: cast\noptions:\n  mirror: both\n",
+		"type: cast\noptions:\n  address: 192.168.1.50\n  mirror: sideways\n",
+	} {
+		if _, err := newControlProtocol(decodeControlProtocolConfig(t, src), slog.Default()); err == nil {
+			t.Errorf("expected error for %q", src)
+		}
+	}
+}
+
+// fakeCastDevice is the device end of a session.
+type fakeCastDevice struct {
+	t    *testing.T
+	conn net.Conn
+}
+
+func (d fakeCastDevice) expect(namespace, typ string) map[string]any {
+	d.t.Helper()
+	_ = d.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		m, err := readCastMessage(d.conn)
+		if err != nil {
+			d.t.Fatalf("waiting for %s: %v", typ, err)
+		}
+		var payload map[string]any
+		_ = json.Unmarshal([]byte(m.Payload), &payload)
+		if m.Namespace == namespace && payload["type"] == typ {
+			return payload
+		}
+	}
+}
+
+func (d fakeCastDevice) send(namespace string, payload string) {
+	d.t.Helper()
+	if err := writeCastMessage(d.conn, castMessage{SourceID: castReceiverID, DestinationID: castSenderID, Namespace: namespace, Payload: payload}); err != nil {
+		d.t.Fatal(err)
+	}
+}
+
+func TestCastSession_Mirror(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan Event, 4)
+	forwarded := make(chan Event, 4)
+	go serveSnapshots(events, StateSnapshot{Zone: "main", VolumeKnown: true, VolumePercent: 30, MuteKnown: true}, forwarded)
+
+	p := &castProtocol{opts: castOptions{Mirror: castMirrorBoth}, logger: slog.Default(), push: make(chan struct{}, 1)}
+	client, server := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = p.session(ctx, client, events)
+	}()
+	dev := fakeCastDevice{t: t, conn: server}
+
+	dev.expect(castNSConnection, "CONNECT")
+	dev.expect(castNSReceiver, "GET_STATUS")
+
+	// The status reported on connect matches the daemon: nothing is mirrored.
+	dev.send(castNSReceiver, `{"type":"RECEIVER_STATUS","status":{"volume":{"level":0.3,"muted":false}}}`)
+
+	dev.send(castNSHeartbeat, `{"type":"PING"}`)
+	dev.expect(castNSHeartbeat, "PONG")
+
+	// A change on the device is mirrored to the daemon.
+	dev.send(castNSReceiver, `{"type":"RECEIVER_STATUS","status":{"volume":{"level":0.5,"muted":false}}}`)
+	if ev := <-forwarded; !reflect.DeepEqual(ev, SetVolumePercent{Percent: 50, Origin: "cast"}) {
+		t.Fatalf("got %#v", ev)
+	}
+
+	// The daemon's echo of that change is ignored; other changes push its state.
+	p.Notify(ZoneBroadcast{Zone: "main", Broadcast: BroadcastVolumeChanged{VolumeDB: -20, Origin: "cast"}})
+	if len(p.push) != 0 {
+		t.Fatal("own change scheduled a push")
+	}
+	p.Notify(ZoneBroadcast{Zone: "main", Broadcast: BroadcastVolumeChanged{VolumeDB: -40, Origin: "ir"}})
+	req := dev.expect(castNSReceiver, "SET_VOLUME")
+	if level := req["volume"].(map[string]any)["level"]; level != 0.3 {
+		t.Fatalf("SET_VOLUME level = %v", level)
+	}
+
+	cancel()
+	<-done
+}
@@ -23,12 +23,6 @@ func (c CmdSetVolume) String() string {
 	return fmt.Sprintf("CmdSetVolume(target_db=%.3f)", c.TargetDB)
 }
 
-// CmdToggleMute toggles mute in CamillaDSP (Main).
-type CmdToggleMute struct{}
-
-func (CmdToggleMute) commandMarker() {}
-func (CmdToggleMute) String() string { return "CmdToggleMute()" }
-
 // CmdSetMute sets mute explicitly in CamillaDSP (Main).
 type CmdSetMute struct {
 	Muted bool
@@ -37,6 +31,17 @@ type CmdSetMute struct {
 func (CmdSetMute) commandMarker()   {}
 func (c CmdSetMute) String() string { return fmt.Sprintf("CmdSetMute(muted=%v)", c.Muted) }
 
+// CmdSetFaderVolume sets an aux fader volume in CamillaDSP (used by balance/sub encoder modes).
+type CmdSetFaderVolume struct {
+	Fader    int
+	TargetDB float64
+}
+
+func (CmdSetFaderVolume) commandMarker() {}
+func (c CmdSetFaderVolume) String() string {
+	return fmt.Sprintf("CmdSetFaderVolume(fader=%d, target_db=%.3f)", c.Fader, c.TargetDB)
+}
+
 // CmdGetVolume requests current volume from CamillaDSP.
 type CmdGetVolume struct{}
 
@@ -62,7 +67,8 @@ func (CmdGetState) commandMarker() {}
 func (CmdGetState) String() string { return "CmdGetState()" }
 
 // CmdPublishStateSnapshot delivers a reducer-produced StateSnapshot to an external requester.
-// This keeps the reducer pure by moving the actual channel send into the effects layer.
+// This keeps the reducer pure by moving the actual channel send out of it. The daemon
+// loop delivers it directly rather than via the effects worker (no I/O involved).
 type CmdPublishStateSnapshot struct {
 	Snapshot StateSnapshot
 	Reply    chan<- StateSnapshot
@@ -70,3 +76,32 @@ type CmdPublishStateSnapshot struct {
 
 func (CmdPublishStateSnapshot) commandMarker()   {}
 func (c CmdPublishStateSnapshot) String() string { return "CmdPublishStateSnapshot()" }
+
+// CmdSelectOutput switches the active output as one sequenced operation:
+// mute -> (optional) switch config + reload -> (optional) restore volume -> (optional) unmute.
+// If any step fails the sequence stops, leaving the output muted (fail safe).
+type CmdSelectOutput struct {
+	Output     string
+	ConfigPath string   // empty keeps the current CamillaDSP config
+	VolumeDB   *float64 // nil keeps the current volume
+	Unmute     bool
+}
+
+func (CmdSelectOutput) commandMarker() {}
+
+// CmdTestSignal switches to (or, with an empty Channel, back from) a test signal config.
+// It runs the same sequence as CmdSelectOutput.
+type CmdTestSignal struct {
+	Channel    string // empty when restoring the previous config
+	ConfigPath string
+	VolumeDB   *float64
+	Unmute     bool
+}
+
+func (CmdTestSignal) commandMarker() {}
+func (c CmdTestSignal) String() string {
+	return fmt.Sprintf("CmdTestSignal(channel=%s, config=%q)", c.Channel, c.ConfigPath)
+}
+func (c CmdSelectOutput) String() string {
+	return fmt.Sprintf("CmdSelectOutput(output=%s, config=%q)", c.Output, c.ConfigPath)
+}
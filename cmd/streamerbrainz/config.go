@@ -4,8 +4,17 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -21,32 +30,139 @@ import (
 // - Keep flags for small overrides and for environments where a file is awkward.
 // - Preserve current defaults for existing users where practical.
 type Config struct {
-	// Inputs configuration (generic input devices, e.g. keyboards, IR remotes, rotary encoders)
+	// Version is the config schema version (see currentConfigVersion). Files without
+	// one are accepted; legacy keys are migrated with a deprecation warning.
+	Version int `yaml:"version"`
+
+	// Inputs configuration (generic input devices, e.g. keyboards, IR remotes, rotary encoders).
+	// May be empty (`inputs: []`): the daemon then only bridges IPC/HTTP/WebSocket
+	// control to CamillaDSP.
 	Inputs []InputDevice `yaml:"inputs"`
 
+	// InputsOptional starts without input devices that can't be opened (with a
+	// warning) instead of exiting, and keeps retrying them like a disconnected device,
+	// e.g. when the remote's receiver is not always plugged in.
+	InputsOptional bool `yaml:"inputs_optional"`
+
+	// InputReader selects how input devices are read: "epoll" (default; Linux) or
+	// "goroutine" (one reader per device; portable fallback).
+	InputReader string `yaml:"input_reader"`
+
+	// Diagnostics controls daemon loop timing warnings (histograms are always on /metrics)
+	// and where crash dumps are written.
+	Diagnostics DiagnosticsConfig `yaml:"diagnostics"`
+
+	// InputReconnect controls how failed input devices are reopened.
+	InputReconnect InputReconnectConfig `yaml:"input_reconnect"`
+
 	// CamillaDSP control configuration
 	CamillaDSP CamillaDSPConfig `yaml:"camilladsp"`
 
+	// Zones configures multiple CamillaDSP instances managed by one daemon
+	// (e.g. speakers + headphone amp). Fields left unset inherit from `camilladsp`.
+	// If empty, a single zone named "main" is built from `camilladsp`.
+	Zones []ZoneConfig `yaml:"zones,omitempty"`
+
+	// DefaultZone is the zone IR/rotary/unzoned events control at startup.
+	// Empty selects the first zone.
+	DefaultZone string `yaml:"default_zone,omitempty"`
+
+	// ZoneLinks is the initial volume link group: zone id -> offset (dB).
+	// Linked zones mirror each other's volume changes; change at runtime via
+	// link_zone / unlink_zone events.
+	ZoneLinks map[string]float64 `yaml:"zone_links,omitempty"`
+
 	// Velocity engine configuration
 	Velocity VelocityFileConfig `yaml:"velocity"`
 
+	// Modifier+volume-key combinations (e.g. Shift+VolUp) for fine/coarse steps or presets.
+	KeyCombos []KeyComboConfig `yaml:"key_combos,omitempty"`
+
+	// Mute-aware volume gesture policy
+	Mute MuteConfig `yaml:"mute"`
+
+	// Arbitration between simultaneous volume control sources
+	Arbitration ArbitrationConfig `yaml:"arbitration"`
+
+	// Fading the volume in after startup / CamillaDSP reconnects
+	FadeIn FadeInConfig `yaml:"fade_in"`
+
+	// What to leave CamillaDSP at when the daemon exits
+	Shutdown ShutdownConfig `yaml:"shutdown"`
+
+	// Calibration (reference level) mode
+	Calibration CalibrationConfig `yaml:"calibration"`
+
+	// Test signals (pink noise / tone) for speaker checks
+	TestSignal TestSignalConfig `yaml:"test_signal"`
+
+	// Per-track loudness normalization from ReplayGain / Plex loudness data
+	Normalization NormalizationConfig `yaml:"normalization"`
+
+	// Temporarily lifting camilladsp.user_min_db/user_max_db (limit_override events)
+	LimitOverride LimitOverrideConfig `yaml:"limit_override"`
+
+	// Timing of ui_hint broadcasts (volume overlay, mute flash) for displays
+	UIHints UIHintsConfig `yaml:"ui_hints"`
+
+	// Outputs (e.g. speakers/headphones) selectable via output_select / KEY_AUDIO.
+	// Applies to every zone.
+	Outputs []OutputConfig `yaml:"outputs,omitempty"`
+
 	// IPC configuration (used by librespot hook integration)
 	IPC IPCConfig `yaml:"ipc"`
 
 	// Webhooks server configuration
 	Webhooks WebhooksConfig `yaml:"webhooks"`
 
+	// Control API server configuration (state WS/SSE, event injection).
+	// When api.port is 0 the API shares the webhooks listener.
+	API APIConfig `yaml:"api"`
+
 	// WebSocket server configuration (state updates / UI clients)
 	WebSocket WebSocketConfig `yaml:"websocket"`
 
+	// OSC (Open Sound Control) UDP server for touchscreen/DAW controllers
+	OSC OSCConfig `yaml:"osc"`
+
+	// LED ring / PWM LED volume indicator
+	LED LEDConfig `yaml:"led"`
+
+	// IR transmitter for forwarding commands to an amplifier
+	IRTx IRTxConfig `yaml:"ir_tx"`
+
+	// Push notifications for faults (ntfy, Pushover, webhook)
+	Alerts AlertsConfig `yaml:"alerts"`
+
+	// Opt-in check for newer GitHub releases
+	UpdateCheck UpdateCheckConfig `yaml:"update_check"`
+
+	// Control protocol plugins (see control_protocol.go), e.g. udp_text.
+	ControlProtocols []ControlProtocolConfig `yaml:"control_protocols,omitempty"`
+
+	// Outbound webhooks (POST state changes to user-configured URLs)
+	OutboundWebhooks []OutboundWebhookConfig `yaml:"outbound_webhooks,omitempty"`
+
 	// Plex integration
 	Plex PlexConfig `yaml:"plex"`
 
+	// Tidal Connect now-playing metadata (see tidal_connect.go)
+	TidalConnect TidalConnectConfig `yaml:"tidal_connect"`
+
+	// Scrobbling of played tracks to ListenBrainz / Last.fm (see scrobble.go)
+	Scrobble ScrobbleConfig `yaml:"scrobble"`
+
+	// Listening statistics (see stats.go)
+	Stats StatsConfig `yaml:"stats"`
+
 	// Rotary encoder configuration
 	Rotary RotaryConfig `yaml:"rotary"`
 
 	// Logging
 	Logging LoggingConfig `yaml:"logging"`
+
+	// deprecations collects warnings for migrated legacy keys, logged at startup.
+	deprecations []string
 }
 
 // InputDeviceType describes how to interpret events from an input device
@@ -55,327 +171,2171 @@ type InputDeviceType string
 const (
 	InputDeviceTypeKey    InputDeviceType = "key"    // EV_KEY events (IR remotes, keyboards)
 	InputDeviceTypeRotary InputDeviceType = "rotary" // EV_REL events (rotary encoders)
+	InputDeviceTypeFifo   InputDeviceType = "fifo"   // newline-delimited event envelopes (named pipe, or "-" for stdin)
+	InputDeviceTypeHotkey InputDeviceType = "hotkey" // keyboard media keys (Windows, macOS; path unused)
 )
 
 // InputDevice describes a single input device with its path and type
 type InputDevice struct {
 	Path string          `yaml:"path"` // Device path (e.g., /dev/input/event6)
-	Type InputDeviceType `yaml:"type"` // Device type: "key" or "rotary"
+	Type InputDeviceType `yaml:"type"` // Device type: "key", "rotary", "fifo" or "hotkey"
+
+	// Keymap binds this device's key codes to actions (see keymap.go); unmapped
+	// keys keep their default meaning. `streamerbrainz learn` writes it.
+	Keymap []KeyBinding `yaml:"keymap,omitempty"`
+
+	// RepeatIntervalMS is how often the device repeats a held key, for remotes that
+	// repeat slower than velocity.hold_timeout_ms allows or send a release per repeat
+	// (see repeat_interval.go; 0 = off). AutoRepeatInterval measures it instead.
+	RepeatIntervalMS   int  `yaml:"repeat_interval_ms,omitempty"`
+	AutoRepeatInterval bool `yaml:"auto_repeat_interval,omitempty"`
+
+	// NoRelease reads the volume keys of a remote that never sends a release:
+	// "step" (each press/repeat steps by StepDB; 0 = the default step) or "release"
+	// (the release is synthesized when the repeats stop); see no_release.go.
+	NoRelease string  `yaml:"no_release,omitempty"`
+	StepDB    float64 `yaml:"step_db,omitempty"`
 }
 
 type CamillaDSPConfig struct {
-	WsURL      string  `yaml:"ws_url"`
-	TimeoutMS  int     `yaml:"timeout_ms"`
-	MinDB      float64 `yaml:"min_db"`
-	MaxDB      float64 `yaml:"max_db"`
-	UpdateHz   int     `yaml:"update_hz"`
-	RampUpMS   int     `yaml:"ramp_up_ms,omitempty"`   // optional: if you want to document it alongside config
-	RampDownMS int     `yaml:"ramp_down_ms,omitempty"` // optional
+	WsURL     string `yaml:"ws_url"`
+	TimeoutMS int    `yaml:"timeout_ms"`
+	// CommandTimeoutsMS overrides timeout_ms per command, e.g. {GetConfigJson: 5000}
+	// (see camilladsp_deadlines.go for the built-in ones).
+	CommandTimeoutsMS map[string]int `yaml:"command_timeouts_ms,omitempty"`
+	MinDB             float64        `yaml:"min_db"`
+	MaxDB             float64        `yaml:"max_db"`
+	UpdateHz          int            `yaml:"update_hz"`
+	IdleHz            int            `yaml:"idle_hz"`  // tick rate while nothing is moving (0 = always update_hz)
+	Pipeline          bool           `yaml:"pipeline"` // send queued commands back to back instead of one round trip each
+	// KeepaliveSec pings the control connection after this long idle and re-dials
+	// if it doesn't answer (0 = off; see camilladsp_keepalive.go).
+	KeepaliveSec int `yaml:"keepalive_sec"`
+	// Reconnect paces connect attempts (see camilladsp_reconnect.go).
+	Reconnect CamillaDSPReconnectConfig `yaml:"reconnect"`
+	// WakeOnLAN wakes a CamillaDSP host that sleeps (see wake_on_lan.go).
+	WakeOnLAN WakeOnLANConfig `yaml:"wake_on_lan,omitempty"`
+	// MonitorHz polls signal levels and faders this often over a second connection,
+	// broadcast as signal_levels frames (0 = off; see camilladsp_monitor.go).
+	MonitorHz int `yaml:"monitor_hz"`
+	// TLS and auth for a CamillaDSP behind a wss:// proxy (see camilladsp_dial.go).
+	CAFile             string            `yaml:"ca_file,omitempty"`              // PEM bundle trusted besides the system roots
+	InsecureSkipVerify bool              `yaml:"insecure_skip_verify,omitempty"` // accept any certificate
+	Username           string            `yaml:"username,omitempty"`             // HTTP basic auth on the upgrade request
+	PasswordFile       string            `yaml:"password_file,omitempty"`        // secret source for the basic auth password
+	Password           string            `yaml:"password,omitempty"`             // deprecated: literal password, use password_file
+	Headers            map[string]string `yaml:"headers,omitempty"`              // extra upgrade request headers
+	// Volume sent to CamillaDSP is rounded to step_db (0 = full precision); broadcast and
+	// snapshot volumes are rounded to display_step_db.
+	StepDB        float64 `yaml:"step_db"`
+	DisplayStepDB float64 `yaml:"display_step_db"`
+	// Optional user limits enforced on every source, inside min_db/max_db (which clamp
+	// what is sent to CamillaDSP). A limit_override event lifts them temporarily.
+	UserMinDB *float64 `yaml:"user_min_db,omitempty"`
+	UserMaxDB *float64 `yaml:"user_max_db,omitempty"`
+	// Absolute sets that raise (lower) the volume fade over ramp_up_ms (ramp_down_ms),
+	// however far they go; 0 falls back to velocity.ramp_db_per_sec. The fade is
+	// stepped by the daemon (CamillaDSP has no per-call ramp), so keep
+	// devices.volume_ramp_time short.
+	RampUpMS   int `yaml:"ramp_up_ms,omitempty"`
+	RampDownMS int `yaml:"ramp_down_ms,omitempty"`
 }
 
-type IPCConfig struct {
-	SocketPath string `yaml:"socket_path"`
-}
+// CamillaDSPReconnectConfig controls retries when CamillaDSP can't be reached.
+type CamillaDSPReconnectConfig struct {
+	// Attempts bounds dial attempts per (re)connect.
+	Attempts int `yaml:"attempts"`
 
-type WebhooksConfig struct {
-	Port int `yaml:"port"`
+	// IntervalMS is the wait after the first failed attempt.
+	IntervalMS int `yaml:"interval_ms"`
+
+	// BackoffFactor multiplies the wait after each further failure (1 = fixed interval).
+	BackoffFactor float64 `yaml:"backoff_factor"`
+
+	// MaxBackoffMS caps the wait.
+	MaxBackoffMS int `yaml:"max_backoff_ms"`
+
+	// WaitAtStartup blocks daemon startup, retrying forever, until CamillaDSP
+	// answers, instead of starting disconnected (see camilladsp_startup.go).
+	WaitAtStartup bool `yaml:"wait_at_startup"`
 }
 
-type WebSocketConfig struct {
-	// SendBuf is the per-client outbound queue size. Slow clients are disconnected
-	// if they can't keep up and this buffer fills.
-	SendBuf int `yaml:"send_buf"`
+// KeyComboConfig maps a modifier set + volume key to a step size or a preset volume.
+type KeyComboConfig struct {
+	Modifiers []string `yaml:"modifiers"` // shift | ctrl | alt | meta (all must be held, no others)
+	Key       string   `yaml:"key"`       // volume_up | volume_down
 
-	// BroadcastBuf is the hub inbound broadcast queue size (frames waiting to be
-	// fanned out to clients).
-	BroadcastBuf int `yaml:"broadcast_buf"`
+	// Exactly one of:
+	StepDB   float64  `yaml:"step_db,omitempty"`   // dB per press/autorepeat
+	VolumeDB *float64 `yaml:"volume_db,omitempty"` // jump to this volume (preset)
 }
 
-type PlexConfig struct {
-	ServerURL  string `yaml:"server_url"`
-	TokenFile  string `yaml:"token_file"`
-	MachineID  string `yaml:"machine_id"`
-	Enabled    bool   `yaml:"enabled"`
-	BindLocal  bool   `yaml:"bind_local,omitempty"`  // optional hardening knob for future
-	AllowCIDRs []any  `yaml:"allow_cidrs,omitempty"` // placeholder for future; keep as any to avoid committing to a format
+// modifierMask returns the combo's modifier set (unknown names are rejected by Validate).
+func (k KeyComboConfig) modifierMask() modifierMask {
+	var m modifierMask
+	for _, name := range k.Modifiers {
+		m |= modifierNames[strings.ToLower(name)]
+	}
+	return m
 }
 
-type LoggingConfig struct {
-	Level string `yaml:"level"`
+// InputReconnectConfig controls reopening of input devices after read errors/unplugs.
+// Retries back off exponentially; hotplug events (udev) trigger an immediate retry.
+type InputReconnectConfig struct {
+	// MaxRetries bounds backoff retries before waiting for hotplug only (0 = retry forever).
+	MaxRetries int `yaml:"max_retries"`
+
+	// InitialBackoffMS is the delay before the first reopen attempt.
+	InitialBackoffMS int `yaml:"initial_backoff_ms"`
+
+	// MaxBackoffMS caps the exponential backoff delay.
+	MaxBackoffMS int `yaml:"max_backoff_ms"`
 }
 
-// VelocityFileConfig is the user-facing velocity configuration as represented in YAML.
-//
-// It maps 1:1 to VelocityConfig used by the engine, but uses YAML-friendly types.
-// (e.g., hold timeout is represented in milliseconds).
-type VelocityFileConfig struct {
-	Mode string `yaml:"mode"` // "accelerating" or "constant"
+// MuteConfig controls how volume gestures (hold/rotary/step) behave while muted.
+type MuteConfig struct {
+	// UnmuteOnVolumeUp makes volume-up while muted unmute instead of silently raising the level.
+	UnmuteOnVolumeUp bool `yaml:"unmute_on_volume_up"`
 
-	// Shared:
-	// - accelerating: max velocity (dB/s)
-	// - constant: base hold rate (dB/s)
-	MaxDBPerSec float64 `yaml:"max_db_per_sec"`
+	// RestoreVolume restores the volume observed when mute engaged on such an unmute.
+	RestoreVolume bool `yaml:"restore_volume"`
 
-	// Accelerating-mode only:
-	AccelTimeSec float64 `yaml:"accel_time_sec,omitempty"`
-	DecayTauSec  float64 `yaml:"decay_tau_sec,omitempty"`
+	// VolumeDownWhileMuted is "adjust" (lower the stored level) or "ignore".
+	VolumeDownWhileMuted string `yaml:"volume_down_while_muted"`
+}
 
-	// Constant-mode turbo:
-	TurboMult  float64 `yaml:"turbo_mult,omitempty"`
-	TurboDelay float64 `yaml:"turbo_delay_sec,omitempty"`
+// FadeInConfig ramps the volume up from the minimum when CamillaDSP's level is
+// (re)adopted, instead of playing at it straight away (see fade_in.go).
+type FadeInConfig struct {
+	Enabled bool `yaml:"enabled"`
 
-	// Hold behavior:
-	HoldTimeoutMS int `yaml:"hold_timeout_ms"`
+	// DurationMS is how long the fade from min_db to the restored level takes.
+	DurationMS int `yaml:"duration_ms"`
 
-	// Danger zone (near max volume, ramp-up only):
-	DangerZoneDB            float64 `yaml:"danger_zone_db"`
-	DangerVelMaxDBPerSec    float64 `yaml:"danger_vel_max_db_per_sec"`
-	DangerVelMinNear0DBPerS float64 `yaml:"danger_vel_min_near0_db_per_sec"`
+	// OnReconnect also fades in when CamillaDSP answers again after being unreachable.
+	OnReconnect bool `yaml:"on_reconnect"`
 }
 
-// RotaryConfig contains rotary encoder-specific configuration
-type RotaryConfig struct {
-	DbPerStep          float64 `yaml:"db_per_step"`         // dB change per encoder step
-	VelocityWindowMS   int     `yaml:"velocity_window_ms"`  // Time window for velocity detection (ms)
-	VelocityMultiplier float64 `yaml:"velocity_multiplier"` // Multiplier for "fast spinning"
-	VelocityThreshold  int     `yaml:"velocity_threshold"`  // Steps in window to trigger velocity mode
+// ShutdownConfig makes CamillaDSP safe when the daemon exits (see shutdown.go).
+type ShutdownConfig struct {
+	// Action is "none", "mute" or "volume" (lower to VolumeDB if above it).
+	Action   string  `yaml:"action"`
+	VolumeDB float64 `yaml:"volume_db"`
 }
 
-// DefaultConfig returns a fully-populated Config with defaults.
-// Keep this aligned with constants.go defaults and current CLI defaults.
-func DefaultConfig() Config {
-	return Config{
-		Inputs: []InputDevice{
-			{Path: "/dev/input/event6", Type: InputDeviceTypeKey},
-		},
-		CamillaDSP: CamillaDSPConfig{
-			WsURL:     "ws://127.0.0.1:1234",
-			TimeoutMS: defaultReadTimeoutMS,
-			MinDB:     -65.0,
-			MaxDB:     0.0,
-			UpdateHz:  defaultUpdateHz,
-		},
-		Velocity: VelocityFileConfig{
-			Mode:                    string(VelocityModeAccelerating),
-			MaxDBPerSec:             defaultVelMaxDBPerS,
-			AccelTimeSec:            defaultAccelTime,
-			DecayTauSec:             defaultDecayTau,
-			TurboMult:               1.0,
-			TurboDelay:              0.0,
-			HoldTimeoutMS:           600,
-			DangerZoneDB:            dangerZoneDB,
-			DangerVelMaxDBPerSec:    dangerVelMaxDBPerS,
-			DangerVelMinNear0DBPerS: dangerVelMinNear0DBPerS,
-		},
-		IPC: IPCConfig{
-			SocketPath: "/tmp/streamerbrainz.sock",
-		},
-		Webhooks: WebhooksConfig{
-			Port: 3001,
-		},
-		WebSocket: WebSocketConfig{
-			SendBuf:      32,
-			BroadcastBuf: 128,
-		},
-		Plex: PlexConfig{
-			Enabled:   false,
-			ServerURL: "",
-			TokenFile: "",
-			MachineID: "",
-		},
-		Rotary: RotaryConfig{
-			DbPerStep:          defaultRotaryDbPerStep,
-			VelocityWindowMS:   defaultRotaryVelocityWindowMS,
-			VelocityMultiplier: defaultRotaryVelocityMultiplier,
-			VelocityThreshold:  defaultRotaryVelocityThreshold,
-		},
-		Logging: LoggingConfig{
-			Level: "info",
-		},
+// duration is the fade length, 0 when disabled.
+func (f FadeInConfig) duration() time.Duration {
+	if !f.Enabled {
+		return 0
 	}
+	return time.Duration(f.DurationMS) * time.Millisecond
 }
 
-// LoadConfigFile reads and parses a YAML config file.
-//
-// Notes:
-//   - The file must be valid YAML.
-//   - Unknown fields are rejected (helps catch typos) via KnownFields(true).
-//   - Relative paths inside the config (like plex token file) are not rewritten here;
-//     handle that in validation or in the call site as needed.
-func LoadConfigFile(path string) (Config, error) {
-	if path == "" {
-		return Config{}, errors.New("config path is empty")
-	}
-	b, err := os.ReadFile(ExpandPath(path))
-	if err != nil {
-		return Config{}, fmt.Errorf("read config file: %w", err)
-	}
+// ArbitrationConfig decides which source wins when several change the volume at
+// once (see arbitration.go).
+type ArbitrationConfig struct {
+	// Policy is "none" (every intent applies), "physical" (physical controls lock
+	// out other sources) or "last_writer" (the last source to change the volume
+	// locks out the others).
+	Policy string `yaml:"policy"`
 
-	cfg := DefaultConfig()
+	// LockoutMS is how long the controlling source keeps the lock after its last input.
+	LockoutMS int `yaml:"lockout_ms"`
 
-	dec := yaml.NewDecoder(bytes.NewReader(b))
-	dec.KnownFields(true)
+	// PhysicalOrigins lists the volume origins treated as physical controls by the
+	// "physical" policy (e.g. add "ipc:argon-ctl" for a knob driven over IPC).
+	PhysicalOrigins []string `yaml:"physical_origins,omitempty"`
+}
 
-	if err := dec.Decode(&cfg); err != nil {
-		return Config{}, fmt.Errorf("decode config yaml: %w", err)
-	}
+// CalibrationConfig configures calibration mode (see calibration.go).
+type CalibrationConfig struct {
+	// ReferenceDB is the level calibration mode pins the volume to.
+	ReferenceDB float64 `yaml:"reference_db"`
 
-	// Ensure there's no trailing garbage (only whitespace/comments are allowed after the document).
-	if err := dec.Decode(&struct{}{}); err == nil {
-		return Config{}, fmt.Errorf("decode config yaml: unexpected trailing document")
-	}
+	// LongPressKey enters/exits calibration mode when held for LongPressMS
+	// ("mute", "audio", "play_pause", "stop", "button"; empty disables). A short
+	// press of the key keeps its normal action, triggered on release.
+	LongPressKey string `yaml:"long_press_key"`
+	LongPressMS  int    `yaml:"long_press_ms"`
 
-	return cfg, nil
+	// RestoreOnExit returns to the volume from before the session when exiting.
+	RestoreOnExit bool `yaml:"restore_on_exit"`
 }
 
-// Validate checks config invariants and returns a user-friendly error.
-// This is intended to be called after defaults + file + overrides are applied.
-func (c *Config) Validate() error {
-	// Inputs
-	if len(c.Inputs) == 0 {
-		return errors.New("inputs must not be empty")
-	}
+// UIHintsConfig sets how long displays show reducer-issued ui_hint feedback
+// (see ui_hints.go). 0 disables a hint.
+type UIHintsConfig struct {
+	VolumeOverlayMS int `yaml:"volume_overlay_ms"`
+	MuteFlashMS     int `yaml:"mute_flash_ms"`
+}
 
-	// Validate all input devices
-	for i, dev := range c.Inputs {
-		if dev.Path == "" {
-			return fmt.Errorf("inputs[%d].path is empty", i)
-		}
-		if dev.Type == "" {
-			return fmt.Errorf("inputs[%d].type is empty", i)
-		}
-		if dev.Type != InputDeviceTypeKey && dev.Type != InputDeviceTypeRotary {
-			return fmt.Errorf("inputs[%d].type must be %q or %q", i, InputDeviceTypeKey, InputDeviceTypeRotary)
-		}
-	}
+// TestSignalConfig configures test_signal events (see test_signal.go).
+type TestSignalConfig struct {
+	// Channels are the selectable test configs (e.g. left, right, sub).
+	Channels []TestSignalChannelConfig `yaml:"channels,omitempty"`
 
-	// CamillaDSP
-	if c.CamillaDSP.WsURL == "" {
-		return errors.New("camilladsp.ws_url must not be empty")
-	}
-	if c.CamillaDSP.TimeoutMS <= 0 {
-		return errors.New("camilladsp.timeout_ms must be > 0")
-	}
-	if c.CamillaDSP.MinDB > c.CamillaDSP.MaxDB {
-		return errors.New("camilladsp.min_db must be <= camilladsp.max_db")
-	}
-	if c.CamillaDSP.UpdateHz <= 0 || c.CamillaDSP.UpdateHz > 1000 {
-		return errors.New("camilladsp.update_hz must be between 1 and 1000")
-	}
+	// LevelDB is the volume a test signal plays at.
+	LevelDB float64 `yaml:"level_db"`
 
-	// Velocity
-	mode := c.Velocity.Mode
-	if mode == "" {
-		mode = string(VelocityModeAccelerating)
+	// DurationSec is how long a test signal plays unless the event says otherwise.
+	DurationSec int `yaml:"duration_sec"`
+}
+
+// NormalizationConfig applies per-track loudness data as a gain offset on an aux
+// fader (see normalization.go).
+type NormalizationConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Fader is the CamillaDSP aux fader (1-4) driving a Volume filter in the pipeline.
+	Fader int `yaml:"fader"`
+
+	// Mode picks the track or album gain ("track" by default; album falls back to track).
+	Mode string `yaml:"mode"`
+
+	// PreampDB is added to the tagged gain (ReplayGain aims at about -18 LUFS).
+	PreampDB float64 `yaml:"preamp_db"`
+
+	// MaxBoostDB/MaxCutDB bound the offset.
+	MaxBoostDB float64 `yaml:"max_boost_db"`
+	MaxCutDB   float64 `yaml:"max_cut_db"`
+
+	// PreventClipping limits boosts so the tagged peak stays at or below full scale.
+	PreventClipping bool `yaml:"prevent_clipping"`
+}
+
+// TestSignalChannelConfig is one test signal: a CamillaDSP config producing noise or a
+// tone on the channel(s) under test.
+type TestSignalChannelConfig struct {
+	ID         string `yaml:"id"`
+	ConfigPath string `yaml:"config_path"`
+}
+
+// LimitOverrideConfig controls limit_override events, which lift the user volume
+// limits for calibration sessions.
+type LimitOverrideConfig struct {
+	// TokenFile is the source (see readSecret) of the token a limit_override event
+	// must carry. Empty disables overrides.
+	TokenFile string `yaml:"token_file"`
+
+	// TimeoutSec is the longest an override lasts before the limits are restored.
+	TimeoutSec int `yaml:"timeout_sec"`
+}
+
+// OutputConfig describes one selectable output.
+type OutputConfig struct {
+	ID string `yaml:"id"`
+
+	// ConfigPath is the CamillaDSP config file activated for this output.
+	// Empty keeps the current config (mute/volume handling only).
+	ConfigPath string `yaml:"config_path,omitempty"`
+
+	// VolumeDB is the volume restored the first time this output is selected.
+	// Afterwards the last volume used on this output is restored. Nil keeps the current volume.
+	VolumeDB *float64 `yaml:"volume_db,omitempty"`
+}
+
+// defaultZoneID is the zone id used when no zones are configured.
+const defaultZoneID = "main"
+
+// ZoneConfig describes one CamillaDSP instance (zone).
+// Unset fields inherit from the top-level camilladsp block.
+type ZoneConfig struct {
+	ID        string   `yaml:"id"`
+	WsURL     string   `yaml:"ws_url"`
+	TimeoutMS int      `yaml:"timeout_ms,omitempty"`
+	MinDB     *float64 `yaml:"min_db,omitempty"`
+	MaxDB     *float64 `yaml:"max_db,omitempty"`
+	UpdateHz  int      `yaml:"update_hz,omitempty"`
+	UserMinDB *float64 `yaml:"user_min_db,omitempty"`
+	UserMaxDB *float64 `yaml:"user_max_db,omitempty"`
+
+	// WakeOnLAN replaces the top-level camilladsp.wake_on_lan (a zone's DSP may
+	// run on its own host).
+	WakeOnLAN *WakeOnLANConfig `yaml:"wake_on_lan,omitempty"`
+}
+
+// resolve materializes a zone's CamillaDSP config on top of base.
+func (z ZoneConfig) resolve(base CamillaDSPConfig) CamillaDSPConfig {
+	out := base
+	if z.WsURL != "" {
+		out.WsURL = z.WsURL
 	}
-	if mode != string(VelocityModeAccelerating) && mode != string(VelocityModeConstant) {
-		return fmt.Errorf("velocity.mode must be %q or %q", VelocityModeAccelerating, VelocityModeConstant)
+	if z.TimeoutMS != 0 {
+		out.TimeoutMS = z.TimeoutMS
 	}
-	if c.Velocity.MaxDBPerSec < 0 {
-		return errors.New("velocity.max_db_per_sec must be >= 0")
+	if z.MinDB != nil {
+		out.MinDB = *z.MinDB
 	}
-	if c.Velocity.HoldTimeoutMS < 0 {
-		return errors.New("velocity.hold_timeout_ms must be >= 0")
+	if z.MaxDB != nil {
+		out.MaxDB = *z.MaxDB
 	}
-	if c.Velocity.DangerZoneDB < 0 {
-		return errors.New("velocity.danger_zone_db must be >= 0")
+	if z.UpdateHz != 0 {
+		out.UpdateHz = z.UpdateHz
 	}
-	if c.Velocity.DangerVelMaxDBPerSec < 0 {
-		return errors.New("velocity.danger_vel_max_db_per_sec must be >= 0")
+	if z.UserMinDB != nil {
+		out.UserMinDB = z.UserMinDB
 	}
-	if c.Velocity.DangerVelMinNear0DBPerS < 0 {
-		return errors.New("velocity.danger_vel_min_near0_db_per_sec must be >= 0")
+	if z.UserMaxDB != nil {
+		out.UserMaxDB = z.UserMaxDB
 	}
-	if c.Velocity.DangerVelMinNear0DBPerS > c.Velocity.DangerVelMaxDBPerSec {
-		return errors.New("velocity.danger_vel_min_near0_db_per_sec must be <= velocity.danger_vel_max_db_per_sec")
+	if z.WakeOnLAN != nil {
+		out.WakeOnLAN = *z.WakeOnLAN
 	}
+	return out
+}
 
-	// Plex
-	if c.Plex.Enabled {
-		if c.Plex.ServerURL == "" {
-			return errors.New("plex.enabled is true but plex.server_url is empty")
-		}
-		if c.Plex.TokenFile == "" {
-			return errors.New("plex.enabled is true but plex.token_file is empty")
-		}
-		if c.Plex.MachineID == "" {
-			return errors.New("plex.enabled is true but plex.machine_id is empty")
-		}
-	}
+// ZoneTarget is a fully-resolved zone: id plus its CamillaDSP config.
+type ZoneTarget struct {
+	ID         string
+	CamillaDSP CamillaDSPConfig
+}
 
-	// WebSocket
-	if c.WebSocket.SendBuf <= 0 {
-		return errors.New("websocket.send_buf must be > 0")
+// ZoneTargets returns the effective list of zones (at least one).
+func (c *Config) ZoneTargets() []ZoneTarget {
+	if len(c.Zones) == 0 {
+		return []ZoneTarget{{ID: defaultZoneID, CamillaDSP: c.CamillaDSP}}
 	}
-	if c.WebSocket.BroadcastBuf <= 0 {
-		return errors.New("websocket.broadcast_buf must be > 0")
+	out := make([]ZoneTarget, 0, len(c.Zones))
+	for _, z := range c.Zones {
+		out = append(out, ZoneTarget{ID: z.ID, CamillaDSP: z.resolve(c.CamillaDSP)})
 	}
+	return out
+}
 
-	// Rotary encoder
-	if c.Rotary.DbPerStep < 0 {
-		return errors.New("rotary.db_per_step must be >= 0")
-	}
-	if c.Rotary.VelocityWindowMS < 0 {
-		return errors.New("rotary.velocity_window_ms must be >= 0")
-	}
-	if c.Rotary.VelocityMultiplier < 1 {
-		return errors.New("rotary.velocity_multiplier must be >= 1")
-	}
-	if c.Rotary.VelocityThreshold < 1 {
-		return errors.New("rotary.velocity_threshold must be >= 1")
+// InitialZone returns the zone id that unzoned inputs control at startup.
+func (c *Config) InitialZone() string {
+	if c.DefaultZone != "" {
+		return c.DefaultZone
 	}
+	return c.ZoneTargets()[0].ID
+}
 
-	// Logging
-	if c.Logging.Level == "" {
-		return errors.New("logging.level must not be empty")
-	}
+type IPCConfig struct {
+	SocketPath string `yaml:"socket_path"`
+}
 
-	return nil
+type WebhooksConfig struct {
+	Port int `yaml:"port"`
+
+	// BindAddress is the interface address to listen on (e.g. "192.168.1.10").
+	// Empty binds all interfaces.
+	BindAddress string `yaml:"bind_address,omitempty"`
+
+	// Event enables POST /webhooks/event for injecting arbitrary events over HTTP.
+	Event EventWebhookConfig `yaml:"event"`
 }
 
-// ToVelocityConfig converts file config + CamillaDSP bounds into the internal engine config.
-func (c *Config) ToVelocityConfig() VelocityConfig {
-	cfg := VelocityConfig{
-		Mode: VelocityMode(c.Velocity.Mode),
+// ListenAddr returns the host:port the webhooks server listens on.
+func (w WebhooksConfig) ListenAddr() string {
+	return net.JoinHostPort(w.BindAddress, strconv.Itoa(w.Port))
+}
+
+// APIConfig configures the optional dedicated control API listener.
+type APIConfig struct {
+	// Port for the control API. 0 serves the API on the webhooks listener.
+	Port int `yaml:"port"`
 
-		VelMaxDBPerS: c.Velocity.MaxDBPerSec,
+	// BindAddress is the interface address to listen on (e.g. "127.0.0.1").
+	// Empty binds all interfaces.
+	BindAddress string `yaml:"bind_address,omitempty"`
 
-		MinDB: c.CamillaDSP.MinDB,
-		MaxDB: c.CamillaDSP.MaxDB,
+	// Socket additionally serves the API on a Unix domain socket at this path, e.g.
+	// for a reverse proxy (nginx, caddy) without exposing a TCP port. With port 0
+	// the socket serves the shared webhooks/API mux.
+	Socket string `yaml:"socket,omitempty"`
 
-		HoldTimeout: time.Duration(c.Velocity.HoldTimeoutMS) * time.Millisecond,
+	// SocketMode is the octal file mode of Socket (default "0660").
+	SocketMode string `yaml:"socket_mode,omitempty"`
 
-		DangerZoneDB:            c.Velocity.DangerZoneDB,
-		DangerVelMaxDBPerS:      c.Velocity.DangerVelMaxDBPerSec,
-		DangerVelMinNear0DBPerS: c.Velocity.DangerVelMinNear0DBPerS,
-	}
+	// BasePath serves all HTTP/WS routes (webhooks included) under this prefix,
+	// e.g. "/streamer" behind a reverse proxy. Empty serves them at the root.
+	BasePath string `yaml:"base_path,omitempty"`
 
-	// Mode-specific mapping
-	switch cfg.Mode {
-	case VelocityModeConstant:
-		cfg.AccelTime = c.Velocity.TurboMult // turbo multiplier
-		cfg.DecayTau = c.Velocity.TurboDelay // turbo delay (seconds)
-	default:
-		cfg.Mode = VelocityModeAccelerating
-		cfg.AccelTime = c.Velocity.AccelTimeSec
-		cfg.DecayTau = c.Velocity.DecayTauSec
-	}
+	// TrustedProxies lists reverse proxy IPs or CIDRs whose X-Forwarded-For,
+	// X-Forwarded-Proto and X-Forwarded-Host headers are honored (see http_proxy.go).
+	TrustedProxies []string `yaml:"trusted_proxies,omitempty"`
 
-	return cfg
+	// CORS lets browser pages from other origins call the REST and SSE endpoints.
+	CORS CORSConfig `yaml:"cors"`
 }
 
-// ExpandPath expands a leading "~" in a path using $HOME.
-// This is handy for config values like plex.token_file.
-func ExpandPath(p string) string {
-	if p == "" {
-		return p
-	}
-	if p[0] != '~' {
+// CORSConfig configures cross-origin access to the control API.
+type CORSConfig struct {
+	// AllowedOrigins lists permitted browser origins (e.g. "http://homeassistant.local:8123").
+	// "*" allows any origin (not with allow_credentials). Empty disables CORS.
+	AllowedOrigins []string `yaml:"allowed_origins,omitempty"`
+
+	// AllowedMethods defaults to GET, POST, PUT.
+	AllowedMethods []string `yaml:"allowed_methods,omitempty"`
+
+	// AllowedHeaders are the request headers a page may send. Defaults to
+	// Content-Type, Authorization and X-StreamerBrainz-Token.
+	AllowedHeaders []string `yaml:"allowed_headers,omitempty"`
+
+	// AllowCredentials lets pages send cookies / HTTP auth with their requests.
+	AllowCredentials bool `yaml:"allow_credentials,omitempty"`
+
+	// MaxAgeSec is how long browsers may cache a preflight result (0 = browser default).
+	MaxAgeSec int `yaml:"max_age_sec,omitempty"`
+}
+
+// BasePathPrefix returns BasePath normalized to "" or "/prefix" (no trailing slash).
+func (a APIConfig) BasePathPrefix() string {
+	p := strings.Trim(strings.TrimSpace(a.BasePath), "/")
+	if p == "" {
+		return ""
+	}
+	return "/" + p
+}
+
+// SocketPerm returns SocketMode as a file mode (validated by Config.Validate).
+func (a APIConfig) SocketPerm() os.FileMode {
+	mode, err := strconv.ParseUint(a.SocketMode, 8, 32)
+	if err != nil {
+		return 0o660
+	}
+	return os.FileMode(mode)
+}
+
+// ListenAddr returns the host:port the API server listens on ("" if it shares the webhooks listener).
+func (a APIConfig) ListenAddr() string {
+	if a.Port == 0 {
+		return ""
+	}
+	return net.JoinHostPort(a.BindAddress, strconv.Itoa(a.Port))
+}
+
+// EventWebhookConfig configures the generic event injection endpoint.
+type EventWebhookConfig struct {
+	Enabled   bool   `yaml:"enabled"`
+	TokenFile string `yaml:"token_file"` // shared auth token source (file path, env:, credential: or exec:)
+}
+
+// OutboundWebhookConfig describes one destination for state change notifications.
+type OutboundWebhookConfig struct {
+	URL string `yaml:"url"`
+
+	// Events filters which broadcast types are delivered
+	// ("volume_changed", "mute_changed", "player_changed", "zone_selected", "zone_link_changed", "output_changed", "encoder_changed", "device_down", "device_up", "ir_send", "dsp_connection_changed", "dsp_command_error", "limit_override_changed", "calibration_mode", "test_signal", "tuning_changed", "update_available", "exposure_warning", "ui_hint").
+	// Empty means all.
+	Events []string `yaml:"events,omitempty"`
+
+	// Secret, if set, enables HMAC-SHA256 signing of the request body.
+	Secret string `yaml:"secret,omitempty"` // secret source: file path, env:, credential: or exec:
+
+	TimeoutMS  int `yaml:"timeout_ms,omitempty"`  // per-attempt timeout; 0 uses a 3s default
+	MaxRetries int `yaml:"max_retries,omitempty"` // retries after the first attempt
+}
+
+// OSCConfig configures the OSC UDP server (see osc.go for address patterns).
+type OSCConfig struct {
+	Enabled bool `yaml:"enabled"`
+	Port    int  `yaml:"port"`
+
+	// BindAddress is the interface address to listen on. Empty binds all interfaces.
+	BindAddress string `yaml:"bind_address,omitempty"`
+
+	// FeedbackTargets receive state feedback (host:port), e.g. a TouchOSC tablet.
+	FeedbackTargets []string `yaml:"feedback_targets,omitempty"`
+
+	// ReplyToSenders sends feedback to every client that recently sent a message.
+	ReplyToSenders bool `yaml:"reply_to_senders"`
+
+	// ReplyPort sends sender feedback to this port instead of the sender's source port.
+	ReplyPort int `yaml:"reply_port,omitempty"`
+
+	// Presets maps names (/preset <name>, /preset/<name>) to volumes (dB).
+	Presets map[string]float64 `yaml:"presets,omitempty"`
+}
+
+// ListenAddr returns the host:port the OSC server listens on.
+func (o OSCConfig) ListenAddr() string {
+	return net.JoinHostPort(o.BindAddress, strconv.Itoa(o.Port))
+}
+
+// LEDConfig configures the LED volume indicator (see led.go).
+type LEDConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Driver is "ws2812", "apa102" (SPI LED rings) or "pwm" (single LED via sysfs PWM).
+	Driver string `yaml:"driver"`
+
+	// Device is the spidev path (e.g. /dev/spidev0.0) or, for pwm, the sysfs PWM
+	// channel directory (e.g. /sys/class/pwm/pwmchip0/pwm0, already exported).
+	Device string `yaml:"device"`
+
+	// SPISpeedHz is the APA102 clock (WS2812 always uses its own encoding rate).
+	SPISpeedHz int `yaml:"spi_speed_hz"`
+
+	// PWMPeriodNS is the pwm driver period.
+	PWMPeriodNS int `yaml:"pwm_period_ns"`
+
+	// Pixels is the number of LEDs on the ring.
+	Pixels int `yaml:"pixels"`
+
+	// Brightness scales all colors (0..1).
+	Brightness float64 `yaml:"brightness"`
+
+	// Colors maps ring position (0..1) to color ("#rrggbb"), linearly interpolated.
+	Colors []LEDColorStopConfig `yaml:"colors"`
+
+	// MuteColor is the flash color while muted.
+	MuteColor string `yaml:"mute_color"`
+}
+
+// LEDColorStopConfig is one color map entry.
+type LEDColorStopConfig struct {
+	At    float64 `yaml:"at"`
+	Color string  `yaml:"color"`
+}
+
+// renderer validates the color settings and builds the frame renderer.
+func (l LEDConfig) renderer() (ledRenderer, error) {
+	r := ledRenderer{pixels: l.Pixels, brightness: l.Brightness}
+	for i, st := range l.Colors {
+		c, err := parseLEDColor(st.Color)
+		if err != nil {
+			return r, fmt.Errorf("led.colors[%d]: %w", i, err)
+		}
+		if st.At < 0 || st.At > 1 || (i > 0 && st.At <= l.Colors[i-1].At) {
+			return r, fmt.Errorf("led.colors[%d].at must be in 0..1 and increasing", i)
+		}
+		r.stops = append(r.stops, ledColorStop{At: st.At, Color: c})
+	}
+	c, err := parseLEDColor(l.MuteColor)
+	if err != nil {
+		return r, fmt.Errorf("led.mute_color: %w", err)
+	}
+	r.muteColor = c
+	return r, nil
+}
+
+// IRTxConfig configures the IR transmitter (see ir_tx.go).
+type IRTxConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Backend is "irsend" (lircd) or "lirc" (rc-core /dev/lircN scancode transmit).
+	Backend string `yaml:"backend"`
+
+	// Device is the lirc transmitter device (backend "lirc").
+	Device string `yaml:"device"`
+
+	// IRSendPath is the irsend binary (backend "irsend").
+	IRSendPath string `yaml:"irsend_path"`
+
+	// Commands maps names to step sequences (e.g. amp_on: [power, input_cd]).
+	Commands map[string][]IRTxStepConfig `yaml:"commands"`
+
+	// Triggers run commands on state changes.
+	Triggers []IRTxTriggerConfig `yaml:"triggers,omitempty"`
+}
+
+// IRTxStepConfig is one transmission within a command.
+type IRTxStepConfig struct {
+	// irsend backend: lircd remote and key names.
+	Remote string `yaml:"remote,omitempty"`
+	Key    string `yaml:"key,omitempty"`
+
+	// lirc backend: kernel protocol name (e.g. "nec", "rc5") and scancode.
+	Protocol string `yaml:"protocol,omitempty"`
+	Scancode uint64 `yaml:"scancode,omitempty"`
+
+	// Count repeats the code (default 1).
+	Count int `yaml:"count,omitempty"`
+
+	// DelayMS pauses after this step (e.g. while the amp powers up).
+	DelayMS int `yaml:"delay_ms,omitempty"`
+}
+
+// IRTxTriggerConfig runs a command when a condition occurs.
+type IRTxTriggerConfig struct {
+	// On is player_playing, player_stopped, mute_on, mute_off, output:<id> or zone:<id>.
+	On string `yaml:"on"`
+
+	Command string `yaml:"command"`
+
+	// DelayMS defers the command; any other trigger firing first cancels it.
+	DelayMS int `yaml:"delay_ms,omitempty"`
+}
+
+// validate checks backend settings, command steps and trigger references.
+func (t IRTxConfig) validate() error {
+	switch t.Backend {
+	case irTxBackendIRSend:
+		if t.IRSendPath == "" {
+			return errors.New("ir_tx.irsend_path is empty")
+		}
+	case irTxBackendLirc:
+		if t.Device == "" {
+			return errors.New("ir_tx.device is empty")
+		}
+	default:
+		return fmt.Errorf("ir_tx.backend must be %q or %q", irTxBackendIRSend, irTxBackendLirc)
+	}
+	if len(t.Commands) == 0 {
+		return errors.New("ir_tx.commands must not be empty")
+	}
+	for name, steps := range t.Commands {
+		if len(steps) == 0 {
+			return fmt.Errorf("ir_tx.commands.%s has no steps", name)
+		}
+		for i, st := range steps {
+			switch t.Backend {
+			case irTxBackendIRSend:
+				if st.Remote == "" || st.Key == "" {
+					return fmt.Errorf("ir_tx.commands.%s[%d] needs remote and key", name, i)
+				}
+			case irTxBackendLirc:
+				if _, ok := rcProtocols[st.Protocol]; !ok {
+					return fmt.Errorf("ir_tx.commands.%s[%d].protocol %q is not a supported protocol", name, i, st.Protocol)
+				}
+			}
+			if st.Count < 0 || st.DelayMS < 0 {
+				return fmt.Errorf("ir_tx.commands.%s[%d]: count and delay_ms must be >= 0", name, i)
+			}
+		}
+	}
+	for i, tr := range t.Triggers {
+		switch {
+		case tr.On == irTriggerPlayerPlaying, tr.On == irTriggerPlayerStopped,
+			tr.On == irTriggerMuteOn, tr.On == irTriggerMuteOff,
+			strings.HasPrefix(tr.On, irTriggerOutputPrefix), strings.HasPrefix(tr.On, irTriggerZonePrefix):
+		default:
+			return fmt.Errorf("ir_tx.triggers[%d].on %q is not a supported condition", i, tr.On)
+		}
+		if _, ok := t.Commands[tr.Command]; !ok {
+			return fmt.Errorf("ir_tx.triggers[%d].command %q is not defined", i, tr.Command)
+		}
+		if tr.DelayMS < 0 {
+			return fmt.Errorf("ir_tx.triggers[%d].delay_ms must be >= 0", i)
+		}
+	}
+	return nil
+}
+
+// DiagnosticsConfig configures daemon loop timing warnings, crash dumps and the
+// CamillaDSP protocol tap.
+type DiagnosticsConfig struct {
+	// LatencyWarnMS logs a warning when an event takes longer than this from entering
+	// a daemon loop to its commands being dispatched. 0 disables the warning.
+	LatencyWarnMS int `yaml:"latency_warn_ms"`
+
+	// JitterWarnMS logs a warning when a tick arrives this far off its period. 0 disables the warning.
+	JitterWarnMS int `yaml:"jitter_warn_ms"`
+
+	// CrashDumpDir is where a diagnostic dump is written when a goroutine panics
+	// (see crash.go). Empty uses the system temp directory.
+	CrashDumpDir string `yaml:"crash_dump_dir,omitempty"`
+
+	// TapFile receives CamillaDSP frames while the protocol tap is on (see
+	// camilladsp_tap.go). Empty streams them to state clients only.
+	TapFile string `yaml:"tap_file,omitempty"`
+
+	// TapMaxMB bounds TapFile; the tap turns itself off when it is reached.
+	TapMaxMB int `yaml:"tap_max_mb"`
+}
+
+// UpdateCheckConfig configures the release check (see update_check.go).
+type UpdateCheckConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// IntervalHours between checks; the first runs a minute after startup.
+	IntervalHours int `yaml:"interval_hours"`
+
+	// Repository is the GitHub "owner/name" whose releases are checked.
+	Repository string `yaml:"repository"`
+}
+
+// AlertsConfig configures fault alerts (see alerts.go).
+type AlertsConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Channels are the notification destinations.
+	Channels []AlertChannelConfig `yaml:"channels"`
+
+	// Per alert type settings.
+	DSPDisconnected AlertRuleConfig `yaml:"dsp_disconnected"`
+	InputDeviceDown AlertRuleConfig `yaml:"input_device_down"`
+
+	// DSPProtocolError is one-shot: after_sec and notify_resolved are ignored.
+	DSPProtocolError AlertRuleConfig `yaml:"dsp_protocol_error"`
+}
+
+// AlertChannelConfig is one notification destination.
+type AlertChannelConfig struct {
+	// Name identifies the channel in rules and logs (defaults to the type).
+	Name string `yaml:"name,omitempty"`
+
+	// Type is "ntfy", "pushover" or "webhook".
+	Type string `yaml:"type"`
+
+	// URL is the ntfy server (default https://ntfy.sh), the Pushover API endpoint
+	// (default the public API) or the webhook URL (required).
+	URL string `yaml:"url,omitempty"`
+
+	// Topic is the ntfy topic.
+	Topic string `yaml:"topic,omitempty"`
+
+	// Token is the ntfy access token (optional) or the Pushover application token.
+	Token string `yaml:"token,omitempty"` // secret source: file path, env:, credential: or exec:
+
+	// User is the Pushover user or group key.
+	User string `yaml:"user,omitempty"`
+
+	// Secret, if set, HMAC-signs webhook bodies like outbound_webhooks.
+	Secret string `yaml:"secret,omitempty"` // secret source, like token
+}
+
+// name returns the channel name used in rules and logs.
+func (ch AlertChannelConfig) name() string {
+	if ch.Name != "" {
+		return ch.Name
+	}
+	return ch.Type
+}
+
+// AlertRuleConfig configures one alert type.
+type AlertRuleConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// AfterSec is how long the fault must persist before alerting.
+	AfterSec int `yaml:"after_sec"`
+
+	// CooldownSec is the minimum time between alerts for the same zone/device.
+	CooldownSec int `yaml:"cooldown_sec"`
+
+	// NotifyResolved sends a follow-up when an alerted fault clears.
+	NotifyResolved bool `yaml:"notify_resolved"`
+
+	// Channels restricts delivery to the named channels. Empty means all.
+	Channels []string `yaml:"channels,omitempty"`
+}
+
+// validate checks channel settings and rule references.
+func (a AlertsConfig) validate() error {
+	if len(a.Channels) == 0 {
+		return errors.New("alerts.channels must not be empty")
+	}
+	names := make(map[string]bool, len(a.Channels))
+	for i, ch := range a.Channels {
+		switch ch.Type {
+		case alertChannelNtfy:
+			if ch.Topic == "" {
+				return fmt.Errorf("alerts.channels[%d].topic is empty", i)
+			}
+		case alertChannelPushover:
+			if ch.Token == "" || ch.User == "" {
+				return fmt.Errorf("alerts.channels[%d] needs token and user", i)
+			}
+		case alertChannelWebhook:
+			if ch.URL == "" {
+				return fmt.Errorf("alerts.channels[%d].url is empty", i)
+			}
+		default:
+			return fmt.Errorf("alerts.channels[%d].type must be %q, %q or %q", i, alertChannelNtfy, alertChannelPushover, alertChannelWebhook)
+		}
+		if ch.URL != "" {
+			u, err := url.Parse(ch.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				return fmt.Errorf("alerts.channels[%d].url must be an http(s) URL", i)
+			}
+		}
+		if err := validateSecretRef(ch.Token); err != nil {
+			return fmt.Errorf("alerts.channels[%d].token: %w", i, err)
+		}
+		if err := validateSecretRef(ch.Secret); err != nil {
+			return fmt.Errorf("alerts.channels[%d].secret: %w", i, err)
+		}
+		if names[ch.name()] {
+			return fmt.Errorf("alerts.channels[%d].name %q is not unique", i, ch.name())
+		}
+		names[ch.name()] = true
+	}
+	for typ, rule := range map[string]AlertRuleConfig{
+		alertDSPDisconnected:  a.DSPDisconnected,
+		alertInputDeviceDown:  a.InputDeviceDown,
+		alertDSPProtocolError: a.DSPProtocolError,
+	} {
+		if rule.AfterSec < 0 || rule.CooldownSec < 0 {
+			return fmt.Errorf("alerts.%s: after_sec and cooldown_sec must be >= 0", typ)
+		}
+		for _, n := range rule.Channels {
+			if !names[n] {
+				return fmt.Errorf("alerts.%s.channels: unknown channel %q", typ, n)
+			}
+		}
+	}
+	return nil
+}
+
+// ControlProtocolConfig configures one control protocol plugin instance.
+type ControlProtocolConfig struct {
+	// Name identifies the instance in logs (defaults to the type).
+	Name string `yaml:"name,omitempty"`
+
+	// Type selects a registered protocol (e.g. "udp_text").
+	Type string `yaml:"type"`
+
+	// Options are protocol-specific; decoded and validated by the protocol factory.
+	Options yaml.Node `yaml:"options,omitempty"`
+}
+
+type WebSocketConfig struct {
+	// SendBuf is the per-client outbound queue size. Slow clients are disconnected
+	// if they can't keep up and this buffer fills.
+	SendBuf int `yaml:"send_buf"`
+
+	// BroadcastBuf is the hub inbound broadcast queue size (frames waiting to be
+	// fanned out to clients).
+	BroadcastBuf int `yaml:"broadcast_buf"`
+
+	// AllowedOrigins is the browser Origin allow-list. Empty (or "*") allows any origin.
+	AllowedOrigins []string `yaml:"allowed_origins,omitempty"`
+
+	// MaxClients caps concurrently connected clients (0 = unlimited).
+	MaxClients int `yaml:"max_clients"`
+
+	// MaxClientsPerIP caps concurrent clients per remote IP (0 = unlimited).
+	MaxClientsPerIP int `yaml:"max_clients_per_ip"`
+
+	// ReplayBuf is the number of recent broadcasts kept so reconnecting clients
+	// can resume without a new snapshot (0 = always send a fresh state_init).
+	ReplayBuf int `yaml:"replay_buf"`
+
+	// CoalesceMs maps high-rate broadcast types (e.g. "volume_changed") to a
+	// latest-wins throttle window in milliseconds; 0 sends every event.
+	CoalesceMs map[string]int `yaml:"coalesce_ms,omitempty"`
+}
+
+type PlexConfig struct {
+	ServerURL  string `yaml:"server_url"`
+	TokenFile  string `yaml:"token_file"` // token source: file path, env:, credential: or exec:
+	MachineID  string `yaml:"machine_id"`
+	Enabled    bool   `yaml:"enabled"`
+	BindLocal  bool   `yaml:"bind_local,omitempty"`  // optional hardening knob for future
+	AllowCIDRs []any  `yaml:"allow_cidrs,omitempty"` // placeholder for future; keep as any to avoid committing to a format
+
+	// TimeoutMS bounds each request to the Plex server (0 uses a 5s default).
+	TimeoutMS int `yaml:"timeout_ms,omitempty"`
+
+	// InsecureSkipVerify accepts self-signed certificates on an https server_url.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify,omitempty"`
+}
+
+// TidalConnectConfig reads the Tidal Connect player's output, from Command or LogFile.
+type TidalConnectConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Command prints the player's output, e.g. [docker, logs, -f, --tail, "0", tidal-connect].
+	Command []string `yaml:"command,omitempty"`
+
+	// LogFile is tailed instead of running Command.
+	LogFile string `yaml:"log_file,omitempty"`
+}
+
+// ScrobbleConfig submits the tracks the integrations report playing.
+type ScrobbleConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// QueueFile keeps unsent scrobbles across restarts (empty = memory only).
+	QueueFile string `yaml:"queue_file,omitempty"`
+
+	// Sources limits scrobbling to these player sources (plex, librespot, tidal); empty = all.
+	Sources []string `yaml:"sources,omitempty"`
+
+	ListenBrainz ListenBrainzConfig `yaml:"listenbrainz"`
+	LastFM       LastFMConfig       `yaml:"lastfm"`
+}
+
+type ListenBrainzConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Token   string `yaml:"token"`         // user token source: file path, env:, credential: or exec:
+	URL     string `yaml:"url,omitempty"` // API root (default https://api.listenbrainz.org)
+}
+
+type LastFMConfig struct {
+	Enabled   bool   `yaml:"enabled"`
+	APIKey    string `yaml:"api_key"`
+	APISecret string `yaml:"api_secret"` // secret source: file path, env:, credential: or exec:
+
+	// SessionKeyFile holds the session key written by `streamerbrainz lastfm-login`.
+	SessionKeyFile string `yaml:"session_key_file"`
+}
+
+// StatsConfig records daily listening statistics.
+type StatsConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// File stores the statistics (empty = memory only).
+	File string `yaml:"file"`
+
+	// RetentionDays is how long days are kept (0 = forever).
+	RetentionDays int `yaml:"retention_days"`
+
+	// LoudThresholdDB counts listening time at or above this volume as loud.
+	LoudThresholdDB float64 `yaml:"loud_threshold_db"`
+
+	// WeeklySummary logs the past week's statistics every Monday.
+	WeeklySummary bool `yaml:"weekly_summary"`
+
+	// Exposure warns when loud listening exceeds a daily budget.
+	Exposure ExposureConfig `yaml:"exposure"`
+}
+
+// ExposureConfig budgets daily listening at or above stats.loud_threshold_db.
+type ExposureConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// DailyBudgetMin is the loud listening time allowed per day.
+	DailyBudgetMin float64 `yaml:"daily_budget_min"`
+
+	// WarnAtPercent also warns once this share of the budget is used (0 = only when exceeded).
+	WarnAtPercent float64 `yaml:"warn_at_percent"`
+
+	// AutoDim lowers zones still playing loud to DimToDB once the budget is exceeded.
+	AutoDim bool    `yaml:"auto_dim"`
+	DimToDB float64 `yaml:"dim_to_db"`
+}
+
+type LoggingConfig struct {
+	Level string `yaml:"level"`
+}
+
+// VelocityFileConfig is the user-facing velocity configuration as represented in YAML.
+//
+// It maps 1:1 to VelocityConfig used by the engine, but uses YAML-friendly types.
+// (e.g., hold timeout is represented in milliseconds).
+type VelocityFileConfig struct {
+	Mode string `yaml:"mode" json:"mode"` // "accelerating" or "constant"
+
+	// Shared:
+	// - accelerating: max velocity (dB/s)
+	// - constant: base hold rate (dB/s)
+	MaxDBPerSec float64 `yaml:"max_db_per_sec" json:"max_db_per_sec"`
+
+	// Accelerating-mode only:
+	AccelTimeSec float64 `yaml:"accel_time_sec,omitempty" json:"accel_time_sec"`
+	DecayTauSec  float64 `yaml:"decay_tau_sec,omitempty" json:"decay_tau_sec"`
+
+	// Constant-mode turbo:
+	TurboMult  float64 `yaml:"turbo_mult,omitempty" json:"turbo_mult"`
+	TurboDelay float64 `yaml:"turbo_delay_sec,omitempty" json:"turbo_delay_sec"`
+
+	// Hold behavior:
+	HoldTimeoutMS int `yaml:"hold_timeout_ms" json:"hold_timeout_ms"`
+
+	// Absolute sets (web UI sliders, presets) fade at this rate (dB/s); 0 = jump immediately.
+	RampDBPerSec float64 `yaml:"ramp_db_per_sec,omitempty" json:"ramp_db_per_sec"`
+
+	// Danger zone (near max volume, ramp-up only):
+	DangerZoneDB            float64 `yaml:"danger_zone_db" json:"danger_zone_db"`
+	DangerVelMaxDBPerSec    float64 `yaml:"danger_vel_max_db_per_sec" json:"danger_vel_max_db_per_sec"`
+	DangerVelMinNear0DBPerS float64 `yaml:"danger_vel_min_near0_db_per_sec" json:"danger_vel_min_near0_db_per_sec"`
+
+	// Danger zone for rotary and absolute sources (see danger_zone.go): upward rotary
+	// detents inside the zone move at most DangerRotaryDBPerStep dB each (0 = unlimited),
+	// and with DangerRampAbsolute absolute sets into the zone fade through it.
+	DangerRotaryDBPerStep float64 `yaml:"danger_rotary_db_per_step,omitempty" json:"danger_rotary_db_per_step"`
+	DangerRampAbsolute    bool    `yaml:"danger_ramp_absolute,omitempty" json:"danger_ramp_absolute"`
+
+	// HoldCheckpointDB stops an upward hold that started below it at this level; going
+	// further takes a release and a new press (unset disables; see hold_checkpoint.go).
+	HoldCheckpointDB *float64 `yaml:"hold_checkpoint_db,omitempty" json:"hold_checkpoint_db,omitempty"`
+
+	// ExternalChangeDB: a volume read back during a hold or ramp that is this far (dB)
+	// from what the daemon set means another client changed it; the hold/ramp is
+	// cancelled and the new level adopted (0 = keep going; see external_change.go).
+	ExternalChangeDB float64 `yaml:"external_change_db" json:"external_change_db"`
+}
+
+// RotaryConfig contains rotary encoder-specific configuration
+type RotaryConfig struct {
+	DbPerStep          float64 `yaml:"db_per_step" json:"db_per_step"`                 // dB change per encoder step
+	VelocityWindowMS   int     `yaml:"velocity_window_ms" json:"velocity_window_ms"`   // Time window for velocity detection (ms)
+	VelocityMultiplier float64 `yaml:"velocity_multiplier" json:"velocity_multiplier"` // Multiplier for "fast spinning"
+	VelocityThreshold  int     `yaml:"velocity_threshold" json:"velocity_threshold"`   // Steps in window to trigger velocity mode
+
+	// DebounceMS rejects direction reversals closer than this to the previous detent
+	// (contact bounce on cheap encoders). 0 disables.
+	DebounceMS int `yaml:"debounce_ms" json:"debounce_ms"`
+
+	// Curve maps detent rate (steps/s over the velocity window) to dB per step,
+	// interpolating linearly between points. When empty, the legacy
+	// db_per_step + threshold/multiplier scheme is used.
+	Curve []RotaryCurvePoint `yaml:"curve" json:"curve"`
+
+	// Push-button (encoder click):
+	// - "mute": toggle mute
+	// - "mode": cycle encoder mode volume -> balance -> sub (modes without faders are skipped)
+	// - "none": ignore
+	ButtonAction  string `yaml:"button_action" json:"button_action"`
+	ModeTimeoutMS int    `yaml:"mode_timeout_ms" json:"mode_timeout_ms"` // Revert to volume mode after this idle time (0 = never)
+	BalanceFaders []int  `yaml:"balance_faders" json:"balance_faders"`   // CamillaDSP aux faders [left, right] for balance mode
+	SubFader      int    `yaml:"sub_fader" json:"sub_fader"`             // CamillaDSP aux fader (1-4) for sub mode; 0 disables
+}
+
+// RotaryCurvePoint is one point of the rotary acceleration curve.
+type RotaryCurvePoint struct {
+	Rate      float64 `yaml:"rate" json:"rate"`               // detent rate (steps/s)
+	DbPerStep float64 `yaml:"db_per_step" json:"db_per_step"` // dB per step at this rate
+}
+
+// defaultRotaryCurve keeps slow turns fine-grained while fast spins sweep quickly.
+func defaultRotaryCurve() []RotaryCurvePoint {
+	return []RotaryCurvePoint{
+		{Rate: 0, DbPerStep: 0.25},
+		{Rate: 10, DbPerStep: 0.5},
+		{Rate: 25, DbPerStep: 1.5},
+		{Rate: 50, DbPerStep: 3.0},
+	}
+}
+
+// DefaultConfig returns a fully-populated Config with defaults.
+// Keep this aligned with constants.go defaults and current CLI defaults.
+func DefaultConfig() Config {
+	return Config{
+		Version: currentConfigVersion,
+		Inputs: []InputDevice{
+			{Path: "/dev/input/event6", Type: InputDeviceTypeKey},
+		},
+		InputReader: inputReaderEpoll,
+		InputReconnect: InputReconnectConfig{
+			MaxRetries:       defaultInputReconnectMaxRetries,
+			InitialBackoffMS: defaultInputReconnectInitialBackoffMS,
+			MaxBackoffMS:     defaultInputReconnectMaxBackoffMS,
+		},
+		Diagnostics: DiagnosticsConfig{
+			LatencyWarnMS: defaultLatencyWarnMS,
+			JitterWarnMS:  defaultJitterWarnMS,
+			TapMaxMB:      defaultTapMaxMB,
+		},
+		CamillaDSP: CamillaDSPConfig{
+			WsURL:        "ws://127.0.0.1:1234",
+			TimeoutMS:    defaultReadTimeoutMS,
+			KeepaliveSec: defaultCamillaKeepaliveSec,
+			Reconnect: CamillaDSPReconnectConfig{
+				Attempts:      defaultCamillaConnectAttempts,
+				IntervalMS:    defaultCamillaConnectIntervalMS,
+				BackoffFactor: 1,
+				MaxBackoffMS:  defaultCamillaConnectMaxBackoffMS,
+			},
+			MinDB:    -65.0,
+			MaxDB:    0.0,
+			UpdateHz: defaultUpdateHz,
+			IdleHz:   defaultIdleHz,
+
+			DisplayStepDB: defaultDisplayStepDB,
+		},
+		Velocity: VelocityFileConfig{
+			Mode:                    string(VelocityModeAccelerating),
+			MaxDBPerSec:             defaultVelMaxDBPerS,
+			AccelTimeSec:            defaultAccelTime,
+			DecayTauSec:             defaultDecayTau,
+			TurboMult:               1.0,
+			TurboDelay:              0.0,
+			HoldTimeoutMS:           600,
+			DangerZoneDB:            dangerZoneDB,
+			DangerVelMaxDBPerSec:    dangerVelMaxDBPerS,
+			DangerVelMinNear0DBPerS: dangerVelMinNear0DBPerS,
+			ExternalChangeDB:        defaultExternalChangeDB,
+		},
+		FadeIn: FadeInConfig{
+			DurationMS:  defaultFadeInMS,
+			OnReconnect: true,
+		},
+		Shutdown: ShutdownConfig{
+			Action:   shutdownActionNone,
+			VolumeDB: safeDefaultDB,
+		},
+		Mute: MuteConfig{
+			VolumeDownWhileMuted: "adjust",
+		},
+		Arbitration: ArbitrationConfig{
+			Policy:          arbitrationNone,
+			LockoutMS:       defaultArbitrationLockoutMS,
+			PhysicalOrigins: slices.Clone(defaultPhysicalOrigins),
+		},
+		UIHints: UIHintsConfig{
+			VolumeOverlayMS: defaultVolumeOverlayMS,
+			MuteFlashMS:     defaultMuteFlashMS,
+		},
+		Calibration: CalibrationConfig{
+			ReferenceDB:   defaultCalibrationReferenceDB,
+			LongPressMS:   defaultCalibrationLongPressMS,
+			RestoreOnExit: true,
+		},
+		TestSignal: TestSignalConfig{
+			LevelDB:     defaultTestSignalLevelDB,
+			DurationSec: defaultTestSignalDurationSec,
+		},
+		Stats: StatsConfig{
+			File:            defaultStatsFile,
+			RetentionDays:   defaultStatsRetentionDays,
+			LoudThresholdDB: defaultStatsLoudThresholdDB,
+			Exposure: ExposureConfig{
+				DailyBudgetMin: defaultExposureDailyBudgetMin,
+				WarnAtPercent:  defaultExposureWarnAtPercent,
+				DimToDB:        defaultExposureDimToDB,
+			},
+		},
+		Normalization: NormalizationConfig{
+			Mode:            NormalizationModeTrack,
+			MaxBoostDB:      defaultNormalizationMaxBoostDB,
+			MaxCutDB:        defaultNormalizationMaxCutDB,
+			PreventClipping: true,
+		},
+		LimitOverride: LimitOverrideConfig{
+			TimeoutSec: defaultLimitOverrideTimeoutSec,
+		},
+		IPC: IPCConfig{
+			SocketPath: "/tmp/streamerbrainz.sock",
+		},
+		Webhooks: WebhooksConfig{
+			Port: 3001,
+		},
+		API: APIConfig{
+			SocketMode: defaultAPISocketMode,
+		},
+		WebSocket: WebSocketConfig{
+			SendBuf:      32,
+			BroadcastBuf: 128,
+			ReplayBuf:    256,
+			CoalesceMs:   maps.Clone(defaultCoalesceWindows),
+		},
+		OSC: OSCConfig{
+			Port:           defaultOSCPort,
+			ReplyToSenders: true,
+		},
+		Alerts: AlertsConfig{
+			DSPDisconnected: AlertRuleConfig{
+				Enabled:        true,
+				AfterSec:       defaultAlertDSPDisconnectedAfterSec,
+				CooldownSec:    defaultAlertCooldownSec,
+				NotifyResolved: true,
+			},
+			InputDeviceDown: AlertRuleConfig{
+				Enabled:     true,
+				CooldownSec: defaultAlertCooldownSec,
+			},
+			DSPProtocolError: AlertRuleConfig{
+				Enabled:     true,
+				CooldownSec: defaultAlertCooldownSec,
+			},
+		},
+		UpdateCheck: UpdateCheckConfig{
+			IntervalHours: defaultUpdateCheckIntervalHours,
+			Repository:    defaultUpdateCheckRepository,
+		},
+		IRTx: IRTxConfig{
+			Backend:    irTxBackendIRSend,
+			Device:     "/dev/lirc0",
+			IRSendPath: "irsend",
+		},
+		LED: LEDConfig{
+			Driver:      ledDriverWS2812,
+			Device:      "/dev/spidev0.0",
+			SPISpeedHz:  4_000_000,
+			PWMPeriodNS: 1_000_000,
+			Pixels:      16,
+			Brightness:  0.3,
+			Colors: []LEDColorStopConfig{
+				{At: 0, Color: "#00ff00"},
+				{At: 0.7, Color: "#ffff00"},
+				{At: 1, Color: "#ff0000"},
+			},
+			MuteColor: "#ff0000",
+		},
+		Plex: PlexConfig{
+			Enabled:   false,
+			ServerURL: "",
+			TokenFile: "",
+			MachineID: "",
+		},
+		Rotary: RotaryConfig{
+			DbPerStep:          defaultRotaryDbPerStep,
+			VelocityWindowMS:   defaultRotaryVelocityWindowMS,
+			VelocityMultiplier: defaultRotaryVelocityMultiplier,
+			VelocityThreshold:  defaultRotaryVelocityThreshold,
+			Curve:              defaultRotaryCurve(),
+			ButtonAction:       "mute",
+			ModeTimeoutMS:      defaultRotaryModeTimeoutMS,
+		},
+		Logging: LoggingConfig{
+			Level: "info",
+		},
+	}
+}
+
+// Environment variables overriding config values. Non-empty values win over the
+// config file and drop-ins; command-line flags still win over the environment.
+const (
+	envConfigPath = "STREAMERBRAINZ_CONFIG"
+	envWsURL      = "STREAMERBRAINZ_WS_URL"
+	envSocketPath = "STREAMERBRAINZ_SOCKET_PATH"
+	envLogLevel   = "STREAMERBRAINZ_LOG_LEVEL"
+	envInstance   = "STREAMERBRAINZ_INSTANCE"  // see instance_name.go
+	envContainer  = "STREAMERBRAINZ_CONTAINER" // see container.go
+)
+
+// configDropInDir is the directory (next to the main config file) whose *.yaml
+// fragments are merged over the main file in lexical order.
+const configDropInDir = "conf.d"
+
+// ResolveConfigPath returns the config path to load: the -config flag value if set,
+// else $STREAMERBRAINZ_CONFIG, else the default location (in container mode, or of
+// the selected instance, if any).
+func ResolveConfigPath(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if p := os.Getenv(envConfigPath); p != "" {
+		return p
+	}
+	if containerMode() {
+		return containerConfigPath
+	}
+	if name := instanceName(); name != "" {
+		return instanceConfigPath(name)
+	}
+	return defaultConfigPath
+}
+
+// LoadConfigFile reads and parses a YAML config file.
+//
+// Notes:
+//   - The file must be valid YAML.
+//   - Unknown fields are rejected (helps catch typos) via KnownFields(true).
+//   - Fragments in conf.d/*.yaml next to the file are merged over it (scalars and
+//     lists replace, maps and sections merge key by key), then STREAMERBRAINZ_*
+//     environment overrides are applied.
+//   - Relative paths inside the config (like plex token file) are not rewritten here;
+//     handle that in validation or in the call site as needed.
+func LoadConfigFile(path string) (Config, error) {
+	if path == "" {
+		return Config{}, errors.New("config path is empty")
+	}
+	path = ExpandPath(path)
+
+	cfg := DefaultConfig()
+	if containerMode() {
+		applyContainerDefaults(&cfg)
+	}
+	if name := instanceName(); name != "" {
+		applyInstanceDefaults(&cfg, name)
+	}
+	if err := decodeConfigFile(path, &cfg, false); err != nil {
+		// A container may be configured through the environment alone.
+		if !containerMode() || !errors.Is(err, fs.ErrNotExist) {
+			return Config{}, err
+		}
+	}
+
+	fragments, err := filepath.Glob(filepath.Join(filepath.Dir(path), configDropInDir, "*.yaml"))
+	if err != nil {
+		return Config{}, fmt.Errorf("list config drop-ins: %w", err)
+	}
+	for _, f := range fragments {
+		if err := decodeConfigFile(f, &cfg, true); err != nil {
+			return Config{}, fmt.Errorf("%s: %w", f, err)
+		}
+	}
+
+	cfg.applyEnvOverrides(os.LookupEnv)
+	return cfg, nil
+}
+
+// decodeConfigFile decodes one YAML document from path over cfg, migrating legacy
+// keys first (see migrateConfigNode). allowEmpty accepts files without a document
+// (e.g. a fully commented-out drop-in).
+func decodeConfigFile(path string, cfg *Config, allowEmpty bool) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	var doc yaml.Node
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	if err := dec.Decode(&doc); err != nil {
+		if allowEmpty && errors.Is(err, io.EOF) {
+			return nil
+		}
+		return fmt.Errorf("decode config yaml: %w", err)
+	}
+
+	// Ensure there's no trailing garbage (only whitespace/comments are allowed after the document).
+	if err := dec.Decode(&struct{}{}); err == nil {
+		return fmt.Errorf("decode config yaml: unexpected trailing document")
+	}
+
+	warnings, err := migrateConfigNode(&doc)
+	if err != nil {
+		return fmt.Errorf("migrate config: %w", err)
+	}
+	if len(warnings) > 0 {
+		// Line numbers in decode errors refer to the migrated document from here on.
+		if b, err = yaml.Marshal(&doc); err != nil {
+			return fmt.Errorf("migrate config: %w", err)
+		}
+		for _, w := range warnings {
+			cfg.deprecations = append(cfg.deprecations, path+": "+w)
+		}
+	}
+
+	dec = yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil {
+		return fmt.Errorf("decode config yaml: %w", err)
+	}
+	return nil
+}
+
+// applyEnvOverrides applies non-empty STREAMERBRAINZ_* variables found by lookup.
+func (c *Config) applyEnvOverrides(lookup func(string) (string, bool)) {
+	for _, o := range []struct {
+		name  string
+		field *string
+	}{
+		{envWsURL, &c.CamillaDSP.WsURL},
+		{envSocketPath, &c.IPC.SocketPath},
+		{envLogLevel, &c.Logging.Level},
+	} {
+		if v, ok := lookup(o.name); ok && v != "" {
+			*o.field = v
+		}
+	}
+}
+
+// Validate checks config invariants and returns a user-friendly error.
+// This is intended to be called after defaults + file + overrides are applied.
+func (c *Config) Validate() error {
+	// Validate all input devices. An empty list is allowed: the daemon is then
+	// controlled only through IPC, the HTTP API and the WebSocket.
+	stdinInputs, hotkeyInputs := 0, 0
+	for i, dev := range c.Inputs {
+		if dev.Type == "" {
+			return fmt.Errorf("inputs[%d].type is empty", i)
+		}
+		if dev.Path == "" && dev.Type != InputDeviceTypeHotkey {
+			return fmt.Errorf("inputs[%d].path is empty", i)
+		}
+		switch dev.Type {
+		case InputDeviceTypeKey, InputDeviceTypeRotary:
+			if dev.Path == fifoStdinPath {
+				return fmt.Errorf("inputs[%d].path %q is only valid for type %q", i, fifoStdinPath, InputDeviceTypeFifo)
+			}
+		case InputDeviceTypeFifo:
+			if dev.Path == fifoStdinPath {
+				stdinInputs++
+			}
+		case InputDeviceTypeHotkey:
+			if hotkeyInputs++; hotkeyInputs > 1 {
+				return fmt.Errorf("inputs[%d]: at most one %q input may be configured", i, InputDeviceTypeHotkey)
+			}
+		default:
+			return fmt.Errorf("inputs[%d].type must be %q, %q, %q or %q", i, InputDeviceTypeKey, InputDeviceTypeRotary, InputDeviceTypeFifo, InputDeviceTypeHotkey)
+		}
+		if len(dev.Keymap) > 0 {
+			if dev.Type != InputDeviceTypeKey && dev.Type != InputDeviceTypeRotary {
+				return fmt.Errorf("inputs[%d].keymap is only valid for types %q and %q", i, InputDeviceTypeKey, InputDeviceTypeRotary)
+			}
+			if err := validateKeymap(fmt.Sprintf("inputs[%d].keymap", i), dev.Keymap, c.CamillaDSP); err != nil {
+				return err
+			}
+		}
+		if dev.RepeatIntervalMS != 0 || dev.AutoRepeatInterval {
+			switch {
+			case dev.Type != InputDeviceTypeKey && dev.Type != InputDeviceTypeRotary:
+				return fmt.Errorf("inputs[%d].repeat_interval_ms and auto_repeat_interval are only valid for types %q and %q", i, InputDeviceTypeKey, InputDeviceTypeRotary)
+			case dev.RepeatIntervalMS < 0 || dev.RepeatIntervalMS > maxRepeatIntervalMS:
+				return fmt.Errorf("inputs[%d].repeat_interval_ms must be within 0..%d", i, maxRepeatIntervalMS)
+			case dev.RepeatIntervalMS > 0 && dev.AutoRepeatInterval:
+				return fmt.Errorf("inputs[%d]: set repeat_interval_ms or auto_repeat_interval, not both", i)
+			}
+		}
+		switch dev.NoRelease {
+		case "":
+		case noReleaseStep, noReleaseRelease:
+			if dev.Type != InputDeviceTypeKey && dev.Type != InputDeviceTypeRotary {
+				return fmt.Errorf("inputs[%d].no_release is only valid for types %q and %q", i, InputDeviceTypeKey, InputDeviceTypeRotary)
+			}
+		default:
+			return fmt.Errorf("inputs[%d].no_release must be %q or %q", i, noReleaseStep, noReleaseRelease)
+		}
+		if dev.StepDB < 0 {
+			return fmt.Errorf("inputs[%d].step_db must be >= 0", i)
+		}
+		if dev.StepDB > 0 && dev.NoRelease != noReleaseStep {
+			return fmt.Errorf("inputs[%d].step_db is only valid with no_release %q", i, noReleaseStep)
+		}
+	}
+	if stdinInputs > 1 {
+		return fmt.Errorf("at most one input may read stdin (path %q)", fifoStdinPath)
+	}
+
+	switch c.InputReader {
+	case inputReaderEpoll, inputReaderGoroutine:
+	default:
+		return fmt.Errorf("input_reader must be %q or %q", inputReaderEpoll, inputReaderGoroutine)
+	}
+	if c.InputReconnect.MaxRetries < 0 {
+		return errors.New("input_reconnect.max_retries must be >= 0")
+	}
+	if c.InputReconnect.InitialBackoffMS <= 0 {
+		return errors.New("input_reconnect.initial_backoff_ms must be > 0")
+	}
+	if c.InputReconnect.MaxBackoffMS < c.InputReconnect.InitialBackoffMS {
+		return errors.New("input_reconnect.max_backoff_ms must be >= input_reconnect.initial_backoff_ms")
+	}
+	if c.Diagnostics.LatencyWarnMS < 0 || c.Diagnostics.JitterWarnMS < 0 {
+		return errors.New("diagnostics.latency_warn_ms and diagnostics.jitter_warn_ms must be >= 0")
+	}
+	if c.Diagnostics.TapMaxMB < 1 {
+		return errors.New("diagnostics.tap_max_mb must be >= 1")
+	}
+
+	// CamillaDSP
+	if err := validateCamillaDSP("camilladsp", c.CamillaDSP); err != nil {
+		return err
+	}
+	if _, ok := calibrationKeyCodes[c.Calibration.LongPressKey]; c.Calibration.LongPressKey != "" && !ok {
+		return fmt.Errorf("calibration.long_press_key %q is not supported", c.Calibration.LongPressKey)
+	}
+	if c.Calibration.LongPressMS <= 0 {
+		return errors.New("calibration.long_press_ms must be > 0")
+	}
+	if c.TestSignal.DurationSec <= 0 || time.Duration(c.TestSignal.DurationSec)*time.Second > testSignalMaxDuration {
+		return fmt.Errorf("test_signal.duration_sec must be between 1 and %d", int(testSignalMaxDuration.Seconds()))
+	}
+	seenTestChannels := make(map[string]bool, len(c.TestSignal.Channels))
+	for i, ch := range c.TestSignal.Channels {
+		if ch.ID == "" || ch.ConfigPath == "" {
+			return fmt.Errorf("test_signal.channels[%d] needs id and config_path", i)
+		}
+		if seenTestChannels[ch.ID] {
+			return fmt.Errorf("test_signal.channels[%d].id %q is duplicated", i, ch.ID)
+		}
+		seenTestChannels[ch.ID] = true
+	}
+	if c.LimitOverride.TimeoutSec <= 0 {
+		return errors.New("limit_override.timeout_sec must be > 0")
+	}
+	if err := validateSecretRef(c.LimitOverride.TokenFile); err != nil {
+		return fmt.Errorf("limit_override.token_file: %w", err)
+	}
+
+	// Outputs
+	seenOutputs := make(map[string]bool, len(c.Outputs))
+	for i, o := range c.Outputs {
+		if o.ID == "" {
+			return fmt.Errorf("outputs[%d].id is empty", i)
+		}
+		if seenOutputs[o.ID] {
+			return fmt.Errorf("outputs[%d].id %q is duplicated", i, o.ID)
+		}
+		seenOutputs[o.ID] = true
+	}
+
+	// Zones
+	seenZones := make(map[string]bool, len(c.Zones))
+	for i, z := range c.Zones {
+		if z.ID == "" {
+			return fmt.Errorf("zones[%d].id is empty", i)
+		}
+		if seenZones[z.ID] {
+			return fmt.Errorf("zones[%d].id %q is duplicated", i, z.ID)
+		}
+		seenZones[z.ID] = true
+		if err := validateCamillaDSP(fmt.Sprintf("zones[%d]", i), z.resolve(c.CamillaDSP)); err != nil {
+			return err
+		}
+	}
+	if c.DefaultZone != "" {
+		found := false
+		for _, z := range c.ZoneTargets() {
+			if z.ID == c.DefaultZone {
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("default_zone %q does not match any zone id", c.DefaultZone)
+		}
+	}
+	for id := range c.ZoneLinks {
+		if !seenZones[id] {
+			return fmt.Errorf("zone_links: %q does not match any zone id", id)
+		}
+	}
+
+	// Velocity
+	if err := c.Velocity.validate(); err != nil {
+		return err
+	}
+
+	// Plex
+	if c.Plex.Enabled {
+		if c.Plex.ServerURL == "" {
+			return errors.New("plex.enabled is true but plex.server_url is empty")
+		}
+		if c.Plex.TokenFile == "" {
+			return errors.New("plex.enabled is true but plex.token_file is empty")
+		}
+		if err := validateSecretRef(c.Plex.TokenFile); err != nil {
+			return fmt.Errorf("plex.token_file: %w", err)
+		}
+		if c.Plex.MachineID == "" {
+			return errors.New("plex.enabled is true but plex.machine_id is empty")
+		}
+		if c.Plex.TimeoutMS < 0 {
+			return errors.New("plex.timeout_ms must be >= 0")
+		}
+	}
+
+	// Tidal Connect
+	if c.TidalConnect.Enabled {
+		if (len(c.TidalConnect.Command) == 0) == (c.TidalConnect.LogFile == "") {
+			return errors.New("tidal_connect.enabled is true: set exactly one of tidal_connect.command and tidal_connect.log_file")
+		}
+		if len(c.TidalConnect.Command) > 0 && c.TidalConnect.Command[0] == "" {
+			return errors.New("tidal_connect.command[0] is empty")
+		}
+	}
+
+	// Loudness normalization
+	if n := c.Normalization; n.Enabled {
+		if n.Fader < 1 || n.Fader > 4 {
+			return errors.New("normalization.fader must be an aux fader 1-4")
+		}
+		if n.Fader == c.Rotary.SubFader || slices.Contains(c.Rotary.BalanceFaders, n.Fader) {
+			return fmt.Errorf("normalization.fader %d is already used by rotary.sub_fader/balance_faders", n.Fader)
+		}
+		switch n.Mode {
+		case "", NormalizationModeTrack, NormalizationModeAlbum:
+		default:
+			return fmt.Errorf("normalization.mode must be %q or %q", NormalizationModeTrack, NormalizationModeAlbum)
+		}
+		if n.MaxBoostDB < 0 || n.MaxCutDB < 0 {
+			return errors.New("normalization.max_boost_db and max_cut_db must be >= 0")
+		}
+	}
+
+	// Listening statistics
+	if c.Stats.RetentionDays < 0 {
+		return errors.New("stats.retention_days must be >= 0")
+	}
+	if x := c.Stats.Exposure; x.Enabled {
+		if !c.Stats.Enabled {
+			return errors.New("stats.exposure.enabled requires stats.enabled")
+		}
+		if x.DailyBudgetMin <= 0 {
+			return errors.New("stats.exposure.daily_budget_min must be > 0")
+		}
+		if x.WarnAtPercent < 0 || x.WarnAtPercent >= 100 {
+			return errors.New("stats.exposure.warn_at_percent must be 0-99")
+		}
+		if x.AutoDim && x.DimToDB >= c.Stats.LoudThresholdDB {
+			return fmt.Errorf("stats.exposure.dim_to_db must be below stats.loud_threshold_db (%g)", c.Stats.LoudThresholdDB)
+		}
+	}
+
+	// Scrobbling
+	if c.Scrobble.Enabled {
+		if !c.Scrobble.ListenBrainz.Enabled && !c.Scrobble.LastFM.Enabled {
+			return errors.New("scrobble.enabled is true but neither scrobble.listenbrainz nor scrobble.lastfm is enabled")
+		}
+		for _, src := range c.Scrobble.Sources {
+			switch src {
+			case "plex", "librespot", "tidal":
+			default:
+				return fmt.Errorf("scrobble.sources: unknown source %q (want plex, librespot or tidal)", src)
+			}
+		}
+		if lb := c.Scrobble.ListenBrainz; lb.Enabled {
+			if lb.Token == "" {
+				return errors.New("scrobble.listenbrainz.enabled is true but scrobble.listenbrainz.token is empty")
+			}
+			if err := validateSecretRef(lb.Token); err != nil {
+				return fmt.Errorf("scrobble.listenbrainz.token: %w", err)
+			}
+			if lb.URL != "" {
+				if u, err := url.Parse(lb.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+					return fmt.Errorf("scrobble.listenbrainz.url %q must be an http(s) URL", lb.URL)
+				}
+			}
+		}
+		if fm := c.Scrobble.LastFM; fm.Enabled {
+			if fm.APIKey == "" || fm.APISecret == "" || fm.SessionKeyFile == "" {
+				return errors.New("scrobble.lastfm.enabled is true: scrobble.lastfm.api_key, api_secret and session_key_file are required")
+			}
+			if err := validateSecretRef(fm.APISecret); err != nil {
+				return fmt.Errorf("scrobble.lastfm.api_secret: %w", err)
+			}
+			if err := validateSecretRef(fm.SessionKeyFile); err != nil {
+				return fmt.Errorf("scrobble.lastfm.session_key_file: %w", err)
+			}
+		}
+	}
+
+	// Webhooks / API listeners
+	if name := instanceName(); name != "" && c.Webhooks.Port == 0 {
+		return fmt.Errorf("webhooks.port must be set for instance %q (each instance needs its own ports)", name)
+	}
+	if c.Webhooks.Port <= 0 || c.Webhooks.Port > 65535 {
+		return errors.New("webhooks.port must be between 1 and 65535")
+	}
+	if c.API.Port < 0 || c.API.Port > 65535 {
+		return errors.New("api.port must be between 0 and 65535")
+	}
+	if c.API.Port != 0 && c.API.Port == c.Webhooks.Port && c.API.BindAddress == c.Webhooks.BindAddress {
+		return errors.New("api.port must differ from webhooks.port (or set api.port to 0 to share the listener)")
+	}
+	if strings.ContainsAny(c.API.BasePath, "?#") || (c.API.BasePath != "" && !strings.HasPrefix(c.API.BasePath, "/")) {
+		return fmt.Errorf("api.base_path %q must be a path starting with /", c.API.BasePath)
+	}
+	if _, err := parseTrustedProxies(c.API.TrustedProxies); err != nil {
+		return err
+	}
+	for i, o := range c.API.CORS.AllowedOrigins {
+		if strings.TrimSpace(o) == "" {
+			return fmt.Errorf("api.cors.allowed_origins[%d] is empty", i)
+		}
+		if o == "*" && c.API.CORS.AllowCredentials {
+			return errors.New(`api.cors.allowed_origins "*" cannot be combined with allow_credentials`)
+		}
+	}
+	if c.API.CORS.MaxAgeSec < 0 {
+		return errors.New("api.cors.max_age_sec must be >= 0")
+	}
+	if c.API.Socket != "" {
+		if mode, err := strconv.ParseUint(c.API.SocketMode, 8, 32); err != nil || mode > 0o777 {
+			return fmt.Errorf("api.socket_mode %q must be an octal file mode (e.g. \"0660\")", c.API.SocketMode)
+		}
+		if c.API.Socket == c.IPC.SocketPath {
+			return errors.New("api.socket must differ from ipc.socket_path")
+		}
+	}
+	if c.OSC.Enabled {
+		if name := instanceName(); name != "" && c.OSC.Port == 0 {
+			return fmt.Errorf("osc.port must be set for instance %q (each instance needs its own ports)", name)
+		}
+		if c.OSC.Port <= 0 || c.OSC.Port > 65535 {
+			return errors.New("osc.port must be between 1 and 65535")
+		}
+		if c.OSC.ReplyPort < 0 || c.OSC.ReplyPort > 65535 {
+			return errors.New("osc.reply_port must be between 0 and 65535")
+		}
+		for i, t := range c.OSC.FeedbackTargets {
+			if _, port, err := net.SplitHostPort(t); err != nil || port == "" {
+				return fmt.Errorf("osc.feedback_targets[%d] must be host:port", i)
+			}
+		}
+		for name, db := range c.OSC.Presets {
+			if name == "" || strings.Contains(name, "/") {
+				return fmt.Errorf("osc.presets: invalid preset name %q", name)
+			}
+			if db < c.CamillaDSP.MinDB || db > c.CamillaDSP.MaxDB {
+				return fmt.Errorf("osc.presets.%s must be within camilladsp.min_db..max_db", name)
+			}
+		}
+	}
+	if c.LED.Enabled {
+		switch c.LED.Driver {
+		case ledDriverWS2812, ledDriverAPA102, ledDriverPWM:
+		default:
+			return fmt.Errorf("led.driver must be %q, %q or %q", ledDriverWS2812, ledDriverAPA102, ledDriverPWM)
+		}
+		if c.LED.Device == "" {
+			return errors.New("led.device is empty")
+		}
+		if c.LED.Pixels <= 0 {
+			return errors.New("led.pixels must be > 0")
+		}
+		if c.LED.Brightness < 0 || c.LED.Brightness > 1 {
+			return errors.New("led.brightness must be between 0 and 1")
+		}
+		if c.LED.Driver == ledDriverAPA102 && c.LED.SPISpeedHz <= 0 {
+			return errors.New("led.spi_speed_hz must be > 0")
+		}
+		if c.LED.Driver == ledDriverPWM && c.LED.PWMPeriodNS <= 0 {
+			return errors.New("led.pwm_period_ns must be > 0")
+		}
+		if _, err := c.LED.renderer(); err != nil {
+			return err
+		}
+	}
+	if c.IRTx.Enabled {
+		if err := c.IRTx.validate(); err != nil {
+			return err
+		}
+	}
+	if c.Alerts.Enabled {
+		if err := c.Alerts.validate(); err != nil {
+			return err
+		}
+	}
+	if c.UpdateCheck.Enabled {
+		if c.UpdateCheck.IntervalHours < 1 {
+			return errors.New("update_check.interval_hours must be >= 1")
+		}
+		if owner, name, ok := strings.Cut(c.UpdateCheck.Repository, "/"); !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+			return fmt.Errorf("update_check.repository must be \"owner/name\", got %q", c.UpdateCheck.Repository)
+		}
+	}
+	for i, cp := range c.ControlProtocols {
+		if cp.Type == "" {
+			return fmt.Errorf("control_protocols[%d].type is empty", i)
+		}
+		if _, err := newControlProtocol(cp, slog.New(slog.DiscardHandler)); err != nil {
+			return fmt.Errorf("control_protocols[%d]: %w", i, err)
+		}
+	}
+	if c.Webhooks.Event.Enabled && c.Webhooks.Event.TokenFile == "" {
+		return errors.New("webhooks.event.enabled is true but webhooks.event.token_file is empty")
+	}
+	if err := validateSecretRef(c.Webhooks.Event.TokenFile); err != nil {
+		return fmt.Errorf("webhooks.event.token_file: %w", err)
+	}
+
+	// WebSocket
+	if c.WebSocket.SendBuf <= 0 {
+		return errors.New("websocket.send_buf must be > 0")
+	}
+	if c.WebSocket.BroadcastBuf <= 0 {
+		return errors.New("websocket.broadcast_buf must be > 0")
+	}
+	if c.WebSocket.MaxClients < 0 {
+		return errors.New("websocket.max_clients must be >= 0")
+	}
+	if c.WebSocket.MaxClientsPerIP < 0 {
+		return errors.New("websocket.max_clients_per_ip must be >= 0")
+	}
+	if c.WebSocket.ReplayBuf < 0 {
+		return errors.New("websocket.replay_buf must be >= 0")
+	}
+	for topic, ms := range c.WebSocket.CoalesceMs {
+		if strings.TrimSpace(topic) == "" {
+			return errors.New("websocket.coalesce_ms has an empty topic")
+		}
+		if ms < 0 || ms > 10000 {
+			return fmt.Errorf("websocket.coalesce_ms.%s must be between 0 and 10000", topic)
+		}
+	}
+	for i, o := range c.WebSocket.AllowedOrigins {
+		if strings.TrimSpace(o) == "" {
+			return fmt.Errorf("websocket.allowed_origins[%d] is empty", i)
+		}
+	}
+
+	// Outbound webhooks
+	for i, w := range c.OutboundWebhooks {
+		if w.URL == "" {
+			return fmt.Errorf("outbound_webhooks[%d].url is empty", i)
+		}
+		u, err := url.Parse(w.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("outbound_webhooks[%d].url must be an http(s) URL", i)
+		}
+		for _, e := range w.Events {
+			switch e {
+			case "volume_changed", "mute_changed", "player_changed", "zone_selected", "zone_link_changed", "output_changed", "encoder_changed", "device_down", "device_up", "ir_send", "dsp_connection_changed", "dsp_command_error", "limit_override_changed", "calibration_mode", "test_signal", "tuning_changed", "update_available", "exposure_warning", "ui_hint":
+			default:
+				return fmt.Errorf("outbound_webhooks[%d].events: unknown event %q", i, e)
+			}
+		}
+		if w.TimeoutMS < 0 {
+			return fmt.Errorf("outbound_webhooks[%d].timeout_ms must be >= 0", i)
+		}
+		if err := validateSecretRef(w.Secret); err != nil {
+			return fmt.Errorf("outbound_webhooks[%d].secret: %w", i, err)
+		}
+		if w.MaxRetries < 0 {
+			return fmt.Errorf("outbound_webhooks[%d].max_retries must be >= 0", i)
+		}
+	}
+
+	// Key combos
+	for i, k := range c.KeyCombos {
+		if len(k.Modifiers) == 0 {
+			return fmt.Errorf("key_combos[%d].modifiers must not be empty", i)
+		}
+		for _, name := range k.Modifiers {
+			if _, ok := modifierNames[strings.ToLower(name)]; !ok {
+				return fmt.Errorf("key_combos[%d].modifiers: unknown modifier %q (shift, ctrl, alt, meta)", i, name)
+			}
+		}
+		if _, ok := comboKeyCodes[k.Key]; !ok {
+			return fmt.Errorf("key_combos[%d].key must be \"volume_up\" or \"volume_down\"", i)
+		}
+		if (k.StepDB > 0) == (k.VolumeDB != nil) {
+			return fmt.Errorf("key_combos[%d] must set exactly one of step_db (> 0) or volume_db", i)
+		}
+	}
+
+	// Mute gestures
+	switch c.Mute.VolumeDownWhileMuted {
+	case "", "adjust", "ignore":
+	default:
+		return errors.New(`mute.volume_down_while_muted must be "adjust" or "ignore"`)
+	}
+	switch c.Shutdown.Action {
+	case "", shutdownActionNone, shutdownActionMute:
+	case shutdownActionVolume:
+		if c.Shutdown.VolumeDB > 0 {
+			return errors.New("shutdown.volume_db must be <= 0")
+		}
+	default:
+		return fmt.Errorf("shutdown.action must be %q, %q or %q", shutdownActionNone, shutdownActionMute, shutdownActionVolume)
+	}
+	if c.FadeIn.Enabled && c.FadeIn.DurationMS <= 0 {
+		return errors.New("fade_in.duration_ms must be > 0")
+	}
+	if c.Mute.RestoreVolume && !c.Mute.UnmuteOnVolumeUp {
+		return errors.New("mute.restore_volume requires mute.unmute_on_volume_up")
+	}
+
+	// Control arbitration
+	switch c.Arbitration.Policy {
+	case "", arbitrationNone, arbitrationPhysical, arbitrationLastWriter:
+	default:
+		return errors.New(`arbitration.policy must be "none", "physical" or "last_writer"`)
+	}
+	if c.Arbitration.LockoutMS < 0 || c.Arbitration.LockoutMS > 60000 {
+		return errors.New("arbitration.lockout_ms must be between 0 and 60000")
+	}
+	if c.UIHints.VolumeOverlayMS < 0 || c.UIHints.VolumeOverlayMS > 60000 {
+		return errors.New("ui_hints.volume_overlay_ms must be between 0 and 60000")
+	}
+	if c.UIHints.MuteFlashMS < 0 || c.UIHints.MuteFlashMS > 60000 {
+		return errors.New("ui_hints.mute_flash_ms must be between 0 and 60000")
+	}
+	for i, o := range c.Arbitration.PhysicalOrigins {
+		if o == "" {
+			return fmt.Errorf("arbitration.physical_origins[%d] must not be empty", i)
+		}
+	}
+
+	// Rotary encoder
+	if err := c.Rotary.validate(); err != nil {
+		return err
+	}
+
+	// Logging
+	if c.Logging.Level == "" {
+		return errors.New("logging.level must not be empty")
+	}
+
+	return nil
+}
+
+// validateCamillaDSP checks a (possibly zone-resolved) CamillaDSP block.
+// prefix is used in error messages (e.g. "camilladsp", "zones[1]").
+func validateCamillaDSP(prefix string, c CamillaDSPConfig) error {
+	if c.WsURL == "" {
+		return fmt.Errorf("%s.ws_url must not be empty", prefix)
+	}
+	u, err := url.Parse(c.WsURL)
+	if err != nil || (u.Scheme != "ws" && u.Scheme != "wss") {
+		return fmt.Errorf("%s.ws_url must be a ws:// or wss:// URL", prefix)
+	}
+	if (c.CAFile != "" || c.InsecureSkipVerify) && u.Scheme != "wss" {
+		return fmt.Errorf("%s.ca_file and %s.insecure_skip_verify require a wss:// ws_url", prefix, prefix)
+	}
+	if c.Password != "" && c.PasswordFile != "" {
+		return fmt.Errorf("%s.password and %s.password_file are both set; keep password_file", prefix, prefix)
+	}
+	if (c.Password != "" || c.PasswordFile != "") && c.Username == "" {
+		return fmt.Errorf("%s.password_file requires %s.username", prefix, prefix)
+	}
+	if err := validateSecretRef(c.PasswordFile); err != nil {
+		return fmt.Errorf("%s.password_file: %w", prefix, err)
+	}
+	if c.TimeoutMS <= 0 {
+		return fmt.Errorf("%s.timeout_ms must be > 0", prefix)
+	}
+	if c.KeepaliveSec < 0 {
+		return fmt.Errorf("%s.keepalive_sec must be >= 0", prefix)
+	}
+	if err := c.WakeOnLAN.validate(prefix); err != nil {
+		return err
+	}
+	if c.Reconnect.Attempts < 1 {
+		return fmt.Errorf("%s.reconnect.attempts must be >= 1", prefix)
+	}
+	if c.Reconnect.IntervalMS <= 0 {
+		return fmt.Errorf("%s.reconnect.interval_ms must be > 0", prefix)
+	}
+	if c.Reconnect.BackoffFactor < 1 {
+		return fmt.Errorf("%s.reconnect.backoff_factor must be >= 1", prefix)
+	}
+	if c.Reconnect.MaxBackoffMS < c.Reconnect.IntervalMS {
+		return fmt.Errorf("%s.reconnect.max_backoff_ms must be >= %s.reconnect.interval_ms", prefix, prefix)
+	}
+	for name, ms := range c.CommandTimeoutsMS {
+		if name == "" || ms <= 0 {
+			return fmt.Errorf("%s.command_timeouts_ms.%s must be > 0", prefix, name)
+		}
+	}
+	if c.MinDB > c.MaxDB {
+		return fmt.Errorf("%s.min_db must be <= %s.max_db", prefix, prefix)
+	}
+	if c.UpdateHz <= 0 || c.UpdateHz > 1000 {
+		return fmt.Errorf("%s.update_hz must be between 1 and 1000", prefix)
+	}
+	if c.IdleHz < 0 || c.IdleHz > c.UpdateHz {
+		return fmt.Errorf("%s.idle_hz must be between 0 and %s.update_hz", prefix, prefix)
+	}
+	if c.MonitorHz < 0 || c.MonitorHz > maxMonitorHz {
+		return fmt.Errorf("%s.monitor_hz must be between 0 and %d", prefix, maxMonitorHz)
+	}
+	// Volume is kept in millibels (volume_mb.go): steps finer than 0.01 dB can't be represented.
+	if c.StepDB < 0 || c.StepDB > c.MaxDB-c.MinDB || (c.StepDB > 0 && c.StepDB < 0.01) {
+		return fmt.Errorf("%s.step_db must be 0 or between 0.01 and the volume range", prefix)
+	}
+	if c.DisplayStepDB < 0.01 {
+		return fmt.Errorf("%s.display_step_db must be >= 0.01", prefix)
+	}
+	if c.UserMinDB != nil && (*c.UserMinDB < c.MinDB || *c.UserMinDB > c.MaxDB) {
+		return fmt.Errorf("%s.user_min_db must be between %s.min_db and %s.max_db", prefix, prefix, prefix)
+	}
+	if c.UserMaxDB != nil && (*c.UserMaxDB < c.MinDB || *c.UserMaxDB > c.MaxDB) {
+		return fmt.Errorf("%s.user_max_db must be between %s.min_db and %s.max_db", prefix, prefix, prefix)
+	}
+	if c.UserMinDB != nil && c.UserMaxDB != nil && *c.UserMinDB > *c.UserMaxDB {
+		return fmt.Errorf("%s.user_min_db must be <= %s.user_max_db", prefix, prefix)
+	}
+	if c.RampUpMS < 0 || c.RampDownMS < 0 {
+		return fmt.Errorf("%s.ramp_up_ms and %s.ramp_down_ms must be >= 0", prefix, prefix)
+	}
+	return nil
+}
+
+// validate checks the velocity section (also used for runtime tuning updates).
+func (v VelocityFileConfig) validate() error {
+	mode := v.Mode
+	if mode == "" {
+		mode = string(VelocityModeAccelerating)
+	}
+	if mode != string(VelocityModeAccelerating) && mode != string(VelocityModeConstant) {
+		return fmt.Errorf("velocity.mode must be %q or %q", VelocityModeAccelerating, VelocityModeConstant)
+	}
+	if v.MaxDBPerSec < 0 {
+		return errors.New("velocity.max_db_per_sec must be >= 0")
+	}
+	if v.HoldTimeoutMS < 0 {
+		return errors.New("velocity.hold_timeout_ms must be >= 0")
+	}
+	if v.RampDBPerSec < 0 {
+		return errors.New("velocity.ramp_db_per_sec must be >= 0")
+	}
+	if v.ExternalChangeDB < 0 {
+		return errors.New("velocity.external_change_db must be >= 0")
+	}
+	if v.DangerZoneDB < 0 {
+		return errors.New("velocity.danger_zone_db must be >= 0")
+	}
+	if v.DangerVelMaxDBPerSec < 0 {
+		return errors.New("velocity.danger_vel_max_db_per_sec must be >= 0")
+	}
+	if v.DangerVelMinNear0DBPerS < 0 {
+		return errors.New("velocity.danger_vel_min_near0_db_per_sec must be >= 0")
+	}
+	if v.DangerVelMinNear0DBPerS > v.DangerVelMaxDBPerSec {
+		return errors.New("velocity.danger_vel_min_near0_db_per_sec must be <= velocity.danger_vel_max_db_per_sec")
+	}
+	if v.DangerRotaryDBPerStep < 0 {
+		return errors.New("velocity.danger_rotary_db_per_step must be >= 0")
+	}
+	if v.HoldCheckpointDB != nil && *v.HoldCheckpointDB > 0 {
+		return errors.New("velocity.hold_checkpoint_db must be <= 0")
+	}
+	return nil
+}
+
+// validate checks the rotary section (also used for runtime tuning updates).
+func (r RotaryConfig) validate() error {
+	if r.DbPerStep < 0 {
+		return errors.New("rotary.db_per_step must be >= 0")
+	}
+	if r.VelocityWindowMS < 0 {
+		return errors.New("rotary.velocity_window_ms must be >= 0")
+	}
+	if r.VelocityMultiplier < 1 {
+		return errors.New("rotary.velocity_multiplier must be >= 1")
+	}
+	if r.VelocityThreshold < 1 {
+		return errors.New("rotary.velocity_threshold must be >= 1")
+	}
+	for i, p := range r.Curve {
+		if p.Rate < 0 {
+			return fmt.Errorf("rotary.curve[%d].rate must be >= 0", i)
+		}
+		if p.DbPerStep <= 0 {
+			return fmt.Errorf("rotary.curve[%d].db_per_step must be > 0", i)
+		}
+		if i > 0 && p.Rate <= r.Curve[i-1].Rate {
+			return fmt.Errorf("rotary.curve[%d].rate must be greater than the previous point", i)
+		}
+	}
+	if len(r.Curve) > 0 && r.VelocityWindowMS == 0 {
+		return errors.New("rotary.velocity_window_ms must be > 0 when rotary.curve is set")
+	}
+	if r.DebounceMS < 0 {
+		return errors.New("rotary.debounce_ms must be >= 0")
+	}
+	switch r.ButtonAction {
+	case "", "mute", "mode", "none":
+	default:
+		return errors.New(`rotary.button_action must be "mute", "mode" or "none"`)
+	}
+	if r.ModeTimeoutMS < 0 {
+		return errors.New("rotary.mode_timeout_ms must be >= 0")
+	}
+	if n := len(r.BalanceFaders); n != 0 && n != 2 {
+		return errors.New("rotary.balance_faders must list exactly 2 faders [left, right]")
+	}
+	for _, f := range r.BalanceFaders {
+		if f < 1 || f > 4 {
+			return errors.New("rotary.balance_faders entries must be aux faders 1-4")
+		}
+	}
+	if r.SubFader < 0 || r.SubFader > 4 {
+		return errors.New("rotary.sub_fader must be an aux fader 1-4 (0 disables)")
+	}
+	return nil
+}
+
+// ToVelocityConfig converts file config + CamillaDSP bounds into the internal engine config.
+func (c *Config) ToVelocityConfig() VelocityConfig {
+	return c.ToVelocityConfigFor(c.CamillaDSP)
+}
+
+// ToReducerPolicy is ToReducerPolicyFor the primary CamillaDSP instance.
+func (c *Config) ToReducerPolicy() ReducerPolicy {
+	return c.ToReducerPolicyFor(c.CamillaDSP)
+}
+
+// ToVelocityConfigFor converts file config + the given zone's CamillaDSP bounds into
+// the internal engine config.
+func (c *Config) ToVelocityConfigFor(dsp CamillaDSPConfig) VelocityConfig {
+	cfg := VelocityConfig{
+		MinDB: dsp.MinDB,
+		MaxDB: dsp.MaxDB,
+	}
+	c.Velocity.applyTo(&cfg)
+
+	return cfg
+}
+
+// ToReducerPolicyFor builds the reducer policy for one zone's CamillaDSP settings.
+// LimitOverrideToken is read from its secret at startup and set by the caller.
+func (c *Config) ToReducerPolicyFor(dsp CamillaDSPConfig) ReducerPolicy {
+	return ReducerPolicy{
+		StepDB:        dsp.StepDB,
+		DisplayStepDB: dsp.DisplayStepDB,
+
+		UserMinDB:            dsp.UserMinDB,
+		UserMaxDB:            dsp.UserMaxDB,
+		LimitOverrideTimeout: time.Duration(c.LimitOverride.TimeoutSec) * time.Second,
+
+		RampUp:   time.Duration(dsp.RampUpMS) * time.Millisecond,
+		RampDown: time.Duration(dsp.RampDownMS) * time.Millisecond,
+
+		TestSignal:    c.TestSignal,
+		Normalization: c.Normalization,
+
+		CalibrationReferenceDB: c.Calibration.ReferenceDB,
+		CalibrationRestore:     c.Calibration.RestoreOnExit,
+
+		UnmuteOnVolumeUp:           c.Mute.UnmuteOnVolumeUp,
+		UnmuteRestoreVolume:        c.Mute.RestoreVolume,
+		IgnoreVolumeDownWhileMuted: c.Mute.VolumeDownWhileMuted == "ignore",
+
+		FadeIn:            c.FadeIn.duration(),
+		FadeInOnReconnect: c.FadeIn.OnReconnect,
+
+		WakeOnLAN: dsp.WakeOnLAN,
+
+		ArbitrationPolicy:  c.Arbitration.Policy,
+		ArbitrationLockout: time.Duration(c.Arbitration.LockoutMS) * time.Millisecond,
+		PhysicalOrigins:    c.Arbitration.PhysicalOrigins,
+
+		VolumeOverlay: time.Duration(c.UIHints.VolumeOverlayMS) * time.Millisecond,
+		MuteFlash:     time.Duration(c.UIHints.MuteFlashMS) * time.Millisecond,
+	}
+}
+
+// applyTo sets the controller dynamics in cfg from the velocity section, leaving
+// bounds, limits and other non-velocity fields untouched.
+func (v VelocityFileConfig) applyTo(cfg *VelocityConfig) {
+	cfg.Mode = VelocityMode(v.Mode)
+	cfg.VelMaxDBPerS = v.MaxDBPerSec
+	cfg.HoldTimeout = time.Duration(v.HoldTimeoutMS) * time.Millisecond
+	cfg.RampDBPerS = v.RampDBPerSec
+	cfg.ExternalChangeDB = v.ExternalChangeDB
+	cfg.DangerZoneDB = v.DangerZoneDB
+	cfg.DangerVelMaxDBPerS = v.DangerVelMaxDBPerSec
+	cfg.DangerVelMinNear0DBPerS = v.DangerVelMinNear0DBPerS
+	cfg.DangerRotaryDBPerStep = v.DangerRotaryDBPerStep
+	cfg.DangerRampAbsolute = v.DangerRampAbsolute
+	cfg.HoldCheckpointDB = nil
+	if v.HoldCheckpointDB != nil {
+		cp := *v.HoldCheckpointDB
+		cfg.HoldCheckpointDB = &cp
+	}
+
+	// Mode-specific mapping
+	switch cfg.Mode {
+	case VelocityModeConstant:
+		cfg.AccelTime = v.TurboMult // turbo multiplier
+		cfg.DecayTau = v.TurboDelay // turbo delay (seconds)
+	default:
+		cfg.Mode = VelocityModeAccelerating
+		cfg.AccelTime = v.AccelTimeSec
+		cfg.DecayTau = v.DecayTauSec
+	}
+}
+
+// expandPaths expands a leading "~" in the config's file and socket paths.
+func (c *Config) expandPaths() {
+	c.IPC.SocketPath = ExpandPath(c.IPC.SocketPath)
+	c.API.Socket = ExpandPath(c.API.Socket)
+	for i := range c.Inputs {
+		c.Inputs[i].Path = ExpandPath(c.Inputs[i].Path)
+	}
+	c.Plex.TokenFile = ExpandPath(c.Plex.TokenFile)
+	c.CamillaDSP.PasswordFile = ExpandPath(c.CamillaDSP.PasswordFile)
+	for i := range c.OutboundWebhooks {
+		c.OutboundWebhooks[i].Secret = ExpandPath(c.OutboundWebhooks[i].Secret)
+	}
+	for i := range c.Alerts.Channels {
+		c.Alerts.Channels[i].Token = ExpandPath(c.Alerts.Channels[i].Token)
+		c.Alerts.Channels[i].Secret = ExpandPath(c.Alerts.Channels[i].Secret)
+	}
+	c.TidalConnect.LogFile = ExpandPath(c.TidalConnect.LogFile)
+	c.Scrobble.QueueFile = ExpandPath(c.Scrobble.QueueFile)
+	c.Stats.File = ExpandPath(c.Stats.File)
+	c.Scrobble.LastFM.SessionKeyFile = ExpandPath(c.Scrobble.LastFM.SessionKeyFile)
+	c.Webhooks.Event.TokenFile = ExpandPath(c.Webhooks.Event.TokenFile)
+	c.LimitOverride.TokenFile = ExpandPath(c.LimitOverride.TokenFile)
+	c.Diagnostics.CrashDumpDir = ExpandPath(c.Diagnostics.CrashDumpDir)
+	c.Diagnostics.TapFile = ExpandPath(c.Diagnostics.TapFile)
+}
+
+// ExpandPath expands a leading "~" in a path using $HOME.
+// This is handy for config values like plex.token_file.
+func ExpandPath(p string) string {
+	if p == "" {
+		return p
+	}
+	if p[0] != '~' {
 		return p
 	}
 	home, err := os.UserHomeDir()
 	if err != nil {
-		return p
+		// No home directory (e.g. a container user without $HOME): resolve
+		// against the working directory instead.
+		if home, err = os.Getwd(); err != nil {
+			return p
+		}
 	}
 	if p == "~" {
 		return home
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ============================================================================
+// `config` subcommand
+// ============================================================================
+//   streamerbrainz config validate [-config path]
+//       Load and validate a config file, then print the effective config
+//       (defaults, drop-ins and env overrides applied, paths expanded) as YAML.
+//   streamerbrainz config schema
+//       Print a JSON Schema of the config file for editors and CI.
+// ============================================================================
+
+const configSchemaID = "https://github.com/nikoskalogridis/streamerbrainz/config.schema.json"
+
+func printConfigUsage() {
+	fmt.Println("USAGE:")
+	fmt.Println("  streamerbrainz config validate [-config path]")
+	fmt.Println("        Validate a config file and print the effective config (defaults, conf.d")
+	fmt.Println("        drop-ins and STREAMERBRAINZ_* overrides applied)")
+	fmt.Println()
+	fmt.Println("  streamerbrainz config schema")
+	fmt.Println("        Print a JSON Schema for the config file")
+	fmt.Println()
+}
+
+// runConfigSubcommand handles `streamerbrainz config ...`.
+func runConfigSubcommand(args []string) {
+	if len(args) == 0 {
+		printConfigUsage()
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "validate":
+		fs := flag.NewFlagSet("config validate", flag.ExitOnError)
+		configPath := fs.String("config", "", "Path to YAML config file")
+		fs.Parse(args[1:])
+		*configPath = ResolveConfigPath(*configPath)
+
+		cfg, err := loadEffectiveConfig(*configPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		b, err := yaml.Marshal(cfg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error: marshal config:", err)
+			os.Exit(1)
+		}
+		os.Stdout.Write(b)
+		for _, w := range cfg.deprecations {
+			fmt.Fprintln(os.Stderr, "warning:", w)
+		}
+		fmt.Fprintf(os.Stderr, "%s: config OK\n", *configPath)
+
+	case "schema":
+		if err := writeConfigSchema(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+
+	case "-h", "-help", "--help", "help":
+		printConfigUsage()
+
+	default:
+		fmt.Fprintf(os.Stderr, "error: unknown config subcommand %q\n", args[0])
+		printConfigUsage()
+		os.Exit(2)
+	}
+}
+
+// loadEffectiveConfig loads a config file, expands paths and validates it.
+func loadEffectiveConfig(path string) (Config, error) {
+	cfg, err := LoadConfigFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.expandPaths()
+	if err := cfg.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid config: %w", err)
+	}
+	return cfg, nil
+}
+
+// configSchemaEnums lists the allowed values of enum-like string fields, by YAML path
+// ("[]" marks list items).
+var configSchemaEnums = map[string][]string{
+	"input_reader":                 {inputReaderEpoll, inputReaderGoroutine},
+	"inputs[].type":                {string(InputDeviceTypeKey), string(InputDeviceTypeRotary), string(InputDeviceTypeFifo), string(InputDeviceTypeHotkey)},
+	"inputs[].keymap[].action":     keymapActionNames(),
+	"inputs[].no_release":          {"", noReleaseStep, noReleaseRelease},
+	"velocity.mode":                {string(VelocityModeAccelerating), string(VelocityModeConstant)},
+	"mute.volume_down_while_muted": {"", "adjust", "ignore"},
+	"arbitration.policy":           {"", arbitrationNone, arbitrationPhysical, arbitrationLastWriter},
+	"shutdown.action":              {"", shutdownActionNone, shutdownActionMute, shutdownActionVolume},
+	"rotary.button_action":         {"", "mute", "mode", "none"},
+	"logging.level":                {"error", "warn", "warning", "info", "debug"},
+	"led.driver":                   {ledDriverWS2812, ledDriverAPA102, ledDriverPWM},
+	"ir_tx.backend":                {irTxBackendIRSend, irTxBackendLirc},
+	"alerts.channels[].type":       {alertChannelNtfy, alertChannelPushover, alertChannelWebhook},
+	"calibration.long_press_key":   {"", "mute", "audio", "play_pause", "stop", "button"},
+	"normalization.mode":           {"", NormalizationModeTrack, NormalizationModeAlbum},
+}
+
+// writeConfigSchema writes a JSON Schema (draft 2020-12) for Config, with defaults
+// taken from DefaultConfig.
+func writeConfigSchema(w io.Writer) error {
+	schema := schemaFor(reflect.TypeOf(Config{}), reflect.ValueOf(DefaultConfig()), "")
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["$id"] = configSchemaID
+	schema["title"] = "StreamerBrainz configuration"
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(schema)
+}
+
+// schemaFor builds the schema of t. def is the default value (invalid if none) and
+// path the YAML path used for configSchemaEnums.
+func schemaFor(t reflect.Type, def reflect.Value, path string) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		if def.IsValid() {
+			if def.IsNil() {
+				def = reflect.Value{}
+			} else {
+				def = def.Elem()
+			}
+		}
+	}
+
+	// Free-form blocks (e.g. control_protocols[].options) are validated elsewhere.
+	if t == reflect.TypeOf(yaml.Node{}) {
+		return map[string]any{}
+	}
+
+	s := map[string]any{}
+	switch t.Kind() {
+	case reflect.Struct:
+		props := map[string]any{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, ok := yamlFieldName(f)
+			if !ok {
+				continue
+			}
+			var fd reflect.Value
+			if def.IsValid() {
+				fd = def.Field(i)
+			}
+			props[name] = schemaFor(f.Type, fd, joinSchemaPath(path, name))
+		}
+		s["type"] = "object"
+		s["properties"] = props
+		// Matches LoadConfigFile's KnownFields(true): unknown keys are errors.
+		s["additionalProperties"] = false
+		return s
+
+	case reflect.Slice:
+		s["type"] = "array"
+		s["items"] = schemaFor(t.Elem(), reflect.Value{}, path+"[]")
+
+	case reflect.Map:
+		s["type"] = "object"
+		s["additionalProperties"] = schemaFor(t.Elem(), reflect.Value{}, path+"[]")
+
+	case reflect.String:
+		s["type"] = "string"
+		if values, ok := configSchemaEnums[path]; ok {
+			s["enum"] = values
+		}
+
+	case reflect.Bool:
+		s["type"] = "boolean"
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s["type"] = "integer"
+
+	case reflect.Float32, reflect.Float64:
+		s["type"] = "number"
+	}
+
+	if def.IsValid() && !def.IsZero() && t.Kind() != reflect.Slice && t.Kind() != reflect.Map {
+		s["default"] = def.Interface()
+	}
+	return s
+}
+
+// yamlFieldName returns the YAML key of a struct field (false if not serialized).
+func yamlFieldName(f reflect.StructField) (string, bool) {
+	if !f.IsExported() {
+		return "", false
+	}
+	tag := f.Tag.Get("yaml")
+	if tag == "-" {
+		return "", false
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = strings.ToLower(f.Name)
+	}
+	return name, true
+}
+
+func joinSchemaPath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
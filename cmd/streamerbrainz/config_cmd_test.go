@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteConfigSchema_DefaultsAndEnums(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeConfigSchema(&buf); err != nil {
+		t.Fatalf("writeConfigSchema: %v", err)
+	}
+
+	var schema struct {
+		AdditionalProperties bool `json:"additionalProperties"`
+		Properties           map[string]struct {
+			Properties map[string]struct {
+				Type    string   `json:"type"`
+				Default any      `json:"default"`
+				Enum    []string `json:"enum"`
+			} `json:"properties"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+	if schema.AdditionalProperties {
+		t.Fatalf("expected unknown top-level keys to be rejected")
+	}
+
+	level := schema.Properties["logging"].Properties["level"]
+	if level.Type != "string" || level.Default != "info" {
+		t.Fatalf("unexpected logging.level schema: %+v", level)
+	}
+	if len(level.Enum) == 0 {
+		t.Fatalf("expected logging.level enum")
+	}
+
+	hold := schema.Properties["velocity"].Properties["hold_timeout_ms"]
+	if hold.Type != "integer" {
+		t.Fatalf("expected velocity.hold_timeout_ms to be integer, got %q", hold.Type)
+	}
+}
+
+func TestLoadEffectiveConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	good := filepath.Join(dir, "good.yaml")
+	if err := os.WriteFile(good, []byte("logging:\n  level: debug\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loadEffectiveConfig(good)
+	if err != nil {
+		t.Fatalf("loadEffectiveConfig: %v", err)
+	}
+	if cfg.Logging.Level != "debug" {
+		t.Fatalf("expected overridden logging.level, got %q", cfg.Logging.Level)
+	}
+	if cfg.CamillaDSP.WsURL != DefaultConfig().CamillaDSP.WsURL {
+		t.Fatalf("expected default camilladsp.ws_url to be filled in")
+	}
+
+	typo := filepath.Join(dir, "typo.yaml")
+	if err := os.WriteFile(typo, []byte("loging:\n  level: debug\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadEffectiveConfig(typo); err == nil || !strings.Contains(err.Error(), "loging") {
+		t.Fatalf("expected unknown key error, got %v", err)
+	}
+}
+
+func TestLoadConfigFile_DropInsAndEnvOverrides(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte("camilladsp:\n  ws_url: ws://main:1234\n  max_db: -6\nlogging:\n  level: warn\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, configDropInDir), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	for name, body := range map[string]string{
+		"10-dsp.yaml":   "camilladsp:\n  ws_url: ws://dropin:1234\n",
+		"20-log.yaml":   "logging:\n  level: debug\n",
+		"30-empty.yaml": "# nothing here yet\n",
+		"ignored.txt":   "not: yaml: at all\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, configDropInDir, name), []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Setenv(envLogLevel, "error")
+	t.Setenv(envSocketPath, "")
+
+	cfg, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("LoadConfigFile: %v", err)
+	}
+	if cfg.CamillaDSP.WsURL != "ws://dropin:1234" {
+		t.Fatalf("expected drop-in ws_url, got %q", cfg.CamillaDSP.WsURL)
+	}
+	if cfg.CamillaDSP.MaxDB != -6 {
+		t.Fatalf("expected path file max_db kept alongside drop-in, got %v", cfg.CamillaDSP.MaxDB)
+	}
+	if cfg.Logging.Level != "error" {
+		t.Fatalf("expected env to override drop-in logging.level, got %q", cfg.Logging.Level)
+	}
+	if cfg.IPC.SocketPath != DefaultConfig().IPC.SocketPath {
+		t.Fatalf("expected empty env value to be ignored, got %q", cfg.IPC.SocketPath)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, configDropInDir, "40-typo.yaml"), []byte("loging: {}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfigFile(path); err == nil || !strings.Contains(err.Error(), "40-typo.yaml") {
+		t.Fatalf("expected drop-in error naming the fragment, got %v", err)
+	}
+}
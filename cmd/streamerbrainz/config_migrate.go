@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ============================================================================
+// Config migration
+// ============================================================================
+// Older configs used different keys for the same settings. Before the strict
+// decode, legacy keys are rewritten in the YAML tree to the current schema and a
+// deprecation warning is recorded for each, so existing files keep working until
+// users get around to editing them.
+//
+// Handled today (all end up in `inputs`, with type "key" unless given):
+//   input: /dev/input/event6               (single device path)
+//   ir: {device: /dev/input/event6}        (single device path)
+//   ir: {devices: [/dev/input/event6]}     (list of paths)
+//   ir: {input_devices: [{path, type}]}    (typed list)
+//
+// camilladsp.password (a literal) is still read as is, with a warning to move
+// it to camilladsp.password_file.
+// ============================================================================
+
+// currentConfigVersion is the schema version written by -print-default-config.
+// Files without a version are treated as pre-versioning and migrated by key.
+const currentConfigVersion = 1
+
+// migrateConfigNode rewrites legacy keys in a decoded YAML document in place and
+// returns one deprecation warning per migrated key.
+func migrateConfigNode(doc *yaml.Node) ([]string, error) {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	if v := mappingValue(root, "version"); v != nil {
+		var version int
+		if err := v.Decode(&version); err != nil {
+			return nil, fmt.Errorf("version: %w", err)
+		}
+		if version > currentConfigVersion {
+			return nil, fmt.Errorf("config version %d is newer than this build supports (%d)", version, currentConfigVersion)
+		}
+	}
+
+	var (
+		warnings []string
+		legacy   []*yaml.Node // migrated inputs entries
+	)
+
+	if v := mappingValue(root, "input"); v != nil {
+		if v.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("input: expected a device path")
+		}
+		legacy = append(legacy, legacyInputNode(v.Value, ""))
+		deleteMappingKey(root, "input")
+		warnings = append(warnings, "input is deprecated; use inputs: [{path: ..., type: key}]")
+	}
+
+	if ir := mappingValue(root, "ir"); ir != nil {
+		if ir.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("ir: expected a mapping")
+		}
+		for i := 0; i < len(ir.Content); i += 2 {
+			key, v := ir.Content[i].Value, ir.Content[i+1]
+			switch key {
+			case "device":
+				if v.Kind != yaml.ScalarNode {
+					return nil, fmt.Errorf("ir.device: expected a device path")
+				}
+				legacy = append(legacy, legacyInputNode(v.Value, ""))
+			case "devices":
+				var paths []string
+				if err := v.Decode(&paths); err != nil {
+					return nil, fmt.Errorf("ir.devices: %w", err)
+				}
+				for _, p := range paths {
+					legacy = append(legacy, legacyInputNode(p, ""))
+				}
+			case "input_devices":
+				var devs []InputDevice
+				if err := v.Decode(&devs); err != nil {
+					return nil, fmt.Errorf("ir.input_devices: %w", err)
+				}
+				for _, d := range devs {
+					legacy = append(legacy, legacyInputNode(d.Path, d.Type))
+				}
+			default:
+				return nil, fmt.Errorf("ir.%s is no longer supported", key)
+			}
+			warnings = append(warnings, fmt.Sprintf("ir.%s is deprecated; use inputs", key))
+		}
+		deleteMappingKey(root, "ir")
+	}
+
+	if dsp := mappingValue(root, "camilladsp"); dsp != nil && dsp.Kind == yaml.MappingNode && mappingValue(dsp, "password") != nil {
+		warnings = append(warnings, "camilladsp.password is deprecated; use camilladsp.password_file (a file, env:NAME or credential:NAME)")
+	}
+
+	if len(legacy) > 0 {
+		if mappingValue(root, "inputs") != nil {
+			return nil, fmt.Errorf("inputs and legacy input keys are both set; remove the legacy keys")
+		}
+		root.Content = append(root.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "inputs"},
+			&yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: legacy},
+		)
+	}
+
+	return warnings, nil
+}
+
+func legacyInputNode(path string, typ InputDeviceType) *yaml.Node {
+	if typ == "" {
+		typ = InputDeviceTypeKey
+	}
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: "path"},
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: path},
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: "type"},
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: string(typ)},
+	}}
+}
+
+// mappingValue returns the value node for key in a mapping node (nil if absent).
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func deleteMappingKey(m *yaml.Node, key string) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content = append(m.Content[:i], m.Content[i+2:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigFile_MigratesLegacyInputKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	body := "ir:\n  device: /dev/input/event3\n  input_devices:\n    - path: /dev/input/event7\n      type: rotary\n"
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("LoadConfigFile: %v", err)
+	}
+	want := []InputDevice{
+		{Path: "/dev/input/event3", Type: InputDeviceTypeKey},
+		{Path: "/dev/input/event7", Type: InputDeviceTypeRotary},
+	}
+	if !reflect.DeepEqual(cfg.Inputs, want) {
+		t.Fatalf("inputs = %#v, want %#v", cfg.Inputs, want)
+	}
+	if len(cfg.deprecations) != 2 || !strings.Contains(cfg.deprecations[0], "ir.device is deprecated") {
+		t.Fatalf("expected one deprecation per legacy key, got %q", cfg.deprecations)
+	}
+}
+
+func TestLoadConfigFile_MigrationErrors(t *testing.T) {
+	for name, body := range map[string]string{
+		"newer version":    "version: 99\n",
+		"legacy and new":   "input: /dev/input/event3\ninputs:\n  - path: /dev/input/event4\n    type: key\n",
+		"unknown ir field": "ir:\n  repeat_ms: 100\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadConfigFile(path); err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
+
+func TestLoadConfigFile_CurrentConfigHasNoDeprecations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("version: 1\ninputs:\n  - path: /dev/input/event3\n    type: key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("LoadConfigFile: %v", err)
+	}
+	if len(cfg.deprecations) != 0 {
+		t.Fatalf("expected no deprecations, got %q", cfg.deprecations)
+	}
+}
+
+func TestLoadConfigFile_WarnsAboutLiteralCamillaDSPPassword(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("camilladsp:\n  username: admin\n  password: s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("LoadConfigFile: %v", err)
+	}
+	if cfg.CamillaDSP.Password != "s3cret" {
+		t.Fatalf("password = %q, want it kept", cfg.CamillaDSP.Password)
+	}
+	if len(cfg.deprecations) != 1 || !strings.Contains(cfg.deprecations[0], "camilladsp.password_file") {
+		t.Fatalf("deprecations = %q", cfg.deprecations)
+	}
+}
+
+func TestLoadConfigFile_EmptyInputs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("inputs: []\ninputs_optional: true\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("LoadConfigFile: %v", err)
+	}
+	if len(cfg.Inputs) != 0 || !cfg.InputsOptional {
+		t.Fatalf("expected no inputs and inputs_optional, got %#v (optional %v)", cfg.Inputs, cfg.InputsOptional)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+}
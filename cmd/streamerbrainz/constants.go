@@ -1,10 +1,26 @@
 package main
 
+import "time"
+
 // Linux input event types and codes (from <linux/input.h>)
 const (
+	EV_SYN = 0x00
 	EV_KEY = 0x01
 	EV_REL = 0x02
+	EV_MSC = 0x04
+
+	MSC_SCAN = 0x04 // raw scancode (rc-core IR receivers, HID)
+
+	SYN_REPORT = 0
 
+	KEY_LEFTCTRL     = 29
+	KEY_LEFTSHIFT    = 42
+	KEY_RIGHTSHIFT   = 54
+	KEY_LEFTALT      = 56
+	KEY_RIGHTCTRL    = 97
+	KEY_RIGHTALT     = 100
+	KEY_LEFTMETA     = 125
+	KEY_RIGHTMETA    = 126
 	KEY_MUTE         = 113
 	KEY_VOLUMEDOWN   = 114
 	KEY_VOLUMEUP     = 115
@@ -14,11 +30,17 @@ const (
 	KEY_NEXTSONG     = 163
 	KEY_PLAYCD       = 200
 	KEY_PAUSECD      = 201
+	KEY_AUDIO        = 392   // output select (speakers/headphones)
+	BTN_0            = 0x100 // rotary encoder push-button (gpio-keys default)
 
 	// Rotary encoder relative axis codes
 	REL_DIAL  = 0x07
 	REL_WHEEL = 0x08
 	REL_MISC  = 0x09
+
+	// High-resolution wheel: 120 units per detent (a device sending it also sends REL_WHEEL)
+	REL_WHEEL_HI_RES    = 0x0B
+	hiResUnitsPerDetent = 120
 )
 
 // Input event value constants
@@ -30,11 +52,47 @@ const (
 
 // Velocity-based volume control configuration
 const (
-	defaultUpdateHz      = 30   // Update loop frequency (Hz)
-	defaultVelMaxDBPerS  = 15.0 // Maximum velocity in dB/s
-	defaultAccelTime     = 2.0  // Time to reach max velocity (seconds)
-	defaultDecayTau      = 0.2  // Decay time constant (seconds)
-	defaultReadTimeoutMS = 500  // Default timeout for reading websocket responses (ms)
+	defaultUpdateHz            = 30   // Update loop frequency (Hz)
+	defaultIdleHz              = 1    // Housekeeping tick frequency while nothing is moving (Hz)
+	defaultVelMaxDBPerS        = 15.0 // Maximum velocity in dB/s
+	defaultAccelTime           = 2.0  // Time to reach max velocity (seconds)
+	defaultDecayTau            = 0.2  // Decay time constant (seconds)
+	defaultReadTimeoutMS       = 500  // Default timeout for reading websocket responses (ms)
+	defaultCamillaKeepaliveSec = 15   // Idle time before the CamillaDSP connection is pinged (s)
+	maxMonitorHz               = 50   // Fastest allowed meter polling (camilladsp.monitor_hz)
+
+	// CamillaDSP (re)connect pacing (camilladsp.reconnect)
+	defaultCamillaConnectAttempts     = 10   // Dial attempts per (re)connect
+	defaultCamillaConnectIntervalMS   = 500  // Wait after the first failed attempt (ms)
+	defaultCamillaConnectMaxBackoffMS = 5000 // Backoff cap (ms)
+
+	// Slow-repeating inputs (inputs[].repeat_interval_ms): a release or repeat gap up to
+	// repeatGraceFactor intervals keeps a hold alive.
+	maxRepeatIntervalMS     = 1000
+	repeatGraceFactor       = 1.5
+	maxHoldTimeoutExtension = 2 * maxRepeatIntervalMS * time.Millisecond // cap on VolumeHeld.HoldTimeoutMS
+	defaultDisplayStepDB    = 0.1                                        // Rounding of volume in broadcasts/snapshots (dB)
+
+	defaultLimitOverrideTimeoutSec = 1800 // Longest a limit_override lasts before the user limits return
+	defaultCalibrationReferenceDB  = -20.0
+	defaultCalibrationLongPressMS  = 2000
+	defaultArbitrationLockoutMS    = 1000 // How long the controlling source keeps the volume after its last input
+	defaultVolumeOverlayMS         = 2000 // ui_hint: how long displays show the volume after a change
+	defaultMuteFlashMS             = 1000 // ui_hint: how long displays flash the mute icon
+	defaultTestSignalLevelDB       = -30.0
+	defaultTestSignalDurationSec   = 5
+	testSignalMaxDuration          = 60 * time.Second // A test signal never plays longer than this
+	defaultNormalizationMaxBoostDB = 6.0
+	defaultNormalizationMaxCutDB   = 15.0
+	defaultStatsFile               = "~/.local/state/streamerbrainz/stats.json"
+	defaultStatsRetentionDays      = 365
+	defaultStatsLoudThresholdDB    = -10.0 // Set relative to the system's calibration (see docs/stats.md)
+	defaultExposureDailyBudgetMin  = 60.0
+	defaultExposureWarnAtPercent   = 80.0
+	defaultExposureDimToDB         = -15.0
+	defaultFadeInMS                = 3000
+	defaultTapMaxMB                = 10
+	defaultExternalChangeDB        = 1.0
 
 	// Danger zone (near max volume):
 	//
@@ -51,11 +109,50 @@ const (
 	safeDefaultDB = -45.0 // Safe default volume when query fails (dB)
 
 	// Volume update threshold
-	volumeUpdateThresholdDB = 0.02 // Minimum volume difference to send update (dB)
+	volumeUpdateThresholdMB Millibel = 2 // Minimum volume difference to send update (0.02 dB)
+
+	// Commands the effects worker takes from its queue at once (pipelined when enabled)
+	maxEffectsBatch = 16
+
+	// CamillaDSP reachability probe while unreachable (see CamillaDSPState.Unreachable)
+	camillaProbeInterval = 10 * time.Second
+
+	// How long a sent SetMute counts as in flight when resolving toggles (see expectedMute)
+	muteSentTimeout = 2 * time.Second
 
 	// Rotary encoder configuration defaults
 	defaultRotaryDbPerStep          = 0.5 // Default dB change per encoder step
 	defaultRotaryVelocityWindowMS   = 200 // Time window for velocity detection (ms)
 	defaultRotaryVelocityMultiplier = 2.0 // Multiplier for "fast spinning"
 	defaultRotaryVelocityThreshold  = 3   // Steps in window to trigger velocity mode
+
+	// Rotary encoder modes (push-button cycling)
+	defaultRotaryModeTimeoutMS = 5000  // Revert to volume mode after this idle time (ms)
+	balanceMaxDB               = 12.0  // Max attenuation of either channel in balance mode (dB)
+	subLevelMinDB              = -20.0 // Sub level range in sub mode (dB)
+	subLevelMaxDB              = 10.0
+
+	// OSC server
+	defaultOSCPort = 9000 // UDP port (TouchOSC/Open Stage Control convention)
+
+	// Input device reconnect
+	defaultInputReconnectMaxRetries       = 10    // Backoff retries before waiting for hotplug only
+	defaultInputReconnectInitialBackoffMS = 500   // First reopen delay (ms)
+	defaultInputReconnectMaxBackoffMS     = 30000 // Backoff cap (ms)
+
+	// Daemon loop diagnostics
+	defaultLatencyWarnMS    = 50               // Warn when an event takes longer to reach the effects worker (ms)
+	defaultJitterWarnMS     = 50               // Warn when a tick is this far off its period (ms)
+	diagnosticsWarnInterval = 10 * time.Second // Minimum time between repeated warnings
+
+	// Alerts
+	defaultAlertDSPDisconnectedAfterSec = 30  // CamillaDSP outage before alerting (s)
+	defaultAlertCooldownSec             = 900 // Minimum time between repeats of one alert (s)
+
+	// Control API Unix socket
+	defaultAPISocketMode = "0660" // Owner and group (e.g. the reverse proxy) may connect
+
+	// Release check
+	defaultUpdateCheckIntervalHours = 24
+	defaultUpdateCheckRepository    = "nikoskalogridis/streamerbrainz"
 )
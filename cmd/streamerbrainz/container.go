@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ============================================================================
+// Container mode
+// ============================================================================
+// `-container` (or STREAMERBRAINZ_CONTAINER=1, which subcommands such as
+// `ctl ready` pick up too) adapts the daemon to a container:
+//
+//   - the config is /config/config.yaml (a mounted volume); if it isn't there
+//     the daemon runs from defaults plus the STREAMERBRAINZ_* environment
+//   - no input devices by default (inputs_optional on), statistics in
+//     /data/stats.json
+//   - GET /readyz (and `ctl ready`) report when CamillaDSP answers; GET
+//     /healthz reports liveness
+//
+// Outside this mode too: a "~" path resolves against the working directory
+// when there is no home directory, and shutdown is bounded by shutdownGrace
+// (a second SIGTERM/SIGINT exits at once), so `docker stop` never has to
+// resort to SIGKILL.
+//
+// `-print-default-config -container` prints the container config with a
+// docker-compose example.
+// ============================================================================
+
+const (
+	containerConfigPath = "/config/config.yaml"
+	containerStatsFile  = "/data/stats.json"
+)
+
+// containerMode reports whether container mode is on.
+func containerMode() bool {
+	on, _ := strconv.ParseBool(os.Getenv(envContainer))
+	return on
+}
+
+// setContainerMode turns container mode on for this process and its children.
+func setContainerMode() {
+	os.Setenv(envContainer, "1")
+}
+
+// applyContainerDefaults gives cfg's defaults the container layout.
+func applyContainerDefaults(cfg *Config) {
+	cfg.Inputs = nil
+	cfg.InputsOptional = true
+	cfg.Stats.File = containerStatsFile
+}
+
+// containerComposeExample heads the -print-default-config -container output.
+const containerComposeExample = `# StreamerBrainz container config. Mount it as /config/config.yaml, or mount
+# nothing and configure through the environment (STREAMERBRAINZ_WS_URL, ...).
+# Statistics are kept in /data.
+#
+# docker-compose.yml:
+#
+#   services:
+#     streamerbrainz:
+#       image: streamerbrainz              # any image with the streamerbrainz binary on PATH
+#       command: ["streamerbrainz"]
+#       environment:
+#         STREAMERBRAINZ_CONTAINER: "1"
+#         STREAMERBRAINZ_WS_URL: ws://camilladsp:1234
+#       volumes:
+#         - ./streamerbrainz:/config:ro
+#         - streamerbrainz-data:/data
+#       ports:
+#         - "3001:3001"                    # webhooks, API and WebSocket
+#       healthcheck:
+#         test: ["CMD", "streamerbrainz", "ctl", "ready"]
+#         interval: 30s
+#       stop_grace_period: 10s
+#       # For IR remotes or encoders, pass the device and list it under inputs:
+#       # devices:
+#       #   - /dev/input/event6
+#
+#   volumes:
+#     streamerbrainz-data:
+
+`
+
+// containerConfigTemplate is the -print-default-config -container output.
+func containerConfigTemplate() ([]byte, error) {
+	cfg := DefaultConfig()
+	applyContainerDefaults(&cfg)
+	b, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(containerComposeExample), b...), nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestContainerMode_ConfigFromEnvironment(t *testing.T) {
+	t.Setenv(envConfigPath, "")
+	t.Setenv(envInstance, "")
+	t.Setenv(envContainer, "1")
+	t.Setenv(envWsURL, "ws://camilladsp:1234")
+
+	if got := ResolveConfigPath(""); got != containerConfigPath {
+		t.Fatalf("config path = %q", got)
+	}
+
+	// No mounted config: defaults plus the environment.
+	cfg, err := LoadConfigFile(filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CamillaDSP.WsURL != "ws://camilladsp:1234" || len(cfg.Inputs) != 0 || !cfg.InputsOptional || cfg.Stats.File != containerStatsFile {
+		t.Fatalf("unexpected config: ws %q inputs %v stats %q", cfg.CamillaDSP.WsURL, cfg.Inputs, cfg.Stats.File)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Outside container mode the file is still required.
+	t.Setenv(envContainer, "")
+	if _, err := LoadConfigFile(filepath.Join(t.TempDir(), "config.yaml")); err == nil {
+		t.Fatal("missing config accepted outside container mode")
+	}
+}
+
+func TestContainerConfigTemplate(t *testing.T) {
+	b, err := containerConfigTemplate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(envContainer, "")
+	cfg, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The compose example in the header is valid YAML once uncommented.
+	var compose map[string]any
+	var lines []byte
+	for _, l := range strings.Split(containerComposeExample, "\n") {
+		if len(l) > 4 && l[:4] == "#   " {
+			lines = append(lines, l[4:]...)
+			lines = append(lines, '\n')
+		}
+	}
+	if err := yaml.Unmarshal(lines, &compose); err != nil || compose["services"] == nil {
+		t.Fatalf("compose example: %v", err)
+	}
+}
+
+func TestExpandPath_WithoutHome(t *testing.T) {
+	t.Setenv("HOME", "")
+	t.Setenv("USERPROFILE", "")
+	t.Setenv("home", "")
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ExpandPath("~/state/stats.json"); got != filepath.Join(wd, "state/stats.json") {
+		t.Fatalf("ExpandPath = %q", got)
+	}
+}
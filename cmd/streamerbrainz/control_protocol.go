@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ============================================================================
+// Control protocol plugins
+// ============================================================================
+// A ControlProtocol bridges an external control protocol (home automation buses,
+// lighting consoles, simple UDP/TCP text protocols, ...) to the event bus without
+// touching core code:
+//
+//   - Implementations register a factory by type name from an init() function.
+//   - Instances are configured under `control_protocols` with a `type` and
+//     free-form `options` decoded by the factory.
+//   - Start runs the protocol, injecting events; Notify delivers state broadcasts
+//     for feedback.
+//
+// See control_udp.go for the reference implementation ("udp_text").
+// ============================================================================
+
+// ControlProtocol is an external control surface plugin.
+type ControlProtocol interface {
+	// Start runs the protocol until ctx is canceled, sending decoded requests to events.
+	// A returned error (other than on cancellation) is logged; it does not stop the daemon.
+	Start(ctx context.Context, events chan<- Event) error
+
+	// Notify delivers a state broadcast for feedback. It must not block; implementations
+	// queue or drop as appropriate.
+	Notify(b StateBroadcast)
+}
+
+// ControlProtocolFactory builds a protocol instance from its `options` block.
+// It must validate options without acquiring resources (sockets are opened in Start).
+type ControlProtocolFactory func(options *yaml.Node, logger *slog.Logger) (ControlProtocol, error)
+
+var (
+	controlProtocolsMu sync.Mutex
+	controlProtocols   = map[string]ControlProtocolFactory{}
+)
+
+// registerControlProtocol makes a protocol type available to config. It panics on
+// duplicate registration (a programming error).
+func registerControlProtocol(typ string, factory ControlProtocolFactory) {
+	controlProtocolsMu.Lock()
+	defer controlProtocolsMu.Unlock()
+	if _, dup := controlProtocols[typ]; dup {
+		panic("control protocol registered twice: " + typ)
+	}
+	controlProtocols[typ] = factory
+}
+
+// controlProtocolTypes returns the registered type names, sorted.
+func controlProtocolTypes() []string {
+	controlProtocolsMu.Lock()
+	defer controlProtocolsMu.Unlock()
+	types := make([]string, 0, len(controlProtocols))
+	for t := range controlProtocols {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// newControlProtocol builds a configured protocol instance.
+func newControlProtocol(cfg ControlProtocolConfig, logger *slog.Logger) (ControlProtocol, error) {
+	controlProtocolsMu.Lock()
+	factory, ok := controlProtocols[cfg.Type]
+	controlProtocolsMu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown control protocol type %q (available: %v)", cfg.Type, controlProtocolTypes())
+	}
+	return factory(&cfg.Options, logger)
+}
+
+// decodeControlProtocolOptions decodes an options block into out, rejecting unknown
+// fields like the rest of the config. An absent block leaves out unchanged (defaults).
+func decodeControlProtocolOptions(options *yaml.Node, out any) error {
+	if options == nil || options.Kind == 0 {
+		return nil
+	}
+	b, err := yaml.Marshal(options)
+	if err != nil {
+		return err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	return dec.Decode(out)
+}
+
+// runControlProtocols starts every protocol and fans broadcasts out to them until
+// ctx is canceled or broadcasts is closed. It returns once all protocols have stopped.
+func runControlProtocols(ctx context.Context, protocols []namedControlProtocol, events chan<- Event, broadcasts <-chan StateBroadcast, logger *slog.Logger) {
+	var wg sync.WaitGroup
+	for _, p := range protocols {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := p.Start(ctx, events); err != nil && ctx.Err() == nil {
+				logger.Error("control protocol stopped", "protocol", p.Name, "error", err)
+			}
+		}()
+	}
+	defer wg.Wait()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case b, ok := <-broadcasts:
+			if !ok {
+				return
+			}
+			for _, p := range protocols {
+				p.Notify(b)
+			}
+		}
+	}
+}
+
+// namedControlProtocol pairs an instance with a name for logging.
+type namedControlProtocol struct {
+	Name string
+	ControlProtocol
+}
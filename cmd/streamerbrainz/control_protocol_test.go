@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func decodeControlProtocolConfig(t *testing.T, src string) ControlProtocolConfig {
+	t.Helper()
+	var cfg ControlProtocolConfig
+	dec := yaml.NewDecoder(bytes.NewReader([]byte(src)))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return cfg
+}
+
+func TestNewControlProtocol_ValidatesOptions(t *testing.T) {
+	good := decodeControlProtocolConfig(t, "type: udp_text\noptions:\n  listen: 127.0.0.1:7000\n  feedback_targets: [127.0.0.1:7001]\n")
+	if _, err := newControlProtocol(good, slog.Default()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, src := range []string{
+		"type: nope\n",
+		"type: udp_text\noptions:\n  listen: 7000\n",
+		"type: udp_text\noptions:\n  listen: 127.0.0.1:7000\n  typo: 1\n",
+	} {
+		if _, err := newControlProtocol(decodeControlProtocolConfig(t, src), slog.Default()); err == nil {
+			t.Errorf("expected error for %q", src)
+		}
+	}
+}
+
+func TestParseUDPTextCommand(t *testing.T) {
+	tests := []struct {
+		line string
+		want Event
+	}{
+		{"volume -30.5", SetVolumeAbsolute{Db: -30.5, Origin: "udp_text"}},
+		{"UP", VolumeStep{Steps: 1}},
+		{"down", VolumeStep{Steps: -1}},
+		{"mute", ToggleMute{}},
+		{"zone phones", SelectZone{Zone: "phones"}},
+		{"@phones volume -20", ZonedEvent{Zone: "phones", Event: SetVolumeAbsolute{Db: -20, Origin: "udp_text"}}},
+	}
+	for _, tt := range tests {
+		got, err := parseUDPTextCommand(tt.line)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %#v err=%v, want %#v", tt.line, got, err, tt.want)
+		}
+	}
+	for _, line := range []string{"volume", "volume loud", "@phones", "@phones zone x", "reboot"} {
+		if _, err := parseUDPTextCommand(line); err == nil {
+			t.Errorf("%q: expected error", line)
+		}
+	}
+}
+
+func TestUDPTextFeedback(t *testing.T) {
+	line, ok := udpTextFeedback(ZoneBroadcast{Zone: "main", Broadcast: BroadcastVolumeChanged{VolumeDB: -12.5}})
+	if !ok || line != "@main volume -12.5" {
+		t.Fatalf("unexpected feedback %q", line)
+	}
+	if _, ok := udpTextFeedback(BroadcastEncoderChanged{}); ok {
+		t.Fatalf("expected no feedback for encoder changes")
+	}
+}
+
+// fakeControlProtocol records notifications and injects one event on start.
+type fakeControlProtocol struct {
+	notified chan StateBroadcast
+}
+
+func (f *fakeControlProtocol) Start(ctx context.Context, events chan<- Event) error {
+	events <- ToggleMute{}
+	<-ctx.Done()
+	return nil
+}
+
+func (f *fakeControlProtocol) Notify(b StateBroadcast) { f.notified <- b }
+
+func TestRunControlProtocols_StartsAndNotifies(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan Event, 1)
+	broadcasts := make(chan StateBroadcast, 1)
+	fake := &fakeControlProtocol{notified: make(chan StateBroadcast, 1)}
+
+	done := make(chan struct{})
+	go func() {
+		runControlProtocols(ctx, []namedControlProtocol{{Name: "fake", ControlProtocol: fake}}, events, broadcasts, slog.Default())
+		close(done)
+	}()
+
+	if ev := <-events; ev != (ToggleMute{}) {
+		t.Fatalf("unexpected event %#v", ev)
+	}
+	broadcasts <- BroadcastMuteChanged{Muted: true}
+	select {
+	case b := <-fake.notified:
+		if mc, ok := b.(BroadcastMuteChanged); !ok || !mc.Muted {
+			t.Fatalf("unexpected broadcast %#v", b)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timeout waiting for notify")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("runControlProtocols did not stop")
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ============================================================================
+// udp_text control protocol
+// ============================================================================
+// Reference ControlProtocol: one command per UDP datagram (or per line within a
+// datagram), as plain text — easy to send from home-automation controllers that
+// can only emit fixed UDP strings.
+//
+//   volume <dB>     set volume
+//   up | down       one volume step
+//   mute            toggle mute
+//   zone [<id>]     select a zone (no id cycles)
+//   @<zone> <cmd>   run cmd against a specific zone, e.g. "@phones volume -30"
+//
+// Feedback lines ("volume -30.0", "mute 1", "zone phones"; zone-scoped ones are
+// prefixed "@<zone> ") are sent to the configured feedback targets.
+// ============================================================================
+
+func init() {
+	registerControlProtocol("udp_text", newUDPTextProtocol)
+}
+
+// udpTextOptions are the `options` of a udp_text control protocol.
+type udpTextOptions struct {
+	Listen          string   `yaml:"listen"`           // host:port to receive commands on
+	FeedbackTargets []string `yaml:"feedback_targets"` // host:port destinations for feedback
+}
+
+const udpTextFeedbackQueue = 32
+
+type udpTextProtocol struct {
+	opts     udpTextOptions
+	logger   *slog.Logger
+	feedback chan string
+}
+
+func newUDPTextProtocol(options *yaml.Node, logger *slog.Logger) (ControlProtocol, error) {
+	var opts udpTextOptions
+	if err := decodeControlProtocolOptions(options, &opts); err != nil {
+		return nil, fmt.Errorf("udp_text options: %w", err)
+	}
+	if _, _, err := net.SplitHostPort(opts.Listen); err != nil {
+		return nil, errors.New("udp_text options.listen must be host:port")
+	}
+	for i, t := range opts.FeedbackTargets {
+		if _, _, err := net.SplitHostPort(t); err != nil {
+			return nil, fmt.Errorf("udp_text options.feedback_targets[%d] must be host:port", i)
+		}
+	}
+	return &udpTextProtocol{
+		opts:     opts,
+		logger:   logger,
+		feedback: make(chan string, udpTextFeedbackQueue),
+	}, nil
+}
+
+func (p *udpTextProtocol) Start(ctx context.Context, events chan<- Event) error {
+	conn, err := net.ListenPacket("udp", p.opts.Listen)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", p.opts.Listen, err)
+	}
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	p.logger.Info("udp_text control listening", "addr", conn.LocalAddr().String())
+
+	var targets []net.Addr
+	for _, t := range p.opts.FeedbackTargets {
+		addr, err := net.ResolveUDPAddr("udp", t)
+		if err != nil {
+			p.logger.Warn("udp_text feedback target unresolvable", "target", t, "error", err)
+			continue
+		}
+		targets = append(targets, addr)
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case line := <-p.feedback:
+				for _, t := range targets {
+					_, _ = conn.WriteTo([]byte(line+"\n"), t)
+				}
+			}
+		}
+	}()
+
+	buf := make([]byte, 2048)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		for _, line := range strings.Split(string(buf[:n]), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			ev, err := parseUDPTextCommand(line)
+			if err != nil {
+				p.logger.Debug("udp_text: command ignored", "from", from, "line", line, "error", err)
+				continue
+			}
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+func (p *udpTextProtocol) Notify(b StateBroadcast) {
+	line, ok := udpTextFeedback(b)
+	if !ok {
+		return
+	}
+	select {
+	case p.feedback <- line:
+	default:
+		p.logger.Debug("udp_text feedback queue full, dropping", "line", line)
+	}
+}
+
+// parseUDPTextCommand parses one command line.
+func parseUDPTextCommand(line string) (Event, error) {
+	fields := strings.Fields(line)
+	if zone, ok := strings.CutPrefix(fields[0], "@"); ok {
+		if zone == "" || len(fields) < 2 {
+			return nil, errors.New("expected @<zone> <command>")
+		}
+		ev, err := parseUDPTextCommand(strings.Join(fields[1:], " "))
+		if err != nil {
+			return nil, err
+		}
+		if _, isZone := ev.(SelectZone); isZone {
+			return nil, errors.New("zone cannot be zone-scoped")
+		}
+		return ZonedEvent{Zone: zone, Event: ev}, nil
+	}
+
+	switch strings.ToLower(fields[0]) {
+	case "volume":
+		if len(fields) != 2 {
+			return nil, errors.New("usage: volume <dB>")
+		}
+		db, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid volume %q", fields[1])
+		}
+		return SetVolumeAbsolute{Db: db, Origin: "udp_text"}, nil
+	case "up":
+		return VolumeStep{Steps: 1}, nil
+	case "down":
+		return VolumeStep{Steps: -1}, nil
+	case "mute":
+		return ToggleMute{}, nil
+	case "zone":
+		if len(fields) > 1 {
+			return SelectZone{Zone: fields[1]}, nil
+		}
+		return SelectZone{}, nil
+	}
+	return nil, fmt.Errorf("unknown command %q", fields[0])
+}
+
+// udpTextFeedback renders a broadcast as a feedback line.
+func udpTextFeedback(b StateBroadcast) (string, bool) {
+	switch ev := b.(type) {
+	case ZoneBroadcast:
+		line, ok := udpTextFeedback(ev.Broadcast)
+		return "@" + ev.Zone + " " + line, ok
+	case BroadcastVolumeChanged:
+		return "volume " + strconv.FormatFloat(ev.VolumeDB, 'f', 1, 64), true
+	case BroadcastMuteChanged:
+		if ev.Muted {
+			return "mute 1", true
+		}
+		return "mute 0", true
+	case BroadcastZoneSelected:
+		return "zone " + ev.Zone, true
+	}
+	return "", false
+}
@@ -0,0 +1,82 @@
+package main
+
+import "time"
+
+// ============================================================================
+// Controller feedback for UIs
+// ============================================================================
+// The observed volume only moves when CamillaDSP confirms a step, so a UI that
+// follows volume_changed alone jumps during holds and ramps. Snapshots and
+// controller_changed frames also expose where the hold/velocity controller is
+// headed: held_direction, ramping, velocity_db_per_s and target_db (the ramp's
+// destination, or the hold's current position), all omitted while idle.
+//
+// controller_changed is sent when a hold or ramp starts or ends (direction
+// changes included) and every controllerFeedbackInterval while one is in
+// progress, so a knob can be animated between observations.
+// ============================================================================
+
+// controllerFeedbackInterval throttles progress broadcasts during a hold or ramp.
+const controllerFeedbackInterval = 100 * time.Millisecond
+
+// BroadcastControllerChanged reports the hold/velocity controller's motion.
+type BroadcastControllerChanged struct {
+	HeldDirection  int
+	Ramping        bool
+	VelocityDBPerS float64
+	TargetDB       *float64 // nil while idle
+	At             time.Time
+}
+
+func (BroadcastControllerChanged) stateBroadcastMarker() {}
+
+// moving reports whether a hold or ramp is in progress.
+func (ctrl VolumeControllerState) moving() bool {
+	return ctrl.HeldDirection != 0 || ctrl.Ramping
+}
+
+// feedbackTarget is where the controller is headed (nil while idle).
+func (ctrl VolumeControllerState) feedbackTarget() *float64 {
+	var target float64
+	switch {
+	case ctrl.Ramping:
+		target = ctrl.RampTarget.DB()
+	case ctrl.HeldDirection != 0:
+		target = ctrl.TargetDB
+	default:
+		return nil
+	}
+	return &target
+}
+
+// controllerFeedback returns the controller_changed broadcast owed after an
+// event: on a start, stop or reversal of a hold or ramp, and throttled progress
+// on ticks while moving. prev is the controller before the event.
+func controllerFeedback(s *DaemonState, prev VolumeControllerState, e Event, at time.Time) []StateBroadcast {
+	ctrl := &s.VolumeCtrl
+	changed := prev.HeldDirection != ctrl.HeldDirection || prev.Ramping != ctrl.Ramping
+	if !changed && !ctrl.moving() {
+		return nil
+	}
+	switch t := e.(type) {
+	case Tick:
+		at = t.Now
+	case *Tick:
+		at = t.Now
+	default:
+		if !changed {
+			return nil
+		}
+	}
+	if !changed && !at.IsZero() && at.Sub(ctrl.FeedbackAt) < controllerFeedbackInterval {
+		return nil
+	}
+	ctrl.FeedbackAt = at
+	return []StateBroadcast{BroadcastControllerChanged{
+		HeldDirection:  ctrl.HeldDirection,
+		Ramping:        ctrl.Ramping,
+		VelocityDBPerS: ctrl.VelocityDBPerS,
+		TargetDB:       ctrl.feedbackTarget(),
+		At:             at,
+	}}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// controllerBroadcasts returns the controller_changed broadcasts of a reduce.
+func controllerBroadcasts(rr ReduceResult) []BroadcastControllerChanged {
+	var out []BroadcastControllerChanged
+	for _, b := range rr.Broadcasts {
+		if c, ok := b.(BroadcastControllerChanged); ok {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func TestReduce_ControllerFeedback_Hold(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0, Mode: VelocityModeConstant, VelMaxDBPerS: 10, HoldTimeout: time.Second}
+	rotaryCfg := RotaryConfig{DbPerStep: 1}
+	t0 := time.Unix(1000, 0).UTC()
+
+	s := &DaemonState{}
+	s.SetObservedVolume(-30, t0)
+	s.VolumeCtrl.TargetDB = -30
+
+	rr := Reduce(s, TimedEvent{Event: VolumeHeld{Direction: 1}, At: t0}, cfg, rotaryCfg, ReducerPolicy{})
+	if got := controllerBroadcasts(rr); len(got) != 1 || got[0].HeldDirection != 1 || got[0].TargetDB == nil {
+		t.Fatalf("hold start: got %+v", got)
+	}
+
+	// Progress is throttled to one per controllerFeedbackInterval.
+	rr = Reduce(rr.State, Tick{Now: t0.Add(50 * time.Millisecond), Dt: 0.05}, cfg, rotaryCfg, ReducerPolicy{})
+	if got := controllerBroadcasts(rr); len(got) != 0 {
+		t.Fatalf("tick within interval: got %+v", got)
+	}
+	rr = Reduce(rr.State, Tick{Now: t0.Add(100 * time.Millisecond), Dt: 0.05}, cfg, rotaryCfg, ReducerPolicy{})
+	got := controllerBroadcasts(rr)
+	if len(got) != 1 || got[0].TargetDB == nil || *got[0].TargetDB <= -30 {
+		t.Fatalf("progress: got %+v", got)
+	}
+
+	// Snapshots carry the motion while it lasts.
+	rr = Reduce(rr.State, RequestStateSnapshot{}, cfg, rotaryCfg, ReducerPolicy{})
+	snap := rr.Commands[0].(CmdPublishStateSnapshot).Snapshot
+	if snap.HeldDirection != 1 || snap.TargetDB == nil || *snap.TargetDB != *got[0].TargetDB {
+		t.Fatalf("snapshot while held: %+v", snap)
+	}
+
+	rr = Reduce(rr.State, VolumeRelease{}, cfg, rotaryCfg, ReducerPolicy{})
+	if got := controllerBroadcasts(rr); len(got) != 1 || got[0].HeldDirection != 0 || got[0].TargetDB != nil {
+		t.Fatalf("release: got %+v", got)
+	}
+	rr = Reduce(rr.State, Tick{Now: t0.Add(time.Second), Dt: 0.1}, cfg, rotaryCfg, ReducerPolicy{})
+	if got := controllerBroadcasts(rr); len(got) != 0 {
+		t.Fatalf("idle tick: got %+v", got)
+	}
+	rr = Reduce(rr.State, RequestStateSnapshot{}, cfg, rotaryCfg, ReducerPolicy{})
+	if snap := rr.Commands[0].(CmdPublishStateSnapshot).Snapshot; snap.HeldDirection != 0 || snap.TargetDB != nil {
+		t.Fatalf("snapshot while idle: %+v", snap)
+	}
+}
+
+func TestReduce_ControllerFeedback_Ramp(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0, RampDBPerS: 10}
+	rotaryCfg := RotaryConfig{DbPerStep: 1}
+	t0 := time.Unix(1000, 0).UTC()
+
+	s := &DaemonState{}
+	s.SetObservedVolume(-30, t0)
+
+	rr := Reduce(s, SetVolumeAbsolute{Db: -20}, cfg, rotaryCfg, ReducerPolicy{})
+	got := controllerBroadcasts(rr)
+	if len(got) != 1 || !got[0].Ramping || got[0].TargetDB == nil || *got[0].TargetDB != -20 {
+		t.Fatalf("ramp start: got %+v", got)
+	}
+
+	var ended bool
+	for i := 1; i <= 11 && !ended; i++ {
+		rr = Reduce(rr.State, Tick{Now: t0.Add(time.Duration(i) * 100 * time.Millisecond), Dt: 0.1}, cfg, rotaryCfg, ReducerPolicy{})
+		for _, b := range controllerBroadcasts(rr) {
+			ended = !b.Ramping
+		}
+	}
+	if !ended {
+		t.Fatalf("expected a broadcast when the ramp ends")
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ============================================================================
+// Panic recovery and crash dumps
+// ============================================================================
+// A panic in one goroutine would otherwise either kill the process without a
+// trace of what led to it or, if recovered locally, leave the rest of the daemon
+// running without e.g. its event loop. Long-running goroutines instead defer
+// recoverPanic (or call handlePanic from their own recover), which:
+//
+//   - logs the panic with its stack trace,
+//   - writes a JSON dump to diagnostics.crash_dump_dir: the stack, the last
+//     crashEventRingSize events received by the daemon loops, and a DaemonState
+//     snapshot from every zone loop that still answers (null otherwise),
+//   - cancels the daemon context so everything shuts down cleanly; main then
+//     exits non-zero (systemd's Restart=on-failure brings it back).
+// ============================================================================
+
+const (
+	crashEventRingSize = 64
+	// crashStateTimeout bounds how long a dump waits for zone loops to report state.
+	crashStateTimeout = 500 * time.Millisecond
+)
+
+// crashEvent is one entry of the recent-events ring.
+type crashEvent struct {
+	At    time.Time `json:"at"`
+	Zone  string    `json:"zone"`
+	Type  string    `json:"type"`
+	Event string    `json:"event"`
+}
+
+// crashDump is the file written on a panic.
+type crashDump struct {
+	Time      time.Time                  `json:"time"`
+	Version   string                     `json:"version"`
+	Component string                     `json:"component"`
+	Panic     string                     `json:"panic"`
+	Stack     string                     `json:"stack"`
+	Events    []crashEvent               `json:"events"`
+	States    map[string]json.RawMessage `json:"states"`
+}
+
+// crashReporter is shared by all goroutines of one daemon run.
+type crashReporter struct {
+	dir      string
+	shutdown func()
+	logger   *slog.Logger
+	crashed  atomic.Bool
+
+	mu     sync.Mutex
+	ring   [crashEventRingSize]crashEvent
+	next   int
+	filled bool
+	zones  map[string]chan chan json.RawMessage
+}
+
+// newCrashReporter returns a reporter writing dumps to dir (empty = temp dir) and
+// calling shutdown after a panic.
+func newCrashReporter(dir string, shutdown func(), logger *slog.Logger) *crashReporter {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return &crashReporter{
+		dir:      dir,
+		shutdown: shutdown,
+		logger:   logger,
+		zones:    make(map[string]chan chan json.RawMessage),
+	}
+}
+
+// Crashed reports whether any goroutine panicked.
+func (c *crashReporter) Crashed() bool {
+	return c.crashed.Load()
+}
+
+// Go runs fn in a new goroutine with panic recovery.
+func (c *crashReporter) Go(component string, fn func()) {
+	go func() {
+		defer c.recoverPanic(component)
+		fn()
+	}()
+}
+
+// recoverPanic must be deferred directly; it handles a panic of the calling goroutine.
+func (c *crashReporter) recoverPanic(component string) {
+	if r := recover(); r != nil {
+		c.handlePanic(component, r, debug.Stack(), nil)
+	}
+}
+
+// recordEvent adds an ingress event to the recent-events ring.
+func (c *crashReporter) recordEvent(zone string, ev Event, at time.Time) {
+	c.mu.Lock()
+	c.ring[c.next] = crashEvent{At: at, Zone: zone, Type: fmt.Sprintf("%T", ev), Event: fmt.Sprintf("%+v", ev)}
+	c.next = (c.next + 1) % crashEventRingSize
+	if c.next == 0 {
+		c.filled = true
+	}
+	c.mu.Unlock()
+}
+
+// stateRequests registers a zone loop; it must answer each request with
+// crashStateJSON of its current state.
+func (c *crashReporter) stateRequests(zone string) <-chan chan json.RawMessage {
+	req := make(chan chan json.RawMessage)
+	c.mu.Lock()
+	c.zones[zone] = req
+	c.mu.Unlock()
+	return req
+}
+
+// handlePanic logs, dumps and shuts down. states holds snapshots the caller
+// already has (a panicking zone loop cannot answer its own request).
+func (c *crashReporter) handlePanic(component string, value any, stack []byte, states map[string]json.RawMessage) {
+	c.crashed.Store(true)
+	c.logger.Error("panic recovered, shutting down", "component", component, "panic", fmt.Sprint(value), "stack", string(stack))
+
+	dump := crashDump{
+		Time:      time.Now().UTC(),
+		Version:   version,
+		Component: component,
+		Panic:     fmt.Sprint(value),
+		Stack:     string(stack),
+		Events:    c.recentEvents(),
+		States:    c.zoneStates(states, crashStateTimeout),
+	}
+
+	if path, err := c.writeDump(dump); err != nil {
+		c.logger.Error("failed to write crash dump", "error", err)
+	} else {
+		c.logger.Error("crash dump written", "path", path)
+	}
+	c.shutdown()
+}
+
+// recentEvents returns the recent-events ring, oldest first.
+func (c *crashReporter) recentEvents() []crashEvent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var events []crashEvent
+	if c.filled {
+		events = append(events, c.ring[c.next:]...)
+	}
+	return append(events, c.ring[:c.next]...)
+}
+
+// zoneStates asks every registered zone loop for its state, waiting at most
+// timeout; known holds states the caller already has. Zones that don't answer
+// in time are null.
+func (c *crashReporter) zoneStates(known map[string]json.RawMessage, timeout time.Duration) map[string]json.RawMessage {
+	c.mu.Lock()
+	zones := make(map[string]chan chan json.RawMessage, len(c.zones))
+	for zone, req := range c.zones {
+		zones[zone] = req
+	}
+	c.mu.Unlock()
+
+	states := make(map[string]json.RawMessage, len(zones))
+	for zone, s := range known {
+		states[zone] = s
+	}
+	deadline := time.After(timeout)
+	for zone, req := range zones {
+		if _, ok := known[zone]; ok {
+			continue
+		}
+		states[zone] = json.RawMessage("null")
+		reply := make(chan json.RawMessage, 1)
+		select {
+		case req <- reply:
+		case <-deadline:
+			continue
+		}
+		select {
+		case s := <-reply:
+			states[zone] = s
+		case <-deadline:
+		}
+	}
+	return states
+}
+
+func (c *crashReporter) writeDump(dump crashDump) (string, error) {
+	b, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(c.dir, fmt.Sprintf("streamerbrainz-crash-%s.json", dump.Time.Format("20060102T150405.000Z")))
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// crashStateJSON renders a state for a crash dump, falling back to its %+v
+// formatting if it does not marshal.
+func crashStateJSON(s *DaemonState) json.RawMessage {
+	b, err := json.Marshal(s)
+	if err != nil {
+		b, _ = json.Marshal(fmt.Sprintf("%+v", s))
+	}
+	return b
+}
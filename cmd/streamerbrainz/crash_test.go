@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func readCrashDump(t *testing.T, dir string) crashDump {
+	t.Helper()
+	files, _ := filepath.Glob(filepath.Join(dir, "streamerbrainz-crash-*.json"))
+	if len(files) != 1 {
+		t.Fatalf("expected one crash dump, got %v", files)
+	}
+	b, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dump crashDump
+	if err := json.Unmarshal(b, &dump); err != nil {
+		t.Fatalf("decode dump: %v", err)
+	}
+	return dump
+}
+
+func TestCrashReporter_GoRecoversAndDumps(t *testing.T) {
+	dir := t.TempDir()
+	shutdown := make(chan struct{})
+	c := newCrashReporter(dir, func() { close(shutdown) }, slog.New(slog.DiscardHandler))
+
+	// A zone loop that answers state requests.
+	req := c.stateRequests("living")
+	go func() {
+		reply := <-req
+		reply <- crashStateJSON(&DaemonState{Zone: "living"})
+	}()
+
+	for i := range crashEventRingSize + 6 {
+		c.recordEvent("living", SetVolumeAbsolute{Db: float64(-i)}, time.Unix(int64(i), 0))
+	}
+
+	c.Go("test", func() { panic("boom") })
+	select {
+	case <-shutdown:
+	case <-time.After(2 * time.Second):
+		t.Fatal("shutdown was not triggered")
+	}
+	if !c.Crashed() {
+		t.Fatal("expected Crashed() after a panic")
+	}
+
+	dump := readCrashDump(t, dir)
+	if dump.Component != "test" || dump.Panic != "boom" || dump.Stack == "" {
+		t.Fatalf("unexpected dump header: %+v", dump)
+	}
+	if len(dump.Events) != crashEventRingSize {
+		t.Fatalf("expected %d events, got %d", crashEventRingSize, len(dump.Events))
+	}
+	if first, last := dump.Events[0], dump.Events[len(dump.Events)-1]; first.At.Unix() != 6 || last.At.Unix() != crashEventRingSize+5 {
+		t.Fatalf("events out of order: first %v, last %v", first.At, last.At)
+	}
+	if want := fmt.Sprintf("%T", SetVolumeAbsolute{}); dump.Events[0].Type != want {
+		t.Fatalf("expected event type %q, got %q", want, dump.Events[0].Type)
+	}
+	var st DaemonState
+	if err := json.Unmarshal(dump.States["living"], &st); err != nil || st.Zone != "living" {
+		t.Fatalf("unexpected zone state %s (%v)", dump.States["living"], err)
+	}
+}
+
+func TestCrashReporter_UnresponsiveZoneIsNull(t *testing.T) {
+	dir := t.TempDir()
+	c := newCrashReporter(dir, func() {}, slog.New(slog.DiscardHandler))
+	c.stateRequests("stuck")
+
+	c.handlePanic("daemon own", "boom", []byte("stack"), map[string]json.RawMessage{"own": crashStateJSON(&DaemonState{Zone: "own"})})
+
+	dump := readCrashDump(t, dir)
+	if string(dump.States["stuck"]) != "null" {
+		t.Fatalf("expected null state for an unresponsive zone, got %s", dump.States["stuck"])
+	}
+	if len(dump.States["own"]) == 0 {
+		t.Fatalf("expected the caller's own state in the dump")
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ============================================================================
+// ctl subcommand
+// ============================================================================
+// `streamerbrainz ctl <command>` sends one-off commands to the running daemon
+// over the IPC socket (ipc.socket_path from the config). dump-state, tap and
+// ready instead use the control API (see debug_state.go, camilladsp_tap.go and
+// health.go).
+// ============================================================================
+
+// ctlCommands maps ctl command names to the event they send.
+var ctlCommands = map[string]Event{
+	"resync": ResyncState{},
+}
+
+func printCtlUsage() {
+	fmt.Println("USAGE:")
+	fmt.Println("  streamerbrainz ctl [-config path] [-zone id] <command>")
+	fmt.Println()
+	fmt.Println("COMMANDS:")
+	fmt.Println("  resync   Re-read volume, mute and config state from CamillaDSP and")
+	fmt.Println("           re-broadcast it (after another tool changed the DSP)")
+	fmt.Println("  ready    Exit non-zero unless every zone's CamillaDSP answers (GET /readyz),")
+	fmt.Println("           e.g. as a container healthcheck")
+	fmt.Println("  dump-state")
+	fmt.Println("           Print the daemon's internal state, recent events and errors")
+	fmt.Println("           as JSON (secrets redacted), to attach to a bug report")
+	fmt.Println("  tap on [duration] | tap off | tap status")
+	fmt.Println("           Log every CamillaDSP protocol frame to diagnostics.tap_file and")
+	fmt.Println("           the state stream, for duration (default 10m)")
+	fmt.Println()
+	fmt.Println("  -zone limits the command to one zone (default: every zone).")
+	fmt.Println("  -url is the daemon API base URL for dump-state, tap and ready (default: from the config).")
+	fmt.Println()
+}
+
+// ctlEvent returns the event for a ctl command, targeted at zone if set.
+func ctlEvent(command, zone string) (Event, error) {
+	ev, ok := ctlCommands[command]
+	if !ok {
+		return nil, fmt.Errorf("unknown command %q", command)
+	}
+	if zone != "" {
+		ev = ZonedEvent{Zone: zone, Event: ev}
+	}
+	return ev, nil
+}
+
+// runCtlSubcommand handles `streamerbrainz ctl`.
+func runCtlSubcommand(args []string) {
+	fs := flag.NewFlagSet("ctl", flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to YAML config file")
+	zone := fs.String("zone", "", "Zone to target (default: every zone)")
+	baseURL := fs.String("url", "", "Daemon API base URL for dump-state, tap and ready (default: from the config)")
+	fs.Usage = printCtlUsage
+	fs.Parse(args)
+
+	// dump-state and tap talk to the control API rather than the IPC socket.
+	apiURL := func() string {
+		if *baseURL == "" {
+			cfg, err := LoadConfigFile(ResolveConfigPath(*configPath))
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "error:", err)
+				os.Exit(1)
+			}
+			*baseURL = tuneBaseURL(cfg)
+		}
+		return strings.TrimSuffix(*baseURL, "/")
+	}
+	// tap needs the control API token (see api_auth.go).
+	apiToken := func() string {
+		cfg, err := LoadConfigFile(ResolveConfigPath(*configPath))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		token, err := apiClientToken(cfg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		return token
+	}
+	if fs.Arg(0) == "tap" && fs.NArg() >= 2 && fs.NArg() <= 3 {
+		if err := ctlTap(apiURL(), apiToken(), fs.Args()[1:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if fs.NArg() != 1 {
+		printCtlUsage()
+		os.Exit(2)
+	}
+	if fs.Arg(0) == "ready" {
+		if err := ctlReady(apiURL(), os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if fs.Arg(0) == "dump-state" {
+		if err := ctlDumpState(apiURL(), apiToken(), os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	ev, err := ctlEvent(fs.Arg(0), *zone)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(2)
+	}
+
+	cfg, err := LoadConfigFile(ResolveConfigPath(*configPath))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	msg, err := SendIPCEvent(cfg.IPC.SocketPath, ev)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	if msg != "" {
+		fmt.Println(msg)
+	}
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"log/slog"
+	"runtime/debug"
 	"time"
 )
 
@@ -13,7 +15,8 @@ import (
 // Responsibilities:
 // - Receive Events from multiple producers (input, IPC, integrations)
 // - Assign timestamps to ingress events via TimedEvent (reducer stays deterministic)
-// - Emit Tick at a fixed cadence
+// - Emit Tick at a fixed cadence while anything is moving, and at a low housekeeping
+//   rate (idle_hz) otherwise; the next event that starts movement wakes the loop instantly
 // - Reduce events into (next state, Commands)
 // - Execute Commands via the effects layer (runEffect) WITHOUT blocking the event loop,
 //   and feed observations back as Events
@@ -27,36 +30,61 @@ import (
 // - Uses explicit event/command queues to avoid re-entrant execution
 // - Commands are executed by a dedicated worker goroutine so CamillaDSP calls never block
 //   the event loop
+// - CmdPublishStateSnapshot skips the worker and is answered in the loop, so snapshot
+//   latency doesn't depend on CamillaDSP health
 //
 // ============================================================================
 
 // runDaemon is the orchestrator:
 // - Ingress: receives external Events and wraps them in TimedEvent{At: time.Now()}
-// - Scheduling: produces Tick events at a fixed cadence
+// - Scheduling: produces Tick events at updateHz, or idleHz while the state is idle (0 = never idle)
 // - Reduction: calls Reduce() to compute next state + Commands
 // - Effects: executes Commands via a worker and feeds resulting observation Events back into Reduce()
 //
 // Shutdown:
 // - Returns when ctx is canceled
 // - Returns cleanly when the events channel is closed
+// - A panic in the loop or its effects worker is recovered and reported via crash
 func runDaemon(
 	ctx context.Context,
+	zone string,
 	events <-chan Event,
 	stateBroadcasts chan<- StateBroadcast,
-	client *CamillaDSPClient,
+	client CamillaDSPClientInterface,
 	cfg VelocityConfig,
 	rotaryCfg RotaryConfig,
+	policy ReducerPolicy,
+	outputs []OutputConfig,
 	updateHz int,
+	idleHz int,
+	diag *loopDiagnostics,
+	crash *crashReporter,
 	logger *slog.Logger,
 ) {
-	state := &DaemonState{}
+	state := &DaemonState{Zone: zone}
+	state.Output.Outputs = outputs
 	state.VolumeCtrl.TargetDB = safeDefaultDB
 	state.VolumeCtrl.LastHeldAt = time.Now()
 
-	// Configure tick cadence.
+	// Crash dumps ask every zone loop for its state; this loop answers between events,
+	// or includes its own state if it is the one panicking.
+	stateReq := crash.stateRequests(zone)
+	defer func() {
+		if r := recover(); r != nil {
+			crash.handlePanic("daemon "+zone, r, debug.Stack(), map[string]json.RawMessage{zone: crashStateJSON(state)})
+		}
+	}()
+
+	// Configure tick cadence. tickInterval is the ticker's current period: updateInterval
+	// while moving, idleInterval while idle.
 	updateInterval := time.Second / time.Duration(updateHz)
+	var idleInterval time.Duration
+	if idleHz > 0 {
+		idleInterval = time.Second / time.Duration(idleHz)
+	}
 	ticker := time.NewTicker(updateInterval)
 	defer ticker.Stop()
+	tickInterval := updateInterval
 
 	// Keep dt clamping consistent with the old velocity engine behavior.
 	// Allow up to ~2 ticks worth of time to be integrated in one step.
@@ -69,8 +97,26 @@ func runDaemon(
 	// Explicit queues:
 	// - eventQueue holds events awaiting reduction
 	// - cmdQueue holds commands awaiting execution (staged for dispatch to worker)
-	var eventQueue []Event
-	var cmdQueue []Command
+	// Both keep their backing arrays across flushes so the steady state doesn't allocate.
+	eventQueue := make([]Event, 0, 16)
+	cmdQueue := make([]Command, 0, 16)
+
+	// Hot-path wrappers are reused instead of boxing a new value per event/tick:
+	// - timedFree is a free list of *TimedEvent (returned after reduction)
+	// - tickEv is the single Tick passed by pointer (the queue is flushed within each tick)
+	var timedFree []*TimedEvent
+	tickEv := &Tick{}
+	newTimedEvent := func(ev Event, at time.Time) *TimedEvent {
+		var te *TimedEvent
+		if n := len(timedFree); n > 0 {
+			te = timedFree[n-1]
+			timedFree = timedFree[:n-1]
+		} else {
+			te = &TimedEvent{}
+		}
+		te.Event, te.At = ev, at
+		return te
+	}
 
 	// Worker channels:
 	// - cmdCh: daemon -> worker
@@ -81,53 +127,84 @@ func runDaemon(
 	enqueueEvent := func(ev Event) {
 		eventQueue = append(eventQueue, ev)
 	}
+	// Snapshot replies and event acknowledgements need no I/O, so they are delivered
+	// right here instead of queuing behind CamillaDSP commands: UI connects stay fast
+	// while the DSP is slow or unreachable.
 	enqueueCommands := func(cmds []Command) {
-		if len(cmds) == 0 {
-			return
+		for _, cmd := range cmds {
+			switch c := cmd.(type) {
+			case CmdPublishStateSnapshot:
+				publishStateSnapshot(c, logger)
+				continue
+			case CmdAck:
+				deliverAck(c.Reply, c.Ack, logger)
+				continue
+			case CmdWakeDSP:
+				// One datagram; must not wait behind commands failing on the sleeping host.
+				if err := sendWakeOnLAN(c.MAC, c.Address); err != nil {
+					logger.Warn("wake-on-lan failed", "mac", c.MAC, "address", c.Address, "error", err)
+				} else {
+					logger.Info("sent wake-on-lan packet to the CamillaDSP host", "mac", c.MAC, "address", c.Address)
+				}
+				continue
+			}
+			cmdQueue = append(cmdQueue, cmd)
 		}
-		cmdQueue = append(cmdQueue, cmds...)
 	}
 
 	// Reduce all queued events, enqueuing any resulting commands and publishing any broadcasts.
 	flushEvents := func() {
-		for len(eventQueue) > 0 {
-			ev := eventQueue[0]
-			eventQueue = eventQueue[1:]
+		for i, ev := range eventQueue {
+			eventQueue[i] = nil
 
-			rr := Reduce(state, ev, cfg, rotaryCfg)
+			rr := Reduce(state, ev, cfg, rotaryCfg, policy)
 			if rr.State != nil {
 				state = rr.State
 			}
 			enqueueCommands(rr.Commands)
+			if te, ok := ev.(*TimedEvent); ok {
+				te.Event = nil
+				timedFree = append(timedFree, te)
+			}
 
 			// Publish reducer-emitted broadcasts to external consumers (e.g., WebSocket hub).
 			// Never block the daemon loop; drop on backpressure, similar to obsCh behavior.
-			if stateBroadcasts != nil && len(rr.Broadcasts) > 0 {
-				for _, b := range rr.Broadcasts {
-					select {
-					case stateBroadcasts <- b:
-					default:
-						logger.Warn("state broadcast queue full, dropping broadcast")
-					}
+			// Broadcasts are tagged with this loop's zone so consumers can tell zones apart.
+			for _, b := range rr.Broadcasts {
+				if v, ok := b.(BroadcastVolumeChanged); ok && v.Origin != "" {
+					// Volume audit trail: who/what changed the volume.
+					logger.Info("volume changed", "zone", zone, "volume_db", v.VolumeDB, "origin", v.Origin)
+				}
+				if stateBroadcasts == nil {
+					continue
+				}
+				select {
+				case stateBroadcasts <- ZoneBroadcast{Zone: zone, Broadcast: b}:
+				default:
+					logger.Warn("state broadcast queue full, dropping broadcast")
 				}
 			}
 		}
+		eventQueue = eventQueue[:0]
 	}
 
 	// Dispatch all queued commands to the worker (non-blocking).
 	// If the worker queue is full, keep remaining commands queued for the next flush.
 	flushCommands := func() {
-		for len(cmdQueue) > 0 {
-			cmd := cmdQueue[0]
-
+		sent := 0
+	dispatch:
+		for _, cmd := range cmdQueue {
 			select {
 			case cmdCh <- cmd:
-				cmdQueue = cmdQueue[1:]
+				sent++
 			default:
 				// Worker is backed up; try again later.
-				return
+				break dispatch
 			}
 		}
+		rest := copy(cmdQueue, cmdQueue[sent:])
+		clear(cmdQueue[rest:])
+		cmdQueue = cmdQueue[:rest]
 	}
 
 	// Drain any available observation events from the worker without blocking.
@@ -145,13 +222,25 @@ func runDaemon(
 
 	// Start effects worker.
 	// All CamillaDSP I/O happens here; the daemon event loop remains responsive.
-	go func() {
+	crash.Go("effects "+zone, func() {
+		batch := make([]Command, 0, maxEffectsBatch)
 		for {
 			select {
 			case <-ctx.Done():
 				return
 			case cmd := <-cmdCh:
-				runEffect(client, cmd, logger, func(obs Event) {
+				// Take whatever else is already queued so the client can pipeline it.
+				batch = append(batch[:0], cmd)
+			drain:
+				for len(batch) < maxEffectsBatch {
+					select {
+					case next := <-cmdCh:
+						batch = append(batch, next)
+					default:
+						break drain
+					}
+				}
+				runEffects(ctx, client, batch, logger, func(obs Event) {
 					// Avoid blocking the worker indefinitely; if obsCh is full, drop and rely on future
 					// polling/commands to converge. This prevents deadlock.
 					select {
@@ -160,12 +249,42 @@ func runDaemon(
 						logger.Warn("effects observation queue full, dropping event")
 					}
 				})
+				clear(batch)
 			}
 		}
-	}()
+	})
+
+	// tick runs one housekeeping pass:
+	// - integrate hold/velocity controller (Tick)
+	// - drain any pending observations
+	// - dispatch any queued commands
+	tick := func(now time.Time, dt float64) {
+		lastTick = now
+		*tickEv = Tick{Now: now, Dt: dt}
+		enqueueEvent(tickEv)
+		drainObservations()
+		flushEvents()
+		flushCommands()
+		diag.dispatched(time.Now(), len(cmdQueue) == 0 && !state.HasPendingIntent())
+	}
+
+	// adaptRate slows the ticker once nothing is moving and wakes it (with an
+	// immediate tick, so pending intents flush without waiting) when something starts.
+	adaptRate := func() {
+		idle := state.Idle() && len(cmdQueue) == 0
+		switch {
+		case idleInterval > 0 && idle && tickInterval != idleInterval:
+			tickInterval = idleInterval
+			ticker.Reset(tickInterval)
+		case !idle && tickInterval != updateInterval:
+			tickInterval = updateInterval
+			ticker.Reset(tickInterval)
+			tick(time.Now(), updateInterval.Seconds())
+		}
+	}
 
 	// Bootstrap: ask reducer to emit initial CmdGet* commands.
-	enqueueEvent(TimedEvent{Event: DaemonStarted{}, At: time.Now()})
+	enqueueEvent(newTimedEvent(DaemonStarted{}, time.Now()))
 	flushEvents()
 	flushCommands()
 
@@ -181,28 +300,31 @@ func runDaemon(
 			enqueueEvent(obs)
 			flushEvents()
 			flushCommands()
+			adaptRate()
 
 		case ev, ok := <-events:
 			if !ok {
 				logger.Info("daemon stopping (events channel closed)")
 				return
 			}
-			enqueueEvent(TimedEvent{Event: ev, At: time.Now()})
+			at := time.Now()
+			diag.ingress(at)
+			recorded, _ := splitAck(ev)
+			crash.recordEvent(zone, recorded, at)
+			enqueueEvent(newTimedEvent(ev, at))
 			flushEvents()
 			flushCommands()
+			diag.dispatched(time.Now(), len(cmdQueue) == 0 && !state.HasPendingIntent())
+			adaptRate()
+
+		case reply := <-stateReq:
+			reply <- crashStateJSON(state)
 
 		case now := <-ticker.C:
-			// Periodic housekeeping:
-			// - integrate hold/velocity controller (Tick)
-			// - drain any pending observations
-			// - dispatch any queued commands
-			dt := now.Sub(lastTick).Seconds()
-			lastTick = now
-
-			enqueueEvent(Tick{Now: now, Dt: dt})
-			drainObservations()
-			flushEvents()
-			flushCommands()
+			interval := now.Sub(lastTick)
+			diag.tick(now, interval, tickInterval)
+			tick(now, interval.Seconds())
+			adaptRate()
 		}
 	}
 }
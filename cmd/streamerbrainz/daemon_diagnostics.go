@@ -0,0 +1,71 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+)
+
+// loopDiagnostics records daemon loop timing into the process metrics and logs
+// rate-limited warnings when a zone's loop is falling behind (e.g. a loaded Pi).
+// Each daemon loop owns its own instance; it is not safe for concurrent use.
+type loopDiagnostics struct {
+	metrics     *Metrics
+	latencyWarn time.Duration // 0 disables warnings
+	jitterWarn  time.Duration // 0 disables warnings
+	logger      *slog.Logger
+
+	// pendingSince is the ingress time of the oldest event whose commands are not
+	// yet dispatched (zero when idle).
+	pendingSince time.Time
+
+	lastLatencyWarn time.Time
+	lastJitterWarn  time.Time
+}
+
+func newLoopDiagnostics(metrics *Metrics, cfg DiagnosticsConfig, logger *slog.Logger) *loopDiagnostics {
+	return &loopDiagnostics{
+		metrics:     metrics,
+		latencyWarn: time.Duration(cfg.LatencyWarnMS) * time.Millisecond,
+		jitterWarn:  time.Duration(cfg.JitterWarnMS) * time.Millisecond,
+		logger:      logger,
+	}
+}
+
+// ingress notes that an external event entered the loop at t.
+func (d *loopDiagnostics) ingress(t time.Time) {
+	if d == nil || !d.pendingSince.IsZero() {
+		return
+	}
+	d.pendingSince = t
+}
+
+// dispatched notes that the command queue drained at now; drained is false while
+// commands are still waiting for the effects worker.
+func (d *loopDiagnostics) dispatched(now time.Time, drained bool) {
+	if d == nil || d.pendingSince.IsZero() || !drained {
+		return
+	}
+	latency := now.Sub(d.pendingSince)
+	d.pendingSince = time.Time{}
+	d.metrics.ReduceLatency.Observe(latency)
+	if d.latencyWarn > 0 && latency > d.latencyWarn && now.Sub(d.lastLatencyWarn) >= diagnosticsWarnInterval {
+		d.lastLatencyWarn = now
+		d.logger.Warn("slow event processing", "latency", latency, "threshold", d.latencyWarn)
+	}
+}
+
+// tick records the deviation of one tick interval from the expected period.
+func (d *loopDiagnostics) tick(now time.Time, interval, expected time.Duration) {
+	if d == nil {
+		return
+	}
+	jitter := interval - expected
+	if jitter < 0 {
+		jitter = -jitter
+	}
+	d.metrics.TickJitter.Observe(jitter)
+	if d.jitterWarn > 0 && jitter > d.jitterWarn && now.Sub(d.lastJitterWarn) >= diagnosticsWarnInterval {
+		d.lastJitterWarn = now
+		d.logger.Warn("tick jitter", "interval", interval, "expected", expected, "threshold", d.jitterWarn)
+	}
+}
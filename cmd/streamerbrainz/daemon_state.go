@@ -13,6 +13,10 @@ import "time"
 // NOTE: This file only introduces the state model. Wiring it into the daemon loop
 // (initial sync, applying effects, publishing snapshots) is handled elsewhere.
 type DaemonState struct {
+	// Zone is the id of the zone (CamillaDSP instance) this state belongs to.
+	// It is fixed at startup; each zone runs its own daemon loop.
+	Zone string
+
 	// CamillaDSP is the authoritative backend for volume/mute/config.
 	// We cache what we last observed from CamillaDSP so we can expose it to other clients.
 	Camilla CamillaDSPState
@@ -28,6 +32,83 @@ type DaemonState struct {
 	// Intent contains desired changes that should be applied by the daemon's
 	// centralized effects stage (the only place that should talk to CamillaDSP).
 	Intent DaemonIntent
+
+	// VolumeOrigin names the source of the latest volume intent, used to attribute
+	// observed volume changes (see volume_origin.go).
+	VolumeOrigin VolumeOriginState
+
+	// PreMuteVolumeDB is the observed volume when CamillaDSP was last seen becoming muted.
+	// Used to restore the level when a volume-up gesture auto-unmutes. Nil if unknown.
+	PreMuteVolumeDB *float64
+
+	// Output tracks selectable outputs (speakers/headphones) and per-output saved volume.
+	Output OutputState
+
+	// Player is the last reported playback state from player integrations
+	// (librespot hook, Plex webhook). Apart from its loudness data (normalization),
+	// it never drives CamillaDSP.
+	Player PlayerState
+
+	// NormalizationDB is the per-track gain offset applied to the normalization fader
+	// (see normalization.go).
+	NormalizationDB float64
+
+	// FadeInPending starts a fade-in at the next volume observation (see fade_in.go).
+	FadeInPending bool
+
+	// Calibration is the reference level mode that locks out volume changes.
+	Calibration CalibrationState
+
+	// TestSignal tracks a speaker check in progress.
+	TestSignal TestSignalState
+
+	// LimitOverrideUntil is when an active limit override reverts (zero = user limits apply).
+	LimitOverrideUntil time.Time
+
+	// Tuning holds velocity/rotary settings changed at runtime (nil = startup config).
+	Tuning *ConfigUpdated
+
+	// UIHints tracks display hints in progress (see ui_hints.go).
+	UIHints UIHintState
+
+	// Acks are acknowledged events waiting for CamillaDSP (see ack.go).
+	Acks []PendingAck
+}
+
+// OutputState is the reducer-owned output selection state.
+type OutputState struct {
+	// Outputs are the configured outputs (static; seeded at startup).
+	Outputs []OutputConfig
+
+	// Active is the confirmed active output id (empty if none selected yet).
+	Active string
+
+	// Pending is the output currently being switched to (empty if idle).
+	Pending string
+
+	// SavedVolumeDB remembers the last volume used on each output.
+	SavedVolumeDB map[string]float64
+}
+
+// PlayerState is the reducer-owned view of the most recently active player/source.
+type PlayerState struct {
+	// Source identifies the integration that reported the state ("plex", "librespot", "tidal").
+	Source string
+
+	// State is the playback state ("playing", "paused", "stopped", ...).
+	State string
+
+	Title  string
+	Artist string
+	Album  string
+
+	// DurationMs is the track's length (0 if unknown).
+	DurationMs int64
+
+	// Loudness is the track's loudness data (nil if unknown).
+	Loudness *TrackLoudness
+
+	At time.Time
 }
 
 // VolumeControllerState is the reducer-owned state for the velocity/hold volume controller.
@@ -41,7 +122,7 @@ type DaemonState struct {
 type VolumeControllerState struct {
 	// TargetDB is the controller's current desired target position (in dB).
 	// This is a controller state variable used for integration; it is not the authoritative
-	// observed volume (which lives in CamillaDSPState.VolumeDB).
+	// observed volume (which lives in CamillaDSPState.VolumeMB).
 	TargetDB float64
 
 	// VelocityDBPerS is the current velocity in dB/s (signed).
@@ -54,6 +135,32 @@ type VolumeControllerState struct {
 	// Timing for hold gestures and safety timeouts
 	LastHeldAt  time.Time
 	HoldBeganAt time.Time
+	// HoldTimeout is the current hold's own timeout (VolumeHeld.HoldTimeoutMS); the
+	// longer of it and VelocityConfig.HoldTimeout applies (see holdTimeout).
+	HoldTimeout time.Duration
+
+	// SuppressedDirection is a hold direction consumed by the muted-gesture policy
+	// (auto-unmute/ignore) or cancelled by an external change (external_change.go).
+	// Repeats in that direction are dropped until release.
+	SuppressedDirection int
+
+	// Ramping is true while an absolute set is being faded toward RampTarget
+	// (see VelocityConfig.RampDBPerS). Any hold/step gesture cancels the ramp.
+	Ramping    bool
+	RampTarget Millibel
+	// RampRateDBPerS overrides RampDBPerS for the current ramp (0 = use it), e.g.
+	// for a fade-in or a camilladsp.ramp_up_ms/ramp_down_ms fade of fixed duration.
+	RampRateDBPerS float64
+
+	// CheckpointArmed is set when the current upward hold began below CheckpointDB
+	// (VelocityConfig.HoldCheckpointDB): it stops there until released and re-pressed.
+	// CheckpointHit records that it got there (broadcast once per hold).
+	CheckpointArmed bool
+	CheckpointHit   bool
+	CheckpointDB    float64
+
+	// FeedbackAt is when controller_changed was last broadcast (see controller_feedback.go).
+	FeedbackAt time.Time
 }
 
 // RotaryReducerState tracks recent rotary turns for reducer-side velocity detection.
@@ -61,8 +168,30 @@ type VolumeControllerState struct {
 // without depending on any external mutable state.
 type RotaryReducerState struct {
 	RecentSteps []RotaryReducerStep
+
+	// HiResUnits accumulates REL_WHEEL_HI_RES units not yet forming a whole detent.
+	HiResUnits int
+
+	// Mode is the active encoder mode (empty means volume).
+	// ModeAt is the last push/turn in a non-volume mode (drives auto-revert).
+	Mode   EncoderMode
+	ModeAt time.Time
+
+	// BalanceDB (negative = left, positive = right) and SubDB are the levels
+	// adjusted in balance/sub modes.
+	BalanceDB float64
+	SubDB     float64
 }
 
+// EncoderMode selects what rotary turns adjust.
+type EncoderMode string
+
+const (
+	EncoderModeVolume  EncoderMode = "volume"
+	EncoderModeBalance EncoderMode = "balance"
+	EncoderModeSub     EncoderMode = "sub"
+)
+
 // RotaryReducerStep is one observed rotary detent/step at a given time.
 // Direction is -1 or +1.
 type RotaryReducerStep struct {
@@ -75,16 +204,22 @@ type RotaryReducerStep struct {
 // This is "observed" state: it should be updated when we successfully query CamillaDSP
 // or when a CamillaDSP command returns a value confirming the new state.
 type CamillaDSPState struct {
-	// VolumeDB is the last observed actual volume from CamillaDSP.
-	VolumeDB    float64
+	// VolumeMB is the last observed actual volume from CamillaDSP (see volume_mb.go).
+	VolumeMB    Millibel
 	VolumeKnown bool
-	VolumeAt    time.Time // when VolumeDB was last refreshed
+	VolumeAt    time.Time // when VolumeMB was last refreshed
 
 	// Muted is the last observed mute status from CamillaDSP.
 	Muted     bool
 	MuteKnown bool
 	MuteAt    time.Time // when Muted was last refreshed
 
+	// MuteSent is the state of a SetMute sent but not yet observed (nil if
+	// none), sent at MuteSentAt. Toggles resolve against it so a toggle racing
+	// its predecessor's observation doesn't undo it.
+	MuteSent   *bool
+	MuteSentAt time.Time
+
 	// Config is the last observed/known active config identifier.
 	// Prefer storing file path/title/hash instead of full YAML to keep snapshots small.
 	Config CamillaDSPConfigState
@@ -92,6 +227,29 @@ type CamillaDSPState struct {
 	// State is the processing state reported by CamillaDSP (e.g. Running/Paused/etc),
 	// if you choose to cache it.
 	Processing CamillaDSPProcessingState
+
+	// Unreachable is set when a command fails and cleared by the next successful
+	// observation. ProbeAt is the last time a probe (CmdGetVolume) was issued while unreachable.
+	Unreachable      bool
+	UnreachableSince time.Time
+	ProbeAt          time.Time
+
+	// WakeAt is when a Wake-on-LAN packet was last sent (see wake_on_lan.go).
+	WakeAt time.Time
+
+	// VerifyAt is when the volume was last read back during a hold or ramp to
+	// detect external changes (zero while not moving; see external_change.go).
+	VerifyAt time.Time
+
+	// VolumeRetries / VolumeRetryAt schedule GetVolume retries after timeouts
+	// (see camilladsp_errors.go); reset by the next volume observation.
+	VolumeRetries int
+	VolumeRetryAt time.Time
+
+	// ResyncVolume / ResyncMute make the next volume / mute observation broadcast
+	// even if unchanged, so clients receive the values refreshed by ResyncState.
+	ResyncVolume bool
+	ResyncMute   bool
 }
 
 type CamillaDSPConfigState struct {
@@ -112,75 +270,101 @@ type CamillaDSPProcessingState struct {
 // These are applied by the daemon's centralized side-effect stage (the only code
 // that should talk to CamillaDSP).
 type DaemonIntent struct {
-	// MuteTogglePending indicates a ToggleMute was requested and not yet applied.
-	// If multiple toggles are requested before being applied, they collapse into one
-	// (toggle is its own inverse).
-	MuteTogglePending bool
-
-	// DesiredMute, if non-nil, represents an intent to set mute to a specific state.
-	// This is more expressive than toggle and is useful for UI synchronization.
+	// DesiredMute, if non-nil, is the mute state to set on the next Tick.
+	// Toggles are resolved into a state when requested (see RequestToggleMute),
+	// so every mute change reaches CamillaDSP as an idempotent SetMute.
 	DesiredMute *bool
 
-	// DesiredVolumeDB, if non-nil, represents an intent to set volume to a specific value.
+	// DesiredVolume, if non-nil, represents an intent to set volume to a specific value.
 	// This is intentionally separate from any velocity engine/controller state.
-	DesiredVolumeDB *float64
+	DesiredVolume *Millibel
+
+	// BalancePending/SubPending indicate encoder-mode levels that still need to be applied.
+	BalancePending bool
+	SubPending     bool
+
+	// NormalizationPending indicates NormalizationDB still needs to be applied.
+	NormalizationPending bool
 }
 
-// RequestToggleMute records a mute toggle intent.
+// RequestToggleMute records an intent to invert the expected mute state at now.
+// Repeated toggles before a Tick cancel out, as each inverts the last.
 // This is intended to be called only by the daemon goroutine (single-owner).
-func (s *DaemonState) RequestToggleMute() {
-	s.Intent.MuteTogglePending = true
-	// If there is an explicit DesiredMute pending, a toggle becomes ambiguous.
-	// Keep DesiredMute as-is and let the effects layer decide precedence.
+func (s *DaemonState) RequestToggleMute(now time.Time) {
+	s.SetDesiredMute(!s.expectedMute(now))
 }
 
-// ConsumeToggleMute consumes a pending toggle intent.
-// Returns true if there was a toggle pending.
-// This is intended to be called only by the daemon goroutine (single-owner).
-func (s *DaemonState) ConsumeToggleMute() bool {
-	if !s.Intent.MuteTogglePending {
-		return false
+// expectedMute is the mute state CamillaDSP will have once pending and in-flight
+// commands land: the pending intent, else a SetMute sent within muteSentTimeout,
+// else the observed state. Unknown counts as unmuted, so a toggle mutes.
+func (s *DaemonState) expectedMute(now time.Time) bool {
+	switch {
+	case s.Intent.DesiredMute != nil:
+		return *s.Intent.DesiredMute
+	case s.Camilla.MuteSent != nil && now.Sub(s.Camilla.MuteSentAt) < muteSentTimeout:
+		return *s.Camilla.MuteSent
 	}
-	s.Intent.MuteTogglePending = false
-	return true
+	return s.Camilla.MuteKnown && s.Camilla.Muted
 }
 
-// SetDesiredVolume records an explicit desired volume intent.
+// SetDesiredMute records an explicit desired mute intent, replacing any earlier one.
+// This is intended to be called only by the daemon goroutine (single-owner).
+func (s *DaemonState) SetDesiredMute(muted bool) {
+	s.Intent.DesiredMute = &muted
+}
+
+// SetDesiredVolume records an explicit desired volume intent, rounded to a millibel.
 // This is intended to be called only by the daemon goroutine (single-owner).
 func (s *DaemonState) SetDesiredVolume(db float64) {
-	s.Intent.DesiredVolumeDB = &db
+	mb := mbFromDB(db)
+	s.Intent.DesiredVolume = &mb
 }
 
 // ClearDesiredVolume clears any pending desired volume intent.
 // This is intended to be called only by the daemon goroutine (single-owner).
 func (s *DaemonState) ClearDesiredVolume() {
-	s.Intent.DesiredVolumeDB = nil
+	s.Intent.DesiredVolume = nil
 }
 
 // GetDesiredVolume returns (value, true) if a desired volume intent is present.
 // This is intended to be called only by the daemon goroutine (single-owner).
 func (s *DaemonState) GetDesiredVolume() (float64, bool) {
-	if s.Intent.DesiredVolumeDB == nil {
+	if s.Intent.DesiredVolume == nil {
 		return 0, false
 	}
-	return *s.Intent.DesiredVolumeDB, true
+	return s.Intent.DesiredVolume.DB(), true
 }
 
 // ConsumeDesiredVolume consumes the desired volume intent, if present.
 // Returns (value, true) if there was an intent.
 // This is intended to be called only by the daemon goroutine (single-owner).
 func (s *DaemonState) ConsumeDesiredVolume() (float64, bool) {
-	if s.Intent.DesiredVolumeDB == nil {
+	if s.Intent.DesiredVolume == nil {
 		return 0, false
 	}
-	v := *s.Intent.DesiredVolumeDB
-	s.Intent.DesiredVolumeDB = nil
+	v := s.Intent.DesiredVolume.DB()
+	s.Intent.DesiredVolume = nil
 	return v, true
 }
 
+// HasPendingIntent reports whether any intent is waiting to be flushed into Commands
+// by the next Tick.
+func (s *DaemonState) HasPendingIntent() bool {
+	i := s.Intent
+	return i.DesiredMute != nil || i.DesiredVolume != nil || i.BalancePending || i.SubPending || i.NormalizationPending
+}
+
+// Idle reports whether nothing is moving: no hold or ramp in progress, no residual
+// controller velocity and no pending intent. The daemon loop slows its tick while idle.
+func (s *DaemonState) Idle() bool {
+	const velEps = 0.01 // dB/s, matches the reducer's "effectively stopped" threshold
+	c := s.VolumeCtrl
+	return c.HeldDirection == 0 && !c.Ramping && c.VelocityDBPerS < velEps && c.VelocityDBPerS > -velEps && !s.HasPendingIntent()
+}
+
 // SetObservedMute updates the cached mute state from CamillaDSP.
 // This is intended to be called only by the daemon goroutine (single-owner),
-// after successful GetMute/ToggleMute/SetMute results.
+// after successful GetMute/SetMute results.
 func (s *DaemonState) SetObservedMute(muted bool, now time.Time) {
 	s.Camilla.Muted = muted
 	s.Camilla.MuteKnown = true
@@ -191,7 +375,7 @@ func (s *DaemonState) SetObservedMute(muted bool, now time.Time) {
 // This is intended to be called only by the daemon goroutine (single-owner),
 // after successful GetVolume/SetVolume results.
 func (s *DaemonState) SetObservedVolume(volumeDB float64, now time.Time) {
-	s.Camilla.VolumeDB = volumeDB
+	s.Camilla.VolumeMB = mbFromDB(volumeDB)
 	s.Camilla.VolumeKnown = true
 	s.Camilla.VolumeAt = now
 }
@@ -213,3 +397,77 @@ func (s *DaemonState) SetObservedProcessingState(state string, now time.Time) {
 	s.Camilla.Processing.Known = true
 	s.Camilla.Processing.At = now
 }
+
+// setPlayer stores the latest player state and returns a broadcast if anything
+// user-visible changed (source, state or track metadata).
+// This is intended to be called only by the daemon goroutine (single-owner).
+func (s *DaemonState) setPlayer(next PlayerState) (BroadcastPlayerChanged, bool) {
+	prev := s.Player
+	s.Player = next
+
+	if prev.Source == next.Source && prev.State == next.State &&
+		prev.Title == next.Title && prev.Artist == next.Artist && prev.Album == next.Album &&
+		prev.DurationMs == next.DurationMs {
+		return BroadcastPlayerChanged{}, false
+	}
+	return BroadcastPlayerChanged{
+		Source: next.Source,
+		State:  next.State,
+		Title:  next.Title,
+		Artist: next.Artist,
+		Album:  next.Album,
+		At:     next.At,
+
+		DurationMs: next.DurationMs,
+	}, true
+}
+
+// nextOutput resolves an OutputSelect target. An empty id cycles to the output after
+// the active one (or the first output if none is active).
+func (s *DaemonState) nextOutput(id string) (OutputConfig, bool) {
+	outs := s.Output.Outputs
+	if len(outs) == 0 {
+		return OutputConfig{}, false
+	}
+	if id != "" {
+		for _, o := range outs {
+			if o.ID == id {
+				return o, true
+			}
+		}
+		return OutputConfig{}, false
+	}
+	for i, o := range outs {
+		if o.ID == s.Output.Active {
+			return outs[(i+1)%len(outs)], true
+		}
+	}
+	return outs[0], true
+}
+
+// encoderMode returns the active encoder mode, defaulting to volume.
+func (s *DaemonState) encoderMode() EncoderMode {
+	if s.Rotary.Mode == "" {
+		return EncoderModeVolume
+	}
+	return s.Rotary.Mode
+}
+
+// nextEncoderMode returns the mode after the active one in the cycle
+// volume -> balance -> sub, skipping modes without configured faders.
+func (s *DaemonState) nextEncoderMode(cfg RotaryConfig) EncoderMode {
+	modes := []EncoderMode{EncoderModeVolume}
+	if len(cfg.BalanceFaders) == 2 {
+		modes = append(modes, EncoderModeBalance)
+	}
+	if cfg.SubFader > 0 {
+		modes = append(modes, EncoderModeSub)
+	}
+	cur := s.encoderMode()
+	for i, m := range modes {
+		if m == cur {
+			return modes[(i+1)%len(modes)]
+		}
+	}
+	return EncoderModeVolume
+}
@@ -1,24 +1,33 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"math"
 	"os"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 )
 
-// mockCamillaDSPClient is a test double for CamillaDSPClient
+// mockCamillaDSPClient is a test double for CamillaDSPClient. It is safe for
+// use from the effects worker goroutine.
 type mockCamillaDSPClient struct {
+	mu          sync.Mutex
 	volume      float64
 	muted       bool
 	setVolCalls []float64
 	getVolCalls int
-	toggleCalls int
 
 	// Initial daemon-state sync helpers
 	configFilePath string
 	state          string
+
+	// calls records every method invoked, in order; failOn makes the named
+	// method fail with the given error.
+	calls  []string
+	failOn map[string]error
 }
 
 func newMockCamillaDSPClient(initialVolume float64) *mockCamillaDSPClient {
@@ -31,40 +40,106 @@ func newMockCamillaDSPClient(initialVolume float64) *mockCamillaDSPClient {
 	}
 }
 
-func (m *mockCamillaDSPClient) SetVolume(db float64) (float64, error) {
+// call records method and returns its injected error, if any. m.mu must be held.
+func (m *mockCamillaDSPClient) call(method string) error {
+	m.calls = append(m.calls, method)
+	return m.failOn[method]
+}
+
+// callLog returns the methods invoked so far.
+func (m *mockCamillaDSPClient) callLog() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.calls)
+}
+
+// setVolumeLog returns the SetVolume arguments so far.
+func (m *mockCamillaDSPClient) setVolumeLog() []float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.setVolCalls)
+}
+
+func (m *mockCamillaDSPClient) SetVolume(_ context.Context, db float64) (float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.call("SetVolume"); err != nil {
+		return 0, err
+	}
 	m.setVolCalls = append(m.setVolCalls, db)
 	m.volume = db
 	return db, nil
 }
 
-func (m *mockCamillaDSPClient) GetVolume() (float64, error) {
+func (m *mockCamillaDSPClient) GetVolume(_ context.Context) (float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.call("GetVolume"); err != nil {
+		return 0, err
+	}
 	m.getVolCalls++
 	return m.volume, nil
 }
 
-func (m *mockCamillaDSPClient) GetMute() (bool, error) {
+func (m *mockCamillaDSPClient) GetMute(_ context.Context) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.call("GetMute"); err != nil {
+		return false, err
+	}
 	return m.muted, nil
 }
 
-func (m *mockCamillaDSPClient) SetMute(mute bool) error {
+func (m *mockCamillaDSPClient) SetMute(_ context.Context, mute bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.call("SetMute"); err != nil {
+		return err
+	}
 	m.muted = mute
 	return nil
 }
 
-func (m *mockCamillaDSPClient) ToggleMute() (bool, error) {
-	m.toggleCalls++
-	m.muted = !m.muted
-	return m.muted, nil
-}
-
-func (m *mockCamillaDSPClient) GetConfigFilePath() (string, error) {
+func (m *mockCamillaDSPClient) GetConfigFilePath(_ context.Context) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.call("GetConfigFilePath"); err != nil {
+		return "", err
+	}
 	return m.configFilePath, nil
 }
 
-func (m *mockCamillaDSPClient) GetState() (string, error) {
+func (m *mockCamillaDSPClient) GetState(_ context.Context) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.call("GetState"); err != nil {
+		return "", err
+	}
 	return m.state, nil
 }
 
+func (m *mockCamillaDSPClient) SetFaderVolume(_ context.Context, fader int, targetDB float64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.call("SetFaderVolume")
+}
+
+func (m *mockCamillaDSPClient) SetConfigFilePath(_ context.Context, path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.call("SetConfigFilePath"); err != nil {
+		return err
+	}
+	m.configFilePath = path
+	return nil
+}
+
+func (m *mockCamillaDSPClient) Reload(_ context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.call("Reload")
+}
+
 func (m *mockCamillaDSPClient) Close() error {
 	return nil
 }
@@ -83,7 +158,7 @@ func TestReducer_VolumeStep_Basic(t *testing.T) {
 	state.VolumeCtrl.TargetDB = -30.0
 
 	// Reduce the action
-	rr := Reduce(state, TimedEvent{Event: VolumeStep{Steps: 2, DbPerStep: 0.5}, At: time.Now()}, cfg, RotaryConfig{})
+	rr := Reduce(state, TimedEvent{Event: VolumeStep{Steps: 2, DbPerStep: 0.5}, At: time.Now()}, cfg, RotaryConfig{}, ReducerPolicy{})
 
 	// No side effects have run yet
 	if len(client.setVolCalls) != 0 {
@@ -93,7 +168,7 @@ func TestReducer_VolumeStep_Basic(t *testing.T) {
 	// Reducer should have emitted a CmdSetVolume once Tick is processed; here we follow the current reducer contract:
 	// it records desired volume intent on the action, and emits commands on Tick.
 	// So we drive a Tick to flush intents into commands.
-	rr = Reduce(rr.State, Tick{Now: time.Now(), Dt: 0.01}, cfg, RotaryConfig{})
+	rr = Reduce(rr.State, Tick{Now: time.Now(), Dt: 0.01}, cfg, RotaryConfig{}, ReducerPolicy{})
 
 	if len(rr.Commands) != 1 {
 		t.Fatalf("expected 1 command on Tick, got %d", len(rr.Commands))
@@ -110,13 +185,13 @@ func TestReducer_VolumeStep_Basic(t *testing.T) {
 	}
 
 	// Execute the command (simulated)
-	currentVol, err := client.SetVolume(cmd.TargetDB)
+	currentVol, err := client.SetVolume(t.Context(), cmd.TargetDB)
 	if err != nil {
 		t.Fatalf("SetVolume failed: %v", err)
 	}
 
 	// Feed observation back to reducer
-	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: currentVol, At: time.Now()}, cfg, RotaryConfig{})
+	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: currentVol, At: time.Now()}, cfg, RotaryConfig{}, ReducerPolicy{})
 
 	if len(client.setVolCalls) != 1 {
 		t.Fatalf("expected 1 SetVolume call after executing command, got %d", len(client.setVolCalls))
@@ -124,8 +199,8 @@ func TestReducer_VolumeStep_Basic(t *testing.T) {
 	if client.setVolCalls[0] != expected {
 		t.Errorf("expected volume %f, got %f", expected, client.setVolCalls[0])
 	}
-	if !rr.State.Camilla.VolumeKnown || rr.State.Camilla.VolumeDB != expected {
-		t.Errorf("expected observed volume %f, got %f (known=%v)", expected, rr.State.Camilla.VolumeDB, rr.State.Camilla.VolumeKnown)
+	if !rr.State.Camilla.VolumeKnown || rr.State.Camilla.VolumeMB.DB() != expected {
+		t.Errorf("expected observed volume %f, got %f (known=%v)", expected, rr.State.Camilla.VolumeMB.DB(), rr.State.Camilla.VolumeKnown)
 	}
 }
 
@@ -140,8 +215,8 @@ func TestReducer_VolumeStep_Negative(t *testing.T) {
 	state.SetObservedVolume(-30.0, time.Now())
 	state.VolumeCtrl.TargetDB = -30.0
 
-	rr := Reduce(state, TimedEvent{Event: VolumeStep{Steps: -3, DbPerStep: 0.5}, At: time.Now()}, cfg, RotaryConfig{})
-	rr = Reduce(rr.State, Tick{Now: time.Now(), Dt: 0.01}, cfg, RotaryConfig{})
+	rr := Reduce(state, TimedEvent{Event: VolumeStep{Steps: -3, DbPerStep: 0.5}, At: time.Now()}, cfg, RotaryConfig{}, ReducerPolicy{})
+	rr = Reduce(rr.State, Tick{Now: time.Now(), Dt: 0.01}, cfg, RotaryConfig{}, ReducerPolicy{})
 
 	if len(rr.Commands) != 1 {
 		t.Fatalf("expected 1 command on Tick, got %d", len(rr.Commands))
@@ -156,11 +231,11 @@ func TestReducer_VolumeStep_Negative(t *testing.T) {
 		t.Errorf("expected CmdSetVolume target %f, got %f", expected, cmd.TargetDB)
 	}
 
-	currentVol, err := client.SetVolume(cmd.TargetDB)
+	currentVol, err := client.SetVolume(t.Context(), cmd.TargetDB)
 	if err != nil {
 		t.Fatalf("SetVolume failed: %v", err)
 	}
-	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: currentVol, At: time.Now()}, cfg, RotaryConfig{})
+	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: currentVol, At: time.Now()}, cfg, RotaryConfig{}, ReducerPolicy{})
 
 	if len(client.setVolCalls) != 1 {
 		t.Fatalf("expected 1 SetVolume call after executing command, got %d", len(client.setVolCalls))
@@ -168,8 +243,8 @@ func TestReducer_VolumeStep_Negative(t *testing.T) {
 	if client.setVolCalls[0] != expected {
 		t.Errorf("expected volume %f, got %f", expected, client.setVolCalls[0])
 	}
-	if !rr.State.Camilla.VolumeKnown || rr.State.Camilla.VolumeDB != expected {
-		t.Errorf("expected observed volume %f, got %f (known=%v)", expected, rr.State.Camilla.VolumeDB, rr.State.Camilla.VolumeKnown)
+	if !rr.State.Camilla.VolumeKnown || rr.State.Camilla.VolumeMB.DB() != expected {
+		t.Errorf("expected observed volume %f, got %f (known=%v)", expected, rr.State.Camilla.VolumeMB.DB(), rr.State.Camilla.VolumeKnown)
 	}
 }
 
@@ -184,8 +259,8 @@ func TestReducer_VolumeStep_ClampMax(t *testing.T) {
 	state.SetObservedVolume(-1.0, time.Now())
 	state.VolumeCtrl.TargetDB = -1.0
 
-	rr := Reduce(state, TimedEvent{Event: VolumeStep{Steps: 10, DbPerStep: 0.5}, At: time.Now()}, cfg, RotaryConfig{})
-	rr = Reduce(rr.State, Tick{Now: time.Now(), Dt: 0.01}, cfg, RotaryConfig{})
+	rr := Reduce(state, TimedEvent{Event: VolumeStep{Steps: 10, DbPerStep: 0.5}, At: time.Now()}, cfg, RotaryConfig{}, ReducerPolicy{})
+	rr = Reduce(rr.State, Tick{Now: time.Now(), Dt: 0.01}, cfg, RotaryConfig{}, ReducerPolicy{})
 
 	if len(rr.Commands) != 1 {
 		t.Fatalf("expected 1 command on Tick, got %d", len(rr.Commands))
@@ -200,11 +275,11 @@ func TestReducer_VolumeStep_ClampMax(t *testing.T) {
 		t.Errorf("expected CmdSetVolume target %f, got %f", expected, cmd.TargetDB)
 	}
 
-	currentVol, err := client.SetVolume(cmd.TargetDB)
+	currentVol, err := client.SetVolume(t.Context(), cmd.TargetDB)
 	if err != nil {
 		t.Fatalf("SetVolume failed: %v", err)
 	}
-	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: currentVol, At: time.Now()}, cfg, RotaryConfig{})
+	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: currentVol, At: time.Now()}, cfg, RotaryConfig{}, ReducerPolicy{})
 
 	if len(client.setVolCalls) != 1 {
 		t.Fatalf("expected 1 SetVolume call after executing command, got %d", len(client.setVolCalls))
@@ -226,8 +301,8 @@ func TestReducer_VolumeStep_ClampMin(t *testing.T) {
 	state.VolumeCtrl.TargetDB = -64.0
 
 	// Reduce action then Tick to flush into commands
-	rr := Reduce(state, TimedEvent{Event: VolumeStep{Steps: -10, DbPerStep: 0.5}, At: time.Now()}, cfg, RotaryConfig{})
-	rr = Reduce(rr.State, Tick{Now: time.Now(), Dt: 0.01}, cfg, RotaryConfig{})
+	rr := Reduce(state, TimedEvent{Event: VolumeStep{Steps: -10, DbPerStep: 0.5}, At: time.Now()}, cfg, RotaryConfig{}, ReducerPolicy{})
+	rr = Reduce(rr.State, Tick{Now: time.Now(), Dt: 0.01}, cfg, RotaryConfig{}, ReducerPolicy{})
 
 	if len(rr.Commands) != 1 {
 		t.Fatalf("expected 1 command on Tick, got %d", len(rr.Commands))
@@ -243,11 +318,11 @@ func TestReducer_VolumeStep_ClampMin(t *testing.T) {
 	}
 
 	// Execute command and feed observation back
-	currentVol, err := client.SetVolume(cmd.TargetDB)
+	currentVol, err := client.SetVolume(t.Context(), cmd.TargetDB)
 	if err != nil {
 		t.Fatalf("SetVolume failed: %v", err)
 	}
-	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: currentVol, At: time.Now()}, cfg, RotaryConfig{})
+	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: currentVol, At: time.Now()}, cfg, RotaryConfig{}, ReducerPolicy{})
 
 	if len(client.setVolCalls) != 1 {
 		t.Fatalf("expected 1 SetVolume call after executing command, got %d", len(client.setVolCalls))
@@ -255,8 +330,8 @@ func TestReducer_VolumeStep_ClampMin(t *testing.T) {
 	if client.setVolCalls[0] != expected {
 		t.Errorf("expected volume %f, got %f", expected, client.setVolCalls[0])
 	}
-	if !rr.State.Camilla.VolumeKnown || rr.State.Camilla.VolumeDB != expected {
-		t.Errorf("expected observed volume %f, got %f (known=%v)", expected, rr.State.Camilla.VolumeDB, rr.State.Camilla.VolumeKnown)
+	if !rr.State.Camilla.VolumeKnown || rr.State.Camilla.VolumeMB.DB() != expected {
+		t.Errorf("expected observed volume %f, got %f (known=%v)", expected, rr.State.Camilla.VolumeMB.DB(), rr.State.Camilla.VolumeKnown)
 	}
 }
 
@@ -272,8 +347,8 @@ func TestReducer_VolumeStep_DefaultStepSize(t *testing.T) {
 	state.VolumeCtrl.TargetDB = -30.0
 
 	// DbPerStep is 0 -> should use defaultRotaryDbPerStep
-	rr := Reduce(state, TimedEvent{Event: VolumeStep{Steps: 2, DbPerStep: 0}, At: time.Now()}, cfg, RotaryConfig{})
-	rr = Reduce(rr.State, Tick{Now: time.Now(), Dt: 0.01}, cfg, RotaryConfig{})
+	rr := Reduce(state, TimedEvent{Event: VolumeStep{Steps: 2, DbPerStep: 0}, At: time.Now()}, cfg, RotaryConfig{}, ReducerPolicy{})
+	rr = Reduce(rr.State, Tick{Now: time.Now(), Dt: 0.01}, cfg, RotaryConfig{}, ReducerPolicy{})
 
 	if len(rr.Commands) != 1 {
 		t.Fatalf("expected 1 command on Tick, got %d", len(rr.Commands))
@@ -289,11 +364,11 @@ func TestReducer_VolumeStep_DefaultStepSize(t *testing.T) {
 		t.Errorf("expected CmdSetVolume target %f, got %f", expected, cmd.TargetDB)
 	}
 
-	currentVol, err := client.SetVolume(cmd.TargetDB)
+	currentVol, err := client.SetVolume(t.Context(), cmd.TargetDB)
 	if err != nil {
 		t.Fatalf("SetVolume failed: %v", err)
 	}
-	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: currentVol, At: time.Now()}, cfg, RotaryConfig{})
+	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: currentVol, At: time.Now()}, cfg, RotaryConfig{}, ReducerPolicy{})
 
 	if len(client.setVolCalls) != 1 {
 		t.Fatalf("expected 1 SetVolume call after executing command, got %d", len(client.setVolCalls))
@@ -313,8 +388,8 @@ func TestReducer_VolumeStep_VolumeUnknown(t *testing.T) {
 	// Do not set observed volume in daemon state; this should fall back to controller TargetDB (initially 0.0).
 	state := &DaemonState{}
 
-	rr := Reduce(state, TimedEvent{Event: VolumeStep{Steps: 2, DbPerStep: 0.5}, At: time.Now()}, cfg, RotaryConfig{})
-	rr = Reduce(rr.State, Tick{Now: time.Now(), Dt: 0.01}, cfg, RotaryConfig{})
+	rr := Reduce(state, TimedEvent{Event: VolumeStep{Steps: 2, DbPerStep: 0.5}, At: time.Now()}, cfg, RotaryConfig{}, ReducerPolicy{})
+	rr = Reduce(rr.State, Tick{Now: time.Now(), Dt: 0.01}, cfg, RotaryConfig{}, ReducerPolicy{})
 
 	if len(rr.Commands) != 1 {
 		t.Fatalf("expected 1 command on Tick, got %d", len(rr.Commands))
@@ -330,11 +405,11 @@ func TestReducer_VolumeStep_VolumeUnknown(t *testing.T) {
 		t.Errorf("expected CmdSetVolume target %f, got %f", expected, cmd.TargetDB)
 	}
 
-	currentVol, err := client.SetVolume(cmd.TargetDB)
+	currentVol, err := client.SetVolume(t.Context(), cmd.TargetDB)
 	if err != nil {
 		t.Fatalf("SetVolume failed: %v", err)
 	}
-	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: currentVol, At: time.Now()}, cfg, RotaryConfig{})
+	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: currentVol, At: time.Now()}, cfg, RotaryConfig{}, ReducerPolicy{})
 
 	if len(client.setVolCalls) != 1 {
 		t.Fatalf("expected 1 SetVolume call after executing command, got %d", len(client.setVolCalls))
@@ -355,23 +430,23 @@ func TestReducer_VolumeStep_MultipleSteps(t *testing.T) {
 	state.SetObservedVolume(-30.0, time.Now())
 	state.VolumeCtrl.TargetDB = -30.0
 
-	rr := Reduce(state, TimedEvent{Event: VolumeStep{Steps: 2, DbPerStep: 0.5}, At: time.Now()}, cfg, RotaryConfig{})
-	rr = Reduce(rr.State, Tick{Now: time.Now(), Dt: 0.01}, cfg, RotaryConfig{})
+	rr := Reduce(state, TimedEvent{Event: VolumeStep{Steps: 2, DbPerStep: 0.5}, At: time.Now()}, cfg, RotaryConfig{}, ReducerPolicy{})
+	rr = Reduce(rr.State, Tick{Now: time.Now(), Dt: 0.01}, cfg, RotaryConfig{}, ReducerPolicy{})
 	cmd1 := rr.Commands[0].(CmdSetVolume)
-	v1, _ := client.SetVolume(cmd1.TargetDB)
-	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: v1, At: time.Now()}, cfg, RotaryConfig{})
+	v1, _ := client.SetVolume(t.Context(), cmd1.TargetDB)
+	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: v1, At: time.Now()}, cfg, RotaryConfig{}, ReducerPolicy{})
 
-	rr = Reduce(rr.State, TimedEvent{Event: VolumeStep{Steps: 2, DbPerStep: 0.5}, At: time.Now()}, cfg, RotaryConfig{})
-	rr = Reduce(rr.State, Tick{Now: time.Now(), Dt: 0.01}, cfg, RotaryConfig{})
+	rr = Reduce(rr.State, TimedEvent{Event: VolumeStep{Steps: 2, DbPerStep: 0.5}, At: time.Now()}, cfg, RotaryConfig{}, ReducerPolicy{})
+	rr = Reduce(rr.State, Tick{Now: time.Now(), Dt: 0.01}, cfg, RotaryConfig{}, ReducerPolicy{})
 	cmd2 := rr.Commands[0].(CmdSetVolume)
-	v2, _ := client.SetVolume(cmd2.TargetDB)
-	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: v2, At: time.Now()}, cfg, RotaryConfig{})
+	v2, _ := client.SetVolume(t.Context(), cmd2.TargetDB)
+	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: v2, At: time.Now()}, cfg, RotaryConfig{}, ReducerPolicy{})
 
-	rr = Reduce(rr.State, TimedEvent{Event: VolumeStep{Steps: -1, DbPerStep: 0.5}, At: time.Now()}, cfg, RotaryConfig{})
-	rr = Reduce(rr.State, Tick{Now: time.Now(), Dt: 0.01}, cfg, RotaryConfig{})
+	rr = Reduce(rr.State, TimedEvent{Event: VolumeStep{Steps: -1, DbPerStep: 0.5}, At: time.Now()}, cfg, RotaryConfig{}, ReducerPolicy{})
+	rr = Reduce(rr.State, Tick{Now: time.Now(), Dt: 0.01}, cfg, RotaryConfig{}, ReducerPolicy{})
 	cmd3 := rr.Commands[0].(CmdSetVolume)
-	v3, _ := client.SetVolume(cmd3.TargetDB)
-	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: v3, At: time.Now()}, cfg, RotaryConfig{})
+	v3, _ := client.SetVolume(t.Context(), cmd3.TargetDB)
+	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: v3, At: time.Now()}, cfg, RotaryConfig{}, ReducerPolicy{})
 
 	if len(client.setVolCalls) != 3 {
 		t.Fatalf("expected 3 SetVolume calls, got %d", len(client.setVolCalls))
@@ -381,8 +456,8 @@ func TestReducer_VolumeStep_MultipleSteps(t *testing.T) {
 	if client.setVolCalls[2] != expected {
 		t.Errorf("expected final volume %f, got %f", expected, client.setVolCalls[2])
 	}
-	if !rr.State.Camilla.VolumeKnown || rr.State.Camilla.VolumeDB != expected {
-		t.Errorf("expected daemon observed volume %f, got %f (known=%v)", expected, rr.State.Camilla.VolumeDB, rr.State.Camilla.VolumeKnown)
+	if !rr.State.Camilla.VolumeKnown || rr.State.Camilla.VolumeMB.DB() != expected {
+		t.Errorf("expected daemon observed volume %f, got %f (known=%v)", expected, rr.State.Camilla.VolumeMB.DB(), rr.State.Camilla.VolumeKnown)
 	}
 }
 
@@ -397,8 +472,8 @@ func TestReducer_VolumeStep_LargeStepSize(t *testing.T) {
 	state.SetObservedVolume(-30.0, time.Now())
 	state.VolumeCtrl.TargetDB = -30.0
 
-	rr := Reduce(state, TimedEvent{Event: VolumeStep{Steps: 3, DbPerStep: 1.0}, At: time.Now()}, cfg, RotaryConfig{})
-	rr = Reduce(rr.State, Tick{Now: time.Now(), Dt: 0.01}, cfg, RotaryConfig{})
+	rr := Reduce(state, TimedEvent{Event: VolumeStep{Steps: 3, DbPerStep: 1.0}, At: time.Now()}, cfg, RotaryConfig{}, ReducerPolicy{})
+	rr = Reduce(rr.State, Tick{Now: time.Now(), Dt: 0.01}, cfg, RotaryConfig{}, ReducerPolicy{})
 
 	if len(rr.Commands) != 1 {
 		t.Fatalf("expected 1 command on Tick, got %d", len(rr.Commands))
@@ -413,11 +488,11 @@ func TestReducer_VolumeStep_LargeStepSize(t *testing.T) {
 		t.Errorf("expected CmdSetVolume target %f, got %f", expected, cmd.TargetDB)
 	}
 
-	currentVol, err := client.SetVolume(cmd.TargetDB)
+	currentVol, err := client.SetVolume(t.Context(), cmd.TargetDB)
 	if err != nil {
 		t.Fatalf("SetVolume failed: %v", err)
 	}
-	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: currentVol, At: time.Now()}, cfg, RotaryConfig{})
+	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: currentVol, At: time.Now()}, cfg, RotaryConfig{}, ReducerPolicy{})
 
 	if len(client.setVolCalls) != 1 {
 		t.Fatalf("expected 1 SetVolume call after executing command, got %d", len(client.setVolCalls))
@@ -442,12 +517,12 @@ func TestReducer_VolumeHeld_Integration(t *testing.T) {
 	state.VolumeCtrl.TargetDB = -30.0
 
 	// Hold, then step, then release. Rotary step should cancel hold movement.
-	rr := Reduce(state, TimedEvent{Event: VolumeHeld{Direction: 1}, At: time.Now()}, cfg, RotaryConfig{})
-	rr = Reduce(rr.State, TimedEvent{Event: VolumeStep{Steps: 2, DbPerStep: 0.5}, At: time.Now()}, cfg, RotaryConfig{})
-	rr = Reduce(rr.State, TimedEvent{Event: VolumeRelease{}, At: time.Now()}, cfg, RotaryConfig{})
+	rr := Reduce(state, TimedEvent{Event: VolumeHeld{Direction: 1}, At: time.Now()}, cfg, RotaryConfig{}, ReducerPolicy{})
+	rr = Reduce(rr.State, TimedEvent{Event: VolumeStep{Steps: 2, DbPerStep: 0.5}, At: time.Now()}, cfg, RotaryConfig{}, ReducerPolicy{})
+	rr = Reduce(rr.State, TimedEvent{Event: VolumeRelease{}, At: time.Now()}, cfg, RotaryConfig{}, ReducerPolicy{})
 
 	// Flush to commands on Tick
-	rr = Reduce(rr.State, Tick{Now: time.Now(), Dt: 0.01}, cfg, RotaryConfig{})
+	rr = Reduce(rr.State, Tick{Now: time.Now(), Dt: 0.01}, cfg, RotaryConfig{}, ReducerPolicy{})
 
 	if len(rr.Commands) != 1 {
 		t.Fatalf("expected 1 command on Tick, got %d", len(rr.Commands))
@@ -462,11 +537,11 @@ func TestReducer_VolumeHeld_Integration(t *testing.T) {
 		t.Errorf("expected CmdSetVolume target %f, got %f", expected, cmd.TargetDB)
 	}
 
-	currentVol, err := client.SetVolume(cmd.TargetDB)
+	currentVol, err := client.SetVolume(t.Context(), cmd.TargetDB)
 	if err != nil {
 		t.Fatalf("SetVolume failed: %v", err)
 	}
-	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: currentVol, At: time.Now()}, cfg, RotaryConfig{})
+	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: currentVol, At: time.Now()}, cfg, RotaryConfig{}, ReducerPolicy{})
 
 	if len(client.setVolCalls) != 1 {
 		t.Fatalf("expected 1 SetVolume call after executing command, got %d", len(client.setVolCalls))
@@ -476,7 +551,8 @@ func TestReducer_VolumeHeld_Integration(t *testing.T) {
 	}
 }
 
-// TestReducer_ToggleMute tests that ToggleMute results in a CmdToggleMute on Tick and updates observed state on reply.
+// TestReducer_ToggleMute tests that ToggleMute results in a CmdSetMute of the inverted state on Tick
+// and updates observed state on reply.
 func TestReducer_ToggleMute(t *testing.T) {
 	client := newMockCamillaDSPClient(-30.0)
 	cfg := VelocityConfig{
@@ -486,59 +562,81 @@ func TestReducer_ToggleMute(t *testing.T) {
 	state := &DaemonState{}
 	state.SetObservedMute(false, time.Now())
 
-	// Reduce action: should set intent, no command until Tick
-	rr := Reduce(state, TimedEvent{Event: ToggleMute{}, At: time.Now()}, cfg, RotaryConfig{})
-
-	if client.toggleCalls != 0 {
-		t.Errorf("expected 0 ToggleMute calls before executing reducer commands, got %d", client.toggleCalls)
-	}
+	for i, want := range []bool{true, false} {
+		// Reduce action: should set intent, no command until Tick
+		rr := Reduce(state, TimedEvent{Event: ToggleMute{}, At: time.Now()}, cfg, RotaryConfig{}, ReducerPolicy{})
+		if len(rr.Commands) != 0 {
+			t.Fatalf("toggle %d: expected no commands before Tick, got %v", i, rr.Commands)
+		}
 
-	// Drive a Tick to flush intents into commands
-	rr = Reduce(rr.State, Tick{Now: time.Now(), Dt: 0.01}, cfg, RotaryConfig{})
+		// Drive a Tick to flush intents into commands
+		rr = Reduce(rr.State, Tick{Now: time.Now(), Dt: 0.01}, cfg, RotaryConfig{}, ReducerPolicy{})
+		if len(rr.Commands) != 1 {
+			t.Fatalf("toggle %d: expected 1 command on Tick, got %d", i, len(rr.Commands))
+		}
+		c, ok := rr.Commands[0].(CmdSetMute)
+		if !ok || c.Muted != want {
+			t.Fatalf("toggle %d: expected CmdSetMute(muted=%v), got %v", i, want, rr.Commands[0])
+		}
 
-	if len(rr.Commands) != 1 {
-		t.Fatalf("expected 1 command on Tick, got %d", len(rr.Commands))
-	}
-	if _, ok := rr.Commands[0].(CmdToggleMute); !ok {
-		t.Fatalf("expected CmdToggleMute, got %T", rr.Commands[0])
+		// Execute command and feed observation
+		if err := client.SetMute(t.Context(), c.Muted); err != nil {
+			t.Fatalf("SetMute failed: %v", err)
+		}
+		rr = Reduce(rr.State, CamillaMuteObserved{Muted: client.muted, At: time.Now()}, cfg, RotaryConfig{}, ReducerPolicy{})
+		if !rr.State.Camilla.MuteKnown || rr.State.Camilla.Muted != want || rr.State.Camilla.MuteSent != nil {
+			t.Fatalf("toggle %d: camilla state = %+v", i, rr.State.Camilla)
+		}
 	}
+}
 
-	// Execute command and feed observation
-	muted, err := client.ToggleMute()
-	if err != nil {
-		t.Fatalf("ToggleMute failed: %v", err)
-	}
-	rr = Reduce(rr.State, CamillaMuteObserved{Muted: muted, At: time.Now()}, cfg, RotaryConfig{})
+// TestReducer_ToggleMuteRacesObservation checks that a toggle arriving before the
+// previous toggle's observation inverts the state that was sent, not the stale one.
+func TestReducer_ToggleMuteRacesObservation(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -65, MaxDB: 0}
+	t0 := time.Unix(1000, 0)
+	s := &DaemonState{}
+	s.SetObservedMute(false, t0)
 
-	if client.toggleCalls != 1 {
-		t.Errorf("expected 1 ToggleMute call after executing command, got %d", client.toggleCalls)
+	Reduce(s, TimedEvent{Event: ToggleMute{}, At: t0}, cfg, RotaryConfig{}, ReducerPolicy{})
+	rr := Reduce(s, Tick{Now: t0.Add(10 * time.Millisecond), Dt: 0.01}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if len(rr.Commands) != 1 || rr.Commands[0] != (CmdSetMute{Muted: true}) {
+		t.Fatalf("first toggle: %v", rr.Commands)
 	}
-	if !rr.State.Camilla.MuteKnown || !rr.State.Camilla.Muted {
-		t.Error("expected daemon state to have observed mute=true after observation")
-	}
-
-	// Second toggle
-	rr = Reduce(rr.State, TimedEvent{Event: ToggleMute{}, At: time.Now()}, cfg, RotaryConfig{})
-	rr = Reduce(rr.State, Tick{Now: time.Now(), Dt: 0.01}, cfg, RotaryConfig{})
 
-	if len(rr.Commands) != 1 {
-		t.Fatalf("expected 1 command on second Tick, got %d", len(rr.Commands))
+	// Second press while the mute is still in flight: unmute, not mute again.
+	Reduce(s, TimedEvent{Event: ToggleMute{}, At: t0.Add(20 * time.Millisecond)}, cfg, RotaryConfig{}, ReducerPolicy{})
+	rr = Reduce(s, Tick{Now: t0.Add(30 * time.Millisecond), Dt: 0.01}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if len(rr.Commands) != 1 || rr.Commands[0] != (CmdSetMute{Muted: false}) {
+		t.Fatalf("second toggle: %v", rr.Commands)
 	}
-	if _, ok := rr.Commands[0].(CmdToggleMute); !ok {
-		t.Fatalf("expected CmdToggleMute on second toggle, got %T", rr.Commands[0])
+
+	// The first observation arrives late; a third press still follows the last sent state.
+	Reduce(s, CamillaMuteObserved{Muted: true, At: t0.Add(40 * time.Millisecond)}, cfg, RotaryConfig{}, ReducerPolicy{})
+	Reduce(s, TimedEvent{Event: ToggleMute{}, At: t0.Add(50 * time.Millisecond)}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if m := s.Intent.DesiredMute; m == nil || !*m {
+		t.Fatalf("third toggle: intent = %+v", s.Intent)
 	}
 
-	muted, err = client.ToggleMute()
-	if err != nil {
-		t.Fatalf("ToggleMute failed: %v", err)
+	// Two presses within one tick cancel out into the expected state.
+	Reduce(s, TimedEvent{Event: ToggleMute{}, At: t0.Add(60 * time.Millisecond)}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if m := s.Intent.DesiredMute; m == nil || *m {
+		t.Fatalf("fourth toggle: intent = %+v", s.Intent)
 	}
-	rr = Reduce(rr.State, CamillaMuteObserved{Muted: muted, At: time.Now()}, cfg, RotaryConfig{})
 
-	if client.toggleCalls != 2 {
-		t.Errorf("expected 2 ToggleMute calls after executing second command, got %d", client.toggleCalls)
+	// A failed SetMute, or one never observed, stops counting as in flight.
+	s.Intent.DesiredMute = nil
+	Reduce(s, CamillaCommandFailed{Command: CmdSetMute{Muted: false}, At: t0.Add(70 * time.Millisecond)}, cfg, RotaryConfig{}, ReducerPolicy{})
+	Reduce(s, TimedEvent{Event: ToggleMute{}, At: t0.Add(80 * time.Millisecond)}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if m := s.Intent.DesiredMute; m == nil || *m {
+		t.Fatalf("toggle after failure: intent = %+v (observed muted)", s.Intent)
 	}
-	if !rr.State.Camilla.MuteKnown || rr.State.Camilla.Muted {
-		t.Error("expected daemon state to have observed mute=false after second observation")
+	s.Intent.DesiredMute = nil
+	on := false
+	s.Camilla.MuteSent, s.Camilla.MuteSentAt = &on, t0
+	Reduce(s, TimedEvent{Event: ToggleMute{}, At: t0.Add(muteSentTimeout)}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if m := s.Intent.DesiredMute; m == nil || *m {
+		t.Fatalf("toggle after timeout: intent = %+v", s.Intent)
 	}
 }
 
@@ -565,16 +663,16 @@ func TestReducer_InertiaDecayAfterRelease(t *testing.T) {
 	state.VolumeCtrl.TargetDB = -30.0
 
 	// Press/hold volume up for a few ticks to build up velocity (acceleration).
-	state = Reduce(state, TimedEvent{Event: VolumeHeld{Direction: 1}, At: now}, cfg, RotaryConfig{}).State
+	state = Reduce(state, TimedEvent{Event: VolumeHeld{Direction: 1}, At: now}, cfg, RotaryConfig{}, ReducerPolicy{}).State
 	for i := 0; i < 10; i++ {
 		now = now.Add(33 * time.Millisecond)
-		state = Reduce(state, Tick{Now: now, Dt: 0.033}, cfg, RotaryConfig{}).State
+		state = Reduce(state, Tick{Now: now, Dt: 0.033}, cfg, RotaryConfig{}, ReducerPolicy{}).State
 
 		// In the real program, after each Tick the daemon executes CmdSetVolume and then
 		// feeds CamillaVolumeObserved back into the reducer. If we don't do that here,
 		// the reducer's baseline selection may snap back to the observed volume and
 		// fight the controller's inertial motion.
-		state = Reduce(state, CamillaVolumeObserved{VolumeDB: state.VolumeCtrl.TargetDB, At: now}, cfg, RotaryConfig{}).State
+		state = Reduce(state, CamillaVolumeObserved{VolumeDB: state.VolumeCtrl.TargetDB, At: now}, cfg, RotaryConfig{}, ReducerPolicy{}).State
 	}
 
 	// The controller should have built some positive velocity.
@@ -583,7 +681,7 @@ func TestReducer_InertiaDecayAfterRelease(t *testing.T) {
 	}
 
 	// Release should NOT zero velocity immediately in accelerating mode; it should decay over time.
-	state = Reduce(state, TimedEvent{Event: VolumeRelease{}, At: now}, cfg, RotaryConfig{}).State
+	state = Reduce(state, TimedEvent{Event: VolumeRelease{}, At: now}, cfg, RotaryConfig{}, ReducerPolicy{}).State
 	if state.VolumeCtrl.HeldDirection != 0 {
 		t.Fatalf("expected heldDirection=0 after release, got %d", state.VolumeCtrl.HeldDirection)
 	}
@@ -594,8 +692,8 @@ func TestReducer_InertiaDecayAfterRelease(t *testing.T) {
 	// After release, the target should continue moving for at least one tick (inertia),
 	// and velocity should start decaying.
 	now = now.Add(33 * time.Millisecond)
-	state = Reduce(state, Tick{Now: now, Dt: 0.033}, cfg, RotaryConfig{}).State
-	state = Reduce(state, CamillaVolumeObserved{VolumeDB: state.VolumeCtrl.TargetDB, At: now}, cfg, RotaryConfig{}).State
+	state = Reduce(state, Tick{Now: now, Dt: 0.033}, cfg, RotaryConfig{}, ReducerPolicy{}).State
+	state = Reduce(state, CamillaVolumeObserved{VolumeDB: state.VolumeCtrl.TargetDB, At: now}, cfg, RotaryConfig{}, ReducerPolicy{}).State
 
 	target1 := state.VolumeCtrl.TargetDB
 	vel1 := state.VolumeCtrl.VelocityDBPerS
@@ -614,8 +712,8 @@ func TestReducer_InertiaDecayAfterRelease(t *testing.T) {
 	prevTarget := target1
 	for i := 0; i < 10; i++ {
 		now = now.Add(33 * time.Millisecond)
-		state = Reduce(state, Tick{Now: now, Dt: 0.033}, cfg, RotaryConfig{}).State
-		state = Reduce(state, CamillaVolumeObserved{VolumeDB: state.VolumeCtrl.TargetDB, At: now}, cfg, RotaryConfig{}).State
+		state = Reduce(state, Tick{Now: now, Dt: 0.033}, cfg, RotaryConfig{}, ReducerPolicy{}).State
+		state = Reduce(state, CamillaVolumeObserved{VolumeDB: state.VolumeCtrl.TargetDB, At: now}, cfg, RotaryConfig{}, ReducerPolicy{}).State
 
 		v := state.VolumeCtrl.VelocityDBPerS
 		tgt := state.VolumeCtrl.TargetDB
@@ -657,15 +755,15 @@ func TestReducer_AccelerationBuildsVelocityWhileHeld(t *testing.T) {
 	state.VolumeCtrl.TargetDB = -30.0
 
 	// Start holding volume up.
-	state = Reduce(state, TimedEvent{Event: VolumeHeld{Direction: 1}, At: now}, cfg, RotaryConfig{}).State
+	state = Reduce(state, TimedEvent{Event: VolumeHeld{Direction: 1}, At: now}, cfg, RotaryConfig{}, ReducerPolicy{}).State
 
 	// Step a few ticks and verify velocity increases over time until capped.
 	var prevVel float64
 	for i := 0; i < 8; i++ {
 		now = now.Add(33 * time.Millisecond)
-		state = Reduce(state, Tick{Now: now, Dt: 0.033}, cfg, RotaryConfig{}).State
+		state = Reduce(state, Tick{Now: now, Dt: 0.033}, cfg, RotaryConfig{}, ReducerPolicy{}).State
 		// Simulate that CamillaDSP applies the desired volume (keeps baseline aligned).
-		state = Reduce(state, CamillaVolumeObserved{VolumeDB: state.VolumeCtrl.TargetDB, At: now}, cfg, RotaryConfig{}).State
+		state = Reduce(state, CamillaVolumeObserved{VolumeDB: state.VolumeCtrl.TargetDB, At: now}, cfg, RotaryConfig{}, ReducerPolicy{}).State
 
 		v := state.VolumeCtrl.VelocityDBPerS
 		if v < 0 {
@@ -685,3 +783,58 @@ func TestReducer_AccelerationBuildsVelocityWhileHeld(t *testing.T) {
 		t.Fatalf("expected velocity to build up due to acceleration, got %f", state.VolumeCtrl.VelocityDBPerS)
 	}
 }
+
+func TestDaemonState_Idle(t *testing.T) {
+	s := &DaemonState{}
+	if !s.Idle() {
+		t.Fatalf("expected fresh state to be idle")
+	}
+
+	s.SetDesiredVolume(-20)
+	if s.Idle() || !s.HasPendingIntent() {
+		t.Fatalf("expected pending volume intent to keep the loop awake")
+	}
+	s.ClearDesiredVolume()
+
+	s.VolumeCtrl.HeldDirection = 1
+	if s.Idle() {
+		t.Fatalf("expected active hold to keep the loop awake")
+	}
+	s.VolumeCtrl.HeldDirection = 0
+
+	s.VolumeCtrl.VelocityDBPerS = -2
+	if s.Idle() {
+		t.Fatalf("expected residual velocity to keep the loop awake")
+	}
+	s.VolumeCtrl.VelocityDBPerS = 0.001
+	if !s.Idle() {
+		t.Fatalf("expected negligible velocity to count as idle")
+	}
+
+	s.RequestToggleMute(time.Now())
+	if s.Idle() {
+		t.Fatalf("expected pending mute toggle to keep the loop awake")
+	}
+}
+
+// TestRunDaemon_SnapshotWithoutDSP checks that snapshot requests are answered by
+// the loop itself, even when no CamillaDSP command can complete.
+func TestRunDaemon_SnapshotWithoutDSP(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := slog.New(slog.DiscardHandler)
+	events := make(chan Event)
+	crash := newCrashReporter(t.TempDir(), func() {}, logger)
+	go runDaemon(ctx, "main", events, nil, nil, VelocityConfig{}, RotaryConfig{}, ReducerPolicy{}, nil, 50, 0, nil, crash, logger)
+
+	reply := make(chan StateSnapshot, 1)
+	events <- RequestStateSnapshot{Reply: reply}
+	select {
+	case snap := <-reply:
+		if snap.Zone != "main" {
+			t.Fatalf("zone = %q", snap.Zone)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no snapshot")
+	}
+}
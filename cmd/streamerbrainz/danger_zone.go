@@ -0,0 +1,100 @@
+package main
+
+import "math"
+
+// ============================================================================
+// Danger zone (near MaxDB)
+// ============================================================================
+// The top velocity.danger_zone_db below MaxDB is approached carefully:
+//
+//   - Holds slow down from danger_vel_max_db_per_sec at the zone's edge to
+//     danger_vel_min_near0_db_per_sec at MaxDB (StepVolumeController).
+//   - Rotary detents upward inside the zone move at most
+//     danger_rotary_db_per_step dB each, whatever the spin rate, so a fast
+//     spin needs several detents per dB instead of multiplying its step.
+//   - With danger_ramp_absolute, absolute sets (web sliders, network presets)
+//     that go up into the zone fade through it at the hold's danger velocity
+//     instead of jumping, even with ramp_db_per_sec 0.
+//
+// Downward movement is never limited.
+// ============================================================================
+
+// dangerRampMinDBPerS keeps danger-zone fades moving when
+// danger_vel_min_near0_db_per_sec is 0, so an absolute set always completes.
+const dangerRampMinDBPerS = 0.1
+
+// dangerThresholdDB returns where the danger zone starts (false if disabled).
+func dangerThresholdDB(cfg VelocityConfig) (float64, bool) {
+	if cfg.DangerZoneDB <= 0 {
+		return 0, false
+	}
+	return cfg.MaxDB - cfg.DangerZoneDB, true
+}
+
+// dangerVelMax returns the upward velocity cap at level db: false outside the
+// zone, otherwise easing from DangerVelMaxDBPerS at the zone's edge down to
+// DangerVelMinNear0DBPerS at MaxDB.
+func dangerVelMax(db float64, cfg VelocityConfig) (float64, bool) {
+	threshold, ok := dangerThresholdDB(cfg)
+	if !ok || db <= threshold {
+		return 0, false
+	}
+	x := (db - threshold) / cfg.DangerZoneDB
+	x = math.Max(0, math.Min(1, x))
+	extra := math.Max(0, math.Min(1, 1.0-(x*x*x)))
+	return cfg.DangerVelMinNear0DBPerS + (cfg.DangerVelMaxDBPerS-cfg.DangerVelMinNear0DBPerS)*extra, true
+}
+
+// rampStep moves an absolute-set fade from `from` toward `to` by one tick of dt
+// seconds at RampDBPerS (0 = jump), slowed to the danger velocity on the way up
+// through the zone when DangerRampAbsolute is set.
+func rampStep(from, to, dt float64, cfg VelocityConfig) float64 {
+	rate := cfg.RampDBPerS
+	if threshold, ok := dangerThresholdDB(cfg); ok && cfg.DangerRampAbsolute && to > from && to > threshold {
+		if from < threshold {
+			if rate <= 0 {
+				// Jump as usual up to the zone's edge, then fade through it.
+				return threshold
+			}
+		} else if v, _ := dangerVelMax(from, cfg); rate <= 0 || v < rate {
+			rate = math.Max(v, dangerRampMinDBPerS)
+		}
+	}
+	step := rate * dt
+	switch diff := to - from; {
+	case rate <= 0 || math.Abs(diff) <= step:
+		return to
+	case diff > 0:
+		return from + step
+	default:
+		return from - step
+	}
+}
+
+// dangerRampNeeded reports whether an absolute set from `from` to `to` must fade
+// even though RampDBPerS is 0.
+func dangerRampNeeded(from, to float64, cfg VelocityConfig) bool {
+	threshold, ok := dangerThresholdDB(cfg)
+	return ok && cfg.DangerRampAbsolute && to > from && to > threshold
+}
+
+// dangerRotaryTarget applies deltaDB (a rotary move of `detents` detents from
+// current) with the zone's per-detent cap: detents spent inside the danger zone
+// move at most DangerRotaryDBPerStep each.
+func dangerRotaryTarget(current, deltaDB, detents float64, cfg VelocityConfig) float64 {
+	threshold, ok := dangerThresholdDB(cfg)
+	if !ok || cfg.DangerRotaryDBPerStep <= 0 || deltaDB <= 0 || detents <= 0 || current+deltaDB <= threshold {
+		return current + deltaDB
+	}
+	perDetent := deltaDB / detents
+	if perDetent <= cfg.DangerRotaryDBPerStep {
+		return current + deltaDB
+	}
+	base := current
+	if current < threshold {
+		// Detents below the zone keep their full step.
+		detents -= (threshold - current) / perDetent
+		base = threshold
+	}
+	return base + detents*cfg.DangerRotaryDBPerStep
+}
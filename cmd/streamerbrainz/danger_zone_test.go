@@ -0,0 +1,58 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDangerRotaryTarget(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0, DangerZoneDB: 10, DangerRotaryDBPerStep: 0.25}
+	for _, tt := range []struct {
+		current, delta, detents, want float64
+	}{
+		{-30, 4, 2, -26},   // below the zone: unchanged
+		{-8, 4, 2, -7.5},   // inside: 0.25 dB per detent
+		{-12, 8, 4, -9.25}, // crossing: 1 detent to reach -10, 3 capped
+		{-8, -4, -2, -12},  // down: never limited
+		{-8, 0.5, 2, -7.5}, // small steps already under the cap
+	} {
+		if got := dangerRotaryTarget(tt.current, tt.delta, tt.detents, cfg); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("dangerRotaryTarget(%v, %v, %v) = %v, want %v", tt.current, tt.delta, tt.detents, got, tt.want)
+		}
+	}
+}
+
+func TestReduce_AbsoluteSetFadesThroughDangerZone(t *testing.T) {
+	cfg := VelocityConfig{
+		MinDB: -80, MaxDB: 0,
+		DangerZoneDB: 10, DangerVelMaxDBPerS: 4, DangerVelMinNear0DBPerS: 1,
+		DangerRampAbsolute: true,
+	}
+	t0 := time.Unix(1000, 0).UTC()
+	s := &DaemonState{}
+	s.SetObservedVolume(-30, t0)
+
+	rr := Reduce(s, TimedEvent{At: t0, Event: SetVolumeAbsolute{Db: 0, Origin: "webui"}}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if !rr.State.VolumeCtrl.Ramping {
+		t.Fatal("set into the danger zone did not fade")
+	}
+
+	// First tick jumps to the zone's edge (ramp_db_per_sec is 0), then it fades.
+	rr = Reduce(rr.State, Tick{Now: t0.Add(100 * time.Millisecond), Dt: 0.1}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if got := rr.State.VolumeCtrl.TargetDB; got != -10 {
+		t.Fatalf("after first tick target = %v, want -10", got)
+	}
+	rr = Reduce(rr.State, Tick{Now: t0.Add(200 * time.Millisecond), Dt: 0.1}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if got := rr.State.VolumeCtrl.TargetDB; got <= -10 || got > -9.5 {
+		t.Fatalf("after second tick target = %v, want a slow climb", got)
+	}
+
+	// Sets below the zone still jump.
+	s = &DaemonState{}
+	s.SetObservedVolume(-30, t0)
+	rr = Reduce(s, TimedEvent{At: t0, Event: SetVolumeAbsolute{Db: -20}}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if v, ok := rr.State.GetDesiredVolume(); rr.State.VolumeCtrl.Ramping || !ok || v != -20 {
+		t.Fatalf("set below the zone: ramping=%v desired=%v", rr.State.VolumeCtrl.Ramping, v)
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// State dump for bug reports (GET /api/v1/debug/state, ctl dump-state)
+// ============================================================================
+// One JSON document to attach to an issue: the build, a checksum of the config
+// file (to tell configs apart without sharing them), every zone loop's full
+// DaemonState, the recent events the loops received (the crash dump ring) and
+// the last debugLogRingSize warnings and errors logged. Anything that looks like
+// a secret (token, password, API key... fields and URL parameters) is replaced
+// by "[redacted]" throughout.
+// ============================================================================
+
+const (
+	debugLogRingSize     = 50
+	debugStateTimeout    = 2 * time.Second
+	debugDumpHTTPTimeout = 10 * time.Second
+)
+
+// debugState is the GET /api/v1/debug/state response.
+type debugState struct {
+	Time         time.Time                  `json:"time"`
+	Version      VersionInfo                `json:"version"`
+	OS           string                     `json:"os"`
+	Arch         string                     `json:"arch"`
+	UptimeSec    float64                    `json:"uptime_sec"`
+	ConfigPath   string                     `json:"config_path"`
+	ConfigSHA256 string                     `json:"config_sha256,omitempty"`
+	States       map[string]json.RawMessage `json:"states"`
+	Events       []crashEvent               `json:"events"`
+	Errors       []logEntry                 `json:"errors"`
+}
+
+// debugStateHandler serves GET /api/v1/debug/state.
+type debugStateHandler struct {
+	crash      *crashReporter
+	logs       *logRing
+	configPath string
+	configSum  string
+	started    time.Time
+}
+
+func (h *debugStateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeEventWebhookResponse(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	now := time.Now()
+	d := debugState{
+		Time:         now.UTC(),
+		Version:      buildVersionInfo(),
+		OS:           runtime.GOOS,
+		Arch:         runtime.GOARCH,
+		UptimeSec:    now.Sub(h.started).Round(time.Second).Seconds(),
+		ConfigPath:   h.configPath,
+		ConfigSHA256: h.configSum,
+		States:       h.crash.zoneStates(nil, debugStateTimeout),
+		Events:       h.crash.recentEvents(),
+		Errors:       h.logs.recent(),
+	}
+	for zone, s := range d.States {
+		d.States[zone] = redactJSON(s)
+	}
+	for i := range d.Events {
+		d.Events[i].Event = redactSecrets(d.Events[i].Event)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(d)
+}
+
+// fileSHA256 returns the hex SHA-256 of the file at path ("" if unreadable).
+func fileSHA256(path string) string {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// ----------------------------------------------------------------------------
+// Redaction
+// ----------------------------------------------------------------------------
+
+const redacted = "[redacted]"
+
+// secretKeyRe matches field and parameter names that hold secrets.
+var secretKeyRe = regexp.MustCompile(`(?i)token|secret|password|passwd|api_?key|session_?key`)
+
+// secretValueRe matches "name=value" / "name: value" pairs with such a name,
+// e.g. URL parameters in error messages or %+v-formatted events.
+var secretValueRe = regexp.MustCompile(`(?i)([\w-]*(?:token|secret|password|passwd|api_?key|session_?key)[\w-]*"?\s*[:=]\s*"?)([^\s&"},\]]+)`)
+
+// redactSecrets replaces the values of secret-looking pairs in s.
+func redactSecrets(s string) string {
+	return secretValueRe.ReplaceAllString(s, "${1}"+redacted)
+}
+
+// redactJSON blanks secret-looking fields and pairs in a JSON document. Input
+// that doesn't decode is returned as is.
+func redactJSON(raw json.RawMessage) json.RawMessage {
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return raw
+	}
+	b, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return raw
+	}
+	return b
+}
+
+func redactValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, x := range v {
+			if s, ok := x.(string); ok && s != "" && secretKeyRe.MatchString(k) {
+				v[k] = redacted
+				continue
+			}
+			v[k] = redactValue(x)
+		}
+	case []any:
+		for i, x := range v {
+			v[i] = redactValue(x)
+		}
+	case string:
+		return redactSecrets(v)
+	}
+	return v
+}
+
+// ----------------------------------------------------------------------------
+// Recent warnings and errors
+// ----------------------------------------------------------------------------
+
+// logEntry is one warning or error kept for the dump.
+type logEntry struct {
+	Time    time.Time         `json:"time"`
+	Level   string            `json:"level"`
+	Message string            `json:"message"`
+	Attrs   map[string]string `json:"attrs,omitempty"`
+}
+
+// logRing keeps the last debugLogRingSize entries.
+type logRing struct {
+	mu      sync.Mutex
+	entries [debugLogRingSize]logEntry
+	next    int
+	filled  bool
+}
+
+func (r *logRing) add(e logEntry) {
+	r.mu.Lock()
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % debugLogRingSize
+	if r.next == 0 {
+		r.filled = true
+	}
+	r.mu.Unlock()
+}
+
+// recent returns the entries, oldest first.
+func (r *logRing) recent() []logEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entries := []logEntry{}
+	if r.filled {
+		entries = append(entries, r.entries[r.next:]...)
+	}
+	return append(entries, r.entries[:r.next]...)
+}
+
+// logRingHandler passes records on to next and keeps warnings and errors in
+// ring, whatever the configured log level.
+type logRingHandler struct {
+	next   slog.Handler
+	ring   *logRing
+	attrs  []slog.Attr // from WithAttrs, keys prefixed with their group
+	prefix string      // current group prefix ("group.")
+}
+
+// withLogRing returns logger with its warnings and errors also kept in ring.
+func withLogRing(logger *slog.Logger, ring *logRing) *slog.Logger {
+	return slog.New(&logRingHandler{next: logger.Handler(), ring: ring})
+}
+
+func (h *logRingHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return l >= slog.LevelWarn || h.next.Enabled(ctx, l)
+}
+
+func (h *logRingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelWarn {
+		e := logEntry{Time: r.Time.UTC(), Level: r.Level.String(), Message: redactSecrets(r.Message)}
+		add := func(key string, v slog.Value) {
+			if e.Attrs == nil {
+				e.Attrs = make(map[string]string)
+			}
+			val := v.Resolve().String()
+			if secretKeyRe.MatchString(key) {
+				val = redacted
+			}
+			e.Attrs[key] = redactSecrets(val)
+		}
+		for _, a := range h.attrs {
+			add(a.Key, a.Value)
+		}
+		r.Attrs(func(a slog.Attr) bool {
+			add(h.prefix+a.Key, a.Value)
+			return true
+		})
+		h.ring.add(e)
+	}
+	if !h.next.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *logRingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.next = h.next.WithAttrs(attrs)
+	c.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, a := range attrs {
+		c.attrs = append(c.attrs, slog.Attr{Key: h.prefix + a.Key, Value: a.Value})
+	}
+	return &c
+}
+
+func (h *logRingHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	c.next = h.next.WithGroup(name)
+	c.prefix = h.prefix + name + "."
+	return &c
+}
+
+// ----------------------------------------------------------------------------
+// ctl dump-state
+// ----------------------------------------------------------------------------
+
+// ctlDumpState writes the daemon's state dump from baseURL to w.
+func ctlDumpState(baseURL, token string, w io.Writer) error {
+	req, err := http.NewRequest(http.MethodGet, baseURL+"/api/v1/debug/state", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := newAPIClient(token, debugDumpHTTPTimeout).Do(req)
+	if err != nil {
+		return fmt.Errorf("fetch state dump (is the daemon running?): %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return tuneHTTPError(resp)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedactSecrets(t *testing.T) {
+	cases := map[string]string{
+		"GET http://plex:32400/status/sessions?X-Plex-Token=abc123&foo=1": "GET http://plex:32400/status/sessions?X-Plex-Token=[redacted]&foo=1",
+		"{Token:s3cret MinDB:-90 MaxDB:0}":                                "{Token:[redacted] MinDB:-90 MaxDB:0}",
+		`{"api_key": "k", "volume_db": -20}`:                              `{"api_key": "[redacted]", "volume_db": -20}`,
+		"volume changed from -20 to -18":                                  "volume changed from -20 to -18",
+	}
+	for in, want := range cases {
+		if got := redactSecrets(in); got != want {
+			t.Errorf("redactSecrets(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRedactJSON(t *testing.T) {
+	in := json.RawMessage(`{"Zone":"main","Auth":{"SessionKey":"sk","Empty":""},"Err":"dial ws://x?password=hunter2","Levels":[1,2]}`)
+	var got map[string]any
+	if err := json.Unmarshal(redactJSON(in), &got); err != nil {
+		t.Fatal(err)
+	}
+	auth := got["Auth"].(map[string]any)
+	if auth["SessionKey"] != redacted || auth["Empty"] != "" || got["Zone"] != "main" || got["Err"] != "dial ws://x?password=[redacted]" {
+		t.Fatalf("redacted = %v", got)
+	}
+	if raw := json.RawMessage(`not json`); string(redactJSON(raw)) != "not json" {
+		t.Fatal("invalid JSON was changed")
+	}
+}
+
+func TestLogRingHandler(t *testing.T) {
+	var out bytes.Buffer
+	ring := &logRing{}
+	base := slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelError}))
+	logger := withLogRing(base, ring).With("zone", "main").WithGroup("plex")
+
+	logger.Info("ignored")
+	logger.Warn("poll failed", "error", `Get "http://plex/status?X-Plex-Token=abc": timeout`, "token", "abc")
+	if out.Len() != 0 {
+		t.Fatalf("warning passed to an error-level handler: %s", out.String())
+	}
+	logger.Error("gave up")
+	if !strings.Contains(out.String(), "gave up") {
+		t.Fatalf("error not passed on: %s", out.String())
+	}
+
+	got := ring.recent()
+	if len(got) != 2 || got[0].Message != "poll failed" || got[1].Level != "ERROR" {
+		t.Fatalf("entries = %+v", got)
+	}
+	want := map[string]string{"zone": "main", "plex.error": `Get "http://plex/status?X-Plex-Token=[redacted]": timeout`, "plex.token": redacted}
+	for k, v := range want {
+		if got[0].Attrs[k] != v {
+			t.Errorf("attr %s = %q, want %q", k, got[0].Attrs[k], v)
+		}
+	}
+
+	for range debugLogRingSize + 3 {
+		logger.Warn("again")
+	}
+	if n := len(ring.recent()); n != debugLogRingSize {
+		t.Fatalf("kept %d entries", n)
+	}
+}
+
+func TestDebugStateHandler(t *testing.T) {
+	c := newCrashReporter(t.TempDir(), func() {}, slog.New(slog.DiscardHandler))
+	req := c.stateRequests("main")
+	go func() {
+		reply := <-req
+		reply <- crashStateJSON(&DaemonState{Zone: "main"})
+	}()
+	c.recordEvent("main", LimitOverride{Token: "s3cret"}, time.Unix(1, 0))
+	ring := &logRing{}
+	withLogRing(slog.New(slog.DiscardHandler), ring).Error("camilladsp unreachable")
+
+	h := &debugStateHandler{crash: c, logs: ring, configPath: "/etc/sb.yaml", configSum: "abc", started: time.Now().Add(-time.Minute)}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/debug/state", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d", rec.Code)
+	}
+	body := rec.Body.String()
+	if strings.Contains(body, "s3cret") {
+		t.Fatalf("secret in dump: %s", body)
+	}
+	var d debugState
+	if err := json.Unmarshal(rec.Body.Bytes(), &d); err != nil {
+		t.Fatal(err)
+	}
+	var st DaemonState
+	if err := json.Unmarshal(d.States["main"], &st); err != nil || st.Zone != "main" {
+		t.Fatalf("state = %s (%v)", d.States["main"], err)
+	}
+	if len(d.Events) != 1 || len(d.Errors) != 1 || d.ConfigSHA256 != "abc" || d.UptimeSec != 60 || d.Version.Version != version {
+		t.Fatalf("dump = %+v", d)
+	}
+}
+
+func TestDebugStateRequiresToken(t *testing.T) {
+	c := newCrashReporter(t.TempDir(), func() {}, slog.New(slog.DiscardHandler))
+	auth := apiAuth{token: "tok", logger: slog.New(slog.DiscardHandler)}
+	h := auth.require(&debugStateHandler{crash: c, logs: &logRing{}, started: time.Now()})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/debug/state", nil)
+	req.RemoteAddr = "192.0.2.10:40000"
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", rec.Code)
+	}
+}
+
+func TestCtlDumpState(t *testing.T) {
+	auth := apiAuth{token: "tok", logger: slog.New(slog.DiscardHandler)}
+	srv := httptest.NewServer(auth.require(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/debug/state" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"states":{}}`))
+	})))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	if err := ctlDumpState(srv.URL, "", &out); err == nil {
+		t.Fatal("dump-state without the token succeeded")
+	}
+	if err := ctlDumpState(srv.URL, "tok", &out); err != nil || out.String() != `{"states":{}}` {
+		t.Fatalf("out %q, err %v", out.String(), err)
+	}
+	if err := ctlDumpState(srv.URL+"/prefix", "tok", &out); err == nil {
+		t.Fatal("expected an error for a 404")
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ============================================================================
+// doctor subcommand
+// ============================================================================
+// `streamerbrainz doctor` runs the checks behind most setup problems and prints
+// one PASS/WARN/FAIL line per check, with a hint on how to fix anything that
+// isn't a pass:
+//
+//   - the config file parses and validates
+//   - every input device can be opened (permissions) and isn't grabbed by
+//     another process; fifo paths can be created
+//   - every zone's CamillaDSP answers, and which version it runs
+//   - the IPC socket is served by a running daemon or can be created
+//   - the webhooks/API/OSC ports are free (or held by the running daemon)
+//   - the Plex server accepts the token (if plex is enabled)
+//
+// It exits 1 if any check fails.
+// ============================================================================
+
+// doctorTimeout bounds each network check.
+const doctorTimeout = 3 * time.Second
+
+const (
+	doctorPass = "PASS"
+	doctorWarn = "WARN"
+	doctorFail = "FAIL"
+)
+
+// doctorResult is the outcome of one check.
+type doctorResult struct {
+	Check  string
+	Status string
+	Detail string
+	Hint   string // how to fix it (WARN/FAIL)
+}
+
+func printDoctorUsage() {
+	fmt.Println("USAGE:")
+	fmt.Println("  streamerbrainz doctor [-config path]")
+	fmt.Println()
+	fmt.Println("Checks the config, input devices, CamillaDSP, the IPC socket, listener ports")
+	fmt.Println("and Plex credentials, and prints what to fix. Exits 1 if any check fails.")
+	fmt.Println()
+}
+
+// runDoctorSubcommand handles `streamerbrainz doctor`.
+func runDoctorSubcommand(args []string) {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to YAML config file")
+	fs.Usage = printDoctorUsage
+	fs.Parse(args)
+
+	if !runDoctor(context.Background(), ResolveConfigPath(*configPath), os.Stdout) {
+		os.Exit(1)
+	}
+}
+
+// runDoctor runs every check against the config at path, printing results to
+// out. It returns false if any check failed.
+func runDoctor(ctx context.Context, path string, out io.Writer) bool {
+	cfg, results := doctorConfig(path)
+	if cfg != nil {
+		results = append(results, doctorInputs(*cfg)...)
+		results = append(results, doctorCamillaDSP(ctx, *cfg)...)
+		ipc, daemonRunning := doctorIPC(*cfg)
+		results = append(results, ipc)
+		results = append(results, doctorPorts(*cfg, daemonRunning)...)
+		results = append(results, doctorPlex(ctx, *cfg)...)
+	}
+
+	ok := true
+	for _, r := range results {
+		fmt.Fprintf(out, "[%s] %s: %s\n", r.Status, r.Check, r.Detail)
+		if r.Hint != "" {
+			fmt.Fprintf(out, "       -> %s\n", r.Hint)
+		}
+		ok = ok && r.Status != doctorFail
+	}
+	return ok
+}
+
+// doctorConfig loads and validates the config. The config is nil if the other
+// checks can't run.
+func doctorConfig(path string) (*Config, []doctorResult) {
+	cfg, err := LoadConfigFile(path)
+	if err != nil {
+		return nil, []doctorResult{{
+			Check: "config", Status: doctorFail, Detail: err.Error(),
+			Hint: "create one with `streamerbrainz -print-default-config > " + path + "`, or pass -config",
+		}}
+	}
+	cfg.expandPaths()
+	if err := cfg.Validate(); err != nil {
+		return nil, []doctorResult{{
+			Check: "config", Status: doctorFail, Detail: err.Error(),
+			Hint: "fix the setting above; `streamerbrainz config schema` documents every field",
+		}}
+	}
+	return &cfg, []doctorResult{{Check: "config", Status: doctorPass, Detail: ExpandPath(path) + " is valid"}}
+}
+
+// doctorInputs checks that every configured input can be read.
+func doctorInputs(cfg Config) []doctorResult {
+	var results []doctorResult
+	for _, in := range cfg.Inputs {
+		check := "input " + in.Path
+		switch in.Type {
+		case InputDeviceTypeHotkey:
+			results = append(results, doctorResult{Check: "input hotkey", Status: doctorPass, Detail: "global media keys"})
+
+		case InputDeviceTypeFifo:
+			results = append(results, doctorFifo(check, in.Path))
+
+		default:
+			if !evdevSupported {
+				results = append(results, doctorResult{Check: check, Status: doctorWarn,
+					Detail: string(in.Type) + " inputs are only read on Linux", Hint: "use a hotkey or fifo input on " + runtime.GOOS})
+				continue
+			}
+			name, grabbed, err := probeInputDevice(in.Path)
+			switch {
+			case errors.Is(err, fs.ErrNotExist):
+				results = append(results, doctorResult{Check: check, Status: doctorFail, Detail: "no such device",
+					Hint: "list devices with `ls -l /dev/input/by-id/` and prefer those stable paths"})
+			case errors.Is(err, fs.ErrPermission):
+				results = append(results, doctorResult{Check: check, Status: doctorFail, Detail: "permission denied",
+					Hint: "add the user to the input group (`sudo usermod -aG input $USER`, then log in again) or add a udev rule"})
+			case err != nil:
+				results = append(results, doctorResult{Check: check, Status: doctorFail, Detail: err.Error()})
+			case grabbed:
+				results = append(results, doctorResult{Check: check, Status: doctorWarn, Detail: fmt.Sprintf("%q is grabbed by another process", name),
+					Hint: "its events won't reach streamerbrainz; stop the other reader (e.g. triggerhappy, another streamerbrainz)"})
+			default:
+				results = append(results, doctorResult{Check: check, Status: doctorPass, Detail: fmt.Sprintf("%q readable (%s)", name, in.Type)})
+			}
+		}
+	}
+	return results
+}
+
+// doctorFifo checks a fifo input: an existing named pipe, or a path the daemon can create.
+func doctorFifo(check, path string) doctorResult {
+	if path == "-" {
+		return doctorResult{Check: "input stdin", Status: doctorPass, Detail: "reads events from stdin"}
+	}
+	st, err := os.Stat(path)
+	switch {
+	case err == nil && st.Mode()&fs.ModeNamedPipe != 0:
+		return doctorResult{Check: check, Status: doctorPass, Detail: "named pipe exists"}
+	case err == nil:
+		return doctorResult{Check: check, Status: doctorFail, Detail: "exists but is not a named pipe", Hint: "remove it; the daemon creates the pipe"}
+	case !errors.Is(err, fs.ErrNotExist):
+		return doctorResult{Check: check, Status: doctorFail, Detail: err.Error()}
+	}
+	if err := dirWritable(filepath.Dir(path)); err != nil {
+		return doctorResult{Check: check, Status: doctorFail, Detail: "can't create the pipe: " + err.Error(),
+			Hint: "create the directory or pick a writable path"}
+	}
+	return doctorResult{Check: check, Status: doctorPass, Detail: "named pipe will be created"}
+}
+
+// dirWritable reports whether files can be created in dir.
+func dirWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".streamerbrainz-doctor-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// doctorCamillaDSP connects to every zone's CamillaDSP once and asks for its version.
+func doctorCamillaDSP(ctx context.Context, cfg Config) []doctorResult {
+	var results []doctorResult
+	for _, zt := range cfg.ZoneTargets() {
+		check := "camilladsp " + zt.CamillaDSP.WsURL
+		if len(cfg.Zones) > 0 {
+			check = "camilladsp (zone " + zt.ID + ")"
+		}
+		dial, err := zt.CamillaDSP.dialOptions()
+		if err != nil {
+			results = append(results, doctorResult{Check: check, Status: doctorFail, Detail: err.Error()})
+			continue
+		}
+		client := &CamillaDSPClient{url: zt.CamillaDSP.WsURL, dial: dial, logger: slog.New(slog.DiscardHandler), readTimeout: doctorTimeout}
+		if err := client.connect(ctx); err != nil {
+			results = append(results, doctorResult{Check: check, Status: doctorFail, Detail: "not reachable: " + err.Error(),
+				Hint: "is CamillaDSP running with its websocket server enabled (`camilladsp -p <port>`) at " + zt.CamillaDSP.WsURL + "?"})
+			continue
+		}
+		resp, err := client.Call(ctx, "GetVersion")
+		client.Close()
+		var version string
+		if err == nil {
+			var v []byte
+			if v, err = dspReplyValue("GetVersion", resp); err == nil {
+				version = strings.Trim(string(v), `"`)
+			}
+		}
+		if err != nil {
+			results = append(results, doctorResult{Check: check, Status: doctorWarn, Detail: "connected, but GetVersion failed: " + err.Error(),
+				Hint: "is this a CamillaDSP websocket port?"})
+			continue
+		}
+		if v, ok := parseCamillaVersion(version); ok && !v.supports(camillaFaders) {
+			results = append(results, doctorResult{Check: check, Status: doctorWarn, Detail: "CamillaDSP " + version,
+				Hint: "aux faders (balance/sub encoder modes) and signal meters need CamillaDSP 2.x"})
+			continue
+		}
+		results = append(results, doctorResult{Check: check, Status: doctorPass, Detail: "CamillaDSP " + version})
+	}
+	return results
+}
+
+// doctorIPC checks the IPC socket and reports whether a daemon answers on it.
+func doctorIPC(cfg Config) (doctorResult, bool) {
+	check := "ipc " + cfg.IPC.SocketPath
+	if conn, err := dialIPC(cfg.IPC.SocketPath); err == nil {
+		conn.Close()
+		return doctorResult{Check: check, Status: doctorPass, Detail: "a running daemon is listening"}, true
+	}
+	if runtime.GOOS == "windows" {
+		return doctorResult{Check: check, Status: doctorPass, Detail: "daemon not running (named pipe)"}, false
+	}
+	if err := dirWritable(filepath.Dir(cfg.IPC.SocketPath)); err != nil {
+		return doctorResult{Check: check, Status: doctorFail, Detail: "can't create the socket: " + err.Error(),
+			Hint: "create the directory, or set ipc.socket_path to a writable location (e.g. /run/user/<uid>/streamerbrainz.sock)"}, false
+	}
+	return doctorResult{Check: check, Status: doctorPass, Detail: "daemon not running; socket can be created"}, false
+}
+
+// doctorPorts checks that the configured listeners can bind their ports. With a
+// daemon running, a port in use is most likely its own.
+func doctorPorts(cfg Config, daemonRunning bool) []doctorResult {
+	var results []doctorResult
+	ports := listenPorts(cfg)
+	for i, l := range ports {
+		check := "port " + l.name + " " + l.network + " " + l.addr
+		if j := slices.IndexFunc(ports[:i], l.overlaps); j >= 0 {
+			results = append(results, doctorResult{Check: check, Status: doctorFail, Detail: "same port as " + ports[j].setting,
+				Hint: "change " + l.setting + " or " + ports[j].setting})
+			continue
+		}
+		err := tryListen(l.network, l.addr)
+		switch {
+		case err == nil:
+			results = append(results, doctorResult{Check: check, Status: doctorPass, Detail: "free"})
+		case errors.Is(err, syscall.EADDRINUSE) && daemonRunning:
+			results = append(results, doctorResult{Check: check, Status: doctorPass, Detail: "in use (by the running daemon)"})
+		case errors.Is(err, syscall.EADDRINUSE):
+			_, port, _ := net.SplitHostPort(l.addr)
+			results = append(results, doctorResult{Check: check, Status: doctorFail, Detail: "in use by another program",
+				Hint: "stop it (`ss -lnp | grep :" + port + "` shows which) or change " + l.setting})
+		default:
+			results = append(results, doctorResult{Check: check, Status: doctorFail, Detail: err.Error(),
+				Hint: "check the bind address and, for ports below 1024, privileges"})
+		}
+	}
+	return results
+}
+
+// tryListen binds addr briefly.
+func tryListen(network, addr string) error {
+	if network == "udp" {
+		c, err := net.ListenPacket(network, addr)
+		if err == nil {
+			c.Close()
+		}
+		return err
+	}
+	l, err := net.Listen(network, addr)
+	if err == nil {
+		l.Close()
+	}
+	return err
+}
+
+// doctorPlex checks the Plex token against the server (if plex is enabled).
+func doctorPlex(ctx context.Context, cfg Config) []doctorResult {
+	if !cfg.Plex.Enabled {
+		return nil
+	}
+	const check = "plex"
+	token, err := readSecret(cfg.Plex.TokenFile)
+	if err != nil {
+		return []doctorResult{{Check: check, Status: doctorFail, Detail: "can't read token: " + err.Error(),
+			Hint: "run `streamerbrainz plex-login`"}}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+	plexCfg := PlexampConfig{ServerUrl: cfg.Plex.ServerURL, Token: token, Client: newPlexHTTPClient(cfg.Plex)}
+	var sessions plexSessionsContainer
+	err = fetchPlexXML(ctx, plexCfg, "/status/sessions", &sessions, slog.New(slog.DiscardHandler))
+	var statusErr *plexStatusError
+	switch {
+	case errors.As(err, &statusErr) && (statusErr.Status == http.StatusUnauthorized || statusErr.Status == http.StatusForbidden):
+		return []doctorResult{{Check: check, Status: doctorFail, Detail: "server rejected the token (HTTP " + strconv.Itoa(statusErr.Status) + ")",
+			Hint: "run `streamerbrainz plex-login` again"}}
+	case err != nil:
+		return []doctorResult{{Check: check, Status: doctorFail, Detail: "server not reachable: " + err.Error(),
+			Hint: "check plex.server_url (" + cfg.Plex.ServerURL + ")"}}
+	case cfg.Plex.MachineID == "":
+		return []doctorResult{{Check: check, Status: doctorWarn, Detail: "token accepted, but plex.machine_id is empty",
+			Hint: "run `streamerbrainz plex-discover` to find your player's id"}}
+	}
+	return []doctorResult{{Check: check, Status: doctorPass, Detail: "token accepted by " + cfg.Plex.ServerURL}}
+}
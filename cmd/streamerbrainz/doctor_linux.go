@@ -0,0 +1,39 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// probeInputDevice opens an evdev device and reports its name and whether
+// another process holds an exclusive grab on it (EVIOCGRAB fails with EBUSY).
+// A successful test grab is released immediately.
+func probeInputDevice(path string) (name string, grabbed bool, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false, err
+	}
+	defer f.Close()
+	fd := int(f.Fd())
+
+	name, err = evdevName(fd)
+	if err != nil {
+		if errors.Is(err, unix.ENOTTY) || errors.Is(err, unix.EINVAL) {
+			return "", false, errors.New("not an input event device")
+		}
+		return "", false, err
+	}
+
+	if err := unix.IoctlSetInt(fd, evdevIOCGrab, 1); err != nil {
+		if errors.Is(err, unix.EBUSY) {
+			return name, true, nil
+		}
+		return name, false, err
+	}
+	_ = unix.IoctlSetInt(fd, evdevIOCGrab, 0)
+	return name, false, nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// probeInputDevice is unavailable outside Linux (evdev).
+func probeInputDevice(path string) (name string, grabbed bool, err error) {
+	return "", false, errors.New("evdev input devices require linux")
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRunDoctor_InvalidConfigFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("webhooks:\n  port: 70000\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if runDoctor(context.Background(), path, &out) {
+		t.Fatal("doctor passed an invalid config")
+	}
+	if !strings.HasPrefix(out.String(), "[FAIL] config: ") || strings.Count(out.String(), "\n[") != 0 {
+		t.Fatalf("output:\n%s", out.String())
+	}
+}
+
+func TestDoctorInputs(t *testing.T) {
+	dir := t.TempDir()
+	notPipe := filepath.Join(dir, "events")
+	if err := os.WriteFile(notPipe, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	results := doctorInputs(Config{Inputs: []InputDevice{
+		{Type: InputDeviceTypeFifo, Path: filepath.Join(dir, "new.fifo")},
+		{Type: InputDeviceTypeFifo, Path: notPipe},
+		{Type: InputDeviceTypeKey, Path: filepath.Join(dir, "no-such-event0")},
+	}})
+	want := []string{doctorPass, doctorFail}
+	if evdevSupported {
+		want = append(want, doctorFail)
+	} else {
+		want = append(want, doctorWarn)
+	}
+	for i, r := range results {
+		if r.Status != want[i] {
+			t.Errorf("%s: %s %s, want %s", r.Check, r.Status, r.Detail, want[i])
+		}
+	}
+	if evdevSupported && results[2].Hint == "" {
+		t.Error("missing device has no hint")
+	}
+}
+
+func TestDoctorCamillaDSP(t *testing.T) {
+	dsp := newFakeCamillaDSP(t)
+	cfg := DefaultConfig()
+	cfg.CamillaDSP.WsURL = dsp.url()
+	results := doctorCamillaDSP(t.Context(), cfg)
+	if len(results) != 1 || results[0].Status != doctorPass || results[0].Detail != "CamillaDSP 2.0.3" {
+		t.Fatalf("results = %+v", results)
+	}
+
+	dsp.srv.Close()
+	if results := doctorCamillaDSP(t.Context(), cfg); results[0].Status != doctorFail || results[0].Hint == "" {
+		t.Fatalf("unreachable: %+v", results)
+	}
+}
+
+func TestDoctorPorts_Collision(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+
+	cfg := DefaultConfig()
+	cfg.Webhooks.BindAddress = "127.0.0.1"
+	cfg.Webhooks.Port = port
+	if r := doctorPorts(cfg, false); r[0].Status != doctorFail || !strings.Contains(r[0].Hint, ":"+strconv.Itoa(port)) {
+		t.Fatalf("collision: %+v", r)
+	}
+	// With the daemon running, the port is presumably its own.
+	if r := doctorPorts(cfg, true); r[0].Status != doctorPass {
+		t.Fatalf("daemon running: %+v", r)
+	}
+}
+
+func TestDoctorPlex(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("X-Plex-Token") != "good" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`<MediaContainer size="0"></MediaContainer>`))
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.Plex = PlexConfig{Enabled: true, ServerURL: srv.URL, TokenFile: filepath.Join(t.TempDir(), "token"), MachineID: "abc"}
+	for token, want := range map[string]string{"good": doctorPass, "stale": doctorFail} {
+		if err := os.WriteFile(cfg.Plex.TokenFile, []byte(token), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		r := doctorPlex(context.Background(), cfg)
+		if len(r) != 1 || r[0].Status != want {
+			t.Fatalf("token %s: %+v", token, r)
+		}
+		if want == doctorFail && !strings.Contains(r[0].Detail, "rejected") {
+			t.Fatalf("token %s: %+v", token, r)
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ============================================================================
+// dsp subcommand
+// ============================================================================
+// `streamerbrainz dsp` talks to CamillaDSP directly, for debugging what the
+// daemon sees:
+//
+//	streamerbrainz dsp watch [volume,mute,state,levels]
+//	streamerbrainz dsp cmd <Command> [arg...]
+//
+// It uses the daemon's CamillaDSP client (reconnects, timeouts, wss:// and
+// auth from the config), so it connects the same way the daemon does.
+// CamillaDSP doesn't push changes; watch polls and prints what changed.
+// ============================================================================
+
+const defaultDSPWatchInterval = 500 * time.Millisecond
+
+// dspWatchItem is a watchable value and the CamillaDSP getter that reads it.
+type dspWatchItem struct{ name, command string }
+
+// dspWatchItems lists the watchable items in output order.
+var dspWatchItems = []dspWatchItem{
+	{"volume", "GetVolume"},
+	{"mute", "GetMute"},
+	{"state", "GetState"},
+	{"levels", "GetSignalLevels"},
+}
+
+// dspCaller sends one CamillaDSP request and returns the raw response.
+type dspCaller interface {
+	Call(ctx context.Context, request any) ([]byte, error)
+}
+
+func printDSPUsage() {
+	fmt.Println("USAGE:")
+	fmt.Println("  streamerbrainz dsp [OPTIONS] watch [volume,mute,state,levels]")
+	fmt.Println("  streamerbrainz dsp [OPTIONS] cmd <Command> [arg...]")
+	fmt.Println()
+	fmt.Println("COMMANDS:")
+	fmt.Println("  watch   Poll CamillaDSP and print changes of the listed items")
+	fmt.Println("          (default: volume,mute,state; levels are printed every poll)")
+	fmt.Println("  cmd     Send one command and print its value, e.g. `cmd GetVolume`,")
+	fmt.Println("          `cmd SetVolume -20`, `cmd SetFaderVolume 1 -10`. An argument that")
+	fmt.Println("          isn't JSON is sent as a string; several are sent as an array.")
+	fmt.Println()
+	fmt.Println("OPTIONS:")
+	fmt.Println("  -config string     Path to YAML config file (CamillaDSP URL, TLS, auth)")
+	fmt.Println("  -zone string       Zone whose CamillaDSP to use (default: the first)")
+	fmt.Println("  -url string        CamillaDSP WebSocket URL (overrides the config)")
+	fmt.Println("  -interval duration Poll interval for watch (default 500ms)")
+	fmt.Println("  -json              Print JSON lines instead of text")
+	fmt.Println()
+}
+
+// runDSPSubcommand handles `streamerbrainz dsp`.
+func runDSPSubcommand(args []string) {
+	fs := flag.NewFlagSet("dsp", flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to YAML config file")
+	zone := fs.String("zone", "", "Zone whose CamillaDSP to use")
+	wsURL := fs.String("url", "", "CamillaDSP WebSocket URL")
+	interval := fs.Duration("interval", defaultDSPWatchInterval, "Poll interval for watch")
+	jsonOut := fs.Bool("json", false, "Print JSON lines")
+	fs.Usage = printDSPUsage
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		printDSPUsage()
+		os.Exit(2)
+	}
+
+	var run func(ctx context.Context, client dspCaller) error
+	switch sub := fs.Arg(0); sub {
+	case "watch":
+		if fs.NArg() > 2 {
+			printDSPUsage()
+			os.Exit(2)
+		}
+		items, err := parseDSPWatchItems(fs.Arg(1))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(2)
+		}
+		if *interval <= 0 {
+			fmt.Fprintln(os.Stderr, "error: -interval must be > 0")
+			os.Exit(2)
+		}
+		run = func(ctx context.Context, client dspCaller) error {
+			return runDSPWatch(ctx, client, items, *interval, *jsonOut, os.Stdout, os.Stderr)
+		}
+	case "cmd":
+		if fs.NArg() < 2 {
+			printDSPUsage()
+			os.Exit(2)
+		}
+		req := dspRequest(fs.Arg(1), fs.Args()[2:])
+		run = func(ctx context.Context, client dspCaller) error {
+			return runDSPCommand(ctx, client, fs.Arg(1), req, *jsonOut, os.Stdout)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "error: unknown dsp command %q\n", sub)
+		os.Exit(2)
+	}
+
+	target, err := dspTarget(*configPath, *zone, *wsURL)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	dial, err := target.dialOptions()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	client, err := NewCamillaDSPClientWithOptions(target.WsURL, dial, logger, target.TimeoutMS)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	defer client.Close()
+	client.timeouts = target.commandTimeouts()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := run(ctx, client); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		client.Close()
+		os.Exit(1)
+	}
+}
+
+// dspTarget resolves the CamillaDSP to talk to: -url if given (TLS/auth still
+// come from the config when one is found), else the zone's camilladsp block.
+func dspTarget(configPath, zone, wsURL string) (CamillaDSPConfig, error) {
+	cfg, err := LoadConfigFile(ResolveConfigPath(configPath))
+	if err != nil {
+		if wsURL == "" || configPath != "" {
+			return CamillaDSPConfig{}, err
+		}
+		// A bare -url needs no config.
+		cfg = DefaultConfig()
+	}
+	targets := cfg.ZoneTargets()
+	target := targets[0].CamillaDSP
+	if zone != "" {
+		i := slices.IndexFunc(targets, func(zt ZoneTarget) bool { return zt.ID == zone })
+		if i < 0 {
+			return CamillaDSPConfig{}, fmt.Errorf("unknown zone %q", zone)
+		}
+		target = targets[i].CamillaDSP
+	}
+	if wsURL != "" {
+		target.WsURL = wsURL
+	}
+	return target, nil
+}
+
+// parseDSPWatchItems parses a comma-separated item list (empty = volume,mute,state).
+func parseDSPWatchItems(list string) ([]string, error) {
+	if list == "" {
+		return []string{"volume", "mute", "state"}, nil
+	}
+	var items []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if !slices.ContainsFunc(dspWatchItems, func(w dspWatchItem) bool { return w.name == name }) {
+			return nil, fmt.Errorf("unknown watch item %q (volume, mute, state, levels)", name)
+		}
+		if !slices.Contains(items, name) {
+			items = append(items, name)
+		}
+	}
+	return items, nil
+}
+
+// dspRequest builds the CamillaDSP request for a command and its arguments:
+// "Name" without arguments, else {"Name": arg} where each argument is JSON if
+// it parses as such and a string otherwise (several become an array).
+func dspRequest(command string, args []string) any {
+	if len(args) == 0 {
+		return command
+	}
+	values := make([]any, len(args))
+	for i, arg := range args {
+		if json.Valid([]byte(arg)) {
+			values[i] = json.RawMessage(arg)
+		} else {
+			values[i] = arg
+		}
+	}
+	if len(values) == 1 {
+		return map[string]any{command: values[0]}
+	}
+	return map[string]any{command: values}
+}
+
+// dspReplyValue extracts the value of the reply to command, failing unless the
+// result is Ok.
+func dspReplyValue(command string, resp []byte) (json.RawMessage, error) {
+	r, err := parseCamillaReply[json.RawMessage](resp, command)
+	if err != nil {
+		return nil, err
+	}
+	if r.Result != "Ok" {
+		return nil, fmt.Errorf("%s: %s %s", command, r.Result, r.Value)
+	}
+	return r.Value, nil
+}
+
+// runDSPCommand sends one request and prints the reply's value (the whole
+// response with -json).
+func runDSPCommand(ctx context.Context, client dspCaller, command string, req any, jsonOut bool, out io.Writer) error {
+	resp, err := client.Call(ctx, req)
+	if err != nil {
+		return err
+	}
+	value, err := dspReplyValue(command, resp)
+	if err != nil {
+		return err
+	}
+	switch {
+	case jsonOut:
+		fmt.Fprintf(out, "%s\n", resp)
+	case len(value) > 0:
+		fmt.Fprintf(out, "%s\n", value)
+	default:
+		fmt.Fprintln(out, "Ok")
+	}
+	return nil
+}
+
+// dspWatchLine is one -json output line of watch.
+type dspWatchLine struct {
+	Ts    time.Time       `json:"ts"`
+	Item  string          `json:"item"`
+	Value json.RawMessage `json:"value,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
+
+// runDSPWatch polls items every interval until ctx is canceled, printing a line
+// per changed value (levels: every poll) and per new error.
+func runDSPWatch(ctx context.Context, client dspCaller, items []string, interval time.Duration, jsonOut bool, out, errOut io.Writer) error {
+	last := map[string]string{}
+	enc := json.NewEncoder(out)
+	poll := func(now time.Time) {
+		for _, w := range dspWatchItems {
+			if !slices.Contains(items, w.name) {
+				continue
+			}
+			line := dspWatchLine{Ts: now.UTC(), Item: w.name}
+			resp, err := client.Call(ctx, w.command)
+			if err == nil {
+				line.Value, err = dspReplyValue(w.command, resp)
+			}
+			key := string(line.Value)
+			if err != nil {
+				line.Error = err.Error()
+				key = "error: " + line.Error
+			}
+			if prev, seen := last[w.name]; seen && prev == key && (w.name != "levels" || err != nil) {
+				continue
+			}
+			last[w.name] = key
+
+			switch {
+			case jsonOut:
+				enc.Encode(line)
+			case err != nil:
+				fmt.Fprintf(errOut, "%s %s error: %s\n", line.Ts.Format(time.RFC3339), w.name, line.Error)
+			default:
+				fmt.Fprintf(out, "%s %s %s\n", line.Ts.Format(time.RFC3339), w.name, formatDSPWatchValue(w.name, line.Value))
+			}
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	poll(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			if ctx.Err() != nil {
+				return nil
+			}
+			poll(now)
+		}
+	}
+}
+
+// formatDSPWatchValue renders a watched value for text output.
+func formatDSPWatchValue(item string, value json.RawMessage) string {
+	switch item {
+	case "volume":
+		var db float64
+		if json.Unmarshal(value, &db) == nil {
+			return fmt.Sprintf("%.2f dB", db)
+		}
+	case "mute":
+		var muted bool
+		if json.Unmarshal(value, &muted) == nil {
+			if muted {
+				return "muted"
+			}
+			return "unmuted"
+		}
+	case "state":
+		var st string
+		if json.Unmarshal(value, &st) == nil {
+			return st
+		}
+	case "levels":
+		var l CamillaSignalLevels
+		if json.Unmarshal(value, &l) == nil {
+			return fmt.Sprintf("playback rms %v peak %v, capture rms %v peak %v",
+				formatDBList(l.PlaybackRMS), formatDBList(l.PlaybackPeak), formatDBList(l.CaptureRMS), formatDBList(l.CapturePeak))
+		}
+	}
+	return string(value)
+}
+
+// formatDBList renders per-channel levels with one decimal.
+func formatDBList(levels []float64) string {
+	parts := make([]string, len(levels))
+	for i, db := range levels {
+		parts[i] = fmt.Sprintf("%.1f", db)
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
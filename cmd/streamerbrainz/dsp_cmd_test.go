@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scriptedDSP answers each getter from a list of responses, one per poll (the
+// last one repeats), and cancels after `polls` GetVolume calls.
+type scriptedDSP struct {
+	replies map[string][]string
+	calls   map[string]int
+	polls   int
+	cancel  context.CancelFunc
+}
+
+func (s *scriptedDSP) Call(_ context.Context, request any) ([]byte, error) {
+	name, _ := request.(string)
+	n := s.calls[name]
+	s.calls[name]++
+	if name == "GetVolume" && s.calls[name] == s.polls {
+		s.cancel()
+	}
+	replies := s.replies[name]
+	reply := replies[min(n, len(replies)-1)]
+	if reply == "" {
+		return nil, errors.New("connection refused")
+	}
+	return []byte(reply), nil
+}
+
+func TestDSPRequest(t *testing.T) {
+	for _, tc := range []struct {
+		command string
+		args    []string
+		want    string
+	}{
+		{"GetVolume", nil, `"GetVolume"`},
+		{"SetVolume", []string{"-20"}, `{"SetVolume":-20}`},
+		{"SetConfigFilePath", []string{"/etc/camilladsp/phones.yml"}, `{"SetConfigFilePath":"/etc/camilladsp/phones.yml"}`},
+		{"SetFaderVolume", []string{"1", "-10.5"}, `{"SetFaderVolume":[1,-10.5]}`},
+	} {
+		got, err := json.Marshal(dspRequest(tc.command, tc.args))
+		if err != nil || string(got) != tc.want {
+			t.Errorf("dspRequest(%s, %v) = %s, %v; want %s", tc.command, tc.args, got, err, tc.want)
+		}
+	}
+}
+
+func TestParseDSPWatchItems(t *testing.T) {
+	if items, err := parseDSPWatchItems(""); err != nil || strings.Join(items, ",") != "volume,mute,state" {
+		t.Fatalf("default = %v, %v", items, err)
+	}
+	if items, err := parseDSPWatchItems("levels, volume,levels"); err != nil || strings.Join(items, ",") != "levels,volume" {
+		t.Fatalf("items = %v, %v", items, err)
+	}
+	if _, err := parseDSPWatchItems("volume,bass"); err == nil {
+		t.Fatal("unknown item accepted")
+	}
+}
+
+func TestRunDSPCommand(t *testing.T) {
+	dsp := &scriptedDSP{calls: map[string]int{}, replies: map[string][]string{
+		"GetVolume": {`{"GetVolume":{"result":"Ok","value":-20.5}}`},
+		"Reload":    {`{"Reload":{"result":"Error","value":"invalid config"}}`},
+	}}
+
+	var out bytes.Buffer
+	if err := runDSPCommand(t.Context(), dsp, "GetVolume", "GetVolume", false, &out); err != nil || out.String() != "-20.5\n" {
+		t.Fatalf("text = %q, %v", out.String(), err)
+	}
+	out.Reset()
+	if err := runDSPCommand(t.Context(), dsp, "GetVolume", "GetVolume", true, &out); err != nil || out.String() != dsp.replies["GetVolume"][0]+"\n" {
+		t.Fatalf("json = %q, %v", out.String(), err)
+	}
+	if err := runDSPCommand(t.Context(), dsp, "Reload", "Reload", false, &out); err == nil || !strings.Contains(err.Error(), "invalid config") {
+		t.Fatalf("error result: %v", err)
+	}
+}
+
+func TestRunDSPWatch_PrintsChangesOnly(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	levels := `{"GetSignalLevels":{"result":"Ok","value":{"playback_rms":[-30],"playback_peak":[-12],"capture_rms":[],"capture_peak":[]}}}`
+	dsp := &scriptedDSP{calls: map[string]int{}, polls: 4, cancel: cancel, replies: map[string][]string{
+		"GetVolume": {
+			`{"GetVolume":{"result":"Ok","value":-20}}`,
+			`{"GetVolume":{"result":"Ok","value":-20}}`,
+			`{"GetVolume":{"result":"Ok","value":-18}}`,
+		},
+		"GetMute":         {`{"GetMute":{"result":"Ok","value":false}}`, "", ""},
+		"GetSignalLevels": {levels},
+	}}
+
+	var out, errOut bytes.Buffer
+	if err := runDSPWatch(ctx, dsp, []string{"volume", "mute", "levels"}, time.Millisecond, false, &out, &errOut); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for line := range strings.Lines(out.String()) {
+		_, rest, _ := strings.Cut(strings.TrimSpace(line), " ") // drop the timestamp
+		got = append(got, rest)
+	}
+	want := []string{
+		"volume -20.00 dB", "mute unmuted", "levels playback rms [-30.0] peak [-12.0], capture rms [] peak []",
+		"levels playback rms [-30.0] peak [-12.0], capture rms [] peak []",
+		"volume -18.00 dB", "levels playback rms [-30.0] peak [-12.0], capture rms [] peak []",
+		"levels playback rms [-30.0] peak [-12.0], capture rms [] peak []",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("output:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	// The repeated mute failure is reported once.
+	if n := strings.Count(errOut.String(), "mute error: connection refused"); n != 1 {
+		t.Fatalf("errors:\n%s", errOut.String())
+	}
+}
+
+func TestRunDSPWatch_JSONLines(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dsp := &scriptedDSP{calls: map[string]int{}, polls: 2, cancel: cancel, replies: map[string][]string{
+		"GetVolume": {`{"GetVolume":{"result":"Ok","value":-20}}`, `{"GetVolume":{"result":"Ok","value":-19.5}}`},
+	}}
+
+	var out bytes.Buffer
+	if err := runDSPWatch(ctx, dsp, []string{"volume"}, time.Millisecond, true, &out, &out); err != nil {
+		t.Fatal(err)
+	}
+	var values []string
+	for line := range strings.Lines(out.String()) {
+		var l dspWatchLine
+		if err := json.Unmarshal([]byte(line), &l); err != nil || l.Item != "volume" || l.Ts.IsZero() {
+			t.Fatalf("line %q: %+v, %v", line, l, err)
+		}
+		values = append(values, string(l.Value))
+	}
+	if strings.Join(values, ",") != "-20,-19.5" {
+		t.Fatalf("values = %v", values)
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log/slog"
 	"time"
 )
@@ -12,14 +14,16 @@ import (
 // - This function is allowed to perform I/O.
 // - It must never call Reduce() directly; it only emits Events to be reduced by the daemon loop.
 // - The daemon loop is responsible for sequencing: Reduce -> Commands -> runEffect -> Events -> Reduce.
+// - ctx bounds the CamillaDSP calls; once it is canceled (shutdown) nothing more is sent.
 func runEffect(
-	client *CamillaDSPClient,
+	ctx context.Context,
+	client CamillaDSPClientInterface,
 	cmd Command,
 	logger *slog.Logger,
 	onEvent func(Event),
 ) {
-	if onEvent == nil {
-		// No place to report observations/errors; nothing sensible to do.
+	if onEvent == nil || ctx.Err() != nil {
+		// No place to report observations/errors, or shutting down; nothing sensible to do.
 		return
 	}
 
@@ -36,16 +40,16 @@ func runEffect(
 
 	switch c := cmd.(type) {
 	case CmdSetVolume:
-		vol, err := client.SetVolume(c.TargetDB)
+		vol, err := client.SetVolume(ctx, c.TargetDB)
 		if err != nil {
 			logger.Error("camilladsp SetVolume failed", "error", err, "target_db", c.TargetDB)
 			onEvent(CamillaCommandFailed{Command: cmd, Err: err, At: now})
 			return
 		}
-		onEvent(CamillaVolumeObserved{VolumeDB: vol, At: now})
+		onEvent(CamillaVolumeObserved{VolumeDB: vol, Confirmed: true, At: now})
 
 	case CmdGetVolume:
-		vol, err := client.GetVolume()
+		vol, err := client.GetVolume(ctx)
 		if err != nil {
 			logger.Error("camilladsp GetVolume failed", "error", err)
 			onEvent(CamillaCommandFailed{Command: cmd, Err: err, At: now})
@@ -53,17 +57,8 @@ func runEffect(
 		}
 		onEvent(CamillaVolumeObserved{VolumeDB: vol, At: now})
 
-	case CmdToggleMute:
-		muted, err := client.ToggleMute()
-		if err != nil {
-			logger.Error("camilladsp ToggleMute failed", "error", err)
-			onEvent(CamillaCommandFailed{Command: cmd, Err: err, At: now})
-			return
-		}
-		onEvent(CamillaMuteObserved{Muted: muted, At: now})
-
 	case CmdSetMute:
-		if err := client.SetMute(c.Muted); err != nil {
+		if err := client.SetMute(ctx, c.Muted); err != nil {
 			logger.Error("camilladsp SetMute failed", "error", err, "muted", c.Muted)
 			onEvent(CamillaCommandFailed{Command: cmd, Err: err, At: now})
 			return
@@ -72,7 +67,7 @@ func runEffect(
 		onEvent(CamillaMuteObserved{Muted: c.Muted, At: now})
 
 	case CmdGetMute:
-		muted, err := client.GetMute()
+		muted, err := client.GetMute(ctx)
 		if err != nil {
 			logger.Error("camilladsp GetMute failed", "error", err)
 			onEvent(CamillaCommandFailed{Command: cmd, Err: err, At: now})
@@ -81,7 +76,7 @@ func runEffect(
 		onEvent(CamillaMuteObserved{Muted: muted, At: now})
 
 	case CmdGetConfigFilePath:
-		path, err := client.GetConfigFilePath()
+		path, err := client.GetConfigFilePath(ctx)
 		if err != nil {
 			logger.Error("camilladsp GetConfigFilePath failed", "error", err)
 			onEvent(CamillaCommandFailed{Command: cmd, Err: err, At: now})
@@ -90,7 +85,7 @@ func runEffect(
 		onEvent(CamillaConfigFilePathObserved{Path: path, At: now})
 
 	case CmdGetState:
-		st, err := client.GetState()
+		st, err := client.GetState(ctx)
 		if err != nil {
 			logger.Error("camilladsp GetState failed", "error", err)
 			onEvent(CamillaCommandFailed{Command: cmd, Err: err, At: now})
@@ -98,21 +93,35 @@ func runEffect(
 		}
 		onEvent(CamillaProcessingStateObserved{State: st, At: now})
 
-	case CmdPublishStateSnapshot:
-		// Deliver reducer-produced snapshot to the requester.
-		// This keeps the reducer pure by moving the channel send into the effects layer.
-		if c.Reply == nil {
-			logger.Warn("state snapshot requested with nil reply channel")
+	case CmdSetFaderVolume:
+		if err := client.SetFaderVolume(ctx, c.Fader, c.TargetDB); err != nil {
+			if errors.As(err, &errCamillaUnsupported{}) {
+				// Warned about at connect; the DSP is still reachable.
+				logger.Debug("camilladsp SetFaderVolume skipped", "error", err, "fader", c.Fader)
+				return
+			}
+			logger.Error("camilladsp SetFaderVolume failed", "error", err, "fader", c.Fader, "target_db", c.TargetDB)
+			onEvent(CamillaCommandFailed{Command: cmd, Err: err, At: now})
+		}
+
+	case CmdSelectOutput:
+		if !runConfigSwitch(ctx, client, cmd, c.ConfigPath, c.VolumeDB, c.Unmute, logger, onEvent) {
 			return
 		}
+		logger.Info("output selected", "output", c.Output)
+		onEvent(OutputSelected{Output: c.Output, At: time.Now()})
 
-		// Never block the effects worker indefinitely.
-		select {
-		case c.Reply <- c.Snapshot:
-			// delivered
-		default:
-			logger.Warn("state snapshot reply channel not ready; dropping snapshot")
+	case CmdTestSignal:
+		if !runConfigSwitch(ctx, client, cmd, c.ConfigPath, c.VolumeDB, c.Unmute, logger, onEvent) {
+			return
 		}
+		logger.Info("test signal applied", "channel", c.Channel)
+		onEvent(TestSignalApplied{Channel: c.Channel, At: time.Now()})
+
+	case CmdPublishStateSnapshot:
+		// Normally delivered by the daemon loop (see enqueueCommands); handled here too
+		// so runEffect accepts every Command.
+		publishStateSnapshot(c, logger)
 
 	default:
 		// Unknown command: record failure so reducer can react (if desired).
@@ -125,6 +134,208 @@ func runEffect(
 	}
 }
 
+// publishStateSnapshot delivers a reducer-produced snapshot to its requester. It
+// performs no I/O and never blocks: a requester that isn't ready loses the reply.
+func publishStateSnapshot(c CmdPublishStateSnapshot, logger *slog.Logger) {
+	if c.Reply == nil {
+		logger.Warn("state snapshot requested with nil reply channel")
+		return
+	}
+	select {
+	case c.Reply <- c.Snapshot:
+		// delivered
+	default:
+		logger.Warn("state snapshot reply channel not ready; dropping snapshot")
+	}
+}
+
+// camillaPipeliner is implemented by clients that can send several requests back
+// to back (CamillaDSPClient, when camilladsp.pipeline is enabled).
+type camillaPipeliner interface {
+	Pipelining() bool
+	Pipeline(ctx context.Context, requests []any) ([][]byte, error)
+}
+
+// runEffects executes a batch of queued Commands in order. With pipelining enabled on
+// the client, consecutive single-request commands are sent back to back (see
+// CamillaDSPClient.Pipeline); everything else runs through runEffect.
+func runEffects(
+	ctx context.Context,
+	client CamillaDSPClientInterface,
+	cmds []Command,
+	logger *slog.Logger,
+	onEvent func(Event),
+) {
+	pipeliner, ok := client.(camillaPipeliner)
+	if !ok || !pipeliner.Pipelining() {
+		for _, cmd := range cmds {
+			runEffect(ctx, client, cmd, logger, onEvent)
+		}
+		return
+	}
+
+	for len(cmds) > 0 {
+		n := 0
+		for n < len(cmds) {
+			if _, ok := camillaRequestFor(cmds[n]); !ok {
+				break
+			}
+			n++
+		}
+		if n < 2 {
+			// Nothing to gain from pipelining a single (or sequenced) command.
+			runEffect(ctx, client, cmds[0], logger, onEvent)
+			cmds = cmds[1:]
+			continue
+		}
+		runPipelined(ctx, pipeliner, cmds[:n], logger, onEvent)
+		cmds = cmds[n:]
+	}
+}
+
+// runPipelined sends cmds (all with a camillaRequestFor mapping) as one pipeline and
+// emits an observation or failure per command.
+func runPipelined(ctx context.Context, client camillaPipeliner, cmds []Command, logger *slog.Logger, onEvent func(Event)) {
+	reqs := make([]any, len(cmds))
+	for i, cmd := range cmds {
+		reqs[i], _ = camillaRequestFor(cmd)
+	}
+	responses, err := client.Pipeline(ctx, reqs)
+	now := time.Now()
+	logger.Debug("camilladsp pipeline", "commands", len(cmds), "responses", len(responses))
+
+	for i, cmd := range cmds {
+		if i >= len(responses) {
+			logger.Error("camilladsp pipelined command failed", "command", cmd.String(), "error", err)
+			onEvent(CamillaCommandFailed{Command: cmd, Err: err, At: now})
+			continue
+		}
+		obs, perr := camillaObservationFor(cmd, responses[i], now)
+		if perr != nil {
+			logger.Error("camilladsp pipelined command failed", "command", cmd.String(), "error", perr)
+			onEvent(CamillaCommandFailed{Command: cmd, Err: perr, At: now})
+			continue
+		}
+		if obs != nil {
+			onEvent(obs)
+		}
+	}
+}
+
+// camillaRequestFor returns the CamillaDSP request for commands that map to exactly
+// one request/response (and can therefore be pipelined).
+func camillaRequestFor(cmd Command) (any, bool) {
+	switch c := cmd.(type) {
+	case CmdSetVolume:
+		return map[string]any{"SetVolume": c.TargetDB}, true
+	case CmdGetVolume:
+		return "GetVolume", true
+	case CmdSetMute:
+		return map[string]any{"SetMute": c.Muted}, true
+	case CmdGetMute:
+		return "GetMute", true
+	case CmdGetConfigFilePath:
+		return "GetConfigFilePath", true
+	case CmdGetState:
+		return "GetState", true
+	case CmdSetFaderVolume:
+		return map[string]any{"SetFaderVolume": []any{c.Fader, c.TargetDB}}, true
+	}
+	return nil, false
+}
+
+// camillaObservationFor turns the response to a pipelined command into the same
+// observation runEffect would emit (nil if the command has none).
+func camillaObservationFor(cmd Command, resp []byte, at time.Time) (Event, error) {
+	switch c := cmd.(type) {
+	case CmdSetVolume:
+		// Setters are confirmed by what we sent (as in the sequential path).
+		if err := checkCamillaReply("SetVolume", resp); err != nil {
+			return nil, err
+		}
+		return CamillaVolumeObserved{VolumeDB: c.TargetDB, Confirmed: true, At: at}, nil
+	case CmdGetVolume:
+		r, err := parseCamillaReply[float64](resp, "GetVolume")
+		if err != nil {
+			return nil, err
+		}
+		return CamillaVolumeObserved{VolumeDB: r.Value, At: at}, nil
+	case CmdSetMute:
+		if err := checkCamillaReply("SetMute", resp); err != nil {
+			return nil, err
+		}
+		return CamillaMuteObserved{Muted: c.Muted, At: at}, nil
+	case CmdSetFaderVolume:
+		return nil, checkCamillaReply("SetFaderVolume", resp)
+	case CmdGetMute:
+		r, err := parseCamillaReply[bool](resp, "GetMute")
+		if err != nil {
+			return nil, err
+		}
+		return CamillaMuteObserved{Muted: r.Value, At: at}, nil
+	case CmdGetConfigFilePath:
+		r, err := parseCamillaReply[string](resp, "GetConfigFilePath")
+		if err != nil {
+			return nil, err
+		}
+		return CamillaConfigFilePathObserved{Path: r.Value, At: at}, nil
+	case CmdGetState:
+		r, err := parseCamillaReply[string](resp, "GetState")
+		if err != nil {
+			return nil, err
+		}
+		return CamillaProcessingStateObserved{State: r.Value, At: at}, nil
+	}
+	return nil, nil
+}
+
+// runConfigSwitch runs the sequenced switch shared by output selection and test signals:
+// mute -> (optional) switch config + reload -> (optional) set volume -> (optional) unmute.
+// It stops at the first failure so we never unmute into a half-applied state, reporting
+// cmd as failed, and returns whether every step succeeded.
+func runConfigSwitch(ctx context.Context, client CamillaDSPClientInterface, cmd Command, configPath string, volumeDB *float64, unmute bool, logger *slog.Logger, onEvent func(Event)) bool {
+	if err := client.SetMute(ctx, true); err != nil {
+		logger.Error("camilladsp SetMute failed", "error", err, "muted", true, "command", cmd.String())
+		onEvent(CamillaCommandFailed{Command: cmd, Err: err, At: time.Now()})
+		return false
+	}
+	onEvent(CamillaMuteObserved{Muted: true, At: time.Now()})
+
+	if configPath != "" {
+		if err := client.SetConfigFilePath(ctx, configPath); err != nil {
+			logger.Error("camilladsp SetConfigFilePath failed", "error", err, "path", configPath)
+			onEvent(CamillaCommandFailed{Command: cmd, Err: err, At: time.Now()})
+			return false
+		}
+		if err := client.Reload(ctx); err != nil {
+			logger.Error("camilladsp Reload failed", "error", err, "path", configPath)
+			onEvent(CamillaCommandFailed{Command: cmd, Err: err, At: time.Now()})
+			return false
+		}
+		onEvent(CamillaConfigFilePathObserved{Path: configPath, At: time.Now()})
+	}
+
+	if volumeDB != nil {
+		vol, err := client.SetVolume(ctx, *volumeDB)
+		if err != nil {
+			logger.Error("camilladsp SetVolume failed", "error", err, "target_db", *volumeDB)
+			onEvent(CamillaCommandFailed{Command: cmd, Err: err, At: time.Now()})
+			return false
+		}
+		onEvent(CamillaVolumeObserved{VolumeDB: vol, Confirmed: true, At: time.Now()})
+	}
+
+	if unmute {
+		if err := client.SetMute(ctx, false); err != nil {
+			logger.Error("camilladsp SetMute failed", "error", err, "muted", false, "command", cmd.String())
+			onEvent(CamillaCommandFailed{Command: cmd, Err: err, At: time.Now()})
+			return false
+		}
+		onEvent(CamillaMuteObserved{Muted: false, At: time.Now()})
+	}
+	return true
+}
+
 // errNoClient indicates the daemon was asked to execute a command without a CamillaDSP client.
 type errNoClient struct{}
 
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+)
+
+// pipeliningMock adds request pipelining to mockCamillaDSPClient. Each pipeline
+// answers the first `answer` requests (all if 0) from replies, keyed by request
+// name, and fails the rest.
+type pipeliningMock struct {
+	*mockCamillaDSPClient
+	replies   map[string]string
+	answer    int
+	pipelines [][]string
+}
+
+func (p *pipeliningMock) Pipelining() bool { return true }
+
+func (p *pipeliningMock) Pipeline(_ context.Context, requests []any) ([][]byte, error) {
+	names := make([]string, len(requests))
+	for i, req := range requests {
+		switch r := req.(type) {
+		case string:
+			names[i] = r
+		case map[string]any:
+			for k := range r {
+				names[i] = k
+			}
+		}
+	}
+	p.pipelines = append(p.pipelines, names)
+
+	n := len(names)
+	if p.answer > 0 && p.answer < n {
+		n = p.answer
+	}
+	responses := make([][]byte, n)
+	for i := range n {
+		responses[i] = []byte(p.replies[names[i]])
+	}
+	if n < len(names) {
+		return responses, errors.New("connection lost")
+	}
+	return responses, nil
+}
+
+func collectEffects(client CamillaDSPClientInterface, cmds ...Command) []Event {
+	var got []Event
+	runEffects(context.Background(), client, cmds, slog.New(slog.DiscardHandler), func(ev Event) {
+		got = append(got, ev)
+	})
+	return got
+}
+
+func TestRunEffect_FailureBecomesCommandFailed(t *testing.T) {
+	errDown := errors.New("camilladsp down")
+	client := newMockCamillaDSPClient(-30)
+	client.failOn = map[string]error{"GetVolume": errDown}
+
+	got := collectEffects(client, CmdGetVolume{})
+	if len(got) != 1 {
+		t.Fatalf("events = %#v", got)
+	}
+	f, ok := got[0].(CamillaCommandFailed)
+	if !ok || f.Command != (CmdGetVolume{}) || !errors.Is(f.Err, errDown) {
+		t.Fatalf("event = %#v", got[0])
+	}
+}
+
+func TestRunEffect_NoClient(t *testing.T) {
+	got := collectEffects(nil, CmdGetMute{})
+	if len(got) != 1 {
+		t.Fatalf("events = %#v", got)
+	}
+	if f, ok := got[0].(CamillaCommandFailed); !ok || !errors.As(f.Err, new(errNoClient)) {
+		t.Fatalf("event = %#v", got[0])
+	}
+}
+
+func TestRunEffect_ConfigSwitchStopsAtFirstFailure(t *testing.T) {
+	errReload := errors.New("bad config")
+	client := newMockCamillaDSPClient(-30)
+	client.failOn = map[string]error{"Reload": errReload}
+	vol := -20.0
+	cmd := CmdSelectOutput{Output: "speakers", ConfigPath: "/etc/camilladsp/speakers.yml", VolumeDB: &vol, Unmute: true}
+
+	got := collectEffects(client, cmd)
+
+	if calls := client.callLog(); !slices.Equal(calls, []string{"SetMute", "SetConfigFilePath", "Reload"}) {
+		t.Fatalf("calls = %v (must not set volume or unmute after a failed reload)", calls)
+	}
+	if len(got) != 2 {
+		t.Fatalf("events = %#v", got)
+	}
+	if m, ok := got[0].(CamillaMuteObserved); !ok || !m.Muted {
+		t.Fatalf("event 0 = %#v", got[0])
+	}
+	if f, ok := got[1].(CamillaCommandFailed); !ok || !errors.Is(f.Err, errReload) {
+		t.Fatalf("event 1 = %#v", got[1])
+	}
+}
+
+func TestRunEffects_SequentialKeepsOrder(t *testing.T) {
+	errState := errors.New("timeout")
+	client := newMockCamillaDSPClient(-30)
+	client.failOn = map[string]error{"GetState": errState}
+
+	got := collectEffects(client, CmdSetVolume{TargetDB: -25}, CmdSetMute{Muted: true}, CmdGetState{}, CmdGetVolume{})
+
+	if calls := client.callLog(); !slices.Equal(calls, []string{"SetVolume", "SetMute", "GetState", "GetVolume"}) {
+		t.Fatalf("calls = %v", calls)
+	}
+	if len(got) != 4 {
+		t.Fatalf("events = %#v", got)
+	}
+	if v, ok := got[0].(CamillaVolumeObserved); !ok || v.VolumeDB != -25 {
+		t.Fatalf("event 0 = %#v", got[0])
+	}
+	if m, ok := got[1].(CamillaMuteObserved); !ok || !m.Muted {
+		t.Fatalf("event 1 = %#v", got[1])
+	}
+	// A failure in the middle of a batch doesn't stop the commands after it.
+	if f, ok := got[2].(CamillaCommandFailed); !ok || f.Command != (CmdGetState{}) {
+		t.Fatalf("event 2 = %#v", got[2])
+	}
+	if v, ok := got[3].(CamillaVolumeObserved); !ok || v.VolumeDB != -25 {
+		t.Fatalf("event 3 = %#v", got[3])
+	}
+}
+
+func TestRunEffects_PipelinesRunsAroundSequencedCommands(t *testing.T) {
+	client := &pipeliningMock{
+		mockCamillaDSPClient: newMockCamillaDSPClient(-30),
+		replies: map[string]string{
+			"SetVolume": `{"SetVolume":{"result":"Ok"}}`,
+			"GetMute":   `{"GetMute":{"result":"Ok","value":false}}`,
+			"GetVolume": `{"GetVolume":{"result":"Ok","value":-18}}`,
+			"GetState":  `{"GetState":{"result":"Ok","value":"Running"}}`,
+		},
+	}
+	vol := -18.0
+
+	got := collectEffects(client,
+		CmdSetVolume{TargetDB: -22}, CmdGetMute{},
+		CmdSelectOutput{Output: "phones", VolumeDB: &vol},
+		CmdSetMute{Muted: true},
+		CmdSelectOutput{Output: "speakers", VolumeDB: &vol},
+		CmdGetVolume{}, CmdGetState{},
+	)
+
+	wantPipelines := [][]string{{"SetVolume", "GetMute"}, {"GetVolume", "GetState"}}
+	if !slices.EqualFunc(client.pipelines, wantPipelines, slices.Equal) {
+		t.Fatalf("pipelines = %v, want %v", client.pipelines, wantPipelines)
+	}
+	// Config switches and a lone command between them go through the client's methods.
+	if calls := client.callLog(); !slices.Equal(calls, []string{"SetMute", "SetVolume", "SetMute", "SetMute", "SetVolume"}) {
+		t.Fatalf("calls = %v", calls)
+	}
+
+	var kinds []string
+	for _, ev := range got {
+		switch ev.(type) {
+		case CamillaVolumeObserved:
+			kinds = append(kinds, "volume")
+		case CamillaMuteObserved:
+			kinds = append(kinds, "mute")
+		case OutputSelected:
+			kinds = append(kinds, "output")
+		case CamillaProcessingStateObserved:
+			kinds = append(kinds, "state")
+		default:
+			t.Fatalf("unexpected event %#v", ev)
+		}
+	}
+	want := []string{"volume", "mute", "mute", "volume", "output", "mute", "mute", "volume", "output", "volume", "state"}
+	if !slices.Equal(kinds, want) {
+		t.Fatalf("events = %v, want %v", kinds, want)
+	}
+}
+
+func TestRunEffects_PipelineFailureFailsRemainingCommands(t *testing.T) {
+	client := &pipeliningMock{
+		mockCamillaDSPClient: newMockCamillaDSPClient(-30),
+		replies:              map[string]string{"GetVolume": `{"GetVolume":{"result":"Ok","value":-30}}`},
+		answer:               1,
+	}
+
+	got := collectEffects(client, CmdGetVolume{}, CmdGetMute{}, CmdGetState{})
+
+	if len(got) != 3 {
+		t.Fatalf("events = %#v", got)
+	}
+	if _, ok := got[0].(CamillaVolumeObserved); !ok {
+		t.Fatalf("event 0 = %#v", got[0])
+	}
+	for i, want := range []Command{CmdGetMute{}, CmdGetState{}} {
+		if f, ok := got[i+1].(CamillaCommandFailed); !ok || f.Command != want || f.Err == nil {
+			t.Fatalf("event %d = %#v", i+1, got[i+1])
+		}
+	}
+}
+
+// TestRunDaemon_CoalescesVolumeSets drives the whole loop against the mock: absolute
+// sets arriving between ticks are coalesced, and the last SetVolume is the latest level.
+func TestRunDaemon_CoalescesVolumeSets(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := slog.New(slog.DiscardHandler)
+	client := newMockCamillaDSPClient(-30)
+	events := make(chan Event)
+	crash := newCrashReporter(t.TempDir(), func() {}, logger)
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0}
+	go runDaemon(ctx, "main", events, nil, client, cfg, RotaryConfig{}, ReducerPolicy{}, nil, 5, 0, nil, crash, logger)
+
+	const sets = 10
+	for i := range sets {
+		events <- SetVolumeAbsolute{Db: -25 + float64(i)}
+	}
+	const last = -25 + sets - 1
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		calls := client.setVolumeLog()
+		if n := len(calls); n > 0 && calls[n-1] == last {
+			if n >= sets {
+				t.Fatalf("SetVolume calls = %v, expected sets to coalesce", calls)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("SetVolume calls = %v, want last %v", calls, float64(last))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,57 @@
+//go:build linux
+
+package main
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// evdev ioctls (from <linux/input.h>).
+const (
+	evdevIOCGName = 0x81004506 // EVIOCGNAME(256) (_IOC(_IOC_READ, 'E', 0x06, 256))
+	evdevIOCGrab  = 0x40044590 // EVIOCGRAB (_IOW('E', 0x90, int))
+
+	evdevKeyMax = 0x2ff // KEY_MAX
+	evdevRelMax = 0x0f  // REL_MAX
+	evdevMscMax = 0x07  // MSC_MAX
+)
+
+// evdevIOCGBit is EVIOCGBIT(evType, size): the bitmap of codes a device reports
+// for evType (evType 0 returns the event types themselves).
+func evdevIOCGBit(evType, size int) uintptr {
+	return uintptr(2<<30 | size<<16 | 'E'<<8 | (0x20 + evType))
+}
+
+// evdevIoctl issues an ioctl that fills buf.
+func evdevIoctl(fd int, req uintptr, buf []byte) error {
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), req, uintptr(unsafe.Pointer(&buf[0]))); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// evdevName returns the device name reported by EVIOCGNAME.
+func evdevName(fd int) (string, error) {
+	var buf [256]byte
+	if err := evdevIoctl(fd, evdevIOCGName, buf[:]); err != nil {
+		return "", err
+	}
+	return unix.ByteSliceToString(buf[:]), nil
+}
+
+// evdevCodes returns the codes of evType the device reports, up to maxCode.
+func evdevCodes(fd int, evType int, maxCode int) ([]uint16, error) {
+	buf := make([]byte, maxCode/8+1)
+	if err := evdevIoctl(fd, evdevIOCGBit(evType, len(buf)), buf); err != nil {
+		return nil, err
+	}
+	var codes []uint16
+	for code := 0; code <= maxCode; code++ {
+		if buf[code/8]&(1<<(code%8)) != 0 {
+			codes = append(codes, uint16(code))
+		}
+	}
+	return codes, nil
+}
@@ -22,7 +22,11 @@ type Action interface {
 
 // VolumeHeld indicates a volume button is being held
 type VolumeHeld struct {
-	Direction int `json:"direction"` // -1 for down, 0 for none, +1 for up
+	Direction int    `json:"direction"`        // -1 for down, 0 for none, +1 for up
+	Origin    string `json:"origin,omitempty"` // who/what asked (see volume_origin.go); default "ir"
+	// HoldTimeoutMS keeps this hold alive this long without a repeat when longer than
+	// velocity.hold_timeout_ms (inputs with slow repeats, see inputs[].repeat_interval_ms).
+	HoldTimeoutMS int `json:"hold_timeout_ms,omitempty"`
 }
 
 func (VolumeHeld) eventMarker() {}
@@ -35,16 +39,33 @@ func (VolumeRelease) eventMarker() {}
 // RotaryTurn represents a raw rotary encoder movement (detents/steps).
 // The reducer owns policy for converting this into volume changes (including velocity scaling).
 type RotaryTurn struct {
-	Steps int `json:"steps"` // positive=up, negative=down
+	Steps  int    `json:"steps"`            // positive=up, negative=down
+	Origin string `json:"origin,omitempty"` // default "rotary"
 }
 
 func (RotaryTurn) eventMarker() {}
 
+// RotaryTurnHiRes represents high-resolution wheel movement (REL_WHEEL_HI_RES),
+// in units of 1/120 detent. The reducer accumulates partial detents.
+type RotaryTurnHiRes struct {
+	Units  int    `json:"units"`            // positive=up, negative=down
+	Origin string `json:"origin,omitempty"` // default "rotary"
+}
+
+func (RotaryTurnHiRes) eventMarker() {}
+
+// RotaryPress represents a click of the rotary encoder's push-button.
+// The reducer maps it to mute or encoder mode cycling (see RotaryConfig.ButtonAction).
+type RotaryPress struct{}
+
+func (RotaryPress) eventMarker() {}
+
 // VolumeStep represents discrete volume adjustments from rotary encoders.
 // NOTE: This is an internal "derived" action that may be produced by the reducer.
 type VolumeStep struct {
 	Steps     int     `json:"steps"`                 // Number of detents/steps (positive=up, negative=down)
 	DbPerStep float64 `json:"db_per_step,omitempty"` // Optional: override default step size
+	Origin    string  `json:"origin,omitempty"`      // default "rotary"
 }
 
 func (VolumeStep) eventMarker() {}
@@ -54,14 +75,84 @@ type ToggleMute struct{}
 
 func (ToggleMute) eventMarker() {}
 
+// ResyncState re-reads volume, mute, config path and processing state from
+// CamillaDSP and re-broadcasts volume and mute, e.g. after an external tool
+// changed the DSP behind the daemon's back. Unzoned, it resyncs every zone.
+type ResyncState struct{}
+
+func (ResyncState) eventMarker() {}
+
 // SetVolumeAbsolute requests volume to be set to a specific value
 type SetVolumeAbsolute struct {
 	Db     float64 `json:"db"`
-	Origin string  `json:"origin"` // e.g., "ir", "librespot", "ipc", "ui"
+	Origin string  `json:"origin"` // e.g., "ir", "librespot", "ipc:argon-ctl", "webui"; default "input"
 }
 
 func (SetVolumeAbsolute) eventMarker() {}
 
+// SetVolumePercent requests volume to be set to a percentage (0-100) of the
+// zone's volume range; see volume_percent.go for the mapping.
+type SetVolumePercent struct {
+	Percent float64 `json:"percent"`
+	Origin  string  `json:"origin,omitempty"` // default "input"
+}
+
+func (SetVolumePercent) eventMarker() {}
+
+// SetMute requests mute to be set to a specific state (unlike ToggleMute,
+// repeating it is harmless).
+type SetMute struct {
+	Muted bool `json:"muted"`
+}
+
+func (SetMute) eventMarker() {}
+
+// OutputSelect switches the active output (e.g. speakers <-> headphones).
+// An empty Output cycles to the next configured output.
+type OutputSelect struct {
+	Output string `json:"output"`
+}
+
+func (OutputSelect) eventMarker() {}
+
+// ============================================================================
+// Zone Actions
+// ============================================================================
+
+// ZonedEvent targets an event at a specific zone (CamillaDSP instance).
+// Events without a zone are routed to the current zone (see runZoneRouter).
+type ZonedEvent struct {
+	Zone  string
+	Event Event
+}
+
+func (ZonedEvent) eventMarker() {}
+
+// SelectZone switches the current zone that unzoned inputs (IR, rotary, IPC) control.
+// An empty Zone cycles to the next configured zone.
+type SelectZone struct {
+	Zone string `json:"zone"`
+}
+
+func (SelectZone) eventMarker() {}
+
+// LinkZone adds a zone to the volume link group (or updates its offset).
+// Relative volume changes applied to any linked zone are mirrored to the others;
+// absolute sets are mirrored with each zone's offset applied.
+type LinkZone struct {
+	Zone     string  `json:"zone"`
+	OffsetDB float64 `json:"offset_db"` // offset relative to the group's reference level
+}
+
+func (LinkZone) eventMarker() {}
+
+// UnlinkZone removes a zone from the volume link group.
+type UnlinkZone struct {
+	Zone string `json:"zone"`
+}
+
+func (UnlinkZone) eventMarker() {}
+
 // ============================================================================
 // Media Transport Actions (no-op for now; emitted by input devices / IPC / UI)
 // ============================================================================
@@ -111,6 +202,8 @@ func (LibrespotVolumeChanged) eventMarker() {}
 type LibrespotTrackChanged struct {
 	TrackId    string `json:"track_id"`
 	Name       string `json:"name"`
+	Artists    string `json:"artists,omitempty"` // newline-separated, as librespot reports them
+	Album      string `json:"album,omitempty"`
 	DurationMs string `json:"duration_ms"`
 	Uri        string `json:"uri"`
 }
@@ -142,10 +235,35 @@ type PlexStateChanged struct {
 	RatingKey     string `json:"rating_key"`     // Plex rating key
 	PlayerTitle   string `json:"player_title"`   // Player name
 	PlayerProduct string `json:"player_product"` // Player product (e.g., "Plexamp")
+
+	// Loudness is the track's Plex loudness analysis, if any (see normalization.go).
+	Loudness *TrackLoudness `json:"loudness,omitempty"`
 }
 
 func (PlexStateChanged) eventMarker() {}
 
+// TidalStateChanged reports the Tidal Connect player's state (see tidal_connect.go).
+type TidalStateChanged struct {
+	State      string `json:"state"` // "playing", "paused", "stopped"
+	Title      string `json:"title"`
+	Artist     string `json:"artist"`
+	Album      string `json:"album"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+func (TidalStateChanged) eventMarker() {}
+
+// TrackLoudness is ReplayGain-style loudness data for the track now playing.
+// Gains are in dB (nil = not tagged); peaks are linear sample peaks (1.0 = full scale, 0 = unknown).
+type TrackLoudness struct {
+	TrackGainDB *float64 `json:"track_gain_db,omitempty"`
+	AlbumGainDB *float64 `json:"album_gain_db,omitempty"`
+	TrackPeak   float64  `json:"track_peak,omitempty"`
+	AlbumPeak   float64  `json:"album_peak,omitempty"`
+}
+
+func (TrackLoudness) eventMarker() {}
+
 // ============================================================================
 // JSON Encoding/Decoding Support
 // ============================================================================
@@ -156,16 +274,30 @@ func (PlexStateChanged) eventMarker() {}
 // EventEnvelope wraps an event with a type discriminator for JSON marshaling
 type EventEnvelope struct {
 	Type string          `json:"type"`
+	Zone string          `json:"zone,omitempty"` // optional target zone; empty = current zone
 	Data json.RawMessage `json:"data,omitempty"`
 }
 
-// UnmarshalEvent deserializes a JSON event envelope into a concrete Event
+// UnmarshalEvent deserializes a JSON event envelope into a concrete Event.
+// If the envelope names a zone, the event is wrapped in a ZonedEvent.
 func UnmarshalEvent(data []byte) (Event, error) {
 	var env EventEnvelope
 	if err := json.Unmarshal(data, &env); err != nil {
 		return nil, fmt.Errorf("unmarshal envelope: %w", err)
 	}
 
+	ev, err := unmarshalEnvelope(env)
+	if err != nil {
+		return nil, err
+	}
+	if env.Zone != "" {
+		return ZonedEvent{Zone: env.Zone, Event: ev}, nil
+	}
+	return ev, nil
+}
+
+// unmarshalEnvelope decodes the payload of an envelope based on its type discriminator.
+func unmarshalEnvelope(env EventEnvelope) (Event, error) {
 	switch env.Type {
 	case "volume_held":
 		var a VolumeHeld
@@ -181,9 +313,19 @@ func UnmarshalEvent(data []byte) (Event, error) {
 		}
 		return a, nil
 
+	case "rotary_turn_hi_res":
+		var a RotaryTurnHiRes
+		if err := json.Unmarshal(env.Data, &a); err != nil {
+			return nil, fmt.Errorf("unmarshal RotaryTurnHiRes: %w", err)
+		}
+		return a, nil
+
 	case "volume_release":
 		return VolumeRelease{}, nil
 
+	case "rotary_press":
+		return RotaryPress{}, nil
+
 	case "volume_step":
 		var a VolumeStep
 		if err := json.Unmarshal(env.Data, &a); err != nil {
@@ -194,6 +336,9 @@ func UnmarshalEvent(data []byte) (Event, error) {
 	case "toggle_mute":
 		return ToggleMute{}, nil
 
+	case "resync_state":
+		return ResyncState{}, nil
+
 	case "set_volume_absolute":
 		var a SetVolumeAbsolute
 		if err := json.Unmarshal(env.Data, &a); err != nil {
@@ -201,6 +346,82 @@ func UnmarshalEvent(data []byte) (Event, error) {
 		}
 		return a, nil
 
+	case "set_volume_percent":
+		var a SetVolumePercent
+		if err := json.Unmarshal(env.Data, &a); err != nil {
+			return nil, fmt.Errorf("unmarshal SetVolumePercent: %w", err)
+		}
+		return a, nil
+
+	case "set_mute":
+		var a SetMute
+		if err := json.Unmarshal(env.Data, &a); err != nil {
+			return nil, fmt.Errorf("unmarshal SetMute: %w", err)
+		}
+		return a, nil
+
+	case "output_select":
+		var a OutputSelect
+		if len(env.Data) > 0 {
+			if err := json.Unmarshal(env.Data, &a); err != nil {
+				return nil, fmt.Errorf("unmarshal OutputSelect: %w", err)
+			}
+		}
+		return a, nil
+
+	case "select_zone":
+		var a SelectZone
+		if len(env.Data) > 0 {
+			if err := json.Unmarshal(env.Data, &a); err != nil {
+				return nil, fmt.Errorf("unmarshal SelectZone: %w", err)
+			}
+		}
+		return a, nil
+
+	case "link_zone":
+		var a LinkZone
+		if err := json.Unmarshal(env.Data, &a); err != nil {
+			return nil, fmt.Errorf("unmarshal LinkZone: %w", err)
+		}
+		return a, nil
+
+	case "unlink_zone":
+		var a UnlinkZone
+		if err := json.Unmarshal(env.Data, &a); err != nil {
+			return nil, fmt.Errorf("unmarshal UnlinkZone: %w", err)
+		}
+		return a, nil
+
+	case "ir_send":
+		var a IRSend
+		if err := json.Unmarshal(env.Data, &a); err != nil {
+			return nil, fmt.Errorf("unmarshal IRSend: %w", err)
+		}
+		return a, nil
+
+	case "test_signal":
+		var a TestSignal
+		if err := json.Unmarshal(env.Data, &a); err != nil {
+			return nil, fmt.Errorf("unmarshal TestSignal: %w", err)
+		}
+		return a, nil
+
+	case "calibration_mode":
+		var a CalibrationMode
+		if len(env.Data) > 0 {
+			if err := json.Unmarshal(env.Data, &a); err != nil {
+				return nil, fmt.Errorf("unmarshal CalibrationMode: %w", err)
+			}
+		}
+		return a, nil
+
+	case "limit_override":
+		var a LimitOverride
+		if err := json.Unmarshal(env.Data, &a); err != nil {
+			return nil, fmt.Errorf("unmarshal LimitOverride: %w", err)
+		}
+		return a, nil
+
 	case "media_play_pause":
 		return MediaPlayPause{}, nil
 	case "media_next":
@@ -256,6 +477,20 @@ func UnmarshalEvent(data []byte) (Event, error) {
 		}
 		return a, nil
 
+	case "tidal_state_changed":
+		var a TidalStateChanged
+		if err := json.Unmarshal(env.Data, &a); err != nil {
+			return nil, fmt.Errorf("unmarshal TidalStateChanged: %w", err)
+		}
+		return a, nil
+
+	case "track_loudness":
+		var a TrackLoudness
+		if err := json.Unmarshal(env.Data, &a); err != nil {
+			return nil, fmt.Errorf("unmarshal TrackLoudness: %w", err)
+		}
+		return a, nil
+
 	default:
 		return nil, fmt.Errorf("unknown event type: %q", env.Type)
 	}
@@ -265,6 +500,11 @@ func UnmarshalEvent(data []byte) (Event, error) {
 func MarshalEvent(e Event) ([]byte, error) {
 	var env EventEnvelope
 
+	if ze, ok := e.(ZonedEvent); ok {
+		env.Zone = ze.Zone
+		e = ze.Event
+	}
+
 	switch e := e.(type) {
 	case VolumeHeld:
 		env.Type = "volume_held"
@@ -285,6 +525,17 @@ func MarshalEvent(e Event) ([]byte, error) {
 		}
 		env.Data = data
 
+	case RotaryTurnHiRes:
+		env.Type = "rotary_turn_hi_res"
+		data, err := json.Marshal(e)
+		if err != nil {
+			return nil, fmt.Errorf("marshal RotaryTurnHiRes: %w", err)
+		}
+		env.Data = data
+
+	case RotaryPress:
+		env.Type = "rotary_press"
+
 	case VolumeStep:
 		env.Type = "volume_step"
 		data, err := json.Marshal(e)
@@ -296,6 +547,9 @@ func MarshalEvent(e Event) ([]byte, error) {
 	case ToggleMute:
 		env.Type = "toggle_mute"
 
+	case ResyncState:
+		env.Type = "resync_state"
+
 	case SetVolumeAbsolute:
 		env.Type = "set_volume_absolute"
 		data, err := json.Marshal(e)
@@ -304,6 +558,86 @@ func MarshalEvent(e Event) ([]byte, error) {
 		}
 		env.Data = data
 
+	case SetVolumePercent:
+		env.Type = "set_volume_percent"
+		data, err := json.Marshal(e)
+		if err != nil {
+			return nil, fmt.Errorf("marshal SetVolumePercent: %w", err)
+		}
+		env.Data = data
+
+	case SetMute:
+		env.Type = "set_mute"
+		data, err := json.Marshal(e)
+		if err != nil {
+			return nil, fmt.Errorf("marshal SetMute: %w", err)
+		}
+		env.Data = data
+
+	case OutputSelect:
+		env.Type = "output_select"
+		data, err := json.Marshal(e)
+		if err != nil {
+			return nil, fmt.Errorf("marshal OutputSelect: %w", err)
+		}
+		env.Data = data
+
+	case SelectZone:
+		env.Type = "select_zone"
+		data, err := json.Marshal(e)
+		if err != nil {
+			return nil, fmt.Errorf("marshal SelectZone: %w", err)
+		}
+		env.Data = data
+
+	case LinkZone:
+		env.Type = "link_zone"
+		data, err := json.Marshal(e)
+		if err != nil {
+			return nil, fmt.Errorf("marshal LinkZone: %w", err)
+		}
+		env.Data = data
+
+	case UnlinkZone:
+		env.Type = "unlink_zone"
+		data, err := json.Marshal(e)
+		if err != nil {
+			return nil, fmt.Errorf("marshal UnlinkZone: %w", err)
+		}
+		env.Data = data
+
+	case IRSend:
+		env.Type = "ir_send"
+		data, err := json.Marshal(e)
+		if err != nil {
+			return nil, fmt.Errorf("marshal IRSend: %w", err)
+		}
+		env.Data = data
+
+	case TestSignal:
+		env.Type = "test_signal"
+		data, err := json.Marshal(e)
+		if err != nil {
+			return nil, fmt.Errorf("marshal TestSignal: %w", err)
+		}
+		env.Data = data
+
+	case CalibrationMode:
+		env.Type = "calibration_mode"
+		data, err := json.Marshal(e)
+		if err != nil {
+			return nil, fmt.Errorf("marshal CalibrationMode: %w", err)
+		}
+		env.Data = data
+
+	case LimitOverride:
+		env.Type = "limit_override"
+		data, err := json.Marshal(e)
+		if err != nil {
+			return nil, fmt.Errorf("marshal LimitOverride: %w", err)
+		}
+		env.Data = data
+
 	case MediaPlayPause:
 		env.Type = "media_play_pause"
 	case MediaNext:
@@ -365,6 +699,22 @@ func MarshalEvent(e Event) ([]byte, error) {
 		}
 		env.Data = data
 
+	case TidalStateChanged:
+		env.Type = "tidal_state_changed"
+		data, err := json.Marshal(e)
+		if err != nil {
+			return nil, fmt.Errorf("marshal TidalStateChanged: %w", err)
+		}
+		env.Data = data
+
+	case TrackLoudness:
+		env.Type = "track_loudness"
+		data, err := json.Marshal(e)
+		if err != nil {
+			return nil, fmt.Errorf("marshal TrackLoudness: %w", err)
+		}
+		env.Data = data
+
 	default:
 		return nil, fmt.Errorf("unsupported event type: %T", e)
 	}
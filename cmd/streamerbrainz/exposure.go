@@ -0,0 +1,129 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// ============================================================================
+// Hearing-safety exposure warnings (stats.exposure)
+// ============================================================================
+// Opt-in, on top of the listening statistics: the time listened at or above
+// stats.loud_threshold_db today is compared with a daily budget
+// (daily_budget_min). Once warn_at_percent of it is used, and again when it is
+// exceeded, an ExposureWarning event is sent, which the zone router publishes
+// as "exposure_warning" (each level at most once per day, also across
+// restarts). With auto_dim, every zone still playing loud after the budget is
+// exceeded is lowered to dim_to_db (origin "exposure"), including when it is
+// turned back up. The check runs whenever the statistics are updated, so it
+// lags by at most about a minute.
+// ============================================================================
+
+// Exposure warning levels.
+const (
+	ExposureApproaching = "approaching"
+	ExposureExceeded    = "exceeded"
+)
+
+// exposureDimRetry keeps a zone from being dimmed again before the first dim
+// has been reflected in its volume.
+const exposureDimRetry = 5 * time.Second
+
+// ExposureWarning reports that today's loud listening reached a budget level.
+type ExposureWarning struct {
+	Level       string   `json:"level"`
+	LoudSec     float64  `json:"loud_sec"`
+	BudgetSec   float64  `json:"budget_sec"`
+	ThresholdDB float64  `json:"threshold_db"`
+	DimToDB     *float64 `json:"dim_to_db,omitempty"` // set with auto_dim
+}
+
+func (ExposureWarning) eventMarker() {}
+
+// BroadcastExposureWarning is emitted by the zone router for ExposureWarning.
+type BroadcastExposureWarning struct {
+	Warning ExposureWarning `json:"warning"`
+	At      time.Time       `json:"at"`
+}
+
+func (BroadcastExposureWarning) stateBroadcastMarker() {}
+
+// exposureLevel is the budget level reached with loudSec of loud listening ("" below it).
+func exposureLevel(loudSec float64, cfg ExposureConfig) string {
+	budget := cfg.DailyBudgetMin * 60
+	switch {
+	case loudSec >= budget:
+		return ExposureExceeded
+	case cfg.WarnAtPercent > 0 && loudSec >= budget*cfg.WarnAtPercent/100:
+		return ExposureApproaching
+	}
+	return ""
+}
+
+// checkExposure warns about and, with auto_dim, limits today's loud listening
+// (st.mu held).
+func (st *listeningStats) checkExposure(now time.Time) {
+	x := st.cfg.Exposure
+	if !x.Enabled {
+		return
+	}
+	day := st.today(now)
+	if day == nil {
+		return
+	}
+	level := exposureLevel(day.LoudSec, x)
+	if level == "" {
+		return
+	}
+
+	if level != day.ExposureWarning {
+		day.ExposureWarning, st.dirty = level, true
+		w := ExposureWarning{
+			Level:       level,
+			LoudSec:     math.Round(day.LoudSec),
+			BudgetSec:   x.DailyBudgetMin * 60,
+			ThresholdDB: st.cfg.LoudThresholdDB,
+		}
+		if x.AutoDim {
+			w.DimToDB = &x.DimToDB
+		}
+		st.logger.Warn("loud listening budget "+level, "loud_minutes", math.Round(day.LoudSec/60),
+			"budget_minutes", x.DailyBudgetMin, "threshold_db", st.cfg.LoudThresholdDB)
+		st.emit(w)
+	}
+
+	if level != ExposureExceeded || !x.AutoDim {
+		return
+	}
+	for id, z := range st.zones {
+		if !z.listening() || z.volumeDB < st.cfg.LoudThresholdDB || now.Sub(z.dimmedAt) < exposureDimRetry {
+			continue
+		}
+		z.dimmedAt = now
+		st.logger.Info("dimming zone over loud listening budget", "zone", id, "from_db", z.volumeDB, "to_db", x.DimToDB)
+		var ev Event = SetVolumeAbsolute{Db: x.DimToDB, Origin: "exposure"}
+		if id != "" {
+			ev = ZonedEvent{Zone: id, Event: ev}
+		}
+		st.emit(ev)
+	}
+}
+
+// today returns the record for now's local date, or nil before any listening.
+func (st *listeningStats) today(now time.Time) *statsDay {
+	if n := len(st.data.Days); n > 0 && st.data.Days[n-1].Date == now.Format(statsDateLayout) {
+		return st.data.Days[n-1]
+	}
+	return nil
+}
+
+func (st *listeningStats) emit(ev Event) {
+	if st.events == nil {
+		return
+	}
+	select {
+	case st.events <- ev:
+	default:
+		st.logger.Warn("event queue full, dropping exposure event")
+	}
+}
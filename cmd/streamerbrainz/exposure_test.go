@@ -0,0 +1,113 @@
+package main
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestExposureLevel(t *testing.T) {
+	cfg := ExposureConfig{DailyBudgetMin: 60, WarnAtPercent: 80}
+	for _, c := range []struct {
+		loudSec float64
+		want    string
+	}{
+		{0, ""},
+		{47 * 60, ""},
+		{48 * 60, ExposureApproaching},
+		{60 * 60, ExposureExceeded},
+	} {
+		if got := exposureLevel(c.loudSec, cfg); got != c.want {
+			t.Errorf("loud %vs: level %q, want %q", c.loudSec, got, c.want)
+		}
+	}
+	cfg.WarnAtPercent = 0
+	if got := exposureLevel(59*60, cfg); got != "" {
+		t.Errorf("warn_at_percent 0: level %q", got)
+	}
+}
+
+func TestListeningStats_ExposureWarnsAndDims(t *testing.T) {
+	events := make(chan Event, 16)
+	clock := &fakeClock{t: time.Date(2026, 3, 2, 20, 0, 0, 0, time.Local)}
+	cfg := StatsConfig{LoudThresholdDB: -10, Exposure: ExposureConfig{
+		Enabled: true, DailyBudgetMin: 10, WarnAtPercent: 50, AutoDim: true, DimToDB: -20,
+	}}
+	st := newListeningStats(cfg, events, slog.New(slog.DiscardHandler))
+	st.now = clock.now
+	next := func() Event {
+		t.Helper()
+		select {
+		case ev := <-events:
+			return ev
+		default:
+			return nil
+		}
+	}
+
+	st.observe(zoneBroadcast(BroadcastVolumeChanged{VolumeDB: -5}))
+	st.observe(zoneBroadcast(BroadcastPlayerChanged{Source: "plex", State: "playing", Title: "Loud"}))
+	clock.advance(5 * time.Minute)
+	st.flush()
+	if w, ok := next().(ExposureWarning); !ok || w.Level != ExposureApproaching || w.LoudSec != 300 || w.BudgetSec != 600 || *w.DimToDB != -20 {
+		t.Fatalf("expected an approaching warning, got %+v", w)
+	}
+	clock.advance(time.Minute)
+	st.flush()
+	if ev := next(); ev != nil {
+		t.Fatalf("warned twice: %+v", ev)
+	}
+
+	clock.advance(4 * time.Minute)
+	st.flush()
+	if w, ok := next().(ExposureWarning); !ok || w.Level != ExposureExceeded {
+		t.Fatalf("expected an exceeded warning, got %+v", w)
+	}
+	dim, ok := next().(ZonedEvent)
+	if !ok || dim.Zone != "main" || dim.Event != (SetVolumeAbsolute{Db: -20, Origin: "exposure"}) {
+		t.Fatalf("expected the zone to be dimmed, got %+v", dim)
+	}
+
+	// Not dimmed again until the first dim had time to apply...
+	clock.advance(time.Second)
+	st.observe(zoneBroadcast(BroadcastMuteChanged{Muted: false}))
+	if ev := next(); ev != nil {
+		t.Fatalf("dimmed again too soon: %+v", ev)
+	}
+	// ...or once the zone is below the threshold...
+	st.observe(zoneBroadcast(BroadcastVolumeChanged{VolumeDB: -20}))
+	clock.advance(time.Minute)
+	st.flush()
+	if ev := next(); ev != nil {
+		t.Fatalf("quiet zone dimmed: %+v", ev)
+	}
+	// ...but turning it back up is dimmed again.
+	st.observe(zoneBroadcast(BroadcastVolumeChanged{VolumeDB: -8}))
+	if _, ok := next().(ZonedEvent); !ok {
+		t.Fatal("expected a second dim")
+	}
+
+	// The day records the level announced, so a restart doesn't warn again.
+	st.mu.Lock()
+	if r := st.report(clock.now(), 1); r.Days[0].ExposureWarning != ExposureExceeded || r.ExposureBudgetSec != 600 {
+		t.Fatalf("report = %+v", r)
+	}
+	st.mu.Unlock()
+}
+
+func TestConfigValidate_Exposure(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Stats.Exposure.Enabled = true
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected error without stats.enabled")
+	}
+	cfg.Stats.Enabled = true
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg.Stats.Exposure.AutoDim = true
+	cfg.Stats.Exposure.DimToDB = -5
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected error for dim_to_db above loud_threshold_db")
+	}
+}
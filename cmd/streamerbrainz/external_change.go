@@ -0,0 +1,71 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// ============================================================================
+// External changes during holds and ramps
+// ============================================================================
+// While a hold or ramp is moving the volume, the reducer drives it from its own
+// controller and every SetVolume overwrites whatever CamillaDSP had. Someone
+// turning the volume in the CamillaDSP GUI (or any other client) meanwhile
+// would be fought back on the next tick.
+//
+// So while moving, the reducer reads the volume back (GetVolume) every
+// externalCheckInterval. A reading more than velocity.external_change_db away
+// from the last level it set can only come from someone else. The reducer then
+//
+//   - cancels the hold or ramp (a hold stays cancelled until its key is
+//     released, like a hold consumed by the muted-gesture policy),
+//   - drops any pending SetVolume and adopts the new level, and
+//   - broadcasts volume_changed with origin "external" and external_change set.
+//
+// SetVolume confirmations (CamillaVolumeObserved.Confirmed) echo what was sent
+// and are never treated as external.
+// ============================================================================
+
+// externalCheckInterval is how often the volume is read back while moving.
+const externalCheckInterval = time.Second
+
+// externalCheck returns a GetVolume read-back when one is due at now. The
+// first check comes externalCheckInterval after the movement starts.
+func externalCheck(s *DaemonState, now time.Time, cfg VelocityConfig) []Command {
+	if cfg.ExternalChangeDB <= 0 || !s.VolumeCtrl.moving() || s.Camilla.Unreachable {
+		s.Camilla.VerifyAt = time.Time{}
+		return nil
+	}
+	if s.Camilla.VerifyAt.IsZero() {
+		s.Camilla.VerifyAt = now
+		return nil
+	}
+	if now.Sub(s.Camilla.VerifyAt) < externalCheckInterval {
+		return nil
+	}
+	s.Camilla.VerifyAt = now
+	return []Command{CmdGetVolume{}}
+}
+
+// isExternalChange reports whether a volume reading taken while moving is far
+// enough from the last level the daemon set (prevDB) to be someone else's.
+func isExternalChange(s *DaemonState, ev CamillaVolumeObserved, prevKnown bool, prevDB float64, cfg VelocityConfig) bool {
+	return cfg.ExternalChangeDB > 0 && !ev.Confirmed && prevKnown && s.VolumeCtrl.moving() &&
+		math.Abs(ev.VolumeDB-prevDB) > cfg.ExternalChangeDB
+}
+
+// yieldToExternalChange stops the current hold or ramp so the controller
+// adopts the externally set level instead of overwriting it.
+func yieldToExternalChange(s *DaemonState, at time.Time) {
+	if s.VolumeCtrl.HeldDirection != 0 {
+		s.VolumeCtrl.SuppressedDirection = s.VolumeCtrl.HeldDirection
+	}
+	s.VolumeCtrl.HeldDirection = 0
+	s.VolumeCtrl.HoldBeganAt = time.Time{}
+	s.VolumeCtrl.VelocityDBPerS = 0
+	s.VolumeCtrl.Ramping = false
+	s.VolumeCtrl.RampRateDBPerS = 0
+	s.Intent.DesiredVolume = nil
+	s.Camilla.VerifyAt = time.Time{}
+	s.noteVolumeOrigin(volumeOriginExternal, at)
+}
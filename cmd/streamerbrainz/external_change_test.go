@@ -0,0 +1,119 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// moveTicks runs ticks of 100 ms from t0 for d, confirming every SetVolume, and
+// returns the commands emitted.
+func moveTicks(s *DaemonState, t0 time.Time, d time.Duration, cfg VelocityConfig) []Command {
+	var cmds []Command
+	for el := time.Duration(0); el < d; el += 100 * time.Millisecond {
+		rr := Reduce(s, Tick{Now: t0.Add(el), Dt: 0.1}, cfg, RotaryConfig{}, ReducerPolicy{})
+		for _, c := range rr.Commands {
+			cmds = append(cmds, c)
+			if v, ok := c.(CmdSetVolume); ok {
+				Reduce(s, CamillaVolumeObserved{VolumeDB: v.TargetDB, Confirmed: true, At: t0.Add(el)}, cfg, RotaryConfig{}, ReducerPolicy{})
+			}
+		}
+	}
+	return cmds
+}
+
+func countCommands[T Command](cmds []Command) int {
+	n := 0
+	for _, c := range cmds {
+		if _, ok := c.(T); ok {
+			n++
+		}
+	}
+	return n
+}
+
+func TestReduce_ExternalChangeCancelsHold(t *testing.T) {
+	cfg := VelocityConfig{Mode: VelocityModeConstant, VelMaxDBPerS: 5, AccelTime: 1, MinDB: -80, MaxDB: 0, ExternalChangeDB: 1}
+	t0 := time.Unix(1000, 0).UTC()
+	s := &DaemonState{}
+	s.SetObservedVolume(-40, t0)
+	Reduce(s, TimedEvent{At: t0, Event: VolumeHeld{Direction: 1}}, cfg, RotaryConfig{}, ReducerPolicy{})
+
+	cmds := moveTicks(s, t0, 1500*time.Millisecond, cfg)
+	if n := countCommands[CmdGetVolume](cmds); n != 1 {
+		t.Fatalf("expected one read-back in 1.5 s, got %d: %v", n, cmds)
+	}
+	// The read-back matches what was set: keep going.
+	before := s.Camilla.VolumeMB.DB()
+	rr := Reduce(s, CamillaVolumeObserved{VolumeDB: before + 0.5, At: t0.Add(1500 * time.Millisecond)}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if !rr.State.VolumeCtrl.moving() {
+		t.Fatal("small read-back difference cancelled the hold")
+	}
+
+	// Someone set -20 dB in the CamillaDSP GUI.
+	at := t0.Add(1600 * time.Millisecond)
+	rr = Reduce(s, CamillaVolumeObserved{VolumeDB: -20, At: at}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if rr.State.VolumeCtrl.moving() || rr.State.VolumeCtrl.SuppressedDirection != 1 || rr.State.Intent.DesiredVolume != nil {
+		t.Fatalf("hold not cancelled: %+v", rr.State.VolumeCtrl)
+	}
+	var vc *BroadcastVolumeChanged
+	for _, b := range rr.Broadcasts {
+		if v, ok := b.(BroadcastVolumeChanged); ok {
+			vc = &v
+		}
+	}
+	if vc == nil || !vc.ExternalChange || vc.Origin != volumeOriginExternal || vc.VolumeDB != -20 {
+		t.Fatalf("volume_changed = %+v", vc)
+	}
+
+	// Repeats of the same hold don't resume it; the level stays where it was put.
+	Reduce(s, TimedEvent{At: at.Add(100 * time.Millisecond), Event: VolumeHeld{Direction: 1}}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if cmds := moveTicks(s, at.Add(100*time.Millisecond), 500*time.Millisecond, cfg); countCommands[CmdSetVolume](cmds) != 0 {
+		t.Fatalf("fought the external change: %v", cmds)
+	}
+
+	// A new press starts from the adopted level.
+	Reduce(s, TimedEvent{At: at.Add(time.Second), Event: VolumeRelease{}}, cfg, RotaryConfig{}, ReducerPolicy{})
+	Reduce(s, TimedEvent{At: at.Add(1100 * time.Millisecond), Event: VolumeHeld{Direction: 1}}, cfg, RotaryConfig{}, ReducerPolicy{})
+	cmds = moveTicks(s, at.Add(1100*time.Millisecond), 300*time.Millisecond, cfg)
+	if countCommands[CmdSetVolume](cmds) == 0 || s.Camilla.VolumeMB.DB() < -20 {
+		t.Fatalf("new hold didn't continue from -20 dB: %v", cmds)
+	}
+}
+
+func TestReduce_ExternalChangeCancelsRamp(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0, RampDBPerS: 10, ExternalChangeDB: 1}
+	t0 := time.Unix(1000, 0).UTC()
+	s := &DaemonState{}
+	s.SetObservedVolume(-60, t0)
+	Reduce(s, TimedEvent{At: t0, Event: SetVolumeAbsolute{Db: -10, Origin: "webui"}}, cfg, RotaryConfig{}, ReducerPolicy{})
+	moveTicks(s, t0, 1200*time.Millisecond, cfg)
+
+	// A confirmation is never external, however far it is.
+	Reduce(s, CamillaVolumeObserved{VolumeDB: -30, Confirmed: true, At: t0.Add(1200 * time.Millisecond)}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if !s.VolumeCtrl.Ramping {
+		t.Fatal("confirmation cancelled the ramp")
+	}
+
+	rr := Reduce(s, CamillaVolumeObserved{VolumeDB: -70, At: t0.Add(1300 * time.Millisecond)}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if rr.State.VolumeCtrl.Ramping || rr.State.VolumeCtrl.TargetDB != -70 {
+		t.Fatalf("ramp not cancelled: %+v", rr.State.VolumeCtrl)
+	}
+	if cmds := moveTicks(s, t0.Add(1400*time.Millisecond), time.Second, cfg); len(cmds) != 0 {
+		t.Fatalf("commands after the external change: %v", cmds)
+	}
+}
+
+func TestReduce_ExternalChangeDisabled(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0, RampDBPerS: 10}
+	t0 := time.Unix(1000, 0).UTC()
+	s := &DaemonState{}
+	s.SetObservedVolume(-60, t0)
+	Reduce(s, TimedEvent{At: t0, Event: SetVolumeAbsolute{Db: -10}}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if cmds := moveTicks(s, t0, 2*time.Second, cfg); countCommands[CmdGetVolume](cmds) != 0 {
+		t.Fatalf("read back while disabled: %v", cmds)
+	}
+	Reduce(s, CamillaVolumeObserved{VolumeDB: -70, At: t0.Add(2 * time.Second)}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if !s.VolumeCtrl.Ramping {
+		t.Fatal("ramp cancelled while disabled")
+	}
+}
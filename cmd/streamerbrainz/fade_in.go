@@ -0,0 +1,42 @@
+package main
+
+import "time"
+
+// ============================================================================
+// Fade-in on startup / CamillaDSP reconnect (fade_in)
+// ============================================================================
+// CamillaDSP keeps its own volume, so after the daemon starts, or after
+// CamillaDSP comes back from a crash, playback would continue at whatever level
+// it restored. With fade_in.enabled, the first volume observation after startup
+// (and, with fade_in.on_reconnect, after an outage) instead drops the volume to
+// min_db and ramps back up to that level over fade_in.duration_ms, like an
+// absolute set (origin "fade_in"; still slowed through the danger zone). Any
+// volume gesture during the fade takes over from it.
+// ============================================================================
+
+// fadeInMinSpanDB skips fades that would hardly be audible.
+const fadeInMinSpanDB = 1.0
+
+// startFadeIn starts a pending fade toward the observed volume.
+func startFadeIn(s *DaemonState, at time.Time, cfg VelocityConfig, policy ReducerPolicy) {
+	if !s.FadeInPending || !s.Camilla.VolumeKnown {
+		return
+	}
+	s.FadeInPending = false
+
+	// Leave volume changes already under way, and calibration's reference level, alone.
+	target := s.Camilla.VolumeMB.DB()
+	ctrl := &s.VolumeCtrl
+	if ctrl.HeldDirection != 0 || ctrl.Ramping || s.Intent.DesiredVolume != nil || s.Calibration.Active ||
+		target-cfg.MinDB < fadeInMinSpanDB {
+		return
+	}
+
+	ctrl.VelocityDBPerS = 0
+	ctrl.TargetDB = cfg.MinDB
+	ctrl.Ramping = true
+	ctrl.RampTarget = s.Camilla.VolumeMB
+	ctrl.RampRateDBPerS = (target - cfg.MinDB) / policy.FadeIn.Seconds()
+	s.SetDesiredVolume(cfg.MinDB)
+	s.noteVolumeOrigin("fade_in", at)
+}
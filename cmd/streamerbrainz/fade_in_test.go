@@ -0,0 +1,117 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// fadeTicks runs ticks of dt seconds for d, returning the volumes set.
+func fadeTicks(s *DaemonState, t0 time.Time, d time.Duration, dt float64, cfg VelocityConfig) []float64 {
+	var set []float64
+	for el := time.Duration(0); el < d; el += time.Duration(dt * float64(time.Second)) {
+		rr := Reduce(s, Tick{Now: t0.Add(el), Dt: dt}, cfg, RotaryConfig{}, ReducerPolicy{})
+		for _, c := range rr.Commands {
+			if v, ok := c.(CmdSetVolume); ok {
+				set = append(set, v.TargetDB)
+				// CamillaDSP applies it.
+				Reduce(s, CamillaVolumeObserved{VolumeDB: v.TargetDB, At: t0.Add(el)}, cfg, RotaryConfig{}, ReducerPolicy{})
+			}
+		}
+	}
+	return set
+}
+
+func TestReduce_FadeInOnStartup(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0}
+	policy := ReducerPolicy{FadeIn: 2 * time.Second}
+	t0 := time.Unix(1000, 0).UTC()
+	rr := Reduce(&DaemonState{}, DaemonStarted{}, cfg, RotaryConfig{}, policy)
+	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: -20, At: t0}, cfg, RotaryConfig{}, policy)
+	if !rr.State.VolumeCtrl.Ramping || rr.State.FadeInPending {
+		t.Fatal("expected a fade to start on the first volume observation")
+	}
+
+	set := fadeTicks(rr.State, t0, time.Second, 0.1, cfg)
+	if len(set) == 0 || set[0] > -70 {
+		t.Fatalf("expected the fade to start near min_db, got %v", set)
+	}
+	if mid := set[len(set)-1]; mid < -55 || mid > -45 {
+		t.Fatalf("expected about -50 dB halfway, got %v", mid)
+	}
+	set = fadeTicks(rr.State, t0.Add(time.Second), 1500*time.Millisecond, 0.1, cfg)
+	if set[len(set)-1] != -20 || rr.State.VolumeCtrl.Ramping || rr.State.VolumeCtrl.RampRateDBPerS != 0 {
+		t.Fatalf("expected the fade to end at -20 dB, got %v", set)
+	}
+
+	// Later observations are adopted as usual.
+	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: -30, At: t0.Add(5 * time.Second)}, cfg, RotaryConfig{}, policy)
+	if rr.State.VolumeCtrl.Ramping {
+		t.Fatal("faded in again")
+	}
+}
+
+func TestReduce_FadeInSkipped(t *testing.T) {
+	t0 := time.Unix(1000, 0).UTC()
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0}
+	observe := func(policy ReducerPolicy, db float64) *DaemonState {
+		rr := Reduce(&DaemonState{}, DaemonStarted{}, cfg, RotaryConfig{}, policy)
+		return Reduce(rr.State, CamillaVolumeObserved{VolumeDB: db, At: t0}, cfg, RotaryConfig{}, policy).State
+	}
+	if s := observe(ReducerPolicy{}, -20); s.VolumeCtrl.Ramping {
+		t.Fatal("faded in while disabled")
+	}
+	if s := observe(ReducerPolicy{FadeIn: time.Second}, -79.5); s.VolumeCtrl.Ramping {
+		t.Fatal("faded in at min_db")
+	}
+}
+
+func TestReduce_FadeInOnReconnect(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0}
+	policy := ReducerPolicy{FadeIn: time.Second, FadeInOnReconnect: true}
+	t0 := time.Unix(1000, 0).UTC()
+	s := &DaemonState{}
+	s.SetObservedVolume(-20, t0)
+	rr := Reduce(s, CamillaCommandFailed{Command: CmdGetVolume{}, Err: errNoClient{}, At: t0}, cfg, RotaryConfig{}, policy)
+
+	// Recovery noticed through another observation asks for the volume first.
+	rr = Reduce(rr.State, CamillaMuteObserved{Muted: false, At: t0.Add(time.Second)}, cfg, RotaryConfig{}, policy)
+	found := false
+	for _, c := range rr.Commands {
+		_, found = c.(CmdGetVolume)
+		if found {
+			break
+		}
+	}
+	if !found || !rr.State.FadeInPending {
+		t.Fatalf("expected a volume read before fading, got %v", rr.Commands)
+	}
+	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: -10, At: t0.Add(time.Second)}, cfg, RotaryConfig{}, policy)
+	if !rr.State.VolumeCtrl.Ramping || rr.State.VolumeCtrl.RampTarget != mbFromDB(-10) {
+		t.Fatal("expected a fade to the restored volume")
+	}
+
+	// Without on_reconnect only startup fades.
+	policy.FadeInOnReconnect = false
+	s = &DaemonState{}
+	s.SetObservedVolume(-20, t0)
+	rr = Reduce(s, CamillaCommandFailed{Command: CmdGetVolume{}, Err: errNoClient{}, At: t0}, cfg, RotaryConfig{}, policy)
+	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: -10, At: t0.Add(time.Second)}, cfg, RotaryConfig{}, policy)
+	if rr.State.VolumeCtrl.Ramping {
+		t.Fatal("faded in on reconnect without on_reconnect")
+	}
+}
+
+func TestConfigFadeIn(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.ToReducerPolicy().FadeIn != 0 {
+		t.Fatal("fade-in enabled by default")
+	}
+	cfg.FadeIn.Enabled = true
+	if v := cfg.ToReducerPolicy(); v.FadeIn != 3*time.Second || !v.FadeInOnReconnect {
+		t.Fatalf("reducer policy = %v, %v", v.FadeIn, v.FadeInOnReconnect)
+	}
+	cfg.FadeIn.DurationMS = 0
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected error for duration_ms 0")
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// Health
+// ============================================================================
+// GET /healthz on the control API listener reports daemon liveness and input
+// device status. It answers 200 with status "ok" when every input device is up,
+// 200 with "degraded" when some device is down (still reconnecting), and 503
+// when the daemon does not answer a state snapshot request in time.
+//
+// GET /readyz reports readiness: 200 "ready" once every zone's CamillaDSP
+// answers and its volume is known, else 503 "not_ready" naming the zones that
+// aren't (the daemon starts before CamillaDSP is reachable). `streamerbrainz
+// ctl ready` checks it from the command line, e.g. as a container healthcheck.
+// ============================================================================
+
+// healthTimeout bounds how long /healthz waits for a state snapshot.
+const healthTimeout = 1 * time.Second
+
+// healthResponse is the JSON body of GET /healthz.
+type healthResponse struct {
+	Status string              `json:"status"`
+	Inputs []InputDeviceStatus `json:"inputs,omitempty"`
+}
+
+// healthHandler serves GET /healthz using snapshots requested through events.
+type healthHandler struct {
+	events chan<- Event
+	logger *slog.Logger
+}
+
+func (h *healthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	snap, err := requestStateSnapshot(r.Context(), h.events, healthTimeout)
+	if err != nil {
+		if r.Context().Err() == nil {
+			h.unavailable(w)
+		}
+		return
+	}
+
+	resp := healthResponse{Status: "ok", Inputs: snap.Inputs}
+	for _, in := range snap.Inputs {
+		if !in.Up {
+			resp.Status = "degraded"
+			break
+		}
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// requestStateSnapshot asks the daemon for an aggregated snapshot and waits up to timeout.
+func requestStateSnapshot(ctx context.Context, events chan<- Event, timeout time.Duration) (StateSnapshot, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	reply := make(chan StateSnapshot, 1)
+	select {
+	case events <- RequestStateSnapshot{Reply: reply}:
+	case <-ctx.Done():
+		return StateSnapshot{}, ctx.Err()
+	}
+	select {
+	case snap := <-reply:
+		return snap, nil
+	case <-ctx.Done():
+		return StateSnapshot{}, ctx.Err()
+	}
+}
+
+func (h *healthHandler) unavailable(w http.ResponseWriter) {
+	h.logger.Warn("health check: daemon did not answer snapshot request")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	_ = json.NewEncoder(w).Encode(healthResponse{Status: "unavailable"})
+}
+
+// readyResponse is the JSON body of GET /readyz.
+type readyResponse struct {
+	Status   string   `json:"status"`
+	NotReady []string `json:"not_ready,omitempty"` // zones without a reachable CamillaDSP
+}
+
+// readyHandler serves GET /readyz using snapshots requested through events.
+type readyHandler struct {
+	events chan<- Event
+	logger *slog.Logger
+}
+
+func (h *readyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	snap, err := requestStateSnapshot(r.Context(), h.events, healthTimeout)
+	if err != nil {
+		if r.Context().Err() == nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(readyResponse{Status: "unavailable"})
+		}
+		return
+	}
+
+	resp := readiness(snap)
+	if resp.Status != "ready" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// readiness checks every zone of an aggregated snapshot.
+func readiness(snap StateSnapshot) readyResponse {
+	zones := snap.Zones
+	if len(zones) == 0 {
+		zones = []StateSnapshot{snap}
+	}
+	resp := readyResponse{Status: "ready"}
+	for _, z := range zones {
+		if z.DSPUnreachable || !z.VolumeKnown {
+			resp.NotReady = append(resp.NotReady, z.Zone)
+		}
+	}
+	if len(resp.NotReady) > 0 {
+		resp.Status = "not_ready"
+	}
+	return resp
+}
+
+// ctlReady checks GET /readyz on the daemon at baseURL (`streamerbrainz ctl ready`).
+func ctlReady(baseURL string, w io.Writer) error {
+	client := &http.Client{Timeout: 2 * healthTimeout}
+	resp, err := client.Get(baseURL + "/readyz")
+	if err != nil {
+		return fmt.Errorf("check readiness (is the daemon running?): %w", err)
+	}
+	defer resp.Body.Close()
+	var body readyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("readyz: %s", resp.Status)
+	}
+	fmt.Fprintln(w, body.Status)
+	if resp.StatusCode != http.StatusOK {
+		if len(body.NotReady) > 0 {
+			return fmt.Errorf("not ready: %s", strings.Join(body.NotReady, ", "))
+		}
+		return fmt.Errorf("not ready: %s", body.Status)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthHandler_ReportsDegradedInputs(t *testing.T) {
+	events := make(chan Event, 1)
+	go func() {
+		req := (<-events).(RequestStateSnapshot)
+		req.Reply <- StateSnapshot{Inputs: []InputDeviceStatus{
+			{Device: "/dev/input/event0", Up: true},
+			{Device: "/dev/input/event1", Reason: "EOF"},
+		}}
+	}()
+
+	rec := httptest.NewRecorder()
+	(&healthHandler{events: events, logger: slog.Default()}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var resp healthResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != "degraded" || len(resp.Inputs) != 2 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+}
+
+func TestReadyHandler_NamesUnreachableZones(t *testing.T) {
+	events := make(chan Event, 1)
+	go func() {
+		req := (<-events).(RequestStateSnapshot)
+		req.Reply <- StateSnapshot{Zones: []StateSnapshot{
+			{Zone: "living", VolumeKnown: true},
+			{Zone: "office", VolumeKnown: true, DSPUnreachable: true},
+			{Zone: "kitchen"},
+		}}
+	}()
+
+	rec := httptest.NewRecorder()
+	(&readyHandler{events: events, logger: slog.Default()}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", rec.Code)
+	}
+	var resp readyResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != "not_ready" || len(resp.NotReady) != 2 || resp.NotReady[0] != "office" || resp.NotReady[1] != "kitchen" {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+
+	if r := readiness(StateSnapshot{Zone: "main", VolumeKnown: true}); r.Status != "ready" {
+		t.Fatalf("single zone: %+v", r)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import "time"
+
+// ============================================================================
+// Hold-to-max guard
+// ============================================================================
+// The danger zone only slows a hold down near MaxDB; a key wedged down (or a
+// remote left on the sofa cushion) still gets there. With
+// velocity.hold_checkpoint_db set, an upward hold that starts below the
+// checkpoint stops at it. Going further takes releasing the key and pressing
+// it again: a hold that starts at or above the checkpoint is not stopped.
+//
+// Only holds are guarded; rotary turns, steps and absolute sets are deliberate
+// per-event changes and keep their usual limits.
+// ============================================================================
+
+// holdCheckpointEpsilon tolerates rounding of the observed level, so a hold
+// that stopped at the checkpoint counts as starting at it when re-pressed.
+const holdCheckpointEpsilon = 0.05
+
+// BroadcastHoldCheckpoint is emitted when a hold stops at the checkpoint.
+type BroadcastHoldCheckpoint struct {
+	LevelDB float64   `json:"level_db"`
+	At      time.Time `json:"at"`
+}
+
+func (BroadcastHoldCheckpoint) stateBroadcastMarker() {}
+
+// armHoldCheckpoint decides, at the start of a hold gesture in direction, whether
+// the hold must stop at the configured checkpoint.
+func armHoldCheckpoint(s *DaemonState, direction int, cfg VelocityConfig) {
+	s.VolumeCtrl.CheckpointArmed = false
+	s.VolumeCtrl.CheckpointHit = false
+	if direction <= 0 || cfg.HoldCheckpointDB == nil {
+		return
+	}
+
+	current := s.currentVolumeDB()
+	if cp := *cfg.HoldCheckpointDB; current < cp-holdCheckpointEpsilon {
+		s.VolumeCtrl.CheckpointArmed = true
+		s.VolumeCtrl.CheckpointDB = cp
+	}
+}
+
+// applyHoldCheckpoint stops an armed upward hold at its checkpoint. It returns the
+// broadcast for the first time the hold gets there.
+func applyHoldCheckpoint(ctrl *VolumeControllerState, at time.Time) []StateBroadcast {
+	if !ctrl.CheckpointArmed || ctrl.HeldDirection <= 0 || ctrl.TargetDB < ctrl.CheckpointDB {
+		return nil
+	}
+	ctrl.TargetDB = ctrl.CheckpointDB
+	ctrl.VelocityDBPerS = 0
+	if ctrl.CheckpointHit {
+		return nil
+	}
+	ctrl.CheckpointHit = true
+	return []StateBroadcast{BroadcastHoldCheckpoint{LevelDB: ctrl.CheckpointDB, At: at}}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReduce_HoldStopsAtCheckpointUntilRepressed(t *testing.T) {
+	cp := -10.0
+	cfg := VelocityConfig{Mode: VelocityModeConstant, VelMaxDBPerS: 20, MinDB: -80, MaxDB: 0, HoldCheckpointDB: &cp}
+	t0 := time.Unix(1000, 0).UTC()
+	s := &DaemonState{}
+	s.SetObservedVolume(-12, t0)
+
+	hold := func(s *DaemonState, start time.Time) (*DaemonState, int) {
+		t.Helper()
+		rr := Reduce(s, TimedEvent{At: start, Event: VolumeHeld{Direction: 1}}, cfg, RotaryConfig{}, ReducerPolicy{})
+		var hits int
+		for i := 1; i <= 10; i++ {
+			now := start.Add(time.Duration(i) * 100 * time.Millisecond)
+			rr = Reduce(rr.State, TimedEvent{At: now, Event: VolumeHeld{Direction: 1}}, cfg, RotaryConfig{}, ReducerPolicy{})
+			rr = Reduce(rr.State, Tick{Now: now, Dt: 0.1}, cfg, RotaryConfig{}, ReducerPolicy{})
+			for _, b := range rr.Broadcasts {
+				if _, ok := b.(BroadcastHoldCheckpoint); ok {
+					hits++
+				}
+			}
+			for _, c := range rr.Commands {
+				if c, ok := c.(CmdSetVolume); ok {
+					rr.State.SetObservedVolume(c.TargetDB, now)
+				}
+			}
+		}
+		rr = Reduce(rr.State, TimedEvent{At: start.Add(1100 * time.Millisecond), Event: VolumeRelease{}}, cfg, RotaryConfig{}, ReducerPolicy{})
+		return rr.State, hits
+	}
+
+	s, hits := hold(s, t0)
+	if s.Camilla.VolumeMB.DB() != cp || hits != 1 {
+		t.Fatalf("first hold ended at %v with %d checkpoint broadcasts", s.Camilla.VolumeMB.DB(), hits)
+	}
+
+	// Pressed again at the checkpoint: the hold continues into the danger zone.
+	s, hits = hold(s, t0.Add(2*time.Second))
+	if s.Camilla.VolumeMB.DB() <= cp || hits != 0 {
+		t.Fatalf("second hold ended at %v with %d checkpoint broadcasts", s.Camilla.VolumeMB.DB(), hits)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// CORS (api.cors)
+// ============================================================================
+// Lets a separately hosted dashboard (e.g. a Home Assistant card) call the REST
+// and SSE endpoints from the browser. Requests whose Origin is in
+// allowed_origins get the Access-Control-Allow-* headers; preflight (OPTIONS)
+// requests are answered here and never reach the endpoints. Other origins get
+// no CORS headers, so the browser blocks the response. WebSockets don't use CORS
+// (see websocket.allowed_origins).
+// ============================================================================
+
+var (
+	defaultCORSMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut}
+	defaultCORSHeaders = []string{"Content-Type", "Authorization", "X-StreamerBrainz-Token"}
+)
+
+type corsHandler struct {
+	cfg     CORSConfig
+	methods []string
+	headers string
+	next    http.Handler
+}
+
+// newCORSHandler wraps h with cfg's CORS policy (h itself if no origins are allowed).
+func newCORSHandler(cfg CORSConfig, h http.Handler) http.Handler {
+	if len(cfg.AllowedOrigins) == 0 {
+		return h
+	}
+	methods, headers := cfg.AllowedMethods, cfg.AllowedHeaders
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	if len(headers) == 0 {
+		headers = defaultCORSHeaders
+	}
+	return &corsHandler{
+		cfg:     cfg,
+		methods: methods,
+		headers: strings.Join(headers, ", "),
+		next:    h,
+	}
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for origin ("" if not allowed).
+func (c *corsHandler) allowOrigin(origin string) string {
+	for _, o := range c.cfg.AllowedOrigins {
+		if o == "*" {
+			return "*"
+		}
+		if strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+func (c *corsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		c.next.ServeHTTP(w, r)
+		return
+	}
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	allowed := c.allowOrigin(origin)
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+	if allowed == "" {
+		if preflight {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		c.next.ServeHTTP(w, r)
+		return
+	}
+
+	h.Set("Access-Control-Allow-Origin", allowed)
+	if c.cfg.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if !preflight {
+		c.next.ServeHTTP(w, r)
+		return
+	}
+
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+	method := r.Header.Get("Access-Control-Request-Method")
+	if slices.ContainsFunc(c.methods, func(m string) bool { return strings.EqualFold(m, method) }) {
+		h.Set("Access-Control-Allow-Methods", strings.Join(c.methods, ", "))
+		h.Set("Access-Control-Allow-Headers", c.headers)
+		if c.cfg.MaxAgeSec > 0 {
+			h.Set("Access-Control-Max-Age", strconv.Itoa(c.cfg.MaxAgeSec))
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORSHandler(t *testing.T) {
+	reached := 0
+	h := newCORSHandler(CORSConfig{
+		AllowedOrigins:   []string{"http://ha.local:8123/"},
+		AllowCredentials: true,
+		MaxAgeSec:        600,
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { reached++ }))
+
+	// Preflight from an allowed origin is answered without reaching the endpoint.
+	req := httptest.NewRequest(http.MethodOptions, "/jsonrpc", nil)
+	req.Header.Set("Origin", "http://ha.local:8123")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	hdr := rec.Header()
+	if rec.Code != http.StatusNoContent || reached != 0 {
+		t.Fatalf("preflight: got %d (reached %d)", rec.Code, reached)
+	}
+	if hdr.Get("Access-Control-Allow-Origin") != "http://ha.local:8123" || hdr.Get("Access-Control-Allow-Credentials") != "true" ||
+		hdr.Get("Access-Control-Allow-Methods") != "GET, POST, PUT" || hdr.Get("Access-Control-Max-Age") != "600" {
+		t.Fatalf("preflight: unexpected headers %v", hdr)
+	}
+
+	// Simple request from an allowed origin.
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/version", nil)
+	req.Header.Set("Origin", "http://ha.local:8123")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if reached != 1 || rec.Header().Get("Access-Control-Allow-Origin") != "http://ha.local:8123" {
+		t.Fatalf("simple request: reached %d, headers %v", reached, rec.Header())
+	}
+
+	// Other origins get no CORS headers.
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/version", nil)
+	req.Header.Set("Origin", "http://evil.example")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("unexpected CORS headers for a foreign origin: %v", rec.Header())
+	}
+}
+
+func TestCORSHandler_DisabledWithoutOrigins(t *testing.T) {
+	inner := http.NewServeMux()
+	if h := newCORSHandler(CORSConfig{}, inner); h != http.Handler(inner) {
+		t.Fatal("expected the handler to be returned unchanged")
+	}
+}
+
+func TestConfigValidate_CORSWildcardWithCredentials(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.API.CORS = CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected an error")
+	}
+}
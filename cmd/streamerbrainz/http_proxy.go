@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ============================================================================
+// Reverse proxy support
+// ============================================================================
+// api.base_path serves every HTTP/WS route under a prefix (e.g. "/streamer", so
+// the UI can live at https://house.example/streamer/ behind an existing proxy).
+// The prefix is stripped before routing; requests outside it get 404.
+//
+// Requests from api.trusted_proxies (IPs or CIDRs) and from the api.socket Unix
+// socket have their X-Forwarded-For / X-Forwarded-Proto / X-Forwarded-Host
+// headers applied: RemoteAddr becomes the client address (logging, per-IP
+// connection limits) and the scheme/host the browser used is what same-origin
+// checks compare against. The headers of other peers are ignored.
+// ============================================================================
+
+// proxyHandler applies base_path and trusted X-Forwarded-* headers before h.
+type proxyHandler struct {
+	basePath string // normalized: "" or "/prefix" without a trailing slash
+	trusted  []netip.Prefix
+	next     http.Handler
+}
+
+// newProxyHandler wraps h according to cfg. It returns h unchanged when neither
+// base_path nor trusted_proxies is set and the API has no Unix socket.
+func newProxyHandler(cfg APIConfig, h http.Handler) (http.Handler, error) {
+	trusted, err := parseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	p := &proxyHandler{basePath: cfg.BasePathPrefix(), trusted: trusted, next: h}
+	if p.basePath == "" && len(p.trusted) == 0 && cfg.Socket == "" {
+		return h, nil
+	}
+	return p, nil
+}
+
+// parseTrustedProxies parses IPs and CIDRs (an IP is a single-address prefix).
+func parseTrustedProxies(list []string) ([]netip.Prefix, error) {
+	var out []netip.Prefix
+	for i, s := range list {
+		s = strings.TrimSpace(s)
+		if p, err := netip.ParsePrefix(s); err == nil {
+			out = append(out, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return nil, fmt.Errorf("api.trusted_proxies[%d] %q is not an IP address or CIDR", i, s)
+		}
+		out = append(out, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return out, nil
+}
+
+func (p *proxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if p.basePath != "" {
+		switch {
+		case r.URL.Path == p.basePath:
+			target := p.basePath + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		case !strings.HasPrefix(r.URL.Path, p.basePath+"/"):
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	r2 := new(http.Request)
+	*r2 = *r
+	u := *r.URL
+	r2.URL = &u
+	if p.basePath != "" {
+		r2.URL.Path = strings.TrimPrefix(r.URL.Path, p.basePath)
+		r2.URL.RawPath = strings.TrimPrefix(r.URL.RawPath, p.basePath)
+	}
+	if p.trustedPeer(r.RemoteAddr) {
+		applyForwardedHeaders(r2, p.trusted)
+	}
+	p.next.ServeHTTP(w, r2)
+}
+
+// trustedPeer reports whether the connection comes from a trusted proxy. Unix
+// socket peers (RemoteAddr without a host:port) are trusted: reaching the socket
+// already requires filesystem access.
+func (p *proxyHandler) trustedPeer(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return true
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	return addrInPrefixes(addr.Unmap(), p.trusted)
+}
+
+func addrInPrefixes(addr netip.Addr, prefixes []netip.Prefix) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyForwardedHeaders rewrites r from the X-Forwarded-* headers of a trusted
+// proxy. The client is the rightmost X-Forwarded-For address that is not itself a
+// trusted proxy, so a client can't spoof its address by sending the header.
+func applyForwardedHeaders(r *http.Request, trusted []netip.Prefix) {
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				break
+			}
+			addr = addr.Unmap()
+			r.RemoteAddr = net.JoinHostPort(addr.String(), "0")
+			if !addrInPrefixes(addr, trusted) {
+				break
+			}
+		}
+	}
+	if proto := forwardedValue(r, "X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		r.URL.Scheme = proto
+	}
+	if host := forwardedValue(r, "X-Forwarded-Host"); host != "" {
+		r.Host = host
+	}
+}
+
+// forwardedValue returns the first (client-side) value of a comma-separated
+// X-Forwarded-* header.
+func forwardedValue(r *http.Request, name string) string {
+	v, _, _ := strings.Cut(r.Header.Get(name), ",")
+	return strings.ToLower(strings.TrimSpace(v))
+}
+
+// requestOrigin returns the Origin a browser on the same site as r would send:
+// the scheme and host it used, after proxy headers are applied.
+func requestOrigin(r *http.Request) string {
+	scheme := r.URL.Scheme
+	if scheme == "" {
+		scheme = "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+	}
+	return scheme + "://" + r.Host
+}
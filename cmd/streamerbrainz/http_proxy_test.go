@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestProxyHandler wraps a proxyHandler so the request passed to ServeHTTP is
+// replaced by the one the inner handler saw.
+func newTestProxyHandler(t *testing.T, cfg APIConfig) http.Handler {
+	t.Helper()
+	var seen *http.Request
+	h, err := newProxyHandler(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { seen = r }))
+	if err != nil {
+		t.Fatalf("newProxyHandler: %v", err)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = nil
+		h.ServeHTTP(w, r)
+		if seen != nil {
+			*r = *seen
+		}
+	})
+}
+
+func TestProxyHandler_BasePath(t *testing.T) {
+	h := newTestProxyHandler(t, APIConfig{BasePath: "/streamer/"})
+
+	req := httptest.NewRequest(http.MethodGet, "/streamer/ws/state", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || req.URL.Path != "/ws/state" {
+		t.Fatalf("expected stripped path /ws/state, got %d %q", rec.Code, req.URL.Path)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/streamer?x=1", nil))
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/streamer/?x=1" {
+		t.Fatalf("expected redirect to /streamer/?x=1, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 outside the base path, got %d", rec.Code)
+	}
+}
+
+func TestProxyHandler_ForwardedHeaders(t *testing.T) {
+	h := newTestProxyHandler(t, APIConfig{TrustedProxies: []string{"10.0.0.0/8", "192.168.1.2"}})
+
+	for _, tc := range []struct {
+		name, remote, xff, wantRemote, wantOrigin string
+	}{
+		{"trusted proxy", "192.168.1.2:5000", "203.0.113.7", "203.0.113.7:0", "https://house.example"},
+		{"spoofed hop is skipped", "192.168.1.2:5000", "198.51.100.1, 203.0.113.7, 10.1.2.3", "203.0.113.7:0", "https://house.example"},
+		{"untrusted peer", "203.0.113.9:5000", "1.2.3.4", "203.0.113.9:5000", "http://sb.local"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/ws/state", nil)
+		req.Host = "sb.local"
+		req.RemoteAddr = tc.remote
+		req.Header.Set("X-Forwarded-For", tc.xff)
+		req.Header.Set("X-Forwarded-Proto", "https")
+		req.Header.Set("X-Forwarded-Host", "house.example")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		if req.RemoteAddr != tc.wantRemote || requestOrigin(req) != tc.wantOrigin {
+			t.Errorf("%s: got remote %q origin %q, want %q %q", tc.name, req.RemoteAddr, requestOrigin(req), tc.wantRemote, tc.wantOrigin)
+		}
+	}
+}
+
+func TestCheckOrigin_SameOriginBehindProxy(t *testing.T) {
+	s := &Server{allowedOrigins: []string{"http://ui.home.arpa"}}
+	req := httptest.NewRequest(http.MethodGet, "/ws/state", nil)
+	req.Host = "house.example"
+	req.URL.Scheme = "https"
+	req.Header.Set("Origin", "https://house.example")
+	if !s.checkOrigin(req) {
+		t.Fatal("expected the proxied same-origin page to be allowed")
+	}
+	req.Header.Set("Origin", "http://house.example")
+	if s.checkOrigin(req) {
+		t.Fatal("expected a scheme mismatch to be rejected")
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	if _, err := parseTrustedProxies([]string{"127.0.0.1", "::1", "10.0.0.0/8"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := parseTrustedProxies([]string{"proxy.local"}); err == nil {
+		t.Fatal("expected an error for a host name")
+	}
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ============================================================================
+// hue control protocol
+// ============================================================================
+// Local-only voice control: emulates a (v1) Philips Hue bridge whose dimmable
+// "lights" are the voice targets, so an Echo on the same network discovers them
+// without a cloud skill ("Alexa, discover devices"):
+//
+//	"set the hi-fi to 40 percent"  brightness 40% -> set_volume_percent 40
+//	"turn off the hi-fi"           set_mute true
+//	"turn on the hi-fi"            set_mute false
+//
+// Discovery is SSDP (multicast 239.255.255.250:1900) pointing at
+// /description.xml; the bridge API (/api/...) accepts any username. Echo
+// devices only look for emulated bridges on port 80, so options.listen is
+// usually ":80". options.advertise (host:port) is the address put in the SSDP
+// LOCATION; by default the listen address, or the first non-loopback IPv4
+// address when listening on all interfaces.
+// ============================================================================
+
+func init() {
+	registerControlProtocol("hue", newHueProtocol)
+}
+
+// hueOptions are the `options` of a hue control protocol.
+type hueOptions struct {
+	Listen       string `yaml:"listen"`    // host:port for the bridge API
+	Advertise    string `yaml:"advertise"` // host:port announced via SSDP (default: derived from listen)
+	voiceOptions `yaml:",inline"`
+}
+
+const (
+	hueSSDPAddr = "239.255.255.250:1900"
+	hueMaxBri   = 254
+
+	// maxHueBody bounds a state change request.
+	maxHueBody = 4 << 10
+)
+
+type hueProtocol struct {
+	opts   hueOptions
+	logger *slog.Logger
+}
+
+func newHueProtocol(options *yaml.Node, logger *slog.Logger) (ControlProtocol, error) {
+	var opts hueOptions
+	if err := decodeControlProtocolOptions(options, &opts); err != nil {
+		return nil, fmt.Errorf("hue options: %w", err)
+	}
+	if _, _, err := net.SplitHostPort(opts.Listen); err != nil {
+		return nil, errors.New("hue options.listen must be host:port")
+	}
+	if opts.Advertise != "" {
+		if host, _, err := net.SplitHostPort(opts.Advertise); err != nil || host == "" {
+			return nil, errors.New("hue options.advertise must be host:port")
+		}
+	}
+	if err := opts.validate("hue"); err != nil {
+		return nil, err
+	}
+	return &hueProtocol{opts: opts, logger: logger}, nil
+}
+
+func (p *hueProtocol) Start(ctx context.Context, events chan<- Event) error {
+	ln, err := net.Listen("tcp", p.opts.Listen)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", p.opts.Listen, err)
+	}
+	advertise, err := hueAdvertiseAddr(p.opts.Advertise, ln.Addr().String())
+	if err != nil {
+		ln.Close()
+		return err
+	}
+	bridge := newHueBridge(p.opts.voiceOptions, advertise, events, p.logger)
+	p.logger.Info("hue bridge emulation listening", "addr", ln.Addr().String(), "advertise", advertise)
+
+	go func() {
+		if err := bridge.serveSSDP(ctx); err != nil {
+			p.logger.Warn("hue SSDP responder stopped; discovery unavailable", "error", err)
+		}
+	}()
+	return serveHTTP(ctx, "hue", ln, bridge)
+}
+
+// Notify is a no-op: the bridge is polled for state.
+func (p *hueProtocol) Notify(StateBroadcast) {}
+
+// hueAdvertiseAddr picks the host:port announced via SSDP.
+func hueAdvertiseAddr(advertise, listen string) (string, error) {
+	if advertise != "" {
+		return advertise, nil
+	}
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return "", err
+	}
+	if ip := net.ParseIP(host); ip != nil && !ip.IsUnspecified() {
+		return listen, nil
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "", fmt.Errorf("hue: list interface addresses: %w", err)
+	}
+	for _, a := range addrs {
+		if ipn, ok := a.(*net.IPNet); ok && !ipn.IP.IsLoopback() && ipn.IP.To4() != nil {
+			return net.JoinHostPort(ipn.IP.String(), port), nil
+		}
+	}
+	return "", errors.New("hue: no non-loopback IPv4 address to advertise; set options.advertise")
+}
+
+// hueBridge serves the emulated bridge.
+type hueBridge struct {
+	targets   []voiceTarget
+	advertise string
+	serial    string // 12 hex digits, derived from the target names
+	events    chan<- Event
+	logger    *slog.Logger
+}
+
+func newHueBridge(opts voiceOptions, advertise string, events chan<- Event, logger *slog.Logger) *hueBridge {
+	targets := opts.targets()
+	sum := sha1.New()
+	for _, t := range targets {
+		io.WriteString(sum, t.ID+"\x00")
+	}
+	return &hueBridge{
+		targets:   targets,
+		advertise: advertise,
+		serial:    hex.EncodeToString(sum.Sum(nil))[:12],
+		events:    events,
+		logger:    logger,
+	}
+}
+
+// hueBrightness maps a volume percent onto Hue's 1..254 brightness.
+func hueBrightness(percent int) int {
+	return max(1, int(math.Round(float64(percent)*hueMaxBri/100)))
+}
+
+// huePercent maps a Hue brightness onto a volume percent.
+func huePercent(bri int) int {
+	return int(math.Round(float64(min(max(bri, 0), hueMaxBri)) * 100 / hueMaxBri))
+}
+
+func (b *hueBridge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/description.xml" {
+		w.Header().Set("Content-Type", "text/xml")
+		_, _ = io.WriteString(w, b.description())
+		return
+	}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if parts[0] != "api" {
+		http.NotFound(w, r)
+		return
+	}
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodPost:
+		// Pairing: any username is accepted.
+		b.writeJSON(w, []any{map[string]any{"success": map[string]string{"username": "streamerbrainz"}}})
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		lights, err := b.lights(r.Context())
+		b.reply(w, map[string]any{"lights": lights}, err)
+	case len(parts) == 3 && parts[2] == "lights" && r.Method == http.MethodGet:
+		lights, err := b.lights(r.Context())
+		b.reply(w, lights, err)
+	case len(parts) == 4 && parts[2] == "lights" && r.Method == http.MethodGet:
+		t, ok := b.light(parts[3])
+		if !ok {
+			b.writeError(w, 3, "/lights/"+parts[3], "resource not available")
+			return
+		}
+		state, err := currentVoiceState(r.Context(), b.events, t)
+		b.reply(w, b.lightJSON(t, state), err)
+	case len(parts) == 5 && parts[2] == "lights" && parts[4] == "state" && r.Method == http.MethodPut:
+		b.setState(w, r, parts[3])
+	default:
+		b.writeError(w, 4, r.URL.Path, "method not available for resource")
+	}
+}
+
+// setState applies a PUT /api/<user>/lights/<id>/state.
+func (b *hueBridge) setState(w http.ResponseWriter, r *http.Request, id string) {
+	t, ok := b.light(id)
+	if !ok {
+		b.writeError(w, 3, "/lights/"+id, "resource not available")
+		return
+	}
+	var req struct {
+		On  *bool `json:"on"`
+		Bri *int  `json:"bri"`
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxHueBody))
+	if err != nil || json.Unmarshal(body, &req) != nil {
+		b.writeError(w, 2, "/lights/"+id+"/state", "body contains invalid json")
+		return
+	}
+
+	prefix := "/lights/" + id + "/state/"
+	var evs []Event
+	var result []any
+	if req.Bri != nil {
+		evs = append(evs, SetVolumePercent{Percent: float64(huePercent(*req.Bri)), Origin: "hue"})
+		result = append(result, map[string]any{"success": map[string]int{prefix + "bri": *req.Bri}})
+	}
+	if req.On != nil {
+		evs = append(evs, SetMute{Muted: !*req.On})
+		result = append(result, map[string]any{"success": map[string]bool{prefix + "on": *req.On}})
+	}
+	for _, ev := range evs {
+		if err := sendVoiceEvent(b.events, t.event(ev)); err != nil {
+			b.writeError(w, 901, prefix, err.Error())
+			return
+		}
+	}
+	b.logger.Debug("hue state change", "target", t.Name, "on", req.On, "bri", req.Bri)
+	b.writeJSON(w, result)
+}
+
+// light resolves a light id (1-based index into the targets).
+func (b *hueBridge) light(id string) (voiceTarget, bool) {
+	n, err := strconv.Atoi(id)
+	if err != nil || n < 1 || n > len(b.targets) {
+		return voiceTarget{}, false
+	}
+	return b.targets[n-1], true
+}
+
+func (b *hueBridge) lights(ctx context.Context) (map[string]any, error) {
+	snap, err := requestStateSnapshot(ctx, b.events, voiceSnapshotTimeout)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]any, len(b.targets))
+	for i, t := range b.targets {
+		state, err := voiceTargetState(snap, t)
+		if err != nil {
+			b.logger.Debug("hue: target state unavailable", "target", t.Name, "error", err)
+		}
+		out[strconv.Itoa(i+1)] = b.lightJSON(t, state)
+	}
+	return out, nil
+}
+
+func (b *hueBridge) lightJSON(t voiceTarget, state voiceState) map[string]any {
+	return map[string]any{
+		"state": map[string]any{
+			"on":        !state.Muted,
+			"bri":       hueBrightness(state.Percent),
+			"alert":     "none",
+			"reachable": true,
+		},
+		"type":             "Dimmable light",
+		"name":             t.Name,
+		"modelid":          "LWB007",
+		"manufacturername": "Philips",
+		"uniqueid":         b.uniqueID(t),
+		"swversion":        "66012040",
+	}
+}
+
+// uniqueID is a stable Hue-style unique id for a target.
+func (b *hueBridge) uniqueID(t voiceTarget) string {
+	h := sha1.Sum([]byte(t.ID))
+	return fmt.Sprintf("00:17:88:01:%02x:%02x:%02x:%02x-0b", h[0], h[1], h[2], h[3])
+}
+
+func (b *hueBridge) reply(w http.ResponseWriter, v any, err error) {
+	if err != nil {
+		b.writeError(w, 901, "/", err.Error())
+		return
+	}
+	b.writeJSON(w, v)
+}
+
+// writeError writes a Hue API error (the API reports errors with status 200).
+func (b *hueBridge) writeError(w http.ResponseWriter, typ int, address, description string) {
+	b.writeJSON(w, []any{map[string]any{"error": map[string]any{"type": typ, "address": address, "description": description}}})
+}
+
+func (b *hueBridge) writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (b *hueBridge) udn() string {
+	return "uuid:2f402f80-da50-11e1-9b23-" + b.serial
+}
+
+func (b *hueBridge) description() string {
+	return `<?xml version="1.0" encoding="UTF-8" ?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+<specVersion><major>1</major><minor>0</minor></specVersion>
+<URLBase>http://` + b.advertise + `/</URLBase>
+<device>
+<deviceType>urn:schemas-upnp-org:device:Basic:1</deviceType>
+<friendlyName>` + instanceDisplayName() + ` (` + b.advertise + `)</friendlyName>
+<manufacturer>Royal Philips Electronics</manufacturer>
+<manufacturerURL>http://www.philips.com</manufacturerURL>
+<modelDescription>Philips hue Personal Wireless Lighting</modelDescription>
+<modelName>Philips hue bridge 2012</modelName>
+<modelNumber>929000226503</modelNumber>
+<serialNumber>` + b.serial + `</serialNumber>
+<UDN>` + b.udn() + `</UDN>
+</device>
+</root>
+`
+}
+
+// ssdpResponse answers an M-SEARCH request, or returns "" if it isn't one for us.
+func (b *hueBridge) ssdpResponse(req string) string {
+	if !strings.HasPrefix(req, "M-SEARCH ") {
+		return ""
+	}
+	var st string
+	for _, line := range strings.Split(req, "\r\n") {
+		if k, v, ok := strings.Cut(line, ":"); ok && strings.EqualFold(strings.TrimSpace(k), "ST") {
+			st = strings.TrimSpace(v)
+		}
+	}
+	switch st {
+	case "ssdp:all", "upnp:rootdevice", "urn:schemas-upnp-org:device:basic:1", "urn:schemas-upnp-org:device:Basic:1":
+	default:
+		return ""
+	}
+	return "HTTP/1.1 200 OK\r\n" +
+		"CACHE-CONTROL: max-age=100\r\n" +
+		"EXT:\r\n" +
+		"LOCATION: http://" + b.advertise + "/description.xml\r\n" +
+		"SERVER: Linux/3.14.0 UPnP/1.0 IpBridge/1.17.0\r\n" +
+		"hue-bridgeid: " + strings.ToUpper(b.serial[:6]+"FFFE"+b.serial[6:]) + "\r\n" +
+		"ST: " + st + "\r\n" +
+		"USN: " + b.udn() + "::upnp:rootdevice\r\n" +
+		"\r\n"
+}
+
+// serveSSDP answers discovery requests until ctx is canceled.
+func (b *hueBridge) serveSSDP(ctx context.Context) error {
+	group, err := net.ResolveUDPAddr("udp4", hueSSDPAddr)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenMulticastUDP("udp4", nil, group)
+	if err != nil {
+		return fmt.Errorf("join %s: %w", hueSSDPAddr, err)
+	}
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	buf := make([]byte, 2048)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if resp := b.ssdpResponse(string(buf[:n])); resp != "" {
+			_, _ = conn.WriteToUDP([]byte(resp), from)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func hueRequest(t *testing.T, h http.Handler, method, path, body string) any {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	var out any
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatalf("%s %s: %v (%s)", method, path, err, rec.Body.String())
+	}
+	return out
+}
+
+func TestHueBrightnessMapping(t *testing.T) {
+	for _, p := range []int{0, 1, 40, 99, 100} {
+		if got := huePercent(hueBrightness(p)); got != p {
+			t.Errorf("%d%% -> bri %d -> %d%%", p, hueBrightness(p), got)
+		}
+	}
+	if got := huePercent(102); got != 40 {
+		t.Errorf("bri 102 = %d%%, want 40", got)
+	}
+}
+
+func TestHueBridge_API(t *testing.T) {
+	events := make(chan Event, 4)
+	defer close(events)
+	forwarded := make(chan Event, 4)
+	go serveSnapshots(events, StateSnapshot{VolumeKnown: true, VolumePercent: 50, Muted: true}, forwarded)
+	b := newHueBridge(voiceOptions{Zones: map[string]string{"kitchen": "Kitchen"}}, "192.168.1.10:80", events, slog.Default())
+
+	if got := hueRequest(t, b, http.MethodPost, "/api", `{"devicetype":"Echo"}`); !strings.Contains(mustJSON(t, got), `"username":"streamerbrainz"`) {
+		t.Fatalf("pairing: %v", got)
+	}
+
+	lights := hueRequest(t, b, http.MethodGet, "/api/anyone/lights", "").(map[string]any)
+	one := lights["1"].(map[string]any)
+	state := one["state"].(map[string]any)
+	if len(lights) != 2 || one["name"] != "Hi-Fi" || state["on"] != false || state["bri"] != float64(127) {
+		t.Fatalf("lights: %v", lights)
+	}
+
+	got := hueRequest(t, b, http.MethodPut, "/api/anyone/lights/2/state", `{"on":true,"bri":102}`)
+	if !strings.Contains(mustJSON(t, got), `"/lights/2/state/bri":102`) {
+		t.Fatalf("state change: %v", got)
+	}
+	for _, want := range []Event{
+		ZonedEvent{Zone: "kitchen", Event: SetVolumePercent{Percent: 40, Origin: "hue"}},
+		ZonedEvent{Zone: "kitchen", Event: SetMute{Muted: false}},
+	} {
+		if ev := <-forwarded; !reflect.DeepEqual(ev, want) {
+			t.Fatalf("got %#v, want %#v", ev, want)
+		}
+	}
+
+	if got := hueRequest(t, b, http.MethodPut, "/api/anyone/lights/9/state", `{"on":false}`); !strings.Contains(mustJSON(t, got), `"error"`) {
+		t.Fatalf("unknown light: %v", got)
+	}
+}
+
+func TestHueBridge_SSDP(t *testing.T) {
+	b := newHueBridge(voiceOptions{}, "192.168.1.10:80", nil, slog.Default())
+	resp := b.ssdpResponse("M-SEARCH * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\nMAN: \"ssdp:discover\"\r\nST: urn:schemas-upnp-org:device:basic:1\r\n\r\n")
+	if !strings.Contains(resp, "LOCATION: http://192.168.1.10:80/description.xml\r\n") || !strings.Contains(resp, "ST: urn:schemas-upnp-org:device:basic:1\r\n") {
+		t.Fatalf("response: %q", resp)
+	}
+	if resp := b.ssdpResponse("M-SEARCH * HTTP/1.1\r\nST: urn:dial-multiscreen-org:service:dial:1\r\n\r\n"); resp != "" {
+		t.Fatalf("other search targets are ignored: %q", resp)
+	}
+	if resp := b.ssdpResponse("NOTIFY * HTTP/1.1\r\nNT: upnp:rootdevice\r\n\r\n"); resp != "" {
+		t.Fatalf("notifications are ignored: %q", resp)
+	}
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
@@ -1,11 +1,15 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"encoding/binary"
+	"fmt"
 	"io"
 	"log/slog"
 	"os"
+	"runtime"
+	"sync"
+	"time"
 )
 
 // inputEvent represents a Linux input event structure
@@ -18,35 +22,479 @@ type inputEvent struct {
 	Value int32
 }
 
+// evdevSupported reports whether "key" and "rotary" inputs (evdev devices) can be
+// read on this platform; elsewhere they are skipped with a warning.
+const evdevSupported = runtime.GOOS == "linux"
+
+// inputEventSize is the wire size of struct input_event on 64-bit kernels.
+const inputEventSize = 24
+
+// decodeInputEvent decodes one little-endian struct input_event from b
+// (len(b) >= inputEventSize). Unlike binary.Read it does not allocate.
+func decodeInputEvent(b []byte) inputEvent {
+	_ = b[inputEventSize-1]
+	return inputEvent{
+		Sec:   int64(binary.LittleEndian.Uint64(b[0:8])),
+		Usec:  int64(binary.LittleEndian.Uint64(b[8:16])),
+		Type:  binary.LittleEndian.Uint16(b[16:18]),
+		Code:  binary.LittleEndian.Uint16(b[18:20]),
+		Value: int32(binary.LittleEndian.Uint32(b[20:24])),
+	}
+}
+
+// time returns the kernel timestamp of the event.
+func (ev inputEvent) time() time.Time {
+	return time.Unix(ev.Sec, ev.Usec*int64(time.Microsecond))
+}
+
+// inputDecoder holds per-device translation state (rotary debounce and frame coalescing).
+// One decoder per device reader; mu only orders the reader against the scancode
+// release timer (see keymap.go).
+type inputDecoder struct {
+	mu sync.Mutex
+
+	events  chan<- Event
+	metrics *Metrics
+	logger  *slog.Logger
+
+	rotary rotaryDebouncer
+	// pendingSteps accumulates accepted detents until the frame's EV_SYN.
+	pendingSteps  int
+	pendingEvents int
+
+	// hiRes is set once the device reports REL_WHEEL_HI_RES; its legacy REL_WHEEL
+	// duplicates are then ignored. pendingHiRes accumulates units until EV_SYN.
+	hiRes        bool
+	pendingHiRes int
+
+	// modifiers is the set of modifier keys currently held on this device.
+	modifiers modifierMask
+	combos    []KeyComboConfig
+
+	longPress *longPressDetector // nil unless a calibration long-press key is configured
+
+	keymap     map[uint16]KeyBinding // per-device key remapping (nil: standard codes only)
+	scanKeymap map[uint32]KeyBinding // MSC_SCAN scancode bindings (nil: scancodes ignored)
+	scan       scancodeState
+
+	repeat    repeatState    // slow-repeat normalization (see repeat_interval.go)
+	noRelease noReleaseState // volume keys of remotes without releases (see no_release.go)
+}
+
+// inputDecoderConfig configures per-device input translation.
+type inputDecoderConfig struct {
+	RotaryDebounce time.Duration    // rotary glitch rejection window (0 disables)
+	KeyCombos      []KeyComboConfig // modifier+volume-key mappings
+	LongPressKey   uint16           // key whose long press toggles calibration mode (0 disables)
+	LongPressHold  time.Duration
+	Keymap         []KeyBinding // per-device key bindings (see keymap.go)
+	// RepeatInterval is the device's key repeat interval (see repeat_interval.go);
+	// AutoRepeatInterval measures it instead. Both off: repeats pass through as-is.
+	RepeatInterval     time.Duration
+	AutoRepeatInterval bool
+	// NoRelease is the device's no_release mode and NoReleaseStepDB its step size
+	// (see no_release.go).
+	NoRelease       string
+	NoReleaseStepDB float64
+}
+
+// newInputDecoder creates a decoder emitting into events.
+func newInputDecoder(events chan<- Event, cfg inputDecoderConfig, metrics *Metrics, logger *slog.Logger) *inputDecoder {
+	d := &inputDecoder{
+		events:  events,
+		metrics: metrics,
+		logger:  logger,
+		rotary:  rotaryDebouncer{window: cfg.RotaryDebounce},
+		combos:  cfg.KeyCombos,
+		repeat:  repeatState{interval: cfg.RepeatInterval, auto: cfg.AutoRepeatInterval},
+
+		noRelease: newNoReleaseState(cfg.NoRelease, cfg.NoReleaseStepDB),
+	}
+	d.keymap, d.scanKeymap = keymapByCode(cfg.Keymap)
+	if cfg.LongPressKey != 0 {
+		d.longPress = &longPressDetector{code: cfg.LongPressKey, hold: cfg.LongPressHold}
+	}
+	return d
+}
+
+// reset clears per-device state after a reconnect: partial frames, held modifiers and
+// hi-res detection belong to the previous device instance.
+func (d *inputDecoder) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.rotary = rotaryDebouncer{window: d.rotary.window}
+	d.pendingSteps, d.pendingEvents = 0, 0
+	d.hiRes, d.pendingHiRes = false, 0
+	d.modifiers = 0
+	d.resetScancode()
+	d.repeat.cancelPending()
+	d.noRelease.stop()
+	if d.longPress != nil {
+		d.longPress.pressed = false
+	}
+}
+
+// modifierMask is a set of held modifier keys (left/right variants are merged).
+type modifierMask uint8
+
+const (
+	modShift modifierMask = 1 << iota
+	modCtrl
+	modAlt
+	modMeta
+)
+
+// modifierNames maps config names to modifier bits.
+var modifierNames = map[string]modifierMask{
+	"shift": modShift,
+	"ctrl":  modCtrl,
+	"alt":   modAlt,
+	"meta":  modMeta,
+}
+
+// modifierForKey returns the modifier bit for a key code (0 if not a modifier).
+func modifierForKey(code uint16) modifierMask {
+	switch code {
+	case KEY_LEFTSHIFT, KEY_RIGHTSHIFT:
+		return modShift
+	case KEY_LEFTCTRL, KEY_RIGHTCTRL:
+		return modCtrl
+	case KEY_LEFTALT, KEY_RIGHTALT:
+		return modAlt
+	case KEY_LEFTMETA, KEY_RIGHTMETA:
+		return modMeta
+	}
+	return 0
+}
+
+// comboKeyCodes maps config key names to the volume keys combos can bind.
+var comboKeyCodes = map[string]uint16{
+	"volume_up":   KEY_VOLUMEUP,
+	"volume_down": KEY_VOLUMEDOWN,
+}
+
+// handleKeyCombo emits the mapped event if ev is a volume key pressed with a configured
+// modifier set. It returns true if the key was consumed.
+func (d *inputDecoder) handleKeyCombo(ev inputEvent) bool {
+	// Releases always pass through so a plain hold that gained a modifier still ends.
+	if d.modifiers == 0 || len(d.combos) == 0 || ev.Value == evValueRelease {
+		return false
+	}
+	for _, c := range d.combos {
+		if comboKeyCodes[c.Key] != ev.Code || c.modifierMask() != d.modifiers {
+			continue
+		}
+		switch {
+		case c.VolumeDB != nil:
+			// Preset: press only.
+			if ev.Value == evValuePress {
+				d.events <- SetVolumeAbsolute{Db: *c.VolumeDB}
+			}
+		case ev.Value == evValuePress || ev.Value == evValueRepeat:
+			// Step: press and autorepeat.
+			steps := 1
+			if ev.Code == KEY_VOLUMEDOWN {
+				steps = -1
+			}
+			d.events <- VolumeStep{Steps: steps, DbPerStep: c.StepDB}
+		}
+		return true
+	}
+	return false
+}
+
+// handle translates one raw input event.
+//
+// Rotary detents are debounced and accumulated per input frame; the sum is emitted
+// as a single RotaryTurn on SYN_REPORT. Everything else goes through emitEventFromInputEvent.
+func (d *inputDecoder) handle(ev inputEvent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch ev.Type {
+	case EV_REL:
+		// Only handle rotary encoder relative axis codes
+		switch ev.Code {
+		case REL_DIAL, REL_MISC:
+		case REL_WHEEL:
+			if d.hiRes {
+				return
+			}
+		case REL_WHEEL_HI_RES:
+			d.hiRes = true
+		default:
+			return
+		}
+		if ev.Value == 0 {
+			return
+		}
+		direction := 1
+		if ev.Value < 0 {
+			direction = -1
+		}
+		if !d.rotary.accept(direction, ev.time()) {
+			d.metrics.RotaryGlitchesRejected.Add(1)
+			d.logger.Debug("rotary glitch rejected", "value", ev.Value)
+			return
+		}
+		if ev.Code == REL_WHEEL_HI_RES {
+			d.pendingHiRes += int(ev.Value)
+		} else {
+			d.pendingSteps += int(ev.Value)
+		}
+		d.pendingEvents++
+
+	case EV_SYN:
+		if ev.Code != SYN_REPORT {
+			return
+		}
+		d.scan.frame = false
+		if d.pendingEvents > 1 {
+			d.metrics.RotaryBurstsCoalesced.Add(1)
+		}
+		steps, units := d.pendingSteps, d.pendingHiRes
+		d.pendingSteps, d.pendingHiRes, d.pendingEvents = 0, 0, 0
+		// Emit raw rotary intent; reducer will apply velocity/step-size policy.
+		if steps != 0 {
+			d.events <- rotaryTurnEvent(steps)
+		}
+		if units != 0 {
+			d.events <- RotaryTurnHiRes{Units: units}
+		}
+
+	case EV_KEY:
+		if d.scan.frame {
+			// A bound scancode already produced this frame's key (see keymap.go).
+			return
+		}
+		ev, consumed := d.applyKeymap(ev)
+		if consumed {
+			return
+		}
+		d.handleKey(ev)
+
+	case EV_MSC:
+		if ev.Code == MSC_SCAN {
+			d.handleScancode(ev)
+		}
+
+	default:
+		emitEventFromInputEvent(ev, d.events, d.logger)
+	}
+}
+
+// handleKey translates a key event with its standard code: modifiers, repeat
+// normalization, combos, the calibration long press, then the key's own action.
+func (d *inputDecoder) handleKey(ev inputEvent) {
+	if m := modifierForKey(ev.Code); m != 0 {
+		switch ev.Value {
+		case evValuePress:
+			d.modifiers |= m
+		case evValueRelease:
+			d.modifiers &^= m
+		}
+		return
+	}
+	if d.repeat.interval > 0 || d.repeat.auto {
+		var deferred bool
+		if ev, deferred = d.normalizeRepeat(ev); deferred {
+			return
+		}
+	}
+	d.translateKey(ev)
+}
+
+// translateKey emits the action of a (non-modifier) key event.
+func (d *inputDecoder) translateKey(ev inputEvent) {
+	if d.handleKeyCombo(ev) {
+		return
+	}
+	if consumed, short := d.longPress.handle(ev, d.events); consumed {
+		if short {
+			// Run the key's normal (press) action now that it was not held.
+			ev.Value = evValuePress
+			d.emitKey(ev)
+		}
+		return
+	}
+	d.emitKey(ev)
+}
+
+// emitKey emits a key's own action; volume holds carry the device's repeat grace.
+func (d *inputDecoder) emitKey(ev inputEvent) {
+	if isVolumeKey(ev.Code) && d.emitNoRelease(ev) {
+		return
+	}
+	if isVolumeKey(ev.Code) && (ev.Value == evValuePress || ev.Value == evValueRepeat) {
+		d.events <- d.volumeHeldEvent(ev.Code)
+		return
+	}
+	emitEventFromInputEvent(ev, d.events, d.logger)
+}
+
+// inputDevice is an opened input device together with its per-device decoder.
+type inputDevice struct {
+	file *os.File
+	path string
+	typ  InputDeviceType
+	dec  *inputDecoder
+}
+
+// Input reader implementations (config: input_reader).
+const (
+	inputReaderEpoll     = "epoll"     // one goroutine multiplexing all devices (Linux)
+	inputReaderGoroutine = "goroutine" // one blocking goroutine per device (portable fallback)
+)
+
+// DeviceDown is emitted when an input device stops delivering events (read error,
+// hangup/unplug). Other devices keep working.
+type DeviceDown struct {
+	Device string
+	Reason string
+}
+
+func (DeviceDown) eventMarker() {}
+
+// BroadcastDeviceDown notifies UIs that an input device failed.
+type BroadcastDeviceDown struct {
+	Device string    `json:"device"`
+	Reason string    `json:"reason"`
+	At     time.Time `json:"at"`
+}
+
+func (BroadcastDeviceDown) stateBroadcastMarker() {}
+
+// reportDeviceDown surfaces a device failure to main (readErr) and to the event bus.
+func reportDeviceDown(dev inputDevice, cause error, readErr chan<- error) {
+	readErr <- fmt.Errorf("input device %s: %w", dev.path, cause)
+	dev.dec.events <- DeviceDown{Device: dev.path, Reason: cause.Error()}
+}
+
+// startInputReaders starts reading all devices with the requested reader implementation
+// and returns a function that waits for the readers to exit. Failed devices are reopened
+// by reconn (nil disables reconnects), as are devices without a file (missing at
+// startup under inputs_optional).
+//
+// The readers own the device files: they close them when a device fails and when ctx
+// is canceled.
+func startInputReaders(ctx context.Context, reader string, devs []inputDevice, reconn *inputReconnector, readErr chan<- error, logger *slog.Logger) (wait func()) {
+	var wg sync.WaitGroup
+	if len(devs) == 0 {
+		return wg.Wait
+	}
+
+	if reader == inputReaderEpoll && !epollSupported {
+		logger.Warn("epoll input reader unavailable on this platform, using goroutine reader")
+		reader = inputReaderGoroutine
+	}
+
+	for _, dev := range devs {
+		if dev.file == nil {
+			dev.dec.events <- DeviceDown{Device: dev.path, Reason: errDeviceMissing.Error()}
+			continue
+		}
+		dev.dec.events <- DeviceUp{Device: dev.path}
+	}
+
+	if reader == inputReaderEpoll {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.Debug("starting epoll input reader", "devices", len(devs))
+			readInputDevicesEpoll(ctx, devs, reconn, readErr)
+			logger.Debug("epoll input reader stopped")
+		}()
+		return wg.Wait
+	}
+
+	wg.Add(len(devs))
+	for _, dev := range devs {
+		go func() {
+			defer wg.Done()
+			logger.Debug("starting input reader", "device", dev.path, "type", dev.typ)
+			runInputDevice(ctx, dev, reconn, readErr)
+			logger.Debug("input reader stopped", "device", dev.path)
+		}()
+	}
+	return wg.Wait
+}
+
+// runInputDevice reads one device in a blocking loop, reopening it after failures
+// (or first opening it, if it has no file yet) until ctx is canceled.
+func runInputDevice(ctx context.Context, dev inputDevice, reconn *inputReconnector, readErr chan<- error) {
+	for {
+		if dev.file == nil {
+			if reconn == nil {
+				return
+			}
+			nf, ok := reconn.reopen(ctx, dev, readErr)
+			if !ok {
+				return
+			}
+			dev.file = nf
+			dev.dec.reset()
+			dev.dec.events <- DeviceUp{Device: dev.path}
+		}
+
+		f := dev.file
+		// Closing the file unblocks the pending read on shutdown.
+		stop := context.AfterFunc(ctx, func() { _ = f.Close() })
+		err := readInputEvents(f, dev.dec)
+		stop()
+		_ = f.Close()
+		if ctx.Err() != nil {
+			return
+		}
+
+		reportDeviceDown(dev, err, readErr)
+		dev.file = nil
+	}
+}
+
 // readInputEvents reads Linux input events from a file descriptor and emits event directly.
 // This runs in a dedicated goroutine and blocks on read operations.
 //
 // Design:
 // - Keep the input module responsible for translating device events into event.
 // - Keep reducer responsible for policy (e.g. RotaryTurn -> velocity-scaled volume changes).
-func readInputEvents(f *os.File, event chan<- Event, readErr chan<- error, logger *slog.Logger) {
-	evSize := binary.Size(inputEvent{})
-	buf := make([]byte, evSize)
-	reader := bytes.NewReader(buf) // Reusable reader, reset on each iteration
+// It returns the read error that stopped it.
+func readInputEvents(f *os.File, dec *inputDecoder) error {
+	buf := make([]byte, inputEventSize)
 
 	for {
 		if _, err := io.ReadFull(f, buf); err != nil {
-			readErr <- err
-			return
+			return err
 		}
+		dec.handle(decodeInputEvent(buf))
+	}
+}
 
-		reader.Reset(buf) // Reset reader to reuse it
-		var ev inputEvent
-		if err := binary.Read(reader, binary.LittleEndian, &ev); err != nil {
-			// Skip malformed events
-			continue
+// Pre-boxed events for the key-repeat and rotary hot paths. Converting a struct
+// holding a negative (or large) int to an Event allocates; these don't.
+var (
+	volumeHeldUpEvent   Event = VolumeHeld{Direction: 1}
+	volumeHeldDownEvent Event = VolumeHeld{Direction: -1}
+
+	// rotaryTurnEvents[i] is RotaryTurn{Steps: i - maxPreboxedRotarySteps}.
+	rotaryTurnEvents = func() (t [2*maxPreboxedRotarySteps + 1]Event) {
+		for i := range t {
+			t[i] = RotaryTurn{Steps: i - maxPreboxedRotarySteps}
 		}
+		return t
+	}()
+)
+
+// maxPreboxedRotarySteps bounds the per-frame detent counts served from rotaryTurnEvents.
+const maxPreboxedRotarySteps = 8
 
-		emitEventFromInputEvent(ev, event, logger)
+// rotaryTurnEvent returns RotaryTurn{Steps: steps} as an Event, pre-boxed for common counts.
+func rotaryTurnEvent(steps int) Event {
+	if steps >= -maxPreboxedRotarySteps && steps <= maxPreboxedRotarySteps {
+		return rotaryTurnEvents[steps+maxPreboxedRotarySteps]
 	}
+	return RotaryTurn{Steps: steps}
 }
 
-// emitEventFromInputEvent converts a raw inputEvent into zero or more Events.
+// emitEventFromInputEvent converts a raw (non-rotary) inputEvent into zero or more Events.
 // It must not implement policy (velocity scaling etc.); only event->action mapping.
 func emitEventFromInputEvent(ev inputEvent, events chan<- Event, logger *slog.Logger) {
 	switch ev.Type {
@@ -54,14 +502,14 @@ func emitEventFromInputEvent(ev inputEvent, events chan<- Event, logger *slog.Lo
 		switch ev.Code {
 		case KEY_VOLUMEUP:
 			if ev.Value == evValuePress || ev.Value == evValueRepeat {
-				events <- VolumeHeld{Direction: 1}
+				events <- volumeHeldUpEvent
 			} else if ev.Value == evValueRelease {
 				events <- VolumeRelease{}
 			}
 
 		case KEY_VOLUMEDOWN:
 			if ev.Value == evValuePress || ev.Value == evValueRepeat {
-				events <- VolumeHeld{Direction: -1}
+				events <- volumeHeldDownEvent
 			} else if ev.Value == evValueRelease {
 				events <- VolumeRelease{}
 			}
@@ -71,6 +519,16 @@ func emitEventFromInputEvent(ev inputEvent, events chan<- Event, logger *slog.Lo
 				events <- ToggleMute{}
 			}
 
+		case KEY_AUDIO:
+			if ev.Value == evValuePress {
+				events <- OutputSelect{}
+			}
+
+		case BTN_0:
+			if ev.Value == evValuePress {
+				events <- RotaryPress{}
+			}
+
 		// Media transport keys -> event (no-op in reducer/effects for now)
 		case KEY_PLAYPAUSE:
 			if ev.Value == evValuePress {
@@ -97,17 +555,5 @@ func emitEventFromInputEvent(ev inputEvent, events chan<- Event, logger *slog.Lo
 				events <- MediaStop{}
 			}
 		}
-
-	case EV_REL:
-		// Only handle rotary encoder relative axis codes
-		if ev.Code != REL_DIAL && ev.Code != REL_WHEEL && ev.Code != REL_MISC {
-			return
-		}
-		if ev.Value == 0 {
-			return
-		}
-
-		// Emit raw rotary intent; reducer will apply velocity/step-size policy.
-		events <- RotaryTurn{Steps: int(ev.Value)}
 	}
 }
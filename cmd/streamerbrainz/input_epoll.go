@@ -3,17 +3,23 @@
 package main
 
 import (
-	"bytes"
-	"encoding/binary"
+	"context"
+	"errors"
 	"fmt"
-	"os"
+	"sync"
 	"syscall"
 
 	"golang.org/x/sys/unix"
 )
 
-// readInputEventsEpoll reads from multiple input devices using epoll
-// This is more efficient than spawning a goroutine per device
+// epollSupported reports whether readInputDevicesEpoll is available on this platform.
+const epollSupported = true
+
+// epollWaitTimeoutMS bounds each epoll_wait so the reader notices ctx cancellation.
+const epollWaitTimeoutMS = 250
+
+// readInputDevicesEpoll reads from multiple input devices using epoll.
+// This is more efficient than spawning a goroutine per device.
 //
 // Instead of:
 //   - N goroutines, each blocking on read()
@@ -23,183 +29,161 @@ import (
 //   - 1 goroutine with epoll
 //   - Kernel wakes us only when events are available
 //   - More scalable for many devices
-func readInputEventsEpoll(files []*os.File, events chan<- inputEvent, readErr chan<- error) {
-	if len(files) == 0 {
+//
+// Failures are isolated per device: a device that reports an error/hangup (e.g. an
+// unplugged USB receiver) is removed from the epoll set and reported via readErr and
+// a DeviceDown event, while the remaining devices keep working. With a reconnector the
+// device is reopened in the background and re-registered (DeviceUp) once it is back.
+// Returns when ctx is canceled, or when no devices remain and reconnects are disabled.
+func readInputDevicesEpoll(ctx context.Context, devs []inputDevice, reconn *inputReconnector, readErr chan<- error) {
+	if len(devs) == 0 {
 		readErr <- fmt.Errorf("no input devices provided")
 		return
 	}
 
 	// Create epoll instance
-	epfd, err := unix.EpollCreate1(0)
+	epfd, err := unix.EpollCreate1(unix.EPOLL_CLOEXEC)
 	if err != nil {
 		readErr <- fmt.Errorf("epoll_create1: %w", err)
 		return
 	}
 	defer unix.Close(epfd)
 
-	// Map file descriptors to files for later identification
-	fdToFile := make(map[int]*os.File)
-
-	// Register all input devices with epoll
-	for _, f := range files {
-		fd := int(f.Fd())
-		fdToFile[fd] = f
-
-		// Register this fd for read events
-		event := unix.EpollEvent{
-			Events: unix.EPOLLIN, // Notify when readable
-			Fd:     int32(fd),
+	// Map file descriptors to devices for later identification
+	byFd := make(map[int32]inputDevice, len(devs))
+	defer func() {
+		for _, dev := range byFd {
+			_ = dev.file.Close()
 		}
-
-		if err := unix.EpollCtl(epfd, unix.EPOLL_CTL_ADD, fd, &event); err != nil {
-			readErr <- fmt.Errorf("epoll_ctl_add fd=%d: %w", fd, err)
-			return
-		}
-	}
-
-	// Reusable buffers
-	const maxEvents = 32 // Process up to 32 events per epoll_wait call
-	epollEvents := make([]unix.EpollEvent, maxEvents)
-	evSize := binary.Size(inputEvent{})
-	buf := make([]byte, evSize)
-	reader := bytes.NewReader(buf)
-
-	// Main epoll loop
-	for {
-		// Wait for events (blocks until at least one device has data)
-		// -1 = wait indefinitely
-		n, err := unix.EpollWait(epfd, epollEvents, -1)
-		if err != nil {
-			// Handle interrupted system call (e.g., SIGINT)
-			if err == syscall.EINTR {
-				continue
-			}
-			readErr <- fmt.Errorf("epoll_wait: %w", err)
+	}()
+
+	// Reopened devices come back through readd; reconnecting tracks the
+	// background reopen goroutines so we never return while they can still emit.
+	// Whatever ends the loop (ctx, an epoll_wait failure), cancel stops them
+	// first: nothing receives from readd anymore.
+	readd := make(chan inputDevice)
+	var reconnecting sync.WaitGroup
+	defer reconnecting.Wait()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// reopen opens dev in the background and hands it to the loop through readd.
+	reopen := func(dev inputDevice) {
+		if reconn == nil {
 			return
 		}
-
-		// Process all ready file descriptors
-		for i := 0; i < n; i++ {
-			fd := int(epollEvents[i].Fd)
-			f := fdToFile[fd]
-
-			// Check for errors or hangup
-			if epollEvents[i].Events&(unix.EPOLLERR|unix.EPOLLHUP) != 0 {
-				readErr <- fmt.Errorf("device error/hangup: %s (fd=%d)", f.Name(), fd)
-				// Note: We could remove this fd from epoll and continue with others
-				// For now, we treat any device error as fatal
+		reconnecting.Add(1)
+		go func() {
+			defer reconnecting.Done()
+			f, ok := reconn.reopen(ctx, dev, readErr)
+			if !ok {
 				return
 			}
-
-			// Read the input event
-			if _, err := f.Read(buf); err != nil {
-				readErr <- fmt.Errorf("read from %s: %w", f.Name(), err)
-				return
-			}
-
-			// Parse binary event
-			reader.Reset(buf)
-			var ev inputEvent
-			if err := binary.Read(reader, binary.LittleEndian, &ev); err != nil {
-				// Skip malformed events
-				continue
+			dev.file = f
+			select {
+			case readd <- dev:
+			case <-ctx.Done():
+				_ = f.Close()
 			}
+		}()
+	}
 
-			// Send to event channel
-			events <- ev
+	// drop removes a failed device from the epoll set, reports it and starts reopening it.
+	drop := func(fd int32, dev inputDevice, cause error) {
+		_ = unix.EpollCtl(epfd, unix.EPOLL_CTL_DEL, int(fd), nil)
+		delete(byFd, fd)
+		_ = dev.file.Close()
+		if ctx.Err() != nil {
+			// Device closed as part of shutdown; not a failure.
+			return
 		}
+		reportDeviceDown(dev, cause, readErr)
+		reopen(dev)
 	}
-}
 
-// readInputEventsSelect is an alternative implementation using select()
-// Works on more platforms than epoll (macOS, BSD, etc.)
-func readInputEventsSelect(files []*os.File, events chan<- inputEvent, readErr chan<- error) {
-	if len(files) == 0 {
-		readErr <- fmt.Errorf("no input devices provided")
-		return
+	// add registers a device with epoll; failures are handled like a device failure.
+	add := func(dev inputDevice) bool {
+		fd := int32(dev.file.Fd())
+		event := unix.EpollEvent{
+			Events: unix.EPOLLIN, // Notify when readable (ERR/HUP are always reported)
+			Fd:     fd,
+		}
+		if err := unix.EpollCtl(epfd, unix.EPOLL_CTL_ADD, int(fd), &event); err != nil {
+			drop(fd, dev, fmt.Errorf("epoll_ctl_add: %w", err))
+			return false
+		}
+		byFd[fd] = dev
+		return true
 	}
 
-	// Build FD set
-	var maxFd int
-	fds := make(map[int]*os.File)
-	for _, f := range files {
-		fd := int(f.Fd())
-		fds[fd] = f
-		if fd > maxFd {
-			maxFd = fd
+	// Register all input devices with epoll; those not open yet join once they are.
+	for _, dev := range devs {
+		if dev.file == nil {
+			reopen(dev)
+			continue
 		}
+		add(dev)
 	}
 
-	// Reusable buffers
-	evSize := binary.Size(inputEvent{})
-	buf := make([]byte, evSize)
-	reader := bytes.NewReader(buf)
-
-	// Main select loop
-	for {
-		// Prepare read FD set
-		var readFds unix.FdSet
-		for fd := range fds {
-			readFds.Set(fd)
+	// Reusable buffers: read up to 64 events per wakeup.
+	const maxEvents = 32 // Process up to 32 ready devices per epoll_wait call
+	epollEvents := make([]unix.EpollEvent, maxEvents)
+	buf := make([]byte, inputEventSize*64)
+
+	// Main epoll loop
+	for len(byFd) > 0 || reconn != nil {
+		if ctx.Err() != nil {
+			return
 		}
 
-		// Wait for any fd to become readable
-		// Note: select modifies readFds, so we rebuild it each iteration
-		n, err := unix.Select(maxFd+1, &readFds, nil, nil, nil)
+		// Pick up devices that came back.
+	readded:
+		for {
+			select {
+			case dev := <-readd:
+				dev.dec.reset()
+				if add(dev) {
+					dev.dec.events <- DeviceUp{Device: dev.path}
+				}
+			default:
+				break readded
+			}
+		}
+
+		n, err := unix.EpollWait(epfd, epollEvents, epollWaitTimeoutMS)
 		if err != nil {
-			if err == syscall.EINTR {
+			// Handle interrupted system call (e.g., SIGINT)
+			if errors.Is(err, syscall.EINTR) {
 				continue
 			}
-			readErr <- fmt.Errorf("select: %w", err)
+			readErr <- fmt.Errorf("epoll_wait: %w", err)
 			return
 		}
 
-		if n == 0 {
-			continue // No fds ready (shouldn't happen with nil timeout)
-		}
-
-		// Check which fds are ready
-		for fd, f := range fds {
-			if !readFds.IsSet(fd) {
+		// Process all ready file descriptors
+		for i := 0; i < n; i++ {
+			fd := epollEvents[i].Fd
+			dev, ok := byFd[fd]
+			if !ok {
 				continue
 			}
 
-			// Read the input event
-			if _, err := f.Read(buf); err != nil {
-				readErr <- fmt.Errorf("read from %s: %w", f.Name(), err)
-				return
+			// Check for errors or hangup (device ejected)
+			if epollEvents[i].Events&(unix.EPOLLERR|unix.EPOLLHUP) != 0 {
+				drop(fd, dev, errors.New("device error/hangup"))
+				continue
 			}
 
-			// Parse binary event
-			reader.Reset(buf)
-			var ev inputEvent
-			if err := binary.Read(reader, binary.LittleEndian, &ev); err != nil {
-				// Skip malformed events
+			nr, err := dev.file.Read(buf)
+			if err != nil {
+				drop(fd, dev, err)
 				continue
 			}
 
-			// Send to event channel
-			events <- ev
+			// evdev returns whole events; decode each one.
+			for off := 0; off+inputEventSize <= nr; off += inputEventSize {
+				dev.dec.handle(decodeInputEvent(buf[off:]))
+			}
 		}
 	}
 }
-
-// Notes on using these implementations:
-//
-// To use epoll version (Linux-only, more efficient):
-//   go readInputEventsEpoll(deviceFiles, events, readErr)
-//
-// To use select version (portable, works everywhere):
-//   go readInputEventsSelect(deviceFiles, events, readErr)
-//
-// Advantages of epoll/select approach:
-//   - Single goroutine instead of N goroutines
-//   - Kernel efficiently wakes us only when data is ready
-//   - Lower memory overhead
-//   - Better scalability (10+ devices)
-//
-// Advantages of current multi-goroutine approach:
-//   - Simpler code
-//   - Easier to debug (each device has its own goroutine)
-//   - Device errors are isolated
-//   - Works fine for small number of devices (2-5)
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+// epollSupported reports whether readInputDevicesEpoll is available on this platform.
+const epollSupported = false
+
+// readInputDevicesEpoll is unavailable outside Linux; callers fall back to per-device goroutines.
+func readInputDevicesEpoll(ctx context.Context, devs []inputDevice, reconn *inputReconnector, readErr chan<- error) {
+	readErr <- errors.New("epoll input reader is only supported on linux")
+}
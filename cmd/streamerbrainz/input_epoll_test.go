@@ -0,0 +1,131 @@
+//go:build linux
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestReadInputDevicesEpoll_IsolatesDeviceFailure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan Event, 8)
+	readErr := make(chan error, 4)
+
+	newDev := func(name string) (inputDevice, *os.File) {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatalf("pipe: %v", err)
+		}
+		t.Cleanup(func() { r.Close(); w.Close() })
+		return inputDevice{
+			file: r,
+			path: name,
+			dec:  newInputDecoder(events, inputDecoderConfig{}, &Metrics{}, slog.Default()),
+		}, w
+	}
+	good, goodW := newDev("good")
+	bad, badW := newDev("bad")
+
+	done := make(chan struct{})
+	go func() {
+		readInputDevicesEpoll(ctx, []inputDevice{good, bad}, nil, readErr)
+		close(done)
+	}()
+
+	// Unplug one device: its writer closing produces EPOLLHUP.
+	badW.Close()
+	select {
+	case ev := <-events:
+		if dd, ok := ev.(DeviceDown); !ok || dd.Device != "bad" {
+			t.Fatalf("expected DeviceDown for bad, got %#v", ev)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timeout waiting for DeviceDown")
+	}
+	<-readErr
+
+	// The other device keeps working.
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.LittleEndian, inputEvent{Type: EV_KEY, Code: KEY_MUTE, Value: evValuePress})
+	if _, err := goodW.Write(buf.Bytes()); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	select {
+	case ev := <-events:
+		if _, ok := ev.(ToggleMute); !ok {
+			t.Fatalf("expected ToggleMute, got %#v", ev)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timeout waiting for event from healthy device")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("reader did not stop on cancel")
+	}
+}
+
+func TestReadInputDevicesEpoll_AddsDeviceMissingAtStartup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan Event, 8)
+	readErr := make(chan error, 4)
+	path := filepath.Join(t.TempDir(), "event0")
+	dev := inputDevice{path: path, dec: newInputDecoder(events, inputDecoderConfig{}, &Metrics{}, slog.Default())}
+
+	done := make(chan struct{})
+	go func() {
+		readInputDevicesEpoll(ctx, []inputDevice{dev}, testReconnector(0), readErr)
+		close(done)
+	}()
+
+	// The device appears (a FIFO stands in: epoll can't watch regular files).
+	time.Sleep(20 * time.Millisecond)
+	if err := unix.Mkfifo(path, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	w, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.LittleEndian, inputEvent{Type: EV_KEY, Code: KEY_MUTE, Value: evValuePress})
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	for _, want := range []string{"up", "mute"} {
+		select {
+		case ev := <-events:
+			_, up := ev.(DeviceUp)
+			_, mute := ev.(ToggleMute)
+			if (want == "up" && !up) || (want == "mute" && !mute) {
+				t.Fatalf("expected %s, got %#v", want, ev)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for %s", want)
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("reader did not stop on cancel")
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ============================================================================
+// FIFO / stdin input
+// ============================================================================
+// Inputs of type "fifo" read newline-delimited event envelopes (the same JSON as
+// the IPC socket, without responses) from a named pipe, or from stdin when the
+// path is "-":
+//
+//   echo '{"type":"toggle_mute"}' > /run/streamerbrainz/control
+//
+// Blank lines and lines starting with '#' are ignored. The pipe is created if
+// missing and opened read-write, so it stays open across writers coming and going.
+// ============================================================================
+
+// fifoStdinPath selects stdin as a fifo input.
+const fifoStdinPath = "-"
+
+// maxFifoLine bounds a single envelope line.
+const maxFifoLine = 64 << 10
+
+// startFifoInputs starts one reader per fifo input and returns a function that waits
+// for them to exit (they exit when ctx is canceled or stdin reaches EOF).
+func startFifoInputs(ctx context.Context, paths []string, events chan<- Event, logger *slog.Logger) (wait func(), err error) {
+	var wg sync.WaitGroup
+	for _, path := range paths {
+		r, err := openFifoInput(path)
+		if err != nil {
+			return wg.Wait, err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.Info("fifo input listening", "path", path)
+			runFifoInput(ctx, r, events, logger.With("fifo", path))
+		}()
+	}
+	return wg.Wait, nil
+}
+
+// openFifoInput opens stdin or the named pipe at path, creating it if needed.
+func openFifoInput(path string) (io.ReadCloser, error) {
+	if path == fifoStdinPath {
+		return os.Stdin, nil
+	}
+
+	st, err := os.Stat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		if err := mkfifo(path, 0o620); err != nil {
+			return nil, fmt.Errorf("create fifo %s: %w", path, err)
+		}
+	case err != nil:
+		return nil, fmt.Errorf("stat fifo %s: %w", path, err)
+	case st.Mode()&fs.ModeNamedPipe == 0:
+		return nil, fmt.Errorf("%s exists and is not a named pipe", path)
+	}
+
+	// Read-write keeps a writer open ourselves: open doesn't block waiting for a
+	// writer, and reads never see EOF when a script closes its end.
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open fifo %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// runFifoInput forwards envelopes read from r to events until ctx is canceled or r ends.
+//
+// Lines are scanned in a helper goroutine that never touches events, so a read that
+// cannot be interrupted (stdin) cannot race with the event bus being closed on shutdown.
+func runFifoInput(ctx context.Context, r io.ReadCloser, events chan<- Event, logger *slog.Logger) {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 4096), maxFifoLine)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil && ctx.Err() == nil {
+			logger.Warn("fifo input read error", "error", err)
+		}
+	}()
+	// Closing unblocks the scanner (named pipes are pollable).
+	stop := context.AfterFunc(ctx, func() { _ = r.Close() })
+	defer stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case line, ok := <-lines:
+			if !ok {
+				logger.Info("fifo input closed")
+				return
+			}
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			ev, err := UnmarshalEvent([]byte(line))
+			if err != nil {
+				logger.Warn("fifo input: invalid event", "error", err, "line", line)
+				continue
+			}
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
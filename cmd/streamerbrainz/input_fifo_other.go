@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "errors"
+
+// mkfifo is unavailable on this platform; only stdin ("-") works as a fifo input.
+func mkfifo(path string, mode uint32) error {
+	return errors.New("named pipes are not supported on this platform")
+}
@@ -0,0 +1,84 @@
+//go:build unix
+
+package main
+
+import (
+	"context"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFifoInput_ForwardsEnvelopes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "control")
+	r, err := openFifoInput(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if st, err := os.Stat(path); err != nil || st.Mode()&fs.ModeNamedPipe == 0 {
+		t.Fatalf("expected named pipe to be created: %v", err)
+	}
+
+	events := make(chan Event, 4)
+	done := make(chan struct{})
+	go func() {
+		runFifoInput(ctx, r, events, slog.Default())
+		close(done)
+	}()
+
+	// Two separate writers: the reader must survive the first one closing.
+	for _, chunk := range []string{
+		"\n# comment\nnot json\n{\"type\":\"toggle_mute\"}\n",
+		"{\"type\":\"set_volume_absolute\",\"zone\":\"phones\",\"data\":{\"db\":-30}}\n",
+	} {
+		w, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatalf("open writer: %v", err)
+		}
+		if _, err := w.WriteString(chunk); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		w.Close()
+	}
+
+	want := []func(Event) bool{
+		func(ev Event) bool { _, ok := ev.(ToggleMute); return ok },
+		func(ev Event) bool {
+			ze, ok := ev.(ZonedEvent)
+			return ok && ze.Zone == "phones" && ze.Event.(SetVolumeAbsolute).Db == -30
+		},
+	}
+	for i, match := range want {
+		select {
+		case ev := <-events:
+			if !match(ev) {
+				t.Fatalf("event %d: unexpected %#v", i, ev)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for event %d", i)
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("fifo reader did not stop on cancel")
+	}
+}
+
+func TestOpenFifoInput_RejectsRegularFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "control")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openFifoInput(path); err == nil {
+		t.Fatalf("expected error for regular file")
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+// ============================================================================
+// Media-key hotkey input (Windows, macOS)
+// ============================================================================
+// Inputs of type "hotkey" take the keyboard media keys from the OS instead of
+// reading an evdev device, which only exists on Linux:
+//   - Windows: the keys are registered as global hotkeys (RegisterHotKey). Each
+//     WM_HOTKEY becomes a key press; Windows sends no release, so a held volume
+//     key repeats WM_HOTKEY and the hold ends via velocity.hold_timeout_ms.
+//   - macOS: a session event tap receives the media key (NX_SYSDEFINED) events,
+//     including key-up and auto-repeat. This needs a cgo build and the
+//     Accessibility (Input Monitoring) permission for the binary.
+// Handled keys are consumed, so the OS volume doesn't change as well. Both map
+// to the equivalent Linux key codes and go through emitEventFromInputEvent like
+// any other key. The path is unused.
+// ============================================================================
+
+// Windows virtual-key codes of the media keys.
+const (
+	vkVolumeMute     = 0xAD
+	vkVolumeDown     = 0xAE
+	vkVolumeUp       = 0xAF
+	vkMediaNextTrack = 0xB0
+	vkMediaPrevTrack = 0xB1
+	vkMediaStop      = 0xB2
+	vkMediaPlayPause = 0xB3
+)
+
+// hotkeyKeys maps the registered virtual keys to Linux key codes.
+var hotkeyKeys = map[uint32]uint16{
+	vkVolumeMute:     KEY_MUTE,
+	vkVolumeDown:     KEY_VOLUMEDOWN,
+	vkVolumeUp:       KEY_VOLUMEUP,
+	vkMediaNextTrack: KEY_NEXTSONG,
+	vkMediaPrevTrack: KEY_PREVIOUSSONG,
+	vkMediaStop:      KEY_STOPCD,
+	vkMediaPlayPause: KEY_PLAYPAUSE,
+}
+
+// hotkeyInputEvent returns the key press for virtual key vk.
+func hotkeyInputEvent(vk uint32) (inputEvent, bool) {
+	code, ok := hotkeyKeys[vk]
+	if !ok {
+		return inputEvent{}, false
+	}
+	return inputEvent{Type: EV_KEY, Code: code, Value: evValuePress}, true
+}
+
+// macOS media key types (NX_KEYTYPE_* in IOKit/hidsystem/ev_keymap.h).
+const (
+	nxKeytypeSoundUp   = 0
+	nxKeytypeSoundDown = 1
+	nxKeytypeMute      = 7
+	nxKeytypePlay      = 16
+	nxKeytypeNext      = 17
+	nxKeytypePrevious  = 18
+	nxKeytypeFast      = 19
+	nxKeytypeRewind    = 20
+)
+
+// mediaKeys maps macOS media key types to Linux key codes. Depending on the
+// keyboard, the track keys arrive as next/previous or fast/rewind.
+var mediaKeys = map[int32]uint16{
+	nxKeytypeSoundUp:   KEY_VOLUMEUP,
+	nxKeytypeSoundDown: KEY_VOLUMEDOWN,
+	nxKeytypeMute:      KEY_MUTE,
+	nxKeytypePlay:      KEY_PLAYPAUSE,
+	nxKeytypeNext:      KEY_NEXTSONG,
+	nxKeytypePrevious:  KEY_PREVIOUSSONG,
+	nxKeytypeFast:      KEY_NEXTSONG,
+	nxKeytypeRewind:    KEY_PREVIOUSSONG,
+}
+
+// mediaKeyInputEvent returns the key event for a macOS media key with value
+// evValuePress, evValueRepeat or evValueRelease.
+func mediaKeyInputEvent(key, value int32) (inputEvent, bool) {
+	code, ok := mediaKeys[key]
+	if !ok {
+		return inputEvent{}, false
+	}
+	return inputEvent{Type: EV_KEY, Code: code, Value: value}, true
+}
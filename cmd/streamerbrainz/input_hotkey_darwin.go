@@ -0,0 +1,163 @@
+//go:build cgo
+
+package main
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework ApplicationServices -framework AppKit
+
+#import <AppKit/AppKit.h>
+#include <IOKit/hidsystem/ev_keymap.h>
+#include <fcntl.h>
+#include <stdint.h>
+#include <unistd.h>
+
+// NX_SUBTYPE_AUX_CONTROL_BUTTONS: media keys inside NX_SYSDEFINED events.
+#define SB_SUBTYPE_MEDIA_KEY 8
+
+typedef struct {
+	int32_t key;
+	int32_t value; // evdev semantics: 0 release, 1 press, 2 repeat
+} sbMediaKeyRecord;
+
+static CFMachPortRef sbTap;
+static CFRunLoopRef sbRunLoop;
+static int sbFd = -1;
+
+static int sbHandledKey(int key) {
+	switch (key) {
+	case NX_KEYTYPE_SOUND_UP: case NX_KEYTYPE_SOUND_DOWN: case NX_KEYTYPE_MUTE:
+	case NX_KEYTYPE_PLAY: case NX_KEYTYPE_NEXT: case NX_KEYTYPE_PREVIOUS:
+	case NX_KEYTYPE_FAST: case NX_KEYTYPE_REWIND:
+		return 1;
+	}
+	return 0;
+}
+
+static CGEventRef sbTapCallback(CGEventTapProxy proxy, CGEventType type, CGEventRef event, void *refcon) {
+	if (type == kCGEventTapDisabledByTimeout || type == kCGEventTapDisabledByUserInput) {
+		CGEventTapEnable(sbTap, true);
+		return event;
+	}
+	if (type != NSEventTypeSystemDefined) {
+		return event;
+	}
+	sbMediaKeyRecord rec;
+	@autoreleasepool {
+		NSEvent *ns = [NSEvent eventWithCGEvent:event];
+		if (ns == nil || ns.subtype != SB_SUBTYPE_MEDIA_KEY) {
+			return event;
+		}
+		rec.key = (int32_t)((ns.data1 & 0xFFFF0000) >> 16);
+		int flags = (int)(ns.data1 & 0x0000FFFF);
+		if (!sbHandledKey(rec.key)) {
+			return event;
+		}
+		rec.value = 0;
+		if (((flags & 0xFF00) >> 8) == 0xA) { // NX_KEYDOWN
+			rec.value = (flags & 0x1) ? 2 : 1;
+		}
+	}
+	// Non-blocking: drop the key rather than stall the event tap.
+	(void)write(sbFd, &rec, sizeof rec);
+	return NULL; // consumed
+}
+
+// sbCreateMediaKeyTap installs the event tap on the calling thread's run loop.
+static int sbCreateMediaKeyTap(int fd) {
+	sbFd = fd;
+	fcntl(fd, F_SETFL, fcntl(fd, F_GETFL) | O_NONBLOCK);
+	sbTap = CGEventTapCreate(kCGSessionEventTap, kCGHeadInsertEventTap, kCGEventTapOptionDefault,
+		CGEventMaskBit(NSEventTypeSystemDefined), sbTapCallback, NULL);
+	if (sbTap == NULL) {
+		return -1;
+	}
+	CFRunLoopSourceRef src = CFMachPortCreateRunLoopSource(kCFAllocatorDefault, sbTap, 0);
+	sbRunLoop = CFRunLoopGetCurrent();
+	CFRunLoopAddSource(sbRunLoop, src, kCFRunLoopCommonModes);
+	CFRelease(src);
+	CGEventTapEnable(sbTap, true);
+	return 0;
+}
+
+// sbRunMediaKeyTap runs the run loop until sbStopMediaKeyTap, then removes the tap.
+static void sbRunMediaKeyTap(void) {
+	CFRunLoopRun();
+	CGEventTapEnable(sbTap, false);
+	CFMachPortInvalidate(sbTap);
+	CFRelease(sbTap);
+	sbTap = NULL;
+}
+
+// sbStopMediaKeyTap may be called from any thread, also before the run loop runs.
+static void sbStopMediaKeyTap(void) {
+	CFRunLoopStop(sbRunLoop);
+}
+*/
+import "C"
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"sync"
+)
+
+// startHotkeyInput installs a session event tap for the media keys and forwards
+// them to events until ctx is canceled. The tap's callback writes fixed-size
+// records to a pipe, which a Go reader turns into key events.
+func startHotkeyInput(ctx context.Context, events chan<- Event, logger *slog.Logger) (wait func(), err error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return func() {}, err
+	}
+
+	ready := make(chan error, 1)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer w.Close() // EOF for the reader once the run loop is gone
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		if C.sbCreateMediaKeyTap(C.int(w.Fd())) != 0 {
+			ready <- errors.New("cannot create event tap; allow streamerbrainz under Privacy & Security > Accessibility")
+			return
+		}
+		ready <- nil
+		C.sbRunMediaKeyTap()
+	}()
+	if err := <-ready; err != nil {
+		wg.Wait()
+		r.Close()
+		return func() {}, err
+	}
+	stop := context.AfterFunc(ctx, func() { C.sbStopMediaKeyTap() })
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer r.Close()
+		logger.Info("hotkey input listening (media keys)")
+		var rec [8]byte
+		for {
+			if _, err := io.ReadFull(r, rec[:]); err != nil {
+				return
+			}
+			key := int32(binary.NativeEndian.Uint32(rec[0:4]))
+			value := int32(binary.NativeEndian.Uint32(rec[4:8]))
+			if ev, ok := mediaKeyInputEvent(key, value); ok {
+				emitEventFromInputEvent(ev, events, logger)
+			}
+		}
+	}()
+	return func() {
+		wg.Wait()
+		stop()
+	}, nil
+}
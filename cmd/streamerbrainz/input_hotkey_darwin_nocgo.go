@@ -0,0 +1,15 @@
+//go:build darwin && !cgo
+
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// startHotkeyInput needs the event tap in input_hotkey_darwin.go, which is only
+// built with cgo (CGO_ENABLED=1, the default for native builds on a Mac).
+func startHotkeyInput(ctx context.Context, events chan<- Event, logger *slog.Logger) (wait func(), err error) {
+	return func() {}, errors.New("hotkey input on macOS requires a cgo build (CGO_ENABLED=1)")
+}
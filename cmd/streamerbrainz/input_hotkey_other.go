@@ -0,0 +1,14 @@
+//go:build !windows && !darwin
+
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// startHotkeyInput is unavailable on this platform; use a key input on the evdev device instead.
+func startHotkeyInput(ctx context.Context, events chan<- Event, logger *slog.Logger) (wait func(), err error) {
+	return func() {}, errors.New("hotkey input is only supported on windows")
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"log/slog"
+	"testing"
+)
+
+func TestHotkeyInputEvent(t *testing.T) {
+	events := make(chan Event, 2)
+	for _, tc := range []struct {
+		vk    uint32
+		check func(Event) bool
+	}{
+		{vkVolumeMute, func(ev Event) bool { _, ok := ev.(ToggleMute); return ok }},
+		{vkVolumeUp, func(ev Event) bool { return ev == volumeHeldUpEvent }},
+		{vkVolumeDown, func(ev Event) bool { return ev == volumeHeldDownEvent }},
+		{vkMediaPlayPause, func(ev Event) bool { _, ok := ev.(MediaPlayPause); return ok }},
+		{vkMediaNextTrack, func(ev Event) bool { _, ok := ev.(MediaNext); return ok }},
+		{vkMediaPrevTrack, func(ev Event) bool { _, ok := ev.(MediaPrevious); return ok }},
+		{vkMediaStop, func(ev Event) bool { _, ok := ev.(MediaStop); return ok }},
+	} {
+		ie, ok := hotkeyInputEvent(tc.vk)
+		if !ok {
+			t.Fatalf("vk %#x not mapped", tc.vk)
+		}
+		emitEventFromInputEvent(ie, events, slog.New(slog.DiscardHandler))
+		if len(events) != 1 {
+			t.Fatalf("vk %#x: expected one event, got %d", tc.vk, len(events))
+		}
+		if ev := <-events; !tc.check(ev) {
+			t.Errorf("vk %#x: unexpected event %#v", tc.vk, ev)
+		}
+	}
+	if _, ok := hotkeyInputEvent(0x41); ok {
+		t.Error("expected unmapped virtual key to be ignored")
+	}
+}
+
+func TestMediaKeyInputEvent(t *testing.T) {
+	events := make(chan Event, 4)
+	logger := slog.New(slog.DiscardHandler)
+	for _, v := range []int32{evValuePress, evValueRepeat, evValueRelease} {
+		ie, ok := mediaKeyInputEvent(nxKeytypeSoundUp, v)
+		if !ok {
+			t.Fatal("volume up not mapped")
+		}
+		emitEventFromInputEvent(ie, events, logger)
+	}
+	if ev := <-events; ev != volumeHeldUpEvent {
+		t.Fatalf("press: unexpected event %#v", ev)
+	}
+	if ev := <-events; ev != volumeHeldUpEvent {
+		t.Fatalf("repeat: unexpected event %#v", ev)
+	}
+	if _, ok := (<-events).(VolumeRelease); !ok {
+		t.Fatal("release: expected VolumeRelease")
+	}
+
+	if ie, ok := mediaKeyInputEvent(nxKeytypeFast, evValuePress); !ok || ie.Code != KEY_NEXTSONG {
+		t.Fatalf("fast: got %#v, %v", ie, ok)
+	}
+	if _, ok := mediaKeyInputEvent(2 /* brightness up */, evValuePress); ok {
+		t.Error("expected unmapped media key to be ignored")
+	}
+}
+
+func TestConfigValidate_AllowsNoInputs(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Inputs = nil
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected an input-less (API-only) config to be valid: %v", err)
+	}
+	cfg.Inputs = []InputDevice{{Type: InputDeviceTypeHotkey}, {Type: InputDeviceTypeHotkey}}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected an error for two hotkey inputs")
+	}
+}
@@ -0,0 +1,115 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	user32                 = windows.NewLazySystemDLL("user32.dll")
+	procRegisterHotKey     = user32.NewProc("RegisterHotKey")
+	procUnregisterHotKey   = user32.NewProc("UnregisterHotKey")
+	procGetMessageW        = user32.NewProc("GetMessageW")
+	procPeekMessageW       = user32.NewProc("PeekMessageW")
+	procPostThreadMessageW = user32.NewProc("PostThreadMessageW")
+)
+
+const (
+	wmQuit   = 0x0012
+	wmHotkey = 0x0312
+	wmUser   = 0x0400
+
+	modNoRepeat = 0x4000
+	pmNoRemove  = 0x0000
+)
+
+// winMsg is the Win32 MSG structure.
+type winMsg struct {
+	hwnd    uintptr
+	message uint32
+	wParam  uintptr
+	lParam  uintptr
+	time    uint32
+	pt      struct{ x, y int32 }
+}
+
+// startHotkeyInput registers the media keys as global hotkeys and forwards them
+// to events until ctx is canceled. Hotkeys are bound to the thread that
+// registered them, so the message loop runs on a locked OS thread.
+func startHotkeyInput(ctx context.Context, events chan<- Event, logger *slog.Logger) (wait func(), err error) {
+	type started struct {
+		tid uint32
+		err error
+	}
+	ready := make(chan started, 1)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		// Create the thread's message queue before anyone posts WM_QUIT to it.
+		var msg winMsg
+		procPeekMessageW.Call(uintptr(unsafe.Pointer(&msg)), 0, wmUser, wmUser, pmNoRemove)
+
+		var registered []uintptr
+		for vk := range hotkeyKeys {
+			mods := uintptr(modNoRepeat)
+			if vk == vkVolumeUp || vk == vkVolumeDown {
+				mods = 0 // repeats keep a volume hold going
+			}
+			id := uintptr(vk)
+			if r, _, err := procRegisterHotKey.Call(0, id, mods, uintptr(vk)); r == 0 {
+				logger.Warn("failed to register hotkey", "vk", vk, "error", err)
+				continue
+			}
+			registered = append(registered, id)
+		}
+		defer func() {
+			for _, id := range registered {
+				procUnregisterHotKey.Call(0, id)
+			}
+		}()
+		if len(registered) == 0 {
+			ready <- started{err: errors.New("no media key could be registered (in use by another application?)")}
+			return
+		}
+		ready <- started{tid: windows.GetCurrentThreadId()}
+		logger.Info("hotkey input listening", "keys", len(registered))
+
+		for {
+			r, _, _ := procGetMessageW.Call(uintptr(unsafe.Pointer(&msg)), 0, 0, 0)
+			if int32(r) <= 0 { // WM_QUIT or error
+				return
+			}
+			if msg.message != wmHotkey {
+				continue
+			}
+			// HIWORD(lParam) is the virtual key.
+			if ev, ok := hotkeyInputEvent(uint32(msg.lParam >> 16 & 0xFFFF)); ok {
+				emitEventFromInputEvent(ev, events, logger)
+			}
+		}
+	}()
+
+	st := <-ready
+	if st.err != nil {
+		<-done
+		return func() {}, st.err
+	}
+	stop := context.AfterFunc(ctx, func() {
+		procPostThreadMessageW.Call(uintptr(st.tid), wmQuit, 0, 0)
+	})
+	return func() {
+		<-done
+		stop()
+	}, nil
+}
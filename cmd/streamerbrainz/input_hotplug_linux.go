@@ -0,0 +1,53 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// watchHotplug notifies n whenever device nodes are created or change attributes in the
+// directories containing the configured device paths (udev creates the node, then fixes
+// its permissions). Runs until ctx is canceled; failures only disable hotplug retries.
+func watchHotplug(ctx context.Context, paths []string, n *hotplugNotifier, logger *slog.Logger) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		logger.Warn("hotplug watch unavailable", "error", err)
+		return
+	}
+	defer unix.Close(fd)
+
+	seen := make(map[string]bool)
+	for _, p := range paths {
+		dir := filepath.Dir(p)
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		if _, err := unix.InotifyAddWatch(fd, dir, unix.IN_CREATE|unix.IN_ATTRIB|unix.IN_MOVED_TO); err != nil {
+			logger.Warn("hotplug watch failed", "dir", dir, "error", err)
+		}
+	}
+
+	buf := make([]byte, 4096)
+	pfd := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+	for ctx.Err() == nil {
+		// Poll with a timeout so we notice ctx cancellation.
+		nready, err := unix.Poll(pfd, epollWaitTimeoutMS)
+		if err != nil && err != unix.EINTR {
+			logger.Warn("hotplug watch stopped", "error", err)
+			return
+		}
+		if nready <= 0 {
+			continue
+		}
+		if _, err := unix.Read(fd, buf); err != nil {
+			continue
+		}
+		n.notify()
+	}
+}
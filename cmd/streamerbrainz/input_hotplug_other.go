@@ -0,0 +1,11 @@
+//go:build !linux
+
+package main
+
+import (
+	"context"
+	"log/slog"
+)
+
+// watchHotplug is a no-op outside Linux; reconnects rely on backoff retries only.
+func watchHotplug(ctx context.Context, paths []string, n *hotplugNotifier, logger *slog.Logger) {}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// Input device reconnect
+// ============================================================================
+// When a device fails (read error, unplug) its reader reports DeviceDown and
+// reopens the device path with exponential backoff. Hotplug notifications
+// (udev creating/chmod-ing nodes under /dev/input, watched via inotify on Linux)
+// trigger an immediate retry, including after the bounded retries are exhausted.
+// A successful reopen reports DeviceUp.
+//
+// With inputs_optional, a device that cannot be opened at startup is reported
+// DeviceDown (errDeviceMissing) and retried the same way.
+// ============================================================================
+
+// DeviceUp is emitted when an input device is opened (at startup or after a reconnect).
+type DeviceUp struct {
+	Device string
+}
+
+func (DeviceUp) eventMarker() {}
+
+// BroadcastDeviceUp notifies UIs that an input device is delivering events again.
+type BroadcastDeviceUp struct {
+	Device string    `json:"device"`
+	At     time.Time `json:"at"`
+}
+
+func (BroadcastDeviceUp) stateBroadcastMarker() {}
+
+// InputDeviceStatus is the externally visible status of one input device.
+type InputDeviceStatus struct {
+	Device string    `json:"device"`
+	Up     bool      `json:"up"`
+	Reason string    `json:"reason,omitempty"`
+	Since  time.Time `json:"since"`
+}
+
+// hotplugNotifier broadcasts "device nodes changed" to every waiting reconnector.
+type hotplugNotifier struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+func newHotplugNotifier() *hotplugNotifier {
+	return &hotplugNotifier{ch: make(chan struct{})}
+}
+
+// changed returns a channel closed on the next hotplug notification.
+func (n *hotplugNotifier) changed() <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.ch
+}
+
+// notify wakes all current waiters.
+func (n *hotplugNotifier) notify() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	close(n.ch)
+	n.ch = make(chan struct{})
+}
+
+// errDeviceMissing is the DeviceDown reason for an optional device that could not
+// be opened at startup.
+var errDeviceMissing = errors.New("not available at startup; waiting for it to appear")
+
+// errReconnectExhausted is reported once bounded retries are used up; the device
+// is then only retried on hotplug notifications.
+var errReconnectExhausted = errors.New("reconnect attempts exhausted; waiting for hotplug")
+
+// inputReconnector reopens failed input devices.
+type inputReconnector struct {
+	maxRetries     int // 0 = retry forever
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	hotplug        *hotplugNotifier
+	logger         *slog.Logger
+}
+
+// reopen blocks until path can be opened again (returning the new file) or ctx ends.
+// dev is used to report exhaustion of the bounded retries.
+func (r *inputReconnector) reopen(ctx context.Context, dev inputDevice, readErr chan<- error) (*os.File, bool) {
+	delay := r.initialBackoff
+	attempts := 0
+	for {
+		var timer <-chan time.Time
+		if r.maxRetries == 0 || attempts < r.maxRetries {
+			timer = time.After(delay)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, false
+		case <-r.hotplug.changed():
+			// Device nodes changed: retry now, with a fresh retry budget.
+			attempts = 0
+			delay = r.initialBackoff
+		case <-timer:
+		}
+
+		f, err := os.Open(dev.path)
+		if err == nil {
+			r.logger.Info("input device reconnected", "device", dev.path, "attempts", attempts+1)
+			return f, true
+		}
+
+		attempts++
+		r.logger.Debug("input device reopen failed", "device", dev.path, "attempt", attempts, "error", err)
+		if r.maxRetries > 0 && attempts == r.maxRetries {
+			reportDeviceDown(dev, errReconnectExhausted, readErr)
+		}
+		delay = min(delay*2, r.maxBackoff)
+	}
+}
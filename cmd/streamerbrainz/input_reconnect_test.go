@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testReconnector(maxRetries int) *inputReconnector {
+	return &inputReconnector{
+		maxRetries:     maxRetries,
+		initialBackoff: time.Millisecond,
+		maxBackoff:     5 * time.Millisecond,
+		hotplug:        newHotplugNotifier(),
+		logger:         slog.Default(),
+	}
+}
+
+func TestInputReconnector_ReopensOnceDeviceReturns(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "event0")
+	events := make(chan Event, 4)
+	readErr := make(chan error, 4)
+	dev := inputDevice{path: path, dec: newInputDecoder(events, inputDecoderConfig{}, &Metrics{}, slog.Default())}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = os.WriteFile(path, nil, 0o600)
+	}()
+
+	f, ok := testReconnector(0).reopen(ctx, dev, readErr)
+	if !ok {
+		t.Fatalf("expected reopen to succeed")
+	}
+	f.Close()
+	if len(events) != 0 {
+		t.Fatalf("unbounded retries must not report exhaustion, got %d events", len(events))
+	}
+}
+
+func TestInputReconnector_WaitsForHotplugAfterExhaustion(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "event0")
+	events := make(chan Event, 4)
+	readErr := make(chan error, 4)
+	dev := inputDevice{path: path, dec: newInputDecoder(events, inputDecoderConfig{}, &Metrics{}, slog.Default())}
+	r := testReconnector(2)
+
+	type result struct {
+		f  *os.File
+		ok bool
+	}
+	done := make(chan result, 1)
+	go func() {
+		f, ok := r.reopen(ctx, dev, readErr)
+		done <- result{f, ok}
+	}()
+
+	select {
+	case ev := <-events:
+		dd, ok := ev.(DeviceDown)
+		if !ok || dd.Reason != errReconnectExhausted.Error() {
+			t.Fatalf("expected exhaustion DeviceDown, got %#v", ev)
+		}
+	case <-ctx.Done():
+		t.Fatalf("timeout waiting for exhaustion")
+	}
+	<-readErr
+
+	// The device appears; without a hotplug notification nothing retries.
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+		t.Fatalf("reopened without hotplug notification")
+	case <-time.After(30 * time.Millisecond):
+	}
+
+	r.hotplug.notify()
+	select {
+	case res := <-done:
+		if !res.ok {
+			t.Fatalf("expected reopen after hotplug")
+		}
+		res.f.Close()
+	case <-ctx.Done():
+		t.Fatalf("timeout waiting for reopen after hotplug")
+	}
+}
+
+func TestRunInputDevice_ReconnectsAfterReadError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "event0")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(chan Event, 8)
+	readErr := make(chan error, 8)
+	dev := inputDevice{file: f, path: path, dec: newInputDecoder(events, inputDecoderConfig{}, &Metrics{}, slog.Default())}
+
+	done := make(chan struct{})
+	go func() {
+		runInputDevice(ctx, dev, testReconnector(0), readErr)
+		close(done)
+	}()
+
+	// An empty regular file reads EOF immediately: down, then back up.
+	for _, want := range []string{"down", "up"} {
+		select {
+		case ev := <-events:
+			switch ev.(type) {
+			case DeviceDown:
+				if want != "down" {
+					t.Fatalf("expected DeviceUp, got %#v", ev)
+				}
+			case DeviceUp:
+				if want != "up" {
+					t.Fatalf("expected DeviceDown, got %#v", ev)
+				}
+			default:
+				t.Fatalf("unexpected event %#v", ev)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for device %s", want)
+		}
+	}
+
+	cancel()
+	for {
+		select {
+		case <-done:
+			return
+		case <-events:
+		case <-readErr:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("reader did not stop on cancel")
+		}
+	}
+}
+
+func TestRunInputDevice_OpensDeviceMissingAtStartup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "event0")
+	events := make(chan Event, 8)
+	readErr := make(chan error, 8)
+	dev := inputDevice{path: path, dec: newInputDecoder(events, inputDecoderConfig{}, &Metrics{}, slog.Default())}
+
+	done := make(chan struct{})
+	go func() {
+		runInputDevice(ctx, dev, testReconnector(0), readErr)
+		close(done)
+	}()
+
+	select {
+	case ev := <-events:
+		t.Fatalf("event before the device exists: %#v", ev)
+	case <-time.After(20 * time.Millisecond):
+	}
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case ev := <-events:
+		if _, ok := ev.(DeviceUp); !ok {
+			t.Fatalf("expected DeviceUp, got %#v", ev)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for the device to be opened")
+	}
+
+	cancel()
+	for {
+		select {
+		case <-done:
+			return
+		case <-events:
+		case <-readErr:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("reader did not stop on cancel")
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func relEvent(at time.Time, value int32) inputEvent {
+	return inputEvent{Sec: at.Unix(), Usec: int64(at.Nanosecond() / 1000), Type: EV_REL, Code: REL_DIAL, Value: value}
+}
+
+func synEvent(at time.Time) inputEvent {
+	return inputEvent{Sec: at.Unix(), Usec: int64(at.Nanosecond() / 1000), Type: EV_SYN, Code: SYN_REPORT}
+}
+
+func TestInputDecoder_RejectsBounceAndCoalescesFrames(t *testing.T) {
+	events := make(chan Event, 8)
+	metrics := &Metrics{}
+	dec := newInputDecoder(events, inputDecoderConfig{RotaryDebounce: 5 * time.Millisecond}, metrics, slog.Default())
+	t0 := time.Unix(1000, 0)
+
+	// +1, bounce -1 after 2 ms (rejected), +1 in the same frame (coalesced).
+	dec.handle(relEvent(t0, 1))
+	dec.handle(relEvent(t0.Add(2*time.Millisecond), -1))
+	dec.handle(relEvent(t0.Add(3*time.Millisecond), 1))
+	dec.handle(synEvent(t0.Add(3 * time.Millisecond)))
+
+	select {
+	case ev := <-events:
+		if rt, ok := ev.(RotaryTurn); !ok || rt.Steps != 2 {
+			t.Fatalf("expected RotaryTurn{2}, got %#v", ev)
+		}
+	default:
+		t.Fatalf("expected a RotaryTurn")
+	}
+	if got := metrics.RotaryGlitchesRejected.Load(); got != 1 {
+		t.Fatalf("expected 1 rejected glitch, got %d", got)
+	}
+	if got := metrics.RotaryBurstsCoalesced.Load(); got != 1 {
+		t.Fatalf("expected 1 coalesced burst, got %d", got)
+	}
+
+	// A reversal outside the window is a real turn.
+	dec.handle(relEvent(t0.Add(50*time.Millisecond), -1))
+	dec.handle(synEvent(t0.Add(50 * time.Millisecond)))
+	if rt, ok := (<-events).(RotaryTurn); !ok || rt.Steps != -1 {
+		t.Fatalf("expected RotaryTurn{-1}, got %#v", rt)
+	}
+}
+
+func TestInputDecoder_HiResWheelSupersedesLegacyWheel(t *testing.T) {
+	events := make(chan Event, 8)
+	dec := newInputDecoder(events, inputDecoderConfig{}, &Metrics{}, slog.Default())
+	t0 := time.Unix(1000, 0)
+
+	// A hi-res device reports both codes for the same movement.
+	dec.handle(inputEvent{Sec: t0.Unix(), Type: EV_REL, Code: REL_WHEEL_HI_RES, Value: 120})
+	dec.handle(inputEvent{Sec: t0.Unix(), Type: EV_REL, Code: REL_WHEEL, Value: 1})
+	dec.handle(synEvent(t0))
+
+	if ev, ok := (<-events).(RotaryTurnHiRes); !ok || ev.Units != 120 {
+		t.Fatalf("expected RotaryTurnHiRes{120}, got %#v", ev)
+	}
+	select {
+	case ev := <-events:
+		t.Fatalf("expected legacy wheel duplicate to be dropped, got %#v", ev)
+	default:
+	}
+}
+
+func TestInputDecoder_ModifierCombos(t *testing.T) {
+	events := make(chan Event, 8)
+	preset := -20.0
+	dec := newInputDecoder(events, inputDecoderConfig{KeyCombos: []KeyComboConfig{
+		{Modifiers: []string{"shift"}, Key: "volume_up", StepDB: 0.25},
+		{Modifiers: []string{"ctrl", "shift"}, Key: "volume_up", VolumeDB: &preset},
+	}}, &Metrics{}, slog.Default())
+
+	key := func(code uint16, value int32) {
+		dec.handle(inputEvent{Type: EV_KEY, Code: code, Value: value})
+	}
+
+	key(KEY_LEFTSHIFT, evValuePress)
+	key(KEY_VOLUMEUP, evValuePress)
+	if ev, ok := (<-events).(VolumeStep); !ok || ev.Steps != 1 || ev.DbPerStep != 0.25 {
+		t.Fatalf("expected fine VolumeStep, got %#v", ev)
+	}
+	key(KEY_VOLUMEUP, evValueRelease)
+	if _, ok := (<-events).(VolumeRelease); !ok {
+		t.Fatalf("expected release to pass through")
+	}
+
+	// Right ctrl + left shift matches the ctrl+shift preset.
+	key(KEY_RIGHTCTRL, evValuePress)
+	key(KEY_VOLUMEUP, evValuePress)
+	if ev, ok := (<-events).(SetVolumeAbsolute); !ok || ev.Db != -20 {
+		t.Fatalf("expected preset SetVolumeAbsolute, got %#v", ev)
+	}
+	key(KEY_VOLUMEUP, evValueRepeat)
+	select {
+	case ev := <-events:
+		t.Fatalf("expected preset to ignore autorepeat, got %#v", ev)
+	default:
+	}
+
+	// Without modifiers the key falls back to a plain hold.
+	key(KEY_RIGHTCTRL, evValueRelease)
+	key(KEY_LEFTSHIFT, evValueRelease)
+	key(KEY_VOLUMEUP, evValuePress)
+	if ev, ok := (<-events).(VolumeHeld); !ok || ev.Direction != 1 {
+		t.Fatalf("expected VolumeHeld, got %#v", ev)
+	}
+}
+
+func TestDecodeInputEvent_MatchesBinaryLayout(t *testing.T) {
+	want := inputEvent{Sec: 1700000000, Usec: 123456, Type: EV_REL, Code: REL_DIAL, Value: -3}
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.LittleEndian, want)
+	if buf.Len() != inputEventSize {
+		t.Fatalf("expected %d-byte events, got %d", inputEventSize, buf.Len())
+	}
+	if got := decodeInputEvent(buf.Bytes()); got != want {
+		t.Fatalf("decode: got %+v, want %+v", got, want)
+	}
+}
+
+func TestInputDecoder_HotPathDoesNotAllocate(t *testing.T) {
+	events := make(chan Event, 1)
+	dec := newInputDecoder(events, inputDecoderConfig{}, &Metrics{}, slog.Default())
+	var raw [inputEventSize]byte
+	t0 := time.Unix(1000, 0)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		dec.handle(decodeInputEvent(raw[:]))
+		dec.handle(inputEvent{Type: EV_KEY, Code: KEY_VOLUMEDOWN, Value: evValueRepeat})
+		<-events
+		dec.handle(relEvent(t0, -1))
+		dec.handle(synEvent(t0))
+		<-events
+		t0 = t0.Add(time.Second)
+	})
+	if allocs != 0 {
+		t.Fatalf("expected no allocations per input frame, got %v", allocs)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// Single instance lock
+// ============================================================================
+// A second daemon started with the same ipc.socket_path used to replace the
+// first one's IPC socket and then fight it over CamillaDSP and the inputs. The
+// daemon now takes an exclusive lock on a file next to the socket
+// (<socket_path>.lock; on Windows, <pipe name>.lock in the temp directory)
+// before touching anything, and refuses to start while another instance
+// holds it. The lock is released by the OS when the process exits, so a
+// crashed daemon never leaves a stale lock behind; the file keeps the holder's
+// pid for the error message.
+//
+// Instances meant to run side by side (one per DAC, say) each need their own
+// ipc.socket_path, and their own ports.
+// ============================================================================
+
+// errInstanceLocked is returned by lockInstanceFile when another process
+// holds the lock.
+var errInstanceLocked = errors.New("instance lock held")
+
+// instanceRunningError reports another daemon holding the instance lock.
+type instanceRunningError struct {
+	LockPath string
+	PID      int // 0 if unknown
+}
+
+func (e *instanceRunningError) Error() string {
+	who := "another streamerbrainz instance"
+	if e.PID > 0 {
+		who = fmt.Sprintf("%s (pid %d)", who, e.PID)
+	}
+	return fmt.Sprintf("%s is already running (lock %s); stop it, or give this one its own ipc.socket_path", who, e.LockPath)
+}
+
+// acquireInstanceLock takes the instance lock for socketPath. release drops it.
+func acquireInstanceLock(socketPath string) (release func(), err error) {
+	path := instanceLockPath(socketPath)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open instance lock: %w", err)
+	}
+	if err := lockInstanceFile(f); err != nil {
+		f.Close()
+		if errors.Is(err, errInstanceLocked) {
+			return nil, &instanceRunningError{LockPath: path, PID: readLockPID(path)}
+		}
+		return nil, fmt.Errorf("lock %s: %w", path, err)
+	}
+	if err := f.Truncate(0); err == nil {
+		f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	return func() {
+		f.Truncate(0)
+		f.Close()
+	}, nil
+}
+
+// readLockPID returns the pid recorded in the lock file at path (0 if none).
+func readLockPID(path string) int {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(b)))
+	return pid
+}
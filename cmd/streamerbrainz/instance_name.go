@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ============================================================================
+// Named instances
+// ============================================================================
+// Two DSP chains on one box run as two daemons. `-instance NAME` (given before
+// a subcommand it applies to that too: `streamerbrainz -instance kitchen ctl
+// mute`) or $STREAMERBRAINZ_INSTANCE selects instance NAME:
+//
+//   - the config defaults to ~/.config/streamerbrainz/NAME.yaml (conf.d/ next
+//     to it is still shared)
+//   - defaults that would clash carry the name: ipc.socket_path is
+//     /tmp/streamerbrainz-NAME.sock, stats.file stats-NAME.json
+//   - ports have no default: NAME.yaml must set webhooks.port (and osc.port
+//     with OSC on), so no two instances guess the same one; clashes that
+//     remain are reported at startup (see listen_ports.go)
+//   - log lines carry instance=NAME, and the hue bridge is announced as
+//     "StreamerBrainz NAME"
+//
+// Values set in the config file win as usual. The flag is exported as
+// $STREAMERBRAINZ_INSTANCE, so hooks the daemon runs address the same instance.
+// examples/streamerbrainz@.service runs one systemd unit per instance.
+// ============================================================================
+
+var instanceNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// instanceName is the selected instance ("" for the default one).
+func instanceName() string {
+	return os.Getenv(envInstance)
+}
+
+// setInstance selects instance name for this process and its children.
+func setInstance(name string) error {
+	if !instanceNameRe.MatchString(name) {
+		return fmt.Errorf("invalid instance name %q (letters, digits, - and _)", name)
+	}
+	return os.Setenv(envInstance, name)
+}
+
+// stripInstanceFlag removes a leading -instance NAME (or -instance=NAME) from
+// args, selecting the instance.
+func stripInstanceFlag(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return args, nil
+	}
+	flag, value, hasValue := strings.Cut(strings.TrimPrefix(args[0], "-"), "=")
+	if flag != "-instance" && flag != "instance" {
+		return args, nil
+	}
+	rest := args[1:]
+	if !hasValue {
+		if len(rest) == 0 {
+			return nil, fmt.Errorf("flag needs an argument: -instance")
+		}
+		value, rest = rest[0], rest[1:]
+	}
+	if err := setInstance(value); err != nil {
+		return nil, err
+	}
+	return rest, nil
+}
+
+// instanceConfigPath is the default config file of instance name.
+func instanceConfigPath(name string) string {
+	return strings.TrimSuffix(defaultConfigPath, "config.yaml") + name + ".yaml"
+}
+
+// applyInstanceDefaults gives cfg's defaults instance name's socket and files,
+// and clears the default ports so the instance's config has to set them.
+func applyInstanceDefaults(cfg *Config, name string) {
+	cfg.IPC.SocketPath = "/tmp/streamerbrainz-" + name + ".sock"
+	cfg.Webhooks.Port = 0
+	cfg.OSC.Port = 0
+	cfg.Stats.File = strings.TrimSuffix(defaultStatsFile, ".json") + "-" + name + ".json"
+}
+
+// instanceDisplayName is "StreamerBrainz", plus the instance name if any.
+func instanceDisplayName() string {
+	if name := instanceName(); name != "" {
+		return "StreamerBrainz " + name
+	}
+	return "StreamerBrainz"
+}
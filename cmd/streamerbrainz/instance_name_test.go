@@ -0,0 +1,64 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestStripInstanceFlag(t *testing.T) {
+	for _, tc := range []struct {
+		args     []string
+		want     []string
+		instance string
+	}{
+		{[]string{"ctl", "mute"}, []string{"ctl", "mute"}, ""},
+		{[]string{"-instance", "office", "ctl", "mute"}, []string{"ctl", "mute"}, "office"},
+		{[]string{"--instance=living"}, []string{}, "living"},
+		{[]string{"-config", "x.yaml", "-instance", "office"}, []string{"-config", "x.yaml", "-instance", "office"}, ""},
+	} {
+		t.Setenv(envInstance, "")
+		got, err := stripInstanceFlag(tc.args)
+		if err != nil {
+			t.Fatalf("%v: %v", tc.args, err)
+		}
+		if !slices.Equal(got, tc.want) || instanceName() != tc.instance {
+			t.Errorf("%v: got %v instance %q, want %v instance %q", tc.args, got, instanceName(), tc.want, tc.instance)
+		}
+	}
+
+	for _, bad := range [][]string{{"-instance"}, {"-instance", "../etc"}, {"-instance="}} {
+		if _, err := stripInstanceFlag(bad); err == nil {
+			t.Errorf("%v accepted", bad)
+		}
+	}
+}
+
+func TestInstanceDefaults(t *testing.T) {
+	t.Setenv(envConfigPath, "")
+	t.Setenv(envInstance, "office")
+	if got := ResolveConfigPath(""); got != "~/.config/streamerbrainz/office.yaml" {
+		t.Fatalf("config path = %q", got)
+	}
+
+	cfg := DefaultConfig()
+	applyInstanceDefaults(&cfg, "office")
+	if cfg.IPC.SocketPath != "/tmp/streamerbrainz-office.sock" || cfg.Stats.File != "~/.local/state/streamerbrainz/stats-office.json" {
+		t.Fatalf("socket %q, stats %q", cfg.IPC.SocketPath, cfg.Stats.File)
+	}
+	// Ports have to be chosen per instance.
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "webhooks.port must be set") {
+		t.Fatalf("without webhooks.port: %v", err)
+	}
+	cfg.Webhooks.Port = 3101
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	cfg.OSC.Enabled = true
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "osc.port must be set") {
+		t.Fatalf("without osc.port: %v", err)
+	}
+	if instanceDisplayName() != "StreamerBrainz office" {
+		t.Fatalf("display name %q", instanceDisplayName())
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAcquireInstanceLock(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "sb.sock")
+	release, err := acquireInstanceLock(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = acquireInstanceLock(socket)
+	var running *instanceRunningError
+	if !errors.As(err, &running) {
+		t.Fatalf("second lock: err = %v, want instanceRunningError", err)
+	}
+	if running.PID != os.Getpid() {
+		t.Fatalf("pid = %d, want %d", running.PID, os.Getpid())
+	}
+
+	// Another socket path is another instance.
+	other, err := acquireInstanceLock(filepath.Join(t.TempDir(), "other.sock"))
+	if err != nil {
+		t.Fatalf("independent instance: %v", err)
+	}
+	other()
+
+	release()
+	release, err = acquireInstanceLock(socket)
+	if err != nil {
+		t.Fatalf("after release: %v", err)
+	}
+	release()
+}
+
+func TestListenIPC_RefusesSocketInUse(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "sb.sock")
+	ln, _, cleanup, err := listenIPC(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	defer ln.Close()
+
+	if _, _, _, err := listenIPC(socket); err == nil {
+		t.Fatal("second listener replaced a live socket")
+	}
+}
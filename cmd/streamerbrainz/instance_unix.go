@@ -0,0 +1,23 @@
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// instanceLockPath is the instance lock file for socketPath.
+func instanceLockPath(socketPath string) string {
+	return socketPath + ".lock"
+}
+
+// lockInstanceFile takes a non-blocking exclusive flock on f.
+func lockInstanceFile(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return errInstanceLocked
+	}
+	return err
+}
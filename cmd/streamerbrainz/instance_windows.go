@@ -0,0 +1,29 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// instanceLockPath is the instance lock file for socketPath (named after its pipe).
+func instanceLockPath(socketPath string) string {
+	name := ipcPipeName(socketPath)
+	return filepath.Join(os.TempDir(), name[strings.LastIndex(name, `\`)+1:]+".lock")
+}
+
+// lockInstanceFile takes a non-blocking exclusive lock on f. The locked byte lies
+// past the pid so others can still read it.
+func lockInstanceFile(f *os.File) error {
+	ol := &windows.Overlapped{OffsetHigh: 1}
+	err := windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, ol)
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return errInstanceLocked
+	}
+	return err
+}
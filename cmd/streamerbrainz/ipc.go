@@ -8,15 +8,16 @@ import (
 	"fmt"
 	"log/slog"
 	"net"
-	"os"
+	"path"
 	"strings"
 )
 
 // ============================================================================
-// IPC Server - Unix Domain Socket Interface
+// IPC Server - Unix Domain Socket / Named Pipe Interface
 // ============================================================================
 // The IPC server allows external clients to send JSON events to the daemon
-// via a Unix domain socket. This enables:
+// via a Unix domain socket (a named pipe on Windows; see ipc_windows.go). This
+// enables:
 //   - Remote control via command-line tools
 //   - Integration with librespot and other audio sources
 //   - UI/Web interface control
@@ -24,39 +25,39 @@ import (
 //
 // Protocol: Line-delimited JSON
 //   - Client sends: {"type": "event_name", "data": {...}}
-//   - Server responds: {"status": "ok"} or {"status": "error", "error": "msg"}
+//   - Server responds once the event has been applied (see ack.go):
+//     {"status": "ok"}, optionally with "message" (e.g. "clamped to -10.0 dB"),
+//     or {"status": "error", "error": "msg"}
 // ============================================================================
 
 // IPCResponse represents the response sent back to IPC clients
 type IPCResponse struct {
-	Status string `json:"status"`          // "ok" or "error"
-	Error  string `json:"error,omitempty"` // error message if status == "error"
+	Status  string `json:"status"`            // "ok" or "error"
+	Error   string `json:"error,omitempty"`   // error message if status == "error"
+	Message string `json:"message,omitempty"` // detail if status == "ok"
 }
 
-// runIPCServer starts the Unix domain socket server.
+// ackResponse converts an event acknowledgement into a response.
+func ackResponse(ack EventAck) IPCResponse {
+	if ack.Err != "" {
+		return IPCResponse{Status: "error", Error: ack.Err}
+	}
+	return IPCResponse{Status: "ok", Message: ack.Message}
+}
+
+// runIPCServer starts the IPC server (listenIPC is platform-specific).
 // It runs until ctx is canceled, at which point it closes the listener and exits.
 //
 // This function is context-aware so the main program can implement proper shutdown semantics.
 func runIPCServer(ctx context.Context, socketPath string, events chan<- Event, logger *slog.Logger) error {
-	// Remove existing socket file if it exists
-	if err := os.RemoveAll(socketPath); err != nil {
-		return fmt.Errorf("remove existing socket: %w", err)
-	}
-
-	// Create Unix domain socket listener
-	listener, err := net.Listen("unix", socketPath)
+	listener, addr, cleanup, err := listenIPC(socketPath)
 	if err != nil {
-		return fmt.Errorf("listen on %s: %w", socketPath, err)
+		return err
 	}
+	defer cleanup()
 	defer listener.Close()
-	defer os.Remove(socketPath)
-
-	// Make socket accessible (consider security implications in production)
-	if err := os.Chmod(socketPath, 0666); err != nil {
-		return fmt.Errorf("chmod socket: %w", err)
-	}
 
-	logger.Info("IPC listening", "socket", socketPath)
+	logger.Info("IPC listening", "socket", addr)
 
 	// Close the listener on shutdown. This unblocks Accept().
 	go func() {
@@ -88,6 +89,24 @@ func runIPCServer(ctx context.Context, socketPath string, events chan<- Event, l
 	}
 }
 
+// ipcPipePrefix is the namespace of Windows named pipes.
+const ipcPipePrefix = `\\.\pipe\`
+
+// ipcPipeName maps ipc.socket_path to a Windows named pipe: a path already in
+// the pipe namespace is used as is, anything else (e.g. the default
+// /tmp/streamerbrainz.sock) becomes \\.\pipe\<base name without extension>.
+func ipcPipeName(socketPath string) string {
+	if strings.HasPrefix(strings.ToLower(socketPath), ipcPipePrefix) {
+		return socketPath
+	}
+	base := path.Base(strings.ReplaceAll(socketPath, `\`, "/"))
+	base = strings.TrimSuffix(base, path.Ext(base))
+	if base == "" || base == "." || base == "/" {
+		base = "streamerbrainz"
+	}
+	return ipcPipePrefix + base
+}
+
 // handleIPCConnection processes a single IPC client connection
 // handleIPCConnection handles a single IPC connection
 func handleIPCConnection(conn net.Conn, events chan<- Event, logger *slog.Logger) {
@@ -115,23 +134,19 @@ func handleIPCConnection(conn net.Conn, events chan<- Event, logger *slog.Logger
 			continue
 		}
 
-		// Send event to daemon
-		select {
-		case events <- ev:
-			// Event queued successfully
-			response := IPCResponse{Status: "ok"}
-			if encErr := encoder.Encode(response); encErr != nil {
-				logger.Error("IPC failed to send success response", "error", encErr)
-			}
-		default:
-			// Event channel is full (should rarely happen with buffer)
-			response := IPCResponse{
-				Status: "error",
-				Error:  "event queue full",
-			}
-			if encErr := encoder.Encode(response); encErr != nil {
-				logger.Error("IPC failed to send error response", "error", encErr)
-			}
+		// Volume intents are attributed to the IPC client ("ipc:<origin>", or "ipc").
+		ev = withVolumeOrigin(ev, "ipc", "ipc")
+
+		// Send event to daemon and wait for its outcome
+		response := IPCResponse{
+			Status: "error",
+			Error:  "event queue full", // should rarely happen with buffer
+		}
+		if ack, queued := sendAcked(events, ev); queued {
+			response = ackResponse(ack)
+		}
+		if encErr := encoder.Encode(response); encErr != nil {
+			logger.Error("IPC failed to send response", "error", encErr)
 		}
 	}
 
@@ -145,36 +160,37 @@ func handleIPCConnection(conn net.Conn, events chan<- Event, logger *slog.Logger
 // programs or for testing.
 // ============================================================================
 
-// SendIPCEvent sends an event to the daemon via IPC and returns the response
-func SendIPCEvent(socketPath string, ev Event) error {
+// SendIPCEvent sends an event to the daemon via IPC and returns the message of
+// its acknowledgement (e.g. "clamped to -10.0 dB"; usually empty)
+func SendIPCEvent(socketPath string, ev Event) (string, error) {
 	// Connect to socket
-	conn, err := net.Dial("unix", socketPath)
+	conn, err := dialIPC(socketPath)
 	if err != nil {
-		return fmt.Errorf("connect to %s: %w", socketPath, err)
+		return "", fmt.Errorf("connect to %s: %w", socketPath, err)
 	}
 	defer conn.Close()
 
 	// Marshal event
 	data, err := MarshalEvent(ev)
 	if err != nil {
-		return fmt.Errorf("marshal event: %w", err)
+		return "", fmt.Errorf("marshal event: %w", err)
 	}
 
 	// Send event
 	if _, err := fmt.Fprintf(conn, "%s\n", strings.TrimSpace(string(data))); err != nil {
-		return fmt.Errorf("send event: %w", err)
+		return "", fmt.Errorf("send event: %w", err)
 	}
 
 	// Read response
 	decoder := json.NewDecoder(conn)
 	var resp IPCResponse
 	if err := decoder.Decode(&resp); err != nil {
-		return fmt.Errorf("decode response: %w", err)
+		return "", fmt.Errorf("decode response: %w", err)
 	}
 
 	if resp.Status != "ok" {
-		return fmt.Errorf("ipc error: %s", resp.Error)
+		return "", fmt.Errorf("ipc error: %s", resp.Error)
 	}
 
-	return nil
+	return resp.Message, nil
 }
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIPCPipeName(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{"/tmp/streamerbrainz.sock", `\\.\pipe\streamerbrainz`},
+		{"/run/sb/living.sock", `\\.\pipe\living`},
+		{`C:\ProgramData\streamerbrainz\ctl.sock`, `\\.\pipe\ctl`},
+		{`\\.\pipe\custom`, `\\.\pipe\custom`},
+		{`\\.\PIPE\Custom`, `\\.\PIPE\Custom`},
+		{"", `\\.\pipe\streamerbrainz`},
+		{"/", `\\.\pipe\streamerbrainz`},
+	} {
+		if got := ipcPipeName(tc.in); got != tc.want {
+			t.Errorf("ipcPipeName(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestIPC_SendEventRoundTrip(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	socket := filepath.Join(t.TempDir(), "sb.sock")
+	events := make(chan Event, 1)
+	done := make(chan error, 1)
+	go func() { done <- runIPCServer(ctx, socket, events, slog.New(slog.DiscardHandler)) }()
+
+	got := make(chan Event, 1)
+	go func() {
+		ev, reply := splitAck(<-events)
+		got <- ev
+		reply <- EventAck{Message: "muted"}
+	}()
+
+	var msg string
+	var err error
+	for range 100 {
+		if msg, err = SendIPCEvent(socket, ToggleMute{}); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if _, ok := (<-got).(ToggleMute); !ok || msg != "muted" {
+		t.Fatalf("expected acknowledged ToggleMute, message %q", msg)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("server: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("server did not stop")
+	}
+}
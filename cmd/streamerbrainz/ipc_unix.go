@@ -0,0 +1,43 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// listenIPC listens on the Unix domain socket at socketPath, replacing a stale
+// socket file. cleanup removes the socket file again.
+func listenIPC(socketPath string) (listener net.Listener, addr string, cleanup func(), err error) {
+	// A socket that still accepts connections isn't stale: another process serves it.
+	if conn, err := net.DialTimeout("unix", socketPath, time.Second); err == nil {
+		conn.Close()
+		return nil, "", nil, fmt.Errorf("socket %s is in use by another process", socketPath)
+	}
+
+	// Remove existing socket file if it exists
+	if err := os.RemoveAll(socketPath); err != nil {
+		return nil, "", nil, fmt.Errorf("remove existing socket: %w", err)
+	}
+
+	listener, err = net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("listen on %s: %w", socketPath, err)
+	}
+
+	// Make socket accessible (consider security implications in production)
+	if err := os.Chmod(socketPath, 0666); err != nil {
+		listener.Close()
+		os.Remove(socketPath)
+		return nil, "", nil, fmt.Errorf("chmod socket: %w", err)
+	}
+	return listener, socketPath, func() { os.Remove(socketPath) }, nil
+}
+
+// dialIPC connects to the daemon's Unix domain socket.
+func dialIPC(socketPath string) (net.Conn, error) {
+	return net.Dial("unix", socketPath)
+}
@@ -0,0 +1,170 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// On Windows the IPC server listens on a named pipe (see ipcPipeName) instead of
+// a Unix domain socket. Pipe handles are synchronous; a goroutine blocked in
+// Accept is released on Close by connecting to the pipe once.
+
+const (
+	// pipeRejectRemoteClients keeps the pipe local (PIPE_REJECT_REMOTE_CLIENTS).
+	pipeRejectRemoteClients = 0x00000008
+	pipeBufferSize          = 64 << 10
+
+	// ipcPipeSDDL grants full access to SYSTEM, administrators and the owner, and
+	// read/write to authenticated users (the socket is 0666 on Unix).
+	ipcPipeSDDL = "D:P(A;;GA;;;SY)(A;;GA;;;BA)(A;;GA;;;OW)(A;;GRGW;;;AU)"
+
+	// ipcDialTimeout bounds how long dialIPC retries while every pipe instance is busy.
+	ipcDialTimeout = 2 * time.Second
+)
+
+type pipeAddr string
+
+func (pipeAddr) Network() string  { return "pipe" }
+func (a pipeAddr) String() string { return string(a) }
+
+// pipeConn is a connected pipe handle as a net.Conn.
+type pipeConn struct {
+	*os.File
+	addr pipeAddr
+}
+
+func (c *pipeConn) LocalAddr() net.Addr  { return c.addr }
+func (c *pipeConn) RemoteAddr() net.Addr { return c.addr }
+
+type pipeListener struct {
+	name string
+	sa   *windows.SecurityAttributes
+
+	mu      sync.Mutex
+	pending windows.Handle // instance waiting for the next client (0 if taken by Accept)
+	closed  bool
+}
+
+func listenIPC(socketPath string) (listener net.Listener, addr string, cleanup func(), err error) {
+	name := ipcPipeName(socketPath)
+	sd, err := windows.SecurityDescriptorFromString(ipcPipeSDDL)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("pipe security descriptor: %w", err)
+	}
+	l := &pipeListener{
+		name: name,
+		sa: &windows.SecurityAttributes{
+			Length:             uint32(unsafe.Sizeof(windows.SecurityAttributes{})),
+			SecurityDescriptor: sd,
+		},
+	}
+	// The first instance fails if another process already owns the pipe name.
+	l.pending, err = l.createInstance(true)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("listen on %s: %w", name, err)
+	}
+	return l, name, func() {}, nil
+}
+
+func (l *pipeListener) createInstance(first bool) (windows.Handle, error) {
+	name, err := windows.UTF16PtrFromString(l.name)
+	if err != nil {
+		return 0, err
+	}
+	flags := uint32(windows.PIPE_ACCESS_DUPLEX)
+	if first {
+		flags |= windows.FILE_FLAG_FIRST_PIPE_INSTANCE
+	}
+	return windows.CreateNamedPipe(name, flags,
+		windows.PIPE_TYPE_BYTE|windows.PIPE_READMODE_BYTE|windows.PIPE_WAIT|pipeRejectRemoteClients,
+		windows.PIPE_UNLIMITED_INSTANCES, pipeBufferSize, pipeBufferSize, 0, l.sa)
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil, net.ErrClosed
+	}
+	h := l.pending
+	l.pending = 0
+	l.mu.Unlock()
+
+	if h == 0 {
+		var err error
+		if h, err = l.createInstance(false); err != nil {
+			return nil, fmt.Errorf("create pipe instance: %w", err)
+		}
+	}
+
+	err := windows.ConnectNamedPipe(h, nil)
+	if err != nil && !errors.Is(err, windows.ERROR_PIPE_CONNECTED) {
+		windows.CloseHandle(h)
+		return nil, fmt.Errorf("connect pipe: %w", err)
+	}
+
+	l.mu.Lock()
+	closed := l.closed
+	if !closed && l.pending == 0 {
+		// Keep an instance listening so clients don't see "file not found" between accepts.
+		if next, err := l.createInstance(false); err == nil {
+			l.pending = next
+		}
+	}
+	l.mu.Unlock()
+	if closed {
+		windows.CloseHandle(h)
+		return nil, net.ErrClosed
+	}
+	return &pipeConn{File: os.NewFile(uintptr(h), l.name), addr: pipeAddr(l.name)}, nil
+}
+
+func (l *pipeListener) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	h := l.pending
+	l.pending = 0
+	l.mu.Unlock()
+
+	if h != 0 {
+		return windows.CloseHandle(h)
+	}
+	// Accept holds the only instance and is blocked in ConnectNamedPipe; connect to
+	// release it.
+	if f, err := os.OpenFile(l.name, os.O_RDWR, 0); err == nil {
+		f.Close()
+	}
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr { return pipeAddr(l.name) }
+
+// dialIPC connects to the daemon's named pipe, retrying briefly while all
+// instances are busy.
+func dialIPC(socketPath string) (net.Conn, error) {
+	name := ipcPipeName(socketPath)
+	deadline := time.Now().Add(ipcDialTimeout)
+	for {
+		f, err := os.OpenFile(name, os.O_RDWR, 0)
+		if err == nil {
+			return &pipeConn{File: f, addr: pipeAddr(name)}, nil
+		}
+		if !errors.Is(err, windows.ERROR_PIPE_BUSY) || time.Now().After(deadline) {
+			return nil, err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
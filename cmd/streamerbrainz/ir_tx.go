@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// IR transmit (blaster)
+// ============================================================================
+// Forwards named command sequences (power, input select, ...) to a "dumb"
+// amplifier through an IR transmitter:
+//
+//   - backend "irsend": lircd's irsend tool (`irsend SEND_ONCE <remote> <key>`).
+//   - backend "lirc":   a kernel rc-core transmitter (/dev/lircN, e.g. gpio-ir-tx)
+//                       fed protocol scancodes directly, no lircd required.
+//
+// Commands run when an `ir_send` event names them (IPC, webhooks, fifo, ...) or
+// when a configured trigger matches a state broadcast (e.g. amp on when playback
+// starts, amp off some time after it stops).
+// ============================================================================
+
+// IRSend requests transmission of a named ir_tx command.
+type IRSend struct {
+	Command string `json:"command"`
+}
+
+func (IRSend) eventMarker() {}
+
+// BroadcastIRSend announces that an ir_tx command was requested.
+type BroadcastIRSend struct {
+	Command string    `json:"command"`
+	At      time.Time `json:"at"`
+}
+
+func (BroadcastIRSend) stateBroadcastMarker() {}
+
+// IR transmit backends (config: ir_tx.backend).
+const (
+	irTxBackendIRSend = "irsend"
+	irTxBackendLirc   = "lirc"
+)
+
+// Trigger conditions (config: ir_tx.triggers[].on). Conditions with a ":<id>"
+// suffix match a specific output or zone.
+const (
+	irTriggerPlayerPlaying = "player_playing"
+	irTriggerPlayerStopped = "player_stopped" // paused or stopped
+	irTriggerMuteOn        = "mute_on"
+	irTriggerMuteOff       = "mute_off"
+	irTriggerOutputPrefix  = "output:"
+	irTriggerZonePrefix    = "zone:"
+)
+
+// rcProtocols maps protocol names to the kernel's enum rc_proto values
+// (include/uapi/linux/lirc.h).
+var rcProtocols = map[string]uint16{
+	"rc5":      2,
+	"rc5x_20":  3,
+	"rc5_sz":   4,
+	"jvc":      5,
+	"sony12":   6,
+	"sony15":   7,
+	"sony20":   8,
+	"nec":      9,
+	"necx":     10,
+	"nec32":    11,
+	"sanyo":    12,
+	"rc6_0":    15,
+	"rc6_6a20": 16,
+	"rc6_6a24": 17,
+	"rc6_6a32": 18,
+	"rc6_mce":  19,
+	"sharp":    20,
+	"xmp":      21,
+	"rcmm12":   24,
+	"rcmm24":   25,
+	"rcmm32":   26,
+}
+
+// irTransmitter sends one step of a command.
+type irTransmitter interface {
+	Send(ctx context.Context, step IRTxStepConfig) error
+	Close() error
+}
+
+// irsendTransmitter shells out to lircd's irsend.
+type irsendTransmitter struct {
+	path string
+}
+
+func (t irsendTransmitter) Send(ctx context.Context, step IRTxStepConfig) error {
+	args := []string{"SEND_ONCE", step.Remote, step.Key}
+	if step.Count > 1 {
+		args = append([]string{"--count=" + strconv.Itoa(step.Count)}, args...)
+	}
+	out, err := exec.CommandContext(ctx, t.path, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s: %w: %s", t.path, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (irsendTransmitter) Close() error { return nil }
+
+// openIRTransmitter opens the configured backend.
+func openIRTransmitter(cfg IRTxConfig) (irTransmitter, error) {
+	switch cfg.Backend {
+	case irTxBackendIRSend:
+		return irsendTransmitter{path: cfg.IRSendPath}, nil
+	case irTxBackendLirc:
+		return openLircTransmitter(cfg.Device)
+	}
+	return nil, fmt.Errorf("unknown ir_tx backend %q", cfg.Backend)
+}
+
+// irTriggersFor returns the trigger conditions a broadcast satisfies.
+func irTriggersFor(b StateBroadcast) []string {
+	switch ev := b.(type) {
+	case ZoneBroadcast:
+		return irTriggersFor(ev.Broadcast)
+	case BroadcastPlayerChanged:
+		switch ev.State {
+		case "playing":
+			return []string{irTriggerPlayerPlaying}
+		case "paused", "stopped":
+			return []string{irTriggerPlayerStopped}
+		}
+	case BroadcastMuteChanged:
+		if ev.Muted {
+			return []string{irTriggerMuteOn}
+		}
+		return []string{irTriggerMuteOff}
+	case BroadcastOutputChanged:
+		return []string{irTriggerOutputPrefix + ev.Output}
+	case BroadcastZoneSelected:
+		return []string{irTriggerZonePrefix + ev.Zone}
+	}
+	return nil
+}
+
+// runIRTx transmits commands requested via BroadcastIRSend or matched by triggers
+// until ctx is canceled or broadcasts is closed. Commands run one at a time in order.
+//
+// A delayed trigger (delay_ms) is canceled when any other trigger fires before it
+// runs, so e.g. "amp off 10 minutes after playback stops" is dropped if playback resumes.
+// It takes ownership of tx and closes it on exit.
+func runIRTx(ctx context.Context, cfg IRTxConfig, tx irTransmitter, broadcasts <-chan StateBroadcast, logger *slog.Logger) {
+	logger.Info("ir_tx enabled", "backend", cfg.Backend, "commands", len(cfg.Commands), "triggers", len(cfg.Triggers))
+
+	queue := make(chan string, 16)
+	workerDone := make(chan struct{})
+	defer func() {
+		<-workerDone
+		_ = tx.Close()
+	}()
+	go func() {
+		defer close(workerDone)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case name := <-queue:
+				runIRCommand(ctx, tx, name, cfg.Commands[name], logger)
+			}
+		}
+	}()
+	enqueue := func(name string) {
+		select {
+		case queue <- name:
+		default:
+			logger.Warn("ir_tx queue full, dropping command", "command", name)
+		}
+	}
+
+	var delayed []*time.Timer
+	cancelDelayed := func() {
+		for _, t := range delayed {
+			t.Stop()
+		}
+		delayed = nil
+	}
+	defer cancelDelayed()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case b, ok := <-broadcasts:
+			if !ok {
+				return
+			}
+			if req, isReq := b.(BroadcastIRSend); isReq {
+				if _, known := cfg.Commands[req.Command]; !known {
+					logger.Warn("ir_send: unknown command", "command", req.Command)
+					continue
+				}
+				enqueue(req.Command)
+				continue
+			}
+
+			var matched []IRTxTriggerConfig
+			for _, cond := range irTriggersFor(b) {
+				for _, trig := range cfg.Triggers {
+					if trig.On == cond {
+						matched = append(matched, trig)
+					}
+				}
+			}
+			if len(matched) == 0 {
+				continue
+			}
+			cancelDelayed()
+			for _, trig := range matched {
+				if trig.DelayMS <= 0 {
+					logger.Debug("ir_tx trigger", "on", trig.On, "command", trig.Command)
+					enqueue(trig.Command)
+					continue
+				}
+				logger.Debug("ir_tx trigger scheduled", "on", trig.On, "command", trig.Command, "delay_ms", trig.DelayMS)
+				delayed = append(delayed, time.AfterFunc(time.Duration(trig.DelayMS)*time.Millisecond, func() { enqueue(trig.Command) }))
+			}
+		}
+	}
+}
+
+// runIRCommand sends each step of a command, pausing delay_ms after each.
+func runIRCommand(ctx context.Context, tx irTransmitter, name string, steps []IRTxStepConfig, logger *slog.Logger) {
+	logger.Info("ir_tx sending", "command", name, "steps", len(steps))
+	for i, step := range steps {
+		if err := tx.Send(ctx, step); err != nil {
+			logger.Error("ir_tx send failed", "command", name, "step", i, "error", err)
+			return
+		}
+		if step.DelayMS > 0 {
+			select {
+			case <-time.After(time.Duration(step.DelayMS) * time.Millisecond):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
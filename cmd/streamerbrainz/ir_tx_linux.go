@@ -0,0 +1,64 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// lircSetSendMode is LIRC_SET_SEND_MODE (_IOW('i', 0x11, __u32)).
+	lircSetSendMode = 0x40046911
+	// lircModeScancode is LIRC_MODE_SCANCODE.
+	lircModeScancode = 0x8
+	// lircScancodeSize is sizeof(struct lirc_scancode).
+	lircScancodeSize = 32
+)
+
+// lircTransmitter writes struct lirc_scancode records to an rc-core lirc device;
+// the kernel encodes the protocol.
+type lircTransmitter struct {
+	f *os.File
+}
+
+func openLircTransmitter(path string) (irTransmitter, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open lirc device: %w", err)
+	}
+	if err := unix.IoctlSetPointerInt(int(f.Fd()), lircSetSendMode, lircModeScancode); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("set scancode send mode on %s: %w", path, err)
+	}
+	return &lircTransmitter{f: f}, nil
+}
+
+func (t *lircTransmitter) Send(ctx context.Context, step IRTxStepConfig) error {
+	rec := encodeLircScancode(rcProtocols[step.Protocol], step.Scancode)
+	count := max(step.Count, 1)
+	for i := 0; i < count; i++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if _, err := t.f.Write(rec); err != nil {
+			return fmt.Errorf("lirc write: %w", err)
+		}
+	}
+	return nil
+}
+
+func (t *lircTransmitter) Close() error { return t.f.Close() }
+
+// encodeLircScancode builds a struct lirc_scancode (timestamp, flags, rc_proto,
+// keycode, scancode); only rc_proto and scancode are used for transmit.
+func encodeLircScancode(proto uint16, scancode uint64) []byte {
+	b := make([]byte, lircScancodeSize)
+	binary.NativeEndian.PutUint16(b[10:], proto)
+	binary.NativeEndian.PutUint64(b[16:], scancode)
+	return b
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// openLircTransmitter is unavailable outside Linux (rc-core lirc devices).
+func openLircTransmitter(path string) (irTransmitter, error) {
+	return nil, errors.New("the lirc ir_tx backend requires linux")
+}
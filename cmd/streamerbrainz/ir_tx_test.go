@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// fakeIRTransmitter records sent steps.
+type fakeIRTransmitter struct {
+	sent chan IRTxStepConfig
+}
+
+func (f *fakeIRTransmitter) Send(ctx context.Context, step IRTxStepConfig) error {
+	f.sent <- step
+	return nil
+}
+
+func (f *fakeIRTransmitter) Close() error { return nil }
+
+func TestRunIRTx_SendsRequestedAndTriggeredCommands(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := IRTxConfig{
+		Commands: map[string][]IRTxStepConfig{
+			"amp_on":  {{Remote: "amp", Key: "KEY_POWER"}, {Remote: "amp", Key: "KEY_CD"}},
+			"amp_off": {{Remote: "amp", Key: "KEY_POWER2"}},
+		},
+		Triggers: []IRTxTriggerConfig{
+			{On: irTriggerPlayerPlaying, Command: "amp_on"},
+			{On: irTriggerPlayerStopped, Command: "amp_off", DelayMS: 50},
+		},
+	}
+	tx := &fakeIRTransmitter{sent: make(chan IRTxStepConfig, 8)}
+	broadcasts := make(chan StateBroadcast, 8)
+	go runIRTx(ctx, cfg, tx, broadcasts, slog.Default())
+
+	expectKeys := func(keys ...string) {
+		t.Helper()
+		for _, k := range keys {
+			select {
+			case st := <-tx.sent:
+				if st.Key != k {
+					t.Fatalf("expected %s, got %s", k, st.Key)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("timeout waiting for %s", k)
+			}
+		}
+	}
+
+	broadcasts <- BroadcastIRSend{Command: "amp_off"}
+	expectKeys("KEY_POWER2")
+
+	broadcasts <- ZoneBroadcast{Zone: "main", Broadcast: BroadcastPlayerChanged{Source: "plex", State: "playing"}}
+	expectKeys("KEY_POWER", "KEY_CD")
+
+	// Stop then resume before the delay elapses: the delayed amp_off is canceled.
+	broadcasts <- ZoneBroadcast{Zone: "main", Broadcast: BroadcastPlayerChanged{Source: "plex", State: "paused"}}
+	broadcasts <- ZoneBroadcast{Zone: "main", Broadcast: BroadcastPlayerChanged{Source: "plex", State: "playing"}}
+	expectKeys("KEY_POWER", "KEY_CD")
+	select {
+	case st := <-tx.sent:
+		t.Fatalf("delayed command should have been canceled, got %s", st.Key)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Stop and stay stopped: amp_off after the delay.
+	broadcasts <- ZoneBroadcast{Zone: "main", Broadcast: BroadcastPlayerChanged{Source: "plex", State: "stopped"}}
+	expectKeys("KEY_POWER2")
+}
+
+func TestIRTxConfig_Validate(t *testing.T) {
+	cfg := IRTxConfig{
+		Backend:  irTxBackendLirc,
+		Device:   "/dev/lirc0",
+		Commands: map[string][]IRTxStepConfig{"power": {{Protocol: "nec", Scancode: 0x0408}}},
+		Triggers: []IRTxTriggerConfig{{On: "output:headphones", Command: "power"}},
+	}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	bad := cfg
+	bad.Commands = map[string][]IRTxStepConfig{"power": {{Protocol: "morse"}}}
+	if err := bad.validate(); err == nil {
+		t.Errorf("expected unknown protocol error")
+	}
+	bad = cfg
+	bad.Triggers = []IRTxTriggerConfig{{On: "player_playing", Command: "nope"}}
+	if err := bad.validate(); err == nil {
+		t.Errorf("expected undefined command error")
+	}
+	bad = cfg
+	bad.Triggers = []IRTxTriggerConfig{{On: "sunrise", Command: "power"}}
+	if err := bad.validate(); err == nil {
+		t.Errorf("expected unsupported condition error")
+	}
+}
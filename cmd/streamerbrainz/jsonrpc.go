@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// ============================================================================
+// JSON-RPC 2.0 endpoint
+// ============================================================================
+// POST /jsonrpc on the control API listener, for streamer remote apps that speak
+// generic JSON-RPC (Volumio/moOde-style clients):
+//
+//   {"jsonrpc":"2.0","id":1,"method":"volume.set","params":{"db":-30}}
+//   {"jsonrpc":"2.0","id":1,"result":true}
+//
+// Methods:
+//   volume.get     -> {"volume_db": <f>, "known": <b>}
+//   volume.set     params {"db": <f>} or [<f>] -> true
+//   mute.toggle    -> true
+//   player.status  -> {"volume_db", "muted", "player": {...}|null}
+//
+// Every method accepts an optional "zone" in object params (default: current zone).
+// Batches and notifications (requests without id) are supported.
+// ============================================================================
+
+const (
+	// jsonRPCSnapshotTimeout bounds the state request made by read methods.
+	jsonRPCSnapshotTimeout = 1 * time.Second
+	maxJSONRPCBody         = 64 << 10
+)
+
+// Standard JSON-RPC 2.0 error codes.
+const (
+	jsonRPCParseError     = -32700
+	jsonRPCInvalidRequest = -32600
+	jsonRPCMethodNotFound = -32601
+	jsonRPCInvalidParams  = -32602
+	jsonRPCInternalError  = -32603
+)
+
+type jsonRPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+type jsonRPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  any             `json:"result,omitempty"`
+	Error   *jsonRPCError   `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type jsonRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *jsonRPCError) Error() string { return e.Message }
+
+// jsonRPCParams is the union of supported object params.
+type jsonRPCParams struct {
+	Zone string   `json:"zone"`
+	DB   *float64 `json:"db"`
+}
+
+// jsonRPCVolume is the result of volume.get.
+type jsonRPCVolume struct {
+	VolumeDB float64 `json:"volume_db"`
+	Known    bool    `json:"known"`
+}
+
+// jsonRPCStatus is the result of player.status.
+type jsonRPCStatus struct {
+	Zone     string          `json:"zone,omitempty"`
+	VolumeDB float64         `json:"volume_db"`
+	Muted    bool            `json:"muted"`
+	Player   *PlayerSnapshot `json:"player"`
+}
+
+// jsonRPCHandler serves POST /jsonrpc.
+type jsonRPCHandler struct {
+	events chan<- Event
+	logger *slog.Logger
+}
+
+func (h *jsonRPCHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxJSONRPCBody+1))
+	if err != nil || len(body) > maxJSONRPCBody {
+		writeJSONRPC(w, jsonRPCResponse{JSONRPC: "2.0", Error: &jsonRPCError{Code: jsonRPCInvalidRequest, Message: "invalid request body"}, ID: json.RawMessage("null")})
+		return
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err != nil {
+			writeJSONRPC(w, parseErrorResponse())
+			return
+		}
+		if len(batch) == 0 {
+			writeJSONRPC(w, jsonRPCResponse{JSONRPC: "2.0", Error: &jsonRPCError{Code: jsonRPCInvalidRequest, Message: "empty batch"}, ID: json.RawMessage("null")})
+			return
+		}
+		var out []jsonRPCResponse
+		for _, raw := range batch {
+			if resp, ok := h.handle(r, raw); ok {
+				out = append(out, resp)
+			}
+		}
+		if len(out) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSONRPC(w, out)
+		return
+	}
+
+	resp, ok := h.handle(r, body)
+	if !ok {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSONRPC(w, resp)
+}
+
+// handle processes one request. ok is false for notifications (no response).
+func (h *jsonRPCHandler) handle(r *http.Request, raw json.RawMessage) (jsonRPCResponse, bool) {
+	var req jsonRPCRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return parseErrorResponse(), true
+		}
+		return jsonRPCResponse{JSONRPC: "2.0", Error: &jsonRPCError{Code: jsonRPCInvalidRequest, Message: "invalid request"}, ID: json.RawMessage("null")}, true
+	}
+	notification := len(req.ID) == 0
+	id := req.ID
+	if notification {
+		id = json.RawMessage("null")
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return jsonRPCResponse{JSONRPC: "2.0", Error: &jsonRPCError{Code: jsonRPCInvalidRequest, Message: "invalid request"}, ID: id}, true
+	}
+
+	result, err := h.call(r, req.Method, req.Params)
+	if notification {
+		if err != nil {
+			h.logger.Debug("jsonrpc notification failed", "method", req.Method, "error", err)
+		}
+		return jsonRPCResponse{}, false
+	}
+	if err != nil {
+		var rpcErr *jsonRPCError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &jsonRPCError{Code: jsonRPCInternalError, Message: err.Error()}
+		}
+		return jsonRPCResponse{JSONRPC: "2.0", Error: rpcErr, ID: id}, true
+	}
+	return jsonRPCResponse{JSONRPC: "2.0", Result: result, ID: id}, true
+}
+
+// call dispatches a method.
+func (h *jsonRPCHandler) call(r *http.Request, method string, raw json.RawMessage) (any, error) {
+	params, positional, err := parseJSONRPCParams(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	switch method {
+	case "volume.get":
+		snap, err := h.snapshot(r, params.Zone)
+		if err != nil {
+			return nil, err
+		}
+		return jsonRPCVolume{VolumeDB: snap.VolumeDB, Known: snap.VolumeKnown}, nil
+
+	case "volume.set":
+		db := params.DB
+		if db == nil && len(positional) > 0 {
+			db = &positional[0]
+		}
+		if db == nil {
+			return nil, &jsonRPCError{Code: jsonRPCInvalidParams, Message: "volume.set requires params {\"db\": <number>} or [<number>]"}
+		}
+		return true, h.send(r, params.Zone, SetVolumeAbsolute{Db: *db, Origin: "jsonrpc"})
+
+	case "mute.toggle":
+		return true, h.send(r, params.Zone, ToggleMute{})
+
+	case "player.status":
+		snap, err := h.snapshot(r, params.Zone)
+		if err != nil {
+			return nil, err
+		}
+		return jsonRPCStatus{Zone: snap.Zone, VolumeDB: snap.VolumeDB, Muted: snap.Muted, Player: snap.Player}, nil
+	}
+
+	return nil, &jsonRPCError{Code: jsonRPCMethodNotFound, Message: fmt.Sprintf("method not found: %s", method)}
+}
+
+// parseJSONRPCParams accepts object params or an array of numbers.
+func parseJSONRPCParams(raw json.RawMessage) (jsonRPCParams, []float64, error) {
+	var params jsonRPCParams
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return params, nil, nil
+	}
+	if raw[0] == '[' {
+		var positional []float64
+		if err := json.Unmarshal(raw, &positional); err != nil {
+			return params, nil, &jsonRPCError{Code: jsonRPCInvalidParams, Message: "positional params must be numbers"}
+		}
+		return params, positional, nil
+	}
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return params, nil, &jsonRPCError{Code: jsonRPCInvalidParams, Message: fmt.Sprintf("invalid params: %v", err)}
+	}
+	return params, nil, nil
+}
+
+// send queues ev for zone (empty = current zone).
+func (h *jsonRPCHandler) send(r *http.Request, zone string, ev Event) error {
+	if zone != "" {
+		ev = ZonedEvent{Zone: zone, Event: ev}
+	}
+	select {
+	case h.events <- ev:
+		return nil
+	case <-r.Context().Done():
+		return r.Context().Err()
+	default:
+		return &jsonRPCError{Code: jsonRPCInternalError, Message: "event queue full"}
+	}
+}
+
+// snapshot returns the state of zone (empty = current zone).
+func (h *jsonRPCHandler) snapshot(r *http.Request, zone string) (StateSnapshot, error) {
+	snap, err := requestStateSnapshot(r.Context(), h.events, jsonRPCSnapshotTimeout)
+	if err != nil {
+		return StateSnapshot{}, &jsonRPCError{Code: jsonRPCInternalError, Message: "state unavailable"}
+	}
+	if zone == "" || zone == snap.Zone {
+		return snap, nil
+	}
+	for _, z := range snap.Zones {
+		if z.Zone == zone {
+			return z, nil
+		}
+	}
+	return StateSnapshot{}, &jsonRPCError{Code: jsonRPCInvalidParams, Message: fmt.Sprintf("unknown zone: %s", zone)}
+}
+
+func parseErrorResponse() jsonRPCResponse {
+	return jsonRPCResponse{JSONRPC: "2.0", Error: &jsonRPCError{Code: jsonRPCParseError, Message: "parse error"}, ID: json.RawMessage("null")}
+}
+
+func writeJSONRPC(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
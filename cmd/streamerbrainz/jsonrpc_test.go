@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// serveSnapshots answers snapshot requests on events with snap and forwards other events to out.
+func serveSnapshots(events <-chan Event, snap StateSnapshot, out chan<- Event) {
+	for ev := range events {
+		if req, ok := ev.(RequestStateSnapshot); ok {
+			req.Reply <- snap
+			continue
+		}
+		out <- ev
+	}
+}
+
+func postJSONRPC(t *testing.T, h http.Handler, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/jsonrpc", strings.NewReader(body)))
+	return rec
+}
+
+func TestJSONRPC_Methods(t *testing.T) {
+	events := make(chan Event)
+	defer close(events)
+	forwarded := make(chan Event, 4)
+	go serveSnapshots(events, StateSnapshot{
+		Zone: "main", VolumeDB: -30, VolumeKnown: true, Muted: true,
+		Player: &PlayerSnapshot{Source: "plex", State: "playing", Title: "Song"},
+	}, forwarded)
+	h := &jsonRPCHandler{events: events, logger: slog.Default()}
+
+	rec := postJSONRPC(t, h, `{"jsonrpc":"2.0","id":1,"method":"volume.get"}`)
+	if got := strings.TrimSpace(rec.Body.String()); got != `{"jsonrpc":"2.0","result":{"volume_db":-30,"known":true},"id":1}` {
+		t.Fatalf("volume.get: %s", got)
+	}
+
+	rec = postJSONRPC(t, h, `{"jsonrpc":"2.0","id":"a","method":"player.status"}`)
+	var status struct {
+		Result jsonRPCStatus `json:"result"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	if !status.Result.Muted || status.Result.Player == nil || status.Result.Player.Title != "Song" {
+		t.Fatalf("player.status: %s", rec.Body.String())
+	}
+
+	postJSONRPC(t, h, `{"jsonrpc":"2.0","id":2,"method":"volume.set","params":[-20]}`)
+	if ev := <-forwarded; !reflect.DeepEqual(ev, SetVolumeAbsolute{Db: -20, Origin: "jsonrpc"}) {
+		t.Fatalf("volume.set: got %#v", ev)
+	}
+
+	// Notification: no response body; zone-targeted.
+	rec = postJSONRPC(t, h, `{"jsonrpc":"2.0","method":"mute.toggle","params":{"zone":"phones"}}`)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("notification: expected 204, got %d", rec.Code)
+	}
+	if ev := <-forwarded; !reflect.DeepEqual(ev, ZonedEvent{Zone: "phones", Event: ToggleMute{}}) {
+		t.Fatalf("mute.toggle: got %#v", ev)
+	}
+}
+
+func TestJSONRPC_ErrorsAndBatch(t *testing.T) {
+	events := make(chan Event)
+	defer close(events)
+	go serveSnapshots(events, StateSnapshot{Zone: "main"}, make(chan Event, 4))
+	h := &jsonRPCHandler{events: events, logger: slog.Default()}
+
+	tests := []struct {
+		body string
+		code int
+	}{
+		{`{not json`, jsonRPCParseError},
+		{`{"jsonrpc":"1.0","id":1,"method":"volume.get"}`, jsonRPCInvalidRequest},
+		{`{"jsonrpc":"2.0","id":1,"method":"nope"}`, jsonRPCMethodNotFound},
+		{`{"jsonrpc":"2.0","id":1,"method":"volume.set","params":{}}`, jsonRPCInvalidParams},
+		{`{"jsonrpc":"2.0","id":1,"method":"volume.get","params":{"zone":"nope"}}`, jsonRPCInvalidParams},
+	}
+	for _, tt := range tests {
+		var resp jsonRPCResponse
+		if err := json.Unmarshal(postJSONRPC(t, h, tt.body).Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: %v", tt.body, err)
+		}
+		if resp.Error == nil || resp.Error.Code != tt.code {
+			t.Errorf("%s: expected error %d, got %+v", tt.body, tt.code, resp.Error)
+		}
+	}
+
+	var batch []jsonRPCResponse
+	rec := postJSONRPC(t, h, `[{"jsonrpc":"2.0","id":1,"method":"volume.get"},{"jsonrpc":"2.0","method":"mute.toggle"},{"jsonrpc":"2.0","id":3,"method":"nope"}]`)
+	if err := json.Unmarshal(rec.Body.Bytes(), &batch); err != nil {
+		t.Fatal(err)
+	}
+	if len(batch) != 2 || batch[0].Error != nil || batch[1].Error == nil {
+		t.Fatalf("unexpected batch response: %s", rec.Body.String())
+	}
+}
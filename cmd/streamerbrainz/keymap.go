@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ============================================================================
+// Per-device keymaps
+// ============================================================================
+// By default the decoder acts on the standard codes (KEY_VOLUMEUP, KEY_MUTE,
+// ...). Remotes that send other codes get a keymap on their input:
+//
+//	inputs:
+//	  - path: /dev/input/by-id/usb-flirc-event-kbd
+//	    type: key
+//	    keymap:
+//	      - {code: 103, action: volume_up}
+//	      - {code: 108, action: volume_down}
+//	      - {code: 2, action: preset, volume_db: -35}
+//
+// A mapped code is rewritten to the action's standard code before decoding, so
+// it behaves exactly like that key (holds, combos, long press). "preset" jumps
+// to volume_db on press. Codes not in the keymap keep their default meaning.
+//
+// Raw IR receivers (gpio-ir, rc-core) without a kernel keymap loaded only send
+// EV_MSC/MSC_SCAN with the protocol scancode, so a binding can name a scancode
+// instead ({scancode: 0x40bf, action: volume_up}). The receiver repeats the
+// scancode while the button is held and sends nothing on release, so the first
+// MSC_SCAN is a press, the same scancode again within scancodeReleaseTimeout a
+// repeat, and the release is synthesized once it stops. If a kernel keymap is
+// loaded after all, the EV_KEY that follows a bound scancode is dropped.
+// ============================================================================
+
+// scancodeReleaseTimeout ends a scancode hold: rc-core receivers repeat every
+// ~110ms (NEC, RC-5, RC-6) while a button is held.
+const scancodeReleaseTimeout = 250 * time.Millisecond
+
+// keymapPresetAction jumps to the binding's volume_db.
+const keymapPresetAction = "preset"
+
+// keymapActionCodes maps keymap actions to the standard key codes they stand for.
+var keymapActionCodes = map[string]uint16{
+	"volume_up":     KEY_VOLUMEUP,
+	"volume_down":   KEY_VOLUMEDOWN,
+	"mute":          KEY_MUTE,
+	"output_select": KEY_AUDIO,
+	"play_pause":    KEY_PLAYPAUSE,
+	"play":          KEY_PLAYCD,
+	"pause":         KEY_PAUSECD,
+	"stop":          KEY_STOPCD,
+	"next":          KEY_NEXTSONG,
+	"previous":      KEY_PREVIOUSSONG,
+	"button":        BTN_0,
+}
+
+// KeyBinding binds one key code or scancode of an input device to an action.
+type KeyBinding struct {
+	Code     uint16   `yaml:"code,omitempty"`      // EV_KEY code the device sends
+	Scancode *uint32  `yaml:"scancode,omitempty"`  // or: MSC_SCAN scancode (raw IR receivers)
+	Action   string   `yaml:"action"`              // see keymapActionNames
+	VolumeDB *float64 `yaml:"volume_db,omitempty"` // preset volume (action "preset" only)
+}
+
+// keymapActionNames returns the valid keymap actions, sorted.
+func keymapActionNames() []string {
+	names := []string{keymapPresetAction}
+	for name := range keymapActionCodes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// validateKeymap checks a device keymap; prefix names it in errors.
+func validateKeymap(prefix string, keymap []KeyBinding, dsp CamillaDSPConfig) error {
+	codes := make(map[uint16]bool, len(keymap))
+	scancodes := make(map[uint32]bool)
+	for i, b := range keymap {
+		switch {
+		case (b.Code == 0) == (b.Scancode == nil):
+			return fmt.Errorf("%s[%d] must set exactly one of code (> 0) or scancode", prefix, i)
+		case b.Scancode != nil && scancodes[*b.Scancode]:
+			return fmt.Errorf("%s[%d]: scancode %#x is bound twice", prefix, i, *b.Scancode)
+		case b.Scancode != nil:
+			scancodes[*b.Scancode] = true
+		case codes[b.Code]:
+			return fmt.Errorf("%s[%d]: code %d is bound twice", prefix, i, b.Code)
+		default:
+			codes[b.Code] = true
+		}
+
+		if b.Action == keymapPresetAction {
+			if b.VolumeDB == nil {
+				return fmt.Errorf("%s[%d]: action %q requires volume_db", prefix, i, keymapPresetAction)
+			}
+			if *b.VolumeDB < dsp.MinDB || *b.VolumeDB > dsp.MaxDB {
+				return fmt.Errorf("%s[%d].volume_db must be within camilladsp.min_db..max_db", prefix, i)
+			}
+			continue
+		}
+		if _, ok := keymapActionCodes[b.Action]; !ok {
+			return fmt.Errorf("%s[%d].action %q is not one of %v", prefix, i, b.Action, keymapActionNames())
+		}
+		if b.VolumeDB != nil {
+			return fmt.Errorf("%s[%d].volume_db is only valid for action %q", prefix, i, keymapPresetAction)
+		}
+	}
+	return nil
+}
+
+// keymapByCode indexes a keymap by key code and by scancode (nil maps for none).
+func keymapByCode(keymap []KeyBinding) (byCode map[uint16]KeyBinding, byScancode map[uint32]KeyBinding) {
+	for _, b := range keymap {
+		if b.Scancode != nil {
+			if byScancode == nil {
+				byScancode = make(map[uint32]KeyBinding)
+			}
+			byScancode[*b.Scancode] = b
+			continue
+		}
+		if byCode == nil {
+			byCode = make(map[uint16]KeyBinding)
+		}
+		byCode[b.Code] = b
+	}
+	return byCode, byScancode
+}
+
+// applyKeymap rewrites a mapped key to its action's standard code. Presets are
+// emitted here (on press) and reported as consumed.
+func (d *inputDecoder) applyKeymap(ev inputEvent) (inputEvent, bool) {
+	b, ok := d.keymap[ev.Code]
+	if !ok {
+		return ev, false
+	}
+	return d.bindKey(b, ev)
+}
+
+// bindKey rewrites ev to b's action, emitting presets (on press) directly and
+// reporting them as consumed.
+func (d *inputDecoder) bindKey(b KeyBinding, ev inputEvent) (inputEvent, bool) {
+	if b.Action == keymapPresetAction {
+		if ev.Value == evValuePress {
+			d.events <- SetVolumeAbsolute{Db: *b.VolumeDB}
+		}
+		return ev, true
+	}
+	ev.Code = keymapActionCodes[b.Action]
+	return ev, false
+}
+
+// scancodeState tracks the scancode button currently held on a device.
+type scancodeState struct {
+	frame   bool        // a bound scancode was seen in the current frame
+	held    bool        // a bound scancode is held (no release synthesized yet)
+	code    uint32      // the held scancode
+	timer   *time.Timer // synthesizes the release
+	timerID int         // invalidates callbacks of stopped timers
+}
+
+// handleScancode turns MSC_SCAN of a bound scancode into a press, or a repeat
+// while the same scancode keeps arriving, and (re)arms the release timer.
+func (d *inputDecoder) handleScancode(ev inputEvent) {
+	code := uint32(ev.Value)
+	b, ok := d.scanKeymap[code]
+	if !ok {
+		return
+	}
+	d.scan.frame = true
+
+	value := int32(evValueRepeat)
+	if !d.scan.held || d.scan.code != code {
+		d.releaseScancode()
+		d.scan.held, d.scan.code = true, code
+		value = evValuePress
+	}
+	if key, consumed := d.bindKey(b, inputEvent{Sec: ev.Sec, Usec: ev.Usec, Type: EV_KEY, Value: value}); !consumed {
+		d.handleKey(key)
+	}
+
+	if d.scan.timer != nil {
+		d.scan.timer.Stop()
+	}
+	d.scan.timerID++
+	id := d.scan.timerID
+	d.scan.timer = time.AfterFunc(scancodeReleaseTimeout, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if d.scan.timerID == id {
+			d.releaseScancode()
+		}
+	})
+}
+
+// releaseScancode synthesizes the release of the held scancode, if any.
+func (d *inputDecoder) releaseScancode() {
+	if !d.scan.held {
+		return
+	}
+	d.scan.held = false
+	b := d.scanKeymap[d.scan.code]
+	now := time.Now()
+	ev := inputEvent{Sec: now.Unix(), Usec: int64(now.Nanosecond() / 1000), Type: EV_KEY, Value: evValueRelease}
+	if key, consumed := d.bindKey(b, ev); !consumed {
+		d.handleKey(key)
+	}
+}
+
+// resetScancode forgets a held scancode without releasing it (the device went away).
+func (d *inputDecoder) resetScancode() {
+	if d.scan.timer != nil {
+		d.scan.timer.Stop()
+	}
+	d.scan = scancodeState{timerID: d.scan.timerID + 1}
+}
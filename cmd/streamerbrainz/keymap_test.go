@@ -0,0 +1,129 @@
+package main
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestInputDecoder_Keymap(t *testing.T) {
+	events := make(chan Event, 8)
+	preset := -35.0
+	d := newInputDecoder(events, inputDecoderConfig{Keymap: []KeyBinding{
+		{Code: 103, Action: "volume_up"}, // KEY_UP
+		{Code: 2, Action: keymapPresetAction, VolumeDB: &preset},
+		{Code: KEY_VOLUMEUP, Action: "mute"},
+	}}, &Metrics{}, slog.New(slog.DiscardHandler))
+	key := func(code uint16, value int32) { d.handle(inputEvent{Type: EV_KEY, Code: code, Value: value}) }
+
+	key(103, evValuePress)
+	key(103, evValueRepeat)
+	key(103, evValueRelease)
+	for _, want := range []Event{volumeHeldUpEvent, volumeHeldUpEvent, VolumeRelease{}} {
+		if ev := <-events; ev != want {
+			t.Fatalf("remapped key: got %#v, want %#v", ev, want)
+		}
+	}
+
+	// Presets fire on press only.
+	key(2, evValuePress)
+	key(2, evValueRepeat)
+	key(2, evValueRelease)
+	if ev := <-events; ev != (SetVolumeAbsolute{Db: -35}) || len(events) != 0 {
+		t.Fatalf("preset: got %#v (+%d)", ev, len(events))
+	}
+
+	// A standard code can be rebound; unmapped keys keep their meaning.
+	key(KEY_VOLUMEUP, evValuePress)
+	key(KEY_MUTE, evValuePress)
+	if ev := <-events; ev != (ToggleMute{}) {
+		t.Fatalf("rebound KEY_VOLUMEUP: got %#v", ev)
+	}
+	if ev := <-events; ev != (ToggleMute{}) {
+		t.Fatalf("unmapped KEY_MUTE: got %#v", ev)
+	}
+}
+
+func TestInputDecoder_ScancodeKeymap(t *testing.T) {
+	events := make(chan Event, 16)
+	up, mute := uint32(0x40bf), uint32(0x4012)
+	d := newInputDecoder(events, inputDecoderConfig{Keymap: []KeyBinding{
+		{Scancode: &up, Action: "volume_up"},
+		{Scancode: &mute, Action: "mute"},
+	}}, &Metrics{}, slog.New(slog.DiscardHandler))
+	scan := func(code uint32, withKey bool) {
+		d.handle(inputEvent{Type: EV_MSC, Code: MSC_SCAN, Value: int32(code)})
+		if withKey {
+			// A kernel keymap that maps the scancode to something else.
+			d.handle(inputEvent{Type: EV_KEY, Code: KEY_NEXTSONG, Value: evValuePress})
+		}
+		d.handle(inputEvent{Type: EV_SYN, Code: SYN_REPORT})
+	}
+	next := func() Event {
+		t.Helper()
+		select {
+		case ev := <-events:
+			return ev
+		case <-time.After(2 * time.Second):
+			t.Fatal("no event")
+			return nil
+		}
+	}
+
+	// Held: press, repeats, then the release once the scancode stops.
+	scan(up, false)
+	scan(up, true)
+	scan(up, false)
+	for i, want := range []Event{volumeHeldUpEvent, volumeHeldUpEvent, volumeHeldUpEvent, VolumeRelease{}} {
+		if ev := next(); ev != want {
+			t.Fatalf("event %d: got %#v, want %#v", i, ev, want)
+		}
+	}
+
+	// Repeated mute frames toggle once; another scancode releases the held one first.
+	scan(mute, false)
+	scan(mute, false)
+	scan(up, false)
+	for i, want := range []Event{ToggleMute{}, volumeHeldUpEvent, VolumeRelease{}} {
+		if ev := next(); ev != want {
+			t.Fatalf("event %d: got %#v, want %#v", i, ev, want)
+		}
+	}
+
+	// Unbound scancodes are ignored and leave the kernel's key alone.
+	scan(0x1, true)
+	if ev := next(); ev != (MediaNext{}) {
+		t.Fatalf("unbound scancode: got %#v", ev)
+	}
+}
+
+func TestValidateKeymap(t *testing.T) {
+	dsp := DefaultConfig().CamillaDSP
+	db := func(v float64) *float64 { return &v }
+	scancode := func(v uint32) *uint32 { return &v }
+	for name, tc := range map[string]struct {
+		keymap []KeyBinding
+		ok     bool
+	}{
+		"valid":              {[]KeyBinding{{Code: 103, Action: "volume_up"}, {Code: 2, Action: "preset", VolumeDB: db(-30)}}, true},
+		"unknown action":     {[]KeyBinding{{Code: 103, Action: "louder"}}, false},
+		"zero code":          {[]KeyBinding{{Action: "mute"}}, false},
+		"scancode":           {[]KeyBinding{{Scancode: scancode(0), Action: "mute"}, {Scancode: scancode(0x40bf), Action: "stop"}}, true},
+		"code and scancode":  {[]KeyBinding{{Code: 5, Scancode: scancode(5), Action: "mute"}}, false},
+		"duplicate scancode": {[]KeyBinding{{Scancode: scancode(9), Action: "mute"}, {Scancode: scancode(9), Action: "stop"}}, false},
+		"duplicate code":     {[]KeyBinding{{Code: 5, Action: "mute"}, {Code: 5, Action: "stop"}}, false},
+		"preset without dB":  {[]KeyBinding{{Code: 2, Action: "preset"}}, false},
+		"preset above max":   {[]KeyBinding{{Code: 2, Action: "preset", VolumeDB: db(dsp.MaxDB + 1)}}, false},
+		"volume_db on a key": {[]KeyBinding{{Code: 2, Action: "mute", VolumeDB: db(-30)}}, false},
+	} {
+		if err := validateKeymap("keymap", tc.keymap, dsp); (err == nil) != tc.ok {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+
+	cfg := DefaultConfig()
+	cfg.Inputs = []InputDevice{{Path: "/run/sb", Type: InputDeviceTypeFifo, Keymap: []KeyBinding{{Code: 1, Action: "mute"}}}}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("keymap accepted on a fifo input")
+	}
+}
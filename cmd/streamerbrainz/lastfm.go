@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// Last.fm scrobbler
+// ============================================================================
+// Signed POSTs to the Last.fm 2.0 API (track.scrobble, track.updateNowPlaying)
+// with the account's session key, which `streamerbrainz lastfm-login` obtains.
+// Temporary API errors (service offline, rate limits, ...) are retried; any
+// other error means Last.fm refused the scrobbles and they are dropped.
+// ============================================================================
+
+// lastfmAPIURL is a variable so tests can point the client at a fake API.
+var lastfmAPIURL = "https://ws.audioscrobbler.com/2.0/"
+
+// lastfmMaxBatch is the most scrobbles track.scrobble accepts at once.
+const lastfmMaxBatch = 50
+
+// lastfmRetryableCodes are the API error codes worth retrying.
+var lastfmRetryableCodes = map[int]bool{
+	4:  true, // authentication failed (possibly transient)
+	9:  true, // invalid session key (re-run lastfm-login; keep the queue meanwhile)
+	10: true, // invalid API key (fix the config; keep the queue meanwhile)
+	11: true, // service offline
+	16: true, // temporarily unavailable
+	26: true, // API key suspended
+	29: true, // rate limit exceeded
+}
+
+// lastfmError is an error response from the API.
+type lastfmError struct {
+	Code    int    `json:"error"`
+	Message string `json:"message"`
+}
+
+func (e *lastfmError) Error() string { return fmt.Sprintf("last.fm error %d: %s", e.Code, e.Message) }
+
+type lastfmScrobbler struct {
+	apiKey     string
+	secret     string
+	sessionKey string
+	client     *http.Client
+}
+
+func newLastFMScrobbler(cfg LastFMConfig) (*lastfmScrobbler, error) {
+	secret, err := readSecret(cfg.APISecret)
+	if err != nil {
+		return nil, fmt.Errorf("api_secret: %w", err)
+	}
+	sk, err := readSecret(cfg.SessionKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("session_key_file: %w (run `streamerbrainz lastfm-login`)", err)
+	}
+	return &lastfmScrobbler{apiKey: cfg.APIKey, secret: secret, sessionKey: sk, client: &http.Client{Timeout: scrobbleHTTPTimeout}}, nil
+}
+
+func (l *lastfmScrobbler) Name() string  { return "lastfm" }
+func (l *lastfmScrobbler) MaxBatch() int { return lastfmMaxBatch }
+
+func (l *lastfmScrobbler) Scrobble(ctx context.Context, tracks []scrobbleTrack) error {
+	params := url.Values{"sk": {l.sessionKey}}
+	for i, t := range tracks {
+		k := func(name string) string { return fmt.Sprintf("%s[%d]", name, i) }
+		params.Set(k("artist"), t.Artist)
+		params.Set(k("track"), t.Title)
+		params.Set(k("timestamp"), strconv.FormatInt(t.StartedAt.Unix(), 10))
+		params.Set(k("chosenByUser"), "1")
+		if t.Album != "" {
+			params.Set(k("album"), t.Album)
+		}
+		if t.DurationMs > 0 {
+			params.Set(k("duration"), strconv.FormatInt(t.DurationMs/1000, 10))
+		}
+	}
+	err := lastfmCall(ctx, l.client, "track.scrobble", l.apiKey, l.secret, params, nil)
+	if apiErr, ok := err.(*lastfmError); ok && !lastfmRetryableCodes[apiErr.Code] {
+		return &scrobbleRejectedError{err: err}
+	}
+	return err
+}
+
+func (l *lastfmScrobbler) NowPlaying(ctx context.Context, t scrobbleTrack) error {
+	params := url.Values{"sk": {l.sessionKey}, "artist": {t.Artist}, "track": {t.Title}}
+	if t.Album != "" {
+		params.Set("album", t.Album)
+	}
+	if t.DurationMs > 0 {
+		params.Set("duration", strconv.FormatInt(t.DurationMs/1000, 10))
+	}
+	return lastfmCall(ctx, l.client, "track.updateNowPlaying", l.apiKey, l.secret, params, nil)
+}
+
+// lastfmSignature is the api_sig of params: the md5 of every name and value
+// (sorted by name, format excluded) followed by the shared secret.
+func lastfmSignature(params url.Values, secret string) string {
+	names := make([]string, 0, len(params))
+	for k := range params {
+		if k != "format" {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, k := range names {
+		b.WriteString(k)
+		b.WriteString(params.Get(k))
+	}
+	b.WriteString(secret)
+	sum := md5.Sum([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// lastfmCall makes a signed API call and decodes the JSON response into out
+// (if non-nil). API errors are returned as *lastfmError.
+func lastfmCall(ctx context.Context, client *http.Client, method, apiKey, secret string, params url.Values, out any) error {
+	form := url.Values{}
+	for k, v := range params {
+		form[k] = v
+	}
+	form.Set("method", method)
+	form.Set("api_key", apiKey)
+	form.Set("api_sig", lastfmSignature(form, secret))
+	form.Set("format", "json")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, lastfmAPIURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", "streamerbrainz/"+version)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	var apiErr lastfmError
+	if json.Unmarshal(body, &apiErr) == nil && apiErr.Code != 0 {
+		return &apiErr
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body[:min(len(body), 512)])))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(body, out)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// `lastfm-login` subcommand
+// ============================================================================
+//   streamerbrainz lastfm-login [-config path] [-timeout 10m]
+//
+// Runs the Last.fm desktop auth flow with scrobble.lastfm.api_key/api_secret:
+// get a token, ask the user to approve it at last.fm, poll auth.getSession
+// until it is approved, then write the session key to
+// scrobble.lastfm.session_key_file (mode 0600).
+// ============================================================================
+
+// Variables so tests can point the flow at a fake last.fm and poll faster.
+var (
+	lastfmAuthURL          = "https://www.last.fm/api/auth/"
+	lastfmAuthPollInterval = 3 * time.Second
+)
+
+// lastfmErrTokenUnauthorized is auth.getSession's "not approved yet".
+const lastfmErrTokenUnauthorized = 14
+
+func printLastFMLoginUsage() {
+	fmt.Println("USAGE:")
+	fmt.Println("  streamerbrainz lastfm-login [-config path] [-timeout 10m]")
+	fmt.Println()
+	fmt.Println("  Authorize StreamerBrainz to scrobble to your Last.fm account and write the")
+	fmt.Println("  session key to scrobble.lastfm.session_key_file.")
+	fmt.Println()
+}
+
+// runLastFMLoginSubcommand handles `streamerbrainz lastfm-login`.
+func runLastFMLoginSubcommand(args []string) {
+	fs := flag.NewFlagSet("lastfm-login", flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to YAML config file")
+	timeout := fs.Duration("timeout", 10*time.Minute, "How long to wait for the authorization")
+	fs.Usage = printLastFMLoginUsage
+	fs.Parse(args)
+
+	cfg, err := LoadConfigFile(ResolveConfigPath(*configPath))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	cfg.expandPaths()
+	fm := cfg.Scrobble.LastFM
+
+	if fm.APIKey == "" || fm.APISecret == "" {
+		fmt.Fprintln(os.Stderr, "error: set scrobble.lastfm.api_key and api_secret first (create an API account at https://www.last.fm/api/account/create)")
+		os.Exit(1)
+	}
+	keyFile := fm.SessionKeyFile
+	if keyFile == "" {
+		fmt.Fprintln(os.Stderr, "error: scrobble.lastfm.session_key_file is empty; set it to the file the session key should be written to")
+		os.Exit(1)
+	}
+	if strings.HasPrefix(keyFile, secretPrefixEnv) || strings.HasPrefix(keyFile, secretPrefixCredential) || strings.HasPrefix(keyFile, secretPrefixExec) {
+		fmt.Fprintf(os.Stderr, "error: scrobble.lastfm.session_key_file is %q; lastfm-login can only write to a file path\n", keyFile)
+		os.Exit(1)
+	}
+	keyFile = ExpandPath(strings.TrimPrefix(keyFile, secretPrefixFile))
+
+	secret, err := readSecret(fm.APISecret)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error: scrobble.lastfm.api_secret:", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	client := &http.Client{Timeout: scrobbleHTTPTimeout}
+	sk, user, err := lastfmDesktopLogin(ctx, client, fm.APIKey, secret, func(authURL string) {
+		fmt.Println("Open this URL in a browser and allow access for StreamerBrainz:")
+		fmt.Println()
+		fmt.Println("  " + authURL)
+		fmt.Println()
+		fmt.Println("Waiting for approval...")
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+
+	if err := writeTokenFile(keyFile, sk); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Session key for %s written to %s\n", user, keyFile)
+}
+
+// lastfmDesktopLogin runs the desktop auth flow and returns the session key and
+// user name. prompt receives the URL the user must open.
+func lastfmDesktopLogin(ctx context.Context, client *http.Client, apiKey, secret string, prompt func(authURL string)) (sessionKey, user string, err error) {
+	var tok struct {
+		Token string `json:"token"`
+	}
+	if err := lastfmCall(ctx, client, "auth.getToken", apiKey, secret, url.Values{}, &tok); err != nil {
+		return "", "", fmt.Errorf("request last.fm token: %w", err)
+	}
+	if tok.Token == "" {
+		return "", "", errors.New("request last.fm token: empty token in response")
+	}
+
+	q := url.Values{}
+	q.Set("api_key", apiKey)
+	q.Set("token", tok.Token)
+	prompt(lastfmAuthURL + "?" + q.Encode())
+
+	ticker := time.NewTicker(lastfmAuthPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return "", "", errors.New("timed out waiting for the last.fm authorization")
+		case <-ticker.C:
+		}
+
+		var resp struct {
+			Session struct {
+				Name string `json:"name"`
+				Key  string `json:"key"`
+			} `json:"session"`
+		}
+		err := lastfmCall(ctx, client, "auth.getSession", apiKey, secret, url.Values{"token": {tok.Token}}, &resp)
+		var apiErr *lastfmError
+		switch {
+		case errors.As(err, &apiErr) && apiErr.Code == lastfmErrTokenUnauthorized:
+			continue
+		case err != nil:
+			if ctx.Err() != nil {
+				return "", "", errors.New("timed out waiting for the last.fm authorization")
+			}
+			return "", "", fmt.Errorf("get last.fm session: %w", err)
+		case resp.Session.Key == "":
+			return "", "", errors.New("get last.fm session: empty session key in response")
+		}
+		return resp.Session.Key, resp.Session.Name, nil
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLastFMDesktopLogin_PollsUntilApproved(t *testing.T) {
+	var polls atomic.Int32
+	srv := lastfmServer(t, func(form url.Values) (int, string) {
+		switch form.Get("method") {
+		case "auth.getToken":
+			return http.StatusOK, `{"token":"tok123"}`
+		case "auth.getSession":
+			if form.Get("token") != "tok123" {
+				return http.StatusOK, `{"error":15,"message":"expired"}`
+			}
+			if polls.Add(1) < 3 {
+				return http.StatusForbidden, `{"error":14,"message":"This token has not been authorized"}`
+			}
+			return http.StatusOK, `{"session":{"name":"nina","key":"session-key","subscriber":0}}`
+		}
+		return http.StatusOK, `{"error":3,"message":"Invalid method"}`
+	})
+
+	oldInterval := lastfmAuthPollInterval
+	lastfmAuthPollInterval = 5 * time.Millisecond
+	defer func() { lastfmAuthPollInterval = oldInterval }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var authURL string
+	sk, user, err := lastfmDesktopLogin(ctx, srv.Client(), "key", "secret", func(u string) { authURL = u })
+	if err != nil {
+		t.Fatalf("lastfmDesktopLogin: %v", err)
+	}
+	if sk != "session-key" || user != "nina" {
+		t.Fatalf("got %q, %q", sk, user)
+	}
+	if !strings.Contains(authURL, "token=tok123") || !strings.Contains(authURL, "api_key=key") {
+		t.Fatalf("unexpected auth URL %q", authURL)
+	}
+	if polls.Load() != 3 {
+		t.Fatalf("expected 3 polls, got %d", polls.Load())
+	}
+}
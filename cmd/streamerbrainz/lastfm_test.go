@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestLastFMSignature(t *testing.T) {
+	params := url.Values{
+		"method":  {"auth.getSession"},
+		"api_key": {"key"},
+		"token":   {"tok"},
+		"format":  {"json"},
+	}
+	// md5("api_keykeymethodauth.getSessiontokentoksecret"); format is not signed.
+	if got := lastfmSignature(params, "secret"); got != "04e870be4bb79756721b7bc1937fe83d" {
+		t.Fatalf("signature = %s", got)
+	}
+}
+
+// lastfmServer fakes the API: it checks signatures and answers via respond.
+func lastfmServer(t *testing.T, respond func(form url.Values) (int, string)) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parse form: %v", err)
+		}
+		form := r.PostForm
+		sig := form.Get("api_sig")
+		form.Del("api_sig")
+		if sig != lastfmSignature(form, "secret") {
+			w.Write([]byte(`{"error":13,"message":"Invalid method signature supplied"}`))
+			return
+		}
+		status, body := respond(form)
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	old := lastfmAPIURL
+	lastfmAPIURL = srv.URL
+	t.Cleanup(func() {
+		lastfmAPIURL = old
+		srv.Close()
+	})
+	return srv
+}
+
+func TestLastFMScrobbler_Scrobble(t *testing.T) {
+	var got url.Values
+	srv := lastfmServer(t, func(form url.Values) (int, string) {
+		got = form
+		return http.StatusOK, `{"scrobbles":{"@attr":{"accepted":2,"ignored":0}}}`
+	})
+
+	fm := &lastfmScrobbler{apiKey: "key", secret: "secret", sessionKey: "sk", client: srv.Client()}
+	started := time.Unix(1_700_000_000, 0)
+	err := fm.Scrobble(context.Background(), []scrobbleTrack{
+		{Artist: "Nina Simone", Title: "Sinnerman", Album: "Pastel Blues", DurationMs: 622_000, StartedAt: started},
+		{Artist: "Nina Simone", Title: "Be My Husband", StartedAt: started.Add(11 * time.Minute)},
+	})
+	if err != nil {
+		t.Fatalf("Scrobble: %v", err)
+	}
+	if got.Get("method") != "track.scrobble" || got.Get("sk") != "sk" || got.Get("format") != "json" {
+		t.Fatalf("form = %v", got)
+	}
+	if got.Get("track[0]") != "Sinnerman" || got.Get("duration[0]") != "622" || got.Get("track[1]") != "Be My Husband" || got.Get("timestamp[1]") != "1700000660" {
+		t.Fatalf("form = %v", got)
+	}
+}
+
+func TestLastFMScrobbler_ErrorClassification(t *testing.T) {
+	code := "6"
+	srv := lastfmServer(t, func(url.Values) (int, string) {
+		return http.StatusOK, `{"error":` + code + `,"message":"nope"}`
+	})
+	fm := &lastfmScrobbler{apiKey: "key", secret: "secret", sessionKey: "sk", client: srv.Client()}
+	tracks := []scrobbleTrack{{Artist: "A", Title: "T", StartedAt: time.Now()}}
+
+	var rejected *scrobbleRejectedError
+	if err := fm.Scrobble(context.Background(), tracks); !errors.As(err, &rejected) {
+		t.Fatalf("error 6: expected rejection, got %v", err)
+	}
+	code = "11"
+	if err := fm.Scrobble(context.Background(), tracks); err == nil || errors.As(err, &rejected) {
+		t.Fatalf("error 11: expected a retryable error, got %v", err)
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ============================================================================
+// `learn` subcommand
+// ============================================================================
+//   streamerbrainz learn -device /dev/input/by-id/... [-actions ...] [-presets -40,-25]
+//
+// Builds a keymap (see keymap.go) for a remote interactively: for each action
+// it asks for a button press on the device and records the key code (or the
+// scancode, for a raw IR receiver without a kernel keymap), then writes the
+// result as that input's `keymap:` in the config file (adding the input if
+// needed; the rest of the file, comments included, is kept). An action that
+// gets no press within -timeout is skipped.
+// ============================================================================
+
+const defaultLearnTimeout = 15 * time.Second
+
+// defaultLearnActions are the actions asked for unless -actions is given.
+var defaultLearnActions = []string{"volume_up", "volume_down", "mute", "play_pause", "next", "previous"}
+
+// learnStep is one prompt of a learning session.
+type learnStep struct {
+	Action   string
+	VolumeDB *float64 // preset volume (action "preset")
+}
+
+func (s learnStep) label() string {
+	if s.Action == keymapPresetAction {
+		return fmt.Sprintf("preset %g dB", *s.VolumeDB)
+	}
+	return strings.ReplaceAll(s.Action, "_", " ")
+}
+
+func printLearnUsage() {
+	fmt.Println("USAGE:")
+	fmt.Println("  streamerbrainz learn -device <path> [OPTIONS]")
+	fmt.Println()
+	fmt.Println("Asks for a button press for each action and writes the device's keymap into")
+	fmt.Println("the config file. Find the device with `streamerbrainz list-inputs -identify`.")
+	fmt.Println()
+	fmt.Println("OPTIONS:")
+	fmt.Println("  -device string      Input device to learn from (required)")
+	fmt.Println("  -config string      Config file to update (default: ~/.config/streamerbrainz/config.yaml)")
+	fmt.Println("  -actions string     Comma-separated actions (default: " + strings.Join(defaultLearnActions, ",") + ")")
+	fmt.Println("                      One of: " + strings.Join(keymapActionNames(), ", "))
+	fmt.Println("  -presets string     Comma-separated preset volumes in dB to learn buttons for, e.g. -40,-25")
+	fmt.Println("  -timeout duration   How long to wait for each press before skipping it (default 15s)")
+	fmt.Println("  -dry-run            Print the keymap instead of writing the config")
+	fmt.Println()
+}
+
+// runLearnSubcommand handles `streamerbrainz learn`.
+func runLearnSubcommand(args []string) {
+	fs := flag.NewFlagSet("learn", flag.ExitOnError)
+	device := fs.String("device", "", "Input device to learn from")
+	configPath := fs.String("config", "", "Path to YAML config file")
+	actions := fs.String("actions", strings.Join(defaultLearnActions, ","), "Comma-separated actions")
+	presets := fs.String("presets", "", "Comma-separated preset volumes (dB)")
+	timeout := fs.Duration("timeout", defaultLearnTimeout, "Wait per press before skipping")
+	dryRun := fs.Bool("dry-run", false, "Print the keymap instead of writing the config")
+	fs.Usage = printLearnUsage
+	fs.Parse(args)
+
+	if *device == "" || fs.NArg() > 0 {
+		printLearnUsage()
+		os.Exit(2)
+	}
+	steps, err := parseLearnSteps(*actions, *presets)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(2)
+	}
+	if !evdevSupported {
+		fmt.Fprintln(os.Stderr, "error: learning reads evdev input devices, which requires linux")
+		os.Exit(1)
+	}
+
+	devicePath := ExpandPath(*device)
+	f, err := os.Open(devicePath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	presses := make(chan learnPress, 16)
+	go readKeyPresses(f, presses)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	fmt.Printf("Learning %s: press each button when asked (skipped after %s).\n", devicePath, *timeout)
+	bindings, err := learnKeymap(ctx, presses, steps, *timeout, os.Stdout)
+	f.Close()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	if len(bindings) == 0 {
+		fmt.Fprintln(os.Stderr, "error: no buttons learned")
+		os.Exit(1)
+	}
+
+	if *dryRun {
+		doc := keymapNode(bindings)
+		out, _ := yaml.Marshal(&yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: "keymap"}, doc,
+		}})
+		fmt.Print(string(out))
+		return
+	}
+	path := ResolveConfigPath(*configPath)
+	if err := writeLearnedKeymap(path, *device, bindings); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %d bindings to %s; restart the daemon to apply them.\n", len(bindings), ExpandPath(path))
+}
+
+// parseLearnSteps builds the prompts from the -actions and -presets lists.
+func parseLearnSteps(actions, presets string) ([]learnStep, error) {
+	var steps []learnStep
+	for _, a := range strings.Split(actions, ",") {
+		a = strings.TrimSpace(a)
+		if a == "" {
+			continue
+		}
+		if _, ok := keymapActionCodes[a]; !ok {
+			if a == keymapPresetAction {
+				return nil, errors.New("presets are learned with -presets <dB,...>")
+			}
+			return nil, fmt.Errorf("unknown action %q (one of %s)", a, strings.Join(keymapActionNames(), ", "))
+		}
+		steps = append(steps, learnStep{Action: a})
+	}
+	for _, p := range strings.Split(presets, ",") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		db, err := strconv.ParseFloat(p, 64)
+		if err != nil {
+			return nil, fmt.Errorf("preset %q: not a volume in dB", p)
+		}
+		steps = append(steps, learnStep{Action: keymapPresetAction, VolumeDB: &db})
+	}
+	if len(steps) == 0 {
+		return nil, errors.New("nothing to learn")
+	}
+	return steps, nil
+}
+
+// learnPress is one button press: a key code, or the scancode of a raw IR
+// receiver that sends no key (see keymap.go).
+type learnPress struct {
+	Code     uint16
+	Scancode *uint32
+}
+
+func (p learnPress) String() string {
+	if p.Scancode != nil {
+		return fmt.Sprintf("scancode %#x", *p.Scancode)
+	}
+	return fmt.Sprintf("%s (%d)", inputKeyName(p.Code), p.Code)
+}
+
+// readKeyPresses sends every button press read from an evdev device until
+// reading fails (e.g. the file is closed). A frame with a key press yields the
+// key; one with only MSC_SCAN yields the scancode, unless it repeats the
+// previous scancode within scancodeReleaseTimeout (the button is held).
+func readKeyPresses(r io.Reader, presses chan<- learnPress) {
+	defer close(presses)
+	var (
+		key      *uint16
+		scan     *uint32
+		lastScan *uint32
+		lastAt   time.Time
+	)
+	buf := make([]byte, inputEventSize*64)
+	for {
+		n, err := r.Read(buf)
+		if err != nil {
+			return
+		}
+		for off := 0; off+inputEventSize <= n; off += inputEventSize {
+			ev := decodeInputEvent(buf[off:])
+			switch {
+			case ev.Type == EV_KEY && ev.Value == evValuePress:
+				key = &ev.Code
+			case ev.Type == EV_MSC && ev.Code == MSC_SCAN:
+				code := uint32(ev.Value)
+				scan = &code
+			case ev.Type == EV_SYN && ev.Code == SYN_REPORT:
+				switch {
+				case key != nil:
+					presses <- learnPress{Code: *key}
+				case scan != nil && (lastScan == nil || *lastScan != *scan || ev.time().Sub(lastAt) > scancodeReleaseTimeout):
+					presses <- learnPress{Scancode: scan}
+				}
+				if scan != nil {
+					lastScan, lastAt = scan, ev.time()
+				}
+				key, scan = nil, nil
+			}
+		}
+	}
+}
+
+// learnKeymap prompts for each step on out and binds the next key pressed. A
+// step without a press within timeout is skipped; a key already bound in this
+// session is refused. It fails if ctx is canceled or the device goes away.
+func learnKeymap(ctx context.Context, presses <-chan learnPress, steps []learnStep, timeout time.Duration, out io.Writer) ([]KeyBinding, error) {
+	var bindings []KeyBinding
+	bound := map[string]string{} // press -> step label
+	for _, step := range steps {
+		fmt.Fprintf(out, "Press the button for %s... ", step.label())
+		deadline := time.NewTimer(timeout)
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				deadline.Stop()
+				fmt.Fprintln(out)
+				return nil, ctx.Err()
+			case <-deadline.C:
+				fmt.Fprintln(out, "skipped")
+				break wait
+			case p, ok := <-presses:
+				if !ok {
+					deadline.Stop()
+					fmt.Fprintln(out)
+					return nil, errors.New("input device closed")
+				}
+				if prev, dup := bound[p.String()]; dup {
+					fmt.Fprintf(out, "%s is already %s, press another... ", p, prev)
+					continue
+				}
+				deadline.Stop()
+				bound[p.String()] = step.label()
+				bindings = append(bindings, KeyBinding{Code: p.Code, Scancode: p.Scancode, Action: step.Action, VolumeDB: step.VolumeDB})
+				fmt.Fprintln(out, p)
+				break wait
+			}
+		}
+	}
+	return bindings, nil
+}
+
+// keymapNode encodes bindings as a YAML sequence with one flow-style mapping per
+// binding ({code: 103, action: volume_up}); scancodes are written in hex.
+func keymapNode(bindings []KeyBinding) *yaml.Node {
+	seq := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for _, b := range bindings {
+		var item yaml.Node
+		_ = item.Encode(b) // KeyBinding always encodes
+		item.Style = yaml.FlowStyle
+		if v := mappingValue(&item, "scancode"); v != nil {
+			v.Value = fmt.Sprintf("%#x", *b.Scancode)
+		}
+		seq.Content = append(seq.Content, &item)
+	}
+	return seq
+}
+
+// writeLearnedKeymap sets the keymap of the input with path device in the config
+// file at path (adding a key input if there is none) and writes it back, unless
+// the result doesn't validate.
+func writeLearnedKeymap(path, device string, bindings []KeyBinding) error {
+	path = ExpandPath(path)
+	doc, err := readConfigNode(path)
+	if err != nil {
+		return err
+	}
+	root := doc.Content[0]
+
+	inputs := mappingValue(root, "inputs")
+	if inputs == nil || inputs.Kind != yaml.SequenceNode {
+		inputs = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		setMappingValue(root, "inputs", inputs)
+	}
+	var input *yaml.Node
+	for _, item := range inputs.Content {
+		if p := mappingValue(item, "path"); item.Kind == yaml.MappingNode && p != nil && ExpandPath(p.Value) == ExpandPath(device) {
+			input = item
+			break
+		}
+	}
+	if input == nil {
+		input = legacyInputNode(device, InputDeviceTypeKey)
+		inputs.Content = append(inputs.Content, input)
+	}
+	setMappingValue(input, "keymap", keymapNode(bindings))
+
+	return writeConfigNode(path, doc, func(tmp string) error {
+		cfg, err := LoadConfigFile(tmp)
+		if err == nil {
+			cfg.expandPaths()
+			err = cfg.Validate()
+		}
+		if err != nil {
+			return fmt.Errorf("updated config is invalid, not written: %w", err)
+		}
+		return nil
+	})
+}
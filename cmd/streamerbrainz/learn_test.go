@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLearnSteps(t *testing.T) {
+	steps, err := parseLearnSteps("volume_up, mute", "-40,-25.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var labels []string
+	for _, s := range steps {
+		labels = append(labels, s.label())
+	}
+	if got := strings.Join(labels, "|"); got != "volume up|mute|preset -40 dB|preset -25.5 dB" {
+		t.Fatalf("labels = %s", got)
+	}
+	for _, bad := range [][2]string{{"louder", ""}, {"preset", ""}, {"mute", "loud"}, {"", ""}} {
+		if _, err := parseLearnSteps(bad[0], bad[1]); err == nil {
+			t.Errorf("parseLearnSteps(%q, %q) accepted", bad[0], bad[1])
+		}
+	}
+}
+
+func TestReadKeyPresses(t *testing.T) {
+	var buf bytes.Buffer
+	frame := func(usec int64, events ...inputEvent) {
+		for _, ev := range append(events, inputEvent{Type: EV_SYN, Code: SYN_REPORT}) {
+			ev.Usec = usec
+			binary.Write(&buf, binary.LittleEndian, ev)
+		}
+	}
+	frame(0, inputEvent{Type: EV_KEY, Code: 103, Value: evValuePress})
+	frame(10_000, inputEvent{Type: EV_KEY, Code: 103, Value: evValueRepeat})
+	frame(20_000, inputEvent{Type: EV_KEY, Code: 103, Value: evValueRelease})
+	// A raw IR receiver: the scancode repeats while held; the kernel-keymap key wins.
+	frame(100_000, inputEvent{Type: EV_MSC, Code: MSC_SCAN, Value: 0x40bf})
+	frame(210_000, inputEvent{Type: EV_MSC, Code: MSC_SCAN, Value: 0x40bf})
+	frame(320_000, inputEvent{Type: EV_MSC, Code: MSC_SCAN, Value: 0x40be})
+	frame(900_000, inputEvent{Type: EV_MSC, Code: MSC_SCAN, Value: 0x40be})
+	frame(950_000, inputEvent{Type: EV_MSC, Code: MSC_SCAN, Value: 0x10}, inputEvent{Type: EV_KEY, Code: 108, Value: evValuePress})
+
+	presses := make(chan learnPress, 8)
+	readKeyPresses(&buf, presses)
+	var got []string
+	for p := range presses {
+		got = append(got, p.String())
+	}
+	want := "KEY_103 (103)|scancode 0x40bf|scancode 0x40be|scancode 0x40be|KEY_108 (108)"
+	if strings.Join(got, "|") != want {
+		t.Fatalf("presses = %v", got)
+	}
+}
+
+// learnUser answers learnKeymap's prompts: a prompt for a label in presses is
+// answered with its key codes, any other prompt is left to time out.
+type learnUser struct {
+	out     bytes.Buffer
+	presses map[string][]learnPress
+	keys    chan<- learnPress
+}
+
+func (u *learnUser) Write(p []byte) (int, error) {
+	u.out.Write(p)
+	if label, ok := strings.CutPrefix(string(p), "Press the button for "); ok {
+		for _, p := range u.presses[strings.TrimSuffix(label, "... ")] {
+			u.keys <- p
+		}
+	}
+	return len(p), nil
+}
+
+func TestLearnKeymap(t *testing.T) {
+	steps, _ := parseLearnSteps("volume_up,volume_down,mute", "-40")
+	presses := make(chan learnPress, 8)
+	scan := uint32(0x40bf)
+	// volume down is first answered with volume up's key; mute gets no press.
+	user := &learnUser{keys: presses, presses: map[string][]learnPress{
+		"volume up":     {{Code: 103}},
+		"volume down":   {{Code: 103}, {Code: 108}},
+		"preset -40 dB": {{Scancode: &scan}},
+	}}
+	bindings, err := learnKeymap(context.Background(), presses, steps, 50*time.Millisecond, user)
+	out := &user.out
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bindings) != 3 || bindings[0].Code != 103 || bindings[1].Code != 108 || bindings[1].Action != "volume_down" ||
+		*bindings[2].Scancode != 0x40bf || bindings[2].Action != keymapPresetAction || *bindings[2].VolumeDB != -40 {
+		t.Fatalf("bindings = %+v\n%s", bindings, out.String())
+	}
+	for _, want := range []string{"already volume up", "mute... skipped"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("missing %q in:\n%s", want, out.String())
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := learnKeymap(ctx, make(chan learnPress), steps, time.Second, out); err == nil {
+		t.Fatal("canceled session returned a keymap")
+	}
+}
+
+func TestWriteLearnedKeymap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	body := "# my setup\ninputs:\n  - path: /dev/input/event3 # flirc\n    type: key\nlogging:\n  level: debug\n"
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	preset, scan := -40.0, uint32(0x40bf)
+	bindings := []KeyBinding{{Code: 103, Action: "volume_up"}, {Scancode: &scan, Action: keymapPresetAction, VolumeDB: &preset}}
+	if err := writeLearnedKeymap(path, "/dev/input/event3", bindings); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(path)
+	for _, want := range []string{"# my setup", "# flirc", "- {code: 103, action: volume_up}", "- {scancode: 0x40bf, action: preset, volume_db: -40}"} {
+		if !strings.Contains(string(b), want) {
+			t.Fatalf("missing %q in:\n%s", want, b)
+		}
+	}
+	cfg, err := LoadConfigFile(path)
+	if err != nil || len(cfg.Inputs) != 1 || len(cfg.Inputs[0].Keymap) != 2 {
+		t.Fatalf("reloaded: %+v, %v", cfg.Inputs, err)
+	}
+
+	// A second device is added as a new key input.
+	if err := writeLearnedKeymap(path, "/dev/input/event9", bindings[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if cfg, _ = LoadConfigFile(path); len(cfg.Inputs) != 2 || cfg.Inputs[1].Type != InputDeviceTypeKey {
+		t.Fatalf("second device: %+v", cfg.Inputs)
+	}
+
+	// An out-of-range preset fails validation and leaves the file as it was.
+	before, _ := os.ReadFile(path)
+	loud := 100.0
+	if err := writeLearnedKeymap(path, "/dev/input/event3", []KeyBinding{{Code: 2, Action: keymapPresetAction, VolumeDB: &loud}}); err == nil {
+		t.Fatal("invalid keymap written")
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(before, after) {
+		t.Fatal("config changed despite the validation error")
+	}
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// LED volume indicator
+// ============================================================================
+// Drives an LED ring (WS2812 or APA102 over SPI) or a single PWM LED from the
+// broadcast stream:
+//
+//   - The ring shows the current zone's volume position between the zone's
+//     min_db and max_db, colored by the configured color map (the last lit
+//     pixel is dimmed proportionally for sub-pixel resolution).
+//   - While muted the indicator flashes in the mute color.
+//
+// Rendering (frame computation and wire encoding) is pure; only the drivers
+// touch hardware (led_linux.go).
+// ============================================================================
+
+const (
+	ledDriverWS2812 = "ws2812"
+	ledDriverAPA102 = "apa102"
+	ledDriverPWM    = "pwm"
+
+	// ledFlashPeriod is the on+off period of the mute flash.
+	ledFlashPeriod = 1 * time.Second
+
+	// ws2812SPIHz is the SPI clock for WS2812 bit encoding (3 SPI bits per data bit).
+	ws2812SPIHz = 2_400_000
+)
+
+// ledColor is an RGB color.
+type ledColor struct{ R, G, B uint8 }
+
+// parseLEDColor parses "#rrggbb".
+func parseLEDColor(s string) (ledColor, error) {
+	hex, ok := strings.CutPrefix(s, "#")
+	if !ok || len(hex) != 6 {
+		return ledColor{}, fmt.Errorf("color %q must be #rrggbb", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return ledColor{}, fmt.Errorf("color %q must be #rrggbb", s)
+	}
+	return ledColor{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v)}, nil
+}
+
+// scale multiplies each channel by f (0..1).
+func (c ledColor) scale(f float64) ledColor {
+	f = math.Max(0, math.Min(1, f))
+	return ledColor{
+		R: uint8(math.Round(float64(c.R) * f)),
+		G: uint8(math.Round(float64(c.G) * f)),
+		B: uint8(math.Round(float64(c.B) * f)),
+	}
+}
+
+// ledColorStop is a parsed color map entry.
+type ledColorStop struct {
+	At    float64
+	Color ledColor
+}
+
+// colorAt interpolates the color map at position p (0..1). stops are sorted by At.
+func colorAt(stops []ledColorStop, p float64) ledColor {
+	if len(stops) == 0 {
+		return ledColor{R: 255, G: 255, B: 255}
+	}
+	if p <= stops[0].At {
+		return stops[0].Color
+	}
+	for i := 1; i < len(stops); i++ {
+		a, b := stops[i-1], stops[i]
+		if p <= b.At {
+			t := (p - a.At) / (b.At - a.At)
+			lerp := func(x, y uint8) uint8 { return uint8(math.Round(float64(x) + (float64(y)-float64(x))*t)) }
+			return ledColor{R: lerp(a.Color.R, b.Color.R), G: lerp(a.Color.G, b.Color.G), B: lerp(a.Color.B, b.Color.B)}
+		}
+	}
+	return stops[len(stops)-1].Color
+}
+
+// ledRenderer computes frames from volume/mute state.
+type ledRenderer struct {
+	pixels     int
+	brightness float64
+	stops      []ledColorStop
+	muteColor  ledColor
+}
+
+// frame renders a volume position (0..1). When muted, flashOn selects the flash phase.
+func (r ledRenderer) frame(position float64, muted, flashOn bool) []ledColor {
+	out := make([]ledColor, r.pixels)
+	if muted {
+		if flashOn {
+			for i := range out {
+				out[i] = r.muteColor.scale(r.brightness)
+			}
+		}
+		return out
+	}
+
+	position = math.Max(0, math.Min(1, position))
+	lit := position * float64(r.pixels)
+	for i := range out {
+		level := math.Min(1, lit-float64(i))
+		if level <= 0 {
+			break
+		}
+		c := colorAt(r.stops, (float64(i)+0.5)/float64(r.pixels))
+		out[i] = c.scale(level * r.brightness)
+	}
+	return out
+}
+
+// encodeAPA102 encodes pixels as an APA102 SPI frame (global brightness at max;
+// brightness is already applied to the colors).
+func encodeAPA102(pixels []ledColor) []byte {
+	out := make([]byte, 0, 4+4*len(pixels)+(len(pixels)+15)/16)
+	out = append(out, 0, 0, 0, 0) // start frame
+	for _, p := range pixels {
+		out = append(out, 0xFF, p.B, p.G, p.R)
+	}
+	// End frame: at least n/2 clock edges.
+	for i := 0; i < (len(pixels)+15)/16; i++ {
+		out = append(out, 0xFF)
+	}
+	return out
+}
+
+// encodeWS2812 encodes pixels (GRB order) for WS2812 over SPI at ws2812SPIHz:
+// each data bit becomes 3 SPI bits (1 -> 110, 0 -> 100), followed by a reset gap.
+func encodeWS2812(pixels []ledColor) []byte {
+	const resetBytes = 24 // >= 80µs of low at 2.4 MHz
+	out := make([]byte, 0, 9*len(pixels)+resetBytes)
+	var acc uint32
+	nbits := 0
+	for _, p := range pixels {
+		for _, b := range [3]uint8{p.G, p.R, p.B} {
+			for bit := 7; bit >= 0; bit-- {
+				code := uint32(0b100)
+				if b&(1<<bit) != 0 {
+					code = 0b110
+				}
+				acc = acc<<3 | code
+				nbits += 3
+				for nbits >= 8 {
+					nbits -= 8
+					out = append(out, byte(acc>>nbits))
+				}
+			}
+		}
+	}
+	return append(out, make([]byte, resetBytes)...)
+}
+
+// ledDriver writes frames to hardware.
+type ledDriver interface {
+	Write(pixels []ledColor) error
+	Close() error
+}
+
+// spiLEDDriver writes encoded frames to an spidev device.
+type spiLEDDriver struct {
+	f      *os.File
+	encode func([]ledColor) []byte
+}
+
+func (d *spiLEDDriver) Write(pixels []ledColor) error {
+	_, err := d.f.Write(d.encode(pixels))
+	return err
+}
+
+func (d *spiLEDDriver) Close() error { return d.f.Close() }
+
+// pwmLEDDriver drives a single LED through the sysfs PWM interface; its duty
+// cycle follows the average brightness of the rendered frame.
+type pwmLEDDriver struct {
+	path     string // e.g. /sys/class/pwm/pwmchip0/pwm0
+	periodNS int
+}
+
+func newPWMLEDDriver(path string, periodNS int) (*pwmLEDDriver, error) {
+	d := &pwmLEDDriver{path: path, periodNS: periodNS}
+	if err := d.writeAttr("period", strconv.Itoa(periodNS)); err != nil {
+		return nil, err
+	}
+	if err := d.writeAttr("enable", "1"); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *pwmLEDDriver) writeAttr(name, value string) error {
+	return os.WriteFile(filepath.Join(d.path, name), []byte(value), 0)
+}
+
+func (d *pwmLEDDriver) Write(pixels []ledColor) error {
+	var sum float64
+	for _, p := range pixels {
+		sum += math.Max(float64(p.R), math.Max(float64(p.G), float64(p.B))) / 255
+	}
+	level := 0.0
+	if len(pixels) > 0 {
+		level = sum / float64(len(pixels))
+	}
+	return d.writeAttr("duty_cycle", strconv.Itoa(int(level*float64(d.periodNS))))
+}
+
+func (d *pwmLEDDriver) Close() error {
+	_ = d.writeAttr("duty_cycle", "0")
+	return d.writeAttr("enable", "0")
+}
+
+// openLEDDriver opens the configured driver.
+func openLEDDriver(cfg LEDConfig) (ledDriver, error) {
+	switch cfg.Driver {
+	case ledDriverWS2812:
+		f, err := openSPIDevice(cfg.Device, ws2812SPIHz)
+		if err != nil {
+			return nil, err
+		}
+		return &spiLEDDriver{f: f, encode: encodeWS2812}, nil
+	case ledDriverAPA102:
+		f, err := openSPIDevice(cfg.Device, cfg.SPISpeedHz)
+		if err != nil {
+			return nil, err
+		}
+		return &spiLEDDriver{f: f, encode: encodeAPA102}, nil
+	case ledDriverPWM:
+		return newPWMLEDDriver(cfg.Device, cfg.PWMPeriodNS)
+	}
+	return nil, fmt.Errorf("unknown led driver %q", cfg.Driver)
+}
+
+// ledRange is a zone's volume range used to map dB to ring position.
+type ledRange struct{ MinDB, MaxDB float64 }
+
+func (r ledRange) position(db float64) float64 {
+	if r.MaxDB <= r.MinDB {
+		return 0
+	}
+	return (db - r.MinDB) / (r.MaxDB - r.MinDB)
+}
+
+// runLEDIndicator renders the current zone's volume/mute state on the LED driver
+// until ctx is canceled or broadcasts is closed.
+func runLEDIndicator(ctx context.Context, cfg LEDConfig, ranges map[string]ledRange, currentZone string, events chan<- Event, broadcasts <-chan StateBroadcast, logger *slog.Logger) {
+	renderer, err := cfg.renderer()
+	if err != nil {
+		logger.Error("led indicator disabled", "error", err)
+		return
+	}
+	drv, err := openLEDDriver(cfg)
+	if err != nil {
+		logger.Error("led indicator disabled", "driver", cfg.Driver, "device", cfg.Device, "error", err)
+		return
+	}
+	defer func() {
+		_ = drv.Write(make([]ledColor, renderer.pixels)) // leave the ring dark
+		_ = drv.Close()
+	}()
+	logger.Info("led indicator enabled", "driver", cfg.Driver, "device", cfg.Device, "pixels", renderer.pixels)
+
+	type zoneState struct {
+		volumeDB float64
+		muted    bool
+	}
+	zones := make(map[string]*zoneState)
+	zoneOf := func(id string) *zoneState {
+		z, ok := zones[id]
+		if !ok {
+			z = &zoneState{volumeDB: ranges[id].MinDB}
+			zones[id] = z
+		}
+		return z
+	}
+
+	// Seed from the current snapshot so the ring isn't dark until the first change.
+	if snap, err := requestStateSnapshot(ctx, events, time.Second); err == nil {
+		seed := func(s StateSnapshot) {
+			z := zoneOf(s.Zone)
+			z.volumeDB, z.muted = s.VolumeDB, s.Muted
+		}
+		seed(snap)
+		for _, zs := range snap.Zones {
+			seed(zs)
+		}
+		if snap.Zone != "" {
+			currentZone = snap.Zone
+		}
+	}
+
+	flash := time.NewTicker(ledFlashPeriod / 2)
+	defer flash.Stop()
+	flashOn := true
+
+	render := func() {
+		z := zoneOf(currentZone)
+		pos := ranges[currentZone].position(z.volumeDB)
+		if err := drv.Write(renderer.frame(pos, z.muted, flashOn)); err != nil {
+			logger.Warn("led write failed", "error", err)
+		}
+	}
+	render()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-flash.C:
+			if zoneOf(currentZone).muted {
+				flashOn = !flashOn
+				render()
+			}
+
+		case b, ok := <-broadcasts:
+			if !ok {
+				return
+			}
+			switch ev := b.(type) {
+			case BroadcastZoneSelected:
+				currentZone = ev.Zone
+			case ZoneBroadcast:
+				z := zoneOf(ev.Zone)
+				switch inner := ev.Broadcast.(type) {
+				case BroadcastVolumeChanged:
+					z.volumeDB = inner.VolumeDB
+				case BroadcastMuteChanged:
+					z.muted = inner.Muted
+					flashOn = true
+				default:
+					continue
+				}
+				if ev.Zone != currentZone {
+					continue
+				}
+			default:
+				continue
+			}
+			render()
+		}
+	}
+}
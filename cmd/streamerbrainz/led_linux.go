@@ -0,0 +1,26 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// spiIOCWrMaxSpeedHz is SPI_IOC_WR_MAX_SPEED_HZ (_IOW('k', 4, __u32)).
+const spiIOCWrMaxSpeedHz = 0x40046b04
+
+// openSPIDevice opens an spidev device for writing at speedHz.
+func openSPIDevice(path string, speedHz int) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open spi device: %w", err)
+	}
+	if err := unix.IoctlSetPointerInt(int(f.Fd()), spiIOCWrMaxSpeedHz, speedHz); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("set spi speed on %s: %w", path, err)
+	}
+	return f, nil
+}
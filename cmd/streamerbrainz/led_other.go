@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// openSPIDevice is unavailable outside Linux (spidev).
+func openSPIDevice(path string, speedHz int) (*os.File, error) {
+	return nil, errors.New("spi led drivers require linux spidev")
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestLEDRenderer_Frame(t *testing.T) {
+	r := ledRenderer{
+		pixels:     4,
+		brightness: 1,
+		stops:      []ledColorStop{{At: 0, Color: ledColor{G: 200}}, {At: 1, Color: ledColor{R: 200}}},
+		muteColor:  ledColor{R: 255},
+	}
+
+	// 0.375 of 4 pixels: one full pixel, the second at half level, rest off.
+	got := r.frame(0.375, false, true)
+	want := []ledColor{{R: 25, G: 175}, {R: 38, G: 63}, {}, {}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("frame: got %v, want %v", got, want)
+	}
+
+	if got := r.frame(2, false, true); got[3] == (ledColor{}) {
+		t.Fatalf("expected position to clamp to a full ring, got %v", got)
+	}
+
+	if got := r.frame(0.5, true, true); got[0] != (ledColor{R: 255}) || got[3] != (ledColor{R: 255}) {
+		t.Fatalf("expected mute flash on, got %v", got)
+	}
+	if got := r.frame(0.5, true, false); !reflect.DeepEqual(got, make([]ledColor, 4)) {
+		t.Fatalf("expected mute flash off, got %v", got)
+	}
+}
+
+func TestParseLEDColor(t *testing.T) {
+	c, err := parseLEDColor("#0a80ff")
+	if err != nil || c != (ledColor{R: 0x0a, G: 0x80, B: 0xff}) {
+		t.Fatalf("got %v err=%v", c, err)
+	}
+	for _, s := range []string{"0a80ff", "#0a80f", "#zzzzzz"} {
+		if _, err := parseLEDColor(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
+
+func TestEncodeAPA102(t *testing.T) {
+	got := encodeAPA102([]ledColor{{R: 1, G: 2, B: 3}})
+	want := []byte{0, 0, 0, 0, 0xFF, 3, 2, 1, 0xFF}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("got % x, want % x", got, want)
+	}
+}
+
+func TestEncodeWS2812(t *testing.T) {
+	// G=0x80 (bit 7 set), R=0, B=0xFF.
+	got := encodeWS2812([]ledColor{{R: 0, G: 0x80, B: 0xFF}})
+	if len(got) != 9+24 {
+		t.Fatalf("unexpected length %d", len(got))
+	}
+	// 110 100 100 100 100 100 100 100 -> 0xD2 0x49 0x24
+	if !bytes.Equal(got[0:3], []byte{0xD2, 0x49, 0x24}) {
+		t.Fatalf("green: got % x", got[0:3])
+	}
+	// All zero bits: 100 x8 -> 0x92 0x49 0x24
+	if !bytes.Equal(got[3:6], []byte{0x92, 0x49, 0x24}) {
+		t.Fatalf("red: got % x", got[3:6])
+	}
+	// All one bits: 110 x8 -> 0xDB 0x6D 0xB6
+	if !bytes.Equal(got[6:9], []byte{0xDB, 0x6D, 0xB6}) {
+		t.Fatalf("blue: got % x", got[6:9])
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"os"
 	"strconv"
+	"strings"
 )
 
 // ============================================================================
@@ -53,6 +54,8 @@ func parseLibrespotEvent() (Event, error) {
 		return LibrespotTrackChanged{
 			TrackId:    os.Getenv("TRACK_ID"),
 			Name:       os.Getenv("NAME"),
+			Artists:    os.Getenv("ARTISTS"),
+			Album:      os.Getenv("ALBUM"),
 			DurationMs: os.Getenv("DURATION_MS"),
 			Uri:        os.Getenv("URI"),
 		}, nil
@@ -95,7 +98,7 @@ func runLibrespotHook(socketPath string, logger *slog.Logger) error {
 	logger.Debug("librespot event", "event", os.Getenv("PLAYER_EVENT"), "event", fmt.Sprintf("%T", event))
 
 	// Send event via IPC
-	if err := SendIPCEvent(socketPath, event); err != nil {
+	if _, err := SendIPCEvent(socketPath, event); err != nil {
 		return fmt.Errorf("send IPC event: %w", err)
 	}
 
@@ -103,3 +106,14 @@ func runLibrespotHook(socketPath string, logger *slog.Logger) error {
 
 	return nil
 }
+
+// librespotArtists joins librespot's newline-separated ARTISTS for display.
+func librespotArtists(artists string) string {
+	var names []string
+	for _, a := range strings.Split(artists, "\n") {
+		if a = strings.TrimSpace(a); a != "" {
+			names = append(names, a)
+		}
+	}
+	return strings.Join(names, ", ")
+}
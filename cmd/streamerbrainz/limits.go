@@ -0,0 +1,128 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"time"
+)
+
+// ============================================================================
+// User volume limits
+// ============================================================================
+// camilladsp.user_min_db / user_max_db narrow the range every source can reach
+// (IR, rotary, IPC, web UI, player integrations), on top of the min_db/max_db
+// clamp that protects the CamillaDSP protocol range.
+//
+// A limit_override event carrying the configured token lifts the user limits
+// (back to min_db/max_db) for a calibration session. The override reverts on
+// its own after limit_override.timeout_sec, pulling the volume back under
+// user_max_db if needed.
+// ============================================================================
+
+// LimitOverride lifts (or, with Cancel, restores) the user volume limits.
+type LimitOverride struct {
+	Token       string `json:"token"`
+	DurationSec int    `json:"duration_sec,omitempty"` // <= 0 or above the configured timeout uses the timeout
+	Cancel      bool   `json:"cancel,omitempty"`       // restore the limits now (no token required)
+}
+
+func (LimitOverride) eventMarker() {}
+
+// BroadcastLimitOverrideChanged is emitted when the user limits are lifted or restored,
+// or when an override request is rejected (Error set, state unchanged).
+type BroadcastLimitOverrideChanged struct {
+	Active bool      `json:"active"`
+	Until  time.Time `json:"until,omitzero"`
+	MinDB  float64   `json:"min_db"` // effective limits after the change
+	MaxDB  float64   `json:"max_db"`
+	Error  string    `json:"error,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+func (BroadcastLimitOverrideChanged) stateBroadcastMarker() {}
+
+// limitedConfig narrows cfg's volume bounds to policy's user limits unless an
+// override is active.
+func limitedConfig(s *DaemonState, cfg VelocityConfig, policy ReducerPolicy) VelocityConfig {
+	if !s.LimitOverrideUntil.IsZero() {
+		return cfg
+	}
+	if policy.UserMinDB != nil && *policy.UserMinDB > cfg.MinDB {
+		cfg.MinDB = *policy.UserMinDB
+	}
+	if policy.UserMaxDB != nil && *policy.UserMaxDB < cfg.MaxDB {
+		cfg.MaxDB = *policy.UserMaxDB
+	}
+	return cfg
+}
+
+// reduceLimitOverride handles a LimitOverride event. cfg is the unlimited config.
+func reduceLimitOverride(s *DaemonState, ev LimitOverride, at time.Time, cfg VelocityConfig, policy ReducerPolicy) []StateBroadcast {
+	if ev.Cancel {
+		if s.LimitOverrideUntil.IsZero() {
+			return nil
+		}
+		return endLimitOverride(s, at, cfg, policy)
+	}
+
+	reject := func(msg string) []StateBroadcast {
+		b := limitOverrideBroadcast(s, at, cfg, policy)
+		b.Error = msg
+		return []StateBroadcast{b}
+	}
+	switch {
+	case policy.LimitOverrideToken == "":
+		return reject("limit override is not enabled")
+	case subtle.ConstantTimeCompare([]byte(ev.Token), []byte(policy.LimitOverrideToken)) != 1:
+		return reject("invalid token")
+	case at.IsZero():
+		return nil
+	}
+
+	d := time.Duration(ev.DurationSec) * time.Second
+	if d <= 0 || d > policy.LimitOverrideTimeout {
+		d = policy.LimitOverrideTimeout
+	}
+	s.LimitOverrideUntil = at.Add(d)
+	return []StateBroadcast{limitOverrideBroadcast(s, at, cfg, policy)}
+}
+
+// endLimitOverride restores the user limits and brings the volume back inside them.
+func endLimitOverride(s *DaemonState, at time.Time, cfg VelocityConfig, policy ReducerPolicy) []StateBroadcast {
+	s.LimitOverrideUntil = time.Time{}
+	limited := limitedConfig(s, cfg, policy)
+
+	current := s.currentVolumeDB()
+	if next := clampVolumeDB(current, limited); next != current {
+		s.VolumeCtrl.HeldDirection = 0
+		s.VolumeCtrl.VelocityDBPerS = 0
+		s.VolumeCtrl.Ramping = false
+		s.SetDesiredVolume(next)
+		s.VolumeCtrl.TargetDB = next
+		s.noteVolumeOrigin("limits", at)
+	}
+	return []StateBroadcast{limitOverrideBroadcast(s, at, cfg, policy)}
+}
+
+func limitOverrideBroadcast(s *DaemonState, at time.Time, cfg VelocityConfig, policy ReducerPolicy) BroadcastLimitOverrideChanged {
+	limited := limitedConfig(s, cfg, policy)
+	return BroadcastLimitOverrideChanged{
+		Active: !s.LimitOverrideUntil.IsZero(),
+		Until:  s.LimitOverrideUntil,
+		MinDB:  limited.MinDB,
+		MaxDB:  limited.MaxDB,
+		At:     at,
+	}
+}
+
+// readLimitOverrideToken reads the limit override token ("" when not configured).
+func readLimitOverrideToken(ref string) (string, error) {
+	if ref == "" {
+		return "", nil
+	}
+	token, err := readSecret(ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to read limit override token: %w", err)
+	}
+	return token, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReduce_UserLimitsAndOverride(t *testing.T) {
+	userMax := -20.0
+	cfg := VelocityConfig{
+		MinDB: -80,
+		MaxDB: 0,
+	}
+	policy := ReducerPolicy{
+		UserMaxDB:            &userMax,
+		LimitOverrideToken:   "secret",
+		LimitOverrideTimeout: time.Minute,
+	}
+	rotaryCfg := RotaryConfig{DbPerStep: 1}
+	t0 := time.Unix(1000, 0).UTC()
+
+	s := &DaemonState{}
+	s.SetObservedVolume(-30, t0)
+
+	// Every source is capped at user_max_db.
+	rr := Reduce(s, SetVolumeAbsolute{Db: -5, Origin: "ui"}, cfg, rotaryCfg, policy)
+	if v, ok := rr.State.GetDesiredVolume(); !ok || v != -20 {
+		t.Fatalf("expected desired volume capped at -20, got %v (ok=%v)", v, ok)
+	}
+
+	// A wrong token is rejected and leaves the cap in place.
+	rr = Reduce(rr.State, TimedEvent{Event: LimitOverride{Token: "nope"}, At: t0}, cfg, rotaryCfg, policy)
+	if len(rr.Broadcasts) != 1 {
+		t.Fatalf("expected a rejection broadcast, got %#v", rr.Broadcasts)
+	}
+	if b := rr.Broadcasts[0].(BroadcastLimitOverrideChanged); b.Active || b.Error == "" || b.MaxDB != -20 {
+		t.Fatalf("unexpected rejection broadcast: %#v", b)
+	}
+
+	// The right token lifts the cap up to max_db for the requested duration.
+	rr = Reduce(rr.State, TimedEvent{Event: LimitOverride{Token: "secret", DurationSec: 10}, At: t0}, cfg, rotaryCfg, policy)
+	if b := rr.Broadcasts[0].(BroadcastLimitOverrideChanged); !b.Active || b.MaxDB != 0 || !b.Until.Equal(t0.Add(10*time.Second)) {
+		t.Fatalf("unexpected override broadcast: %#v", b)
+	}
+	rr = Reduce(rr.State, SetVolumeAbsolute{Db: -5, Origin: "ui"}, cfg, rotaryCfg, policy)
+	if v, _ := rr.State.GetDesiredVolume(); v != -5 {
+		t.Fatalf("expected -5 while overridden, got %v", v)
+	}
+	rr = Reduce(rr.State, Tick{Now: t0.Add(time.Second), Dt: 0.01}, cfg, rotaryCfg, policy)
+	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: -5, At: t0.Add(time.Second)}, cfg, rotaryCfg, policy)
+
+	// On expiry the cap returns and the volume is pulled back under it.
+	rr = Reduce(rr.State, Tick{Now: t0.Add(10 * time.Second), Dt: 0.01}, cfg, rotaryCfg, policy)
+	var reverted bool
+	for _, b := range rr.Broadcasts {
+		if lb, ok := b.(BroadcastLimitOverrideChanged); ok && !lb.Active && lb.MaxDB == -20 {
+			reverted = true
+		}
+	}
+	if !reverted {
+		t.Fatalf("expected override to revert, got %#v", rr.Broadcasts)
+	}
+	if len(rr.Commands) != 1 || rr.Commands[0].(CmdSetVolume).TargetDB != -20 {
+		t.Fatalf("expected SetVolume -20 on revert, got %#v", rr.Commands)
+	}
+}
+
+func TestReduce_LimitOverrideDisabledWithoutToken(t *testing.T) {
+	userMax := -20.0
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0}
+	policy := ReducerPolicy{UserMaxDB: &userMax, LimitOverrideTimeout: time.Minute}
+
+	rr := Reduce(&DaemonState{}, TimedEvent{Event: LimitOverride{Token: ""}, At: time.Unix(1000, 0)}, cfg, RotaryConfig{}, policy)
+	if !rr.State.LimitOverrideUntil.IsZero() {
+		t.Fatalf("expected override to be refused when no token is configured")
+	}
+}
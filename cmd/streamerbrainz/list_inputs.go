@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+)
+
+// ============================================================================
+// list-inputs subcommand
+// ============================================================================
+// `streamerbrainz list-inputs` enumerates /dev/input/event* devices with their
+// names and the EV_KEY/EV_REL codes they report, and prints an `inputs:` config
+// stanza for each device streamerbrainz can use (stable /dev/input/by-id or
+// by-path links are preferred over eventN, which changes between boots).
+//
+// With -identify it then reads every device and prints each key press, wheel
+// turn and raw IR scancode with the device it came from, to find out which node
+// a remote or encoder is ("press a key to identify").
+// ============================================================================
+
+// defaultInputDir is where evdev device nodes live.
+const defaultInputDir = "/dev/input"
+
+// inputDeviceInfo describes one evdev device.
+type inputDeviceInfo struct {
+	Path  string   // /dev/input/eventN
+	Links []string // by-id/by-path symlinks pointing at Path
+	Name  string
+	Keys  []uint16 // EV_KEY codes
+	Rels  []uint16 // EV_REL codes
+	Msc   []uint16 // EV_MSC codes (MSC_SCAN: raw scancodes, see keymap.go)
+	Err   error    // set if the device couldn't be opened or queried
+}
+
+// inputKeyNames names the key codes the daemon handles.
+var inputKeyNames = map[uint16]string{
+	KEY_MUTE:         "KEY_MUTE",
+	KEY_VOLUMEDOWN:   "KEY_VOLUMEDOWN",
+	KEY_VOLUMEUP:     "KEY_VOLUMEUP",
+	KEY_PLAYPAUSE:    "KEY_PLAYPAUSE",
+	KEY_STOPCD:       "KEY_STOPCD",
+	KEY_PREVIOUSSONG: "KEY_PREVIOUSSONG",
+	KEY_NEXTSONG:     "KEY_NEXTSONG",
+	KEY_PLAYCD:       "KEY_PLAYCD",
+	KEY_PAUSECD:      "KEY_PAUSECD",
+	KEY_AUDIO:        "KEY_AUDIO",
+	BTN_0:            "BTN_0",
+	KEY_LEFTSHIFT:    "KEY_LEFTSHIFT",
+	KEY_RIGHTSHIFT:   "KEY_RIGHTSHIFT",
+	KEY_LEFTCTRL:     "KEY_LEFTCTRL",
+	KEY_RIGHTCTRL:    "KEY_RIGHTCTRL",
+	KEY_LEFTALT:      "KEY_LEFTALT",
+	KEY_RIGHTALT:     "KEY_RIGHTALT",
+	KEY_LEFTMETA:     "KEY_LEFTMETA",
+	KEY_RIGHTMETA:    "KEY_RIGHTMETA",
+}
+
+// inputKeyActions describes what the daemon does with a key (see emitEventFromInputEvent).
+var inputKeyActions = map[uint16]string{
+	KEY_VOLUMEUP:     "volume up (hold)",
+	KEY_VOLUMEDOWN:   "volume down (hold)",
+	KEY_MUTE:         "toggle mute",
+	KEY_AUDIO:        "output select",
+	BTN_0:            "rotary press",
+	KEY_PLAYPAUSE:    "play/pause",
+	KEY_NEXTSONG:     "next track",
+	KEY_PREVIOUSSONG: "previous track",
+	KEY_PLAYCD:       "play",
+	KEY_PAUSECD:      "pause",
+	KEY_STOPCD:       "stop",
+}
+
+// inputRelNames names relative axes; the rotary ones are handled by the daemon.
+var inputRelNames = map[uint16]string{
+	0x00:             "REL_X",
+	0x01:             "REL_Y",
+	0x06:             "REL_HWHEEL",
+	REL_DIAL:         "REL_DIAL",
+	REL_WHEEL:        "REL_WHEEL",
+	REL_MISC:         "REL_MISC",
+	REL_WHEEL_HI_RES: "REL_WHEEL_HI_RES",
+	0x0c:             "REL_HWHEEL_HI_RES",
+}
+
+// inputKeyName returns the symbolic name of a key code, or its number.
+func inputKeyName(code uint16) string {
+	if name, ok := inputKeyNames[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("KEY_%d", code)
+}
+
+// inputRelName returns the symbolic name of a relative axis, or its number.
+func inputRelName(code uint16) string {
+	if name, ok := inputRelNames[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("REL_%d", code)
+}
+
+// isRotaryRel reports whether the daemon treats a relative axis as a rotary encoder.
+func isRotaryRel(code uint16) bool {
+	return code == REL_DIAL || code == REL_WHEEL || code == REL_MISC || code == REL_WHEEL_HI_RES
+}
+
+// suggestedType returns the input type to configure the device as, or "" if
+// it reports nothing the daemon handles. A pointer (REL_X) is not suggested as
+// rotary even though mice have a wheel.
+func (d inputDeviceInfo) suggestedType() InputDeviceType {
+	if slices.ContainsFunc(d.Rels, isRotaryRel) && !slices.Contains(d.Rels, 0x00) {
+		return InputDeviceTypeRotary
+	}
+	if slices.ContainsFunc(d.Keys, func(code uint16) bool { return inputKeyActions[code] != "" }) || d.scancodesOnly() {
+		return InputDeviceTypeKey
+	}
+	return ""
+}
+
+// scancodesOnly reports whether the device sends raw scancodes but none of the
+// handled keys, like an IR receiver without a kernel keymap: it needs a
+// scancode keymap.
+func (d inputDeviceInfo) scancodesOnly() bool {
+	return slices.Contains(d.Msc, MSC_SCAN) && !slices.Contains(d.Rels, 0x00) &&
+		!slices.ContainsFunc(d.Keys, func(code uint16) bool { return inputKeyActions[code] != "" })
+}
+
+// configPath returns the path to put in the config: a by-id link if there is
+// one, then by-path, then the eventN node.
+func (d inputDeviceInfo) configPath() string {
+	for _, prefix := range []string{"by-id/", "by-path/"} {
+		for _, l := range d.Links {
+			if strings.Contains(l, "/"+prefix) {
+				return l
+			}
+		}
+	}
+	return d.Path
+}
+
+func printListInputsUsage() {
+	fmt.Println("USAGE:")
+	fmt.Println("  streamerbrainz list-inputs [-identify]")
+	fmt.Println()
+	fmt.Println("Lists /dev/input event devices, the key and wheel codes they report, and a")
+	fmt.Println("suggested `inputs:` config entry for each usable device.")
+	fmt.Println()
+	fmt.Println("OPTIONS:")
+	fmt.Println("  -identify    Then print every key press / wheel turn with its device (Ctrl-C to stop)")
+	fmt.Println("  -dir string  Device directory (default /dev/input)")
+	fmt.Println()
+}
+
+// runListInputsSubcommand handles `streamerbrainz list-inputs`.
+func runListInputsSubcommand(args []string) {
+	fs := flag.NewFlagSet("list-inputs", flag.ExitOnError)
+	identify := fs.Bool("identify", false, "Print key presses and wheel turns per device")
+	dir := fs.String("dir", defaultInputDir, "Device directory")
+	fs.Usage = printListInputsUsage
+	fs.Parse(args)
+
+	devices, err := listInputDevices(*dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	printInputDevices(os.Stdout, devices)
+	if !*identify {
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	fmt.Println("Press keys or turn encoders to identify them (Ctrl-C to stop)...")
+	if err := identifyInputs(ctx, devices, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// printInputDevices writes the device list and the suggested config stanza.
+func printInputDevices(out io.Writer, devices []inputDeviceInfo) {
+	if len(devices) == 0 {
+		fmt.Fprintln(out, "No input event devices found.")
+		return
+	}
+	var suggested []inputDeviceInfo
+	for _, d := range devices {
+		fmt.Fprintln(out, d.Path)
+		for _, l := range d.Links {
+			fmt.Fprintf(out, "  link:  %s\n", l)
+		}
+		if d.Err != nil {
+			fmt.Fprintf(out, "  error: %v\n", d.Err)
+			continue
+		}
+		fmt.Fprintf(out, "  name:  %s\n", d.Name)
+		if len(d.Keys) > 0 {
+			fmt.Fprintf(out, "  keys:  %s\n", summarizeInputKeys(d.Keys))
+		}
+		if len(d.Rels) > 0 {
+			names := make([]string, len(d.Rels))
+			for i, code := range d.Rels {
+				names[i] = inputRelName(code)
+			}
+			fmt.Fprintf(out, "  rel:   %s\n", strings.Join(names, " "))
+		}
+		if slices.Contains(d.Msc, MSC_SCAN) {
+			fmt.Fprintln(out, "  msc:   MSC_SCAN")
+		}
+		if t := d.suggestedType(); t != "" {
+			fmt.Fprintf(out, "  use:   type %s\n", t)
+			suggested = append(suggested, d)
+		}
+	}
+
+	if len(suggested) == 0 {
+		fmt.Fprintln(out, "\nNo device reports volume/media keys or a rotary axis.")
+		return
+	}
+	fmt.Fprintln(out, "\nSuggested config:")
+	fmt.Fprintln(out, "inputs:")
+	for _, d := range suggested {
+		fmt.Fprintf(out, "  - path: %s # %s\n", d.configPath(), d.Name)
+		fmt.Fprintf(out, "    type: %s\n", d.suggestedType())
+		if d.scancodesOnly() {
+			fmt.Fprintf(out, "    # sends scancodes only: map them with `streamerbrainz learn -device %s`\n", d.configPath())
+		}
+	}
+}
+
+// summarizeInputKeys lists the handled keys by name and counts the rest
+// (a keyboard reports hundreds).
+func summarizeInputKeys(codes []uint16) string {
+	var handled []string
+	for _, code := range codes {
+		if inputKeyActions[code] != "" {
+			handled = append(handled, inputKeyName(code))
+		}
+	}
+	other := len(codes) - len(handled)
+	switch {
+	case len(handled) == 0:
+		return fmt.Sprintf("%d codes, none handled", other)
+	case other == 0:
+		return strings.Join(handled, " ")
+	}
+	return fmt.Sprintf("%s (+%d other codes)", strings.Join(handled, " "), other)
+}
+
+// describeInputEvent renders a key press, wheel turn or scancode for -identify. It returns
+// "" for events not worth printing (releases, repeats, sync).
+func describeInputEvent(ev inputEvent) string {
+	switch {
+	case ev.Type == EV_KEY && ev.Value == evValuePress:
+		s := fmt.Sprintf("%s (%d)", inputKeyName(ev.Code), ev.Code)
+		if action := inputKeyActions[ev.Code]; action != "" {
+			return s + " -> " + action
+		}
+		return s + " (not handled)"
+	case ev.Type == EV_MSC && ev.Code == MSC_SCAN:
+		return fmt.Sprintf("scancode %#x", uint32(ev.Value))
+	case ev.Type == EV_REL && ev.Value != 0:
+		s := fmt.Sprintf("%s %+d", inputRelName(ev.Code), ev.Value)
+		if isRotaryRel(ev.Code) {
+			return s + " -> rotary"
+		}
+		return s + " (not handled)"
+	}
+	return ""
+}
@@ -0,0 +1,124 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// listInputDevices enumerates dir/event* with their by-id/by-path links and
+// capabilities. Devices that can't be opened are listed with Err set.
+func listInputDevices(dir string) ([]inputDeviceInfo, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "event*"))
+	if err != nil {
+		return nil, err
+	}
+	// Numeric order: event2 before event10.
+	sort.Slice(paths, func(i, j int) bool {
+		ni, _ := strconv.Atoi(strings.TrimPrefix(filepath.Base(paths[i]), "event"))
+		nj, _ := strconv.Atoi(strings.TrimPrefix(filepath.Base(paths[j]), "event"))
+		return ni < nj
+	})
+
+	links := map[string][]string{}
+	for _, sub := range []string{"by-id", "by-path"} {
+		entries, _ := filepath.Glob(filepath.Join(dir, sub, "*"))
+		for _, l := range entries {
+			if target, err := filepath.EvalSymlinks(l); err == nil {
+				links[target] = append(links[target], l)
+			}
+		}
+	}
+
+	devices := make([]inputDeviceInfo, 0, len(paths))
+	for _, p := range paths {
+		d := inputDeviceInfo{Path: p, Links: links[p]}
+		d.Name, d.Keys, d.Rels, d.Msc, d.Err = queryInputDevice(p)
+		devices = append(devices, d)
+	}
+	return devices, nil
+}
+
+// queryInputDevice reads a device's name and EV_KEY/EV_REL/EV_MSC codes.
+func queryInputDevice(path string) (name string, keys, rels, msc []uint16, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", nil, nil, nil, err
+	}
+	defer f.Close()
+	fd := int(f.Fd())
+	if name, err = evdevName(fd); err != nil {
+		return "", nil, nil, nil, err
+	}
+	if keys, err = evdevCodes(fd, EV_KEY, evdevKeyMax); err != nil {
+		return "", nil, nil, nil, err
+	}
+	if rels, err = evdevCodes(fd, EV_REL, evdevRelMax); err != nil {
+		return "", nil, nil, nil, err
+	}
+	if msc, err = evdevCodes(fd, EV_MSC, evdevMscMax); err != nil {
+		return "", nil, nil, nil, err
+	}
+	return name, keys, rels, msc, nil
+}
+
+// identifyInputs reads every openable device (without grabbing it) and prints
+// each key press and wheel turn until ctx is canceled.
+func identifyInputs(ctx context.Context, devices []inputDeviceInfo, out io.Writer) error {
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		files []*os.File
+	)
+	for _, d := range devices {
+		if d.Err != nil {
+			continue
+		}
+		f, err := os.Open(d.Path)
+		if err != nil {
+			continue
+		}
+		files = append(files, f)
+		label := d.Path
+		if d.Name != "" {
+			label += " (" + d.Name + ")"
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, inputEventSize*64)
+			for {
+				n, err := f.Read(buf)
+				if err != nil {
+					return
+				}
+				for off := 0; off+inputEventSize <= n; off += inputEventSize {
+					if s := describeInputEvent(decodeInputEvent(buf[off:])); s != "" {
+						mu.Lock()
+						fmt.Fprintf(out, "%s: %s\n", label, s)
+						mu.Unlock()
+					}
+				}
+			}
+		}()
+	}
+	if len(files) == 0 {
+		return errors.New("no readable input devices (permissions? try `sudo usermod -aG input $USER`)")
+	}
+
+	<-ctx.Done()
+	for _, f := range files {
+		f.Close()
+	}
+	wg.Wait()
+	return nil
+}
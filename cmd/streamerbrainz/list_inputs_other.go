@@ -0,0 +1,21 @@
+//go:build !linux
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var errListInputsUnsupported = errors.New("list-inputs requires linux evdev devices")
+
+// listInputDevices is unavailable outside Linux (evdev).
+func listInputDevices(dir string) ([]inputDeviceInfo, error) {
+	return nil, errListInputsUnsupported
+}
+
+// identifyInputs is unavailable outside Linux (evdev).
+func identifyInputs(ctx context.Context, devices []inputDeviceInfo, out io.Writer) error {
+	return errListInputsUnsupported
+}
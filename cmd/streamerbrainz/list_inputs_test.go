@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestInputDeviceInfo_SuggestedType(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		dev  inputDeviceInfo
+		want InputDeviceType
+	}{
+		{"ir remote", inputDeviceInfo{Keys: []uint16{KEY_MUTE, KEY_VOLUMEDOWN, KEY_VOLUMEUP, 2, 3}}, InputDeviceTypeKey},
+		{"encoder", inputDeviceInfo{Keys: []uint16{BTN_0}, Rels: []uint16{REL_DIAL}}, InputDeviceTypeRotary},
+		{"mouse", inputDeviceInfo{Keys: []uint16{0x110}, Rels: []uint16{0x00, 0x01, REL_WHEEL}}, ""},
+		{"power button", inputDeviceInfo{Keys: []uint16{116}}, ""},
+		{"raw ir receiver", inputDeviceInfo{Keys: []uint16{116}, Msc: []uint16{MSC_SCAN}}, InputDeviceTypeKey},
+		{"mouse with scancodes", inputDeviceInfo{Keys: []uint16{0x110}, Rels: []uint16{0x00, 0x01}, Msc: []uint16{MSC_SCAN}}, ""},
+	} {
+		if got := tc.dev.suggestedType(); got != tc.want {
+			t.Errorf("%s: suggestedType = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestPrintInputDevices_SuggestsStablePaths(t *testing.T) {
+	devices := []inputDeviceInfo{
+		{
+			Path:  "/dev/input/event3",
+			Links: []string{"/dev/input/by-path/platform-ir-receiver@12-event", "/dev/input/by-id/usb-flirc-event-kbd"},
+			Name:  "flirc",
+			Keys:  []uint16{KEY_MUTE, KEY_VOLUMEUP, 30, 31},
+		},
+		{Path: "/dev/input/event4", Name: "rotary@11", Rels: []uint16{REL_DIAL}},
+		{Path: "/dev/input/event5", Err: errors.New("permission denied")},
+	}
+	var out bytes.Buffer
+	printInputDevices(&out, devices)
+	for _, want := range []string{
+		"  keys:  KEY_MUTE KEY_VOLUMEUP (+2 other codes)\n",
+		"  rel:   REL_DIAL\n",
+		"  error: permission denied\n",
+		"inputs:\n  - path: /dev/input/by-id/usb-flirc-event-kbd # flirc\n    type: key\n  - path: /dev/input/event4 # rotary@11\n    type: rotary\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("missing %q in:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestDescribeInputEvent(t *testing.T) {
+	for _, tc := range []struct {
+		ev   inputEvent
+		want string
+	}{
+		{inputEvent{Type: EV_KEY, Code: KEY_VOLUMEUP, Value: evValuePress}, "KEY_VOLUMEUP (115) -> volume up (hold)"},
+		{inputEvent{Type: EV_KEY, Code: 30, Value: evValuePress}, "KEY_30 (30) (not handled)"},
+		{inputEvent{Type: EV_KEY, Code: KEY_VOLUMEUP, Value: evValueRepeat}, ""},
+		{inputEvent{Type: EV_KEY, Code: KEY_VOLUMEUP, Value: evValueRelease}, ""},
+		{inputEvent{Type: EV_REL, Code: REL_DIAL, Value: -1}, "REL_DIAL -1 -> rotary"},
+		{inputEvent{Type: EV_REL, Code: 0x00, Value: 5}, "REL_X +5 (not handled)"},
+		{inputEvent{Type: EV_MSC, Code: MSC_SCAN, Value: 0x40bf}, "scancode 0x40bf"},
+		{inputEvent{Type: EV_SYN}, ""},
+	} {
+		if got := describeInputEvent(tc.ev); got != tc.want {
+			t.Errorf("describeInputEvent(%+v) = %q, want %q", tc.ev, got, tc.want)
+		}
+	}
+}
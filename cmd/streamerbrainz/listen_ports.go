@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// ============================================================================
+// Listener ports
+// ============================================================================
+// Every TCP/UDP port the daemon listens on: webhooks.port, api.port, osc.port
+// and the listen option of control protocols (hue, alexa, udp_text). At
+// startup they are checked once, before any listener starts, so a clash is
+// reported naming the settings involved:
+//
+//   - two settings on the same port (and overlapping bind addresses)
+//   - a port already held by another program, typically a second named
+//     instance whose config kept the same ports
+//
+// `streamerbrainz doctor` checks the same list.
+// ============================================================================
+
+// listenPort is one configured listener.
+type listenPort struct {
+	name    string // "webhooks", "api", "osc", or the control protocol's name
+	network string // "tcp" or "udp"
+	addr    string // host:port
+	setting string // config key that sets it
+}
+
+// controlProtocolNetworks maps the control protocols that listen (options.listen)
+// to their network.
+var controlProtocolNetworks = map[string]string{
+	"alexa":    "tcp",
+	"hue":      "tcp",
+	"udp_text": "udp",
+}
+
+// listenPorts lists cfg's listeners, webhooks first.
+func listenPorts(cfg Config) []listenPort {
+	ports := []listenPort{{"webhooks", "tcp", cfg.Webhooks.ListenAddr(), "webhooks.port"}}
+	if addr := cfg.API.ListenAddr(); addr != "" {
+		ports = append(ports, listenPort{"api", "tcp", addr, "api.port"})
+	}
+	if cfg.OSC.Enabled {
+		ports = append(ports, listenPort{"osc", "udp", cfg.OSC.ListenAddr(), "osc.port"})
+	}
+	for i, cp := range cfg.ControlProtocols {
+		network, ok := controlProtocolNetworks[cp.Type]
+		if !ok {
+			continue
+		}
+		var opts struct {
+			Listen string `yaml:"listen"`
+		}
+		if err := cp.Options.Decode(&opts); err != nil {
+			// Reported by the protocol's own validation.
+			continue
+		}
+		name := cp.Name
+		if name == "" {
+			name = cp.Type
+		}
+		ports = append(ports, listenPort{name, network, opts.Listen, fmt.Sprintf("control_protocols[%d].options.listen", i)})
+	}
+	return ports
+}
+
+// overlaps reports whether p and q would bind the same port.
+func (p listenPort) overlaps(q listenPort) bool {
+	if p.network != q.network {
+		return false
+	}
+	ph, pp, err1 := net.SplitHostPort(p.addr)
+	qh, qp, err2 := net.SplitHostPort(q.addr)
+	if err1 != nil || err2 != nil || pp != qp || pp == "0" {
+		return false
+	}
+	return ph == qh || isWildcardHost(ph) || isWildcardHost(qh)
+}
+
+// isWildcardHost reports whether a listener on host binds every interface.
+func isWildcardHost(host string) bool {
+	ip := net.ParseIP(host)
+	return host == "" || (ip != nil && ip.IsUnspecified())
+}
+
+// checkListenPorts reports the first clash between ports, or with a port that
+// is already in use.
+func checkListenPorts(ports []listenPort) error {
+	for i, p := range ports {
+		for _, q := range ports[:i] {
+			if p.overlaps(q) {
+				return fmt.Errorf("%s (%s) and %s (%s) both use %s %s; change one of them",
+					q.setting, q.addr, p.setting, p.addr, p.network, p.addr)
+			}
+		}
+	}
+	for _, p := range ports {
+		if err := tryListen(p.network, p.addr); errors.Is(err, syscall.EADDRINUSE) {
+			return fmt.Errorf("%s %s (%s) is already in use, e.g. by another streamerbrainz instance; give each instance its own ports",
+				p.network, p.addr, p.setting)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestListenPorts(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.API.Port = 3002
+	cfg.OSC.Enabled = true
+	var opts yaml.Node
+	if err := yaml.Unmarshal([]byte("listen: 0.0.0.0:80\nname: Hi-Fi\n"), &opts); err != nil {
+		t.Fatal(err)
+	}
+	cfg.ControlProtocols = []ControlProtocolConfig{{Type: "hue", Options: *opts.Content[0]}, {Type: "cast"}}
+
+	var got []string
+	for _, p := range listenPorts(cfg) {
+		got = append(got, p.name+" "+p.network+" "+p.addr+" "+p.setting)
+	}
+	want := []string{
+		"webhooks tcp :3001 webhooks.port",
+		"api tcp :3002 api.port",
+		"osc udp :9000 osc.port",
+		"hue tcp 0.0.0.0:80 control_protocols[0].options.listen",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("listenPorts:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCheckListenPorts(t *testing.T) {
+	for _, tc := range []struct {
+		a, b    listenPort
+		overlap bool
+	}{
+		{listenPort{network: "tcp", addr: ":80"}, listenPort{network: "tcp", addr: "127.0.0.1:80"}, true},
+		{listenPort{network: "tcp", addr: "0.0.0.0:80"}, listenPort{network: "tcp", addr: "[::]:80"}, true},
+		{listenPort{network: "tcp", addr: "10.0.0.2:80"}, listenPort{network: "tcp", addr: "127.0.0.1:80"}, false},
+		{listenPort{network: "tcp", addr: ":9000"}, listenPort{network: "udp", addr: ":9000"}, false},
+		{listenPort{network: "tcp", addr: ":0"}, listenPort{network: "tcp", addr: ":0"}, false},
+	} {
+		if got := tc.a.overlaps(tc.b); got != tc.overlap {
+			t.Errorf("%s/%s vs %s/%s: overlaps = %v", tc.a.network, tc.a.addr, tc.b.network, tc.b.addr, got)
+		}
+	}
+
+	err := checkListenPorts([]listenPort{
+		{"webhooks", "tcp", ":0", "webhooks.port"},
+		{"alexa", "tcp", "127.0.0.1:8091", "control_protocols[0].options.listen"},
+		{"hue", "tcp", ":8091", "control_protocols[1].options.listen"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "control_protocols[0].options.listen") || !strings.Contains(err.Error(), "control_protocols[1].options.listen") {
+		t.Fatalf("clash between settings: %v", err)
+	}
+
+	// A port held by someone else (e.g. another instance).
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	err = checkListenPorts([]listenPort{{"webhooks", "tcp", l.Addr().String(), "webhooks.port"}})
+	if err == nil || !strings.Contains(err.Error(), "webhooks.port") || !strings.Contains(err.Error(), "already in use") {
+		t.Fatalf("port in use: %v", err)
+	}
+	if err := checkListenPorts([]listenPort{{"webhooks", "tcp", "127.0.0.1:0", "webhooks.port"}}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ============================================================================
+// ListenBrainz scrobbler
+// ============================================================================
+// POST /1/submit-listens with the user token (https://listenbrainz.org/settings/).
+// A 400 means the payload itself is bad and is dropped; anything else that
+// fails (auth, rate limiting, outages) is retried.
+// ============================================================================
+
+const (
+	defaultListenBrainzURL = "https://api.listenbrainz.org"
+
+	// listenBrainzMaxBatch is well under the API's per-request listen limit.
+	listenBrainzMaxBatch = 100
+)
+
+type listenBrainzScrobbler struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func newListenBrainzScrobbler(cfg ListenBrainzConfig) (*listenBrainzScrobbler, error) {
+	token, err := readSecret(cfg.Token)
+	if err != nil {
+		return nil, fmt.Errorf("token: %w", err)
+	}
+	u := cfg.URL
+	if u == "" {
+		u = defaultListenBrainzURL
+	}
+	return &listenBrainzScrobbler{url: strings.TrimRight(u, "/"), token: token, client: &http.Client{Timeout: scrobbleHTTPTimeout}}, nil
+}
+
+func (l *listenBrainzScrobbler) Name() string  { return "listenbrainz" }
+func (l *listenBrainzScrobbler) MaxBatch() int { return listenBrainzMaxBatch }
+
+// listenBrainzListen is one entry of a submit-listens payload.
+type listenBrainzListen struct {
+	ListenedAt    int64                 `json:"listened_at,omitempty"`
+	TrackMetadata listenBrainzTrackMeta `json:"track_metadata"`
+}
+
+type listenBrainzTrackMeta struct {
+	ArtistName     string         `json:"artist_name"`
+	TrackName      string         `json:"track_name"`
+	ReleaseName    string         `json:"release_name,omitempty"`
+	AdditionalInfo map[string]any `json:"additional_info"`
+}
+
+func listenBrainzEntry(t scrobbleTrack, listened bool) listenBrainzListen {
+	info := map[string]any{
+		"media_player":      t.Source,
+		"submission_client": "streamerbrainz",
+	}
+	if version != "" {
+		info["submission_client_version"] = version
+	}
+	if t.DurationMs > 0 {
+		info["duration_ms"] = t.DurationMs
+	}
+	e := listenBrainzListen{TrackMetadata: listenBrainzTrackMeta{ArtistName: t.Artist, TrackName: t.Title, ReleaseName: t.Album, AdditionalInfo: info}}
+	if listened {
+		e.ListenedAt = t.StartedAt.Unix()
+	}
+	return e
+}
+
+func (l *listenBrainzScrobbler) Scrobble(ctx context.Context, tracks []scrobbleTrack) error {
+	listenType := "import"
+	if len(tracks) == 1 {
+		listenType = "single"
+	}
+	payload := make([]listenBrainzListen, 0, len(tracks))
+	for _, t := range tracks {
+		payload = append(payload, listenBrainzEntry(t, true))
+	}
+	return l.submit(ctx, listenType, payload)
+}
+
+func (l *listenBrainzScrobbler) NowPlaying(ctx context.Context, t scrobbleTrack) error {
+	return l.submit(ctx, "playing_now", []listenBrainzListen{listenBrainzEntry(t, false)})
+}
+
+func (l *listenBrainzScrobbler) submit(ctx context.Context, listenType string, payload []listenBrainzListen) error {
+	body, err := json.Marshal(map[string]any{"listen_type": listenType, "payload": payload})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.url+"/1/submit-listens", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Token "+l.token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	var apiErr struct {
+		Error string `json:"error"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&apiErr)
+	err = fmt.Errorf("HTTP %d: %s", resp.StatusCode, apiErr.Error)
+	if resp.StatusCode == http.StatusBadRequest {
+		return &scrobbleRejectedError{err: err}
+	}
+	return err
+}
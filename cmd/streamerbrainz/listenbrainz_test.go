@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestListenBrainzScrobbler_Submit(t *testing.T) {
+	var got struct {
+		ListenType string               `json:"listen_type"`
+		Payload    []listenBrainzListen `json:"payload"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/1/submit-listens" || r.Header.Get("Authorization") != "Token lb-token" {
+			http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer srv.Close()
+
+	t.Setenv("LB_TOKEN", "lb-token")
+	lb, err := newListenBrainzScrobbler(ListenBrainzConfig{Token: "env:LB_TOKEN", URL: srv.URL + "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := time.Unix(1_700_000_000, 0)
+	track := scrobbleTrack{Source: "plex", Artist: "Nina Simone", Title: "Sinnerman", Album: "Pastel Blues", DurationMs: 622_000, StartedAt: started}
+	if err := lb.Scrobble(context.Background(), []scrobbleTrack{track}); err != nil {
+		t.Fatalf("Scrobble: %v", err)
+	}
+	if got.ListenType != "single" || len(got.Payload) != 1 {
+		t.Fatalf("got %+v", got)
+	}
+	p := got.Payload[0]
+	if p.ListenedAt != started.Unix() || p.TrackMetadata.ReleaseName != "Pastel Blues" || p.TrackMetadata.AdditionalInfo["media_player"] != "plex" {
+		t.Fatalf("payload = %+v", p)
+	}
+
+	got.Payload = nil
+	if err := lb.NowPlaying(context.Background(), track); err != nil {
+		t.Fatalf("NowPlaying: %v", err)
+	}
+	if got.ListenType != "playing_now" || got.Payload[0].ListenedAt != 0 {
+		t.Fatalf("now playing payload = %+v", got)
+	}
+}
+
+func TestListenBrainzScrobbler_Errors(t *testing.T) {
+	status := http.StatusBadRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"bad listen"}`, status)
+	}))
+	defer srv.Close()
+
+	lb := &listenBrainzScrobbler{url: srv.URL, token: "t", client: srv.Client()}
+	tracks := []scrobbleTrack{{Artist: "A", Title: "T", StartedAt: time.Now()}}
+	var rejected *scrobbleRejectedError
+	if err := lb.Scrobble(context.Background(), tracks); !errors.As(err, &rejected) {
+		t.Fatalf("400: expected rejection, got %v", err)
+	}
+	status = http.StatusServiceUnavailable
+	if err := lb.Scrobble(context.Background(), tracks); err == nil || errors.As(err, &rejected) {
+		t.Fatalf("503: expected a retryable error, got %v", err)
+	}
+}
@@ -9,17 +9,17 @@ import (
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 
 	"golang.org/x/sync/errgroup"
 	"gopkg.in/yaml.v3"
 )
 
-const version = "1.0.0"
-
 const defaultConfigPath = "~/.config/streamerbrainz/config.yaml"
 
 func printVersion() {
-	fmt.Printf("StreamerBrainz v%s\n", version)
+	info := buildVersionInfo()
+	fmt.Printf("StreamerBrainz v%s (commit %s, built %s, %s)\n", info.Version, info.Commit, info.BuildDate, info.GoVersion)
 	fmt.Println("IR remote control daemon for CamillaDSP volume control")
 }
 
@@ -29,6 +29,15 @@ func printUsage() {
 	fmt.Println("USAGE:")
 	fmt.Println("  streamerbrainz [OPTIONS]")
 	fmt.Println("  streamerbrainz librespot-hook [OPTIONS]")
+	fmt.Println("  streamerbrainz config validate|schema [OPTIONS]")
+	fmt.Println("  streamerbrainz plex-login|plex-discover [OPTIONS]")
+	fmt.Println("  streamerbrainz lastfm-login [OPTIONS]")
+	fmt.Println("  streamerbrainz tune [OPTIONS]")
+	fmt.Println("  streamerbrainz ctl [OPTIONS] <command>")
+	fmt.Println("  streamerbrainz dsp [OPTIONS] watch|cmd ...")
+	fmt.Println("  streamerbrainz doctor [OPTIONS]")
+	fmt.Println("  streamerbrainz list-inputs [-identify]")
+	fmt.Println("  streamerbrainz learn -device <path> [OPTIONS]")
 	fmt.Println()
 	fmt.Println("DESCRIPTION:")
 	fmt.Println("  Daemon that bridges input/control intent to CamillaDSP volume control.")
@@ -36,10 +45,20 @@ func printUsage() {
 	fmt.Println()
 	fmt.Println("OPTIONS:")
 	fmt.Println("  -config string")
-	fmt.Printf("        Path to YAML config file (default %q)\n", defaultConfigPath)
+	fmt.Printf("        Path to YAML config file (default $STREAMERBRAINZ_CONFIG or %q)\n", defaultConfigPath)
+	fmt.Println()
+	fmt.Println("  -instance string")
+	fmt.Println("        Run as a named instance: config ~/.config/streamerbrainz/NAME.yaml (which must")
+	fmt.Println("        set its ports), own socket and stats file. Given before a subcommand, it")
+	fmt.Println("        targets that instance")
+	fmt.Println()
+	fmt.Println("  -container")
+	fmt.Println("        Container mode: config /config/config.yaml (or defaults + environment if absent),")
+	fmt.Println("        no input devices by default, statistics in /data")
 	fmt.Println()
 	fmt.Println("  -print-default-config")
-	fmt.Println("        Print a default YAML config to stdout and exit")
+	fmt.Println("        Print a default YAML config to stdout and exit (with -container: the")
+	fmt.Println("        container config and a docker-compose example)")
 	fmt.Println()
 	fmt.Println("  -log-level string")
 	fmt.Println("        Override logging.level from config (error, warn, info, debug)")
@@ -50,11 +69,82 @@ func printUsage() {
 	fmt.Println("  -help")
 	fmt.Println("        Print this help message")
 	fmt.Println()
+	fmt.Println("ENVIRONMENT VARIABLES:")
+	fmt.Println("  STREAMERBRAINZ_CONFIG      - Config file path when -config is not given")
+	fmt.Println("  STREAMERBRAINZ_WS_URL      - Override camilladsp.ws_url")
+	fmt.Println("  STREAMERBRAINZ_SOCKET_PATH - Override ipc.socket_path")
+	fmt.Println("  STREAMERBRAINZ_LOG_LEVEL   - Override logging.level (-log-level still wins)")
+	fmt.Println("  STREAMERBRAINZ_INSTANCE    - Instance name when -instance is not given")
+	fmt.Println("  STREAMERBRAINZ_CONTAINER   - 1 turns on container mode (for subcommands too)")
+	fmt.Println()
+	fmt.Println("  Fragments in conf.d/*.yaml next to the config file are merged over it")
+	fmt.Println("  in lexical order.")
+	fmt.Println()
 	fmt.Println("SUBCOMMANDS:")
 	fmt.Println("  librespot-hook")
 	fmt.Println("        Run as librespot event hook (reads PLAYER_EVENT from environment)")
 	fmt.Println("        Options: -config, -log-level")
 	fmt.Println()
+	fmt.Println("  config validate")
+	fmt.Println("        Validate a config file and print the effective config (defaults filled in)")
+	fmt.Println("        Options: -config")
+	fmt.Println()
+	fmt.Println("  config schema")
+	fmt.Println("        Print a JSON Schema for the config file (for editors/CI)")
+	fmt.Println()
+	fmt.Println("  plex-login")
+	fmt.Println("        Sign in to plex.tv with a PIN and write the token to plex.token_file")
+	fmt.Println("        Options: -config, -timeout")
+	fmt.Println()
+	fmt.Println("  lastfm-login")
+	fmt.Println("        Authorize Last.fm scrobbling and write scrobble.lastfm.session_key_file")
+	fmt.Println("        Options: -config, -timeout")
+	fmt.Println()
+	fmt.Println("  plex-discover")
+	fmt.Println("        List Plex players with their machineIdentifier (for plex.machine_id)")
+	fmt.Println("        Options: -config")
+	fmt.Println()
+	fmt.Println("  tune")
+	fmt.Println("        Measure tap/hold/spin behaviour against the running daemon and suggest")
+	fmt.Println("        velocity/rotary settings (optionally applied live or written to the config)")
+	fmt.Println("        Options: -config, -url, -zone")
+	fmt.Println()
+	fmt.Println("  ctl resync")
+	fmt.Println("        Make the running daemon re-read its state from CamillaDSP and re-broadcast it")
+	fmt.Println("        Options: -config, -zone")
+	fmt.Println()
+	fmt.Println("  ctl ready")
+	fmt.Println("        Exit non-zero unless every zone's CamillaDSP answers (container healthcheck)")
+	fmt.Println("        Options: -config, -url")
+	fmt.Println()
+	fmt.Println("  ctl dump-state")
+	fmt.Println("        Print the running daemon's state, recent events and errors as JSON for a bug report")
+	fmt.Println("        Options: -config, -url")
+	fmt.Println()
+	fmt.Println("  ctl tap on [duration] | off | status")
+	fmt.Println("        Log every CamillaDSP protocol frame to diagnostics.tap_file and the state stream")
+	fmt.Println("        Options: -config, -url")
+	fmt.Println()
+	fmt.Println("  dsp watch [volume,mute,state,levels]")
+	fmt.Println("        Poll CamillaDSP directly (as configured) and print changes")
+	fmt.Println("        Options: -config, -zone, -url, -interval, -json")
+	fmt.Println()
+	fmt.Println("  dsp cmd <Command> [arg...]")
+	fmt.Println("        Send one CamillaDSP command and print the reply value")
+	fmt.Println("        Options: -config, -zone, -url, -json")
+	fmt.Println()
+	fmt.Println("  doctor")
+	fmt.Println("        Check config, input devices, CamillaDSP, IPC socket, ports and Plex, and print fixes")
+	fmt.Println("        Options: -config")
+	fmt.Println()
+	fmt.Println("  list-inputs")
+	fmt.Println("        List input devices with their key/wheel codes and suggest an inputs: config")
+	fmt.Println("        Options: -identify (print presses per device), -dir")
+	fmt.Println()
+	fmt.Println("  learn -device <path>")
+	fmt.Println("        Press a remote's buttons for each action and write the device's keymap to the config")
+	fmt.Println("        Options: -config, -actions, -presets, -timeout, -dry-run")
+	fmt.Println()
 	fmt.Println("EXAMPLES:")
 	fmt.Println("  # Print a default config template")
 	fmt.Println("  streamerbrainz -print-default-config > streamerbrainz.yaml")
@@ -69,11 +159,59 @@ func printUsage() {
 }
 
 func main() {
-	// Check for subcommand mode (librespot hook) first
+	// A leading -instance NAME applies to the subcommand too (see instance_name.go).
+	args, err := stripInstanceFlag(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(2)
+	}
+	os.Args = append(os.Args[:1], args...)
+
+	// Check for subcommand mode (librespot hook, config, plex and tuning tools) first
 	if len(os.Args) > 1 && os.Args[1] == "librespot-hook" {
 		runLibrespotSubcommand()
 		return
 	}
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		runConfigSubcommand(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "plex-login" {
+		runPlexLoginSubcommand(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "lastfm-login" {
+		runLastFMLoginSubcommand(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "plex-discover" {
+		runPlexDiscoverSubcommand(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "tune" {
+		runTuneSubcommand(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "ctl" {
+		runCtlSubcommand(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "dsp" {
+		runDSPSubcommand(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		runDoctorSubcommand(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "list-inputs" {
+		runListInputsSubcommand(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "learn" {
+		runLearnSubcommand(os.Args[2:])
+		return
+	}
 
 	// Check for version/help flags early (for main command)
 	for _, arg := range os.Args[1:] {
@@ -90,6 +228,8 @@ func main() {
 	// Parse command-line flags (config-first, minimal overrides)
 	var (
 		configPath         = flag.String("config", "", "Path to YAML config file")
+		instance           = flag.String("instance", "", "Run as named instance (config NAME.yaml with its own ports, own socket and stats file)")
+		container          = flag.Bool("container", false, "Container mode (config /config/config.yaml or the environment, no inputs by default)")
 		printDefaultConfig = flag.Bool("print-default-config", false, "Print default YAML config and exit")
 		logLevelOverride   = flag.String("log-level", "", "Override logging.level from config (error, warn, info, debug)")
 		showVersion        = flag.Bool("version", false, "Print version and exit")
@@ -107,6 +247,18 @@ func main() {
 		printVersion()
 		return
 	}
+	if *container {
+		setContainerMode()
+	}
+	if *printDefaultConfig && containerMode() {
+		b, err := containerConfigTemplate()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error: marshal default config:", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(b))
+		return
+	}
 	if *printDefaultConfig {
 		cfg := DefaultConfig()
 		b, err := yaml.Marshal(cfg)
@@ -117,9 +269,13 @@ func main() {
 		fmt.Println(string(b))
 		return
 	}
-	if *configPath == "" {
-		*configPath = defaultConfigPath
+	if *instance != "" {
+		if err := setInstance(*instance); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(2)
+		}
 	}
+	*configPath = ResolveConfigPath(*configPath)
 
 	cfg, err := LoadConfigFile(*configPath)
 	if err != nil {
@@ -133,11 +289,7 @@ func main() {
 	}
 
 	// Expand user paths
-	cfg.IPC.SocketPath = ExpandPath(cfg.IPC.SocketPath)
-	for i := range cfg.Inputs {
-		cfg.Inputs[i].Path = ExpandPath(cfg.Inputs[i].Path)
-	}
-	cfg.Plex.TokenFile = ExpandPath(cfg.Plex.TokenFile)
+	cfg.expandPaths()
 
 	// Validate fully materialized config
 	if err := cfg.Validate(); err != nil {
@@ -151,19 +303,67 @@ func main() {
 		fmt.Fprintln(os.Stderr, "error:", err)
 		os.Exit(1)
 	}
-	logger := setupLogger(logLevel)
+	// Warnings and errors are also kept for GET /api/v1/debug/state.
+	logs := &logRing{}
+	logger := withLogRing(setupLogger(logLevel), logs)
+	if name := instanceName(); name != "" {
+		logger = logger.With("instance", name)
+	}
+	for _, w := range cfg.deprecations {
+		logger.Warn("deprecated config", "detail", w)
+	}
+
+	// Refuse to run next to another instance on the same IPC socket (see instance.go).
+	releaseLock, err := acquireInstanceLock(cfg.IPC.SocketPath)
+	if err != nil {
+		logger.Error("cannot start", "error", err)
+		os.Exit(1)
+	}
+	defer releaseLock()
+
+	// Report port clashes (two settings, or another instance) before anything listens.
+	if err := checkListenPorts(listenPorts(cfg)); err != nil {
+		logger.Error("cannot start", "error", err)
+		os.Exit(1)
+	}
 
 	// Open all input devices
 	type openDevice struct {
-		file *os.File
-		typ  InputDeviceType
-		path string
+		file   *os.File
+		typ    InputDeviceType
+		path   string
+		keymap []KeyBinding
+		// Slow-repeat normalization (see repeat_interval.go)
+		repeatInterval time.Duration
+		autoRepeat     bool
+		// no_release mode (see no_release.go)
+		noRelease       string
+		noReleaseStepDB float64
 	}
 	var openDevices []openDevice
+	var fifoPaths []string
+	hotkeys := false
 
 	for _, inputDev := range cfg.Inputs {
+		if inputDev.Type == InputDeviceTypeFifo {
+			// Opened (and created if needed) by startFifoInputs.
+			fifoPaths = append(fifoPaths, inputDev.Path)
+			continue
+		}
+		if inputDev.Type == InputDeviceTypeHotkey {
+			hotkeys = true
+			continue
+		}
+		if !evdevSupported {
+			logger.Warn("evdev inputs are not supported on this platform, skipping", "device", inputDev.Path, "type", inputDev.Type, "tip", "use an input of type hotkey for media keys")
+			continue
+		}
 		f, err := os.Open(inputDev.Path)
-		if err != nil {
+		if err != nil && cfg.InputsOptional {
+			// Left without a file: the input readers keep retrying it like a
+			// disconnected device (backoff, hotplug).
+			logger.Warn("input device unavailable, will retry", "device", inputDev.Path, "error", err, "reason", "inputs_optional")
+		} else if err != nil {
 			logger.Error("failed to open input device", "device", inputDev.Path, "error", err, "tip", "run as root or add user to 'input' group")
 			// Close already opened devices
 			for _, od := range openDevices {
@@ -172,25 +372,64 @@ func main() {
 			os.Exit(1)
 		}
 		openDevices = append(openDevices, openDevice{
-			file: f,
-			typ:  inputDev.Type,
-			path: inputDev.Path,
+			file:   f,
+			typ:    inputDev.Type,
+			path:   inputDev.Path,
+			keymap: inputDev.Keymap,
+
+			repeatInterval: time.Duration(inputDev.RepeatIntervalMS) * time.Millisecond,
+			autoRepeat:     inputDev.AutoRepeatInterval,
+
+			noRelease:       inputDev.NoRelease,
+			noReleaseStepDB: inputDev.StepDB,
 		})
-		logger.Debug("opened input device", "device", inputDev.Path, "type", inputDev.Type)
-	}
-	defer func() {
-		for _, od := range openDevices {
-			od.file.Close()
+		if f != nil {
+			logger.Debug("opened input device", "device", inputDev.Path, "type", inputDev.Type)
 		}
-	}()
+	}
+	if len(openDevices) == 0 && len(fifoPaths) == 0 && !hotkeys {
+		logger.Info("no inputs active; control via IPC, HTTP API and WebSocket only")
+	}
 
-	// Setup CamillaDSP client
-	client, err := NewCamillaDSPClient(cfg.CamillaDSP.WsURL, logger, cfg.CamillaDSP.TimeoutMS)
+	limitOverrideToken, err := readLimitOverrideToken(cfg.LimitOverride.TokenFile)
 	if err != nil {
-		logger.Error("failed to connect to CamillaDSP", "error", err)
-		os.Exit(1)
+		logger.Error("limit override disabled", "error", err)
 	}
-	defer client.Close()
+
+	// Setup one CamillaDSP client per zone
+	zoneTargets := cfg.ZoneTargets()
+	clients := make([]*CamillaDSPClient, len(zoneTargets))
+	for i, zt := range zoneTargets {
+		dial, err := zt.CamillaDSP.dialOptions()
+		if err != nil {
+			logger.Error("invalid CamillaDSP TLS settings", "zone", zt.ID, "error", err)
+			os.Exit(1)
+		}
+		// Connect in the background once the zone runs (camilladsp_startup.go),
+		// unless reconnect.wait_at_startup asks to wait for CamillaDSP here.
+		newClient := NewCamillaDSPClientDeferred
+		if zt.CamillaDSP.Reconnect.WaitAtStartup {
+			dial.Reconnect.forever = true
+			newClient = NewCamillaDSPClientWithOptions
+		}
+		client, err := newClient(zt.CamillaDSP.WsURL, dial, logger.With("zone", zt.ID), zt.CamillaDSP.TimeoutMS)
+		if err != nil {
+			logger.Error("failed to connect to CamillaDSP", "zone", zt.ID, "error", err)
+			os.Exit(1)
+		}
+		client.pipelining = zt.CamillaDSP.Pipeline
+		client.timeouts = zt.CamillaDSP.commandTimeouts()
+		client.setRequirements(camillaRequirements(cfg, zt.CamillaDSP))
+		if zt.CamillaDSP.MonitorHz > 0 {
+			client.openMonitor()
+		}
+		clients[i] = client
+	}
+	defer func() {
+		for _, c := range clients {
+			c.Close()
+		}
+	}()
 
 	// Coordinated shutdown using context + errgroup.
 	// - ctx is canceled on SIGINT/SIGTERM
@@ -201,87 +440,395 @@ func main() {
 
 	g, ctx := errgroup.WithContext(ctx)
 
+	// A panic in any long-running goroutine writes a crash dump and shuts everything
+	// down (see crash.go) instead of leaving the rest of the daemon running without it.
+	crash := newCrashReporter(cfg.Diagnostics.CrashDumpDir, stop, logger)
+
 	// Central event bus
 	events := make(chan Event, 64)
 
+	// Process-wide counters (GET /metrics on the control API listener).
+	metrics := &Metrics{}
+
 	// Reducer-emitted state broadcasts (for WebSocket/UI/etc). Must never block the daemon.
 	stateBroadcasts := make(chan StateBroadcast, 64)
 
-	// Start daemon loop (owns DaemonState and bootstraps via DaemonStarted)
+	// CamillaDSP protocol tap (off until turned on via ctl tap / the API). Frames
+	// are dropped rather than queued under backpressure.
+	tap := newCamillaTap(cfg.Diagnostics, func(b StateBroadcast) {
+		select {
+		case stateBroadcasts <- b:
+		default:
+		}
+	}, logger)
+	for i, zt := range zoneTargets {
+		clients[i].tap, clients[i].tapZone = tap, zt.ID
+	}
+	crash.Go("camilladsp tap", func() { tap.Run(ctx) })
+
+	// Start one daemon loop per zone (each owns its DaemonState and bootstraps via DaemonStarted),
+	// plus the zone router that dispatches the central event bus to them.
+	var routes []zoneRoute
+	for i, zt := range zoneTargets {
+		zoneEvents := make(chan Event, 64)
+		zoneSenders := new(sync.WaitGroup)
+		routes = append(routes, zoneRoute{ID: zt.ID, Events: zoneEvents, Senders: zoneSenders})
+
+		client := clients[i]
+		velCfg := cfg.ToVelocityConfigFor(zt.CamillaDSP)
+		policy := cfg.ToReducerPolicyFor(zt.CamillaDSP)
+		policy.LimitOverrideToken = limitOverrideToken
+		g.Go(func() error {
+			runDaemon(ctx, zt.ID, zoneEvents, stateBroadcasts, client, velCfg, cfg.Rotary, policy, cfg.Outputs, zt.CamillaDSP.UpdateHz, zt.CamillaDSP.IdleHz,
+				newLoopDiagnostics(metrics, cfg.Diagnostics, logger.With("zone", zt.ID)), crash, logger.With("zone", zt.ID))
+			return nil
+		})
+		if !zt.CamillaDSP.Reconnect.WaitAtStartup {
+			// Sends to zoneEvents, so the router must not close it before this returns.
+			zoneSenders.Add(1)
+			g.Go(func() error {
+				defer zoneSenders.Done()
+				defer crash.recoverPanic("camilladsp connect " + zt.ID)
+				client.connectInBackground(ctx, func() {
+					select {
+					case zoneEvents <- ResyncState{}:
+					case <-ctx.Done():
+					}
+				})
+				return nil
+			})
+		}
+		if sec := zt.CamillaDSP.KeepaliveSec; sec > 0 {
+			g.Go(func() error {
+				defer crash.recoverPanic("camilladsp keepalive " + zt.ID)
+				runCamillaKeepalive(ctx, client, time.Duration(sec)*time.Second, logger.With("zone", zt.ID))
+				return nil
+			})
+		}
+		if hz := zt.CamillaDSP.MonitorHz; hz > 0 {
+			// Meters skip the zone loop: they change no state and would only add
+			// reducer traffic. Dropped rather than queued under backpressure.
+			g.Go(func() error {
+				defer crash.recoverPanic("camilladsp monitor " + zt.ID)
+				runCamillaMonitor(ctx, client, hz, func(b StateBroadcast) {
+					select {
+					case stateBroadcasts <- ZoneBroadcast{Zone: zt.ID, Broadcast: b}:
+					default:
+					}
+				}, logger.With("zone", zt.ID))
+				return nil
+			})
+		}
+	}
 	g.Go(func() error {
-		runDaemon(ctx, events, stateBroadcasts, client, cfg.ToVelocityConfig(), cfg.Rotary, cfg.CamillaDSP.UpdateHz, logger)
+		defer crash.recoverPanic("zone router")
+		runZoneRouter(ctx, events, routes, cfg.InitialZone(), cfg.ZoneLinks, stateBroadcasts, logger)
 		return nil
 	})
 
 	// Start IPC server (context-aware; blocks until ctx is canceled)
 	g.Go(func() error {
+		defer crash.recoverPanic("ipc server")
 		return runIPCServer(ctx, cfg.IPC.SocketPath, events, logger)
 	})
 
 	// Enable Plex integration (webhooks + session polling) if configured.
-	// NOTE: setupPlexWebhook currently isn't context-aware; it may start background
-	// work internally. If it fails, cancel the program and let coordinated shutdown
-	// handle teardown.
-	// Shared HTTP mux for all HTTP endpoints (webhooks + websockets).
-	mux := http.NewServeMux()
+	// Session lookups run under ctx, so they are canceled on shutdown. If setup
+	// fails, cancel the program and let coordinated shutdown handle teardown.
+	//
+	// HTTP muxes:
+	// - webhooksMux hosts public webhook receivers (e.g. Plex).
+	// - apiMux hosts the control API (state WS/SSE, event injection).
+	// If api.port is 0 both share the webhooks listener (single server, previous behavior).
+	webhooksMux := http.NewServeMux()
+	apiMux := webhooksMux
+	if cfg.API.Port != 0 {
+		apiMux = http.NewServeMux()
+	}
 
 	if cfg.Plex.Enabled {
-		if err := setupPlexWebhook(cfg.Plex.ServerURL, cfg.Plex.TokenFile, cfg.Plex.MachineID, mux, events, logger); err != nil {
+		if err := setupPlexWebhook(ctx, cfg.Plex, webhooksMux, events, logger); err != nil {
 			logger.Error("failed to setup Plex webhook", "error", err)
 			stop()
 		}
 	}
 
+	if cfg.TidalConnect.Enabled {
+		crash.Go("tidal connect", func() { runTidalConnect(ctx, cfg.TidalConnect, events, logger) })
+	}
+
+	if cfg.Webhooks.Event.Enabled {
+		if err := setupEventWebhook(cfg.Webhooks.Event.TokenFile, apiMux, events, logger); err != nil {
+			logger.Error("failed to setup event webhook", "error", err)
+			stop()
+		}
+	}
+
+	// Endpoints that change state or expose internals need the API token (see api_auth.go).
+	auth, err := newAPIAuth(cfg, logger)
+	if err != nil {
+		logger.Error("failed to read control API token", "error", err)
+		stop()
+	}
+	if !auth.open() {
+		logger.Warn("control endpoints that change state are refused: set webhooks.event.token_file or bind the API listener to loopback")
+	}
+
 	// State WebSocket endpoint (initial snapshot via reducer; broadcasts via reducer outputs).
 	wsSrv := NewServer(logger, events, ServerConfig{
 		Hub: HubConfig{
-			SendBuf:      cfg.WebSocket.SendBuf,
-			BroadcastBuf: cfg.WebSocket.BroadcastBuf,
+			SendBuf:         cfg.WebSocket.SendBuf,
+			BroadcastBuf:    cfg.WebSocket.BroadcastBuf,
+			MaxClients:      cfg.WebSocket.MaxClients,
+			MaxClientsPerIP: cfg.WebSocket.MaxClientsPerIP,
+			ReplayBuf:       cfg.WebSocket.ReplayBuf,
 		},
+		AllowedOrigins: cfg.WebSocket.AllowedOrigins,
 	})
-	wsSrv.Register(mux, "/ws/state")
-	go wsSrv.Hub().Run(ctx)
-	go RunBroadcaster(ctx, wsSrv.Hub(), stateBroadcasts, logger)
+	wsSrv.Register(apiMux, "/ws/state")
+	newTuningAPI(&cfg, events, logger).Register(apiMux, "/api/v1/tuning", auth)
+	wsSrv.RegisterSSE(apiMux, "/events")
+	apiMux.Handle("/metrics", metrics)
+	apiMux.Handle("/healthz", &healthHandler{events: events, logger: logger})
+	apiMux.Handle("/readyz", &readyHandler{events: events, logger: logger})
+	apiMux.Handle("/jsonrpc", auth.require(&jsonRPCHandler{events: events, logger: logger}))
+	apiMux.Handle("/api/v1/resync", auth.require(&resyncHandler{events: events, logger: logger}))
+
+	// Build info and, if enabled, the periodic release check.
+	var updates *updateChecker
+	if cfg.UpdateCheck.Enabled {
+		updates = newUpdateChecker(cfg.UpdateCheck, events, logger)
+		crash.Go("update check", func() { updates.Run(ctx) })
+	}
+	apiMux.Handle("/api/v1/version", &versionHandler{updates: updates})
+
+	// Listening statistics (recorded from broadcasts below).
+	var stats *listeningStats
+	if cfg.Stats.Enabled {
+		stats = newListeningStats(cfg.Stats, events, logger)
+	}
+	apiMux.Handle("/api/v1/stats", &statsHandler{stats: stats})
+	apiMux.Handle("/api/v1/debug/state", auth.require(&debugStateHandler{
+		crash:      crash,
+		logs:       logs,
+		configPath: *configPath,
+		configSum:  fileSHA256(*configPath),
+		started:    time.Now(),
+	}))
+	apiMux.Handle("/api/v1/debug/tap", auth.require(&tapHandler{tap: tap}))
+	openapi, err := newOpenAPIHandler(cfg.API.BasePathPrefix())
+	if err != nil {
+		logger.Error("failed to build OpenAPI document", "error", err)
+		os.Exit(1)
+	}
+	apiMux.Handle("/api/openapi.json", openapi)
+	crash.Go("ws hub", func() { wsSrv.Hub().Run(ctx) })
+
+	// Fan reducer broadcasts out to each consumer (WS/SSE hub, outbound webhooks, alerts, OSC feedback, LEDs, IR transmit, control protocols).
+	wsBroadcasts := make(chan StateBroadcast, 64)
+	broadcastConsumers := []chan<- StateBroadcast{wsBroadcasts}
+	if len(cfg.OutboundWebhooks) > 0 {
+		targets, err := resolveOutboundWebhookSecrets(cfg.OutboundWebhooks)
+		if err != nil {
+			logger.Error("outbound webhooks disabled", "error", err)
+		} else {
+			outboundBroadcasts := make(chan StateBroadcast, 64)
+			broadcastConsumers = append(broadcastConsumers, outboundBroadcasts)
+			crash.Go("outbound webhooks", func() { RunOutboundWebhooks(ctx, targets, outboundBroadcasts, logger) })
+		}
+	}
+	if cfg.Alerts.Enabled {
+		alertsCfg := cfg.Alerts
+		var err error
+		if alertsCfg.Channels, err = resolveAlertChannelSecrets(cfg.Alerts.Channels); err != nil {
+			logger.Error("alerts disabled", "error", err)
+		} else {
+			alertBroadcasts := make(chan StateBroadcast, 64)
+			broadcastConsumers = append(broadcastConsumers, alertBroadcasts)
+			crash.Go("alerts", func() { runAlerts(ctx, alertsCfg, alertBroadcasts, logger) })
+		}
+	}
+	if cfg.OSC.Enabled {
+		oscBroadcasts := make(chan StateBroadcast, 64)
+		broadcastConsumers = append(broadcastConsumers, oscBroadcasts)
+		g.Go(func() error {
+			defer crash.recoverPanic("osc server")
+			return runOSCServer(ctx, cfg.OSC, cfg.InitialZone(), events, oscBroadcasts, logger)
+		})
+	}
+	if cfg.LED.Enabled {
+		ranges := make(map[string]ledRange, len(zoneTargets))
+		for _, zt := range zoneTargets {
+			ranges[zt.ID] = ledRange{MinDB: zt.CamillaDSP.MinDB, MaxDB: zt.CamillaDSP.MaxDB}
+		}
+		ledBroadcasts := make(chan StateBroadcast, 64)
+		broadcastConsumers = append(broadcastConsumers, ledBroadcasts)
+		crash.Go("led", func() { runLEDIndicator(ctx, cfg.LED, ranges, cfg.InitialZone(), events, ledBroadcasts, logger) })
+	}
+	if cfg.IRTx.Enabled {
+		tx, err := openIRTransmitter(cfg.IRTx)
+		if err != nil {
+			logger.Error("ir_tx disabled", "backend", cfg.IRTx.Backend, "error", err)
+		} else {
+			irBroadcasts := make(chan StateBroadcast, 64)
+			broadcastConsumers = append(broadcastConsumers, irBroadcasts)
+			crash.Go("ir_tx", func() { runIRTx(ctx, cfg.IRTx, tx, irBroadcasts, logger) })
+		}
+	}
+	if stats != nil {
+		statsBroadcasts := make(chan StateBroadcast, 64)
+		broadcastConsumers = append(broadcastConsumers, statsBroadcasts)
+		g.Go(func() error {
+			defer crash.recoverPanic("stats")
+			stats.Run(ctx, statsBroadcasts) // saves on shutdown, so wait for it
+			return nil
+		})
+	}
+	if cfg.Scrobble.Enabled {
+		services, err := newScrobbleServices(cfg.Scrobble)
+		if err != nil {
+			logger.Error("scrobbling disabled", "error", err)
+		} else {
+			scrobbleBroadcasts := make(chan StateBroadcast, 64)
+			broadcastConsumers = append(broadcastConsumers, scrobbleBroadcasts)
+			crash.Go("scrobbler", func() { runScrobbler(ctx, cfg.Scrobble, services, scrobbleBroadcasts, logger) })
+		}
+	}
+	if len(cfg.ControlProtocols) > 0 {
+		var protocols []namedControlProtocol
+		for _, cp := range cfg.ControlProtocols {
+			name := cp.Name
+			if name == "" {
+				name = cp.Type
+			}
+			p, err := newControlProtocol(cp, logger.With("protocol", name))
+			if err != nil {
+				logger.Error("failed to set up control protocol", "protocol", name, "error", err)
+				os.Exit(1)
+			}
+			protocols = append(protocols, namedControlProtocol{Name: name, ControlProtocol: p})
+		}
+		protocolBroadcasts := make(chan StateBroadcast, 64)
+		broadcastConsumers = append(broadcastConsumers, protocolBroadcasts)
+		g.Go(func() error {
+			defer crash.recoverPanic("control protocols")
+			runControlProtocols(ctx, protocols, events, protocolBroadcasts, logger)
+			return nil
+		})
+	}
+	crash.Go("broadcast tee", func() { TeeBroadcasts(ctx, stateBroadcasts, logger, broadcastConsumers...) })
+	crash.Go("ws broadcaster", func() { RunBroadcaster(ctx, wsSrv.Hub(), wsBroadcasts, cfg.WebSocket.CoalesceMs, logger) })
 	logger.Info("state ws endpoint registered", "path", "/ws/state")
+	logger.Info("state sse endpoint registered", "path", "/events")
+
+	// api.cors applies to the control API; api.base_path and trusted proxy headers
+	// apply to every listener.
+	var webhooksHandler http.Handler = webhooksMux
+	if cfg.API.Port == 0 {
+		webhooksHandler = newCORSHandler(cfg.API.CORS, webhooksMux)
+	}
+	webhooksHandler, err = newProxyHandler(cfg.API, webhooksHandler)
+	if err != nil {
+		logger.Error("invalid reverse proxy settings", "error", err)
+		os.Exit(1)
+	}
+	apiHandler := webhooksHandler
+	if cfg.API.Port != 0 {
+		apiHandler, _ = newProxyHandler(cfg.API, newCORSHandler(cfg.API.CORS, apiMux))
+	}
 
-	// Start webhooks HTTP server (context-aware; blocks until ctx is canceled)
+	// Start HTTP server(s) (context-aware; block until ctx is canceled)
 	g.Go(func() error {
-		return runWebhooksServer(ctx, cfg.Webhooks.Port, mux, logger)
+		defer crash.recoverPanic("webhooks server")
+		return runHTTPServer(ctx, "webhooks", cfg.Webhooks.ListenAddr(), webhooksHandler, logger)
 	})
+	if cfg.API.Port != 0 {
+		g.Go(func() error {
+			defer crash.recoverPanic("api server")
+			return runHTTPServer(ctx, "api", cfg.API.ListenAddr(), apiHandler, logger)
+		})
+	}
+	if cfg.API.Socket != "" {
+		g.Go(func() error {
+			defer crash.recoverPanic("api socket server")
+			return runHTTPServerUnix(ctx, "api", cfg.API.Socket, cfg.API.SocketPerm(), apiHandler, logger)
+		})
+	}
 
 	readErr := make(chan error, len(openDevices))
 
-	// Start a reader goroutine for each input device and track them for shutdown.
-	// Input readers now emit events directly into the central `events` channel.
-	var inputWG sync.WaitGroup
-	inputWG.Add(len(openDevices))
+	// Build device list (hotplug watch + logging)
+	var devicePaths []string
+	for _, od := range openDevices {
+		devicePaths = append(devicePaths, od.path)
+	}
 
+	// Start input readers (epoll by default; goroutine-per-device fallback).
+	// Input readers emit events directly into the central `events` channel.
+	inputDevs := make([]inputDevice, 0, len(openDevices))
 	for _, od := range openDevices {
-		go func(file *os.File, name string, devType InputDeviceType) {
-			defer inputWG.Done()
-			logger.Debug("starting input reader", "device", name, "type", devType)
-			readInputEvents(file, events, readErr, logger)
-			logger.Warn("input reader stopped", "device", name)
-		}(od.file, od.path, od.typ)
+		inputDevs = append(inputDevs, inputDevice{
+			file: od.file,
+			path: od.path,
+			typ:  od.typ,
+			dec: newInputDecoder(events, inputDecoderConfig{
+				RotaryDebounce: time.Duration(cfg.Rotary.DebounceMS) * time.Millisecond,
+				KeyCombos:      cfg.KeyCombos,
+				LongPressKey:   calibrationKeyCodes[cfg.Calibration.LongPressKey],
+				LongPressHold:  time.Duration(cfg.Calibration.LongPressMS) * time.Millisecond,
+				Keymap:         od.keymap,
+
+				RepeatInterval:     od.repeatInterval,
+				AutoRepeatInterval: od.autoRepeat,
+
+				NoRelease:       od.noRelease,
+				NoReleaseStepDB: od.noReleaseStepDB,
+			}, metrics, logger.With("device", od.path)),
+		})
+	}
+	// Failed devices are reopened with backoff; hotplug (udev) events trigger a retry.
+	hotplug := newHotplugNotifier()
+	crash.Go("hotplug", func() { watchHotplug(ctx, devicePaths, hotplug, logger) })
+	reconn := &inputReconnector{
+		maxRetries:     cfg.InputReconnect.MaxRetries,
+		initialBackoff: time.Duration(cfg.InputReconnect.InitialBackoffMS) * time.Millisecond,
+		maxBackoff:     time.Duration(cfg.InputReconnect.MaxBackoffMS) * time.Millisecond,
+		hotplug:        hotplug,
+		logger:         logger,
 	}
+	waitInputs := startInputReaders(ctx, cfg.InputReader, inputDevs, reconn, readErr, logger)
 
-	logger.Debug("starting streamerbrainz", "version", version)
+	// Scripted control via named pipes / stdin.
+	waitFifos, err := startFifoInputs(ctx, fifoPaths, events, logger)
+	if err != nil {
+		logger.Error("failed to open fifo input", "error", err)
+		stop()
+	}
 
-	// Build device list for logging
-	var devicePaths []string
-	for _, od := range openDevices {
-		devicePaths = append(devicePaths, od.path)
+	// Media keys via global hotkeys (Windows).
+	waitHotkeys := func() {}
+	if hotkeys {
+		if waitHotkeys, err = startHotkeyInput(ctx, events, logger); err != nil {
+			logger.Error("failed to start hotkey input", "error", err)
+			stop()
+		}
 	}
 
+	vi := buildVersionInfo()
+	logger.Debug("starting streamerbrainz", "version", vi.Version, "commit", vi.Commit, "build_date", vi.BuildDate)
+
 	logger.Debug("configuration",
 		"config_path", *configPath,
 		"input_devices", devicePaths,
+		"inputs_optional", cfg.InputsOptional,
+		"input_reader", cfg.InputReader,
 		"camilladsp_ws_url", cfg.CamillaDSP.WsURL,
 		"camilladsp_ws_timeout_ms", cfg.CamillaDSP.TimeoutMS,
 		"ipc_socket", cfg.IPC.SocketPath,
 		"camilladsp_min_db", cfg.CamillaDSP.MinDB,
 		"camilladsp_max_db", cfg.CamillaDSP.MaxDB,
 		"camilladsp_update_hz", cfg.CamillaDSP.UpdateHz,
+		"camilladsp_idle_hz", cfg.CamillaDSP.IdleHz,
 		"vel_mode", cfg.Velocity.Mode,
 		"vel_max_db_per_sec", cfg.Velocity.MaxDBPerSec,
 		"rotary_db_per_step", cfg.Rotary.DbPerStep,
@@ -294,15 +841,25 @@ func main() {
 		"vel_danger_vel_max_db_per_sec", cfg.Velocity.DangerVelMaxDBPerSec,
 		"vel_danger_vel_min_near0_db_per_sec", cfg.Velocity.DangerVelMinNear0DBPerS,
 		"vel_hold_timeout_ms", cfg.Velocity.HoldTimeoutMS,
-		"webhooks_port", cfg.Webhooks.Port,
+		"webhooks_listen", cfg.Webhooks.ListenAddr(),
+		"api_listen", cfg.API.ListenAddr(),
+		"api_socket", cfg.API.Socket,
 		"plex_enabled", cfg.Plex.Enabled)
 
 	listenInfo := []any{
 		"input_devices", devicePaths,
 		"ipc", cfg.IPC.SocketPath,
 		"camilladsp_ws", cfg.CamillaDSP.WsURL,
+		"zones", len(zoneTargets),
+		"current_zone", cfg.InitialZone(),
 		"update_rate_hz", cfg.CamillaDSP.UpdateHz,
-		"webhooks_port", cfg.Webhooks.Port,
+		"webhooks_listen", cfg.Webhooks.ListenAddr(),
+	}
+	if cfg.API.Port != 0 {
+		listenInfo = append(listenInfo, "api_listen", cfg.API.ListenAddr())
+	}
+	if cfg.API.Socket != "" {
+		listenInfo = append(listenInfo, "api_socket", cfg.API.Socket)
 	}
 	logger.Info("daemon started", listenInfo...)
 	if cfg.Plex.Enabled {
@@ -314,7 +871,7 @@ func main() {
 	// Main loop - coordination only
 	// ============================================================================
 	// Main is responsible for:
-	// - shutdown coordination (closing input devices, then closing the events channel)
+	// - shutdown coordination (waiting for input readers, then closing the events channel)
 	// - surfacing input reader errors
 	//
 	// All state lives inside the daemon loop (runDaemon).
@@ -327,27 +884,42 @@ func main() {
 		// --------------------------------------------------------------------
 		case <-ctx.Done():
 			logger.Info("shutting down")
-
-			// Stop producers by closing input devices. This unblocks readInputEvents.
-			for _, od := range openDevices {
-				_ = od.file.Close()
-			}
+			// A second signal exits at once; either way, exit within shutdownGrace.
+			stop()
+			time.AfterFunc(shutdownGrace, func() {
+				logger.Error("shutdown took too long; exiting", "grace", shutdownGrace)
+				os.Exit(1)
+			})
 
 			// Ensure input reader goroutines have exited before we close the event bus.
+			// Readers close their devices on ctx cancellation, which unblocks pending reads.
 			// This reduces the risk of panics from sends to a closed channel during teardown.
-			inputWG.Wait()
+			waitInputs()
+			waitFifos()
+			waitHotkeys()
 
 			// Close the event bus to signal downstream consumers (daemon) to stop.
 			// Safe to close once here because main is the coordinator.
 			close(events)
 
-			// Close CamillaDSP client connection.
-			_ = client.Close()
+			// Leave CamillaDSP safe (shutdown.action), then close the client connections.
+			zoneIDs := make([]string, len(zoneTargets))
+			shutdownClients := make([]CamillaDSPClientInterface, len(clients))
+			for i, c := range clients {
+				zoneIDs[i], shutdownClients[i] = zoneTargets[i].ID, c
+			}
+			applyShutdownPolicy(cfg.Shutdown, zoneIDs, shutdownClients, logger)
+			for _, c := range clients {
+				_ = c.Close()
+			}
 
 			// Wait for background components (daemon, IPC, webhooks) to exit.
 			if err := g.Wait(); err != nil {
 				logger.Error("shutdown error", "error", err)
 			}
+			if crash.Crashed() {
+				os.Exit(1)
+			}
 			return
 
 		// --------------------------------------------------------------------
@@ -375,13 +947,15 @@ func printLibrespotHookUsage() {
 	fmt.Println()
 	fmt.Println("OPTIONS:")
 	fmt.Println("  -config string")
-	fmt.Printf("        Path to YAML config file (default %q)\n", defaultConfigPath)
+	fmt.Printf("        Path to YAML config file (default $STREAMERBRAINZ_CONFIG or %q)\n", defaultConfigPath)
 	fmt.Println()
 	fmt.Println("  -log-level string")
 	fmt.Println("        Override logging.level from config (error, warn, info, debug)")
 	fmt.Println()
 	fmt.Println("ENVIRONMENT VARIABLES:")
 	fmt.Println("  PLAYER_EVENT - Event type from librespot (start|stop|playing|paused|changed)")
+	fmt.Println("  STREAMERBRAINZ_CONFIG, STREAMERBRAINZ_SOCKET_PATH, STREAMERBRAINZ_LOG_LEVEL")
+	fmt.Println("               - Same overrides as the daemon (see streamerbrainz -help)")
 	fmt.Println()
 	fmt.Println("EXAMPLE:")
 	fmt.Println("  Add to librespot configuration:")
@@ -409,9 +983,7 @@ func runLibrespotSubcommand() {
 		return
 	}
 
-	if *configPath == "" {
-		*configPath = defaultConfigPath
-	}
+	*configPath = ResolveConfigPath(*configPath)
 
 	cfg, err := LoadConfigFile(*configPath)
 	if err != nil {
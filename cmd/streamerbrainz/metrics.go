@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// ============================================================================
+// Metrics
+// ============================================================================
+// Process-wide counters exposed in Prometheus text format at GET /metrics on
+// the control API listener. Counters and histograms are atomics so producers
+// (input readers, daemon loops) can update them without coordination.
+// ============================================================================
+
+// Metrics holds daemon counters.
+type Metrics struct {
+	// Rotary input
+	RotaryGlitchesRejected atomic.Uint64 // direction reversals dropped by debounce
+	RotaryBurstsCoalesced  atomic.Uint64 // input frames with >1 detent merged into one RotaryTurn
+
+	// Daemon loop timing (all zones)
+	ReduceLatency histogram // event ingress -> resulting commands handed to the effects worker
+	TickJitter    histogram // |actual - expected| tick interval
+}
+
+// latencyBuckets are the histogram upper bounds in seconds.
+var latencyBuckets = [...]float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+
+// histogram is a fixed-bucket duration histogram (Prometheus semantics).
+type histogram struct {
+	buckets [len(latencyBuckets) + 1]atomic.Uint64 // non-cumulative; last is +Inf
+	sumNS   atomic.Uint64
+	count   atomic.Uint64
+}
+
+// Observe records one duration.
+func (h *histogram) Observe(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	i := len(latencyBuckets)
+	for j, le := range latencyBuckets {
+		if d.Seconds() <= le {
+			i = j
+			break
+		}
+	}
+	h.buckets[i].Add(1)
+	h.sumNS.Add(uint64(d))
+	h.count.Add(1)
+}
+
+// write emits the histogram in Prometheus text format.
+func (h *histogram) write(w io.Writer, name, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	var cum uint64
+	for i := range h.buckets {
+		cum += h.buckets[i].Load()
+		le := "+Inf"
+		if i < len(latencyBuckets) {
+			le = strconv.FormatFloat(latencyBuckets[i], 'g', -1, 64)
+		}
+		fmt.Fprintf(w, "%s_bucket{le=%q} %d\n", name, le, cum)
+	}
+	fmt.Fprintf(w, "%s_sum %g\n%s_count %d\n", name, time.Duration(h.sumNS.Load()).Seconds(), name, h.count.Load())
+}
+
+// metricDesc describes one exported counter.
+type metricDesc struct {
+	name  string
+	help  string
+	value func(m *Metrics) uint64
+}
+
+var metricDescs = []metricDesc{
+	{
+		name:  "streamerbrainz_rotary_glitches_rejected_total",
+		help:  "Rotary direction reversals rejected by debounce.",
+		value: func(m *Metrics) uint64 { return m.RotaryGlitchesRejected.Load() },
+	},
+	{
+		name:  "streamerbrainz_rotary_bursts_coalesced_total",
+		help:  "Rotary input frames with multiple detents coalesced into one turn.",
+		value: func(m *Metrics) uint64 { return m.RotaryBurstsCoalesced.Load() },
+	},
+}
+
+// histogramDesc describes one exported histogram.
+type histogramDesc struct {
+	name string
+	help string
+	hist func(m *Metrics) *histogram
+}
+
+var histogramDescs = []histogramDesc{
+	{
+		name: "streamerbrainz_reduce_latency_seconds",
+		help: "Time from an event entering a daemon loop to its commands being dispatched to the effects worker.",
+		hist: func(m *Metrics) *histogram { return &m.ReduceLatency },
+	},
+	{
+		name: "streamerbrainz_tick_jitter_seconds",
+		help: "Deviation of the daemon loop tick interval from its nominal period.",
+		hist: func(m *Metrics) *histogram { return &m.TickJitter },
+	},
+}
+
+// ServeHTTP writes all counters and histograms in Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	for _, d := range metricDescs {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", d.name, d.help, d.name, d.name, d.value(m))
+	}
+	for _, d := range histogramDescs {
+		d.hist(m).write(w, d.name, d.help)
+	}
+}
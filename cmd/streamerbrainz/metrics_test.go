@@ -0,0 +1,60 @@
+package main
+
+import (
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics_HistogramExposition(t *testing.T) {
+	m := &Metrics{}
+	m.ReduceLatency.Observe(300 * time.Microsecond)
+	m.ReduceLatency.Observe(3 * time.Millisecond)
+	m.ReduceLatency.Observe(2 * time.Second)
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		"# TYPE streamerbrainz_reduce_latency_seconds histogram",
+		`streamerbrainz_reduce_latency_seconds_bucket{le="0.0005"} 1`,
+		`streamerbrainz_reduce_latency_seconds_bucket{le="0.005"} 2`,
+		`streamerbrainz_reduce_latency_seconds_bucket{le="1"} 2`,
+		`streamerbrainz_reduce_latency_seconds_bucket{le="+Inf"} 3`,
+		"streamerbrainz_reduce_latency_seconds_sum 2.0033",
+		"streamerbrainz_reduce_latency_seconds_count 3",
+		"streamerbrainz_tick_jitter_seconds_count 0",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %q in:\n%s", want, body)
+		}
+	}
+}
+
+func TestLoopDiagnostics_LatencyMeasuredUntilQueueDrains(t *testing.T) {
+	m := &Metrics{}
+	d := newLoopDiagnostics(m, DiagnosticsConfig{}, slog.Default())
+	t0 := time.Unix(1000, 0)
+
+	d.ingress(t0)
+	d.ingress(t0.Add(time.Millisecond)) // later events don't reset the oldest pending ingress
+	d.dispatched(t0.Add(2*time.Millisecond), false)
+	if m.ReduceLatency.count.Load() != 0 {
+		t.Fatalf("expected no observation while commands are still queued")
+	}
+	d.dispatched(t0.Add(20*time.Millisecond), true)
+	if got := m.ReduceLatency.count.Load(); got != 1 {
+		t.Fatalf("expected 1 observation, got %d", got)
+	}
+	if got := time.Duration(m.ReduceLatency.sumNS.Load()); got != 20*time.Millisecond {
+		t.Fatalf("expected 20ms latency, got %v", got)
+	}
+
+	d.tick(t0, 45*time.Millisecond, 33*time.Millisecond)
+	if got := time.Duration(m.TickJitter.sumNS.Load()); got != 12*time.Millisecond {
+		t.Fatalf("expected 12ms jitter, got %v", got)
+	}
+}
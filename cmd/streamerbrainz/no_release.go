@@ -0,0 +1,98 @@
+package main
+
+import "time"
+
+// ============================================================================
+// Remotes that never send a release
+// ============================================================================
+// Some remotes report presses (and repeats) of a volume key but no release, so
+// a hold only ends when velocity.hold_timeout_ms runs out, overshooting the
+// volume. inputs[].no_release picks how such a device's volume keys are read:
+//
+//	no_release: step     # each press/repeat is one VolumeStep of step_db
+//	no_release: release  # holds as usual, released once the repeats stop
+//
+// In release mode the release is synthesized when no press or repeat arrived
+// for noReleaseTimeout, or for the repeat grace if the input has a longer one
+// (see repeat_interval.go). A release the device does send ends the hold too.
+// ============================================================================
+
+// Values of inputs[].no_release.
+const (
+	noReleaseStep    = "step"
+	noReleaseRelease = "release"
+)
+
+// noReleaseTimeout ends a hold in release mode when the repeats stop: longer
+// than a typical remote's repeat period (~110ms), shorter than a noticeable
+// overshoot.
+const noReleaseTimeout = 300 * time.Millisecond
+
+// noReleaseState converts one device's volume keys for its no_release mode.
+type noReleaseState struct {
+	mode string // "" (off), noReleaseStep or noReleaseRelease
+
+	stepUp, stepDown Event // pre-boxed VolumeSteps (step mode)
+
+	held    bool        // a hold is active (release mode)
+	timer   *time.Timer // synthesizes the release
+	timerID int         // invalidates callbacks of stopped timers
+}
+
+// newNoReleaseState configures the mode; stepDB is the step size (0: the default).
+func newNoReleaseState(mode string, stepDB float64) noReleaseState {
+	return noReleaseState{
+		mode:     mode,
+		stepUp:   VolumeStep{Steps: 1, DbPerStep: stepDB, Origin: "ir"},
+		stepDown: VolumeStep{Steps: -1, DbPerStep: stepDB, Origin: "ir"},
+	}
+}
+
+// emitNoRelease emits the action of a volume-key event in the device's
+// no_release mode. It reports false if the mode is off.
+func (d *inputDecoder) emitNoRelease(ev inputEvent) bool {
+	n := &d.noRelease
+	switch n.mode {
+	case noReleaseStep:
+		switch {
+		case ev.Value == evValueRelease:
+		case ev.Code == KEY_VOLUMEUP:
+			d.events <- n.stepUp
+		default:
+			d.events <- n.stepDown
+		}
+		return true
+
+	case noReleaseRelease:
+		if ev.Value == evValueRelease {
+			if n.held {
+				n.stop()
+				d.events <- VolumeRelease{}
+			}
+			return true
+		}
+		d.events <- d.volumeHeldEvent(ev.Code)
+		n.stop()
+		n.held = true
+		id := n.timerID
+		n.timer = time.AfterFunc(max(noReleaseTimeout, d.repeat.grace()), func() {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			if d.noRelease.timerID == id && d.noRelease.held {
+				d.noRelease.stop()
+				d.events <- VolumeRelease{}
+			}
+		})
+		return true
+	}
+	return false
+}
+
+// stop ends the hold without releasing it and disarms the release timer.
+func (n *noReleaseState) stop() {
+	if n.timer != nil {
+		n.timer.Stop()
+	}
+	n.held, n.timer = false, nil
+	n.timerID++
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInputDecoder_NoReleaseStep(t *testing.T) {
+	events := make(chan Event, 8)
+	d := newInputDecoder(events, inputDecoderConfig{NoRelease: noReleaseStep, NoReleaseStepDB: 2}, &Metrics{}, slog.New(slog.DiscardHandler))
+	key := func(code uint16, value int32) { d.handle(inputEvent{Type: EV_KEY, Code: code, Value: value}) }
+
+	key(KEY_VOLUMEUP, evValuePress)
+	key(KEY_VOLUMEUP, evValueRepeat)
+	key(KEY_VOLUMEUP, evValueRelease)
+	key(KEY_VOLUMEDOWN, evValuePress)
+	key(KEY_MUTE, evValuePress)
+	up := VolumeStep{Steps: 1, DbPerStep: 2, Origin: "ir"}
+	for i, want := range []Event{up, up, VolumeStep{Steps: -1, DbPerStep: 2, Origin: "ir"}, ToggleMute{}} {
+		if ev := <-events; ev != want {
+			t.Fatalf("event %d: got %#v, want %#v", i, ev, want)
+		}
+	}
+	if len(events) != 0 {
+		t.Fatalf("unexpected event %#v", <-events)
+	}
+}
+
+func TestInputDecoder_NoReleaseRelease(t *testing.T) {
+	events := make(chan Event, 8)
+	d := newInputDecoder(events, inputDecoderConfig{NoRelease: noReleaseRelease}, &Metrics{}, slog.New(slog.DiscardHandler))
+	key := func(code uint16, value int32) { d.handle(inputEvent{Type: EV_KEY, Code: code, Value: value}) }
+	next := func() Event {
+		t.Helper()
+		select {
+		case ev := <-events:
+			return ev
+		case <-time.After(2 * time.Second):
+			t.Fatal("no event")
+			return nil
+		}
+	}
+
+	// The release is synthesized once the repeats stop.
+	key(KEY_VOLUMEUP, evValuePress)
+	key(KEY_VOLUMEUP, evValueRepeat)
+	for i, want := range []Event{volumeHeldUpEvent, volumeHeldUpEvent, VolumeRelease{}} {
+		if ev := next(); ev != want {
+			t.Fatalf("event %d: got %#v, want %#v", i, ev, want)
+		}
+	}
+
+	// A real release ends the hold once; nothing is synthesized after it.
+	key(KEY_VOLUMEDOWN, evValuePress)
+	key(KEY_VOLUMEDOWN, evValueRelease)
+	key(KEY_VOLUMEDOWN, evValueRelease)
+	for i, want := range []Event{volumeHeldDownEvent, VolumeRelease{}} {
+		if ev := next(); ev != want {
+			t.Fatalf("event %d: got %#v, want %#v", i, ev, want)
+		}
+	}
+	time.Sleep(noReleaseTimeout + 50*time.Millisecond)
+	if len(events) != 0 {
+		t.Fatalf("unexpected event %#v", <-events)
+	}
+}
+
+func TestValidate_NoRelease(t *testing.T) {
+	for _, tc := range []struct {
+		dev     InputDevice
+		wantErr string
+	}{
+		{InputDevice{Path: "/dev/input/event0", Type: InputDeviceTypeKey, NoRelease: noReleaseStep, StepDB: 1}, ""},
+		{InputDevice{Path: "/dev/input/event0", Type: InputDeviceTypeKey, NoRelease: noReleaseRelease}, ""},
+		{InputDevice{Path: "/dev/input/event0", Type: InputDeviceTypeKey, NoRelease: "never"}, "no_release must be"},
+		{InputDevice{Path: "/dev/input/event0", Type: InputDeviceTypeKey, NoRelease: noReleaseRelease, StepDB: 1}, "step_db is only valid"},
+		{InputDevice{Path: "/run/sb", Type: InputDeviceTypeFifo, NoRelease: noReleaseStep}, "only valid for types"},
+	} {
+		cfg := DefaultConfig()
+		cfg.Inputs = []InputDevice{tc.dev}
+		err := cfg.Validate()
+		if tc.wantErr == "" && err != nil {
+			t.Errorf("%+v: unexpected error %v", tc.dev, err)
+		}
+		if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+			t.Errorf("%+v: got %v, want error containing %q", tc.dev, err, tc.wantErr)
+		}
+	}
+}
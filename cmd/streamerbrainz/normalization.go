@@ -0,0 +1,60 @@
+package main
+
+import "math"
+
+// ============================================================================
+// Per-track loudness normalization
+// ============================================================================
+// When the player reports loudness data for the track (Plex loudness analysis,
+// or ReplayGain tags posted as a track_loudness event, e.g. by an MPD client
+// script), its gain is applied as a temporary offset on a CamillaDSP aux fader
+// (normalization.fader, with a Volume filter on that fader in the pipeline), so
+// quiet and loud albums play at a consistent level without touching the main
+// volume:
+//
+//	offset = gain (track or album, per normalization.mode) + preamp_db
+//
+// bounded to [-max_cut_db, +max_boost_db] and, with prevent_clipping, so that
+// the tagged peak stays at or below full scale. Tracks without data, a change
+// of player source and calibration mode all reset the offset to 0 dB.
+// ============================================================================
+
+// Normalization modes.
+const (
+	NormalizationModeTrack = "track"
+	NormalizationModeAlbum = "album"
+)
+
+// normalizationOffset is the gain offset for l under cfg (0 without data).
+func normalizationOffset(l *TrackLoudness, cfg NormalizationConfig) float64 {
+	if l == nil {
+		return 0
+	}
+	gain, peak := l.TrackGainDB, l.TrackPeak
+	if cfg.Mode == NormalizationModeAlbum && l.AlbumGainDB != nil {
+		gain, peak = l.AlbumGainDB, l.AlbumPeak
+	}
+	if gain == nil {
+		return 0
+	}
+
+	offset := *gain + cfg.PreampDB
+	if cfg.PreventClipping && peak > 0 {
+		offset = min(offset, -20*math.Log10(peak))
+	}
+	offset = max(-cfg.MaxCutDB, min(cfg.MaxBoostDB, offset))
+	return math.Round(offset*10) / 10
+}
+
+// updateNormalization recomputes the offset for the current track, scheduling
+// a fader update when it changes.
+func updateNormalization(s *DaemonState, cfg NormalizationConfig) {
+	var offset float64
+	if !s.Calibration.Active {
+		offset = normalizationOffset(s.Player.Loudness, cfg)
+	}
+	if offset != s.NormalizationDB {
+		s.NormalizationDB = offset
+		s.Intent.NormalizationPending = true
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+)
+
+func dbPtr(v float64) *float64 { return &v }
+
+func TestNormalizationOffset(t *testing.T) {
+	cfg := NormalizationConfig{Mode: NormalizationModeTrack, MaxBoostDB: 6, MaxCutDB: 15, PreventClipping: true}
+	cases := []struct {
+		name string
+		l    *TrackLoudness
+		cfg  func(NormalizationConfig) NormalizationConfig
+		want float64
+	}{
+		{name: "no data", want: 0},
+		{name: "untagged", l: &TrackLoudness{}, want: 0},
+		{name: "cut", l: &TrackLoudness{TrackGainDB: dbPtr(-8.13)}, want: -8.1},
+		{name: "cut bounded", l: &TrackLoudness{TrackGainDB: dbPtr(-20)}, want: -15},
+		{name: "boost bounded", l: &TrackLoudness{TrackGainDB: dbPtr(9)}, want: 6},
+		// Peak at -3 dBFS leaves ~3 dB of headroom.
+		{name: "boost limited by peak", l: &TrackLoudness{TrackGainDB: dbPtr(5), TrackPeak: 0.708}, want: 3},
+		{name: "clipping allowed", l: &TrackLoudness{TrackGainDB: dbPtr(5), TrackPeak: 0.708}, want: 5,
+			cfg: func(c NormalizationConfig) NormalizationConfig { c.PreventClipping = false; return c }},
+		{name: "album", l: &TrackLoudness{TrackGainDB: dbPtr(-8), AlbumGainDB: dbPtr(-6)}, want: -6,
+			cfg: func(c NormalizationConfig) NormalizationConfig { c.Mode = NormalizationModeAlbum; return c }},
+		{name: "album falls back to track", l: &TrackLoudness{TrackGainDB: dbPtr(-8)}, want: -8,
+			cfg: func(c NormalizationConfig) NormalizationConfig { c.Mode = NormalizationModeAlbum; return c }},
+		{name: "preamp", l: &TrackLoudness{TrackGainDB: dbPtr(-8)}, want: -5,
+			cfg: func(c NormalizationConfig) NormalizationConfig { c.PreampDB = 3; return c }},
+	}
+	for _, c := range cases {
+		cc := cfg
+		if c.cfg != nil {
+			cc = c.cfg(cfg)
+		}
+		if got := normalizationOffset(c.l, cc); got != c.want {
+			t.Errorf("%s: offset = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestReduce_NormalizationFollowsTrackAndSource(t *testing.T) {
+	cfg := VelocityConfig{
+		MinDB: -80,
+		MaxDB: 0,
+	}
+	policy := ReducerPolicy{
+		Normalization: NormalizationConfig{
+			Enabled: true, Fader: 4, Mode: NormalizationModeTrack, MaxBoostDB: 6, MaxCutDB: 15,
+		},
+	}
+	t0 := time.Unix(1000, 0).UTC()
+	tick := func(s *DaemonState, d time.Duration) []Command {
+		return Reduce(s, Tick{Now: t0.Add(d), Dt: 0.01}, cfg, RotaryConfig{}, policy).Commands
+	}
+	faderCmd := func(cmds []Command) (CmdSetFaderVolume, bool) {
+		for _, c := range cmds {
+			if f, ok := c.(CmdSetFaderVolume); ok {
+				return f, true
+			}
+		}
+		return CmdSetFaderVolume{}, false
+	}
+
+	s := &DaemonState{}
+	rr := Reduce(s, TimedEvent{Event: PlexStateChanged{State: "playing", Title: "Loud", Artist: "A",
+		Loudness: &TrackLoudness{TrackGainDB: dbPtr(-9)}}, At: t0}, cfg, RotaryConfig{}, policy)
+	if rr.State.NormalizationDB != -9 {
+		t.Fatalf("offset = %v", rr.State.NormalizationDB)
+	}
+	if c, ok := faderCmd(tick(rr.State, 10*time.Millisecond)); !ok || c.Fader != 4 || c.TargetDB != -9 {
+		t.Fatalf("expected fader 4 at -9 dB, got %v (%v)", c, ok)
+	}
+	if _, ok := faderCmd(tick(rr.State, 20*time.Millisecond)); ok {
+		t.Fatal("fader set again without a change")
+	}
+
+	// Pausing keeps the offset; another source resets it.
+	rr = Reduce(rr.State, TimedEvent{Event: PlexStateChanged{State: "paused", Title: "Loud", Artist: "A",
+		Loudness: &TrackLoudness{TrackGainDB: dbPtr(-9)}}, At: t0.Add(time.Second)}, cfg, RotaryConfig{}, policy)
+	if rr.State.Intent.NormalizationPending {
+		t.Fatal("pause changed the offset")
+	}
+	rr = Reduce(rr.State, TimedEvent{Event: TidalStateChanged{State: "playing", Title: "Other", Artist: "B"}, At: t0.Add(2 * time.Second)}, cfg, RotaryConfig{}, policy)
+	if c, ok := faderCmd(tick(rr.State, 2*time.Second+10*time.Millisecond)); !ok || c.TargetDB != 0 {
+		t.Fatalf("expected reset to 0 dB, got %v (%v)", c, ok)
+	}
+
+	// A track_loudness event (e.g. from an MPD script) applies to what is playing.
+	rr = Reduce(rr.State, TrackLoudness{TrackGainDB: dbPtr(-4.5)}, cfg, RotaryConfig{}, policy)
+	if rr.State.NormalizationDB != -4.5 {
+		t.Fatalf("offset after track_loudness = %v", rr.State.NormalizationDB)
+	}
+}
+
+func TestReduce_NormalizationResetsOnStartup(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0}
+	policy := ReducerPolicy{Normalization: NormalizationConfig{Enabled: true, Fader: 4}}
+	rr := Reduce(&DaemonState{}, DaemonStarted{}, cfg, RotaryConfig{}, policy)
+	rr = Reduce(rr.State, Tick{Now: time.Unix(1000, 0), Dt: 0.01}, cfg, RotaryConfig{}, policy)
+	found := false
+	for _, c := range rr.Commands {
+		if f, ok := c.(CmdSetFaderVolume); ok && f.Fader == 4 && f.TargetDB == 0 {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected the fader to be reset on startup, got %v", rr.Commands)
+	}
+}
+
+func TestPlexTrackLoudness(t *testing.T) {
+	const sessions = `<MediaContainer size="1">
+  <Track title="Sinnerman" grandparentTitle="Nina Simone">
+    <Media><Part><Stream streamType="2" gain="-8.13" albumGain="-7.82" peak="0.988" albumPeak="1.000000" loudness="-9.87"/></Part></Media>
+  </Track>
+</MediaContainer>`
+	var c PlexMediaContainer
+	if err := xml.Unmarshal([]byte(sessions), &c); err != nil {
+		t.Fatal(err)
+	}
+	l := c.Tracks[0].loudness()
+	if l == nil || *l.TrackGainDB != -8.13 || *l.AlbumGainDB != -7.82 || l.TrackPeak != 0.988 || l.AlbumPeak != 1 {
+		t.Fatalf("loudness = %+v", l)
+	}
+	if l := (PlexTrack{}).loudness(); l != nil {
+		t.Fatalf("expected nil for an unanalyzed track, got %+v", l)
+	}
+}
+
+func TestConfigValidate_Normalization(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Normalization.Enabled = true
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected error without a fader")
+	}
+	cfg.Normalization.Fader = 4
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg.Rotary.SubFader = 4
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected error for a fader shared with rotary.sub_fader")
+	}
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// OpenAPI document
+// ============================================================================
+// GET /api/openapi.json serves an OpenAPI 3.1 description of the HTTP endpoints
+// and of every event envelope accepted by POST /webhooks/event (and the IPC
+// socket), for generating typed clients. Schemas are derived from the Go types'
+// JSON tags, so they follow the code; the event list below must be kept in step
+// with unmarshalEnvelope (checked by TestOpenAPIEventsMatchUnmarshal).
+// ============================================================================
+
+// openAPIEvents lists the inbound event types with their payload type (nil for
+// events without data).
+var openAPIEvents = []struct {
+	Type    string
+	Payload any
+}{
+	{"volume_held", VolumeHeld{}},
+	{"volume_release", nil},
+	{"volume_step", VolumeStep{}},
+	{"rotary_turn", RotaryTurn{}},
+	{"rotary_turn_hi_res", RotaryTurnHiRes{}},
+	{"rotary_press", nil},
+	{"toggle_mute", nil},
+	{"resync_state", nil},
+	{"set_volume_absolute", SetVolumeAbsolute{}},
+	{"set_volume_percent", SetVolumePercent{}},
+	{"set_mute", SetMute{}},
+	{"output_select", OutputSelect{}},
+	{"select_zone", SelectZone{}},
+	{"link_zone", LinkZone{}},
+	{"unlink_zone", UnlinkZone{}},
+	{"ir_send", IRSend{}},
+	{"test_signal", TestSignal{}},
+	{"calibration_mode", CalibrationMode{}},
+	{"limit_override", LimitOverride{}},
+	{"media_play_pause", nil},
+	{"media_next", nil},
+	{"media_previous", nil},
+	{"media_play", nil},
+	{"media_pause", nil},
+	{"media_stop", nil},
+	{"librespot_session_connected", LibrespotSessionConnected{}},
+	{"librespot_session_disconnected", LibrespotSessionDisconnected{}},
+	{"librespot_volume_changed", LibrespotVolumeChanged{}},
+	{"librespot_track_changed", LibrespotTrackChanged{}},
+	{"librespot_playback_state", LibrespotPlaybackState{}},
+	{"plex_state_changed", PlexStateChanged{}},
+	{"tidal_state_changed", TidalStateChanged{}},
+	{"track_loudness", TrackLoudness{}},
+}
+
+// openAPIHandler serves the pre-rendered document.
+type openAPIHandler struct {
+	doc []byte
+}
+
+func newOpenAPIHandler(basePath string) (*openAPIHandler, error) {
+	doc, err := json.MarshalIndent(buildOpenAPI(basePath), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return &openAPIHandler{doc: doc}, nil
+}
+
+func (h *openAPIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeEventWebhookResponse(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(h.doc)
+}
+
+// openAPISchemas collects named component schemas while building the document.
+type openAPISchemas map[string]any
+
+// ref returns a $ref to the schema of v's type, adding it (and the structs it
+// uses) to the components.
+func (c openAPISchemas) ref(v any) map[string]any {
+	return c.schema(reflect.TypeOf(v))
+}
+
+func (c openAPISchemas) schema(t reflect.Type) map[string]any {
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == reflect.TypeOf(json.RawMessage{}):
+		return map[string]any{}
+	case t.Kind() == reflect.Interface:
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return map[string]any{"oneOf": []any{c.schema(t.Elem()), map[string]any{"type": "null"}}}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": c.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": c.schema(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Struct:
+		name := t.Name()
+		if _, ok := c[name]; !ok {
+			c[name] = nil // placeholder for recursive types
+			c[name] = c.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]any{}
+}
+
+func (c openAPISchemas) structSchema(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	var required []string
+	var addFields func(t reflect.Type)
+	addFields = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" || (!f.IsExported() && !f.Anonymous) {
+				continue
+			}
+			// Embedded structs without a name are inlined, like encoding/json does.
+			if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+				addFields(f.Type)
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			props[name] = c.schema(f.Type)
+			if !strings.Contains(opts, "omitempty") && !strings.Contains(opts, "omitzero") && f.Type.Kind() != reflect.Pointer {
+				required = append(required, name)
+			}
+		}
+	}
+	addFields(t)
+	s := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// buildOpenAPI builds the document; basePath is api.base_path.
+func buildOpenAPI(basePath string) map[string]any {
+	schemas := openAPISchemas{}
+
+	// Event envelopes: one variant per event type, discriminated by "type".
+	var variants []any
+	mapping := map[string]any{}
+	for _, ev := range openAPIEvents {
+		name := "Event_" + ev.Type
+		props := map[string]any{
+			"type": map[string]any{"const": ev.Type},
+			"zone": map[string]any{"type": "string", "description": "Target zone; empty targets the current zone."},
+		}
+		if ev.Payload != nil {
+			props["data"] = schemas.ref(ev.Payload)
+		}
+		schemas[name] = map[string]any{"type": "object", "properties": props, "required": []string{"type"}}
+		ref := "#/components/schemas/" + name
+		variants = append(variants, map[string]any{"$ref": ref})
+		mapping[ev.Type] = ref
+	}
+	schemas["EventEnvelope"] = map[string]any{
+		"oneOf":         variants,
+		"discriminator": map[string]any{"propertyName": "type", "mapping": mapping},
+	}
+	schemas["StateFrame"] = map[string]any{
+		"type":        "object",
+		"description": `WebSocket / SSE frame: "state_init" on connect (with protocol_version and capabilities), "hello" in answer to a client hello, then one frame per state change (volume_changed, mute_changed, player_changed, ...).`,
+		"properties": map[string]any{
+			"type": map[string]any{"type": "string"},
+			"zone": map[string]any{"type": "string"},
+			"ts":   map[string]any{"type": "string", "format": "date-time"},
+			"data": map[string]any{},
+		},
+		"required": []string{"type"},
+	}
+
+	jsonBody := func(schema any) map[string]any {
+		return map[string]any{"content": map[string]any{"application/json": map[string]any{"schema": schema}}}
+	}
+	response := func(desc string, schema any) map[string]any {
+		r := jsonBody(schema)
+		r["description"] = desc
+		return r
+	}
+	status := schemas.ref(IPCResponse{})
+	errorResponses := func(codes ...string) map[string]any {
+		out := map[string]any{}
+		for _, code := range codes {
+			out[code] = response("Error", status)
+		}
+		return out
+	}
+	with := func(m map[string]any, key string, v any) map[string]any {
+		m[key] = v
+		return m
+	}
+
+	// Control endpoints that change state take the API token (see api_auth.go).
+	tokenSecurity := []any{map[string]any{"bearerToken": []string{}}, map[string]any{"tokenHeader": []string{}}}
+
+	paths := map[string]any{
+		"/webhooks/event": map[string]any{
+			"post": map[string]any{
+				"summary":     "Inject an event",
+				"description": "Requires webhooks.event.enabled. Same envelope as the IPC socket.",
+				"security":    tokenSecurity,
+				"requestBody": with(jsonBody(map[string]any{"$ref": "#/components/schemas/EventEnvelope"}), "required", true),
+				"responses":   with(errorResponses("400", "401", "409", "413", "502", "503"), "200", response("Applied", status)),
+			},
+		},
+		"/jsonrpc": map[string]any{
+			"post": map[string]any{
+				"summary":     "JSON-RPC 2.0 (volume.get, volume.set, mute.toggle, player.status)",
+				"security":    tokenSecurity,
+				"requestBody": with(jsonBody(schemas.ref(jsonRPCRequest{})), "required", true),
+				"responses":   with(errorResponses("401", "403"), "200", response("JSON-RPC response", schemas.ref(jsonRPCResponse{}))),
+			},
+		},
+		"/api/v1/tuning": map[string]any{
+			"get": map[string]any{
+				"summary":   "Active velocity and rotary tuning",
+				"security":  tokenSecurity,
+				"responses": with(errorResponses("401", "403"), "200", response("Tuning", schemas.ref(ConfigUpdated{}))),
+			},
+			"put": map[string]any{
+				"summary":     "Merge a partial tuning document and apply it live",
+				"description": "Only velocity max_db_per_sec, accel_time_sec, decay_tau_sec, turbo_mult, turbo_delay_sec and rotary db_per_step, velocity_window_ms, velocity_multiplier, velocity_threshold, debounce_ms, curve may be set.",
+				"security":    tokenSecurity,
+				"requestBody": with(jsonBody(schemas.ref(ConfigUpdated{})), "required", true),
+				"responses":   with(errorResponses("400", "401", "403", "413", "503"), "200", response("Updated tuning", schemas.ref(ConfigUpdated{}))),
+			},
+		},
+		"/api/v1/resync": map[string]any{
+			"post": map[string]any{
+				"summary":    "Re-read state from CamillaDSP and re-broadcast volume and mute",
+				"security":   tokenSecurity,
+				"parameters": []any{map[string]any{"name": "zone", "in": "query", "description": "Only this zone (default: every zone)", "schema": map[string]any{"type": "string"}}},
+				"responses":  with(errorResponses("401", "403", "503"), "200", response("Queued", status)),
+			},
+		},
+		"/api/v1/version": map[string]any{
+			"get": map[string]any{
+				"summary":   "Build metadata and release check status",
+				"responses": map[string]any{"200": response("Version", schemas.ref(versionResponse{}))},
+			},
+		},
+		"/api/v1/stats": map[string]any{
+			"get": map[string]any{
+				"summary":    "Daily listening statistics (time, volume, sources, tracks)",
+				"parameters": []any{map[string]any{"name": "days", "in": "query", "description": "Number of days up to today (default 7, max 366)", "schema": map[string]any{"type": "integer"}}},
+				"responses":  with(errorResponses("400", "404"), "200", response("Statistics", schemas.ref(statsReport{}))),
+			},
+		},
+		"/api/v1/debug/state": map[string]any{
+			"get": map[string]any{
+				"summary":   "Internal state, recent events and errors for bug reports (secrets redacted)",
+				"security":  tokenSecurity,
+				"responses": with(errorResponses("401", "403"), "200", response("State dump", schemas.ref(debugState{}))),
+			},
+		},
+		"/api/v1/debug/tap": map[string]any{
+			"get": map[string]any{
+				"summary":   "CamillaDSP protocol tap status",
+				"security":  tokenSecurity,
+				"responses": with(errorResponses("401", "403"), "200", response("Tap status", schemas.ref(tapStatus{}))),
+			},
+			"put": map[string]any{
+				"summary":     "Turn the CamillaDSP protocol tap on (for duration_sec, default 600) or off",
+				"security":    tokenSecurity,
+				"requestBody": with(jsonBody(schemas.ref(tapRequest{})), "required", true),
+				"responses":   with(errorResponses("400", "401", "403", "500"), "200", response("Tap status", schemas.ref(tapStatus{}))),
+			},
+		},
+		"/api/openapi.json": map[string]any{
+			"get": map[string]any{
+				"summary":   "This document",
+				"responses": map[string]any{"200": response("OpenAPI document", map[string]any{"type": "object"})},
+			},
+		},
+		"/healthz": map[string]any{
+			"get": map[string]any{
+				"summary": "Liveness and input device status",
+				"responses": map[string]any{
+					"200": response(`"ok" or "degraded"`, schemas.ref(healthResponse{})),
+					"503": response("Daemon not responding", schemas.ref(healthResponse{})),
+				},
+			},
+		},
+		"/readyz": map[string]any{
+			"get": map[string]any{
+				"summary": "Readiness: every zone's CamillaDSP answers",
+				"responses": map[string]any{
+					"200": response(`"ready"`, schemas.ref(readyResponse{})),
+					"503": response(`"not_ready" (naming the zones) or daemon not responding`, schemas.ref(readyResponse{})),
+				},
+			},
+		},
+		"/metrics": map[string]any{
+			"get": map[string]any{
+				"summary": "Prometheus metrics",
+				"responses": map[string]any{"200": map[string]any{
+					"description": "Prometheus text exposition format",
+					"content":     map[string]any{"text/plain": map[string]any{"schema": map[string]any{"type": "string"}}},
+				}},
+			},
+		},
+		"/events": map[string]any{
+			"get": map[string]any{
+				"summary": "State updates as Server-Sent Events",
+				"responses": map[string]any{"200": map[string]any{
+					"description": "One StateFrame (JSON) per SSE message",
+					"content":     map[string]any{"text/event-stream": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/StateFrame"}}},
+				}},
+			},
+		},
+		"/ws/state": map[string]any{
+			"get": map[string]any{
+				"summary":   "State updates over WebSocket (one StateFrame per text message)",
+				"responses": map[string]any{"101": map[string]any{"description": "Switching to the WebSocket protocol"}},
+			},
+		},
+		"/webhooks/plex": map[string]any{
+			"post": map[string]any{
+				"summary":     "Plex webhook receiver (requires plex.enabled)",
+				"requestBody": map[string]any{"content": map[string]any{"multipart/form-data": map[string]any{"schema": map[string]any{"type": "object", "properties": map[string]any{"payload": map[string]any{"type": "string"}}}}}},
+				"responses":   map[string]any{"200": map[string]any{"description": "Accepted"}},
+			},
+		},
+	}
+
+	server := basePath
+	if server == "" {
+		server = "/"
+	}
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":   "StreamerBrainz API",
+			"version": version,
+		},
+		"servers": []any{map[string]any{"url": server}},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": map[string]any(schemas),
+			"securitySchemes": map[string]any{
+				"bearerToken": map[string]any{"type": "http", "scheme": "bearer"},
+				"tokenHeader": map[string]any{"type": "apiKey", "in": "header", "name": "X-StreamerBrainz-Token"},
+			},
+		},
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPIEventsMatchUnmarshal(t *testing.T) {
+	seen := map[string]bool{}
+	for _, ev := range openAPIEvents {
+		if seen[ev.Type] {
+			t.Errorf("duplicate event %q", ev.Type)
+		}
+		seen[ev.Type] = true
+		data := "{}"
+		if ev.Payload != nil {
+			b, err := json.Marshal(ev.Payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data = string(b)
+		}
+		if _, err := UnmarshalEvent([]byte(`{"type":"` + ev.Type + `","data":` + data + `}`)); err != nil {
+			t.Errorf("event %q: %v", ev.Type, err)
+		}
+	}
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	h, err := newOpenAPIHandler("/streamer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	var doc struct {
+		OpenAPI string                    `json:"openapi"`
+		Servers []struct{ URL string }    `json:"servers"`
+		Paths   map[string]map[string]any `json:"paths"`
+		Comps   struct {
+			Schemas map[string]map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if doc.OpenAPI != "3.1.0" || len(doc.Servers) != 1 || doc.Servers[0].URL != "/streamer" {
+		t.Fatalf("unexpected header: %s %+v", doc.OpenAPI, doc.Servers)
+	}
+	for _, p := range []string{"/webhooks/event", "/jsonrpc", "/api/v1/tuning", "/api/v1/version", "/healthz", "/events"} {
+		if doc.Paths[p] == nil {
+			t.Errorf("missing path %s", p)
+		}
+	}
+
+	// Every $ref resolves to a component schema.
+	for _, ref := range strings.Split(rec.Body.String(), `"$ref": "#/components/schemas/`)[1:] {
+		name, _, _ := strings.Cut(ref, `"`)
+		if doc.Comps.Schemas[name] == nil {
+			t.Errorf("dangling $ref %q", name)
+		}
+	}
+	sv := doc.Comps.Schemas["SetVolumeAbsolute"]
+	if props, _ := sv["properties"].(map[string]any); props["db"] == nil {
+		t.Errorf("SetVolumeAbsolute schema lacks db: %v", sv)
+	}
+	// Embedded structs are inlined (versionResponse embeds VersionInfo).
+	if props, _ := doc.Comps.Schemas["versionResponse"]["properties"].(map[string]any); props["version"] == nil || props["update_check"] == nil {
+		t.Errorf("unexpected versionResponse schema: %v", doc.Comps.Schemas["versionResponse"])
+	}
+}
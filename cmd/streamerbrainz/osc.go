@@ -0,0 +1,527 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// OSC (Open Sound Control) server
+// ============================================================================
+// A UDP listener for touchscreen controllers (TouchOSC, Open Stage Control) and
+// DAW control surfaces.
+//
+// Incoming address patterns:
+//   /volume <f|i>          set volume (dB)
+//   /volume/up   [f|i]     one step up   (a 0 argument, e.g. a button release, is ignored)
+//   /volume/down [f|i]     one step down (same)
+//   /mute [f|i]            toggle mute   (same)
+//   /preset <s>            jump to a named preset (osc.presets)
+//   /preset/<name> [f|i]   same, for controls that can only send numbers
+//   /zone <s>              select the zone IR/rotary/unzoned events control
+//
+// Any of the volume/mute/preset addresses may be prefixed with /zone/<id> to
+// target a specific zone (e.g. /zone/phones/volume -30).
+//
+// Outgoing feedback (state changes, sent to osc.feedback_targets and, if
+// reply_to_senders is set, to every client heard from recently):
+//   /volume <f>, /mute <i>, /output <s>  for the current zone, and again
+//   prefixed with /zone/<id> for every zone; /zone <s> when the selection changes.
+//
+// Bundles are accepted (elements are handled in order; time tags are ignored).
+// ============================================================================
+
+const (
+	oscMaxPacket = 8192
+	// oscSenderTTL is how long a client keeps receiving feedback after its last message.
+	oscSenderTTL = 10 * time.Minute
+	// oscSnapshotTimeout bounds the state request made when a new client appears.
+	oscSnapshotTimeout = 1 * time.Second
+)
+
+// oscMessage is one decoded OSC message. Args hold int32, float32 or string values.
+type oscMessage struct {
+	Address string
+	Args    []any
+}
+
+// ----------------------------------------------------------------------------
+// Wire format
+// ----------------------------------------------------------------------------
+
+// parseOSCPacket decodes a message or (possibly nested) bundle into its messages.
+func parseOSCPacket(b []byte) ([]oscMessage, error) {
+	if bytes.HasPrefix(b, []byte("#bundle\x00")) {
+		// "#bundle\0" + 8-byte time tag, then size-prefixed elements.
+		if len(b) < 16 {
+			return nil, errors.New("osc: short bundle")
+		}
+		var out []oscMessage
+		rest := b[16:]
+		for len(rest) > 0 {
+			if len(rest) < 4 {
+				return nil, errors.New("osc: truncated bundle element size")
+			}
+			n := int(binary.BigEndian.Uint32(rest))
+			rest = rest[4:]
+			if n < 0 || n > len(rest) {
+				return nil, errors.New("osc: truncated bundle element")
+			}
+			msgs, err := parseOSCPacket(rest[:n])
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, msgs...)
+			rest = rest[n:]
+		}
+		return out, nil
+	}
+
+	msg, err := parseOSCMessage(b)
+	if err != nil {
+		return nil, err
+	}
+	return []oscMessage{msg}, nil
+}
+
+// parseOSCMessage decodes a single OSC message.
+func parseOSCMessage(b []byte) (oscMessage, error) {
+	addr, rest, err := readOSCString(b)
+	if err != nil {
+		return oscMessage{}, fmt.Errorf("osc: address: %w", err)
+	}
+	if !strings.HasPrefix(addr, "/") {
+		return oscMessage{}, fmt.Errorf("osc: invalid address %q", addr)
+	}
+	msg := oscMessage{Address: addr}
+	if len(rest) == 0 {
+		// Type tag string is optional in old implementations.
+		return msg, nil
+	}
+
+	tags, rest, err := readOSCString(rest)
+	if err != nil {
+		return oscMessage{}, fmt.Errorf("osc: type tags: %w", err)
+	}
+	if !strings.HasPrefix(tags, ",") {
+		return oscMessage{}, fmt.Errorf("osc: invalid type tags %q", tags)
+	}
+
+	for _, tag := range tags[1:] {
+		switch tag {
+		case 'i':
+			if len(rest) < 4 {
+				return oscMessage{}, errors.New("osc: truncated int32")
+			}
+			msg.Args = append(msg.Args, int32(binary.BigEndian.Uint32(rest)))
+			rest = rest[4:]
+		case 'f':
+			if len(rest) < 4 {
+				return oscMessage{}, errors.New("osc: truncated float32")
+			}
+			msg.Args = append(msg.Args, math.Float32frombits(binary.BigEndian.Uint32(rest)))
+			rest = rest[4:]
+		case 's':
+			var s string
+			s, rest, err = readOSCString(rest)
+			if err != nil {
+				return oscMessage{}, fmt.Errorf("osc: string arg: %w", err)
+			}
+			msg.Args = append(msg.Args, s)
+		case 'T':
+			msg.Args = append(msg.Args, int32(1))
+		case 'F', 'N', 'I':
+			msg.Args = append(msg.Args, int32(0))
+		default:
+			return oscMessage{}, fmt.Errorf("osc: unsupported type tag %q", tag)
+		}
+	}
+	return msg, nil
+}
+
+// readOSCString reads a NUL-terminated string padded to a multiple of 4 bytes.
+func readOSCString(b []byte) (string, []byte, error) {
+	i := bytes.IndexByte(b, 0)
+	if i < 0 {
+		return "", nil, errors.New("unterminated string")
+	}
+	n := (i + 4) &^ 3
+	if n > len(b) {
+		return "", nil, errors.New("truncated string padding")
+	}
+	return string(b[:i]), b[n:], nil
+}
+
+// appendOSCString appends s NUL-terminated and padded to a multiple of 4 bytes.
+func appendOSCString(b []byte, s string) []byte {
+	b = append(b, s...)
+	pad := 4 - len(s)%4
+	return append(b, make([]byte, pad)...)
+}
+
+// marshal encodes the message. Supported arg types: int32, float32, string.
+func (m oscMessage) marshal() []byte {
+	b := appendOSCString(nil, m.Address)
+	tags := []byte{','}
+	for _, a := range m.Args {
+		switch a.(type) {
+		case int32:
+			tags = append(tags, 'i')
+		case float32:
+			tags = append(tags, 'f')
+		case string:
+			tags = append(tags, 's')
+		}
+	}
+	b = appendOSCString(b, string(tags))
+	for _, a := range m.Args {
+		switch v := a.(type) {
+		case int32:
+			b = binary.BigEndian.AppendUint32(b, uint32(v))
+		case float32:
+			b = binary.BigEndian.AppendUint32(b, math.Float32bits(v))
+		case string:
+			b = appendOSCString(b, v)
+		}
+	}
+	return b
+}
+
+// oscNumber returns the first argument as a float, if it is numeric.
+func oscNumber(args []any) (float64, bool) {
+	if len(args) == 0 {
+		return 0, false
+	}
+	switch v := args[0].(type) {
+	case int32:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// oscPressed reports whether a momentary control fired: no argument, or a non-zero value.
+func oscPressed(args []any) bool {
+	if len(args) == 0 {
+		return true
+	}
+	v, ok := oscNumber(args)
+	return !ok || v != 0
+}
+
+// ----------------------------------------------------------------------------
+// Address mapping
+// ----------------------------------------------------------------------------
+
+// oscEvent maps an incoming message to a daemon event. ok is false for messages
+// that are ignored (button releases) or unknown.
+func oscEvent(msg oscMessage, presets map[string]float64) (Event, bool, error) {
+	addr := msg.Address
+
+	// /zone/<id>/<rest> targets a specific zone.
+	if rest, found := strings.CutPrefix(addr, "/zone/"); found {
+		zone, sub, hasSub := strings.Cut(rest, "/")
+		if !hasSub || zone == "" {
+			return nil, false, fmt.Errorf("unknown address %q", addr)
+		}
+		ev, ok, err := oscEvent(oscMessage{Address: "/" + sub, Args: msg.Args}, presets)
+		if err != nil || !ok {
+			return nil, ok, err
+		}
+		if _, isZone := ev.(SelectZone); isZone {
+			return nil, false, fmt.Errorf("unknown address %q", addr)
+		}
+		return ZonedEvent{Zone: zone, Event: ev}, true, nil
+	}
+
+	switch addr {
+	case "/volume":
+		db, ok := oscNumber(msg.Args)
+		if !ok {
+			return nil, false, errors.New("/volume requires a numeric argument (dB)")
+		}
+		return SetVolumeAbsolute{Db: db, Origin: "osc"}, true, nil
+
+	case "/volume/up", "/volume/down":
+		if !oscPressed(msg.Args) {
+			return nil, false, nil
+		}
+		steps := 1
+		if addr == "/volume/down" {
+			steps = -1
+		}
+		return VolumeStep{Steps: steps}, true, nil
+
+	case "/mute":
+		if !oscPressed(msg.Args) {
+			return nil, false, nil
+		}
+		return ToggleMute{}, true, nil
+
+	case "/preset":
+		if len(msg.Args) == 0 {
+			return nil, false, errors.New("/preset requires a preset name")
+		}
+		name, isString := msg.Args[0].(string)
+		if !isString {
+			return nil, false, errors.New("/preset requires a string argument")
+		}
+		return oscPreset(name, presets)
+
+	case "/zone":
+		if len(msg.Args) == 0 {
+			return SelectZone{}, true, nil // cycle
+		}
+		zone, isString := msg.Args[0].(string)
+		if !isString {
+			return nil, false, errors.New("/zone requires a string argument")
+		}
+		return SelectZone{Zone: zone}, true, nil
+	}
+
+	if name, found := strings.CutPrefix(addr, "/preset/"); found {
+		if !oscPressed(msg.Args) {
+			return nil, false, nil
+		}
+		return oscPreset(name, presets)
+	}
+
+	return nil, false, fmt.Errorf("unknown address %q", addr)
+}
+
+func oscPreset(name string, presets map[string]float64) (Event, bool, error) {
+	db, ok := presets[name]
+	if !ok {
+		return nil, false, fmt.Errorf("unknown preset %q", name)
+	}
+	return SetVolumeAbsolute{Db: db, Origin: "osc"}, true, nil
+}
+
+// oscFeedback maps a state broadcast to outgoing messages. current is the selected zone.
+func oscFeedback(b StateBroadcast, current string) []oscMessage {
+	switch ev := b.(type) {
+	case ZoneBroadcast:
+		inner := oscFeedback(ev.Broadcast, "")
+		out := make([]oscMessage, 0, 2*len(inner))
+		for _, m := range inner {
+			if ev.Zone == current {
+				out = append(out, m)
+			}
+			out = append(out, oscMessage{Address: "/zone/" + ev.Zone + m.Address, Args: m.Args})
+		}
+		return out
+	case BroadcastVolumeChanged:
+		return []oscMessage{{Address: "/volume", Args: []any{float32(ev.VolumeDB)}}}
+	case BroadcastMuteChanged:
+		return []oscMessage{{Address: "/mute", Args: []any{oscBool(ev.Muted)}}}
+	case BroadcastOutputChanged:
+		return []oscMessage{{Address: "/output", Args: []any{ev.Output}}}
+	case BroadcastZoneSelected:
+		return []oscMessage{{Address: "/zone", Args: []any{ev.Zone}}}
+	}
+	return nil
+}
+
+// oscSnapshotFeedback renders a state snapshot for a newly seen client.
+func oscSnapshotFeedback(snap StateSnapshot) []oscMessage {
+	var out []oscMessage
+	if snap.Zone != "" {
+		out = append(out, oscMessage{Address: "/zone", Args: []any{snap.Zone}})
+	}
+	if snap.VolumeKnown {
+		out = append(out, oscMessage{Address: "/volume", Args: []any{float32(snap.VolumeDB)}})
+	}
+	if snap.MuteKnown {
+		out = append(out, oscMessage{Address: "/mute", Args: []any{oscBool(snap.Muted)}})
+	}
+	if snap.Output != "" {
+		out = append(out, oscMessage{Address: "/output", Args: []any{snap.Output}})
+	}
+	return out
+}
+
+func oscBool(v bool) int32 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+// ----------------------------------------------------------------------------
+// Server
+// ----------------------------------------------------------------------------
+
+// runOSCServer serves OSC on cfg's UDP address until ctx is canceled, forwarding
+// events and sending state feedback from broadcasts.
+func runOSCServer(ctx context.Context, cfg OSCConfig, currentZone string, events chan<- Event, broadcasts <-chan StateBroadcast, logger *slog.Logger) error {
+	conn, err := net.ListenPacket("udp", cfg.ListenAddr())
+	if err != nil {
+		return fmt.Errorf("osc listen on %s: %w", cfg.ListenAddr(), err)
+	}
+	defer conn.Close()
+
+	var targets []*net.UDPAddr
+	for _, t := range cfg.FeedbackTargets {
+		addr, err := net.ResolveUDPAddr("udp", t)
+		if err != nil {
+			logger.Warn("osc feedback target unresolvable", "target", t, "error", err)
+			continue
+		}
+		targets = append(targets, addr)
+	}
+
+	logger.Info("OSC listening", "addr", conn.LocalAddr().String(), "feedback_targets", len(targets))
+
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	// Senders are tracked by the reader goroutine and handed to the feedback loop.
+	seen := make(chan *net.UDPAddr, 16)
+
+	readerDone := make(chan struct{})
+	defer func() { <-readerDone }()
+	go func() {
+		defer close(readerDone)
+		buf := make([]byte, oscMaxPacket)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				if ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+					logger.Warn("osc read error", "error", err)
+				}
+				return
+			}
+			msgs, err := parseOSCPacket(buf[:n])
+			if err != nil {
+				logger.Debug("osc: malformed packet", "from", from, "error", err)
+				continue
+			}
+			for _, msg := range msgs {
+				ev, ok, err := oscEvent(msg, cfg.Presets)
+				if err != nil {
+					logger.Debug("osc: message ignored", "from", from, "address", msg.Address, "error", err)
+					continue
+				}
+				if !ok {
+					continue
+				}
+				select {
+				case events <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if udp, ok := from.(*net.UDPAddr); ok && cfg.ReplyToSenders {
+				select {
+				case seen <- udp:
+				default:
+				}
+			}
+		}
+	}()
+
+	send := func(to *net.UDPAddr, msgs []oscMessage) {
+		for _, m := range msgs {
+			if _, err := conn.WriteTo(m.marshal(), to); err != nil {
+				logger.Debug("osc feedback send failed", "to", to, "error", err)
+			}
+		}
+	}
+
+	type oscClient struct {
+		addr *net.UDPAddr
+		last time.Time
+	}
+	clients := make(map[string]*oscClient) // keyed by reply address
+	snapshots := make(chan []oscMessage, 4)
+	awaitingSnapshot := make(map[string]*net.UDPAddr)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case from := <-seen:
+			to := oscReplyAddr(from, cfg.ReplyPort)
+			key := to.String()
+			c, known := clients[key]
+			if !known {
+				// New client: send current state once the daemon answers.
+				logger.Debug("osc client connected", "addr", key)
+				c = &oscClient{addr: to}
+				clients[key] = c
+				awaitingSnapshot[key] = to
+				go requestOSCSnapshot(ctx, events, snapshots)
+			}
+			c.last = time.Now()
+
+		case msgs := <-snapshots:
+			for key, addr := range awaitingSnapshot {
+				send(addr, msgs)
+				delete(awaitingSnapshot, key)
+			}
+
+		case b, ok := <-broadcasts:
+			if !ok {
+				return nil
+			}
+			if sel, isSel := b.(BroadcastZoneSelected); isSel {
+				currentZone = sel.Zone
+			}
+			msgs := oscFeedback(b, currentZone)
+			if len(msgs) == 0 {
+				continue
+			}
+			for _, t := range targets {
+				send(t, msgs)
+			}
+			now := time.Now()
+			for key, c := range clients {
+				if now.Sub(c.last) > oscSenderTTL {
+					delete(clients, key)
+					continue
+				}
+				send(c.addr, msgs)
+			}
+		}
+	}
+}
+
+// oscReplyAddr returns where feedback for a sender goes: its source address, or
+// the sender's IP at replyPort if configured (controllers often listen on a fixed port).
+func oscReplyAddr(from *net.UDPAddr, replyPort int) *net.UDPAddr {
+	if replyPort == 0 {
+		return from
+	}
+	return &net.UDPAddr{IP: from.IP, Port: replyPort, Zone: from.Zone}
+}
+
+// requestOSCSnapshot fetches the current state and renders it as feedback messages.
+func requestOSCSnapshot(ctx context.Context, events chan<- Event, out chan<- []oscMessage) {
+	ctx, cancel := context.WithTimeout(ctx, oscSnapshotTimeout)
+	defer cancel()
+
+	reply := make(chan StateSnapshot, 1)
+	select {
+	case events <- RequestStateSnapshot{Reply: reply}:
+	case <-ctx.Done():
+		return
+	}
+	select {
+	case snap := <-reply:
+		select {
+		case out <- oscSnapshotFeedback(snap):
+		case <-ctx.Done():
+		}
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestOSCMessage_RoundTrip(t *testing.T) {
+	in := oscMessage{Address: "/zone/phones/volume", Args: []any{float32(-30.5), int32(1), "abc"}}
+	out, err := parseOSCPacket(in.marshal())
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(out) != 1 || !reflect.DeepEqual(out[0], in) {
+		t.Fatalf("round trip mismatch: %#v", out)
+	}
+}
+
+func TestParseOSCPacket_Bundle(t *testing.T) {
+	a := oscMessage{Address: "/mute"}.marshal()
+	b := oscMessage{Address: "/volume", Args: []any{float32(-20)}}.marshal()
+
+	pkt := append([]byte("#bundle\x00"), make([]byte, 8)...) // immediate time tag
+	for _, el := range [][]byte{a, b} {
+		pkt = binary.BigEndian.AppendUint32(pkt, uint32(len(el)))
+		pkt = append(pkt, el...)
+	}
+
+	msgs, err := parseOSCPacket(pkt)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(msgs) != 2 || msgs[0].Address != "/mute" || msgs[1].Address != "/volume" {
+		t.Fatalf("unexpected messages: %#v", msgs)
+	}
+
+	if _, err := parseOSCPacket(pkt[:len(pkt)-2]); err == nil {
+		t.Fatalf("expected error for truncated bundle")
+	}
+}
+
+func TestOSCEvent_Mapping(t *testing.T) {
+	presets := map[string]float64{"night": -45}
+
+	tests := []struct {
+		msg  oscMessage
+		want Event
+	}{
+		{oscMessage{Address: "/volume", Args: []any{int32(-30)}}, SetVolumeAbsolute{Db: -30, Origin: "osc"}},
+		{oscMessage{Address: "/volume/up", Args: []any{float32(1)}}, VolumeStep{Steps: 1}},
+		{oscMessage{Address: "/volume/down"}, VolumeStep{Steps: -1}},
+		{oscMessage{Address: "/mute", Args: []any{float32(1)}}, ToggleMute{}},
+		{oscMessage{Address: "/preset", Args: []any{"night"}}, SetVolumeAbsolute{Db: -45, Origin: "osc"}},
+		{oscMessage{Address: "/preset/night", Args: []any{float32(1)}}, SetVolumeAbsolute{Db: -45, Origin: "osc"}},
+		{oscMessage{Address: "/zone", Args: []any{"phones"}}, SelectZone{Zone: "phones"}},
+		{oscMessage{Address: "/zone/phones/mute"}, ZonedEvent{Zone: "phones", Event: ToggleMute{}}},
+	}
+	for _, tt := range tests {
+		got, ok, err := oscEvent(tt.msg, presets)
+		if err != nil || !ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v ok=%v err=%v, want %#v", tt.msg.Address, got, ok, err, tt.want)
+		}
+	}
+
+	// Button releases are ignored without error.
+	if _, ok, err := oscEvent(oscMessage{Address: "/mute", Args: []any{float32(0)}}, presets); ok || err != nil {
+		t.Errorf("expected release to be ignored, ok=%v err=%v", ok, err)
+	}
+	for _, msg := range []oscMessage{
+		{Address: "/preset", Args: []any{"nope"}},
+		{Address: "/volume"},
+		{Address: "/unknown"},
+		{Address: "/zone/phones/zone", Args: []any{"x"}},
+	} {
+		if _, _, err := oscEvent(msg, presets); err == nil {
+			t.Errorf("%s: expected error", msg.Address)
+		}
+	}
+}
+
+func TestOSCFeedback_ZoneBroadcasts(t *testing.T) {
+	b := ZoneBroadcast{Zone: "phones", Broadcast: BroadcastVolumeChanged{VolumeDB: -12}}
+
+	got := oscFeedback(b, "phones")
+	want := []oscMessage{
+		{Address: "/volume", Args: []any{float32(-12)}},
+		{Address: "/zone/phones/volume", Args: []any{float32(-12)}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("current zone feedback: got %#v", got)
+	}
+
+	// Non-current zones only get the prefixed address.
+	got = oscFeedback(ZoneBroadcast{Zone: "phones", Broadcast: BroadcastMuteChanged{Muted: true}}, "speakers")
+	want = []oscMessage{{Address: "/zone/phones/mute", Args: []any{int32(1)}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("other zone feedback: got %#v", got)
+	}
+}
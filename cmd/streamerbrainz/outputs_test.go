@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReduce_OutputSelect_SavesAndRestoresPerOutputVolume(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0}
+	rotaryCfg := RotaryConfig{DbPerStep: 1}
+	t0 := time.Unix(1000, 0).UTC()
+
+	headphonesDB := -30.0
+	s := &DaemonState{}
+	s.Output.Outputs = []OutputConfig{
+		{ID: "speakers", ConfigPath: "/etc/camilladsp/speakers.yml"},
+		{ID: "headphones", ConfigPath: "/etc/camilladsp/headphones.yml", VolumeDB: &headphonesDB},
+	}
+	s.Output.Active = "speakers"
+	s.SetObservedVolume(-20, t0)
+	s.SetObservedMute(false, t0)
+
+	// Empty output cycles to the next configured output.
+	rr := Reduce(s, OutputSelect{}, cfg, rotaryCfg, ReducerPolicy{})
+	if len(rr.Commands) != 1 {
+		t.Fatalf("expected 1 command, got %d", len(rr.Commands))
+	}
+	cmd, ok := rr.Commands[0].(CmdSelectOutput)
+	if !ok {
+		t.Fatalf("expected CmdSelectOutput, got %T", rr.Commands[0])
+	}
+	if cmd.Output != "headphones" || cmd.ConfigPath != "/etc/camilladsp/headphones.yml" || !cmd.Unmute {
+		t.Fatalf("unexpected command %#v", cmd)
+	}
+	if cmd.VolumeDB == nil || *cmd.VolumeDB != -30 {
+		t.Fatalf("expected configured headphones volume -30, got %v", cmd.VolumeDB)
+	}
+	if got := rr.State.Output.SavedVolumeDB["speakers"]; got != -20 {
+		t.Fatalf("expected speakers volume saved at -20, got %v", got)
+	}
+
+	// A second select while switching is ignored.
+	rr = Reduce(rr.State, OutputSelect{Output: "speakers"}, cfg, rotaryCfg, ReducerPolicy{})
+	if len(rr.Commands) != 0 {
+		t.Fatalf("expected select ignored while pending, got %d commands", len(rr.Commands))
+	}
+
+	rr = Reduce(rr.State, OutputSelected{Output: "headphones", At: t0}, cfg, rotaryCfg, ReducerPolicy{})
+	if rr.State.Output.Active != "headphones" || rr.State.Output.Pending != "" {
+		t.Fatalf("unexpected output state %#v", rr.State.Output)
+	}
+	if len(rr.Broadcasts) != 1 {
+		t.Fatalf("expected 1 broadcast, got %d", len(rr.Broadcasts))
+	}
+	if b, ok := rr.Broadcasts[0].(BroadcastOutputChanged); !ok || b.Output != "headphones" {
+		t.Fatalf("unexpected broadcast %#v", rr.Broadcasts[0])
+	}
+
+	// Switching back restores the saved speakers level.
+	rr.State.SetObservedVolume(-25, t0)
+	rr = Reduce(rr.State, OutputSelect{Output: "speakers"}, cfg, rotaryCfg, ReducerPolicy{})
+	cmd = rr.Commands[0].(CmdSelectOutput)
+	if cmd.VolumeDB == nil || *cmd.VolumeDB != -20 {
+		t.Fatalf("expected restored speakers volume -20, got %v", cmd.VolumeDB)
+	}
+	if got := rr.State.Output.SavedVolumeDB["headphones"]; got != -25 {
+		t.Fatalf("expected headphones volume saved at -25, got %v", got)
+	}
+}
+
+func TestReduce_OutputSelect_KeepsUserMute(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0}
+	t0 := time.Unix(1000, 0).UTC()
+
+	s := &DaemonState{}
+	s.Output.Outputs = []OutputConfig{{ID: "speakers"}, {ID: "headphones"}}
+	s.SetObservedMute(true, t0)
+
+	rr := Reduce(s, OutputSelect{Output: "headphones"}, cfg, RotaryConfig{}, ReducerPolicy{})
+	cmd := rr.Commands[0].(CmdSelectOutput)
+	if cmd.Unmute {
+		t.Fatalf("expected muted output to stay muted after switch")
+	}
+	if cmd.VolumeDB != nil {
+		t.Fatalf("expected no volume change without saved/configured level, got %v", *cmd.VolumeDB)
+	}
+
+	// A failed switch clears the pending state so the user can retry.
+	rr = Reduce(rr.State, CamillaCommandFailed{Command: cmd, At: t0}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if rr.State.Output.Pending != "" {
+		t.Fatalf("expected pending cleared after failure")
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+)
+
+// ============================================================================
+// `plex-discover` subcommand
+// ============================================================================
+//   streamerbrainz plex-discover [-config path]
+//
+// Lists the players in active Plex sessions (any media type) and the clients the
+// server currently sees, with their machineIdentifier and product, so the right
+// plex.machine_id can be copied into the config. Uses plex.server_url and
+// plex.token_file from the config.
+// ============================================================================
+
+// plexSessionsContainer is /status/sessions with every session element
+// (Track, Video, Photo, ...) kept, unlike PlexMediaContainer which only has tracks.
+type plexSessionsContainer struct {
+	XMLName  xml.Name           `xml:"MediaContainer"`
+	Sessions []plexSessionEntry `xml:",any"`
+}
+
+type plexSessionEntry struct {
+	XMLName          xml.Name
+	Title            string     `xml:"title,attr"`
+	GrandparentTitle string     `xml:"grandparentTitle,attr"`
+	Player           PlexPlayer `xml:"Player"`
+}
+
+// plexClientsContainer is the /clients response.
+type plexClientsContainer struct {
+	XMLName xml.Name     `xml:"MediaContainer"`
+	Clients []plexClient `xml:"Server"`
+}
+
+type plexClient struct {
+	Name              string `xml:"name,attr"`
+	Host              string `xml:"host,attr"`
+	MachineIdentifier string `xml:"machineIdentifier,attr"`
+	Product           string `xml:"product,attr"`
+	DeviceClass       string `xml:"deviceClass,attr"`
+}
+
+func printPlexDiscoverUsage() {
+	fmt.Println("USAGE:")
+	fmt.Println("  streamerbrainz plex-discover [-config path]")
+	fmt.Println()
+	fmt.Println("  List Plex players (active sessions and connected clients) with their")
+	fmt.Println("  machineIdentifier, for plex.machine_id. Start playback on the target")
+	fmt.Println("  player first so it shows up as a session.")
+	fmt.Println()
+}
+
+// runPlexDiscoverSubcommand handles `streamerbrainz plex-discover`.
+func runPlexDiscoverSubcommand(args []string) {
+	fs := flag.NewFlagSet("plex-discover", flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to YAML config file")
+	fs.Usage = printPlexDiscoverUsage
+	fs.Parse(args)
+
+	cfg, err := LoadConfigFile(ResolveConfigPath(*configPath))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	cfg.expandPaths()
+
+	if cfg.Plex.ServerURL == "" || cfg.Plex.TokenFile == "" {
+		fmt.Fprintln(os.Stderr, "error: plex.server_url and plex.token_file must be set (see `streamerbrainz plex-login`)")
+		os.Exit(1)
+	}
+	token, err := readSecret(cfg.Plex.TokenFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error: failed to read plex token:", err)
+		os.Exit(1)
+	}
+
+	plexCfg := PlexampConfig{
+		ServerUrl:         cfg.Plex.ServerURL,
+		Token:             token,
+		MachineIdentifier: cfg.Plex.MachineID,
+		Client:            newPlexHTTPClient(cfg.Plex),
+	}
+	if err := discoverPlexPlayers(context.Background(), os.Stdout, plexCfg); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// discoverPlexPlayers writes the session and client tables to w. The configured
+// machine ID (if any) is marked with "*".
+func discoverPlexPlayers(ctx context.Context, w io.Writer, config PlexampConfig) error {
+	logger := slog.New(slog.DiscardHandler)
+
+	var sessions plexSessionsContainer
+	if err := fetchPlexXML(ctx, config, "/status/sessions", &sessions, logger); err != nil {
+		return fmt.Errorf("fetch sessions: %w", err)
+	}
+	// Older servers or restricted tokens may not expose /clients; sessions are enough.
+	var clients plexClientsContainer
+	clientsErr := fetchPlexXML(ctx, config, "/clients", &clients, logger)
+
+	mark := func(id string) string {
+		if id != "" && id == config.MachineIdentifier {
+			return "*"
+		}
+		return ""
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ACTIVE SESSIONS")
+	fmt.Fprintln(tw, "\tMACHINE_ID\tPRODUCT\tPLAYER\tSTATE\tNOW PLAYING")
+	for _, s := range sessions.Sessions {
+		if s.Player.MachineIdentifier == "" {
+			continue
+		}
+		playing := s.Title
+		if s.GrandparentTitle != "" {
+			playing = s.GrandparentTitle + " - " + s.Title
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s (%s)\n", mark(s.Player.MachineIdentifier), s.Player.MachineIdentifier,
+			s.Player.Product, s.Player.Title, s.Player.State, playing, s.XMLName.Local)
+	}
+	if len(sessions.Sessions) == 0 {
+		fmt.Fprintln(tw, "\t(none; start playback on the player and run again)")
+	}
+
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "CONNECTED CLIENTS")
+	if clientsErr != nil {
+		fmt.Fprintf(tw, "\t(unavailable: %v)\n", clientsErr)
+	} else {
+		fmt.Fprintln(tw, "\tMACHINE_ID\tPRODUCT\tNAME\tHOST\tCLASS")
+		for _, c := range clients.Clients {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", mark(c.MachineIdentifier), c.MachineIdentifier,
+				c.Product, c.Name, c.Host, c.DeviceClass)
+		}
+		if len(clients.Clients) == 0 {
+			fmt.Fprintln(tw, "\t(none)")
+		}
+	}
+	return tw.Flush()
+}
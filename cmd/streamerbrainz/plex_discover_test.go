@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDiscoverPlexPlayers(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("X-Plex-Token") != "tok" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/status/sessions":
+			w.Write([]byte(`<MediaContainer size="2">
+  <Track title="Song" grandparentTitle="Artist"><Player machineIdentifier="amp-1" product="Plexamp" title="Living Room" state="playing"/></Track>
+  <Video title="Movie"><Player machineIdentifier="tv-1" product="Plex for LG" title="TV" state="paused"/></Video>
+</MediaContainer>`))
+		case "/clients":
+			w.Write([]byte(`<MediaContainer size="1"><Server name="Living Room" host="10.0.0.5" machineIdentifier="amp-1" product="Plexamp" deviceClass="pc"/></MediaContainer>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	if err := discoverPlexPlayers(context.Background(), &buf, PlexampConfig{ServerUrl: srv.URL, Token: "tok", MachineIdentifier: "amp-1"}); err != nil {
+		t.Fatalf("discoverPlexPlayers: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"amp-1", "Plexamp", "Artist - Song (Track)", "tv-1", "Movie (Video)", "10.0.0.5"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("output missing %q:\n%s", want, out)
+		}
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if strings.Contains(line, "tv-1") && strings.HasPrefix(line, "*") {
+			t.Fatalf("unconfigured player marked as selected: %q", line)
+		}
+	}
+	if !strings.Contains(out, "*  amp-1") {
+		t.Fatalf("expected configured player to be marked:\n%s", out)
+	}
+
+	if err := discoverPlexPlayers(context.Background(), &buf, PlexampConfig{ServerUrl: srv.URL, Token: "bad"}); err == nil {
+		t.Fatalf("expected error for rejected token")
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// `plex-login` subcommand
+// ============================================================================
+//   streamerbrainz plex-login [-config path] [-timeout 10m]
+//
+// Runs the plex.tv PIN flow: request a PIN, ask the user to approve it at
+// app.plex.tv, poll until plex.tv hands out a token, then write the token to
+// plex.token_file (mode 0600) and check it against plex.server_url.
+// ============================================================================
+
+// Variables so tests can point the flow at a fake plex.tv and poll faster.
+var (
+	plexTVURL           = "https://plex.tv"
+	plexAuthAppURL      = "https://app.plex.tv/auth"
+	plexPinPollInterval = 2 * time.Second
+)
+
+const (
+	plexProduct          = "StreamerBrainz"
+	plexLoginHTTPTimeout = 15 * time.Second
+)
+
+// plexPin is the plex.tv /api/v2/pins response.
+type plexPin struct {
+	ID        int64  `json:"id"`
+	Code      string `json:"code"`
+	AuthToken string `json:"authToken"`
+}
+
+func printPlexLoginUsage() {
+	fmt.Println("USAGE:")
+	fmt.Println("  streamerbrainz plex-login [-config path] [-timeout 10m]")
+	fmt.Println()
+	fmt.Println("  Sign in to plex.tv with a PIN, write the token to plex.token_file and")
+	fmt.Println("  verify it against plex.server_url.")
+	fmt.Println()
+}
+
+// runPlexLoginSubcommand handles `streamerbrainz plex-login`.
+func runPlexLoginSubcommand(args []string) {
+	fs := flag.NewFlagSet("plex-login", flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to YAML config file")
+	timeout := fs.Duration("timeout", 10*time.Minute, "How long to wait for the PIN to be approved")
+	fs.Usage = printPlexLoginUsage
+	fs.Parse(args)
+
+	cfg, err := LoadConfigFile(ResolveConfigPath(*configPath))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	cfg.expandPaths()
+
+	tokenFile := cfg.Plex.TokenFile
+	if tokenFile == "" {
+		fmt.Fprintln(os.Stderr, "error: plex.token_file is empty; set it to the file the token should be written to")
+		os.Exit(1)
+	}
+	if strings.HasPrefix(tokenFile, secretPrefixEnv) || strings.HasPrefix(tokenFile, secretPrefixCredential) || strings.HasPrefix(tokenFile, secretPrefixExec) {
+		fmt.Fprintf(os.Stderr, "error: plex.token_file is %q; plex-login can only write to a file path\n", tokenFile)
+		os.Exit(1)
+	}
+	tokenFile = ExpandPath(strings.TrimPrefix(tokenFile, secretPrefixFile))
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	client := &http.Client{Timeout: plexLoginHTTPTimeout}
+	token, err := plexPinLogin(ctx, client, plexClientIdentifier(), func(authURL string) {
+		fmt.Println("Open this URL in a browser and sign in to approve StreamerBrainz:")
+		fmt.Println()
+		fmt.Println("  " + authURL)
+		fmt.Println()
+		fmt.Println("Waiting for approval...")
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+
+	if err := writeTokenFile(tokenFile, token); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Token written to %s\n", tokenFile)
+
+	if cfg.Plex.ServerURL == "" {
+		fmt.Println("plex.server_url is empty; skipping server check")
+		return
+	}
+	plexCfg := PlexampConfig{ServerUrl: cfg.Plex.ServerURL, Token: token, Client: newPlexHTTPClient(cfg.Plex)}
+	container, err := fetchPlexSessions(context.Background(), plexCfg, slog.New(slog.DiscardHandler))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: token saved, but %s rejected it: %v\n", cfg.Plex.ServerURL, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Verified access to %s (%d active sessions)\n", cfg.Plex.ServerURL, container.Size)
+}
+
+// plexPinLogin runs the PIN flow and returns the auth token. prompt receives the URL
+// the user must open.
+func plexPinLogin(ctx context.Context, client *http.Client, clientID string, prompt func(authURL string)) (string, error) {
+	var pin plexPin
+	if err := plexTVRequest(ctx, client, http.MethodPost, "/api/v2/pins?strong=true", clientID, &pin); err != nil {
+		return "", fmt.Errorf("request plex PIN: %w", err)
+	}
+	if pin.ID == 0 || pin.Code == "" {
+		return "", errors.New("request plex PIN: empty PIN in response")
+	}
+
+	q := url.Values{}
+	q.Set("clientID", clientID)
+	q.Set("code", pin.Code)
+	q.Set("context[device][product]", plexProduct)
+	prompt(plexAuthAppURL + "#?" + q.Encode())
+
+	ticker := time.NewTicker(plexPinPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return "", errors.New("timed out waiting for the plex PIN to be approved")
+		case <-ticker.C:
+		}
+
+		var status plexPin
+		if err := plexTVRequest(ctx, client, http.MethodGet, fmt.Sprintf("/api/v2/pins/%d", pin.ID), clientID, &status); err != nil {
+			if ctx.Err() != nil {
+				return "", errors.New("timed out waiting for the plex PIN to be approved")
+			}
+			return "", fmt.Errorf("check plex PIN: %w", err)
+		}
+		if status.AuthToken != "" {
+			return status.AuthToken, nil
+		}
+	}
+}
+
+func plexTVRequest(ctx context.Context, client *http.Client, method, path, clientID string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, plexTVURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Plex-Product", plexProduct)
+	req.Header.Set("X-Plex-Version", version)
+	req.Header.Set("X-Plex-Client-Identifier", clientID)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// plexClientIdentifier is stable per host so repeated logins reuse one device entry
+// in the Plex account instead of adding a new one each time.
+func plexClientIdentifier() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	return "streamerbrainz-" + host
+}
+
+// writeTokenFile writes token to path with owner-only permissions, creating the
+// parent directory if needed.
+func writeTokenFile(path, token string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create token directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(token), 0o600); err != nil {
+		return fmt.Errorf("write token file: %w", err)
+	}
+	// WriteFile keeps the mode of an existing file; make sure it ends up 0600.
+	if err := os.Chmod(tmp, 0o600); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write token file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write token file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPlexPinLogin_PollsUntilApproved(t *testing.T) {
+	var polls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Plex-Client-Identifier") != "test-client" {
+			http.Error(w, "missing client id", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v2/pins":
+			w.Write([]byte(`{"id":42,"code":"ABCD","authToken":null}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v2/pins/42":
+			if polls.Add(1) < 3 {
+				w.Write([]byte(`{"id":42,"code":"ABCD","authToken":null}`))
+				return
+			}
+			w.Write([]byte(`{"id":42,"code":"ABCD","authToken":"secret-token"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	oldURL, oldInterval := plexTVURL, plexPinPollInterval
+	plexTVURL, plexPinPollInterval = srv.URL, 5*time.Millisecond
+	defer func() { plexTVURL, plexPinPollInterval = oldURL, oldInterval }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var authURL string
+	token, err := plexPinLogin(ctx, srv.Client(), "test-client", func(u string) { authURL = u })
+	if err != nil {
+		t.Fatalf("plexPinLogin: %v", err)
+	}
+	if token != "secret-token" {
+		t.Fatalf("unexpected token %q", token)
+	}
+	if !strings.Contains(authURL, "code=ABCD") || !strings.Contains(authURL, "clientID=test-client") {
+		t.Fatalf("unexpected auth URL %q", authURL)
+	}
+	if polls.Load() != 3 {
+		t.Fatalf("expected 3 polls, got %d", polls.Load())
+	}
+}
+
+func TestWriteTokenFile_OwnerOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "plex-token")
+	if err := writeTokenFile(path, "tok"); err != nil {
+		t.Fatalf("writeTokenFile: %v", err)
+	}
+	st, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Mode().Perm() != 0o600 {
+		t.Fatalf("expected mode 0600, got %v", st.Mode().Perm())
+	}
+	if got, _ := readSecret(path); got != "tok" {
+		t.Fatalf("unexpected token %q", got)
+	}
+}
@@ -1,14 +1,18 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
-	"os"
-	"strings"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // ============================================================================
@@ -54,63 +58,124 @@ type PlexPlayer struct {
 
 // PlexMedia represents media information in a track
 type PlexMedia struct {
-	AudioChannels int    `xml:"audioChannels,attr"`
-	AudioCodec    string `xml:"audioCodec,attr"`
-	Bitrate       int    `xml:"bitrate,attr"`
-	Duration      int64  `xml:"duration,attr"`
-	Container     string `xml:"container,attr"`
+	AudioChannels int        `xml:"audioChannels,attr"`
+	AudioCodec    string     `xml:"audioCodec,attr"`
+	Bitrate       int        `xml:"bitrate,attr"`
+	Duration      int64      `xml:"duration,attr"`
+	Container     string     `xml:"container,attr"`
+	Parts         []PlexPart `xml:"Part"`
+}
+
+// PlexPart represents a media part (file) of a track
+type PlexPart struct {
+	Streams []PlexStream `xml:"Stream"`
+}
+
+// PlexStream represents a stream of a part. Audio streams (streamType 2) of
+// analyzed tracks carry Plex's loudness data: gains in dB, linear peaks.
+type PlexStream struct {
+	StreamType int    `xml:"streamType,attr"`
+	Gain       string `xml:"gain,attr"`
+	AlbumGain  string `xml:"albumGain,attr"`
+	Peak       string `xml:"peak,attr"`
+	AlbumPeak  string `xml:"albumPeak,attr"`
 }
 
 // PlexampConfig holds configuration for the Plexamp webhook server
 type PlexampConfig struct {
-	ServerUrl         string // Plex server URL (e.g., "http://plex.home.arpa:32400")
-	Token             string // Plex authentication token
-	MachineIdentifier string // Machine identifier to filter sessions by
+	ServerUrl         string       // Plex server URL (e.g., "http://plex.home.arpa:32400")
+	Token             string       // Plex authentication token
+	MachineIdentifier string       // Machine identifier to filter sessions by
+	Client            *http.Client // shared by all Plex requests (see newPlexHTTPClient)
+}
+
+// plexHTTPTimeout is the default per-request timeout for Plex server calls.
+const plexHTTPTimeout = 5 * time.Second
+
+// newPlexHTTPClient returns the client shared by all requests to the Plex server:
+// bounded by a timeout (a hung server must not pile up webhook goroutines), with
+// pooled keep-alive connections, optionally accepting self-signed certificates.
+func newPlexHTTPClient(cfg PlexConfig) *http.Client {
+	timeout := plexHTTPTimeout
+	if cfg.TimeoutMS > 0 {
+		timeout = time.Duration(cfg.TimeoutMS) * time.Millisecond
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = 4
+	if cfg.InsecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}
 }
 
 // fetchPlexSessions queries the Plex API for current sessions
-func fetchPlexSessions(config PlexampConfig, logger *slog.Logger) (*PlexMediaContainer, error) {
+func fetchPlexSessions(ctx context.Context, config PlexampConfig, logger *slog.Logger) (*PlexMediaContainer, error) {
+	var container PlexMediaContainer
+	if err := fetchPlexXML(ctx, config, "/status/sessions", &container, logger); err != nil {
+		return nil, err
+	}
+	return &container, nil
+}
+
+// fetchPlexXML GETs path from the Plex server and decodes the XML response into out.
+func fetchPlexXML(ctx context.Context, config PlexampConfig, path string, out any, logger *slog.Logger) error {
 	// Build URL with token
-	baseURL := fmt.Sprintf("%s/status/sessions", config.ServerUrl)
-	u, err := url.Parse(baseURL)
+	u, err := url.Parse(config.ServerUrl + path)
 	if err != nil {
-		return nil, fmt.Errorf("parse base URL: %w", err)
+		return fmt.Errorf("parse base URL: %w", err)
 	}
 
 	q := u.Query()
 	q.Set("X-Plex-Token", config.Token)
 	u.RawQuery = q.Encode()
 
-	logger.Debug("fetching Plex sessions", "url", u.String())
+	logger.Debug("fetching from Plex", "path", path)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+
+	client := config.Client
+	if client == nil {
+		client = &http.Client{Timeout: plexHTTPTimeout}
+	}
 
 	// Make HTTP request
-	resp, err := http.Get(u.String())
+	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("HTTP request: %w", err)
+		return fmt.Errorf("HTTP request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+		return &plexStatusError{Status: resp.StatusCode, Body: string(body)}
 	}
 
 	// Parse XML response
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("read response body: %w", err)
+		return fmt.Errorf("read response body: %w", err)
 	}
 
 	logger.Debug("received Plex response", "body_length", len(body))
 
-	var container PlexMediaContainer
-	if err := xml.Unmarshal(body, &container); err != nil {
-		return nil, fmt.Errorf("parse XML: %w", err)
+	if err := xml.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("parse XML: %w", err)
 	}
+	return nil
+}
 
-	return &container, nil
+// plexStatusError is returned by fetchPlexXML for a non-200 response.
+type plexStatusError struct {
+	Status int
+	Body   string
 }
 
+func (e *plexStatusError) Error() string { return fmt.Sprintf("HTTP %d: %s", e.Status, e.Body) }
+
 // findTrackByMachineIdentifier searches for a track with matching player
 func findTrackByMachineIdentifier(container *PlexMediaContainer, machineID string) *PlexTrack {
 	for i := range container.Tracks {
@@ -121,76 +186,153 @@ func findTrackByMachineIdentifier(container *PlexMediaContainer, machineID strin
 	return nil
 }
 
-// handlePlexWebhook processes incoming Plex webhook events
-func handlePlexWebhook(config PlexampConfig, events chan<- Event, logger *slog.Logger) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		logger.Debug("received Plex webhook", "method", r.Method, "path", r.URL.Path)
+// Webhook-triggered session lookups run on a small worker pool fed by a bounded
+// queue, so a burst of webhooks (or a slow Plex server) can't pile up goroutines.
+// Each lookup fetches the current state, so a webhook arriving while the queue is
+// full is already covered by a queued lookup and can be dropped.
+const (
+	plexLookupWorkers   = 2
+	plexLookupQueueSize = 4
+)
 
-		// Respond immediately: the webhook delivery has succeeded once we accept it.
-		w.WriteHeader(http.StatusOK)
+// plexLookupPool runs session lookups for the Plex webhook.
+type plexLookupPool struct {
+	config PlexampConfig
+	events chan<- Event
+	logger *slog.Logger
+	queue  chan struct{}
+
+	// seq numbers lookups in start order; results older than the last one sent are
+	// dropped so concurrent workers never publish a stale state after a newer one.
+	seq      atomic.Uint64
+	mu       sync.Mutex
+	lastSent uint64
+}
 
-		// Process session lookup asynchronously.
+func newPlexLookupPool(config PlexampConfig, events chan<- Event, logger *slog.Logger) *plexLookupPool {
+	return &plexLookupPool{
+		config: config,
+		events: events,
+		logger: logger,
+		queue:  make(chan struct{}, plexLookupQueueSize),
+	}
+}
+
+// Run starts the workers and blocks until ctx is canceled, which also aborts
+// in-flight lookups.
+func (p *plexLookupPool) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < plexLookupWorkers; i++ {
+		wg.Add(1)
 		go func() {
-			// Fetch current sessions from Plex
-			container, err := fetchPlexSessions(config, logger)
-			if err != nil {
-				logger.Error("failed to fetch Plex sessions", "error", err)
-				return
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-p.queue:
+					p.lookup(ctx)
+				}
 			}
+		}()
+	}
+	wg.Wait()
+}
 
-			logger.Debug("fetched Plex sessions", "count", container.Size)
+// Enqueue requests a lookup; it reports false if the queue is full.
+func (p *plexLookupPool) Enqueue() bool {
+	select {
+	case p.queue <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
 
-			// Find track for our machine identifier
-			track := findTrackByMachineIdentifier(container, config.MachineIdentifier)
-			if track == nil {
-				logger.Debug("no track found for machine identifier", "machine_id", config.MachineIdentifier)
-				// Not an error: the webhook might be for a different player.
-				return
-			}
+func (p *plexLookupPool) lookup(ctx context.Context) {
+	n := p.seq.Add(1)
 
-			logger.Info("Plex session found",
-				"title", track.Title,
-				"artist", track.GrandparentTitle,
-				"album", track.ParentTitle,
-				"state", track.Player.State,
-				"position_ms", track.ViewOffset,
-				"duration_ms", track.Duration)
-
-			// Create event from track info
-			event := PlexStateChanged{
-				State:         track.Player.State,
-				Title:         track.Title,
-				Artist:        track.GrandparentTitle,
-				Album:         track.ParentTitle,
-				DurationMs:    track.Duration,
-				PositionMs:    track.ViewOffset,
-				SessionKey:    track.SessionKey,
-				RatingKey:     track.RatingKey,
-				PlayerTitle:   track.Player.Title,
-				PlayerProduct: track.Player.Product,
-			}
+	// Fetch current sessions from Plex
+	container, err := fetchPlexSessions(ctx, p.config, p.logger)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		p.logger.Error("failed to fetch Plex sessions", "error", err)
+		return
+	}
 
-			// Send action to daemon
-			select {
-			case events <- event:
-				logger.Debug("Plex action sent", "state", track.Player.State)
-			default:
-				logger.Warn("action queue full, dropping Plex event")
-			}
-		}()
+	p.logger.Debug("fetched Plex sessions", "count", container.Size)
+
+	// Find track for our machine identifier
+	track := findTrackByMachineIdentifier(container, p.config.MachineIdentifier)
+	if track == nil {
+		p.logger.Debug("no track found for machine identifier", "machine_id", p.config.MachineIdentifier)
+		// Not an error: the webhook might be for a different player.
+		return
+	}
+
+	p.logger.Info("Plex session found",
+		"title", track.Title,
+		"artist", track.GrandparentTitle,
+		"album", track.ParentTitle,
+		"state", track.Player.State,
+		"position_ms", track.ViewOffset,
+		"duration_ms", track.Duration)
+
+	// Create event from track info
+	event := PlexStateChanged{
+		State:         track.Player.State,
+		Title:         track.Title,
+		Artist:        track.GrandparentTitle,
+		Album:         track.ParentTitle,
+		DurationMs:    track.Duration,
+		PositionMs:    track.ViewOffset,
+		SessionKey:    track.SessionKey,
+		RatingKey:     track.RatingKey,
+		PlayerTitle:   track.Player.Title,
+		PlayerProduct: track.Player.Product,
+		Loudness:      track.loudness(),
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if n < p.lastSent {
+		p.logger.Debug("dropping stale Plex session result")
+		return
+	}
+	p.lastSent = n
+
+	// Send action to daemon
+	select {
+	case p.events <- event:
+		p.logger.Debug("Plex action sent", "state", track.Player.State)
+	default:
+		p.logger.Warn("action queue full, dropping Plex event")
 	}
 }
 
-// setupPlexWebhook registers the Plex webhook endpoint
-func setupPlexWebhook(serverUrl, tokenFile, machineID string, mux *http.ServeMux, events chan<- Event, logger *slog.Logger) error {
-	// Load token from file
-	tokenBytes, err := os.ReadFile(tokenFile)
-	if err != nil {
-		return fmt.Errorf("failed to read plex token file: %w", err)
+// handlePlexWebhook processes incoming Plex webhook events by queueing a session
+// lookup on pool.
+func handlePlexWebhook(pool *plexLookupPool, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Debug("received Plex webhook", "method", r.Method, "path", r.URL.Path)
+
+		// Respond immediately: the webhook delivery has succeeded once we accept it.
+		w.WriteHeader(http.StatusOK)
+
+		if !pool.Enqueue() {
+			logger.Debug("Plex lookup already queued, dropping webhook")
+		}
 	}
-	token := strings.TrimSpace(string(tokenBytes))
-	if token == "" {
-		return fmt.Errorf("plex token file is empty")
+}
+
+// setupPlexWebhook registers the Plex webhook endpoint on mux and starts its lookup
+// workers, which stop when ctx is canceled.
+func setupPlexWebhook(ctx context.Context, cfg PlexConfig, mux *http.ServeMux, events chan<- Event, logger *slog.Logger) error {
+	token, err := readSecret(cfg.TokenFile)
+	if err != nil {
+		return fmt.Errorf("failed to read plex token: %w", err)
 	}
 
 	if mux == nil {
@@ -199,13 +341,45 @@ func setupPlexWebhook(serverUrl, tokenFile, machineID string, mux *http.ServeMux
 
 	// Configure Plexamp integration
 	plexConfig := PlexampConfig{
-		ServerUrl:         serverUrl,
+		ServerUrl:         cfg.ServerURL,
 		Token:             token,
-		MachineIdentifier: machineID,
+		MachineIdentifier: cfg.MachineID,
+		Client:            newPlexHTTPClient(cfg),
 	}
 
-	mux.HandleFunc("/webhooks/plex", handlePlexWebhook(plexConfig, events, logger))
-	logger.Info("Plex webhook enabled", "server", serverUrl, "machine_id", machineID, "endpoint", "/webhooks/plex")
+	pool := newPlexLookupPool(plexConfig, events, logger)
+	go pool.Run(ctx)
 
+	mux.HandleFunc("/webhooks/plex", handlePlexWebhook(pool, logger))
+	logger.Info("Plex webhook enabled", "server", cfg.ServerURL, "machine_id", cfg.MachineID, "endpoint", "/webhooks/plex")
+
+	return nil
+}
+
+// loudness returns the track's Plex loudness analysis (nil if not analyzed).
+func (t PlexTrack) loudness() *TrackLoudness {
+	parseDB := func(s string) *float64 {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil
+		}
+		return &v
+	}
+	for _, m := range t.Media {
+		for _, p := range m.Parts {
+			for _, st := range p.Streams {
+				if st.StreamType != 2 || st.Gain == "" {
+					continue
+				}
+				l := &TrackLoudness{TrackGainDB: parseDB(st.Gain), AlbumGainDB: parseDB(st.AlbumGain)}
+				l.TrackPeak, _ = strconv.ParseFloat(st.Peak, 64)
+				l.AlbumPeak, _ = strconv.ParseFloat(st.AlbumPeak, 64)
+				if l.TrackGainDB == nil {
+					return nil
+				}
+				return l
+			}
+		}
+	}
 	return nil
 }
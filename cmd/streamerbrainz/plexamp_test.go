@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const plexTestSessionsXML = `<MediaContainer size="1"><Track title="Song"><Player machineIdentifier="amp-1" state="playing"/></Track></MediaContainer>`
+
+func TestFetchPlexSessions_TimesOutOnHungServer(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	cfg := PlexampConfig{ServerUrl: srv.URL, Token: "tok", Client: newPlexHTTPClient(PlexConfig{TimeoutMS: 50})}
+
+	start := time.Now()
+	if _, err := fetchPlexSessions(context.Background(), cfg, slog.Default()); err == nil {
+		t.Fatalf("expected timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("request not bounded by timeout: took %v", elapsed)
+	}
+
+	// Canceling the context (daemon shutdown) aborts a pending request too.
+	cfg.Client = newPlexHTTPClient(PlexConfig{TimeoutMS: 10000})
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start = time.Now()
+	if _, err := fetchPlexSessions(ctx, cfg, slog.Default()); err == nil {
+		t.Fatalf("expected cancellation error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("request not canceled with context: took %v", elapsed)
+	}
+}
+
+func TestNewPlexHTTPClient_InsecureSkipVerify(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(plexTestSessionsXML))
+	}))
+	defer srv.Close()
+
+	cfg := PlexampConfig{ServerUrl: srv.URL, Token: "tok", Client: newPlexHTTPClient(PlexConfig{})}
+	if _, err := fetchPlexSessions(context.Background(), cfg, slog.Default()); err == nil {
+		t.Fatalf("expected self-signed certificate to be rejected by default")
+	}
+
+	cfg.Client = newPlexHTTPClient(PlexConfig{InsecureSkipVerify: true})
+	container, err := fetchPlexSessions(context.Background(), cfg, slog.Default())
+	if err != nil {
+		t.Fatalf("fetchPlexSessions: %v", err)
+	}
+	if track := findTrackByMachineIdentifier(container, "amp-1"); track == nil || track.Title != "Song" {
+		t.Fatalf("unexpected sessions %#v", container)
+	}
+}
+
+func TestPlexWebhook_BoundsConcurrentLookups(t *testing.T) {
+	var inFlight, maxInFlight, total atomic.Int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		total.Add(1)
+		n := inFlight.Add(1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		<-release
+		inFlight.Add(-1)
+		w.Write([]byte(plexTestSessionsXML))
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan Event, 16)
+	pool := newPlexLookupPool(PlexampConfig{ServerUrl: srv.URL, Token: "tok", MachineIdentifier: "amp-1"}, events, slog.Default())
+	done := make(chan struct{})
+	go func() {
+		pool.Run(ctx)
+		close(done)
+	}()
+
+	handler := handlePlexWebhook(pool, slog.Default())
+	for i := 0; i < 20; i++ {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/webhooks/plex", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("webhook status %d", rec.Code)
+		}
+	}
+
+	deadline := time.After(time.Second)
+	for inFlight.Load() < plexLookupWorkers {
+		select {
+		case <-deadline:
+			t.Fatalf("workers did not start lookups")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	close(release)
+
+	select {
+	case ev := <-events:
+		if st, ok := ev.(PlexStateChanged); !ok || st.Title != "Song" {
+			t.Fatalf("unexpected event %#v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timeout waiting for Plex event")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("pool did not stop on cancel")
+	}
+
+	if got := maxInFlight.Load(); got > plexLookupWorkers {
+		t.Fatalf("max concurrent lookups %d exceeds %d workers", got, plexLookupWorkers)
+	}
+	if got := total.Load(); got > plexLookupWorkers+plexLookupQueueSize {
+		t.Fatalf("%d lookups for 20 webhooks; expected at most %d", got, plexLookupWorkers+plexLookupQueueSize)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"math"
+	"strconv"
 	"time"
 )
 
@@ -55,12 +56,14 @@ func (Tick) eventMarker() {}
 // CamillaVolumeObserved is emitted after a successful GetVolume/SetVolume (or any API returning volume).
 type CamillaVolumeObserved struct {
 	VolumeDB float64
-	At       time.Time
+	// Confirmed marks the confirmation of a SetVolume (the value sent) rather than a reading.
+	Confirmed bool
+	At        time.Time
 }
 
 func (CamillaVolumeObserved) eventMarker() {}
 
-// CamillaMuteObserved is emitted after a successful GetMute/SetMute/ToggleMute.
+// CamillaMuteObserved is emitted after a successful GetMute/SetMute.
 type CamillaMuteObserved struct {
 	Muted bool
 	At    time.Time
@@ -84,6 +87,14 @@ type CamillaProcessingStateObserved struct {
 
 func (CamillaProcessingStateObserved) eventMarker() {}
 
+// OutputSelected is emitted after a CmdSelectOutput sequence completes successfully.
+type OutputSelected struct {
+	Output string
+	At     time.Time
+}
+
+func (OutputSelected) eventMarker() {}
+
 // CamillaCommandFailed is emitted when executing a Command fails.
 type CamillaCommandFailed struct {
 	Command Command
@@ -97,6 +108,137 @@ func (CamillaCommandFailed) eventMarker() {}
 // Reducer helpers
 // ==============================
 
+// reduceTick advances the hold/velocity controller and flushes intents into Commands.
+// cfg is the unlimited config; user limits are applied after expiring any limit override.
+func reduceTick(s *DaemonState, ev Tick, cfg VelocityConfig, rotaryCfg RotaryConfig, policy ReducerPolicy) (cmds []Command, broadcasts []StateBroadcast) {
+	if !s.LimitOverrideUntil.IsZero() && !ev.Now.Before(s.LimitOverrideUntil) {
+		broadcasts = append(broadcasts, endLimitOverride(s, ev.Now, cfg, policy)...)
+	}
+	cfg = limitedConfig(s, cfg, policy)
+
+	// Fail acknowledgements CamillaDSP never confirmed.
+	cmds = append(cmds, expireAcks(s, ev.Now)...)
+
+	// Baseline for integration (highest priority wins):
+	//  1) current desired intent (if any)
+	//  2) observed CamillaDSP volume (if known)
+	//  3) controller target (fallback; also used while ramping so sub-threshold steps accumulate)
+	baseline := s.VolumeCtrl.TargetDB
+	if s.Intent.DesiredVolume != nil || !s.VolumeCtrl.Ramping {
+		baseline = s.currentVolumeDB()
+	}
+
+	// Always advance controller so hold-timeout and decay run consistently.
+	ramping := s.VolumeCtrl.Ramping
+	nextCtrl := StepVolumeController(s.VolumeCtrl, baseline, ev.Dt, ev.Now, cfg)
+	broadcasts = append(broadcasts, applyHoldCheckpoint(&nextCtrl, ev.Now)...)
+	s.VolumeCtrl = nextCtrl
+	if nextCtrl.HeldDirection != 0 || ramping {
+		s.SetDesiredVolume(nextCtrl.TargetDB)
+		// Still moving on behalf of the intent that started the hold/ramp.
+		s.VolumeOrigin.At = ev.Now
+	}
+
+	// Encoder modes revert to volume after a period of inactivity.
+	if s.encoderMode() != EncoderModeVolume && rotaryCfg.ModeTimeoutMS > 0 && !s.Rotary.ModeAt.IsZero() &&
+		ev.Now.Sub(s.Rotary.ModeAt) > time.Duration(rotaryCfg.ModeTimeoutMS)*time.Millisecond {
+		s.Rotary.Mode = EncoderModeVolume
+		broadcasts = append(broadcasts, encoderBroadcast(s, ev.Now))
+	}
+	if s.Intent.BalancePending {
+		s.Intent.BalancePending = false
+		if len(rotaryCfg.BalanceFaders) == 2 {
+			l, r := balanceFaderLevels(s.Rotary.BalanceDB)
+			cmds = append(cmds,
+				CmdSetFaderVolume{Fader: rotaryCfg.BalanceFaders[0], TargetDB: l},
+				CmdSetFaderVolume{Fader: rotaryCfg.BalanceFaders[1], TargetDB: r},
+			)
+		}
+	}
+	if s.Intent.SubPending {
+		s.Intent.SubPending = false
+		if rotaryCfg.SubFader > 0 {
+			cmds = append(cmds, CmdSetFaderVolume{Fader: rotaryCfg.SubFader, TargetDB: s.Rotary.SubDB})
+		}
+	}
+	if s.Intent.NormalizationPending {
+		s.Intent.NormalizationPending = false
+		if policy.Normalization.Enabled {
+			cmds = append(cmds, CmdSetFaderVolume{Fader: policy.Normalization.Fader, TargetDB: s.NormalizationDB})
+		}
+	}
+
+	// End a test signal once its time is up.
+	if s.TestSignal.Channel != "" && !s.TestSignal.Pending && !ev.Now.Before(s.TestSignal.Until) {
+		cmds = append(cmds, stopTestSignal(s)...)
+	}
+
+	// A volume or mute change while CamillaDSP is unreachable wakes its host.
+	cmds = append(cmds, wakeDSP(s, ev.Now, policy.WakeOnLAN)...)
+
+	// While CamillaDSP is unreachable, probe it periodically so recovery is noticed
+	// even when nothing else is being sent.
+	if s.Camilla.Unreachable && ev.Now.Sub(s.Camilla.ProbeAt) >= probeInterval(s, ev.Now, policy.WakeOnLAN) {
+		s.Camilla.ProbeAt = ev.Now
+		cmds = append(cmds, CmdGetVolume{})
+	}
+
+	// Retry a GetVolume that timed out (with backoff; see camilladsp_errors.go).
+	cmds = append(cmds, volumeRetry(s, ev.Now)...)
+
+	// Read the volume back now and then while moving, to notice external changes.
+	cmds = append(cmds, externalCheck(s, ev.Now, cfg)...)
+
+	// Flush intents into Commands (coalesced latest-wins).
+	// An unmute is flushed after the volume so a restored level is in place before audio returns.
+	var unmute bool
+	if s.Intent.DesiredMute != nil {
+		m := *s.Intent.DesiredMute
+		s.Intent.DesiredMute = nil
+		s.Camilla.MuteSent, s.Camilla.MuteSentAt = &m, ev.Now
+		if m {
+			cmds = append(cmds, CmdSetMute{Muted: true})
+		} else {
+			unmute = true
+		}
+	}
+	if s.Intent.DesiredVolume != nil {
+		v := quantizeVolumeMB(*s.Intent.DesiredVolume, cfg, policy)
+		s.Intent.DesiredVolume = nil
+
+		// Policy: avoid unnecessary SetVolume commands when we're already close to observed state.
+		// Observed state is authoritative (CamillaDSP), so threshold against it when known.
+		// If volume is unknown, emit the command so we converge quickly.
+		if !s.Camilla.VolumeKnown || absMB(v-s.Camilla.VolumeMB) >= volumeUpdateThresholdMB {
+			cmds = append(cmds, CmdSetVolume{TargetDB: v.DB()})
+		}
+	}
+	if unmute {
+		cmds = append(cmds, CmdSetMute{Muted: false})
+	}
+	return cmds, broadcasts
+}
+
+// camillaObservedAt returns the timestamp of an event confirming a successful
+// CamillaDSP round trip.
+func camillaObservedAt(e Event) (time.Time, bool) {
+	switch ev := e.(type) {
+	case CamillaVolumeObserved:
+		return ev.At, true
+	case CamillaMuteObserved:
+		return ev.At, true
+	case CamillaConfigFilePathObserved:
+		return ev.At, true
+	case CamillaProcessingStateObserved:
+		return ev.At, true
+	case OutputSelected:
+		return ev.At, true
+	case TestSignalApplied:
+		return ev.At, true
+	}
+	return time.Time{}, false
+}
+
 func clampVolumeDB(v float64, cfg VelocityConfig) float64 {
 	if v < cfg.MinDB {
 		return cfg.MinDB
@@ -107,6 +249,222 @@ func clampVolumeDB(v float64, cfg VelocityConfig) float64 {
 	return v
 }
 
+// currentVolumeDB is the level a relative change starts from: the pending
+// intent, else the observed volume, else the controller target.
+//
+// The controller target is used while it rounds to that level, so sub-millibel
+// movement (hi-res wheel units, the start of a hold) accumulates instead of being
+// rounded away on every event.
+func (s *DaemonState) currentVolumeDB() float64 {
+	var mb Millibel
+	switch {
+	case s.Intent.DesiredVolume != nil:
+		mb = *s.Intent.DesiredVolume
+	case s.Camilla.VolumeKnown:
+		mb = s.Camilla.VolumeMB
+	default:
+		return s.VolumeCtrl.TargetDB
+	}
+	if mbFromDB(s.VolumeCtrl.TargetDB) == mb {
+		return s.VolumeCtrl.TargetDB
+	}
+	return mb.DB()
+}
+
+// displayVolumeMB rounds v for broadcasts and snapshots.
+func displayVolumeMB(v Millibel, policy ReducerPolicy) Millibel {
+	step := policy.DisplayStepDB
+	if step <= 0 {
+		step = defaultDisplayStepDB
+	}
+	return v.roundTo(mbFromDB(step))
+}
+
+// quantizeVolumeMB rounds v to policy.StepDB, moving one step inward if rounding
+// crossed one of cfg's volume bounds.
+func quantizeVolumeMB(v Millibel, cfg VelocityConfig, policy ReducerPolicy) Millibel {
+	step := mbFromDB(policy.StepDB)
+	if step <= 1 {
+		return v
+	}
+	q := v.roundTo(step)
+	if maxMB := mbFromDB(cfg.MaxDB); q > maxMB {
+		q = maxMB.floorTo(step)
+	}
+	if minMB := mbFromDB(cfg.MinDB); q < minMB {
+		q = minMB.ceilTo(step)
+	}
+	return q
+}
+
+// applyMutedGesture applies the muted volume-gesture policy for a gesture in the given
+// direction. It returns true if the gesture was consumed (auto-unmute or ignored) and
+// must not change the volume.
+func applyMutedGesture(s *DaemonState, direction int, cfg VelocityConfig, policy ReducerPolicy) bool {
+	if !s.Camilla.MuteKnown || !s.Camilla.Muted {
+		return false
+	}
+	switch {
+	case direction > 0 && policy.UnmuteOnVolumeUp:
+		s.SetDesiredMute(false)
+		if policy.UnmuteRestoreVolume && s.PreMuteVolumeDB != nil {
+			v := clampVolumeDB(*s.PreMuteVolumeDB, cfg)
+			s.SetDesiredVolume(v)
+			s.VolumeCtrl.TargetDB = v
+		}
+		return true
+	case direction < 0 && policy.IgnoreVolumeDownWhileMuted:
+		return true
+	}
+	return false
+}
+
+// rotaryCurveDbPerStep interpolates the rotary acceleration curve at the given detent rate.
+// Rates outside the curve clamp to its first/last point.
+func rotaryCurveDbPerStep(curve []RotaryCurvePoint, rate float64) float64 {
+	if rate <= curve[0].Rate {
+		return curve[0].DbPerStep
+	}
+	for i := 1; i < len(curve); i++ {
+		p0, p1 := curve[i-1], curve[i]
+		if rate <= p1.Rate {
+			f := (rate - p0.Rate) / (p1.Rate - p0.Rate)
+			return p0.DbPerStep + f*(p1.DbPerStep-p0.DbPerStep)
+		}
+	}
+	return curve[len(curve)-1].DbPerStep
+}
+
+// reduceRotary applies a rotary movement of detents (possibly fractional, from hi-res
+// wheels) to the active encoder mode. whole is the number of complete detents to record
+// for velocity detection.
+func reduceRotary(s *DaemonState, detents float64, whole int, at time.Time, cfg VelocityConfig, rotaryCfg RotaryConfig, policy ReducerPolicy) []StateBroadcast {
+	// Rotary input cancels holds and any ongoing controller motion.
+	s.VolumeCtrl.HeldDirection = 0
+	s.VolumeCtrl.VelocityDBPerS = 0
+	s.VolumeCtrl.HoldBeganAt = time.Time{}
+	s.VolumeCtrl.Ramping = false
+
+	if detents == 0 {
+		return nil
+	}
+	direction := 1
+	if detents < 0 {
+		direction = -1
+	}
+
+	// Non-volume encoder modes adjust balance/sub level with plain per-detent steps.
+	if mode := s.encoderMode(); mode != EncoderModeVolume {
+		dbPerStep := rotaryCfg.DbPerStep
+		if dbPerStep == 0 {
+			dbPerStep = defaultRotaryDbPerStep
+		}
+		delta := detents * dbPerStep
+		switch mode {
+		case EncoderModeBalance:
+			s.Rotary.BalanceDB = math.Max(-balanceMaxDB, math.Min(balanceMaxDB, s.Rotary.BalanceDB+delta))
+			s.Intent.BalancePending = true
+		case EncoderModeSub:
+			s.Rotary.SubDB = math.Max(subLevelMinDB, math.Min(subLevelMaxDB, s.Rotary.SubDB+delta))
+			s.Intent.SubPending = true
+		}
+		s.Rotary.ModeAt = at
+		return []StateBroadcast{encoderBroadcast(s, at)}
+	}
+
+	if applyMutedGesture(s, direction, cfg, policy) {
+		return nil
+	}
+
+	// Track recent rotary steps in reducer-owned state for velocity detection.
+	// Requires a timestamp from TimedEvent (assigned by the daemon).
+	now := at
+	if now.IsZero() {
+		// Without a timestamp we can't do windowed velocity detection deterministically.
+		return nil
+	}
+
+	// Prune samples outside the velocity window.
+	windowMS := rotaryCfg.VelocityWindowMS
+	cutoff := now.Add(-time.Duration(windowMS) * time.Millisecond)
+	kept := s.Rotary.RecentSteps[:0]
+	for _, st := range s.Rotary.RecentSteps {
+		if st.At.After(cutoff) {
+			kept = append(kept, st)
+		}
+	}
+	s.Rotary.RecentSteps = kept
+
+	// Add each detent as a separate sample so velocity detection is consistent.
+	stepsAbs := whole
+	if stepsAbs < 0 {
+		stepsAbs = -stepsAbs
+	}
+	for i := 0; i < stepsAbs; i++ {
+		s.Rotary.RecentSteps = append(s.Rotary.RecentSteps, RotaryReducerStep{
+			At:        now,
+			Direction: direction,
+		})
+	}
+
+	// Count recent steps in the same direction (including the new ones we just appended).
+	recentCount := 0
+	for i := len(s.Rotary.RecentSteps) - 1; i >= 0; i-- {
+		if s.Rotary.RecentSteps[i].Direction != direction {
+			continue
+		}
+		if !s.Rotary.RecentSteps[i].At.After(cutoff) {
+			continue
+		}
+		recentCount++
+	}
+
+	// Determine effective step size: continuous curve over detent rate if configured,
+	// otherwise the legacy threshold/multiplier scheme.
+	var dbPerStep float64
+	if len(rotaryCfg.Curve) > 0 && windowMS > 0 {
+		// The current detent opens the window, so an isolated detent has rate 0.
+		rate := float64(max(recentCount-1, 0)) / (float64(windowMS) / 1000.0)
+		dbPerStep = rotaryCurveDbPerStep(rotaryCfg.Curve, rate)
+	} else {
+		dbPerStep = rotaryCfg.DbPerStep
+		if dbPerStep == 0 {
+			dbPerStep = defaultRotaryDbPerStep
+		}
+		if recentCount >= rotaryCfg.VelocityThreshold {
+			dbPerStep *= rotaryCfg.VelocityMultiplier
+		}
+	}
+
+	// Apply step against baseline (desired > observed > controller target).
+	current := s.currentVolumeDB()
+
+	deltaDB := detents * dbPerStep
+	next := clampVolumeDB(dangerRotaryTarget(current, deltaDB, detents, cfg), cfg)
+
+	s.SetDesiredVolume(next)
+	s.VolumeCtrl.TargetDB = next
+	return nil
+}
+
+// encoderBroadcast builds the encoder state broadcast.
+func encoderBroadcast(s *DaemonState, at time.Time) BroadcastEncoderChanged {
+	return BroadcastEncoderChanged{
+		Mode:      s.encoderMode(),
+		BalanceDB: s.Rotary.BalanceDB,
+		SubDB:     s.Rotary.SubDB,
+		At:        at,
+	}
+}
+
+// balanceFaderLevels maps a balance (negative = left) to [left, right] fader attenuation.
+func balanceFaderLevels(balanceDB float64) (left, right float64) {
+	if balanceDB > 0 {
+		return -balanceDB, 0
+	}
+	return 0, balanceDB
+}
+
 // ==============================
 // Reducer output
 // ==============================
@@ -118,14 +476,70 @@ func clampVolumeDB(v float64, cfg VelocityConfig) float64 {
 // NOTE: We'll extend this over time (e.g., players status) without exposing the
 // full internal DaemonState structure.
 type StateSnapshot struct {
+	// Zone is the zone this snapshot describes.
+	Zone string `json:"zone,omitempty"`
+
 	// CamillaDSP (authoritative backend for volume/mute)
 	VolumeDB    float64   `json:"volume_db"`
 	VolumeKnown bool      `json:"volume_known"`
 	VolumeAt    time.Time `json:"volume_at"`
 
+	// VolumePercent is VolumeDB as a percentage of the volume range (see volume_percent.go).
+	VolumePercent float64 `json:"volume_percent"`
+
 	Muted     bool      `json:"muted"`
 	MuteKnown bool      `json:"mute_known"`
 	MuteAt    time.Time `json:"mute_at"`
+
+	// DSPUnreachable is set while CamillaDSP isn't answering.
+	DSPUnreachable bool `json:"dsp_unreachable,omitempty"`
+
+	// PowerState is "on", "waking" or "off" with Wake-on-LAN configured (see wake_on_lan.go).
+	PowerState string `json:"power_state,omitempty"`
+
+	// Output is the active output id (empty if outputs aren't configured/selected).
+	Output string `json:"output,omitempty"`
+
+	// Encoder mode and the levels it adjusts.
+	EncoderMode EncoderMode `json:"encoder_mode"`
+	BalanceDB   float64     `json:"balance_db"`
+	SubDB       float64     `json:"sub_db"`
+
+	// Calibration is true while calibration mode pins the volume.
+	Calibration bool `json:"calibration,omitempty"`
+
+	// Hold/velocity controller motion, omitted while idle (see controller_feedback.go).
+	HeldDirection  int      `json:"held_direction,omitempty"`
+	Ramping        bool     `json:"ramping,omitempty"`
+	VelocityDBPerS float64  `json:"velocity_db_per_s,omitempty"`
+	TargetDB       *float64 `json:"target_db,omitempty"`
+
+	// Player is the most recently active player (nil until a player reports state).
+	Player *PlayerSnapshot `json:"player,omitempty"`
+
+	// NormalizationDB is the per-track loudness offset in effect (see normalization.go).
+	NormalizationDB float64 `json:"normalization_db,omitempty"`
+
+	// Zones holds per-zone snapshots when multiple zones are configured.
+	// Only set on the aggregated snapshot produced by the zone router.
+	Zones []StateSnapshot `json:"zones,omitempty"`
+
+	// LinkedZones maps linked zone ids to their offsets (dB). Aggregated snapshot only.
+	LinkedZones map[string]float64 `json:"linked_zones,omitempty"`
+
+	// Inputs reports each input device's status. Aggregated snapshot only.
+	Inputs []InputDeviceStatus `json:"inputs,omitempty"`
+}
+
+// PlayerSnapshot is the externally visible player state.
+type PlayerSnapshot struct {
+	Source     string    `json:"source"`
+	State      string    `json:"state"`
+	Title      string    `json:"title,omitempty"`
+	Artist     string    `json:"artist,omitempty"`
+	Album      string    `json:"album,omitempty"`
+	DurationMs int64     `json:"duration_ms,omitempty"`
+	At         time.Time `json:"at"`
 }
 
 // StateBroadcast is a reducer-emitted broadcast event intended for external consumers
@@ -136,8 +550,14 @@ type StateBroadcast interface {
 
 // BroadcastVolumeChanged is emitted when observed volume changes.
 type BroadcastVolumeChanged struct {
-	VolumeDB float64   `json:"volume_db"`
-	At       time.Time `json:"at"`
+	VolumeDB float64 `json:"volume_db"`
+	// Origin names who/what caused the change ("ir", "ipc:argon-ctl", "external", ...);
+	// empty when the value was merely (re)learned.
+	Origin string `json:"origin,omitempty"`
+	// ExternalChange is set when the change interrupted a hold or ramp (see
+	// external_change.go).
+	ExternalChange bool      `json:"external_change,omitempty"`
+	At             time.Time `json:"at"`
 }
 
 func (BroadcastVolumeChanged) stateBroadcastMarker() {}
@@ -150,6 +570,47 @@ type BroadcastMuteChanged struct {
 
 func (BroadcastMuteChanged) stateBroadcastMarker() {}
 
+// BroadcastPlayerChanged is emitted when the active player/source or its playback state changes.
+type BroadcastPlayerChanged struct {
+	Source     string    `json:"source"`
+	State      string    `json:"state"`
+	Title      string    `json:"title,omitempty"`
+	Artist     string    `json:"artist,omitempty"`
+	Album      string    `json:"album,omitempty"`
+	DurationMs int64     `json:"duration_ms,omitempty"`
+	At         time.Time `json:"at"`
+}
+
+func (BroadcastPlayerChanged) stateBroadcastMarker() {}
+
+// BroadcastOutputChanged is emitted when the active output changes.
+type BroadcastOutputChanged struct {
+	Output string    `json:"output"`
+	At     time.Time `json:"at"`
+}
+
+func (BroadcastOutputChanged) stateBroadcastMarker() {}
+
+// BroadcastEncoderChanged is emitted when the encoder mode or a balance/sub level changes.
+type BroadcastEncoderChanged struct {
+	Mode      EncoderMode `json:"mode"`
+	BalanceDB float64     `json:"balance_db"`
+	SubDB     float64     `json:"sub_db"`
+	At        time.Time   `json:"at"`
+}
+
+func (BroadcastEncoderChanged) stateBroadcastMarker() {}
+
+// BroadcastDSPConnectionChanged is emitted when CamillaDSP stops answering commands
+// (Connected=false, with the failing error) and when it answers again.
+type BroadcastDSPConnectionChanged struct {
+	Connected bool      `json:"connected"`
+	Error     string    `json:"error,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+func (BroadcastDSPConnectionChanged) stateBroadcastMarker() {}
+
 // RequestStateSnapshot asks the reducer to produce a snapshot for an external consumer.
 // The reply channel is carried through a Command so delivery happens in the effects layer
 // (no side effects in the reducer).
@@ -171,20 +632,55 @@ type ReduceResult struct {
 
 // Reduce is the pure reducer.
 // It computes the next state and a list of Commands for the daemon loop to execute.
-func Reduce(s *DaemonState, e Event, cfg VelocityConfig, rotaryCfg RotaryConfig) ReduceResult {
+func Reduce(s *DaemonState, e Event, cfg VelocityConfig, rotaryCfg RotaryConfig, policy ReducerPolicy) ReduceResult {
 	if s == nil {
 		s = &DaemonState{}
 	}
 
 	// Unwrap timing if present. The reducer never consults wall clock.
+	// The daemon loop passes pooled *TimedEvent wrappers; the reducer must not retain them.
 	var at time.Time
-	if te, ok := e.(TimedEvent); ok {
+	switch te := e.(type) {
+	case TimedEvent:
+		at = te.At
+		e = te.Event
+	case *TimedEvent:
 		at = te.At
 		e = te.Event
 	}
+	// Acknowledged events are answered here or once CamillaDSP confirms (see ack.go).
+	e, reply := splitAck(e)
+
+	// Runtime tuning replaces the startup velocity/rotary settings.
+	cfg, rotaryCfg = tunedConfig(s, cfg, rotaryCfg)
+
+	// Every source is held to the user limits; full keeps the protocol bounds for
+	// the limit override itself.
+	full := cfg
+	cfg = limitedConfig(s, cfg, policy)
+
+	// A percentage is an absolute set within the (limited) volume range.
+	if p, ok := e.(SetVolumePercent); ok {
+		e = p.absolute(cfg)
+	}
+	var requestedDB *float64
+	if a, ok := e.(SetVolumeAbsolute); ok {
+		requestedDB = &a.Db
+	}
+
+	if volumeLocked(s, e) {
+		return ReduceResult{State: s, Commands: ackCmd(reply, EventAck{Err: "volume is locked (calibration or test signal)"})}
+	}
+	if origin, ok := volumeEventOrigin(e); ok {
+		if b, rejected := arbitrate(s, origin, at, policy); rejected {
+			return ReduceResult{State: s, Commands: ackCmd(reply, rejectedAck(b)), Broadcasts: []StateBroadcast{b}}
+		}
+		s.noteVolumeOrigin(origin, at)
+	}
 
 	var cmds []Command
 	var broadcasts []StateBroadcast
+	prevCtrl := s.VolumeCtrl
 
 	switch ev := e.(type) {
 	case DaemonStarted:
@@ -196,133 +692,54 @@ func Reduce(s *DaemonState, e Event, cfg VelocityConfig, rotaryCfg RotaryConfig)
 			CmdGetConfigFilePath{},
 			CmdGetState{},
 		)
+		// Start from no offset, whatever a previous run left on the fader.
+		s.Intent.NormalizationPending = policy.Normalization.Enabled
+		s.FadeInPending = policy.FadeIn > 0
+
+	case ResyncState:
+		// Same reads as the bootstrap; the observations are broadcast even if unchanged.
+		s.Camilla.ResyncVolume = true
+		s.Camilla.ResyncMute = true
+		cmds = append(cmds,
+			CmdGetVolume{},
+			CmdGetMute{},
+			CmdGetConfigFilePath{},
+			CmdGetState{},
+		)
 
 	case Tick:
-		// Tick advances the hold/velocity controller and flushes intents into Commands.
-
-		// Baseline for integration (highest priority wins):
-		//  1) current desired intent (if any)
-		//  2) observed CamillaDSP volume (if known)
-		//  3) controller target (fallback)
-		baseline := s.VolumeCtrl.TargetDB
-		if s.Camilla.VolumeKnown {
-			baseline = s.Camilla.VolumeDB
-		}
-		if s.Intent.DesiredVolumeDB != nil {
-			baseline = *s.Intent.DesiredVolumeDB
-		}
-
-		// Always advance controller so hold-timeout and decay run consistently.
-		nextCtrl := StepVolumeController(s.VolumeCtrl, baseline, ev.Dt, ev.Now, cfg)
-		s.VolumeCtrl = nextCtrl
-		if nextCtrl.HeldDirection != 0 {
-			s.SetDesiredVolume(nextCtrl.TargetDB)
-		}
-
-		// Flush intents into Commands (coalesced latest-wins).
-		if s.Intent.MuteTogglePending {
-			s.Intent.MuteTogglePending = false
-			cmds = append(cmds, CmdToggleMute{})
-		}
-		if s.Intent.DesiredMute != nil {
-			m := *s.Intent.DesiredMute
-			s.Intent.DesiredMute = nil
-			cmds = append(cmds, CmdSetMute{Muted: m})
-		}
-		if s.Intent.DesiredVolumeDB != nil {
-			v := *s.Intent.DesiredVolumeDB
-			s.Intent.DesiredVolumeDB = nil
-
-			// Policy: avoid unnecessary SetVolume commands when we're already close to observed state.
-			// Observed state is authoritative (CamillaDSP), so threshold against it when known.
-			// If volume is unknown, emit the command so we converge quickly.
-			if !s.Camilla.VolumeKnown || math.Abs(v-s.Camilla.VolumeDB) >= volumeUpdateThresholdDB {
-				cmds = append(cmds, CmdSetVolume{TargetDB: v})
-			}
-		}
-
-	case RotaryTurn:
-		// Rotary input cancels holds and any ongoing controller motion.
-		s.VolumeCtrl.HeldDirection = 0
-		s.VolumeCtrl.VelocityDBPerS = 0
-		s.VolumeCtrl.HoldBeganAt = time.Time{}
-
-		steps := ev.Steps
-		if steps == 0 {
-			break
-		}
-
-		// Track recent rotary steps in reducer-owned state for velocity detection.
-		// Requires a timestamp from TimedEvent (assigned by the daemon).
-		now := at
-		if now.IsZero() {
-			// Without a timestamp we can't do windowed velocity detection deterministically.
-			break
-		}
+		cmds, broadcasts = reduceTick(s, ev, full, rotaryCfg, policy)
 
-		direction := 1
-		if steps < 0 {
-			direction = -1
-		}
+	case *Tick:
+		// Pointer form used by the daemon loop (reused per loop; avoids boxing a Tick per tick).
+		cmds, broadcasts = reduceTick(s, *ev, full, rotaryCfg, policy)
 
-		// Prune samples outside the velocity window.
-		windowMS := rotaryCfg.VelocityWindowMS
-		cutoff := now.Add(-time.Duration(windowMS) * time.Millisecond)
-		kept := s.Rotary.RecentSteps[:0]
-		for _, st := range s.Rotary.RecentSteps {
-			if st.At.After(cutoff) {
-				kept = append(kept, st)
-			}
-		}
-		s.Rotary.RecentSteps = kept
+	case LimitOverride:
+		broadcasts = reduceLimitOverride(s, ev, at, full, policy)
 
-		// Add each detent as a separate sample so velocity detection is consistent.
-		stepsAbs := steps
-		if stepsAbs < 0 {
-			stepsAbs = -stepsAbs
-		}
-		for i := 0; i < stepsAbs; i++ {
-			s.Rotary.RecentSteps = append(s.Rotary.RecentSteps, RotaryReducerStep{
-				At:        now,
-				Direction: direction,
-			})
-		}
+	case ConfigUpdated:
+		tuning := ev.clone()
+		s.Tuning = &tuning
 
-		// Count recent steps in the same direction (including the new ones we just appended).
-		recentCount := 0
-		for i := len(s.Rotary.RecentSteps) - 1; i >= 0; i-- {
-			if s.Rotary.RecentSteps[i].Direction != direction {
-				continue
-			}
-			if !s.Rotary.RecentSteps[i].At.After(cutoff) {
-				continue
-			}
-			recentCount++
-		}
+	case CalibrationMode:
+		broadcasts = reduceCalibration(s, ev, at, cfg, policy)
 
-		// Determine effective step size, with optional velocity multiplier.
-		dbPerStep := rotaryCfg.DbPerStep
-		if dbPerStep == 0 {
-			dbPerStep = defaultRotaryDbPerStep
-		}
-		if recentCount >= rotaryCfg.VelocityThreshold {
-			dbPerStep *= rotaryCfg.VelocityMultiplier
-		}
+	case TestSignal:
+		cmds, broadcasts = reduceTestSignal(s, ev, at, cfg, policy)
 
-		// Apply step against baseline (desired > observed > controller target).
-		current := s.VolumeCtrl.TargetDB
-		if s.Camilla.VolumeKnown {
-			current = s.Camilla.VolumeDB
-		}
-		if s.Intent.DesiredVolumeDB != nil {
-			current = *s.Intent.DesiredVolumeDB
-		}
+	case TestSignalApplied:
+		broadcasts = reduceTestSignalApplied(s, ev)
 
-		deltaDB := float64(steps) * dbPerStep
-		next := clampVolumeDB(current+deltaDB, cfg)
+	case RotaryTurn:
+		broadcasts = append(broadcasts, reduceRotary(s, float64(ev.Steps), ev.Steps, at, cfg, rotaryCfg, policy)...)
 
-		s.SetDesiredVolume(next)
-		s.VolumeCtrl.TargetDB = next
+	case RotaryTurnHiRes:
+		// Accumulate sub-detent units; whole detents feed velocity detection while
+		// the fractional movement is applied immediately for smooth control.
+		s.Rotary.HiResUnits += ev.Units
+		whole := s.Rotary.HiResUnits / hiResUnitsPerDetent
+		s.Rotary.HiResUnits -= whole * hiResUnitsPerDetent
+		broadcasts = append(broadcasts, reduceRotary(s, float64(ev.Units)/hiResUnitsPerDetent, whole, at, cfg, rotaryCfg, policy)...)
 
 	case VolumeStep:
 		// Explicit step delta (bypasses reducer-side velocity detection).
@@ -330,6 +747,11 @@ func Reduce(s *DaemonState, e Event, cfg VelocityConfig, rotaryCfg RotaryConfig)
 		s.VolumeCtrl.HeldDirection = 0
 		s.VolumeCtrl.VelocityDBPerS = 0
 		s.VolumeCtrl.HoldBeganAt = time.Time{}
+		s.VolumeCtrl.Ramping = false
+
+		if ev.Steps == 0 || applyMutedGesture(s, ev.Steps, cfg, policy) {
+			break
+		}
 
 		dbPerStep := ev.DbPerStep
 		if dbPerStep == 0 {
@@ -337,13 +759,7 @@ func Reduce(s *DaemonState, e Event, cfg VelocityConfig, rotaryCfg RotaryConfig)
 		}
 		deltaDB := float64(ev.Steps) * dbPerStep
 
-		current := s.VolumeCtrl.TargetDB
-		if s.Camilla.VolumeKnown {
-			current = s.Camilla.VolumeDB
-		}
-		if s.Intent.DesiredVolumeDB != nil {
-			current = *s.Intent.DesiredVolumeDB
-		}
+		current := s.currentVolumeDB()
 
 		next := clampVolumeDB(current+deltaDB, cfg)
 
@@ -358,9 +774,28 @@ func Reduce(s *DaemonState, e Event, cfg VelocityConfig, rotaryCfg RotaryConfig)
 			break
 		}
 
+		s.VolumeCtrl.HoldTimeout = min(time.Duration(ev.HoldTimeoutMS)*time.Millisecond, maxHoldTimeoutExtension)
+
+		// Repeats of a hold consumed by the muted-gesture policy are dropped until release
+		// (or until the hold times out, in case the release was missed).
+		if s.VolumeCtrl.SuppressedDirection != 0 {
+			if timeout := s.VolumeCtrl.holdTimeout(cfg); ev.Direction == s.VolumeCtrl.SuppressedDirection &&
+				(timeout == 0 || now.Sub(s.VolumeCtrl.LastHeldAt) <= timeout) {
+				s.VolumeCtrl.LastHeldAt = now
+				break
+			}
+			s.VolumeCtrl.SuppressedDirection = 0
+		}
+		if s.VolumeCtrl.HeldDirection == 0 && applyMutedGesture(s, ev.Direction, cfg, policy) {
+			s.VolumeCtrl.SuppressedDirection = ev.Direction
+			s.VolumeCtrl.LastHeldAt = now
+			break
+		}
+
 		// New gesture if transitioning from not-held to held, or reversing direction.
 		if s.VolumeCtrl.HeldDirection == 0 || (ev.Direction != 0 && ev.Direction != s.VolumeCtrl.HeldDirection) {
 			s.VolumeCtrl.HoldBeganAt = now
+			armHoldCheckpoint(s, ev.Direction, cfg)
 			// Reset velocity on direction change to keep response snappy.
 			if ev.Direction != s.VolumeCtrl.HeldDirection {
 				s.VolumeCtrl.VelocityDBPerS = 0
@@ -369,13 +804,32 @@ func Reduce(s *DaemonState, e Event, cfg VelocityConfig, rotaryCfg RotaryConfig)
 
 		s.VolumeCtrl.HeldDirection = ev.Direction
 		s.VolumeCtrl.LastHeldAt = now
+		s.VolumeCtrl.Ramping = false
 
 	case VolumeRelease:
 		s.VolumeCtrl.HeldDirection = 0
+		s.VolumeCtrl.SuppressedDirection = 0
 		s.VolumeCtrl.HoldBeganAt = time.Time{}
+		s.VolumeCtrl.HoldTimeout = 0
 
 	case ToggleMute:
-		s.RequestToggleMute()
+		s.RequestToggleMute(at)
+
+	case SetMute:
+		s.SetDesiredMute(ev.Muted)
+
+	case RotaryPress:
+		switch rotaryCfg.ButtonAction {
+		case "none":
+		case "mode":
+			s.Rotary.ModeAt = at
+			if next := s.nextEncoderMode(rotaryCfg); next != s.encoderMode() {
+				s.Rotary.Mode = next
+				broadcasts = append(broadcasts, encoderBroadcast(s, at))
+			}
+		default:
+			s.RequestToggleMute(at)
+		}
 
 	case SetVolumeAbsolute:
 		// Absolute set cancels holds/motion.
@@ -385,52 +839,233 @@ func Reduce(s *DaemonState, e Event, cfg VelocityConfig, rotaryCfg RotaryConfig)
 
 		next := clampVolumeDB(ev.Db, cfg)
 
+		// Optional fade: let Tick drive the controller toward the target.
+		// Without a known starting point there is nothing to fade from, so snap.
+		// Sets up into the danger zone may have to fade even without a ramp rate.
+		from := s.Camilla.VolumeMB.DB()
+		if v, ok := s.GetDesiredVolume(); ok {
+			from = v
+		}
+		rampTime := policy.RampDown
+		if next > from {
+			rampTime = policy.RampUp
+		}
+		if s.Camilla.VolumeKnown && (cfg.RampDBPerS > 0 || rampTime > 0 || s.VolumeCtrl.Ramping || dangerRampNeeded(from, next, cfg)) {
+			if !s.VolumeCtrl.Ramping {
+				start := s.Camilla.VolumeMB.DB()
+				if v, ok := s.ConsumeDesiredVolume(); ok {
+					start = v
+				}
+				s.VolumeCtrl.TargetDB = start
+			}
+			s.VolumeCtrl.Ramping = true
+			s.VolumeCtrl.RampTarget = mbFromDB(next)
+			s.VolumeCtrl.RampRateDBPerS = 0
+			if rampTime > 0 {
+				// camilladsp.ramp_up_ms/ramp_down_ms: cover the distance in that time.
+				s.VolumeCtrl.RampRateDBPerS = math.Abs(next-s.VolumeCtrl.TargetDB) / rampTime.Seconds()
+			}
+			break
+		}
+
+		s.VolumeCtrl.Ramping = false
 		s.SetDesiredVolume(next)
 		s.VolumeCtrl.TargetDB = next
 
 	case RequestStateSnapshot:
 		// Build a DTO snapshot from daemon-owned state (safe copy; no pointers exposed).
-		// Delivery to the requester happens via a Command (effects layer), keeping the reducer pure.
+		// Delivery to the requester happens via a Command (published by the daemon loop
+		// without going through the effects worker), keeping the reducer pure.
 		snap := StateSnapshot{
-			VolumeDB:    s.Camilla.VolumeDB,
+			Zone:        s.Zone,
+			VolumeDB:    displayVolumeMB(s.Camilla.VolumeMB, policy).DB(),
 			VolumeKnown: s.Camilla.VolumeKnown,
 			VolumeAt:    s.Camilla.VolumeAt,
 			Muted:       s.Camilla.Muted,
 			MuteKnown:   s.Camilla.MuteKnown,
 			MuteAt:      s.Camilla.MuteAt,
+			Output:      s.Output.Active,
+			EncoderMode: s.encoderMode(),
+			BalanceDB:   s.Rotary.BalanceDB,
+			SubDB:       s.Rotary.SubDB,
+			Calibration: s.Calibration.Active,
+
+			VolumePercent: volumePercent(s.Camilla.VolumeMB.DB(), cfg),
+
+			HeldDirection:  s.VolumeCtrl.HeldDirection,
+			Ramping:        s.VolumeCtrl.Ramping,
+			VelocityDBPerS: s.VolumeCtrl.VelocityDBPerS,
+			TargetDB:       s.VolumeCtrl.feedbackTarget(),
+
+			NormalizationDB: s.NormalizationDB,
+			DSPUnreachable:  s.Camilla.Unreachable,
+			PowerState:      powerState(s, at, policy.WakeOnLAN),
+		}
+		if p := s.Player; p.Source != "" {
+			snap.Player = &PlayerSnapshot{Source: p.Source, State: p.State, Title: p.Title, Artist: p.Artist, Album: p.Album, DurationMs: p.DurationMs, At: p.At}
 		}
 		cmds = append(cmds, CmdPublishStateSnapshot{
 			Snapshot: snap,
 			Reply:    ev.Reply,
 		})
 
+	case OutputSelect:
+		if len(s.Output.Outputs) == 0 || s.Output.Pending != "" {
+			// Nothing configured, or a switch is already in flight.
+			break
+		}
+		target, ok := s.nextOutput(ev.Output)
+		if !ok || target.ID == s.Output.Active {
+			break
+		}
+
+		// Remember the level used on the output we're leaving.
+		if s.Output.Active != "" && s.Camilla.VolumeKnown {
+			if s.Output.SavedVolumeDB == nil {
+				s.Output.SavedVolumeDB = make(map[string]float64)
+			}
+			s.Output.SavedVolumeDB[s.Output.Active] = s.Camilla.VolumeMB.DB()
+		}
+
+		// The switch owns mute/volume for its duration: cancel holds and pending intents.
+		s.VolumeCtrl.HeldDirection = 0
+		s.VolumeCtrl.VelocityDBPerS = 0
+		s.VolumeCtrl.HoldBeganAt = time.Time{}
+		s.VolumeCtrl.Ramping = false
+		s.ClearDesiredVolume()
+		s.Intent.DesiredMute = nil
+		s.Camilla.MuteSent = nil
+
+		cmd := CmdSelectOutput{
+			Output:     target.ID,
+			ConfigPath: target.ConfigPath,
+			// Preserve a user-selected mute across the switch.
+			Unmute: !(s.Camilla.MuteKnown && s.Camilla.Muted),
+		}
+		if v, ok := s.Output.SavedVolumeDB[target.ID]; ok {
+			v = clampVolumeDB(v, cfg)
+			cmd.VolumeDB = &v
+		} else if target.VolumeDB != nil {
+			v := clampVolumeDB(*target.VolumeDB, cfg)
+			cmd.VolumeDB = &v
+		}
+		if cmd.VolumeDB != nil {
+			s.VolumeCtrl.TargetDB = *cmd.VolumeDB
+		}
+
+		s.Output.Pending = target.ID
+		cmds = append(cmds, cmd)
+
+	case OutputSelected:
+		s.Output.Pending = ""
+		if s.Output.Active != ev.Output {
+			s.Output.Active = ev.Output
+			broadcasts = append(broadcasts, BroadcastOutputChanged{Output: ev.Output, At: ev.At})
+		}
+
+	case PlexStateChanged:
+		next := PlayerState{
+			Source: "plex",
+			State:  ev.State,
+			Title:  ev.Title,
+			Artist: ev.Artist,
+			Album:  ev.Album,
+
+			DurationMs: ev.DurationMs,
+			Loudness:   ev.Loudness,
+			At:         at,
+		}
+		if b, ok := s.setPlayer(next); ok {
+			broadcasts = append(broadcasts, b)
+		}
+
+	case TidalStateChanged:
+		next := PlayerState{
+			Source: "tidal",
+			State:  ev.State,
+			Title:  ev.Title,
+			Artist: ev.Artist,
+			Album:  ev.Album,
+
+			DurationMs: ev.DurationMs,
+			At:         at,
+		}
+		if b, ok := s.setPlayer(next); ok {
+			broadcasts = append(broadcasts, b)
+		}
+
+	case LibrespotPlaybackState:
+		next := s.Player
+		if next.Source != "librespot" {
+			next = PlayerState{Source: "librespot"}
+		}
+		next.State = ev.State
+		next.At = at
+		if b, ok := s.setPlayer(next); ok {
+			broadcasts = append(broadcasts, b)
+		}
+
+	case LibrespotTrackChanged:
+		next := s.Player
+		if next.Source != "librespot" {
+			next = PlayerState{Source: "librespot"}
+		}
+		next.Title = ev.Name
+		next.Artist = librespotArtists(ev.Artists)
+		next.Album = ev.Album
+		next.DurationMs, _ = strconv.ParseInt(ev.DurationMs, 10, 64)
+		next.Loudness = nil
+		next.At = at
+		if b, ok := s.setPlayer(next); ok {
+			broadcasts = append(broadcasts, b)
+		}
+
+	case TrackLoudness:
+		l := ev
+		s.Player.Loudness = &l
+
 	default:
 		// No-op for unhandled event types (e.g. media controls not wired yet).
 
 	case CamillaVolumeObserved:
+		s.Camilla.VolumeRetries, s.Camilla.VolumeRetryAt = 0, time.Time{}
 		prevKnown := s.Camilla.VolumeKnown
-		prevVolRounded := math.Round(s.Camilla.VolumeDB*10.0) / 10.0
+		prevVolRounded := displayVolumeMB(s.Camilla.VolumeMB, policy)
+		external := isExternalChange(s, ev, prevKnown, s.Camilla.VolumeMB.DB(), cfg)
+		if external {
+			yieldToExternalChange(s, ev.At)
+		}
 
-		// Store observed volume at full precision (daemon-owned truth).
-		// Round only for external broadcast emission to avoid noisy float jitter and reduce spam.
-		volRounded := math.Round(ev.VolumeDB*10.0) / 10.0
+		// Store observed volume at millibel precision (daemon-owned truth).
+		// Round to display_step_db only for external broadcast emission to reduce spam.
+		volRounded := displayVolumeMB(mbFromDB(ev.VolumeDB), policy)
 
 		s.SetObservedVolume(ev.VolumeDB, ev.At)
 
-		// Broadcast only when the rounded observed value changes (or becomes known).
-		// NOTE: Payload uses the rounded value (0.1 dB), while internal state remains full precision.
-		if !prevKnown || prevVolRounded != volRounded {
-			broadcasts = append(broadcasts, BroadcastVolumeChanged{
-				VolumeDB: volRounded,
-				At:       ev.At,
-			})
+		// Broadcast only when the rounded observed value changes (or becomes known, or a
+		// resync asked for it).
+		// NOTE: Payload uses the rounded value (display_step_db), while internal state remains full precision.
+		// Only actual changes are attributed; the first observation and resyncs have no origin.
+		if !prevKnown || prevVolRounded != volRounded || s.Camilla.ResyncVolume || external {
+			s.Camilla.ResyncVolume = false
+			b := BroadcastVolumeChanged{
+				VolumeDB:       volRounded.DB(),
+				ExternalChange: external,
+				At:             ev.At,
+			}
+			if (prevKnown && prevVolRounded != volRounded) || external {
+				b.Origin = s.observedVolumeOrigin(ev.At)
+			}
+			broadcasts = append(broadcasts, b)
+			broadcasts = append(broadcasts, volumeOverlayHint(s, b, policy)...)
 		}
 
-		// Keep controller position aligned with observed volume only if we are not currently holding.
+		// Keep controller position aligned with observed volume only if we are not currently holding
+		// or ramping (a ramp integrates from its own position to avoid stalling on lagging observations).
 		// If a hold is active, preserve controller dynamics (inertia/decay) and let Tick integration
 		// choose baseline from desired/observed as appropriate.
-		if s.VolumeCtrl.HeldDirection == 0 {
-			s.VolumeCtrl.TargetDB = ev.VolumeDB
+		if s.VolumeCtrl.HeldDirection == 0 && !s.VolumeCtrl.Ramping {
+			s.VolumeCtrl.TargetDB = s.Camilla.VolumeMB.DB()
 
 			// If we're effectively stopped, snap velocity to 0 to avoid tiny drift.
 			// Otherwise preserve VelocityDBPerS so accelerating-mode decay produces inertia naturally.
@@ -439,19 +1074,33 @@ func Reduce(s *DaemonState, e Event, cfg VelocityConfig, rotaryCfg RotaryConfig)
 				s.VolumeCtrl.VelocityDBPerS = 0
 			}
 		}
+		startFadeIn(s, at, cfg, policy)
 
 	case CamillaMuteObserved:
 		prevKnown := s.Camilla.MuteKnown
 		prevMuted := s.Camilla.Muted
 
+		// Remember the level at the moment mute engages (for restore-on-unmute).
+		if ev.Muted && (!prevKnown || !prevMuted) && s.Camilla.VolumeKnown {
+			v := s.Camilla.VolumeMB.DB()
+			s.PreMuteVolumeDB = &v
+		}
+
 		s.SetObservedMute(ev.Muted, ev.At)
+		if s.Camilla.MuteSent != nil && *s.Camilla.MuteSent == ev.Muted {
+			s.Camilla.MuteSent = nil
+		}
 
-		// Broadcast only on meaningful observed change.
-		if !prevKnown || prevMuted != ev.Muted {
+		// Broadcast only on meaningful observed change (or when a resync asked for it).
+		if !prevKnown || prevMuted != ev.Muted || s.Camilla.ResyncMute {
+			s.Camilla.ResyncMute = false
 			broadcasts = append(broadcasts, BroadcastMuteChanged{
 				Muted: ev.Muted,
 				At:    ev.At,
 			})
+			if prevKnown && prevMuted != ev.Muted {
+				broadcasts = append(broadcasts, muteFlashHint(ev.Muted, ev.At, policy)...)
+			}
 		}
 
 	case CamillaConfigFilePathObserved:
@@ -461,9 +1110,55 @@ func Reduce(s *DaemonState, e Event, cfg VelocityConfig, rotaryCfg RotaryConfig)
 		s.SetObservedProcessingState(ev.State, ev.At)
 
 	case CamillaCommandFailed:
-		// Keep state as-is. Future work could add backoff/retry/disconnected state.
-		_ = ev
+		// Keep observed state as-is; track reachability (probed again from Tick)
+		// and apply the per-class policy (see camilladsp_errors.go).
+		failed, unreachable := reduceCommandFailed(s, ev)
+		switch c := ev.Command.(type) {
+		case CmdSetMute:
+			// Not applied: toggles resolve against the observed state again.
+			s.Camilla.MuteSent = nil
+		case CmdSelectOutput:
+			// The switch aborted (left muted); allow another attempt.
+			s.Output.Pending = ""
+		case CmdTestSignal:
+			cmds, broadcasts = reduceTestSignalFailed(s, c, ev.Err, ev.At)
+		}
+		broadcasts = append(broadcasts, failed...)
+		if unreachable && !s.Camilla.Unreachable {
+			s.Camilla.Unreachable = true
+			s.Camilla.UnreachableSince = ev.At
+			s.Camilla.ProbeAt = ev.At
+			msg := ""
+			if ev.Err != nil {
+				msg = ev.Err.Error()
+			}
+			broadcasts = append(broadcasts, BroadcastDSPConnectionChanged{Connected: false, Error: msg, At: ev.At})
+		}
+	}
+
+	if policy.Normalization.Enabled {
+		updateNormalization(s, policy.Normalization)
+	}
+
+	// Any successful observation means CamillaDSP is answering again.
+	if obsAt, ok := camillaObservedAt(e); ok && s.Camilla.Unreachable {
+		s.Camilla.Unreachable = false
+		// CamillaDSP may have been restarted with its faders reset.
+		s.Intent.NormalizationPending = policy.Normalization.Enabled
+		// ...or at its saved volume, mid-song.
+		if policy.FadeIn > 0 && policy.FadeInOnReconnect {
+			s.FadeInPending = true
+			if _, ok := e.(CamillaVolumeObserved); ok {
+				startFadeIn(s, obsAt, cfg, policy)
+			} else {
+				cmds = append(cmds, CmdGetVolume{})
+			}
+		}
+		broadcasts = append(broadcasts, BroadcastDSPConnectionChanged{Connected: true, At: obsAt})
 	}
+	broadcasts = append(broadcasts, controllerFeedback(s, prevCtrl, e, at)...)
+	cmds = append(cmds, observationAcks(s, e)...)
+	cmds = append(cmds, ackReduced(s, e, reply, requestedDB, at)...)
 
 	return ReduceResult{
 		State:      s,
@@ -22,7 +22,7 @@ func TestReduce_CamillaVolumeObserved_EmitsBroadcastOnlyOnRoundedChange(t *testi
 	// Start with unknown volume; first observation should broadcast.
 	// Internal state keeps full precision; broadcast payload is rounded to 0.1 dB.
 	s := &DaemonState{}
-	rr := Reduce(s, CamillaVolumeObserved{VolumeDB: -12.04, At: t0}, cfg, rotaryCfg)
+	rr := Reduce(s, CamillaVolumeObserved{VolumeDB: -12.04, At: t0}, cfg, rotaryCfg, ReducerPolicy{})
 
 	if rr.State == nil {
 		t.Fatalf("expected non-nil state")
@@ -31,8 +31,8 @@ func TestReduce_CamillaVolumeObserved_EmitsBroadcastOnlyOnRoundedChange(t *testi
 		t.Fatalf("expected volume to become known")
 	}
 	// Internal observed volume is full precision (no rounding applied to DaemonState).
-	if rr.State.Camilla.VolumeDB != -12.04 {
-		t.Fatalf("expected internal volume_db=-12.04, got %v", rr.State.Camilla.VolumeDB)
+	if rr.State.Camilla.VolumeMB.DB() != -12.04 {
+		t.Fatalf("expected internal volume_db=-12.04, got %v", rr.State.Camilla.VolumeMB.DB())
 	}
 
 	if got := len(rr.Broadcasts); got != 1 {
@@ -49,7 +49,7 @@ func TestReduce_CamillaVolumeObserved_EmitsBroadcastOnlyOnRoundedChange(t *testi
 
 	// Second observation differs slightly but rounds to the same 0.1 dB -> should NOT broadcast.
 	t1 := t0.Add(1 * time.Second)
-	rr2 := Reduce(rr.State, CamillaVolumeObserved{VolumeDB: -12.01, At: t1}, cfg, rotaryCfg)
+	rr2 := Reduce(rr.State, CamillaVolumeObserved{VolumeDB: -12.01, At: t1}, cfg, rotaryCfg, ReducerPolicy{})
 
 	if got := len(rr2.Broadcasts); got != 0 {
 		t.Fatalf("expected 0 broadcasts when rounded volume unchanged, got %d (%T)", got, rr2.Broadcasts[0])
@@ -57,7 +57,7 @@ func TestReduce_CamillaVolumeObserved_EmitsBroadcastOnlyOnRoundedChange(t *testi
 
 	// Third observation crosses rounding boundary -> SHOULD broadcast.
 	t2 := t1.Add(1 * time.Second)
-	rr3 := Reduce(rr2.State, CamillaVolumeObserved{VolumeDB: -11.94, At: t2}, cfg, rotaryCfg)
+	rr3 := Reduce(rr2.State, CamillaVolumeObserved{VolumeDB: -11.94, At: t2}, cfg, rotaryCfg, ReducerPolicy{})
 
 	if got := len(rr3.Broadcasts); got != 1 {
 		t.Fatalf("expected 1 broadcast when rounded volume changes, got %d", got)
@@ -87,18 +87,18 @@ func TestReduce_CamillaVolumeObserved_EmitsBroadcastWhenPrevKnownButRoundedDiffe
 	// Seed state with known volume (full precision in internal state).
 	s := &DaemonState{}
 	s.Camilla.VolumeKnown = true
-	s.Camilla.VolumeDB = -20.02
+	s.Camilla.VolumeMB = -2002
 	s.Camilla.VolumeAt = t0.Add(-10 * time.Second)
 
 	// Rounded values: -20.02 -> -20.0, -19.96 -> -20.0 => no broadcast.
-	rr := Reduce(s, CamillaVolumeObserved{VolumeDB: -19.96, At: t0}, cfg, rotaryCfg)
+	rr := Reduce(s, CamillaVolumeObserved{VolumeDB: -19.96, At: t0}, cfg, rotaryCfg, ReducerPolicy{})
 	if got := len(rr.Broadcasts); got != 0 {
 		t.Fatalf("expected 0 broadcasts when rounded volume unchanged, got %d", got)
 	}
 
 	// Rounded values: -19.94 -> -19.9 => broadcast.
 	t1 := t0.Add(1 * time.Second)
-	rr2 := Reduce(rr.State, CamillaVolumeObserved{VolumeDB: -19.94, At: t1}, cfg, rotaryCfg)
+	rr2 := Reduce(rr.State, CamillaVolumeObserved{VolumeDB: -19.94, At: t1}, cfg, rotaryCfg, ReducerPolicy{})
 	if got := len(rr2.Broadcasts); got != 1 {
 		t.Fatalf("expected 1 broadcast when rounded volume changes, got %d", got)
 	}
@@ -113,3 +113,58 @@ func TestReduce_CamillaVolumeObserved_EmitsBroadcastWhenPrevKnownButRoundedDiffe
 		t.Fatalf("expected broadcast timestamp %v, got %v", t1, bc.At)
 	}
 }
+
+func TestReduce_CamillaReachability_BroadcastsTransitionsAndProbes(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0}
+	t0 := time.Unix(1000, 0).UTC()
+
+	rr := Reduce(&DaemonState{}, CamillaCommandFailed{Command: CmdGetVolume{}, Err: errNoClient{}, At: t0}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if !rr.State.Camilla.Unreachable {
+		t.Fatalf("expected unreachable after a failed command")
+	}
+	if len(rr.Broadcasts) != 1 {
+		t.Fatalf("expected 1 broadcast, got %d", len(rr.Broadcasts))
+	}
+	if bc, ok := rr.Broadcasts[0].(BroadcastDSPConnectionChanged); !ok || bc.Connected || bc.Error == "" {
+		t.Fatalf("expected disconnected broadcast with error, got %#v", rr.Broadcasts[0])
+	}
+
+	// Further failures don't repeat the broadcast.
+	rr = Reduce(rr.State, CamillaCommandFailed{Command: CmdGetMute{}, Err: errNoClient{}, At: t0.Add(time.Second)}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if len(rr.Broadcasts) != 0 {
+		t.Fatalf("expected no broadcast on repeated failure, got %d", len(rr.Broadcasts))
+	}
+
+	// Tick probes once the probe interval has passed.
+	rr = Reduce(rr.State, Tick{Now: t0.Add(camillaProbeInterval / 2), Dt: 0.03}, cfg, RotaryConfig{}, ReducerPolicy{})
+	for _, c := range rr.Commands {
+		if _, ok := c.(CmdGetVolume); ok {
+			t.Fatalf("unexpected probe before the interval")
+		}
+	}
+	rr = Reduce(rr.State, Tick{Now: t0.Add(camillaProbeInterval), Dt: 0.03}, cfg, RotaryConfig{}, ReducerPolicy{})
+	probed := false
+	for _, c := range rr.Commands {
+		if _, ok := c.(CmdGetVolume); ok {
+			probed = true
+		}
+	}
+	if !probed {
+		t.Fatalf("expected CmdGetVolume probe, got %#v", rr.Commands)
+	}
+
+	// A successful observation marks CamillaDSP reachable again.
+	rr = Reduce(rr.State, CamillaMuteObserved{Muted: false, At: t0.Add(11 * time.Second)}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if rr.State.Camilla.Unreachable {
+		t.Fatalf("expected reachable after an observation")
+	}
+	found := false
+	for _, b := range rr.Broadcasts {
+		if bc, ok := b.(BroadcastDSPConnectionChanged); ok && bc.Connected {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected connected broadcast, got %#v", rr.Broadcasts)
+	}
+}
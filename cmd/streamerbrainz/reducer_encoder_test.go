@@ -0,0 +1,129 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReduce_RotaryPress_CyclesModesAndReverts(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0}
+	rotaryCfg := RotaryConfig{
+		DbPerStep:         1,
+		VelocityThreshold: 100,
+		ButtonAction:      "mode",
+		ModeTimeoutMS:     1000,
+		BalanceFaders:     []int{1, 2},
+		SubFader:          3,
+	}
+	t0 := time.Unix(1000, 0).UTC()
+
+	s := &DaemonState{}
+	s.SetObservedVolume(-20, t0)
+
+	rr := Reduce(s, TimedEvent{Event: RotaryPress{}, At: t0}, cfg, rotaryCfg, ReducerPolicy{})
+	if got := rr.State.encoderMode(); got != EncoderModeBalance {
+		t.Fatalf("expected balance mode, got %q", got)
+	}
+	if len(rr.Broadcasts) != 1 {
+		t.Fatalf("expected 1 broadcast, got %d", len(rr.Broadcasts))
+	}
+	if b, ok := rr.Broadcasts[0].(BroadcastEncoderChanged); !ok || b.Mode != EncoderModeBalance {
+		t.Fatalf("unexpected broadcast %#v", rr.Broadcasts[0])
+	}
+
+	// Turning right in balance mode attenuates the left fader; volume is untouched.
+	rr = Reduce(rr.State, TimedEvent{Event: RotaryTurn{Steps: 3}, At: t0.Add(100 * time.Millisecond)}, cfg, rotaryCfg, ReducerPolicy{})
+	if _, ok := rr.State.GetDesiredVolume(); ok {
+		t.Fatalf("expected no volume change in balance mode")
+	}
+	rr = Reduce(rr.State, Tick{Now: t0.Add(200 * time.Millisecond), Dt: 0.1}, cfg, rotaryCfg, ReducerPolicy{})
+	if len(rr.Commands) != 2 {
+		t.Fatalf("expected 2 fader commands, got %v", rr.Commands)
+	}
+	if c := rr.Commands[0].(CmdSetFaderVolume); c.Fader != 1 || c.TargetDB != -3 {
+		t.Fatalf("unexpected left fader command %v", c)
+	}
+	if c := rr.Commands[1].(CmdSetFaderVolume); c.Fader != 2 || c.TargetDB != 0 {
+		t.Fatalf("unexpected right fader command %v", c)
+	}
+
+	rr = Reduce(rr.State, TimedEvent{Event: RotaryPress{}, At: t0.Add(300 * time.Millisecond)}, cfg, rotaryCfg, ReducerPolicy{})
+	if got := rr.State.encoderMode(); got != EncoderModeSub {
+		t.Fatalf("expected sub mode, got %q", got)
+	}
+
+	// Idle past the timeout reverts to volume.
+	rr = Reduce(rr.State, Tick{Now: t0.Add(2 * time.Second), Dt: 0.1}, cfg, rotaryCfg, ReducerPolicy{})
+	if got := rr.State.encoderMode(); got != EncoderModeVolume {
+		t.Fatalf("expected revert to volume mode, got %q", got)
+	}
+	if len(rr.Broadcasts) != 1 {
+		t.Fatalf("expected revert broadcast, got %d", len(rr.Broadcasts))
+	}
+}
+
+func TestReduce_RotaryPress_MuteAction(t *testing.T) {
+	rr := Reduce(&DaemonState{}, TimedEvent{Event: RotaryPress{}, At: time.Unix(1000, 0)}, VelocityConfig{}, RotaryConfig{ButtonAction: "mute"}, ReducerPolicy{})
+	if m := rr.State.Intent.DesiredMute; m == nil || !*m {
+		t.Fatalf("expected mute intent")
+	}
+}
+
+func TestReduce_RotaryTurn_CurveScalesWithRate(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0}
+	rotaryCfg := RotaryConfig{
+		VelocityWindowMS: 200,
+		Curve:            defaultRotaryCurve(),
+	}
+	t0 := time.Unix(1000, 0).UTC()
+
+	s := &DaemonState{}
+	s.SetObservedVolume(-40, t0)
+
+	// An isolated detent uses the slowest point of the curve.
+	rr := Reduce(s, TimedEvent{Event: RotaryTurn{Steps: 1}, At: t0}, cfg, rotaryCfg, ReducerPolicy{})
+	if v, _ := rr.State.GetDesiredVolume(); v != -39.75 {
+		t.Fatalf("expected slow detent to move 0.25 dB, got %v", v)
+	}
+
+	// A burst of 6 detents within the window (rate 25/s) uses 1.5 dB per step.
+	s = &DaemonState{}
+	s.SetObservedVolume(-40, t0)
+	rr = Reduce(s, TimedEvent{Event: RotaryTurn{Steps: 6}, At: t0}, cfg, rotaryCfg, ReducerPolicy{})
+	if v, _ := rr.State.GetDesiredVolume(); v != -31 {
+		t.Fatalf("expected 6 fast detents to move 9 dB, got %v", v)
+	}
+}
+
+func TestRotaryCurveDbPerStep_Interpolates(t *testing.T) {
+	curve := []RotaryCurvePoint{{Rate: 0, DbPerStep: 0.25}, {Rate: 10, DbPerStep: 1.25}}
+	cases := map[float64]float64{-1: 0.25, 0: 0.25, 5: 0.75, 10: 1.25, 100: 1.25}
+	for rate, want := range cases {
+		if got := rotaryCurveDbPerStep(curve, rate); got != want {
+			t.Errorf("rate %v: expected %v, got %v", rate, want, got)
+		}
+	}
+}
+
+func TestReduce_RotaryTurnHiRes_AccumulatesFractionalDetents(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0}
+	rotaryCfg := RotaryConfig{DbPerStep: 1, VelocityWindowMS: 200, VelocityThreshold: 100, VelocityMultiplier: 1}
+	t0 := time.Unix(1000, 0).UTC()
+
+	s := &DaemonState{}
+	s.SetObservedVolume(-40, t0)
+
+	// 30 units = a quarter detent -> 0.25 dB, no whole detent recorded yet.
+	rr := Reduce(s, TimedEvent{Event: RotaryTurnHiRes{Units: 30}, At: t0}, cfg, rotaryCfg, ReducerPolicy{})
+	if v, _ := rr.State.GetDesiredVolume(); v != -39.75 {
+		t.Fatalf("expected -39.75, got %v", v)
+	}
+	if len(rr.State.Rotary.RecentSteps) != 0 || rr.State.Rotary.HiResUnits != 30 {
+		t.Fatalf("unexpected accumulation state %#v", rr.State.Rotary)
+	}
+
+	rr = Reduce(rr.State, TimedEvent{Event: RotaryTurnHiRes{Units: 100}, At: t0.Add(10 * time.Millisecond)}, cfg, rotaryCfg, ReducerPolicy{})
+	if len(rr.State.Rotary.RecentSteps) != 1 || rr.State.Rotary.HiResUnits != 10 {
+		t.Fatalf("expected one whole detent and 10 leftover units, got %#v", rr.State.Rotary)
+	}
+}
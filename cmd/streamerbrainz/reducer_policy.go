@@ -0,0 +1,69 @@
+package main
+
+import "time"
+
+// ============================================================================
+// Reducer policy
+// ============================================================================
+// ReducerPolicy holds the reducer's settings that are not part of the volume
+// controller: user limits, calibration, test signals, normalization,
+// quantization, absolute-set ramp times, fade-in, Wake-on-LAN, arbitration, UI hints and what volume
+// gestures do while muted. VelocityConfig keeps the controller itself (rates,
+// bounds, danger zone); runtime tuning (ConfigUpdated) only ever replaces that.
+//
+// Both are built per zone from the config file (Config.ToVelocityConfigFor,
+// Config.ToReducerPolicyFor) and passed to Reduce on every event.
+// ============================================================================
+
+// ReducerPolicy configures reducer behavior outside the velocity engine.
+type ReducerPolicy struct {
+	// User limits (nil = unset) narrow MinDB/MaxDB for every source unless lifted by a
+	// LimitOverride carrying LimitOverrideToken, for at most LimitOverrideTimeout.
+	UserMinDB            *float64
+	UserMaxDB            *float64
+	LimitOverrideToken   string
+	LimitOverrideTimeout time.Duration
+
+	// Calibration mode: reference level and whether exiting restores the previous volume.
+	CalibrationReferenceDB float64
+	CalibrationRestore     bool
+
+	// Test signals (speaker checks).
+	TestSignal TestSignalConfig
+
+	// Per-track loudness normalization (see normalization.go).
+	Normalization NormalizationConfig
+
+	// Quantization. The controller integrates at full precision; StepDB applies to what is
+	// sent to CamillaDSP (0 = full precision), DisplayStepDB to what is broadcast
+	// (0 = defaultDisplayStepDB).
+	StepDB        float64
+	DisplayStepDB float64
+
+	// RampUp/RampDown (0 = use VelocityConfig.RampDBPerS) is how long an absolute
+	// set takes to fade up/down to its target, whatever the distance.
+	RampUp   time.Duration
+	RampDown time.Duration
+
+	// FadeIn (0 = off) fades from MinDB to CamillaDSP's level when it is adopted at
+	// startup and, with FadeInOnReconnect, after an outage (see fade_in.go).
+	FadeIn            time.Duration
+	FadeInOnReconnect bool
+
+	// WakeOnLAN wakes the CamillaDSP host (see wake_on_lan.go).
+	WakeOnLAN WakeOnLANConfig
+
+	// Arbitration between concurrent volume sources (see arbitration.go).
+	ArbitrationPolicy  string
+	ArbitrationLockout time.Duration
+	PhysicalOrigins    []string
+
+	// UI hint durations (see ui_hints.go; 0 disables the hint).
+	VolumeOverlay time.Duration
+	MuteFlash     time.Duration
+
+	// Volume gestures while muted
+	UnmuteOnVolumeUp           bool // volume-up while muted unmutes instead of raising the hidden level
+	UnmuteRestoreVolume        bool // ...and restores the volume observed when mute engaged
+	IgnoreVolumeDownWhileMuted bool // volume-down while muted is dropped instead of adjusting the stored level
+}
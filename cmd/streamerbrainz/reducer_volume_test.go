@@ -0,0 +1,292 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestReduce_SetVolumeAbsolute_RampsWhenConfigured(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0, RampDBPerS: 10}
+	rotaryCfg := RotaryConfig{DbPerStep: 1}
+	t0 := time.Unix(1000, 0).UTC()
+
+	s := &DaemonState{}
+	s.SetObservedVolume(-30, t0)
+
+	rr := Reduce(s, SetVolumeAbsolute{Db: -20}, cfg, rotaryCfg, ReducerPolicy{})
+	if len(rr.Commands) != 0 || rr.State.Intent.DesiredVolume != nil {
+		t.Fatalf("expected ramp to be deferred to Tick, got %d commands", len(rr.Commands))
+	}
+
+	// Each 0.1 s tick moves 1 dB toward the target.
+	now := t0
+	var last float64
+	for i := 0; i < 10; i++ {
+		now = now.Add(100 * time.Millisecond)
+		rr = Reduce(rr.State, Tick{Now: now, Dt: 0.1}, cfg, rotaryCfg, ReducerPolicy{})
+		if len(rr.Commands) != 1 {
+			t.Fatalf("tick %d: expected 1 command, got %d", i, len(rr.Commands))
+		}
+		last = rr.Commands[0].(CmdSetVolume).TargetDB
+		if want := -30 + float64(i+1); math.Abs(last-want) > 1e-9 {
+			t.Fatalf("tick %d: expected %v, got %v", i, want, last)
+		}
+	}
+	if rr.State.VolumeCtrl.Ramping {
+		t.Fatalf("expected ramp to finish at target")
+	}
+
+	// Once finished, further ticks emit nothing.
+	rr.State.SetObservedVolume(last, now)
+	rr = Reduce(rr.State, Tick{Now: now.Add(100 * time.Millisecond), Dt: 0.1}, cfg, rotaryCfg, ReducerPolicy{})
+	if len(rr.Commands) != 0 {
+		t.Fatalf("expected no commands after ramp, got %d", len(rr.Commands))
+	}
+}
+
+func TestReduce_SetVolumeAbsolute_StepCancelsRamp(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0, RampDBPerS: 10}
+	rotaryCfg := RotaryConfig{DbPerStep: 1}
+	t0 := time.Unix(1000, 0).UTC()
+
+	s := &DaemonState{}
+	s.SetObservedVolume(-30, t0)
+
+	rr := Reduce(s, SetVolumeAbsolute{Db: -10}, cfg, rotaryCfg, ReducerPolicy{})
+	rr = Reduce(rr.State, Tick{Now: t0.Add(100 * time.Millisecond), Dt: 0.1}, cfg, rotaryCfg, ReducerPolicy{})
+	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: -29, At: t0.Add(110 * time.Millisecond)}, cfg, rotaryCfg, ReducerPolicy{})
+	rr = Reduce(rr.State, VolumeStep{Steps: -1, DbPerStep: 1}, cfg, rotaryCfg, ReducerPolicy{})
+	if rr.State.VolumeCtrl.Ramping {
+		t.Fatalf("expected step to cancel ramp")
+	}
+	if v, ok := rr.State.GetDesiredVolume(); !ok || math.Abs(v-(-30)) > 1e-9 {
+		t.Fatalf("expected step from observed ramp position to -30, got %v (ok=%v)", v, ok)
+	}
+}
+
+func TestReduce_SetVolumeAbsolute_RampTimes(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0, RampDBPerS: 10}
+	policy := ReducerPolicy{RampUp: time.Second, RampDown: 500 * time.Millisecond}
+	t0 := time.Unix(1000, 0).UTC()
+
+	for _, tc := range []struct {
+		target  float64
+		perTick float64 // dB per 0.1 s tick
+	}{
+		{-10, 2},   // 20 dB up in 1 s
+		{-40, 2},   // 10 dB down in 0.5 s
+		{-29, 0.1}, // 1 dB up in 1 s, slower than ramp_db_per_sec
+	} {
+		s := &DaemonState{}
+		s.SetObservedVolume(-30, t0)
+		rr := Reduce(s, SetVolumeAbsolute{Db: tc.target}, cfg, RotaryConfig{}, policy)
+		rr = Reduce(rr.State, Tick{Now: t0.Add(100 * time.Millisecond), Dt: 0.1}, cfg, RotaryConfig{}, policy)
+		got := rr.Commands[0].(CmdSetVolume).TargetDB
+		if want := -30 + math.Copysign(tc.perTick, tc.target+30); math.Abs(got-want) > 1e-9 {
+			t.Fatalf("to %v: first tick at %v, want %v", tc.target, got, want)
+		}
+	}
+}
+
+func TestReduce_SetVolumeAbsolute_SnapsWithoutRamp(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0}
+	s := &DaemonState{}
+	s.SetObservedVolume(-30, time.Unix(1000, 0))
+
+	rr := Reduce(s, SetVolumeAbsolute{Db: -20}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if v, ok := rr.State.GetDesiredVolume(); !ok || v != -20 {
+		t.Fatalf("expected immediate desired volume -20, got %v (ok=%v)", v, ok)
+	}
+}
+
+func TestReduce_VolumeUpWhileMuted_UnmutesAndRestores(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0, HoldTimeout: time.Second}
+	policy := ReducerPolicy{UnmuteOnVolumeUp: true, UnmuteRestoreVolume: true}
+	rotaryCfg := RotaryConfig{DbPerStep: 1}
+	t0 := time.Unix(1000, 0).UTC()
+
+	s := &DaemonState{}
+	s.SetObservedVolume(-20, t0)
+	rr := Reduce(s, CamillaMuteObserved{Muted: true, At: t0}, cfg, rotaryCfg, policy)
+
+	// Lowering while muted adjusts the stored level (default policy).
+	rr = Reduce(rr.State, VolumeStep{Steps: -5, DbPerStep: 1}, cfg, rotaryCfg, policy)
+	if v, ok := rr.State.GetDesiredVolume(); !ok || v != -25 {
+		t.Fatalf("expected volume-down to adjust to -25, got %v (ok=%v)", v, ok)
+	}
+	rr = Reduce(rr.State, Tick{Now: t0.Add(100 * time.Millisecond), Dt: 0.1}, cfg, rotaryCfg, policy)
+	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: -25, At: t0.Add(110 * time.Millisecond)}, cfg, rotaryCfg, policy)
+
+	// Volume-up hold unmutes (restoring -20) instead of raising the hidden level.
+	t1 := t0.Add(time.Second)
+	rr = Reduce(rr.State, TimedEvent{Event: VolumeHeld{Direction: 1}, At: t1}, cfg, rotaryCfg, policy)
+	if rr.State.VolumeCtrl.HeldDirection != 0 {
+		t.Fatalf("expected hold to be consumed by auto-unmute")
+	}
+	rr = Reduce(rr.State, Tick{Now: t1.Add(10 * time.Millisecond), Dt: 0.01}, cfg, rotaryCfg, policy)
+	if len(rr.Commands) != 2 {
+		t.Fatalf("expected SetVolume then SetMute, got %v", rr.Commands)
+	}
+	if c, ok := rr.Commands[0].(CmdSetVolume); !ok || c.TargetDB != -20 {
+		t.Fatalf("expected restore to -20 first, got %#v", rr.Commands[0])
+	}
+	if c, ok := rr.Commands[1].(CmdSetMute); !ok || c.Muted {
+		t.Fatalf("expected unmute last, got %#v", rr.Commands[1])
+	}
+
+	// Repeats of the same press are dropped until release.
+	rr = Reduce(rr.State, CamillaMuteObserved{Muted: false, At: t1.Add(20 * time.Millisecond)}, cfg, rotaryCfg, policy)
+	rr = Reduce(rr.State, TimedEvent{Event: VolumeHeld{Direction: 1}, At: t1.Add(100 * time.Millisecond)}, cfg, rotaryCfg, policy)
+	if rr.State.VolumeCtrl.HeldDirection != 0 {
+		t.Fatalf("expected repeat of consumed hold to be dropped")
+	}
+	rr = Reduce(rr.State, VolumeRelease{}, cfg, rotaryCfg, policy)
+	rr = Reduce(rr.State, TimedEvent{Event: VolumeHeld{Direction: 1}, At: t1.Add(500 * time.Millisecond)}, cfg, rotaryCfg, policy)
+	if rr.State.VolumeCtrl.HeldDirection != 1 {
+		t.Fatalf("expected new press after release to hold")
+	}
+}
+
+func TestReduce_VolumeDownWhileMuted_Ignored(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0}
+	policy := ReducerPolicy{IgnoreVolumeDownWhileMuted: true}
+	t0 := time.Unix(1000, 0).UTC()
+
+	s := &DaemonState{}
+	s.SetObservedVolume(-20, t0)
+	s.SetObservedMute(true, t0)
+
+	rr := Reduce(s, TimedEvent{Event: RotaryTurn{Steps: -3}, At: t0}, cfg, RotaryConfig{DbPerStep: 1, VelocityThreshold: 10}, policy)
+	if _, ok := rr.State.GetDesiredVolume(); ok {
+		t.Fatalf("expected volume-down while muted to be ignored")
+	}
+
+	// Volume-up keeps the legacy behavior when auto-unmute is off.
+	rr = Reduce(rr.State, VolumeStep{Steps: 1, DbPerStep: 1}, cfg, RotaryConfig{}, policy)
+	if v, ok := rr.State.GetDesiredVolume(); !ok || v != -19 {
+		t.Fatalf("expected volume-up to adjust to -19, got %v (ok=%v)", v, ok)
+	}
+	if rr.State.Intent.DesiredMute != nil {
+		t.Fatalf("expected no unmute intent")
+	}
+}
+
+func TestReduce_IdleTickPointerDoesNotAllocate(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0, Mode: "accelerating", MaxDt: 0.1}
+	s := &DaemonState{}
+	s.SetObservedVolume(-30, time.Unix(1000, 0))
+	s.VolumeCtrl.TargetDB = -30
+	tick := &Tick{}
+	now := time.Unix(1000, 0)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		now = now.Add(time.Second)
+		*tick = Tick{Now: now, Dt: 1}
+		_ = Reduce(s, tick, cfg, RotaryConfig{}, ReducerPolicy{})
+	})
+	if allocs != 0 {
+		t.Fatalf("expected an idle tick to reduce without allocating, got %v", allocs)
+	}
+}
+
+func TestReduce_StepDBQuantizesAppliedVolume(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: -0.2}
+	policy := ReducerPolicy{StepDB: 0.5, DisplayStepDB: 0.5}
+	rotaryCfg := RotaryConfig{DbPerStep: 1}
+	t0 := time.Unix(1000, 0).UTC()
+
+	s := &DaemonState{}
+	s.SetObservedVolume(-30, t0)
+
+	cases := []struct {
+		set, want float64
+		cmds      int
+	}{
+		{set: -29.8, want: -30, cmds: 0}, // rounds to what is already applied
+		{set: -29.7, want: -29.5, cmds: 1},
+		{set: -0.2, want: -0.5, cmds: 1}, // rounding must not cross max_db
+	}
+	for _, tc := range cases {
+		rr := Reduce(s, SetVolumeAbsolute{Db: tc.set}, cfg, rotaryCfg, policy)
+		rr = Reduce(rr.State, Tick{Now: t0.Add(time.Second), Dt: 0.01}, cfg, rotaryCfg, policy)
+		if len(rr.Commands) != tc.cmds {
+			t.Fatalf("set %v: expected %d commands, got %d", tc.set, tc.cmds, len(rr.Commands))
+		}
+		if tc.cmds > 0 {
+			if got := rr.Commands[0].(CmdSetVolume).TargetDB; got != tc.want {
+				t.Fatalf("set %v: expected %v, got %v", tc.set, tc.want, got)
+			}
+		}
+		s = rr.State
+	}
+
+	rr := Reduce(s, CamillaVolumeObserved{VolumeDB: -12.3, At: t0}, cfg, rotaryCfg, policy)
+	if len(rr.Broadcasts) != 1 || rr.Broadcasts[0].(BroadcastVolumeChanged).VolumeDB != -12.5 {
+		t.Fatalf("expected broadcast rounded to display_step_db, got %#v", rr.Broadcasts)
+	}
+}
+
+func TestMillibelRoundTo(t *testing.T) {
+	for _, tc := range []struct{ v, step, want Millibel }{
+		{-2954, 10, -2950},
+		{-2976, 50, -3000},
+		{-2974, 25, -2975},
+		{-2974, 0, -2974},
+		{2975, 50, 3000},
+	} {
+		if got := tc.v.roundTo(tc.step); got != tc.want {
+			t.Fatalf("%d.roundTo(%d) = %d, want %d", tc.v, tc.step, got, tc.want)
+		}
+	}
+}
+
+func TestReduce_RepeatedStepsDoNotDrift(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0}
+	t0 := time.Unix(1000, 0).UTC()
+	s := &DaemonState{}
+	s.SetObservedVolume(-30, t0)
+
+	// 0.1 dB isn't exact in binary float, but intents are whole millibels: a
+	// round trip lands exactly where it started.
+	for i := range 400 {
+		steps := 1
+		if i >= 200 {
+			steps = -1
+		}
+		s = Reduce(s, VolumeStep{Steps: steps, DbPerStep: 0.1}, cfg, RotaryConfig{}, ReducerPolicy{}).State
+	}
+	if v, ok := s.GetDesiredVolume(); !ok || v != -30 {
+		t.Fatalf("desired volume after round trip = %v (%v), want exactly -30", v, ok)
+	}
+}
+
+func TestReduce_HiResUnitsAccumulateBelowAMillibel(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0}
+	rotaryCfg := RotaryConfig{DbPerStep: 0.5, VelocityThreshold: 1000, VelocityMultiplier: 1}
+	t0 := time.Unix(1000, 0).UTC()
+	s := &DaemonState{}
+	s.SetObservedVolume(-30, t0)
+
+	// One unit is 1/120 detent (~0.4 mB): individually below the resolution.
+	for i := range hiResUnitsPerDetent {
+		s = Reduce(s, TimedEvent{At: t0.Add(time.Duration(i) * time.Second), Event: RotaryTurnHiRes{Units: 1}}, cfg, rotaryCfg, ReducerPolicy{}).State
+	}
+	if v, ok := s.GetDesiredVolume(); !ok || v != -29.5 {
+		t.Fatalf("desired volume after one detent of hi-res units = %v (%v), want -29.5", v, ok)
+	}
+}
+
+func TestQuantizeVolumeMB(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -79.9, MaxDB: -0.1}
+	policy := ReducerPolicy{StepDB: 0.5}
+	for _, tc := range []struct{ v, want Millibel }{
+		{-2976, -3000},
+		{-5, -50},      // rounding up past MaxDB moves one step inward
+		{-7995, -7950}, // likewise for MinDB
+	} {
+		if got := quantizeVolumeMB(tc.v, cfg, policy); got != tc.want {
+			t.Fatalf("quantizeVolumeMB(%d) = %d, want %d", tc.v, got, tc.want)
+		}
+	}
+}
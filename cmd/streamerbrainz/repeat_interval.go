@@ -0,0 +1,149 @@
+package main
+
+import (
+	"slices"
+	"time"
+)
+
+// ============================================================================
+// Repeat-rate normalization (slow IR remotes)
+// ============================================================================
+// Keyboards autorepeat a held key every ~33ms, but many IR remotes repeat only
+// every 200-500ms, and some (FLIRC, cheap USB receivers) send a release and a
+// new press for every repeat frame. Without help a hold then stutters: the
+// release ends the gesture, and a gap longer than velocity.hold_timeout_ms times
+// it out.
+//
+// An input with a repeat interval gets two fixes for its volume keys:
+//   - a release is held back for repeatGraceFactor intervals; a new press of the
+//     same key in that window continues the hold as a repeat;
+//   - its VolumeHeld events carry the same grace as HoldTimeoutMS, so the
+//     reducer keeps the hold alive across the expected gaps.
+//
+//	inputs:
+//	  - path: /dev/input/by-id/usb-flirc-event-kbd
+//	    type: key
+//	    repeat_interval_ms: 300      # or: auto_repeat_interval: true
+//
+// With auto_repeat_interval the interval is measured from the gaps between a
+// held key's repeats (the longest of the last repeatSamples gaps).
+// ============================================================================
+
+// repeatSamples is how many repeat gaps auto measurement keeps.
+const repeatSamples = 8
+
+// repeatState normalizes one device's volume-key repeats.
+type repeatState struct {
+	interval time.Duration // configured interval (0: measured if auto, else off)
+	auto     bool
+
+	gaps     []time.Duration // last repeat gaps (auto)
+	lastCode uint16          // key of the last press/repeat
+	lastAt   time.Time       // its kernel timestamp
+
+	pending *inputEvent // release held back until timer fires
+	timer   *time.Timer
+	timerID int // invalidates callbacks of stopped timers
+}
+
+// grace returns how long a hold survives without a repeat (0: normalization off).
+func (r *repeatState) grace() time.Duration {
+	interval := r.interval
+	if interval == 0 && r.auto && len(r.gaps) > 0 {
+		interval = slices.Max(r.gaps)
+	}
+	return time.Duration(float64(interval) * repeatGraceFactor)
+}
+
+// measure records the gap since the previous press/repeat of the same key.
+func (r *repeatState) measure(ev inputEvent) {
+	at := ev.time()
+	if r.auto && ev.Code == r.lastCode && !r.lastAt.IsZero() {
+		if gap := at.Sub(r.lastAt); gap > 0 && gap <= maxRepeatIntervalMS*time.Millisecond {
+			if len(r.gaps) == repeatSamples {
+				r.gaps = r.gaps[1:]
+			}
+			r.gaps = append(r.gaps, gap)
+		}
+	}
+	r.lastCode, r.lastAt = ev.Code, at
+}
+
+// isVolumeKey reports whether code is a hold (volume) key.
+func isVolumeKey(code uint16) bool {
+	return code == KEY_VOLUMEUP || code == KEY_VOLUMEDOWN
+}
+
+// normalizeRepeat defers volume-key releases and turns a press that follows a
+// deferred release of the same key into a repeat; any other key first flushes
+// the deferred release. It reports the event as consumed when it was deferred.
+func (d *inputDecoder) normalizeRepeat(ev inputEvent) (inputEvent, bool) {
+	r := &d.repeat
+	if !isVolumeKey(ev.Code) {
+		d.flushRelease() // keep the order of key events
+		return ev, false
+	}
+	if ev.Value != evValueRelease {
+		r.measure(ev)
+	}
+	grace := r.grace()
+
+	if r.pending != nil {
+		if ev.Value == evValuePress && r.pending.Code == ev.Code {
+			r.cancelPending()
+			ev.Value = evValueRepeat
+		} else {
+			d.flushRelease()
+		}
+	}
+	if ev.Value != evValueRelease || grace == 0 {
+		return ev, false
+	}
+
+	release := ev
+	r.pending = &release
+	r.timerID++
+	id := r.timerID
+	r.timer = time.AfterFunc(grace, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if d.repeat.timerID == id {
+			d.flushRelease()
+		}
+	})
+	return ev, true
+}
+
+// flushRelease emits the deferred release, if any.
+func (d *inputDecoder) flushRelease() {
+	if d.repeat.pending == nil {
+		return
+	}
+	ev := *d.repeat.pending
+	d.repeat.cancelPending()
+	d.translateKey(ev)
+}
+
+// cancelPending drops the deferred release without emitting it.
+func (r *repeatState) cancelPending() {
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+	r.pending, r.timer = nil, nil
+	r.timerID++
+}
+
+// volumeHeldEvent returns the VolumeHeld for a volume-key press or repeat,
+// carrying the device's repeat grace when it has one.
+func (d *inputDecoder) volumeHeldEvent(code uint16) Event {
+	grace := d.repeat.grace()
+	switch {
+	case grace > 0 && code == KEY_VOLUMEUP:
+		return VolumeHeld{Direction: 1, HoldTimeoutMS: int(grace / time.Millisecond)}
+	case grace > 0:
+		return VolumeHeld{Direction: -1, HoldTimeoutMS: int(grace / time.Millisecond)}
+	case code == KEY_VOLUMEUP:
+		return volumeHeldUpEvent
+	}
+	return volumeHeldDownEvent
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestInputDecoder_RepeatInterval(t *testing.T) {
+	events := make(chan Event, 16)
+	d := newInputDecoder(events, inputDecoderConfig{RepeatInterval: 20 * time.Millisecond}, &Metrics{}, slog.New(slog.DiscardHandler))
+	key := func(code uint16, value int32) { d.handle(inputEvent{Type: EV_KEY, Code: code, Value: value}) }
+	next := func() Event {
+		t.Helper()
+		select {
+		case ev := <-events:
+			return ev
+		case <-time.After(2 * time.Second):
+			t.Fatal("no event")
+			return nil
+		}
+	}
+	held := VolumeHeld{Direction: 1, HoldTimeoutMS: 30}
+
+	// A remote that sends press/release for every repeat frame: one hold.
+	key(KEY_VOLUMEUP, evValuePress)
+	key(KEY_VOLUMEUP, evValueRelease)
+	key(KEY_VOLUMEUP, evValuePress)
+	key(KEY_VOLUMEUP, evValueRelease)
+	for i, want := range []Event{held, held, VolumeRelease{}} {
+		if ev := next(); ev != want {
+			t.Fatalf("event %d: got %#v, want %#v", i, ev, want)
+		}
+	}
+
+	// Another key flushes the deferred release first.
+	key(KEY_VOLUMEDOWN, evValuePress)
+	key(KEY_VOLUMEDOWN, evValueRelease)
+	key(KEY_MUTE, evValuePress)
+	for i, want := range []Event{VolumeHeld{Direction: -1, HoldTimeoutMS: 30}, VolumeRelease{}, ToggleMute{}} {
+		if ev := next(); ev != want {
+			t.Fatalf("flush event %d: got %#v, want %#v", i, ev, want)
+		}
+	}
+
+	// A reconnect drops the deferred release.
+	key(KEY_VOLUMEUP, evValuePress)
+	key(KEY_VOLUMEUP, evValueRelease)
+	d.reset()
+	if ev := next(); ev != held {
+		t.Fatalf("got %#v, want %#v", ev, held)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if len(events) != 0 {
+		t.Fatalf("release after reset: %#v", <-events)
+	}
+}
+
+func TestInputDecoder_AutoRepeatInterval(t *testing.T) {
+	events := make(chan Event, 16)
+	d := newInputDecoder(events, inputDecoderConfig{AutoRepeatInterval: true}, &Metrics{}, slog.New(slog.DiscardHandler))
+	key := func(ms int64, value int32) {
+		d.handle(inputEvent{Sec: 100 + ms/1000, Usec: ms % 1000 * 1000, Type: EV_KEY, Code: KEY_VOLUMEUP, Value: value})
+	}
+
+	// Nothing measured yet: plain holds; then the 200ms repeat gap gives a 300ms grace.
+	key(0, evValuePress)
+	key(200, evValueRepeat)
+	key(400, evValueRepeat)
+	want := VolumeHeld{Direction: 1, HoldTimeoutMS: 300}
+	for i, w := range []Event{volumeHeldUpEvent, want, want} {
+		if ev := <-events; ev != w {
+			t.Fatalf("event %d: got %#v, want %#v", i, ev, w)
+		}
+	}
+
+	// Gaps longer than maxRepeatIntervalMS are pauses, not repeats.
+	key(5000, evValueRepeat)
+	if ev := <-events; ev != want {
+		t.Fatalf("after pause: got %#v, want %#v", ev, want)
+	}
+}
+
+func TestReduce_VolumeHeld_HoldTimeoutExtendsHold(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0, VelMaxDBPerS: 15, AccelTime: 2, DecayTau: 0.2, HoldTimeout: 100 * time.Millisecond}
+	rotaryCfg := RotaryConfig{DbPerStep: 1}
+	t0 := time.Unix(1000, 0).UTC()
+
+	s := &DaemonState{}
+	s.SetObservedVolume(-30, t0)
+	rr := Reduce(s, TimedEvent{Event: VolumeHeld{Direction: 1, HoldTimeoutMS: 450}, At: t0}, cfg, rotaryCfg, ReducerPolicy{})
+	rr = Reduce(rr.State, Tick{Now: t0.Add(300 * time.Millisecond), Dt: 0.3}, cfg, rotaryCfg, ReducerPolicy{})
+	if rr.State.VolumeCtrl.HeldDirection != 1 {
+		t.Fatalf("hold ended within its own timeout")
+	}
+	rr = Reduce(rr.State, Tick{Now: t0.Add(500 * time.Millisecond), Dt: 0.2}, cfg, rotaryCfg, ReducerPolicy{})
+	if rr.State.VolumeCtrl.HeldDirection != 0 {
+		t.Fatalf("hold outlived its timeout")
+	}
+
+	// The release clears the hold's timeout; the next hold uses hold_timeout_ms.
+	rr = Reduce(rr.State, VolumeRelease{}, cfg, rotaryCfg, ReducerPolicy{})
+	rr = Reduce(rr.State, TimedEvent{Event: VolumeHeld{Direction: 1}, At: t0.Add(time.Second)}, cfg, rotaryCfg, ReducerPolicy{})
+	rr = Reduce(rr.State, Tick{Now: t0.Add(1200 * time.Millisecond), Dt: 0.2}, cfg, rotaryCfg, ReducerPolicy{})
+	if rr.State.VolumeCtrl.HeldDirection != 0 {
+		t.Fatalf("plain hold outlived hold_timeout_ms")
+	}
+}
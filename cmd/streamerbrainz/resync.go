@@ -0,0 +1,40 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// ============================================================================
+// State resync
+// ============================================================================
+// POST /api/v1/resync (or `streamerbrainz ctl resync`, or a "resync_state" IPC
+// envelope) delivers ResyncState: every zone (or the one named by ?zone=) reads
+// volume, mute, config path and processing state from CamillaDSP again and
+// re-broadcasts volume and mute. Useful after another tool (CamillaGUI, a
+// script) changed the DSP behind the daemon's back.
+// ============================================================================
+
+type resyncHandler struct {
+	events chan<- Event
+	logger *slog.Logger
+}
+
+func (h *resyncHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeEventWebhookResponse(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var ev Event = ResyncState{}
+	if zone := r.URL.Query().Get("zone"); zone != "" {
+		ev = ZonedEvent{Zone: zone, Event: ev}
+	}
+	select {
+	case h.events <- ev:
+		h.logger.Info("state resync requested", "remote_addr", r.RemoteAddr, "zone", r.URL.Query().Get("zone"))
+		writeEventWebhookResponse(w, http.StatusOK, "")
+	default:
+		writeEventWebhookResponse(w, http.StatusServiceUnavailable, "event queue full")
+	}
+}
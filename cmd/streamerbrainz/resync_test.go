@@ -0,0 +1,77 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReduce_ResyncStateRebroadcastsObservations(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0}
+	t0 := time.Unix(1000, 0).UTC()
+	s := &DaemonState{}
+	s.SetObservedVolume(-30, t0)
+	s.SetObservedMute(false, t0)
+
+	rr := Reduce(s, ResyncState{}, cfg, RotaryConfig{}, ReducerPolicy{})
+	var gets int
+	for _, c := range rr.Commands {
+		switch c.(type) {
+		case CmdGetVolume, CmdGetMute, CmdGetConfigFilePath, CmdGetState:
+			gets++
+		}
+	}
+	if gets != 4 {
+		t.Fatalf("commands = %v, want the four CamillaDSP reads", rr.Commands)
+	}
+
+	// Unchanged values are broadcast once after the resync...
+	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: -30, At: t0.Add(time.Second)}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if len(rr.Broadcasts) != 1 {
+		t.Fatalf("volume broadcasts after resync = %v", rr.Broadcasts)
+	}
+	rr = Reduce(rr.State, CamillaMuteObserved{Muted: false, At: t0.Add(time.Second)}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if len(rr.Broadcasts) != 1 {
+		t.Fatalf("mute broadcasts after resync = %v", rr.Broadcasts)
+	}
+
+	// ...and not again on the next poll.
+	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: -30, At: t0.Add(2 * time.Second)}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if len(rr.Broadcasts) != 0 {
+		t.Fatalf("volume broadcasts on later poll = %v", rr.Broadcasts)
+	}
+}
+
+func TestResyncHandler(t *testing.T) {
+	events := make(chan Event, 1)
+	h := &resyncHandler{events: events, logger: slog.New(slog.DiscardHandler)}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/resync", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET status = %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/resync?zone=phones", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST status = %d", w.Code)
+	}
+	if ze, ok := (<-events).(ZonedEvent); !ok || ze.Zone != "phones" || ze.Event != (ResyncState{}) {
+		t.Fatalf("event = %#v", ze)
+	}
+}
+
+func TestCtlEvent(t *testing.T) {
+	if ev, err := ctlEvent("resync", ""); err != nil || ev != (ResyncState{}) {
+		t.Fatalf("resync = %#v, %v", ev, err)
+	}
+	if ev, err := ctlEvent("resync", "phones"); err != nil || ev.(ZonedEvent).Zone != "phones" {
+		t.Fatalf("zoned resync = %#v, %v", ev, err)
+	}
+	if _, err := ctlEvent("reboot", ""); err == nil {
+		t.Fatal("expected unknown command error")
+	}
+}
@@ -5,6 +5,29 @@ import (
 	"time"
 )
 
+// rotaryDebouncer rejects encoder contact bounce in the input path.
+//
+// Cheap encoders emit alternating ±1 detents within a few ms. A direction reversal
+// closer than window to the last accepted detent is treated as a glitch and dropped.
+// Timing uses the kernel event timestamps, so it's independent of read latency.
+//
+// Not thread-safe: one debouncer per device reader.
+type rotaryDebouncer struct {
+	window  time.Duration
+	lastDir int
+	lastAt  time.Time
+}
+
+// accept reports whether a detent in direction (+1/-1) at time at should be kept.
+func (d *rotaryDebouncer) accept(direction int, at time.Time) bool {
+	if d.window > 0 && d.lastDir != 0 && direction != d.lastDir && at.Sub(d.lastAt) < d.window {
+		return false
+	}
+	d.lastDir = direction
+	d.lastAt = at
+	return true
+}
+
 // rotaryState tracks recent encoder activity for velocity detection.
 // This allows us to detect "fast spinning" and scale the step size accordingly.
 //
@@ -0,0 +1,391 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// Scrobbling
+// ============================================================================
+// Everything the integrations report playing (Plex, librespot, Tidal Connect;
+// the player_changed broadcasts) is scrobbled to ListenBrainz and/or Last.fm,
+// in one place rather than per player app.
+//
+// A track counts once it has played (pauses excluded) for half its length or
+// four minutes, whichever comes first; tracks under 30 seconds never count, and
+// tracks of unknown length count after four minutes. A "now playing" update is
+// sent whenever a track starts or resumes playing (best effort, not queued).
+//
+// Each service has its own queue, so one being down doesn't hold up the other.
+// Failed submissions are retried with backoff (30s doubling to 30min); a batch
+// the service rejects as invalid is dropped. scrobble.queue_file keeps unsent
+// scrobbles across restarts.
+// ============================================================================
+
+const (
+	scrobbleMinTrack  = 30 * time.Second
+	scrobbleMaxListen = 4 * time.Minute
+
+	scrobbleRetryMin = 30 * time.Second
+	scrobbleRetryMax = 30 * time.Minute
+
+	// scrobbleQueueMax bounds each service's queue; the oldest scrobbles are dropped beyond it.
+	scrobbleQueueMax = 1000
+
+	scrobbleHTTPTimeout = 15 * time.Second
+)
+
+// scrobbleTrack is a listen to submit.
+type scrobbleTrack struct {
+	Source     string    `json:"source"`
+	Artist     string    `json:"artist"`
+	Title      string    `json:"title"`
+	Album      string    `json:"album,omitempty"`
+	DurationMs int64     `json:"duration_ms,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+}
+
+func (t scrobbleTrack) same(o scrobbleTrack) bool {
+	return t.Source == o.Source && t.Artist == o.Artist && t.Title == o.Title && t.Album == o.Album
+}
+
+// scrobbleService submits listens to one service.
+type scrobbleService interface {
+	Name() string
+	// MaxBatch is the most listens one Scrobble call may carry.
+	MaxBatch() int
+	// Scrobble submits listens; a *scrobbleRejectedError means retrying won't help.
+	Scrobble(ctx context.Context, tracks []scrobbleTrack) error
+	NowPlaying(ctx context.Context, track scrobbleTrack) error
+}
+
+// scrobbleRejectedError is a submission the service refused as invalid.
+type scrobbleRejectedError struct {
+	err error
+}
+
+func (e *scrobbleRejectedError) Error() string { return "rejected: " + e.err.Error() }
+func (e *scrobbleRejectedError) Unwrap() error { return e.err }
+
+// scrobbleThreshold is how long a track must play to count (ok=false: never).
+func scrobbleThreshold(durationMs int64) (time.Duration, bool) {
+	d := time.Duration(durationMs) * time.Millisecond
+	switch {
+	case d == 0:
+		return scrobbleMaxListen, true
+	case d < scrobbleMinTrack:
+		return 0, false
+	}
+	return min(d/2, scrobbleMaxListen), true
+}
+
+// scrobbleTracker follows the player and decides when a track counts.
+type scrobbleTracker struct {
+	cur    scrobbleTrack
+	active bool          // cur is a track being listened to
+	played time.Duration // play time before since
+	since  time.Time     // playing since (zero while paused)
+	done   bool          // cur has been scrobbled
+}
+
+// update applies a player change. It returns the track that became due, if
+// any, and the track that started or resumed playing, if any.
+func (t *scrobbleTracker) update(b BroadcastPlayerChanged) (due, nowPlaying *scrobbleTrack) {
+	due = t.check(b.At)
+	if !t.since.IsZero() {
+		t.played += b.At.Sub(t.since)
+		t.since = time.Time{}
+	}
+
+	next := scrobbleTrack{Source: b.Source, Artist: b.Artist, Title: b.Title, Album: b.Album, DurationMs: b.DurationMs, StartedAt: b.At}
+	if !t.active || !t.cur.same(next) {
+		t.cur, t.played, t.done = next, 0, false
+		t.active = next.Artist != "" && next.Title != ""
+	} else if next.DurationMs != 0 {
+		t.cur.DurationMs = next.DurationMs
+	}
+	switch b.State {
+	case "stopped":
+		t.active = false
+	case "playing":
+		if t.active {
+			t.since = b.At
+			np := t.cur
+			nowPlaying = &np
+		}
+	}
+	return due, nowPlaying
+}
+
+// check returns the current track if it has become due by now.
+func (t *scrobbleTracker) check(now time.Time) *scrobbleTrack {
+	if !t.active || t.done || t.since.IsZero() {
+		return nil
+	}
+	threshold, ok := scrobbleThreshold(t.cur.DurationMs)
+	if !ok || t.played+now.Sub(t.since) < threshold {
+		return nil
+	}
+	t.done = true
+	tr := t.cur
+	return &tr
+}
+
+// deadline is when the current track becomes due if it keeps playing (zero: never).
+func (t *scrobbleTracker) deadline() time.Time {
+	if !t.active || t.done || t.since.IsZero() {
+		return time.Time{}
+	}
+	threshold, ok := scrobbleThreshold(t.cur.DurationMs)
+	if !ok {
+		return time.Time{}
+	}
+	return t.since.Add(threshold - t.played)
+}
+
+// scrobbleQueue holds each service's unsent scrobbles, optionally persisted.
+type scrobbleQueue struct {
+	mu      sync.Mutex
+	path    string
+	pending map[string][]scrobbleTrack
+	logger  *slog.Logger
+}
+
+// loadScrobbleQueue creates the queue for services, restoring path if it exists.
+func loadScrobbleQueue(path string, services []string, logger *slog.Logger) *scrobbleQueue {
+	q := &scrobbleQueue{path: path, pending: make(map[string][]scrobbleTrack, len(services)), logger: logger}
+	var saved map[string][]scrobbleTrack
+	if path != "" {
+		if b, err := os.ReadFile(path); err == nil {
+			if err := json.Unmarshal(b, &saved); err != nil {
+				logger.Warn("scrobble queue unreadable; starting empty", "path", path, "error", err)
+			}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			logger.Warn("scrobble queue unreadable; starting empty", "path", path, "error", err)
+		}
+	}
+	for _, s := range services {
+		q.pending[s] = saved[s]
+		if n := len(saved[s]); n > 0 {
+			logger.Info("restored unsent scrobbles", "service", s, "count", n)
+		}
+	}
+	return q
+}
+
+// add queues a track for every service.
+func (q *scrobbleQueue) add(t scrobbleTrack) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for s, pending := range q.pending {
+		pending = append(pending, t)
+		if over := len(pending) - scrobbleQueueMax; over > 0 {
+			q.logger.Warn("scrobble queue full; dropping oldest", "service", s, "dropped", over)
+			pending = slices.Delete(pending, 0, over)
+		}
+		q.pending[s] = pending
+	}
+	q.save()
+}
+
+// peek returns up to n of service's oldest scrobbles.
+func (q *scrobbleQueue) peek(service string, n int) []scrobbleTrack {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	pending := q.pending[service]
+	return slices.Clone(pending[:min(n, len(pending))])
+}
+
+// drop removes service's n oldest scrobbles.
+func (q *scrobbleQueue) drop(service string, n int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending[service] = slices.Delete(q.pending[service], 0, min(n, len(q.pending[service])))
+	q.save()
+}
+
+// save writes the queue to path (q.mu held).
+func (q *scrobbleQueue) save() {
+	if q.path == "" {
+		return
+	}
+	err := func() error {
+		b, err := json.Marshal(q.pending)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(q.path), 0o700); err != nil {
+			return err
+		}
+		tmp := q.path + ".tmp"
+		if err := os.WriteFile(tmp, b, 0o600); err != nil {
+			return err
+		}
+		return os.Rename(tmp, q.path)
+	}()
+	if err != nil {
+		q.logger.Warn("failed to save scrobble queue", "path", q.path, "error", err)
+	}
+}
+
+// scrobbleWorker delivers one service's queue.
+type scrobbleWorker struct {
+	svc        scrobbleService
+	queue      *scrobbleQueue
+	wake       chan struct{}
+	nowPlaying chan scrobbleTrack
+	logger     *slog.Logger
+}
+
+func (w *scrobbleWorker) run(ctx context.Context) {
+	var backoff time.Duration
+	retry := time.NewTimer(0) // deliver anything restored from the queue file
+	for {
+		select {
+		case <-ctx.Done():
+			retry.Stop()
+			return
+		case t := <-w.nowPlaying:
+			if err := w.svc.NowPlaying(ctx, t); err != nil && ctx.Err() == nil {
+				w.logger.Debug("now playing update failed", "service", w.svc.Name(), "error", err)
+			}
+			continue
+		case <-w.wake:
+			if backoff > 0 {
+				continue // a retry is already scheduled
+			}
+		case <-retry.C:
+		}
+
+		if err := w.flush(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			backoff = min(max(2*backoff, scrobbleRetryMin), scrobbleRetryMax)
+			w.logger.Warn("scrobble failed; will retry", "service", w.svc.Name(), "error", err, "retry_in", backoff)
+			retry.Reset(backoff)
+			continue
+		}
+		backoff = 0
+	}
+}
+
+// flush submits the queue in batches until it is empty or a submission fails.
+func (w *scrobbleWorker) flush(ctx context.Context) error {
+	for {
+		batch := w.queue.peek(w.svc.Name(), w.svc.MaxBatch())
+		if len(batch) == 0 {
+			return nil
+		}
+		err := w.svc.Scrobble(ctx, batch)
+		var rejected *scrobbleRejectedError
+		switch {
+		case errors.As(err, &rejected):
+			w.logger.Error("scrobbles rejected; dropping them", "service", w.svc.Name(), "count", len(batch), "error", err)
+		case err != nil:
+			return err
+		default:
+			w.logger.Info("scrobbled", "service", w.svc.Name(), "count", len(batch), "artist", batch[len(batch)-1].Artist, "title", batch[len(batch)-1].Title)
+		}
+		w.queue.drop(w.svc.Name(), len(batch))
+	}
+}
+
+// newScrobbleServices builds the enabled services, reading their secrets.
+func newScrobbleServices(cfg ScrobbleConfig) ([]scrobbleService, error) {
+	var out []scrobbleService
+	if cfg.ListenBrainz.Enabled {
+		s, err := newListenBrainzScrobbler(cfg.ListenBrainz)
+		if err != nil {
+			return nil, fmt.Errorf("listenbrainz: %w", err)
+		}
+		out = append(out, s)
+	}
+	if cfg.LastFM.Enabled {
+		s, err := newLastFMScrobbler(cfg.LastFM)
+		if err != nil {
+			return nil, fmt.Errorf("lastfm: %w", err)
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// runScrobbler scrobbles the tracks reported in player_changed broadcasts.
+func runScrobbler(ctx context.Context, cfg ScrobbleConfig, services []scrobbleService, src <-chan StateBroadcast, logger *slog.Logger) {
+	names := make([]string, 0, len(services))
+	for _, s := range services {
+		names = append(names, s.Name())
+	}
+	queue := loadScrobbleQueue(cfg.QueueFile, names, logger)
+	workers := make([]*scrobbleWorker, 0, len(services))
+	for _, s := range services {
+		w := &scrobbleWorker{svc: s, queue: queue, wake: make(chan struct{}, 1), nowPlaying: make(chan scrobbleTrack, 1), logger: logger}
+		workers = append(workers, w)
+		go w.run(ctx)
+	}
+	logger.Info("scrobbling enabled", "services", names)
+
+	submit := func(due *scrobbleTrack) {
+		if due == nil {
+			return
+		}
+		queue.add(*due)
+		for _, w := range workers {
+			select {
+			case w.wake <- struct{}{}:
+			default:
+			}
+		}
+	}
+
+	var tracker scrobbleTracker
+	timer := time.NewTimer(0)
+	<-timer.C
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			submit(tracker.check(time.Now()))
+		case b, ok := <-src:
+			if !ok {
+				return
+			}
+			if zb, isZone := b.(ZoneBroadcast); isZone {
+				b = zb.Broadcast
+			}
+			pc, isPlayer := b.(BroadcastPlayerChanged)
+			if !isPlayer || (len(cfg.Sources) > 0 && !slices.Contains(cfg.Sources, pc.Source)) {
+				continue
+			}
+			if pc.At.IsZero() {
+				pc.At = time.Now()
+			}
+			due, np := tracker.update(pc)
+			submit(due)
+			if np != nil {
+				for _, w := range workers {
+					select {
+					case <-w.nowPlaying: // replace a stale update
+					default:
+					}
+					w.nowPlaying <- *np
+				}
+			}
+		}
+		timer.Stop()
+		if d := tracker.deadline(); !d.IsZero() {
+			timer.Reset(time.Until(d))
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestScrobbleThreshold(t *testing.T) {
+	cases := []struct {
+		durationMs int64
+		want       time.Duration
+		ok         bool
+	}{
+		{0, 4 * time.Minute, true},
+		{20_000, 0, false},
+		{180_000, 90 * time.Second, true},
+		{600_000, 4 * time.Minute, true},
+	}
+	for _, c := range cases {
+		got, ok := scrobbleThreshold(c.durationMs)
+		if got != c.want || ok != c.ok {
+			t.Errorf("scrobbleThreshold(%d) = %v, %v; want %v, %v", c.durationMs, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestScrobbleTracker_CountsPlayTimeAcrossPauses(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	track := BroadcastPlayerChanged{Source: "plex", Artist: "Nina Simone", Title: "Sinnerman", DurationMs: 180_000}
+	at := func(d time.Duration, state string) BroadcastPlayerChanged {
+		b := track
+		b.State, b.At = state, t0.Add(d)
+		return b
+	}
+
+	var tr scrobbleTracker
+	due, np := tr.update(at(0, "playing"))
+	if due != nil || np == nil || np.Title != "Sinnerman" {
+		t.Fatalf("start: due=%v nowPlaying=%v", due, np)
+	}
+	if d := tr.deadline(); !d.Equal(t0.Add(90 * time.Second)) {
+		t.Fatalf("deadline = %v", d)
+	}
+
+	// 60s played, 5min paused, then 30s more reaches the 90s threshold.
+	if due, np = tr.update(at(60*time.Second, "paused")); due != nil || np != nil {
+		t.Fatalf("pause: due=%v nowPlaying=%v", due, np)
+	}
+	if d := tr.deadline(); !d.IsZero() {
+		t.Fatalf("deadline while paused = %v", d)
+	}
+	if _, np = tr.update(at(6*time.Minute, "playing")); np == nil {
+		t.Fatal("expected a now playing update on resume")
+	}
+	if got := tr.check(t0.Add(6*time.Minute + 29*time.Second)); got != nil {
+		t.Fatalf("due too early: %+v", got)
+	}
+	got := tr.check(t0.Add(6*time.Minute + 30*time.Second))
+	if got == nil || got.Artist != "Nina Simone" || !got.StartedAt.Equal(t0) {
+		t.Fatalf("expected scrobble, got %+v", got)
+	}
+	if again := tr.check(t0.Add(10 * time.Minute)); again != nil {
+		t.Fatalf("scrobbled twice: %+v", again)
+	}
+}
+
+func TestScrobbleTracker_SkipsShortAndUntitled(t *testing.T) {
+	t0 := time.Now()
+	var tr scrobbleTracker
+	tr.update(BroadcastPlayerChanged{Source: "tidal", State: "playing", Artist: "A", Title: "Jingle", DurationMs: 10_000, At: t0})
+	if got := tr.check(t0.Add(time.Hour)); got != nil {
+		t.Fatalf("short track scrobbled: %+v", got)
+	}
+	tr.update(BroadcastPlayerChanged{Source: "librespot", State: "playing", Title: "No artist", At: t0})
+	if got := tr.check(t0.Add(time.Hour)); got != nil {
+		t.Fatalf("untitled track scrobbled: %+v", got)
+	}
+}
+
+func TestScrobbleTracker_TrackChangeSubmitsDueTrack(t *testing.T) {
+	t0 := time.Now()
+	var tr scrobbleTracker
+	tr.update(BroadcastPlayerChanged{Source: "plex", State: "playing", Artist: "A", Title: "One", At: t0})
+	due, np := tr.update(BroadcastPlayerChanged{Source: "plex", State: "playing", Artist: "A", Title: "Two", At: t0.Add(5 * time.Minute)})
+	if due == nil || due.Title != "One" {
+		t.Fatalf("expected One to be due, got %+v", due)
+	}
+	if np == nil || np.Title != "Two" {
+		t.Fatalf("expected now playing Two, got %+v", np)
+	}
+}
+
+func TestScrobbleQueue_PersistsAcrossRestarts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "scrobbles.json")
+	logger := slog.New(slog.DiscardHandler)
+	q := loadScrobbleQueue(path, []string{"listenbrainz", "lastfm"}, logger)
+	q.add(scrobbleTrack{Artist: "A", Title: "One"})
+	q.add(scrobbleTrack{Artist: "A", Title: "Two"})
+	q.drop("lastfm", 1)
+
+	q = loadScrobbleQueue(path, []string{"listenbrainz", "lastfm"}, logger)
+	if got := q.peek("listenbrainz", 10); len(got) != 2 || got[0].Title != "One" {
+		t.Fatalf("listenbrainz queue = %+v", got)
+	}
+	if got := q.peek("lastfm", 10); len(got) != 1 || got[0].Title != "Two" {
+		t.Fatalf("lastfm queue = %+v", got)
+	}
+}
+
+// fakeScrobbler records submissions and fails while err is set.
+type fakeScrobbler struct {
+	mu       sync.Mutex
+	err      error
+	batches  [][]scrobbleTrack
+	maxBatch int
+}
+
+func (f *fakeScrobbler) Name() string  { return "fake" }
+func (f *fakeScrobbler) MaxBatch() int { return f.maxBatch }
+
+func (f *fakeScrobbler) Scrobble(ctx context.Context, tracks []scrobbleTrack) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	f.batches = append(f.batches, tracks)
+	return nil
+}
+
+func (f *fakeScrobbler) NowPlaying(ctx context.Context, track scrobbleTrack) error { return nil }
+
+func TestScrobbleWorker_FlushKeepsQueueOnFailure(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+	svc := &fakeScrobbler{maxBatch: 2, err: errors.New("offline")}
+	q := loadScrobbleQueue("", []string{"fake"}, logger)
+	for _, title := range []string{"One", "Two", "Three"} {
+		q.add(scrobbleTrack{Artist: "A", Title: title})
+	}
+	w := &scrobbleWorker{svc: svc, queue: q, logger: logger}
+
+	if err := w.flush(context.Background()); err == nil {
+		t.Fatal("expected an error while offline")
+	}
+	if got := q.peek("fake", 10); len(got) != 3 {
+		t.Fatalf("queue lost scrobbles: %+v", got)
+	}
+
+	svc.err = nil
+	if err := w.flush(context.Background()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if len(svc.batches) != 2 || len(svc.batches[0]) != 2 || svc.batches[1][0].Title != "Three" {
+		t.Fatalf("batches = %+v", svc.batches)
+	}
+	if got := q.peek("fake", 10); len(got) != 0 {
+		t.Fatalf("queue not drained: %+v", got)
+	}
+}
+
+func TestScrobbleWorker_DropsRejectedBatch(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+	svc := &fakeScrobbler{maxBatch: 10, err: &scrobbleRejectedError{err: errors.New("bad listen")}}
+	q := loadScrobbleQueue("", []string{"fake"}, logger)
+	q.add(scrobbleTrack{Artist: "A", Title: "One"})
+	w := &scrobbleWorker{svc: svc, queue: q, logger: logger}
+	if err := w.flush(context.Background()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if got := q.peek("fake", 10); len(got) != 0 {
+		t.Fatalf("rejected scrobble kept: %+v", got)
+	}
+}
+
+func TestRunScrobbler_FiltersSources(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	svc := &fakeScrobbler{maxBatch: 10}
+	src := make(chan StateBroadcast)
+	go runScrobbler(ctx, ScrobbleConfig{Sources: []string{"plex"}}, []scrobbleService{svc}, src, slog.New(slog.DiscardHandler))
+
+	t0 := time.Now().Add(-time.Hour)
+	for _, source := range []string{"tidal", "plex"} {
+		src <- ZoneBroadcast{Zone: "main", Broadcast: BroadcastPlayerChanged{Source: source, State: "playing", Artist: "A", Title: source, At: t0}}
+		src <- ZoneBroadcast{Zone: "main", Broadcast: BroadcastPlayerChanged{Source: source, State: "stopped", At: t0.Add(5 * time.Minute)}}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		svc.mu.Lock()
+		batches := svc.batches
+		svc.mu.Unlock()
+		if len(batches) > 0 {
+			if len(batches) != 1 || batches[0][0].Title != "plex" {
+				t.Fatalf("batches = %+v", batches)
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("nothing scrobbled")
+}
+
+func TestConfigValidate_Scrobble(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Scrobble.Enabled = true
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected error without a service")
+	}
+	cfg.Scrobble.ListenBrainz = ListenBrainzConfig{Enabled: true, Token: "env:LB_TOKEN"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg.Scrobble.Sources = []string{"vinyl"}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected error for an unknown source")
+	}
+	cfg.Scrobble.Sources = nil
+	cfg.Scrobble.LastFM = LastFMConfig{Enabled: true, APIKey: "key"}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected error without last.fm secret and session key")
+	}
+}
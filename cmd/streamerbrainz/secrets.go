@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// Secret sources
+// ============================================================================
+// Config fields that name a secret (plex.token_file, webhooks.event.token_file,
+// limit_override.token_file, camilladsp.password_file, outbound_webhooks[].secret,
+// alerts.channels[].token/secret...) accept a source reference:
+//
+//   /path/to/file         read the file (also "file:/path/to/file")
+//   env:NAME              the value of environment variable NAME
+//   credential:NAME       a systemd credential (LoadCredential=NAME:...), read from
+//                         $CREDENTIALS_DIRECTORY/NAME
+//   exec:COMMAND          stdout of COMMAND, run with /bin/sh -c
+//
+// Leading/trailing whitespace is trimmed; an empty secret is an error.
+// ============================================================================
+
+const (
+	secretPrefixFile       = "file:"
+	secretPrefixEnv        = "env:"
+	secretPrefixCredential = "credential:"
+	secretPrefixExec       = "exec:"
+)
+
+// secretExecTimeout bounds exec: secret commands (e.g. a password manager CLI).
+const secretExecTimeout = 10 * time.Second
+
+// validateSecretRef checks the syntax of a secret reference without reading it.
+func validateSecretRef(ref string) error {
+	switch {
+	case strings.HasPrefix(ref, secretPrefixFile):
+		if strings.TrimPrefix(ref, secretPrefixFile) == "" {
+			return errors.New("file: secret needs a path")
+		}
+	case strings.HasPrefix(ref, secretPrefixEnv):
+		if strings.TrimPrefix(ref, secretPrefixEnv) == "" {
+			return errors.New("env: secret needs a variable name")
+		}
+	case strings.HasPrefix(ref, secretPrefixCredential):
+		name := strings.TrimPrefix(ref, secretPrefixCredential)
+		if name == "" || strings.ContainsRune(name, '/') {
+			return fmt.Errorf("credential: secret needs a plain credential name, got %q", name)
+		}
+	case strings.HasPrefix(ref, secretPrefixExec):
+		if strings.TrimSpace(strings.TrimPrefix(ref, secretPrefixExec)) == "" {
+			return errors.New("exec: secret needs a command")
+		}
+	}
+	return nil
+}
+
+// readSecret resolves a secret reference (see above) to its trimmed value.
+func readSecret(ref string) (string, error) {
+	if err := validateSecretRef(ref); err != nil {
+		return "", err
+	}
+
+	var (
+		value string
+		err   error
+	)
+	switch {
+	case strings.HasPrefix(ref, secretPrefixEnv):
+		name := strings.TrimPrefix(ref, secretPrefixEnv)
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		value = v
+
+	case strings.HasPrefix(ref, secretPrefixCredential):
+		dir := os.Getenv("CREDENTIALS_DIRECTORY")
+		if dir == "" {
+			return "", errors.New("CREDENTIALS_DIRECTORY is not set (is LoadCredential= configured in the unit?)")
+		}
+		value, err = readSecretFile(filepath.Join(dir, strings.TrimPrefix(ref, secretPrefixCredential)))
+
+	case strings.HasPrefix(ref, secretPrefixExec):
+		value, err = readSecretCommand(strings.TrimPrefix(ref, secretPrefixExec))
+
+	default:
+		value, err = readSecretFile(ExpandPath(strings.TrimPrefix(ref, secretPrefixFile)))
+	}
+	if err != nil {
+		return "", err
+	}
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", errors.New("secret is empty")
+	}
+	return value, nil
+}
+
+func readSecretFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read secret file: %w", err)
+	}
+	return string(b), nil
+}
+
+func readSecretCommand(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), secretExecTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("secret command failed: %w: %s", err, msg)
+		}
+		return "", fmt.Errorf("secret command failed: %w", err)
+	}
+	return stdout.String(), nil
+}
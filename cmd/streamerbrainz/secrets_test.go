@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadSecret_Sources(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "token")
+	if err := os.WriteFile(file, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "plex"), []byte("from-credential"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SB_TEST_TOKEN", " from-env ")
+	t.Setenv("CREDENTIALS_DIRECTORY", dir)
+
+	for ref, want := range map[string]string{
+		file:                     "from-file",
+		"file:" + file:           "from-file",
+		"env:SB_TEST_TOKEN":      "from-env",
+		"credential:plex":        "from-credential",
+		"exec:echo from-exec":    "from-exec",
+		"exec:printf ' x y \\n'": "x y",
+	} {
+		got, err := readSecret(ref)
+		if err != nil {
+			t.Fatalf("readSecret(%q): %v", ref, err)
+		}
+		if got != want {
+			t.Fatalf("readSecret(%q) = %q, want %q", ref, got, want)
+		}
+	}
+}
+
+func TestReadSecret_Errors(t *testing.T) {
+	t.Setenv("SB_TEST_EMPTY", "  ")
+	t.Setenv("CREDENTIALS_DIRECTORY", t.TempDir())
+
+	for _, ref := range []string{
+		filepath.Join(t.TempDir(), "missing"),
+		"env:SB_TEST_UNSET_VARIABLE",
+		"env:SB_TEST_EMPTY",
+		"env:",
+		"credential:../etc/passwd",
+		"credential:missing",
+		"exec:exit 3",
+		"exec: ",
+	} {
+		if _, err := readSecret(ref); err == nil {
+			t.Fatalf("readSecret(%q): expected error", ref)
+		}
+	}
+}
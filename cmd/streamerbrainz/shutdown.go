@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// Shutdown policy (shutdown)
+// ============================================================================
+// CamillaDSP keeps playing at its last volume after the daemon exits, with
+// nothing left to control it. shutdown.action makes each zone safe first:
+// "mute" mutes it, "volume" lowers it to shutdown.volume_db (never raising a
+// quieter level). This runs on every orderly exit (SIGINT/SIGTERM, e.g. a
+// reboot, and after a crash dump), bounded by shutdownPolicyTimeout so an
+// unreachable CamillaDSP doesn't hold up the exit. The default, "none", leaves
+// CamillaDSP as it is.
+// ============================================================================
+
+// Shutdown actions.
+const (
+	shutdownActionNone   = "none"
+	shutdownActionMute   = "mute"
+	shutdownActionVolume = "volume"
+)
+
+const shutdownPolicyTimeout = 3 * time.Second
+
+// shutdownGrace bounds the whole shutdown after SIGTERM/SIGINT, below the 10 s
+// container runtimes wait before SIGKILL (see container.go).
+const shutdownGrace = 8 * time.Second
+
+// applyShutdownPolicy applies cfg.Action to every zone's CamillaDSP (zones[i]
+// names clients[i]) and waits for it, at most shutdownPolicyTimeout.
+func applyShutdownPolicy(cfg ShutdownConfig, zones []string, clients []CamillaDSPClientInterface, logger *slog.Logger) {
+	if cfg.Action != shutdownActionMute && cfg.Action != shutdownActionVolume {
+		return
+	}
+	// The timeout cancels calls still waiting for CamillaDSP.
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownPolicyTimeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	for i, c := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := applyShutdownAction(ctx, cfg, c, logger.With("zone", zones[i])); err != nil {
+				logger.Warn("shutdown action failed", "zone", zones[i], "action", cfg.Action, "error", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		logger.Warn("shutdown action timed out", "action", cfg.Action)
+	}
+}
+
+func applyShutdownAction(ctx context.Context, cfg ShutdownConfig, c CamillaDSPClientInterface, logger *slog.Logger) error {
+	if cfg.Action == shutdownActionMute {
+		if err := c.SetMute(ctx, true); err != nil {
+			return err
+		}
+		logger.Info("muted on shutdown")
+		return nil
+	}
+	v, err := c.GetVolume(ctx)
+	if err != nil {
+		return err
+	}
+	if v <= cfg.VolumeDB {
+		return nil
+	}
+	if _, err := c.SetVolume(ctx, cfg.VolumeDB); err != nil {
+		return err
+	}
+	logger.Info("volume lowered on shutdown", "from_db", v, "to_db", cfg.VolumeDB)
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+)
+
+func TestApplyShutdownPolicy(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+	run := func(cfg ShutdownConfig, clients ...*mockCamillaDSPClient) {
+		zones := make([]string, len(clients))
+		ifaces := make([]CamillaDSPClientInterface, len(clients))
+		for i, c := range clients {
+			zones[i], ifaces[i] = "zone", c
+		}
+		applyShutdownPolicy(cfg, zones, ifaces, logger)
+	}
+
+	loud, quiet := newMockCamillaDSPClient(-10), newMockCamillaDSPClient(-60)
+	run(ShutdownConfig{Action: shutdownActionVolume, VolumeDB: -45}, loud, quiet)
+	if loud.volume != -45 {
+		t.Fatalf("loud zone left at %v dB", loud.volume)
+	}
+	if len(quiet.setVolumeLog()) != 0 {
+		t.Fatalf("quieter zone raised: %v", quiet.setVolumeLog())
+	}
+
+	c := newMockCamillaDSPClient(-10)
+	run(ShutdownConfig{Action: shutdownActionMute}, c)
+	if !c.muted || c.volume != -10 {
+		t.Fatalf("muted = %v, volume = %v", c.muted, c.volume)
+	}
+
+	c = newMockCamillaDSPClient(-10)
+	run(ShutdownConfig{Action: shutdownActionNone, VolumeDB: -45}, c)
+	if len(c.callLog()) != 0 {
+		t.Fatalf("none touched CamillaDSP: %v", c.callLog())
+	}
+
+	// A failing zone doesn't keep the others from being made safe.
+	bad := newMockCamillaDSPClient(-10)
+	bad.failOn = map[string]error{"SetMute": errors.New("connection refused")}
+	good := newMockCamillaDSPClient(-10)
+	run(ShutdownConfig{Action: shutdownActionMute}, bad, good)
+	if bad.muted || !good.muted || !slices.Equal(bad.callLog(), []string{"SetMute"}) {
+		t.Fatalf("bad muted = %v, good muted = %v", bad.muted, good.muted)
+	}
+}
+
+func TestConfigValidate_Shutdown(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Shutdown.Action = "off"
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected error for an unknown action")
+	}
+	cfg.Shutdown.Action = shutdownActionVolume
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg.Shutdown.VolumeDB = 3
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected error for volume_db above 0 dB")
+	}
+}
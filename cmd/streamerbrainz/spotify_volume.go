@@ -1,7 +1,5 @@
 package main
 
-import "math"
-
 // mapSpotifyVolumeToDB maps Spotify volume (0-65535) to dB range.
 // Uses logarithmic mapping for better perceived volume control.
 //
@@ -16,13 +14,6 @@ func mapSpotifyVolumeToDB(spotifyVol uint16, minDB, maxDB float64) float64 {
 		return maxDB
 	}
 
-	// Normalize to 0.0-1.0
-	normalized := float64(spotifyVol) / spotifyVolumeMax
-
-	// Apply logarithmic curve
-	dbRange := maxDB - minDB
-	logValue := math.Log10(1.0 + 9.0*normalized)
-	db := minDB + dbRange*logValue
-
-	return db
+	// Normalize to 0.0-1.0 and apply the logarithmic curve.
+	return volumeFractionToDB(float64(spotifyVol)/spotifyVolumeMax, minDB, maxDB)
 }
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"time"
+)
+
+// ============================================================================
+// Broadcast coalescing
+// ============================================================================
+// High-rate topics (volume_changed while a knob turns; future levels or
+// playback position) are throttled latest-wins: the first event of a topic
+// starts its window (websocket.coalesce_ms), later ones replace the pending
+// event, and the newest one is sent when the window ends. Windows are not
+// extended by further updates, so a continuous stream still goes out once per
+// window. Pending events are kept per (topic, zone) so a burst in one zone
+// never swallows another zone's update.
+//
+// Every other event is discrete: it first flushes whatever is pending (so
+// clients never see a discrete event before the state change preceding it)
+// and is then sent immediately, in order.
+// ============================================================================
+
+// defaultCoalesceWindows is websocket.coalesce_ms when not configured.
+var defaultCoalesceWindows = map[string]int{
+	"volume_changed": 50,
+}
+
+type coalesceKey struct {
+	topic string
+	zone  string
+}
+
+type coalescePending struct {
+	key coalesceKey
+	ev  wsOutboundEvent
+	due time.Time
+}
+
+// broadcastCoalescer holds the pending latest-wins events. It is not safe for
+// concurrent use; RunBroadcaster owns it.
+type broadcastCoalescer struct {
+	windows map[string]time.Duration
+	pending []coalescePending // in arrival order of each key's first event
+}
+
+// newBroadcastCoalescer builds a coalescer from topic → window in milliseconds.
+// Topics with a window <= 0 are discrete.
+func newBroadcastCoalescer(windowsMs map[string]int) *broadcastCoalescer {
+	c := &broadcastCoalescer{windows: map[string]time.Duration{}}
+	for topic, ms := range windowsMs {
+		if ms > 0 {
+			c.windows[topic] = time.Duration(ms) * time.Millisecond
+		}
+	}
+	return c
+}
+
+// add handles an event received at now. It returns the events to send right
+// away: nothing for a coalesced topic, otherwise the flushed pending events
+// followed by ev.
+func (c *broadcastCoalescer) add(ev wsOutboundEvent, now time.Time) []wsOutboundEvent {
+	window, ok := c.windows[ev.Type]
+	if !ok {
+		return append(c.flushAll(), ev)
+	}
+	key := coalesceKey{topic: ev.Type, zone: ev.Zone}
+	for i := range c.pending {
+		if c.pending[i].key == key {
+			c.pending[i].ev = ev
+			return nil
+		}
+	}
+	c.pending = append(c.pending, coalescePending{key: key, ev: ev, due: now.Add(window)})
+	return nil
+}
+
+// flushDue removes and returns the pending events whose window has ended.
+func (c *broadcastCoalescer) flushDue(now time.Time) []wsOutboundEvent {
+	var out []wsOutboundEvent
+	kept := c.pending[:0]
+	for _, p := range c.pending {
+		if now.Before(p.due) {
+			kept = append(kept, p)
+			continue
+		}
+		out = append(out, p.ev)
+	}
+	clear(c.pending[len(kept):])
+	c.pending = kept
+	return out
+}
+
+// flushAll removes and returns every pending event.
+func (c *broadcastCoalescer) flushAll() []wsOutboundEvent {
+	var out []wsOutboundEvent
+	for _, p := range c.pending {
+		out = append(out, p.ev)
+	}
+	clear(c.pending)
+	c.pending = c.pending[:0]
+	return out
+}
+
+// nextDue returns when the earliest pending window ends (false if none).
+func (c *broadcastCoalescer) nextDue() (time.Time, bool) {
+	var next time.Time
+	for _, p := range c.pending {
+		if next.IsZero() || p.due.Before(next) {
+			next = p.due
+		}
+	}
+	return next, !next.IsZero()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func eventTypes(events []wsOutboundEvent) []string {
+	var out []string
+	for _, ev := range events {
+		out = append(out, ev.Type+"@"+ev.Zone)
+	}
+	return out
+}
+
+func TestBroadcastCoalescer_LatestWinsPerTopicAndZone(t *testing.T) {
+	c := newBroadcastCoalescer(map[string]int{"volume_changed": 50, "levels": 100, "mute_changed": 0})
+	t0 := time.Now()
+
+	for i, ev := range []wsOutboundEvent{
+		{Type: "volume_changed", Data: -40.0},
+		{Type: "levels", Data: 1},
+		{Type: "volume_changed", Zone: "phones", Data: -20.0},
+		{Type: "volume_changed", Data: -39.0},
+		{Type: "levels", Data: 2},
+	} {
+		if out := c.add(ev, t0.Add(time.Duration(i)*time.Millisecond)); len(out) != 0 {
+			t.Fatalf("coalesced event %d sent immediately: %v", i, eventTypes(out))
+		}
+	}
+	if due, ok := c.nextDue(); !ok || !due.Equal(t0.Add(50*time.Millisecond)) {
+		t.Fatalf("nextDue = %v, %v", due, ok)
+	}
+
+	// Windows run from each key's first event and aren't extended by updates.
+	out := c.flushDue(t0.Add(52 * time.Millisecond))
+	if got := eventTypes(out); len(got) != 2 || got[0] != "volume_changed@" || got[1] != "volume_changed@phones" {
+		t.Fatalf("flushDue = %v", got)
+	}
+	if out[0].Data != -39.0 {
+		t.Fatalf("volume not latest-wins: %v", out[0].Data)
+	}
+	if due, ok := c.nextDue(); !ok || !due.Equal(t0.Add(101*time.Millisecond)) {
+		t.Fatalf("nextDue = %v, %v", due, ok)
+	}
+
+	// Discrete events (including topics with a 0 window) flush pending ones first.
+	out = c.add(wsOutboundEvent{Type: "mute_changed"}, t0.Add(60*time.Millisecond))
+	if got := eventTypes(out); len(got) != 2 || got[0] != "levels@" || got[1] != "mute_changed@" || out[0].Data != 2 {
+		t.Fatalf("discrete add = %v", got)
+	}
+	if _, ok := c.nextDue(); ok {
+		t.Fatal("events still pending after discrete flush")
+	}
+}
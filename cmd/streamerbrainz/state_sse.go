@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ============================================================================
+// State Server-Sent Events (SSE)
+// ============================================================================
+//
+// GET /events streams the same JSON envelopes as the state WebSocket ({type, ts, data})
+// as `text/event-stream`. This is useful where WebSockets are awkward (curl, some
+// smart displays, simple dashboards).
+//
+// Design:
+//   - SSE subscribers are regular hub Clients with a nil websocket.Conn. The hub
+//     treats them exactly like WS clients (per-subscriber send queue, slow-client
+//     eviction, admission limits), so the broadcaster needs no changes.
+//   - The initial frame is "state_init", requested through the reducer/event loop.
+//   - A comment line is written every pingPeriod to keep proxies from timing out.
+//
+// ============================================================================
+
+// RegisterSSE registers the SSE handler on the provided mux.
+func (s *Server) RegisterSSE(mux *http.ServeMux, path string) {
+	if mux == nil {
+		return
+	}
+	mux.HandleFunc(path, s.handleStateSSE)
+}
+
+// handleStateSSE registers a hub client and streams its send queue as SSE frames.
+func (s *Server) handleStateSSE(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	if !s.checkOrigin(r) {
+		s.logger.Warn("sse origin rejected", "remote_addr", r.RemoteAddr, "origin", r.Header.Get("Origin"))
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	ip := remoteIP(r)
+	if err := s.hub.Admit(ip); err != nil {
+		s.logger.Warn("sse connection rejected", "remote_addr", r.RemoteAddr, "reason", err)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	client := NewClient(s.hub, nil, r.RemoteAddr, s.logger)
+	client.ip = ip
+	client.admitted = true
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no") // disable nginx response buffering
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// Register client first so broadcasts can reach it.
+	s.hub.register <- client
+
+	s.sendStateInit(r.Context(), client)
+
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			// Client went away: let the hub release its slot and close the queue.
+			s.hub.unregister <- client
+			return
+
+		case msg, ok := <-client.send:
+			if !ok {
+				// Hub is disconnecting us (slow client or shutdown).
+				return
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", msg); err != nil {
+				s.logger.Info("sse stream exiting (write error)", "remote_addr", client.remoteAddr, "error", err)
+				s.hub.unregister <- client
+				return
+			}
+			flusher.Flush()
+
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				s.hub.unregister <- client
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServer_SSEStreamsHubBroadcasts(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srv := NewServer(slog.Default(), nil, ServerConfig{Hub: HubConfig{SendBuf: 4, BroadcastBuf: 8}})
+	go srv.Hub().Run(ctx)
+
+	mux := http.NewServeMux()
+	srv.RegisterSSE(mux, "/events")
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/events")
+	if err != nil {
+		t.Fatalf("GET /events: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+
+	waitUntil(t, 500*time.Millisecond, func() bool {
+		srv.Hub().mu.Lock()
+		defer srv.Hub().mu.Unlock()
+		return len(srv.Hub().clients) == 1
+	}, "sse client not registered in time")
+
+	msg := `{"type":"mute_changed","data":{"muted":true}}`
+	srv.Hub().broadcast <- []byte(msg)
+
+	lines := make(chan string, 1)
+	go func() {
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			if strings.HasPrefix(sc.Text(), "data: ") {
+				lines <- strings.TrimPrefix(sc.Text(), "data: ")
+				return
+			}
+		}
+	}()
+
+	select {
+	case got := <-lines:
+		if want := `{"seq":1,` + msg[1:]; got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timeout waiting for sse frame")
+	}
+}
@@ -5,8 +5,12 @@ import (
 	"encoding/json"
 	"errors"
 	"log/slog"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -29,14 +33,30 @@ import (
 //
 // Notes:
 //   - Slow clients are disconnected when their send buffer fills.
-//   - Messages are JSON text frames with an envelope: {type, ts, data}.
-//   - The initial message on connect is "state_init" with StateSnapshot in data.
+//   - Messages are JSON text frames with an envelope: {seq, type, ts, data}.
+//   - The initial message on connect is "state_init" with StateSnapshot in data,
+//     tagged with protocol_version and capabilities (see state_ws_protocol.go).
+//   - Broadcasts carry a "seq"; reconnecting clients can resume from the last one
+//     they saw instead of taking a new snapshot (see state_ws_resume.go).
+//   - Browser Origins are checked against an optional allow-list (403 on mismatch).
+//   - Connection limits (total and per remote IP) are enforced before upgrading (503).
 //
 // ============================================================================
 
 // wsMessageSnapshot is the JSON `data` payload for the WS "state_init" event.
 // Keep this decoupled from internal state; expand over time (players, etc).
 type wsMessageSnapshot struct {
+	// ProtocolVersion and Capabilities describe the stream (state_ws_protocol.go);
+	// they are only set on the top-level snapshot.
+	ProtocolVersion int      `json:"protocol_version,omitempty"`
+	Capabilities    []string `json:"capabilities,omitempty"`
+
+	// Stream identifies this daemon run's broadcast sequence (top-level only);
+	// clients pass it back with the last seq they saw to resume.
+	Stream string `json:"stream,omitempty"`
+
+	Zone string `json:"zone,omitempty"`
+
 	VolumeDB    float64   `json:"volume_db"`
 	VolumeKnown bool      `json:"volume_known"`
 	VolumeAt    time.Time `json:"volume_at"`
@@ -44,11 +64,58 @@ type wsMessageSnapshot struct {
 	Muted     bool      `json:"muted"`
 	MuteKnown bool      `json:"mute_known"`
 	MuteAt    time.Time `json:"mute_at"`
+
+	Output string `json:"output,omitempty"`
+
+	// PowerState is the CamillaDSP host's power state with Wake-on-LAN configured.
+	PowerState string `json:"power_state,omitempty"`
+
+	EncoderMode EncoderMode `json:"encoder_mode"`
+	BalanceDB   float64     `json:"balance_db"`
+	SubDB       float64     `json:"sub_db"`
+
+	Player *PlayerSnapshot `json:"player,omitempty"`
+
+	// Zones lists every zone's snapshot when multiple zones are configured.
+	Zones []wsMessageSnapshot `json:"zones,omitempty"`
+
+	// LinkedZones maps linked zone ids to their volume offsets (dB).
+	LinkedZones map[string]float64 `json:"linked_zones,omitempty"`
+
+	// Inputs lists input device statuses.
+	Inputs []InputDeviceStatus `json:"inputs,omitempty"`
+}
+
+// newWSMessageSnapshot converts a StateSnapshot into its wire representation.
+func newWSMessageSnapshot(snap StateSnapshot) wsMessageSnapshot {
+	out := wsMessageSnapshot{
+		Zone:        snap.Zone,
+		VolumeDB:    snap.VolumeDB,
+		VolumeKnown: snap.VolumeKnown,
+		VolumeAt:    snap.VolumeAt,
+		Muted:       snap.Muted,
+		MuteKnown:   snap.MuteKnown,
+		MuteAt:      snap.MuteAt,
+		Output:      snap.Output,
+		PowerState:  snap.PowerState,
+		EncoderMode: snap.EncoderMode,
+		BalanceDB:   snap.BalanceDB,
+		SubDB:       snap.SubDB,
+		Player:      snap.Player,
+		LinkedZones: snap.LinkedZones,
+		Inputs:      snap.Inputs,
+	}
+	for _, z := range snap.Zones {
+		out.Zones = append(out.Zones, newWSMessageSnapshot(z))
+	}
+	return out
 }
 
 // wsVolumeChangedData is the JSON `data` payload for "volume_changed".
 type wsVolumeChangedData struct {
-	VolumeDB float64 `json:"volume_db"`
+	VolumeDB       float64 `json:"volume_db"`
+	Origin         string  `json:"origin,omitempty"`
+	ExternalChange bool    `json:"external_change,omitempty"`
 }
 
 // wsMuteChangedData is the JSON `data` payload for "mute_changed".
@@ -56,16 +123,150 @@ type wsMuteChangedData struct {
 	Muted bool `json:"muted"`
 }
 
+// wsPlayerChangedData is the JSON `data` payload for "player_changed".
+type wsPlayerChangedData struct {
+	Source     string `json:"source"`
+	State      string `json:"state"`
+	Title      string `json:"title,omitempty"`
+	Artist     string `json:"artist,omitempty"`
+	Album      string `json:"album,omitempty"`
+	DurationMs int64  `json:"duration_ms,omitempty"`
+}
+
+// wsZoneSelectedData is the JSON `data` payload for "zone_selected".
+type wsZoneSelectedData struct {
+	Zone string `json:"zone"`
+}
+
+// wsEncoderChangedData is the JSON `data` payload for "encoder_changed".
+type wsEncoderChangedData struct {
+	Mode      EncoderMode `json:"mode"`
+	BalanceDB float64     `json:"balance_db"`
+	SubDB     float64     `json:"sub_db"`
+}
+
+// wsDeviceDownData is the JSON `data` payload for "device_down".
+type wsDeviceDownData struct {
+	Device string `json:"device"`
+	Reason string `json:"reason"`
+}
+
+// wsDeviceUpData is the JSON `data` payload for "device_up".
+type wsDeviceUpData struct {
+	Device string `json:"device"`
+}
+
+// wsDSPConnectionChangedData is the JSON `data` payload for "dsp_connection_changed".
+type wsDSPConnectionChangedData struct {
+	Connected bool   `json:"connected"`
+	Error     string `json:"error,omitempty"`
+}
+
+// wsDSPCommandErrorData is the JSON `data` payload for "dsp_command_error".
+type wsDSPCommandErrorData struct {
+	Command string `json:"command"`
+	Class   string `json:"class"`
+	Error   string `json:"error,omitempty"`
+}
+
+// wsTestSignalData is the JSON `data` payload for "test_signal".
+type wsTestSignalData struct {
+	Channel string    `json:"channel"`
+	Until   time.Time `json:"until,omitzero"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// wsUpdateAvailableData is the JSON `data` payload for "update_available".
+type wsUpdateAvailableData struct {
+	Current string `json:"current"`
+	Latest  string `json:"latest"`
+	URL     string `json:"url"`
+}
+
+// wsControlRejectedData is the JSON `data` payload for "control_rejected".
+type wsControlRejectedData struct {
+	Origin string    `json:"origin"`
+	Holder string    `json:"holder"`
+	Until  time.Time `json:"until"`
+}
+
+// wsControllerChangedData is the JSON `data` payload for "controller_changed".
+type wsControllerChangedData struct {
+	HeldDirection  int      `json:"held_direction"`
+	Ramping        bool     `json:"ramping"`
+	VelocityDBPerS float64  `json:"velocity_db_per_s"`
+	TargetDB       *float64 `json:"target_db,omitempty"`
+}
+
+// wsUIHintData is the JSON `data` payload for "ui_hint".
+type wsUIHintData struct {
+	Hint       string   `json:"hint"`
+	DurationMS int      `json:"duration_ms"`
+	VolumeDB   *float64 `json:"volume_db,omitempty"`
+	Muted      *bool    `json:"muted,omitempty"`
+}
+
+// wsHoldCheckpointData is the JSON `data` payload for "hold_checkpoint".
+type wsHoldCheckpointData struct {
+	LevelDB float64 `json:"level_db"`
+}
+
+// wsSignalLevelsData is the JSON `data` payload for "signal_levels".
+type wsSignalLevelsData struct {
+	CamillaSignalLevels
+	Faders []CamillaFader `json:"faders"`
+}
+
+// wsCamillaFrameData is the JSON `data` payload for "camilladsp_frame".
+type wsCamillaFrameData struct {
+	Direction string `json:"direction"`
+	Frame     string `json:"frame"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// wsCalibrationModeData is the JSON `data` payload for "calibration_mode".
+type wsCalibrationModeData struct {
+	Active      bool    `json:"active"`
+	ReferenceDB float64 `json:"reference_db"`
+}
+
+// wsLimitOverrideChangedData is the JSON `data` payload for "limit_override_changed".
+type wsLimitOverrideChangedData struct {
+	Active bool      `json:"active"`
+	Until  time.Time `json:"until,omitzero"`
+	MinDB  float64   `json:"min_db"`
+	MaxDB  float64   `json:"max_db"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// wsIRSendData is the JSON `data` payload for "ir_send".
+type wsIRSendData struct {
+	Command string `json:"command"`
+}
+
+// wsOutputChangedData is the JSON `data` payload for "output_changed".
+type wsOutputChangedData struct {
+	Output string `json:"output"`
+}
+
+// wsZoneLinkChangedData is the JSON `data` payload for "zone_link_changed".
+type wsZoneLinkChangedData struct {
+	Offsets map[string]float64 `json:"offsets"`
+}
+
 // wsOutboundEvent is a pre-typed, externally-consumable state event.
 type wsOutboundEvent struct {
 	Type string
+	Zone string // zone the event belongs to; empty for daemon-global events
 	Data any
 	At   time.Time // optional timestamp; zero means "omit" or use now
 }
 
 // envelope is the wire format envelope for WS messages.
 type envelope struct {
+	Seq  uint64      `json:"seq,omitempty"`
 	Type string      `json:"type"`
+	Zone string      `json:"zone,omitempty"`
 	Ts   *time.Time  `json:"ts,omitempty"`
 	Data interface{} `json:"data,omitempty"`
 }
@@ -85,8 +286,21 @@ type Hub struct {
 	mu      sync.Mutex
 	clients map[*Client]struct{}
 
+	// Admission control (guarded by mu). Counts are taken before the upgrade and
+	// released when the client is removed, so they also cover in-flight handshakes.
+	admitted   int
+	admittedIP map[string]int
+
+	// Sequencing and replay (state_ws_resume.go). replay is owned by Run.
+	seq       atomic.Uint64
+	stream    string
+	replay    []wsReplayFrame
+	replayBuf int
+
 	// Configuration
-	sendBuf int
+	sendBuf    int
+	maxClients int
+	maxPerIP   int
 }
 
 type HubConfig struct {
@@ -97,8 +311,24 @@ type HubConfig struct {
 	// BroadcastBuf is the hub inbound broadcast queue size.
 	// If zero, a conservative default is used.
 	BroadcastBuf int
+
+	// MaxClients caps the number of concurrently connected clients. 0 means unlimited.
+	MaxClients int
+
+	// MaxClientsPerIP caps concurrent clients from a single remote IP. 0 means unlimited.
+	MaxClientsPerIP int
+
+	// ReplayBuf is the number of recent broadcasts kept for resuming clients.
+	// 0 disables resume (reconnecting clients always get a fresh state_init).
+	ReplayBuf int
 }
 
+// Admission errors returned by Hub.Admit.
+var (
+	errHubFull    = errors.New("too many clients")
+	errHubIPLimit = errors.New("too many clients from this address")
+)
+
 // NewHub constructs a hub. Call Run(ctx) to start it.
 func NewHub(logger *slog.Logger, cfg HubConfig) *Hub {
 	sendBuf := cfg.SendBuf
@@ -116,10 +346,53 @@ func NewHub(logger *slog.Logger, cfg HubConfig) *Hub {
 		register:   make(chan *Client, 64),
 		unregister: make(chan *Client, 64),
 		clients:    make(map[*Client]struct{}),
+		admittedIP: make(map[string]int),
+		stream:     newStreamID(),
+		replayBuf:  cfg.ReplayBuf,
 		sendBuf:    sendBuf,
+		maxClients: cfg.MaxClients,
+		maxPerIP:   cfg.MaxClientsPerIP,
 	}
 }
 
+// Admit reserves a connection slot for a client from ip. It returns errHubFull or
+// errHubIPLimit if a configured limit would be exceeded. Every successful Admit must
+// be paired with a release, which happens automatically when an admitted client is removed.
+func (h *Hub) Admit(ip string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.maxClients > 0 && h.admitted >= h.maxClients {
+		return errHubFull
+	}
+	if h.maxPerIP > 0 && h.admittedIP[ip] >= h.maxPerIP {
+		return errHubIPLimit
+	}
+	h.admitted++
+	h.admittedIP[ip]++
+	return nil
+}
+
+// release returns a connection slot reserved by Admit. Caller must hold h.mu.
+func (h *Hub) release(ip string) {
+	if h.admitted > 0 {
+		h.admitted--
+	}
+	if n := h.admittedIP[ip]; n <= 1 {
+		delete(h.admittedIP, ip)
+	} else {
+		h.admittedIP[ip] = n - 1
+	}
+}
+
+// Release returns a connection slot for a client that was admitted but never registered
+// (e.g. the websocket upgrade failed).
+func (h *Hub) Release(ip string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.release(ip)
+}
+
 // Run processes hub events until ctx is canceled.
 // It disconnects all clients on shutdown.
 func (h *Hub) Run(ctx context.Context) {
@@ -138,11 +411,16 @@ func (h *Hub) Run(ctx context.Context) {
 			n := len(h.clients)
 			h.mu.Unlock()
 			h.logger.Info("ws client registered", "remote_addr", c.remoteAddr, "clients", n)
+			if c.resume != nil {
+				c.resume.done <- h.replayTo(c, c.resume.lastSeq)
+			}
 
 		case c := <-h.unregister:
 			h.removeClient(c, "unregister")
 
 		case msg := <-h.broadcast:
+			msg = h.stamp(msg)
+
 			// Avoid mutating the clients map while ranging over it.
 			// Collect slow clients first, then remove them after we unlock.
 			var slow []*Client
@@ -173,6 +451,9 @@ func (h *Hub) closeAllClients() {
 		}
 		close(c.send)
 		delete(h.clients, c)
+		if c.admitted {
+			h.release(c.ip)
+		}
 	}
 }
 
@@ -181,6 +462,9 @@ func (h *Hub) removeClient(c *Client, reason string) {
 	_, ok := h.clients[c]
 	if ok {
 		delete(h.clients, c)
+		if c.admitted {
+			h.release(c.ip)
+		}
 	}
 	n := len(h.clients)
 	h.mu.Unlock()
@@ -226,6 +510,19 @@ type Client struct {
 
 	remoteAddr string
 	logger     *slog.Logger
+
+	// ip is the remote IP used for per-IP admission accounting.
+	// admitted is true if a slot was reserved via Hub.Admit and must be released on removal.
+	ip       string
+	admitted bool
+
+	// protocol is the snapshot schema negotiated via hello (0 until then; see
+	// state_ws_protocol.go). onMessage handles text frames read from conn.
+	protocol  atomic.Int32
+	onMessage func(msg []byte)
+
+	// resume is set when the client asked to resume a stream (state_ws_resume.go).
+	resume *wsResume
 }
 
 // NewClient creates a client with a buffered send channel.
@@ -252,10 +549,6 @@ const (
 	pingPeriod = 20 * time.Second
 )
 
-// wsVolumeCoalesceWindow is the maximum time window during which bursty volume updates
-// are coalesced (latest-wins) before broadcasting to clients.
-const wsVolumeCoalesceWindow = 50 * time.Millisecond
-
 // closeStatus extracts a human-readable websocket close code / text when possible.
 func closeStatus(err error) (code int, text string, ok bool) {
 	var ce *websocket.CloseError
@@ -312,7 +605,8 @@ func (c *Client) writePump(ctx context.Context) {
 	}
 }
 
-// readPump reads and discards incoming messages to detect disconnects and handle control frames.
+// readPump passes incoming text messages to onMessage (others are discarded) and
+// detects disconnects and handles control frames.
 // It exits on read error, then unregisters the client.
 func (c *Client) readPump(ctx context.Context) {
 	_ = c.conn.SetReadDeadline(time.Now().Add(pongWait))
@@ -329,7 +623,11 @@ func (c *Client) readPump(ctx context.Context) {
 			// Continue to read.
 		}
 
-		_, _, err := c.conn.ReadMessage()
+		typ, msg, err := c.conn.ReadMessage()
+		if err == nil && typ == websocket.TextMessage && c.onMessage != nil {
+			c.onMessage(msg)
+			continue
+		}
 		if err != nil {
 			// Normal close is expected on client disconnect.
 			if !errors.Is(err, websocket.ErrCloseSent) {
@@ -359,10 +657,18 @@ type Server struct {
 
 	// Required for initial snapshot request on connect (through reducer/event loop).
 	events chan<- Event
+
+	// allowedOrigins is the Origin allow-list. Empty allows any origin.
+	allowedOrigins []string
 }
 
 type ServerConfig struct {
 	Hub HubConfig
+
+	// AllowedOrigins lists browser Origins permitted to connect (e.g. "http://ui.home.arpa").
+	// "*" allows any origin. Empty preserves the permissive default (any origin).
+	// Requests without an Origin header (non-browser clients) are always allowed.
+	AllowedOrigins []string
 }
 
 // NewServer constructs the WS state server components. Call Register on a mux,
@@ -370,9 +676,10 @@ type ServerConfig struct {
 func NewServer(logger *slog.Logger, events chan<- Event, cfg ServerConfig) *Server {
 	hub := NewHub(logger, cfg.Hub)
 	return &Server{
-		logger: logger,
-		hub:    hub,
-		events: events,
+		logger:         logger,
+		hub:            hub,
+		events:         events,
+		allowedOrigins: cfg.AllowedOrigins,
 	}
 }
 
@@ -387,19 +694,68 @@ func (s *Server) Register(mux *http.ServeMux, path string) {
 }
 
 var upgrader = websocket.Upgrader{
-	// NOTE: If you need stricter origin checks, implement them at integration time.
+	// Origin policy is enforced by Server.checkOrigin before upgrading so rejected
+	// requests never consume a connection slot.
 	CheckOrigin: func(r *http.Request) bool { return true },
 }
 
+// checkOrigin reports whether the request's Origin header is permitted. A page
+// served from the same scheme and host as the request (as the browser saw them,
+// see http_proxy.go) is always allowed.
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || len(s.allowedOrigins) == 0 || strings.EqualFold(origin, requestOrigin(r)) {
+		return true
+	}
+	for _, allowed := range s.allowedOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteIP extracts the IP portion of r.RemoteAddr (falls back to the raw value).
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 // handleStateWS upgrades and registers a client, then sends state_init.
 func (s *Server) handleStateWS(w http.ResponseWriter, r *http.Request) {
+	if !s.checkOrigin(r) {
+		s.logger.Warn("ws origin rejected", "remote_addr", r.RemoteAddr, "origin", r.Header.Get("Origin"))
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	ip := remoteIP(r)
+	if err := s.hub.Admit(ip); err != nil {
+		s.logger.Warn("ws connection rejected", "remote_addr", r.RemoteAddr, "reason", err)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
+		s.hub.Release(ip)
 		s.logger.Warn("ws upgrade failed", "error", err)
 		return
 	}
 
 	client := NewClient(s.hub, conn, r.RemoteAddr, s.logger)
+	client.ip = ip
+	client.admitted = true
+	client.onMessage = func(msg []byte) { s.handleClientMessage(client, msg) }
+	q := r.URL.Query()
+	if q.Get("stream") == s.hub.stream {
+		if lastSeq, err := strconv.ParseUint(q.Get("last_seq"), 10, 64); err == nil {
+			client.resume = &wsResume{lastSeq: lastSeq, done: make(chan bool, 1)}
+		}
+	}
 
 	// Register client first so broadcasts can reach it.
 	s.hub.register <- client
@@ -415,56 +771,76 @@ func (s *Server) handleStateWS(w http.ResponseWriter, r *http.Request) {
 	go client.writePump(context.Background())
 	go client.readPump(context.Background())
 
-	// Request snapshot for initial state_init message (through reducer/event loop).
-	// Use the HTTP request context here so it cancels if the client disconnects
-	// during the snapshot round-trip.
-	if s.events != nil {
-		reply := make(chan StateSnapshot, 1)
-
+	if client.resume != nil {
 		select {
+		case ok := <-client.resume.done:
+			if ok {
+				s.logger.Info("ws client resumed", "remote_addr", r.RemoteAddr, "last_seq", client.resume.lastSeq)
+				return
+			}
 		case <-r.Context().Done():
 			return
-		case s.events <- RequestStateSnapshot{Reply: reply}:
+		case <-time.After(wsResumeWait):
 		}
+	}
 
-		waitCtx := r.Context()
-		if _, has := r.Context().Deadline(); !has {
-			var cancel context.CancelFunc
-			waitCtx, cancel = context.WithTimeout(r.Context(), 1*time.Second)
-			defer cancel()
-		}
+	// Request snapshot for initial state_init message (through reducer/event loop).
+	// Use the HTTP request context here so it cancels if the client disconnects
+	// during the snapshot round-trip.
+	s.sendStateInit(r.Context(), client)
+}
 
-		select {
-		case <-waitCtx.Done():
-			if !errors.Is(waitCtx.Err(), context.Canceled) {
-				s.logger.Warn("ws snapshot request failed", "error", waitCtx.Err())
-			}
-			return
+// sendStateInit requests a StateSnapshot through the reducer/event loop and enqueues
+// it as a "state_init" frame on the client's send queue. It is shared by every
+// transport (WebSocket, SSE) that fans out hub broadcasts.
+func (s *Server) sendStateInit(ctx context.Context, client *Client) {
+	if s.events == nil {
+		return
+	}
 
-		case snap := <-reply:
-			payload := wsMessageSnapshot{
-				VolumeDB:    snap.VolumeDB,
-				VolumeKnown: snap.VolumeKnown,
-				VolumeAt:    snap.VolumeAt,
-				Muted:       snap.Muted,
-				MuteKnown:   snap.MuteKnown,
-				MuteAt:      snap.MuteAt,
-			}
+	seq := s.hub.seq.Load() // the snapshot is at least this current
+	reply := make(chan StateSnapshot, 1)
 
-			now := time.Now().UTC()
-			initMsg, mErr := json.Marshal(envelope{
-				Type: "state_init",
-				Ts:   &now,
-				Data: payload,
-			})
-			if mErr == nil {
-				// Enqueue init message; if client is already slow, disconnect.
-				select {
-				case client.send <- initMsg:
-				default:
-					s.hub.unregister <- client
-					return
-				}
+	select {
+	case <-ctx.Done():
+		return
+	case s.events <- RequestStateSnapshot{Reply: reply}:
+	}
+
+	waitCtx := ctx
+	if _, has := ctx.Deadline(); !has {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, 1*time.Second)
+		defer cancel()
+	}
+
+	select {
+	case <-waitCtx.Done():
+		if !errors.Is(waitCtx.Err(), context.Canceled) {
+			s.logger.Warn("ws snapshot request failed", "error", waitCtx.Err())
+		}
+		return
+
+	case snap := <-reply:
+		payload := newWSMessageSnapshot(snap)
+		payload.ProtocolVersion = client.protocolVersion()
+		payload.Capabilities = wsCapabilities
+		payload.Stream = s.hub.stream
+
+		now := time.Now().UTC()
+		initMsg, mErr := json.Marshal(envelope{
+			Seq:  seq,
+			Type: "state_init",
+			Ts:   &now,
+			Data: payload,
+		})
+		if mErr == nil {
+			// Enqueue init message; if client is already slow, disconnect.
+			select {
+			case client.send <- initMsg:
+			default:
+				s.hub.unregister <- client
+				return
 			}
 		}
 	}
@@ -475,106 +851,51 @@ func (s *Server) handleStateWS(w http.ResponseWriter, r *http.Request) {
 // ============================================================================
 
 // RunBroadcaster reads reducer-emitted StateBroadcast events, marshals them, and broadcasts
-// them to all hub clients. Intended to run as a single goroutine.
-func RunBroadcaster(ctx context.Context, hub *Hub, src <-chan StateBroadcast, logger *slog.Logger) {
+// them to all hub clients, throttling the topics in coalesceMs (topic → window in
+// milliseconds, see state_coalesce.go). Intended to run as a single goroutine.
+func RunBroadcaster(ctx context.Context, hub *Hub, src <-chan StateBroadcast, coalesceMs map[string]int, logger *slog.Logger) {
 	if hub == nil || src == nil {
 		return
 	}
 
-	// Rate-limit bursty volume updates: flush latest pending volume at most once every
-	// wsVolumeCoalesceWindow, even if updates keep arriving (no debounce-on-silence).
-	var pendingVol *wsOutboundEvent
-	var volTimer *time.Timer
-	var volTimerCh <-chan time.Time
-
-	flushPendingVol := func() {
-		if pendingVol == nil {
-			return
-		}
-
-		ts := pendingVol.At
-		if ts.IsZero() {
-			ts = time.Now().UTC()
-		}
-
-		msg, err := json.Marshal(envelope{
-			Type: pendingVol.Type,
-			Ts:   &ts,
-			Data: pendingVol.Data,
-		})
-		if err != nil {
-			logger.Warn("ws broadcaster marshal failed", "error", err, "type", pendingVol.Type)
-			// Drop the pending item so we don't retry-marshal forever.
-			pendingVol = nil
-			return
-		}
-
-		hub.BroadcastBytes(msg)
-		pendingVol = nil
-	}
+	coalescer := newBroadcastCoalescer(coalesceMs)
+	timer := time.NewTimer(time.Hour)
+	timer.Stop() // armed only while events are pending
+	defer timer.Stop()
 
-	stopVolTimer := func() {
-		if volTimer == nil {
-			volTimerCh = nil
-			return
-		}
-		if !volTimer.Stop() {
-			// Drain if needed.
-			select {
-			case <-volTimer.C:
-			default:
+	send := func(events []wsOutboundEvent) {
+		for _, ev := range events {
+			msg, err := marshalOutbound(ev)
+			if err != nil {
+				logger.Warn("ws broadcaster marshal failed", "error", err, "type", ev.Type)
+				continue
 			}
+			hub.BroadcastBytes(msg)
 		}
-		volTimerCh = nil
-		volTimer = nil
 	}
 
-	startVolTimerIfNeeded := func() {
-		if volTimer != nil {
-			return
+	// rearm points the timer at the earliest pending window.
+	rearm := func() {
+		timer.Stop()
+		if due, ok := coalescer.nextDue(); ok {
+			timer.Reset(time.Until(due))
 		}
-		volTimer = time.NewTimer(wsVolumeCoalesceWindow)
-		volTimerCh = volTimer.C
-	}
-
-	resetVolTimer := func() {
-		// Timer must already exist.
-		if volTimer == nil {
-			return
-		}
-		if !volTimer.Stop() {
-			select {
-			case <-volTimer.C:
-			default:
-			}
-		}
-		volTimer.Reset(wsVolumeCoalesceWindow)
-		volTimerCh = volTimer.C
 	}
 
 	for {
 		select {
 		case <-ctx.Done():
-			// Best-effort: flush pending volume update before exit.
-			flushPendingVol()
-			stopVolTimer()
+			// Best-effort: flush pending coalesced updates before exit.
+			send(coalescer.flushAll())
 			return
 
-		case <-volTimerCh:
-			// Timer tick: flush latest pending volume if present.
-			flushPendingVol()
-			// Keep ticking only if more volume updates are pending; otherwise stop.
-			if pendingVol == nil {
-				stopVolTimer()
-			} else {
-				resetVolTimer()
-			}
+		case <-timer.C:
+			send(coalescer.flushDue(time.Now()))
+			rearm()
 
 		case b, ok := <-src:
 			if !ok {
-				// If the source ends, flush any pending coalesced volume update then stop.
-				flushPendingVol()
-				stopVolTimer()
+				send(coalescer.flushAll())
 				logger.Info("ws broadcaster stopping (source ended)")
 				return
 			}
@@ -584,46 +905,185 @@ func RunBroadcaster(ctx context.Context, hub *Hub, src <-chan StateBroadcast, lo
 				// Unknown broadcasts are dropped.
 				continue
 			}
-
-			// Rate-limit only volume_changed; do NOT reset the timer on each update.
-			// Latest-wins: replace pending event and ensure the periodic timer is running.
-			if ev.Type == "volume_changed" {
-				copyEv := ev
-				pendingVol = &copyEv
-				startVolTimerIfNeeded()
-				continue
-			}
-
-			// Non-volume event: flush pending volume first, then emit this event immediately.
-			flushPendingVol()
-			stopVolTimer()
-
-			ts := ev.At
-			if ts.IsZero() {
-				ts = time.Now().UTC()
-			}
-
-			msg, err := json.Marshal(envelope{
-				Type: ev.Type,
-				Ts:   &ts,
-				Data: ev.Data,
-			})
-			if err != nil {
-				logger.Warn("ws broadcaster marshal failed", "error", err, "type", ev.Type)
-				continue
-			}
-
-			hub.BroadcastBytes(msg)
+			send(coalescer.add(ev, time.Now()))
+			rearm()
 		}
 	}
 }
 
+// marshalOutbound serializes an outbound event into the wire envelope.
+// A zero At is replaced with the current time.
+func marshalOutbound(ev wsOutboundEvent) ([]byte, error) {
+	ts := ev.At
+	if ts.IsZero() {
+		ts = time.Now().UTC()
+	}
+	return json.Marshal(envelope{
+		Type: ev.Type,
+		Zone: ev.Zone,
+		Ts:   &ts,
+		Data: ev.Data,
+	})
+}
+
 func convertBroadcast(b StateBroadcast) (wsOutboundEvent, bool) {
 	switch ev := b.(type) {
+	case ZoneBroadcast:
+		out, ok := convertBroadcast(ev.Broadcast)
+		out.Zone = ev.Zone
+		return out, ok
+
+	case BroadcastEncoderChanged:
+		return wsOutboundEvent{
+			Type: "encoder_changed",
+			Data: wsEncoderChangedData{Mode: ev.Mode, BalanceDB: ev.BalanceDB, SubDB: ev.SubDB},
+			At:   ev.At,
+		}, true
+
+	case BroadcastDeviceDown:
+		return wsOutboundEvent{
+			Type: "device_down",
+			Data: wsDeviceDownData{Device: ev.Device, Reason: ev.Reason},
+			At:   ev.At,
+		}, true
+
+	case BroadcastDeviceUp:
+		return wsOutboundEvent{
+			Type: "device_up",
+			Data: wsDeviceUpData{Device: ev.Device},
+			At:   ev.At,
+		}, true
+
+	case BroadcastDSPConnectionChanged:
+		return wsOutboundEvent{
+			Type: "dsp_connection_changed",
+			Data: wsDSPConnectionChangedData{Connected: ev.Connected, Error: ev.Error},
+			At:   ev.At,
+		}, true
+
+	case BroadcastDSPCommandError:
+		return wsOutboundEvent{
+			Type: "dsp_command_error",
+			Data: wsDSPCommandErrorData{Command: ev.Command, Class: string(ev.Class), Error: ev.Error},
+			At:   ev.At,
+		}, true
+
+	case BroadcastTestSignal:
+		return wsOutboundEvent{
+			Type: "test_signal",
+			Data: wsTestSignalData{Channel: ev.Channel, Until: ev.Until, Error: ev.Error},
+			At:   ev.At,
+		}, true
+
+	case BroadcastTuningChanged:
+		return wsOutboundEvent{
+			Type: "tuning_changed",
+			Data: ev.Tuning,
+			At:   ev.At,
+		}, true
+
+	case BroadcastUpdateAvailable:
+		return wsOutboundEvent{
+			Type: "update_available",
+			Data: wsUpdateAvailableData{Current: ev.Current, Latest: ev.Latest, URL: ev.URL},
+			At:   ev.At,
+		}, true
+
+	case BroadcastExposureWarning:
+		return wsOutboundEvent{
+			Type: "exposure_warning",
+			Data: ev.Warning,
+			At:   ev.At,
+		}, true
+
+	case BroadcastControlRejected:
+		return wsOutboundEvent{
+			Type: "control_rejected",
+			Data: wsControlRejectedData{Origin: ev.Origin, Holder: ev.Holder, Until: ev.Until},
+			At:   ev.At,
+		}, true
+
+	case BroadcastControllerChanged:
+		return wsOutboundEvent{
+			Type: "controller_changed",
+			Data: wsControllerChangedData{HeldDirection: ev.HeldDirection, Ramping: ev.Ramping, VelocityDBPerS: ev.VelocityDBPerS, TargetDB: ev.TargetDB},
+			At:   ev.At,
+		}, true
+
+	case BroadcastUIHint:
+		return wsOutboundEvent{
+			Type: "ui_hint",
+			Data: wsUIHintData{Hint: ev.Hint, DurationMS: int(ev.Duration / time.Millisecond), VolumeDB: ev.VolumeDB, Muted: ev.Muted},
+			At:   ev.At,
+		}, true
+
+	case BroadcastHoldCheckpoint:
+		return wsOutboundEvent{
+			Type: "hold_checkpoint",
+			Data: wsHoldCheckpointData{LevelDB: ev.LevelDB},
+			At:   ev.At,
+		}, true
+
+	case BroadcastSignalLevels:
+		return wsOutboundEvent{
+			Type: "signal_levels",
+			Data: wsSignalLevelsData{CamillaSignalLevels: ev.Levels, Faders: ev.Faders},
+			At:   ev.At,
+		}, true
+
+	case BroadcastCamillaFrame:
+		return wsOutboundEvent{
+			Type: "camilladsp_frame",
+			Data: wsCamillaFrameData{Direction: ev.Direction, Frame: ev.Frame, Truncated: ev.Truncated},
+			At:   ev.At,
+		}, true
+
+	case BroadcastCalibrationMode:
+		return wsOutboundEvent{
+			Type: "calibration_mode",
+			Data: wsCalibrationModeData{Active: ev.Active, ReferenceDB: ev.ReferenceDB},
+			At:   ev.At,
+		}, true
+
+	case BroadcastLimitOverrideChanged:
+		return wsOutboundEvent{
+			Type: "limit_override_changed",
+			Data: wsLimitOverrideChangedData{Active: ev.Active, Until: ev.Until, MinDB: ev.MinDB, MaxDB: ev.MaxDB, Error: ev.Error},
+			At:   ev.At,
+		}, true
+
+	case BroadcastIRSend:
+		return wsOutboundEvent{
+			Type: "ir_send",
+			Data: wsIRSendData{Command: ev.Command},
+			At:   ev.At,
+		}, true
+
+	case BroadcastOutputChanged:
+		return wsOutboundEvent{
+			Type: "output_changed",
+			Data: wsOutputChangedData{Output: ev.Output},
+			At:   ev.At,
+		}, true
+
+	case BroadcastZoneLinkChanged:
+		return wsOutboundEvent{
+			Type: "zone_link_changed",
+			Data: wsZoneLinkChangedData{Offsets: ev.Offsets},
+			At:   ev.At,
+		}, true
+
+	case BroadcastZoneSelected:
+		return wsOutboundEvent{
+			Type: "zone_selected",
+			Data: wsZoneSelectedData{Zone: ev.Zone},
+			At:   ev.At,
+		}, true
+
 	case BroadcastVolumeChanged:
 		return wsOutboundEvent{
 			Type: "volume_changed",
-			Data: wsVolumeChangedData{VolumeDB: ev.VolumeDB},
+			Data: wsVolumeChangedData{VolumeDB: ev.VolumeDB, Origin: ev.Origin, ExternalChange: ev.ExternalChange},
 			At:   ev.At,
 		}, true
 
@@ -634,6 +1094,21 @@ func convertBroadcast(b StateBroadcast) (wsOutboundEvent, bool) {
 			At:   ev.At,
 		}, true
 
+	case BroadcastPlayerChanged:
+		return wsOutboundEvent{
+			Type: "player_changed",
+			Data: wsPlayerChangedData{
+				Source: ev.Source,
+				State:  ev.State,
+				Title:  ev.Title,
+				Artist: ev.Artist,
+				Album:  ev.Album,
+
+				DurationMs: ev.DurationMs,
+			},
+			At: ev.At,
+		}, true
+
 	default:
 		return wsOutboundEvent{}, false
 	}
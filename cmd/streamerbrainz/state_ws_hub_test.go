@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 )
@@ -70,6 +72,7 @@ func TestHub_BroadcastDeliveredToAllClients(t *testing.T) {
 	}, "client2 not registered in time")
 
 	msg := []byte(`{"type":"volume_changed","data":{"volume_db":-12.0}}`)
+	want := `{"seq":1,"type":"volume_changed","data":{"volume_db":-12.0}}` // stamped by the hub
 
 	// Avoid BroadcastBytes() here because it is intentionally non-blocking and may
 	// drop if the hub broadcast queue is temporarily full during scheduling.
@@ -78,8 +81,8 @@ func TestHub_BroadcastDeliveredToAllClients(t *testing.T) {
 	// Both clients should receive the message.
 	select {
 	case got := <-c1.send:
-		if string(got) != string(msg) {
-			t.Fatalf("client1 got %q, want %q", string(got), string(msg))
+		if string(got) != want {
+			t.Fatalf("client1 got %q, want %q", string(got), want)
 		}
 	case <-time.After(500 * time.Millisecond):
 		t.Fatalf("timeout waiting for client1 to receive broadcast")
@@ -87,8 +90,8 @@ func TestHub_BroadcastDeliveredToAllClients(t *testing.T) {
 
 	select {
 	case got := <-c2.send:
-		if string(got) != string(msg) {
-			t.Fatalf("client2 got %q, want %q", string(got), string(msg))
+		if string(got) != want {
+			t.Fatalf("client2 got %q, want %q", string(got), want)
 		}
 	case <-time.After(500 * time.Millisecond):
 		t.Fatalf("timeout waiting for client2 to receive broadcast")
@@ -157,6 +160,7 @@ func TestHub_SlowClientDisconnectedOnFullSendBuffer(t *testing.T) {
 	// Broadcast should attempt to enqueue to slow, hit default, and disconnect it,
 	// while still delivering to fast.
 	msg := []byte(`{"type":"mute_changed","data":{"muted":true}}`)
+	want := `{"seq":1,"type":"mute_changed","data":{"muted":true}}`
 
 	// Avoid BroadcastBytes() here for the same reason as above; we want deterministic delivery
 	// into the hub's select loop.
@@ -164,8 +168,8 @@ func TestHub_SlowClientDisconnectedOnFullSendBuffer(t *testing.T) {
 
 	select {
 	case got := <-fast.send:
-		if string(got) != string(msg) {
-			t.Fatalf("fast client got %q, want %q", string(got), string(msg))
+		if string(got) != want {
+			t.Fatalf("fast client got %q, want %q", string(got), want)
 		}
 	case <-time.After(500 * time.Millisecond):
 		t.Fatalf("timeout waiting for fast client to receive broadcast")
@@ -199,3 +203,57 @@ func waitUntil(t *testing.T, timeout time.Duration, cond func() bool, msg string
 	}
 	t.Fatalf("timeout: %s", msg)
 }
+
+func TestHub_AdmitEnforcesLimits(t *testing.T) {
+	hub := NewHub(slog.Default(), HubConfig{MaxClients: 3, MaxClientsPerIP: 2})
+
+	if err := hub.Admit("10.0.0.1"); err != nil {
+		t.Fatalf("first admit: %v", err)
+	}
+	if err := hub.Admit("10.0.0.1"); err != nil {
+		t.Fatalf("second admit: %v", err)
+	}
+	if err := hub.Admit("10.0.0.1"); err != errHubIPLimit {
+		t.Fatalf("expected errHubIPLimit, got %v", err)
+	}
+	if err := hub.Admit("10.0.0.2"); err != nil {
+		t.Fatalf("admit from second ip: %v", err)
+	}
+	if err := hub.Admit("10.0.0.3"); err != errHubFull {
+		t.Fatalf("expected errHubFull, got %v", err)
+	}
+
+	// Removing an admitted client frees its slot.
+	c := &Client{hub: hub, send: make(chan []byte, 1), remoteAddr: "c", logger: slog.Default(), ip: "10.0.0.1", admitted: true}
+	hub.mu.Lock()
+	hub.clients[c] = struct{}{}
+	hub.mu.Unlock()
+	hub.removeClient(c, "test")
+
+	if err := hub.Admit("10.0.0.3"); err != nil {
+		t.Fatalf("admit after release: %v", err)
+	}
+}
+
+func TestServer_CheckOrigin(t *testing.T) {
+	srv := NewServer(slog.Default(), nil, ServerConfig{AllowedOrigins: []string{"http://ui.home.arpa/"}})
+
+	cases := []struct {
+		origin string
+		want   bool
+	}{
+		{"", true},
+		{"http://ui.home.arpa", true},
+		{"HTTP://UI.HOME.ARPA", true},
+		{"http://evil.example", false},
+	}
+	for _, tc := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/ws/state", nil)
+		if tc.origin != "" {
+			r.Header.Set("Origin", tc.origin)
+		}
+		if got := srv.checkOrigin(r); got != tc.want {
+			t.Errorf("checkOrigin(%q) = %v, want %v", tc.origin, got, tc.want)
+		}
+	}
+}
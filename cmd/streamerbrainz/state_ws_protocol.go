@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ============================================================================
+// State protocol versioning
+// ============================================================================
+// state_init carries protocol_version (the snapshot schema it is encoded with)
+// and capabilities (the optional message families this daemon emits), so UIs
+// can feature-detect instead of guessing from the daemon version.
+//
+// A WebSocket client may send a hello listing the versions it understands:
+//
+//	{"type":"hello","data":{"versions":[1,2]}}
+//
+// The daemon answers with a hello carrying the highest common version and its
+// capabilities, and sends a fresh state_init if that version differs from the
+// one already sent. Without a common version the connection is closed with
+// code 4406. Clients that never send a hello get wsProtocolVersion, so existing
+// UIs keep working when a newer schema is added.
+// ============================================================================
+
+const (
+	// wsProtocolVersion is the snapshot schema sent to clients that don't negotiate.
+	wsProtocolVersion = 1
+
+	// wsCloseUnsupportedProtocol closes connections whose hello has no common version.
+	wsCloseUnsupportedProtocol = 4406
+)
+
+// wsProtocolVersions lists the snapshot schemas this daemon can encode.
+var wsProtocolVersions = []int{1}
+
+// wsCapabilities lists the optional message families emitted on the state stream.
+var wsCapabilities = []string{
+	"hello",
+	"player",
+	"zones",
+	"zone_links",
+	"outputs",
+	"encoder",
+	"inputs",
+	"dsp_connection",
+	"dsp_command_error",
+	"limit_override",
+	"calibration",
+	"arbitration",
+	"hold_checkpoint",
+	"controller",
+	"ui_hints",
+	"meters",
+	"test_signal",
+	"tuning",
+	"update_available",
+	"exposure_warning",
+	"protocol_tap",
+}
+
+// wsHelloRequest is the `data` payload of a client "hello".
+type wsHelloRequest struct {
+	Versions []int `json:"versions"`
+}
+
+// wsHelloData is the `data` payload of the daemon's "hello" reply.
+type wsHelloData struct {
+	ProtocolVersion  int      `json:"protocol_version"`
+	ProtocolVersions []int    `json:"protocol_versions"`
+	Capabilities     []string `json:"capabilities"`
+}
+
+// negotiateProtocol returns the highest version both sides understand (0 if none).
+func negotiateProtocol(clientVersions []int) int {
+	best := 0
+	for _, v := range clientVersions {
+		if v > best && slices.Contains(wsProtocolVersions, v) {
+			best = v
+		}
+	}
+	return best
+}
+
+// handleClientMessage processes a text frame received from a WebSocket client.
+// Unknown message types are ignored so clients can send newer messages safely.
+func (s *Server) handleClientMessage(c *Client, msg []byte) {
+	var in struct {
+		Type string          `json:"type"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(msg, &in); err != nil || in.Type != "hello" {
+		return
+	}
+
+	var hello wsHelloRequest
+	if len(in.Data) > 0 {
+		if err := json.Unmarshal(in.Data, &hello); err != nil {
+			s.logger.Debug("ws hello: invalid data", "remote_addr", c.remoteAddr, "error", err)
+			return
+		}
+	}
+	version := negotiateProtocol(hello.Versions)
+	if version == 0 {
+		s.logger.Warn("ws hello: no common protocol version", "remote_addr", c.remoteAddr, "versions", hello.Versions, "supported", wsProtocolVersions)
+		_ = c.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(wsCloseUnsupportedProtocol, "unsupported protocol version"),
+			time.Now().Add(writeWait))
+		s.hub.unregister <- c
+		return
+	}
+
+	previous := c.protocolVersion()
+	c.protocol.Store(int32(version))
+	now := time.Now().UTC()
+	reply, err := json.Marshal(envelope{
+		Type: "hello",
+		Ts:   &now,
+		Data: wsHelloData{
+			ProtocolVersion:  version,
+			ProtocolVersions: wsProtocolVersions,
+			Capabilities:     wsCapabilities,
+		},
+	})
+	if err != nil {
+		return
+	}
+	select {
+	case c.send <- reply:
+	default:
+		s.hub.unregister <- c
+		return
+	}
+	s.logger.Debug("ws hello", "remote_addr", c.remoteAddr, "protocol_version", version)
+
+	if version != previous {
+		ctx, cancel := context.WithTimeout(context.Background(), writeWait)
+		defer cancel()
+		s.sendStateInit(ctx, c)
+	}
+}
+
+// protocolVersion returns the snapshot schema negotiated by c's hello.
+func (c *Client) protocolVersion() int {
+	if v := c.protocol.Load(); v != 0 {
+		return int(v)
+	}
+	return wsProtocolVersion
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestNegotiateProtocol(t *testing.T) {
+	for _, tc := range []struct {
+		in   []int
+		want int
+	}{
+		{nil, 0},
+		{[]int{1}, 1},
+		{[]int{1, 99}, 1},
+		{[]int{99, 1, 0}, 1},
+		{[]int{2, 3}, 0},
+	} {
+		if got := negotiateProtocol(tc.in); got != tc.want {
+			t.Errorf("negotiateProtocol(%v) = %d, want %d", tc.in, got, tc.want)
+		}
+	}
+}
+
+// newProtocolTestServer serves /ws/state backed by a fake event loop that
+// answers snapshot requests.
+func newProtocolTestServer(t *testing.T) string {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	events := make(chan Event, 4)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev := <-events:
+				if req, ok := ev.(RequestStateSnapshot); ok {
+					req.Reply <- StateSnapshot{VolumeDB: -30, VolumeKnown: true}
+				}
+			}
+		}
+	}()
+
+	srv := NewServer(slog.New(slog.DiscardHandler), events, ServerConfig{})
+	go srv.Hub().Run(ctx)
+	mux := http.NewServeMux()
+	srv.Register(mux, "/ws/state")
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws/state"
+}
+
+func readFrame(t *testing.T, conn *websocket.Conn) (envelope, json.RawMessage) {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var frame struct {
+		envelope
+		Data json.RawMessage `json:"data"`
+	}
+	if err := conn.ReadJSON(&frame); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return frame.envelope, frame.Data
+}
+
+func TestStateWS_StateInitCarriesProtocol(t *testing.T) {
+	conn, _, err := websocket.DefaultDialer.Dial(newProtocolTestServer(t), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	env, data := readFrame(t, conn)
+	var snap wsMessageSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil || env.Type != "state_init" {
+		t.Fatalf("frame %q: %v", env.Type, err)
+	}
+	if snap.ProtocolVersion != wsProtocolVersion || len(snap.Capabilities) == 0 || snap.VolumeDB != -30 {
+		t.Fatalf("snapshot = %+v", snap)
+	}
+
+	if err := conn.WriteJSON(map[string]any{"type": "hello", "data": map[string]any{"versions": []int{1, 7}}}); err != nil {
+		t.Fatal(err)
+	}
+	env, data = readFrame(t, conn)
+	var hello wsHelloData
+	if err := json.Unmarshal(data, &hello); err != nil || env.Type != "hello" {
+		t.Fatalf("frame %q: %v", env.Type, err)
+	}
+	if hello.ProtocolVersion != 1 || len(hello.Capabilities) != len(wsCapabilities) {
+		t.Fatalf("hello = %+v", hello)
+	}
+}
+
+func TestStateWS_HelloWithoutCommonVersionCloses(t *testing.T) {
+	conn, _, err := websocket.DefaultDialer.Dial(newProtocolTestServer(t), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	readFrame(t, conn) // state_init
+
+	if err := conn.WriteJSON(map[string]any{"type": "hello", "data": map[string]any{"versions": []int{99}}}); err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		_, _, err := conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+		var ce *websocket.CloseError
+		if !errors.As(err, &ce) || ce.Code != wsCloseUnsupportedProtocol {
+			t.Fatalf("err = %v, want close %d", err, wsCloseUnsupportedProtocol)
+		}
+		return
+	}
+}
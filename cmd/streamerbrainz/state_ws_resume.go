@@ -0,0 +1,156 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// ============================================================================
+// State stream sequencing and resume
+// ============================================================================
+// The hub stamps every broadcast frame with a monotonically increasing "seq"
+// and keeps the last websocket.replay_buf frames. state_init carries the seq
+// it is current to (envelope) and the stream id (data.stream), which changes
+// whenever the daemon restarts.
+//
+// A reconnecting WebSocket client passes both back:
+//
+//	GET /ws/state?stream=<id>&last_seq=<n>
+//
+// If every frame after n is still buffered (and fits the client's send queue),
+// the hub replays them — compacted, so only the latest volume_changed per zone
+// is sent — followed by a "resumed" frame, and no state_init. Otherwise (other
+// stream, too far behind, replay disabled) the client gets a fresh state_init as
+// on a first connect.
+// ============================================================================
+
+// wsResumeWait bounds how long the handler waits for the hub to answer a resume.
+const wsResumeWait = time.Second
+
+// wsReplayFrame is a stamped broadcast kept for resuming clients.
+type wsReplayFrame struct {
+	seq  uint64
+	typ  string
+	zone string
+	msg  []byte
+}
+
+// wsResume is a client's resume request, answered by the hub on registration.
+type wsResume struct {
+	lastSeq uint64
+	done    chan bool // receives whether the replay succeeded
+}
+
+// wsResumedData is the `data` payload of the "resumed" frame.
+type wsResumedData struct {
+	Replayed int `json:"replayed"`
+}
+
+// wsLatestWins lists broadcast types where only the newest frame per zone
+// matters; older ones are dropped from replays.
+var wsLatestWins = map[string]bool{
+	"volume_changed": true,
+}
+
+// wsTransient lists broadcast types that are stale as soon as the next one
+// arrives (meters) or only matter live (protocol tap frames). They are sent
+// without a seq and never buffered for replay, so they can't push other frames
+// out of the buffer.
+var wsTransient = map[string]bool{
+	"signal_levels":    true,
+	"camilladsp_frame": true,
+}
+
+// newStreamID returns a random id for this daemon run's broadcast stream.
+func newStreamID() string {
+	return rand.Text()[:16]
+}
+
+// stamp adds the next sequence number to a broadcast frame and records it for
+// replay. Frames that aren't JSON envelopes, and transient ones, are passed
+// through unchanged.
+// Called only from Run.
+func (h *Hub) stamp(msg []byte) []byte {
+	var hdr struct {
+		Type string `json:"type"`
+		Zone string `json:"zone"`
+	}
+	if len(msg) < 2 || msg[0] != '{' || json.Unmarshal(msg, &hdr) != nil || wsTransient[hdr.Type] {
+		return msg
+	}
+	seq := h.seq.Add(1)
+
+	// Splice "seq" in as the first field rather than re-encoding the frame.
+	out := make([]byte, 0, len(msg)+24)
+	out = append(out, `{"seq":`...)
+	out = strconv.AppendUint(out, seq, 10)
+	if msg[1] != '}' {
+		out = append(out, ',')
+	}
+	out = append(out, msg[1:]...)
+
+	if h.replayBuf > 0 {
+		if len(h.replay) >= h.replayBuf {
+			n := copy(h.replay, h.replay[len(h.replay)-h.replayBuf+1:])
+			h.replay = h.replay[:n]
+		}
+		h.replay = append(h.replay, wsReplayFrame{seq: seq, typ: hdr.Type, zone: hdr.Zone, msg: out})
+	}
+	return out
+}
+
+// replayTo queues the frames c missed since lastSeq, followed by a "resumed"
+// frame. It returns false (queuing nothing) if they are no longer all buffered
+// or don't fit c's send queue. Called only from Run, before c can receive new
+// broadcasts.
+func (h *Hub) replayTo(c *Client, lastSeq uint64) bool {
+	current := h.seq.Load()
+	if lastSeq > current {
+		return false // never issued; the client is confused
+	}
+	var missed []wsReplayFrame
+	if lastSeq < current {
+		if len(h.replay) == 0 || h.replay[0].seq > lastSeq+1 {
+			return false
+		}
+		for _, f := range h.replay {
+			if f.seq > lastSeq {
+				missed = append(missed, f)
+			}
+		}
+	}
+
+	// Compact: keep only the newest latest-wins frame per (type, zone).
+	type key struct{ typ, zone string }
+	newest := map[key]uint64{}
+	for _, f := range missed {
+		if wsLatestWins[f.typ] {
+			newest[key{f.typ, f.zone}] = f.seq
+		}
+	}
+	compact := missed[:0]
+	for _, f := range missed {
+		if wsLatestWins[f.typ] && newest[key{f.typ, f.zone}] != f.seq {
+			continue
+		}
+		compact = append(compact, f)
+	}
+
+	now := time.Now().UTC()
+	resumed, err := json.Marshal(envelope{
+		Seq:  current,
+		Type: "resumed",
+		Ts:   &now,
+		Data: wsResumedData{Replayed: len(compact)},
+	})
+	if err != nil || len(compact)+1 > cap(c.send)-len(c.send) {
+		return false
+	}
+	for _, f := range compact {
+		c.send <- f.msg
+	}
+	c.send <- resumed
+	return true
+}
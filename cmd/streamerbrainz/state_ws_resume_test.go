@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func newResumeTestHub(replayBuf int) *Hub {
+	return NewHub(slog.New(slog.DiscardHandler), HubConfig{ReplayBuf: replayBuf})
+}
+
+func frameType(t *testing.T, msg []byte) (string, uint64) {
+	t.Helper()
+	var env envelope
+	if err := json.Unmarshal(msg, &env); err != nil {
+		t.Fatalf("unmarshal %q: %v", msg, err)
+	}
+	return env.Type, env.Seq
+}
+
+func TestHub_StampSequencesFrames(t *testing.T) {
+	h := newResumeTestHub(2)
+	for i, in := range []string{
+		`{"type":"mute_changed","data":{"muted":true}}`,
+		`{}`,
+		`not json`,
+	} {
+		out := h.stamp([]byte(in))
+		if in == "not json" {
+			if string(out) != in {
+				t.Fatalf("non-envelope modified: %q", out)
+			}
+			continue
+		}
+		var env envelope
+		if err := json.Unmarshal(out, &env); err != nil || env.Seq != uint64(i+1) {
+			t.Fatalf("stamp(%q) = %q (%v)", in, out, err)
+		}
+	}
+	h.stamp([]byte(`{"type":"x"}`))
+	if len(h.replay) != 2 || h.replay[0].seq != 2 || h.replay[1].seq != 3 {
+		t.Fatalf("replay = %+v", h.replay)
+	}
+}
+
+func TestHub_ReplayToCompactsLatestWins(t *testing.T) {
+	h := newResumeTestHub(16)
+	for _, in := range []string{
+		`{"type":"volume_changed","data":{"volume_db":-40}}`,  // 1
+		`{"type":"volume_changed","data":{"volume_db":-39}}`,  // 2
+		`{"type":"mute_changed","data":{"muted":true}}`,       // 3
+		`{"type":"volume_changed","data":{"volume_db":-38}}`,  // 4
+		`{"type":"volume_changed","zone":"phones","data":{}}`, // 5
+		`{"type":"volume_changed","data":{"volume_db":-37}}`,  // 6
+	} {
+		h.stamp([]byte(in))
+	}
+
+	c := &Client{send: make(chan []byte, 8)}
+	if !h.replayTo(c, 1) {
+		t.Fatal("replay refused")
+	}
+	var got []uint64
+	for len(c.send) > 0 {
+		typ, seq := frameType(t, <-c.send)
+		if typ == "resumed" {
+			if seq != 6 || len(c.send) != 0 {
+				t.Fatalf("resumed frame seq %d, %d left", seq, len(c.send))
+			}
+			break
+		}
+		got = append(got, seq)
+	}
+	if want := []uint64{3, 5, 6}; len(got) != len(want) || got[0] != 3 || got[1] != 5 || got[2] != 6 {
+		t.Fatalf("replayed %v, want %v", got, want)
+	}
+
+	// Up to date: only the resumed frame.
+	c = &Client{send: make(chan []byte, 8)}
+	if !h.replayTo(c, 6) || len(c.send) != 1 {
+		t.Fatalf("up-to-date resume queued %d frames", len(c.send))
+	}
+}
+
+func TestHub_ReplayToRefusesGaps(t *testing.T) {
+	h := newResumeTestHub(2)
+	for range 5 {
+		h.stamp([]byte(`{"type":"mute_changed","data":{"muted":true}}`))
+	}
+	c := &Client{send: make(chan []byte, 8)}
+	if h.replayTo(c, 1) {
+		t.Fatal("replayed past the buffer")
+	}
+	if h.replayTo(c, 9) {
+		t.Fatal("replayed from a seq never issued")
+	}
+	if !h.replayTo(c, 3) {
+		t.Fatal("refused a buffered resume")
+	}
+
+	// Missed frames that don't fit the send queue fall back to a snapshot.
+	c = &Client{send: make(chan []byte, 2)}
+	if h.replayTo(c, 3) || len(c.send) != 0 {
+		t.Fatal("overfilled send queue")
+	}
+
+	// Disabled replay.
+	h = newResumeTestHub(0)
+	h.stamp([]byte(`{"type":"mute_changed"}`))
+	if h.replayTo(&Client{send: make(chan []byte, 8)}, 0) {
+		t.Fatal("replayed with replay_buf 0")
+	}
+}
+
+func TestHub_StampPassesTransientFramesThrough(t *testing.T) {
+	h := newResumeTestHub(4)
+	h.stamp([]byte(`{"type":"mute_changed","data":{"muted":true}}`))
+	in := `{"type":"signal_levels","data":{"playback_rms":[-30]}}`
+	if out := h.stamp([]byte(in)); string(out) != in {
+		t.Fatalf("transient frame modified: %q", out)
+	}
+	if len(h.replay) != 1 || h.seq.Load() != 1 {
+		t.Fatalf("transient frame sequenced: seq=%d replay=%+v", h.seq.Load(), h.replay)
+	}
+}
@@ -0,0 +1,471 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"maps"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// Listening statistics
+// ============================================================================
+// Records, per local day, how long music was listened to, at what volume
+// (time-weighted average, peak, and time at or above stats.loud_threshold_db)
+// and from which sources, plus how many tracks were started. "Listening" means
+// a player integration reports playing in a zone that isn't muted; the volume
+// is that zone's.
+//
+// The days are kept in a small JSON file (stats.file, rewritten atomically
+// once a minute and on shutdown) and pruned after stats.retention_days.
+// GET /api/v1/stats?days=N returns the last N days (default 7) with totals;
+// with stats.weekly_summary a summary of the past week is logged every Monday.
+// Daily loud-listening budgets (stats.exposure) are in exposure.go.
+// ============================================================================
+
+const (
+	statsSaveInterval = time.Minute
+	statsDefaultDays  = 7
+	statsMaxDays      = 366
+	statsDateLayout   = "2006-01-02"
+)
+
+// statsDay is one day's record. VolumeDBSec (dB x seconds listened) yields the
+// time-weighted average volume.
+type statsDay struct {
+	Date         string                  `json:"date"`
+	ListeningSec float64                 `json:"listening_sec"`
+	VolumeDBSec  float64                 `json:"volume_db_sec"`
+	PeakVolumeDB *float64                `json:"peak_volume_db,omitempty"`
+	LoudSec      float64                 `json:"loud_sec"`
+	Tracks       int                     `json:"tracks"`
+	Sources      map[string]*statsSource `json:"sources,omitempty"`
+
+	// ExposureWarning is the stats.exposure level already announced today.
+	ExposureWarning string `json:"exposure_warning,omitempty"`
+}
+
+type statsSource struct {
+	ListeningSec float64 `json:"listening_sec"`
+	Tracks       int     `json:"tracks"`
+}
+
+// statsFile is the on-disk format.
+type statsFile struct {
+	Days []*statsDay `json:"days"`
+
+	// SummaryWeek is the ISO week ("2026-W41") last summarized in the log.
+	SummaryWeek string `json:"summary_week,omitempty"`
+}
+
+// statsZone is what the recorder knows about one zone.
+type statsZone struct {
+	volumeDB    float64
+	volumeKnown bool
+	muted       bool
+	playing     bool
+	source      string
+	track       string    // artist + title of the track last counted
+	dimmedAt    time.Time // last stats.exposure auto-dim
+}
+
+func (z *statsZone) listening() bool {
+	return z.playing && !z.muted && z.volumeKnown
+}
+
+// listeningStats records and serves listening statistics.
+type listeningStats struct {
+	cfg    StatsConfig
+	logger *slog.Logger
+	now    func() time.Time
+	events chan<- Event // receives stats.exposure warnings and dims
+
+	mu    sync.Mutex
+	data  statsFile
+	zones map[string]*statsZone
+	last  time.Time // time accounted up to
+	dirty bool
+}
+
+// newListeningStats loads the store at cfg.File (an unreadable store starts empty).
+func newListeningStats(cfg StatsConfig, events chan<- Event, logger *slog.Logger) *listeningStats {
+	st := &listeningStats{cfg: cfg, logger: logger, now: time.Now, events: events, zones: make(map[string]*statsZone)}
+	if cfg.File == "" {
+		return st
+	}
+	b, err := os.ReadFile(cfg.File)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		logger.Warn("listening stats unreadable; starting empty", "path", cfg.File, "error", err)
+	default:
+		if err := json.Unmarshal(b, &st.data); err != nil {
+			logger.Warn("listening stats unreadable; starting empty", "path", cfg.File, "error", err)
+			st.data = statsFile{}
+		}
+	}
+	return st
+}
+
+// Run records broadcasts from src until ctx is canceled, saving periodically.
+func (st *listeningStats) Run(ctx context.Context, src <-chan StateBroadcast) {
+	st.logger.Info("listening stats enabled", "file", st.cfg.File)
+	ticker := time.NewTicker(statsSaveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			st.flush()
+			return
+		case b, ok := <-src:
+			if !ok {
+				st.flush()
+				return
+			}
+			st.observe(b)
+		case <-ticker.C:
+			st.flush()
+		}
+	}
+}
+
+// observe applies one broadcast.
+func (st *listeningStats) observe(b StateBroadcast) {
+	zone := ""
+	if zb, ok := b.(ZoneBroadcast); ok {
+		zone, b = zb.Zone, zb.Broadcast
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	now := st.now()
+	st.advance(now)
+
+	z := st.zones[zone]
+	if z == nil {
+		z = &statsZone{}
+		st.zones[zone] = z
+	}
+	switch ev := b.(type) {
+	case BroadcastVolumeChanged:
+		z.volumeDB, z.volumeKnown = ev.VolumeDB, true
+	case BroadcastMuteChanged:
+		z.muted = ev.Muted
+	case BroadcastPlayerChanged:
+		z.playing = ev.State == "playing"
+		z.source = ev.Source
+		track := ev.Artist + "\x00" + ev.Title
+		if z.playing && ev.Title != "" && track != z.track {
+			z.track = track
+			day := st.day(now)
+			day.Tracks++
+			day.source(ev.Source).Tracks++
+			st.dirty = true
+		}
+		if ev.State == "stopped" {
+			z.track = ""
+		}
+	default:
+		return
+	}
+	if z.listening() {
+		st.notePeak(st.day(now), z.volumeDB)
+	}
+	st.checkExposure(now)
+}
+
+// advance accounts listening time up to now, splitting it at midnight.
+func (st *listeningStats) advance(now time.Time) {
+	from := st.last
+	st.last = now
+	if from.IsZero() || !now.After(from) {
+		return
+	}
+	for from.Before(now) {
+		y, m, d := from.Date()
+		midnight := time.Date(y, m, d+1, 0, 0, 0, 0, from.Location())
+		to := now
+		if midnight.Before(now) {
+			to = midnight
+		}
+		st.account(from, to.Sub(from).Seconds())
+		from = to
+	}
+}
+
+// account adds sec of listening, as of at, for every listening zone.
+func (st *listeningStats) account(at time.Time, sec float64) {
+	var day *statsDay
+	for _, z := range st.zones {
+		if !z.listening() {
+			continue
+		}
+		if day == nil {
+			day = st.day(at)
+		}
+		day.ListeningSec += sec
+		day.VolumeDBSec += z.volumeDB * sec
+		if z.volumeDB >= st.cfg.LoudThresholdDB {
+			day.LoudSec += sec
+		}
+		day.source(z.source).ListeningSec += sec
+		st.notePeak(day, z.volumeDB)
+		st.dirty = true
+	}
+}
+
+func (st *listeningStats) notePeak(day *statsDay, db float64) {
+	if day.PeakVolumeDB == nil || db > *day.PeakVolumeDB {
+		day.PeakVolumeDB = &db
+		st.dirty = true
+	}
+}
+
+// day returns the record for t's local date, creating it if needed.
+func (st *listeningStats) day(t time.Time) *statsDay {
+	date := t.Format(statsDateLayout)
+	if n := len(st.data.Days); n > 0 && st.data.Days[n-1].Date == date {
+		return st.data.Days[n-1]
+	}
+	d := &statsDay{Date: date}
+	st.data.Days = append(st.data.Days, d)
+	slices.SortFunc(st.data.Days, func(a, b *statsDay) int {
+		switch {
+		case a.Date < b.Date:
+			return -1
+		case a.Date > b.Date:
+			return 1
+		}
+		return 0
+	})
+	return d
+}
+
+func (d *statsDay) source(name string) *statsSource {
+	if name == "" {
+		name = "unknown"
+	}
+	if d.Sources == nil {
+		d.Sources = make(map[string]*statsSource)
+	}
+	s := d.Sources[name]
+	if s == nil {
+		s = &statsSource{}
+		d.Sources[name] = s
+	}
+	return s
+}
+
+// flush accounts time so far, logs a weekly summary if one is due, prunes old
+// days and saves the store.
+func (st *listeningStats) flush() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	now := st.now()
+	st.advance(now)
+	st.checkExposure(now)
+
+	if st.cfg.WeeklySummary {
+		y, w := now.ISOWeek()
+		week := strconv.Itoa(y) + "-W" + strconv.Itoa(w)
+		if st.data.SummaryWeek == "" {
+			st.data.SummaryWeek, st.dirty = week, true // start counting from this week
+		} else if week != st.data.SummaryWeek {
+			st.logSummary(now)
+			st.data.SummaryWeek, st.dirty = week, true
+		}
+	}
+
+	if st.cfg.RetentionDays > 0 {
+		cutoff := now.AddDate(0, 0, -st.cfg.RetentionDays).Format(statsDateLayout)
+		keep := slices.DeleteFunc(st.data.Days, func(d *statsDay) bool { return d.Date < cutoff })
+		if len(keep) != len(st.data.Days) {
+			st.dirty = true
+		}
+		st.data.Days = keep
+	}
+
+	if !st.dirty || st.cfg.File == "" {
+		return
+	}
+	if err := st.save(); err != nil {
+		st.logger.Warn("failed to save listening stats", "path", st.cfg.File, "error", err)
+		return
+	}
+	st.dirty = false
+}
+
+func (st *listeningStats) save() error {
+	b, err := json.Marshal(st.data)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(st.cfg.File), 0o700); err != nil {
+		return err
+	}
+	tmp := st.cfg.File + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, st.cfg.File)
+}
+
+// logSummary logs the 7 days before now's date (st.mu held).
+func (st *listeningStats) logSummary(now time.Time) {
+	r := st.report(now.AddDate(0, 0, -1), 7)
+	attrs := []any{
+		"from", r.From, "to", r.To,
+		"listening_hours", math.Round(r.Total.ListeningSec/36) / 100,
+		"tracks", r.Total.Tracks,
+		"loud_minutes", math.Round(r.Total.LoudSec / 60),
+	}
+	if r.Total.AvgVolumeDB != nil {
+		attrs = append(attrs, "avg_volume_db", *r.Total.AvgVolumeDB, "peak_volume_db", *r.Total.PeakVolumeDB)
+	}
+	if top := r.Total.topSource(); top != "" {
+		attrs = append(attrs, "top_source", top)
+	}
+	st.logger.Info("weekly listening summary", attrs...)
+}
+
+// statsDayReport is a day as reported by the API.
+type statsDayReport struct {
+	Date         string                 `json:"date,omitempty"`
+	ListeningSec float64                `json:"listening_sec"`
+	AvgVolumeDB  *float64               `json:"avg_volume_db,omitempty"`
+	PeakVolumeDB *float64               `json:"peak_volume_db,omitempty"`
+	LoudSec      float64                `json:"loud_sec"`
+	Tracks       int                    `json:"tracks"`
+	Sources      map[string]statsSource `json:"sources,omitempty"`
+
+	ExposureWarning string `json:"exposure_warning,omitempty"`
+}
+
+func (r statsDayReport) topSource() string {
+	top := ""
+	for _, name := range slices.Sorted(maps.Keys(r.Sources)) {
+		if top == "" || r.Sources[name].ListeningSec > r.Sources[top].ListeningSec {
+			top = name
+		}
+	}
+	return top
+}
+
+// statsReport is the GET /api/v1/stats response.
+type statsReport struct {
+	From            string  `json:"from"`
+	To              string  `json:"to"`
+	LoudThresholdDB float64 `json:"loud_threshold_db"`
+
+	// ExposureBudgetSec is the daily loud listening budget (stats.exposure).
+	ExposureBudgetSec float64 `json:"exposure_budget_sec,omitempty"`
+
+	Days  []statsDayReport `json:"days"`
+	Total statsDayReport   `json:"total"`
+}
+
+// report summarizes the n days ending on to's date, oldest first (st.mu held).
+// Days without listening are included with zeros.
+func (st *listeningStats) report(to time.Time, n int) statsReport {
+	byDate := make(map[string]*statsDay, len(st.data.Days))
+	for _, d := range st.data.Days {
+		byDate[d.Date] = d
+	}
+	r := statsReport{LoudThresholdDB: st.cfg.LoudThresholdDB, Days: make([]statsDayReport, 0, n)}
+	if st.cfg.Exposure.Enabled {
+		r.ExposureBudgetSec = st.cfg.Exposure.DailyBudgetMin * 60
+	}
+	var total statsDay
+	for i := n - 1; i >= 0; i-- {
+		date := to.AddDate(0, 0, -i).Format(statsDateLayout)
+		d := byDate[date]
+		if d == nil {
+			d = &statsDay{Date: date}
+		}
+		r.Days = append(r.Days, d.report())
+
+		total.ListeningSec += d.ListeningSec
+		total.VolumeDBSec += d.VolumeDBSec
+		total.LoudSec += d.LoudSec
+		total.Tracks += d.Tracks
+		if d.PeakVolumeDB != nil && (total.PeakVolumeDB == nil || *d.PeakVolumeDB > *total.PeakVolumeDB) {
+			total.PeakVolumeDB = d.PeakVolumeDB
+		}
+		for name, s := range d.Sources {
+			ts := total.source(name)
+			ts.ListeningSec += s.ListeningSec
+			ts.Tracks += s.Tracks
+		}
+	}
+	r.From, r.To = r.Days[0].Date, r.Days[len(r.Days)-1].Date
+	r.Total = total.report()
+	r.Total.Date = ""
+	return r
+}
+
+func (d *statsDay) report() statsDayReport {
+	r := statsDayReport{
+		Date:         d.Date,
+		ListeningSec: math.Round(d.ListeningSec),
+		PeakVolumeDB: d.PeakVolumeDB,
+		LoudSec:      math.Round(d.LoudSec),
+		Tracks:       d.Tracks,
+
+		ExposureWarning: d.ExposureWarning,
+	}
+	if d.ListeningSec > 0 {
+		avg := math.Round(d.VolumeDBSec/d.ListeningSec*10) / 10
+		r.AvgVolumeDB = &avg
+	}
+	if len(d.Sources) > 0 {
+		r.Sources = make(map[string]statsSource, len(d.Sources))
+		for name, s := range d.Sources {
+			r.Sources[name] = statsSource{ListeningSec: math.Round(s.ListeningSec), Tracks: s.Tracks}
+		}
+	}
+	return r
+}
+
+// statsHandler serves GET /api/v1/stats.
+type statsHandler struct {
+	stats *listeningStats // nil when stats are disabled
+}
+
+func (h *statsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeEventWebhookResponse(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if h.stats == nil {
+		writeEventWebhookResponse(w, http.StatusNotFound, "stats are disabled (stats.enabled)")
+		return
+	}
+	days := statsDefaultDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > statsMaxDays {
+			writeEventWebhookResponse(w, http.StatusBadRequest, "days must be 1-366")
+			return
+		}
+		days = n
+	}
+
+	h.stats.mu.Lock()
+	now := h.stats.now()
+	h.stats.advance(now)
+	resp := h.stats.report(now, days)
+	h.stats.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
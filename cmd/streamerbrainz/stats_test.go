@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock is a settable clock for listeningStats.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestStats(t *testing.T, cfg StatsConfig, clock *fakeClock) *listeningStats {
+	t.Helper()
+	st := newListeningStats(cfg, nil, slog.New(slog.DiscardHandler))
+	st.now = clock.now
+	return st
+}
+
+func zoneBroadcast(b StateBroadcast) StateBroadcast { return ZoneBroadcast{Zone: "main", Broadcast: b} }
+
+func TestListeningStats_RecordsListeningTimeAndVolume(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2026, 3, 2, 20, 0, 0, 0, time.Local)}
+	st := newTestStats(t, StatsConfig{LoudThresholdDB: -10}, clock)
+
+	st.observe(zoneBroadcast(BroadcastVolumeChanged{VolumeDB: -20}))
+	st.observe(zoneBroadcast(BroadcastPlayerChanged{Source: "plex", State: "playing", Artist: "A", Title: "One"}))
+	clock.advance(30 * time.Minute)
+	st.observe(zoneBroadcast(BroadcastVolumeChanged{VolumeDB: -5})) // loud for the next 10 minutes
+	clock.advance(10 * time.Minute)
+	st.observe(zoneBroadcast(BroadcastMuteChanged{Muted: true})) // muted time doesn't count
+	clock.advance(20 * time.Minute)
+	st.observe(zoneBroadcast(BroadcastMuteChanged{Muted: false}))
+	st.observe(zoneBroadcast(BroadcastPlayerChanged{Source: "tidal", State: "playing", Artist: "B", Title: "Two"}))
+	clock.advance(5 * time.Minute)
+	st.observe(zoneBroadcast(BroadcastPlayerChanged{Source: "tidal", State: "paused", Artist: "B", Title: "Two"}))
+	clock.advance(time.Hour)
+
+	st.mu.Lock()
+	r := st.report(clock.now(), 1)
+	st.mu.Unlock()
+	d := r.Days[0]
+	if d.Date != "2026-03-02" || d.ListeningSec != 45*60 || d.LoudSec != 15*60 || d.Tracks != 2 {
+		t.Fatalf("day = %+v", d)
+	}
+	// (30min at -20 + 15min at -5) / 45min = -15 dB
+	if d.AvgVolumeDB == nil || *d.AvgVolumeDB != -15 || *d.PeakVolumeDB != -5 {
+		t.Fatalf("volume = %v / %v", d.AvgVolumeDB, d.PeakVolumeDB)
+	}
+	if d.Sources["plex"].ListeningSec != 40*60 || d.Sources["tidal"].ListeningSec != 5*60 || d.Sources["tidal"].Tracks != 1 {
+		t.Fatalf("sources = %+v", d.Sources)
+	}
+	if r.Total.topSource() != "plex" {
+		t.Fatalf("top source = %q", r.Total.topSource())
+	}
+}
+
+func TestListeningStats_SplitsAtMidnight(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2026, 3, 2, 23, 30, 0, 0, time.Local)}
+	st := newTestStats(t, StatsConfig{}, clock)
+	st.observe(zoneBroadcast(BroadcastVolumeChanged{VolumeDB: -30}))
+	st.observe(zoneBroadcast(BroadcastPlayerChanged{Source: "librespot", State: "playing", Title: "Long"}))
+	clock.advance(time.Hour)
+
+	st.mu.Lock()
+	st.advance(clock.now())
+	r := st.report(clock.now(), 2)
+	st.mu.Unlock()
+	if r.Days[0].ListeningSec != 30*60 || r.Days[1].ListeningSec != 30*60 || r.Total.ListeningSec != 60*60 {
+		t.Fatalf("days = %+v", r.Days)
+	}
+}
+
+func TestListeningStats_PersistsAndPrunes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "stats.json")
+	clock := &fakeClock{t: time.Date(2026, 3, 2, 20, 0, 0, 0, time.Local)}
+	cfg := StatsConfig{File: path, RetentionDays: 30}
+	st := newTestStats(t, cfg, clock)
+	st.data.Days = []*statsDay{{Date: "2025-01-01", ListeningSec: 100}}
+	st.observe(zoneBroadcast(BroadcastVolumeChanged{VolumeDB: -20}))
+	st.observe(zoneBroadcast(BroadcastPlayerChanged{Source: "plex", State: "playing", Artist: "A", Title: "One"}))
+	clock.advance(10 * time.Minute)
+	st.flush()
+
+	st = newTestStats(t, cfg, clock)
+	if len(st.data.Days) != 1 || st.data.Days[0].Date != "2026-03-02" || st.data.Days[0].ListeningSec != 600 {
+		t.Fatalf("reloaded days = %+v", st.data.Days)
+	}
+}
+
+func TestListeningStats_WeeklySummary(t *testing.T) {
+	var logs bytes.Buffer
+	clock := &fakeClock{t: time.Date(2026, 3, 1, 20, 0, 0, 0, time.Local)} // Sunday
+	st := newListeningStats(StatsConfig{WeeklySummary: true}, nil, slog.New(slog.NewTextHandler(&logs, nil)))
+	st.now = clock.now
+	st.flush()
+	clock.advance(6 * time.Hour) // Monday
+	st.flush()
+	if !strings.Contains(logs.String(), "weekly listening summary") {
+		t.Fatalf("no summary logged: %s", logs.String())
+	}
+	logs.Reset()
+	clock.advance(time.Hour)
+	st.flush()
+	if logs.Len() != 0 {
+		t.Fatalf("summary logged twice: %s", logs.String())
+	}
+}
+
+func TestStatsHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	(&statsHandler{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/stats", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("disabled: status %d", rec.Code)
+	}
+
+	clock := &fakeClock{t: time.Date(2026, 3, 2, 20, 0, 0, 0, time.Local)}
+	h := &statsHandler{stats: newTestStats(t, StatsConfig{}, clock)}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/stats?days=0", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("days=0: status %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/stats?days=3", nil))
+	var r statsReport
+	if err := json.NewDecoder(rec.Body).Decode(&r); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("status %d, err %v", rec.Code, err)
+	}
+	if len(r.Days) != 3 || r.From != "2026-02-28" || r.To != "2026-03-02" {
+		t.Fatalf("report = %+v", r)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import "time"
+
+// ============================================================================
+// Test signals (pink noise / tone) for speaker checks
+// ============================================================================
+// A test_signal event switches CamillaDSP to the designated test config of one
+// channel (e.g. a config with a signal generator routed to the left speaker)
+// at test_signal.level_db for a few seconds, then restores the previous config,
+// volume and mute state. Switching uses the output selection sequence
+// (mute -> load config -> set volume -> unmute), so a failed step leaves the
+// zone muted.
+//
+// Sending another channel while a signal plays moves straight to it (for
+// left/right checks); {"stop": true} ends the check early. Volume changes are
+// locked out while a test signal plays.
+// ============================================================================
+
+// TestSignal starts a test signal on Channel (a test_signal.channels id), or stops it.
+type TestSignal struct {
+	Channel     string `json:"channel"`
+	DurationSec int    `json:"duration_sec,omitempty"` // 0 = test_signal.duration_sec
+	Stop        bool   `json:"stop,omitempty"`
+}
+
+func (TestSignal) eventMarker() {}
+
+// TestSignalApplied is emitted after a CmdTestSignal sequence completes successfully.
+type TestSignalApplied struct {
+	Channel string // empty after restoring the previous config
+	At      time.Time
+}
+
+func (TestSignalApplied) eventMarker() {}
+
+// BroadcastTestSignal is emitted when a test signal starts, moves to another channel
+// or ends, or when a request is refused (Error set).
+type BroadcastTestSignal struct {
+	Channel string    `json:"channel"` // empty when no test signal is playing
+	Until   time.Time `json:"until,omitzero"`
+	Error   string    `json:"error,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+func (BroadcastTestSignal) stateBroadcastMarker() {}
+
+// TestSignalState is the reducer-owned test signal state.
+type TestSignalState struct {
+	// Channel is the channel playing (or being switched to); empty when none.
+	Channel string
+	Until   time.Time
+
+	// Pending is set while a CmdTestSignal sequence is in flight.
+	Pending bool
+
+	// What to restore when the check ends.
+	RestoreConfigPath string
+	RestoreVolumeDB   *float64
+	RestoreMuted      bool
+}
+
+// reduceTestSignal handles a TestSignal event.
+func reduceTestSignal(s *DaemonState, ev TestSignal, at time.Time, cfg VelocityConfig, policy ReducerPolicy) ([]Command, []StateBroadcast) {
+	if ev.Stop {
+		return stopTestSignal(s), nil
+	}
+
+	refuse := func(msg string) ([]Command, []StateBroadcast) {
+		return nil, []StateBroadcast{BroadcastTestSignal{Channel: s.TestSignal.Channel, Until: s.TestSignal.Until, Error: msg, At: at}}
+	}
+	var ch *TestSignalChannelConfig
+	for i := range policy.TestSignal.Channels {
+		if policy.TestSignal.Channels[i].ID == ev.Channel {
+			ch = &policy.TestSignal.Channels[i]
+		}
+	}
+	switch {
+	case ch == nil:
+		return refuse("unknown test signal channel: " + ev.Channel)
+	case at.IsZero():
+		return nil, nil
+	case s.Output.Pending != "" || s.TestSignal.Pending:
+		return refuse("busy")
+	case s.TestSignal.Channel == "" && !s.Camilla.Config.Known:
+		// Without the current config there is nothing to restore afterwards.
+		return refuse("current CamillaDSP config unknown")
+	}
+
+	if s.TestSignal.Channel == "" {
+		s.TestSignal.RestoreConfigPath = s.Camilla.Config.FilePath
+		s.TestSignal.RestoreVolumeDB = nil
+		if s.Camilla.VolumeKnown {
+			v := s.Camilla.VolumeMB.DB()
+			s.TestSignal.RestoreVolumeDB = &v
+		}
+		s.TestSignal.RestoreMuted = s.Camilla.MuteKnown && s.Camilla.Muted
+	}
+
+	d := time.Duration(ev.DurationSec) * time.Second
+	if d <= 0 {
+		d = time.Duration(policy.TestSignal.DurationSec) * time.Second
+	}
+	d = min(d, testSignalMaxDuration)
+
+	// Any gesture in progress would fight the fixed level.
+	s.VolumeCtrl.HeldDirection = 0
+	s.VolumeCtrl.VelocityDBPerS = 0
+	s.VolumeCtrl.Ramping = false
+	s.ClearDesiredVolume()
+
+	level := clampVolumeDB(policy.TestSignal.LevelDB, cfg)
+	s.TestSignal.Channel = ch.ID
+	s.TestSignal.Until = at.Add(d)
+	s.TestSignal.Pending = true
+	return []Command{CmdTestSignal{Channel: ch.ID, ConfigPath: ch.ConfigPath, VolumeDB: &level, Unmute: true}}, nil
+}
+
+// stopTestSignal restores the config, volume and mute state from before the check.
+func stopTestSignal(s *DaemonState) []Command {
+	t := &s.TestSignal
+	if t.Channel == "" || t.Pending {
+		return nil
+	}
+	cmd := CmdTestSignal{ConfigPath: t.RestoreConfigPath, VolumeDB: t.RestoreVolumeDB, Unmute: !t.RestoreMuted}
+	t.Channel = ""
+	t.Until = time.Time{}
+	t.Pending = true
+	return []Command{cmd}
+}
+
+// reduceTestSignalApplied completes a CmdTestSignal sequence.
+func reduceTestSignalApplied(s *DaemonState, ev TestSignalApplied) []StateBroadcast {
+	t := &s.TestSignal
+	t.Pending = false
+	if ev.Channel == "" {
+		t.RestoreConfigPath, t.RestoreVolumeDB = "", nil
+	}
+	return []StateBroadcast{BroadcastTestSignal{Channel: t.Channel, Until: t.Until, At: ev.At}}
+}
+
+// reduceTestSignalFailed handles a failed CmdTestSignal sequence (CamillaDSP is left muted).
+// A failed start falls back to restoring; a failed restore gives up.
+func reduceTestSignalFailed(s *DaemonState, c CmdTestSignal, err error, at time.Time) ([]Command, []StateBroadcast) {
+	s.TestSignal.Pending = false
+	msg := "test signal failed"
+	if err != nil {
+		msg = err.Error()
+	}
+	if c.Channel != "" {
+		cmds := stopTestSignal(s)
+		return cmds, []StateBroadcast{BroadcastTestSignal{Error: msg, At: at}}
+	}
+	s.TestSignal = TestSignalState{}
+	return nil, []StateBroadcast{BroadcastTestSignal{Error: msg, At: at}}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func testSignalConfig() (VelocityConfig, ReducerPolicy) {
+	return VelocityConfig{MinDB: -80, MaxDB: 0}, ReducerPolicy{
+		TestSignal: TestSignalConfig{
+			LevelDB:     -30,
+			DurationSec: 5,
+			Channels: []TestSignalChannelConfig{
+				{ID: "left", ConfigPath: "/etc/camilladsp/noise-left.yml"},
+				{ID: "right", ConfigPath: "/etc/camilladsp/noise-right.yml"},
+			},
+		},
+	}
+}
+
+func TestReduce_TestSignalPlaysAndRestores(t *testing.T) {
+	cfg, policy := testSignalConfig()
+	t0 := time.Unix(1000, 0).UTC()
+
+	s := &DaemonState{}
+	s.SetObservedVolume(-40, t0)
+	s.SetObservedMute(false, t0)
+	s.SetObservedConfigFilePath("/etc/camilladsp/main.yml", t0)
+
+	rr := Reduce(s, TimedEvent{Event: TestSignal{Channel: "left"}, At: t0}, cfg, RotaryConfig{}, policy)
+	if len(rr.Commands) != 1 {
+		t.Fatalf("expected one command, got %#v", rr.Commands)
+	}
+	start := rr.Commands[0].(CmdTestSignal)
+	if start.Channel != "left" || start.ConfigPath != "/etc/camilladsp/noise-left.yml" || *start.VolumeDB != -30 || !start.Unmute {
+		t.Fatalf("unexpected start command: %#v", start)
+	}
+	rr = Reduce(rr.State, TestSignalApplied{Channel: "left", At: t0}, cfg, RotaryConfig{}, policy)
+	if b := rr.Broadcasts[0].(BroadcastTestSignal); b.Channel != "left" || !b.Until.Equal(t0.Add(5*time.Second)) {
+		t.Fatalf("unexpected broadcast: %#v", b)
+	}
+
+	// Volume is locked while the signal plays.
+	rr = Reduce(rr.State, SetVolumeAbsolute{Db: 0}, cfg, RotaryConfig{}, policy)
+	if _, ok := rr.State.GetDesiredVolume(); ok {
+		t.Fatalf("expected volume change to be locked out")
+	}
+
+	rr = Reduce(rr.State, Tick{Now: t0.Add(4 * time.Second), Dt: 1}, cfg, RotaryConfig{}, policy)
+	if len(rr.Commands) != 0 {
+		t.Fatalf("expected signal to keep playing, got %#v", rr.Commands)
+	}
+	rr = Reduce(rr.State, Tick{Now: t0.Add(5 * time.Second), Dt: 1}, cfg, RotaryConfig{}, policy)
+	if len(rr.Commands) != 1 {
+		t.Fatalf("expected restore command, got %#v", rr.Commands)
+	}
+	restore := rr.Commands[0].(CmdTestSignal)
+	if restore.Channel != "" || restore.ConfigPath != "/etc/camilladsp/main.yml" || *restore.VolumeDB != -40 || !restore.Unmute {
+		t.Fatalf("unexpected restore command: %#v", restore)
+	}
+	rr = Reduce(rr.State, TestSignalApplied{At: t0.Add(5 * time.Second)}, cfg, RotaryConfig{}, policy)
+	if rr.State.TestSignal.Channel != "" || rr.State.TestSignal.Pending || rr.Broadcasts[0].(BroadcastTestSignal).Channel != "" {
+		t.Fatalf("expected test signal to end, got %#v", rr.State.TestSignal)
+	}
+}
+
+func TestReduce_TestSignalRefusedWithoutKnownConfig(t *testing.T) {
+	cfg, policy := testSignalConfig()
+	rr := Reduce(&DaemonState{}, TimedEvent{Event: TestSignal{Channel: "left"}, At: time.Unix(1000, 0)}, cfg, RotaryConfig{}, policy)
+	if len(rr.Commands) != 0 || len(rr.Broadcasts) != 1 || rr.Broadcasts[0].(BroadcastTestSignal).Error == "" {
+		t.Fatalf("expected refusal, got %#v / %#v", rr.Commands, rr.Broadcasts)
+	}
+}
+
+func TestReduce_TestSignalFailedStartRestores(t *testing.T) {
+	cfg, policy := testSignalConfig()
+	t0 := time.Unix(1000, 0).UTC()
+	s := &DaemonState{}
+	s.SetObservedConfigFilePath("/etc/camilladsp/main.yml", t0)
+
+	rr := Reduce(s, TimedEvent{Event: TestSignal{Channel: "right"}, At: t0}, cfg, RotaryConfig{}, policy)
+	rr = Reduce(rr.State, CamillaCommandFailed{Command: rr.Commands[0], Err: errors.New("reload failed"), At: t0}, cfg, RotaryConfig{}, policy)
+	if len(rr.Commands) != 1 || rr.Commands[0].(CmdTestSignal).ConfigPath != "/etc/camilladsp/main.yml" {
+		t.Fatalf("expected restore after failed start, got %#v", rr.Commands)
+	}
+}
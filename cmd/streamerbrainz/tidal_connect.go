@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// Tidal Connect (ifi tidal_connect_application) metadata
+// ============================================================================
+// The Tidal Connect player used on streamers (the ifi tidal-connect container
+// and its speaker_controller_application) has no API, but it prints its state:
+//
+//	PlaybackState::PLAYING
+//	artists: Nina Simone
+//	album: Pastel Blues
+//	title: Sinnerman
+//	duration: 622000
+//
+// tidal_connect.command runs something that prints that output (typically
+// `docker logs -f --tail 0 tidal-connect`) and is restarted when it exits;
+// tidal_connect.log_file tails a file instead (rotation and truncation are
+// followed). Lines are parsed into the shared player model (source "tidal"):
+// a block of metadata lines is reported once it has settled, so a track change
+// yields one player_changed rather than one per field. ANSI colors and log
+// prefixes are ignored; D-Bus is not read.
+// ============================================================================
+
+const (
+	// tidalSettle is how long the output must be quiet before a change is reported.
+	tidalSettle = 250 * time.Millisecond
+
+	tidalRestartBackoff = 5 * time.Second
+	tidalPollInterval   = 500 * time.Millisecond
+)
+
+var (
+	tidalANSI          = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+	tidalPlaybackState = regexp.MustCompile(`PlaybackState::([A-Z_]+)`)
+	tidalField         = regexp.MustCompile(`(?i)\b(artists?|album|title|duration)\s*:\s*(.*)$`)
+)
+
+// tidalParser accumulates the player's state from its output lines.
+type tidalParser struct {
+	cur      TidalStateChanged
+	reported TidalStateChanged
+	seen     bool // something has been parsed since the start
+}
+
+// feed parses one line and reports whether it changed the state.
+func (p *tidalParser) feed(line string) bool {
+	line = strings.TrimSpace(tidalANSI.ReplaceAllString(line, ""))
+	if m := tidalPlaybackState.FindStringSubmatch(line); m != nil {
+		var state string
+		switch m[1] {
+		case "PLAYING":
+			state = "playing"
+		case "PAUSED":
+			state = "paused"
+		case "STOPPED", "IDLE":
+			state = "stopped"
+		default:
+			return false // BUFFERING etc. are not transitions
+		}
+		return p.set(&p.cur.State, state)
+	}
+	m := tidalField.FindStringSubmatch(line)
+	if m == nil {
+		return false
+	}
+	value := strings.TrimSpace(m[2])
+	switch strings.ToLower(m[1]) {
+	case "artist", "artists":
+		return p.set(&p.cur.Artist, value)
+	case "album":
+		return p.set(&p.cur.Album, value)
+	case "title":
+		return p.set(&p.cur.Title, value)
+	case "duration":
+		ms, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || ms == p.cur.DurationMs {
+			return false
+		}
+		p.cur.DurationMs = ms
+		p.seen = true
+		return true
+	}
+	return false
+}
+
+func (p *tidalParser) set(field *string, value string) bool {
+	if *field == value {
+		return false
+	}
+	*field = value
+	p.seen = true
+	return true
+}
+
+// flush returns the state to report, if it differs from the last one reported.
+func (p *tidalParser) flush() (TidalStateChanged, bool) {
+	if !p.seen || p.cur == p.reported {
+		return TidalStateChanged{}, false
+	}
+	p.reported = p.cur
+	return p.cur, true
+}
+
+// runTidalConnect reads the player's output until ctx is canceled.
+func runTidalConnect(ctx context.Context, cfg TidalConnectConfig, events chan<- Event, logger *slog.Logger) {
+	lines := make(chan string, 64)
+	if cfg.LogFile != "" {
+		go tailTidalLog(ctx, cfg.LogFile, lines, logger)
+	} else {
+		go runTidalCommand(ctx, cfg.Command, lines, logger)
+	}
+	logger.Info("tidal connect metadata enabled", "command", cfg.Command, "log_file", cfg.LogFile)
+
+	var p tidalParser
+	settle := time.NewTimer(tidalSettle)
+	settle.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case line := <-lines:
+			if p.feed(line) {
+				settle.Reset(tidalSettle)
+			}
+		case <-settle.C:
+			if ev, ok := p.flush(); ok {
+				logger.Debug("tidal connect state", "state", ev.State, "title", ev.Title, "artist", ev.Artist)
+				select {
+				case events <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}
+}
+
+// runTidalCommand runs command, restarting it when it exits, and sends its
+// stdout and stderr lines to lines.
+func runTidalCommand(ctx context.Context, command []string, lines chan<- string, logger *slog.Logger) {
+	for {
+		err := func() error {
+			cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+			pr, pw := io.Pipe()
+			cmd.Stdout, cmd.Stderr = pw, pw
+			if err := cmd.Start(); err != nil {
+				return err
+			}
+			go func() {
+				pw.CloseWithError(cmd.Wait())
+			}()
+			return scanTidalLines(ctx, pr, lines)
+		}()
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Warn("tidal connect command exited; restarting", "command", command, "error", err, "retry_in", tidalRestartBackoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(tidalRestartBackoff):
+		}
+	}
+}
+
+func scanTidalLines(ctx context.Context, r io.Reader, lines chan<- string) error {
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		select {
+		case lines <- sc.Text():
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	return sc.Err()
+}
+
+// tailTidalLog follows path from its current end, reopening it when it is
+// rotated or truncated.
+func tailTidalLog(ctx context.Context, path string, lines chan<- string, logger *slog.Logger) {
+	var (
+		f       *os.File
+		r       *bufio.Reader
+		offset  int64
+		partial string
+	)
+	defer func() {
+		if f != nil {
+			f.Close()
+		}
+	}()
+	open := func(fromEnd bool) {
+		nf, err := os.Open(path)
+		if err != nil {
+			return
+		}
+		var end int64
+		if fromEnd {
+			if end, err = nf.Seek(0, io.SeekEnd); err != nil {
+				nf.Close()
+				return
+			}
+		}
+		if f != nil {
+			f.Close()
+		}
+		f, r, offset, partial = nf, bufio.NewReader(nf), end, ""
+	}
+	open(true)
+	if f == nil {
+		logger.Warn("tidal connect log not readable yet; waiting for it", "path", path)
+	}
+
+	for {
+		for r != nil {
+			s, err := r.ReadString('\n')
+			offset += int64(len(s))
+			if err != nil {
+				partial += s
+				break
+			}
+			select {
+			case lines <- strings.TrimRight(partial+s, "\r\n"):
+			case <-ctx.Done():
+				return
+			}
+			partial = ""
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(tidalPollInterval):
+		}
+
+		// Reopen from the start once the file appears, or after rotation or truncation.
+		st, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if f == nil {
+			open(false)
+			continue
+		}
+		if cur, err := f.Stat(); err != nil || !os.SameFile(cur, st) || st.Size() < offset {
+			open(false)
+		}
+	}
+}
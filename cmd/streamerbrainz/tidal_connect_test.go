@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTidalParser(t *testing.T) {
+	var p tidalParser
+	if _, ok := p.flush(); ok {
+		t.Fatal("nothing parsed yet")
+	}
+
+	for _, line := range []string{
+		"[2024-05-01 10:00:00.123] [info] \x1b[32mPlaybackState::PLAYING\x1b[0m",
+		"  artists: Nina Simone",
+		"  album: Pastel Blues",
+		"  title: Sinnerman",
+		"  duration: 622000",
+		"volume: 40",
+	} {
+		p.feed(line)
+	}
+	got, ok := p.flush()
+	want := TidalStateChanged{State: "playing", Title: "Sinnerman", Artist: "Nina Simone", Album: "Pastel Blues", DurationMs: 622000}
+	if !ok || got != want {
+		t.Fatalf("got %+v (%v), want %+v", got, ok, want)
+	}
+
+	// Repeated output and non-transitions change nothing.
+	if p.feed("title: Sinnerman") || p.feed("PlaybackState::BUFFERING") {
+		t.Fatal("unexpected change")
+	}
+	if _, ok := p.flush(); ok {
+		t.Fatal("unchanged state reported again")
+	}
+
+	p.feed("PlaybackState::PAUSED")
+	if got, ok := p.flush(); !ok || got.State != "paused" || got.Title != "Sinnerman" {
+		t.Fatalf("pause: got %+v", got)
+	}
+	p.feed("PlaybackState::IDLE")
+	if got, _ := p.flush(); got.State != "stopped" {
+		t.Fatalf("idle: got %+v", got)
+	}
+}
+
+func TestRunTidalConnect_LogFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tidal.log")
+	if err := os.WriteFile(path, []byte("title: Old song\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan Event, 4)
+	go runTidalConnect(ctx, TidalConnectConfig{Enabled: true, LogFile: path}, events, slog.Default())
+	time.Sleep(100 * time.Millisecond) // let the tail start at the end
+
+	appendLog := func(s string) {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	appendLog("PlaybackState::PLAYING\ntitle: Sinnerman\n")
+	select {
+	case ev := <-events:
+		if ev != (TidalStateChanged{State: "playing", Title: "Sinnerman"}) {
+			t.Fatalf("got %#v", ev)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no event")
+	}
+
+	// Truncation (e.g. copytruncate rotation) is followed from the start.
+	if err := os.WriteFile(path, []byte("PlaybackState::PAUSED\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case ev := <-events:
+		if ev.(TidalStateChanged).State != "paused" {
+			t.Fatalf("got %#v", ev)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no event after truncation")
+	}
+}
+
+func TestReduce_TidalStateChanged(t *testing.T) {
+	rr := Reduce(&DaemonState{}, TidalStateChanged{State: "playing", Title: "Sinnerman", Artist: "Nina Simone"}, VelocityConfig{}, RotaryConfig{}, ReducerPolicy{})
+	if len(rr.Broadcasts) != 1 {
+		t.Fatalf("broadcasts: %+v", rr.Broadcasts)
+	}
+	if b := rr.Broadcasts[0].(BroadcastPlayerChanged); b.Source != "tidal" || b.Title != "Sinnerman" || b.State != "playing" {
+		t.Fatalf("got %+v", b)
+	}
+}
+
+func TestConfigValidate_TidalConnect(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.TidalConnect.Enabled = true
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected error without a source")
+	}
+	cfg.TidalConnect.Command = []string{"docker", "logs", "-f", "tidal-connect"}
+	cfg.TidalConnect.LogFile = "/var/log/tidal.log"
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected error with both sources")
+	}
+	cfg.TidalConnect.LogFile = ""
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,699 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"gopkg.in/yaml.v3"
+)
+
+// ============================================================================
+// `tune` subcommand
+// ============================================================================
+//   streamerbrainz tune [-config path] [-url http://host:port] [-zone id]
+//
+// Interactive wizard against the running daemon: it follows volume_changed on
+// /ws/state while the user taps, holds and spins the controls, measures how far
+// and how fast the volume moved (and how far it kept coasting after a hold),
+// asks whether each felt right, and suggests velocity/rotary values. The
+// suggestions can be applied live (PUT /api/v1/tuning) and/or written into the
+// config file (only the changed keys; comments elsewhere are kept).
+//
+// Every measurement turns the volume DOWN, and the starting volume is restored
+// (volume.set over /jsonrpc) after each step.
+// ============================================================================
+
+const (
+	tuneHTTPTimeout = 5 * time.Second
+
+	// tuneMaxWait bounds how long a step waits for the user to start.
+	tuneMaxWait = 20 * time.Second
+	// tuneSettle ends a step once the volume has not changed for this long.
+	tuneSettle = 1500 * time.Millisecond
+	// tuneRateWindow is the minimum span used to compute a movement rate; shorter
+	// spans are dominated by the state broadcaster's coalescing.
+	tuneRateWindow = 150 * time.Millisecond
+
+	// tuneMaxCoastDB is how far the volume may keep moving after a hold is released
+	// before a shorter decay_tau_sec is suggested.
+	tuneMaxCoastDB = 2.0
+	// tuneFastSpinRate splits rotary curve points into "slow" and "fast" (steps/s).
+	tuneFastSpinRate = 10.0
+
+	tuneSlowClicks = 10
+	tuneTaps       = 3
+)
+
+// tuneSample is one observed volume.
+type tuneSample struct {
+	At time.Time
+	DB float64
+}
+
+// tuneMovement summarizes the volume samples recorded during one step.
+type tuneMovement struct {
+	TotalDB      float64       // absolute change from first to last sample
+	PeakDBPerSec float64       // fastest rate over tuneRateWindow
+	TimeToPeak   time.Duration // first sample to first reaching 90% of the peak
+	CoastDB      float64       // movement after the rate last was at 90% of the peak
+}
+
+// tuneResults are the measurements and the user's answers, as scale factors
+// (1 = keep, 0 = not asked).
+type tuneResults struct {
+	Hold           tuneMovement
+	TapFactor      float64
+	HoldFactor     float64
+	SlowSpinFactor float64
+	FastSpinFactor float64
+}
+
+// tuneChange is one config key the wizard wants to change.
+type tuneChange struct {
+	Section string // "velocity" or "rotary"
+	Key     string
+	Old     any
+	New     any
+}
+
+func printTuneUsage() {
+	fmt.Println("USAGE:")
+	fmt.Println("  streamerbrainz tune [-config path] [-url http://host:port] [-zone id]")
+	fmt.Println()
+	fmt.Println("  Walk through tapping, holding and spinning the volume controls against the")
+	fmt.Println("  running daemon and suggest velocity/rotary settings. The suggestions can be")
+	fmt.Println("  applied live and/or written to the config file.")
+	fmt.Println()
+	fmt.Println("  -url defaults to the api (or webhooks) listener from the config.")
+	fmt.Println()
+}
+
+// runTuneSubcommand handles `streamerbrainz tune`.
+func runTuneSubcommand(args []string) {
+	fs := flag.NewFlagSet("tune", flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to YAML config file")
+	baseURL := fs.String("url", "", "Daemon API base URL (default: from the config)")
+	zone := fs.String("zone", "", "Zone to measure (default: the first zone reported)")
+	fs.Usage = printTuneUsage
+	fs.Parse(args)
+
+	*configPath = ResolveConfigPath(*configPath)
+	cfg, err := LoadConfigFile(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	if *baseURL == "" {
+		*baseURL = tuneBaseURL(cfg)
+	}
+	*baseURL = strings.TrimSuffix(*baseURL, "/")
+	token, err := apiClientToken(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error: read control API token:", err)
+		os.Exit(1)
+	}
+
+	if err := runTuneWizard(*configPath, *baseURL, token, *zone, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// tuneBaseURL derives the daemon's control API URL from the config, using the
+// loopback address when the listener binds all interfaces.
+func tuneBaseURL(cfg Config) string {
+	addr := cfg.API.ListenAddr()
+	if addr == "" {
+		addr = cfg.Webhooks.ListenAddr()
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "http://" + addr
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+	return "http://" + net.JoinHostPort(host, port) + cfg.API.BasePathPrefix()
+}
+
+func runTuneWizard(configPath, baseURL, token, zone string, stdin io.Reader, out io.Writer) error {
+	client := newAPIClient(token, tuneHTTPTimeout)
+
+	var cur ConfigUpdated
+	if err := tuneGetJSON(client, baseURL+"/api/v1/tuning", &cur); err != nil {
+		return fmt.Errorf("read current tuning from %s (is the daemon running?): %w", baseURL, err)
+	}
+
+	wsURL := "ws" + strings.TrimPrefix(baseURL, "http") + "/ws/state"
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		return fmt.Errorf("connect to %s: %w", wsURL, err)
+	}
+	defer conn.Close()
+
+	samples := make(chan tuneSample, 256)
+	go readTuneFeed(conn, zone, samples)
+
+	var startDB float64
+	select {
+	case s, ok := <-samples:
+		if !ok {
+			return errors.New("state connection closed before the volume was known")
+		}
+		startDB = s.DB
+	case <-time.After(3 * time.Second):
+		return errors.New("daemon did not report a volume (is CamillaDSP connected?)")
+	}
+
+	w := &tuneWizard{
+		in:      bufio.NewReader(stdin),
+		out:     out,
+		samples: samples,
+		restore: func() error {
+			return tuneSetVolume(client, baseURL, zone, startDB)
+		},
+	}
+
+	fmt.Fprintf(out, "Current volume: %.1f dB. Each step turns the volume DOWN and restores it afterwards.\n", startDB)
+	fmt.Fprintln(out, "Type s and Enter at a prompt to skip a step.")
+
+	res := tuneResults{}
+	accelerating := cur.Velocity.Mode == "" || cur.Velocity.Mode == string(VelocityModeAccelerating)
+
+	if accelerating {
+		if m, ok := w.measure(fmt.Sprintf("Tap VOLUME DOWN %d times, about half a second apart.", tuneTaps)); ok {
+			fmt.Fprintf(out, "  %.2f dB per tap\n", m.TotalDB/tuneTaps)
+			res.TapFactor = w.rate("Should a tap move the volume")
+		}
+	}
+	if m, ok := w.measure("Press and hold VOLUME DOWN for about 3 seconds, then release."); ok {
+		fmt.Fprintf(out, "  peak %.1f dB/s after %.1fs, kept moving %.1f dB after release\n",
+			m.PeakDBPerSec, m.TimeToPeak.Seconds(), m.CoastDB)
+		res.Hold = m
+		res.HoldFactor = w.rate("Should holding move the volume")
+	}
+	if m, ok := w.measure(fmt.Sprintf("Turn the knob DOWN slowly: %d clicks, about two per second.", tuneSlowClicks)); ok {
+		fmt.Fprintf(out, "  %.2f dB per click\n", m.TotalDB/tuneSlowClicks)
+		res.SlowSpinFactor = w.rate("Should a slow click move the volume")
+	}
+	if m, ok := w.measure("Spin the knob DOWN quickly for about a second."); ok {
+		fmt.Fprintf(out, "  %.1f dB total, peak %.1f dB/s\n", m.TotalDB, m.PeakDBPerSec)
+		res.FastSpinFactor = w.rate("Should a fast spin move the volume")
+	}
+
+	next := suggestTuning(cur, res)
+	changes, err := diffTuning(cur, next)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Fprintln(out, "\nThe current settings look right; nothing to change.")
+		return nil
+	}
+
+	fmt.Fprintln(out, "\nSuggested settings:")
+	section := ""
+	for _, c := range changes {
+		if c.Section != section {
+			section = c.Section
+			fmt.Fprintf(out, "  %s:\n", section)
+		}
+		fmt.Fprintf(out, "    %s: %s (was %s)\n", c.Key, tuneFormat(c.New), tuneFormat(c.Old))
+	}
+
+	if w.confirm("Apply to the running daemon now?") {
+		body, _ := json.Marshal(tunePatch(changes))
+		if err := tunePutJSON(client, baseURL+"/api/v1/tuning", body); err != nil {
+			return fmt.Errorf("apply tuning: %w", err)
+		}
+		fmt.Fprintln(out, "Applied.")
+	}
+	if w.confirm(fmt.Sprintf("Write to %s?", configPath)) {
+		if err := writeTuningToConfig(configPath, changes); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Written to %s.\n", configPath)
+	}
+	return nil
+}
+
+// tuneWizard holds the interactive state of one `tune` run.
+type tuneWizard struct {
+	in      *bufio.Reader
+	out     io.Writer
+	samples <-chan tuneSample
+	restore func() error
+}
+
+func (w *tuneWizard) readLine() string {
+	line, _ := w.in.ReadString('\n')
+	return strings.ToLower(strings.TrimSpace(line))
+}
+
+// measure prints an instruction, records the movement once the user presses Enter,
+// then restores the starting volume. ok is false if the step was skipped or no
+// movement was seen.
+func (w *tuneWizard) measure(instruction string) (tuneMovement, bool) {
+	fmt.Fprintf(w.out, "\n%s\nPress Enter, then go. ", instruction)
+	if w.readLine() == "s" {
+		return tuneMovement{}, false
+	}
+	drainTuneSamples(w.samples)
+	got := recordMovement(w.samples, tuneMaxWait, tuneSettle)
+
+	if err := w.restore(); err != nil {
+		fmt.Fprintf(w.out, "  (could not restore the volume: %v)\n", err)
+	}
+	// Let any restore ramp finish before the next step.
+	recordMovement(w.samples, 3*time.Second, 500*time.Millisecond)
+
+	if len(got) < 2 {
+		fmt.Fprintln(w.out, "  no volume change seen; skipping")
+		return tuneMovement{}, false
+	}
+	return analyzeMovement(got), true
+}
+
+// rate asks whether something should move the volume less or more and returns the
+// corresponding scale factor.
+func (w *tuneWizard) rate(question string) float64 {
+	for {
+		fmt.Fprintf(w.out, "%s [l]ess, [m]ore, or is it right [Enter]? ", question)
+		switch w.readLine() {
+		case "":
+			return 1
+		case "l", "less":
+			return 2.0 / 3
+		case "m", "more":
+			return 1.5
+		}
+	}
+}
+
+func (w *tuneWizard) confirm(question string) bool {
+	fmt.Fprintf(w.out, "%s [y/N] ", question)
+	a := w.readLine()
+	return a == "y" || a == "yes"
+}
+
+// readTuneFeed forwards the volume from state_init and volume_changed envelopes to
+// out until the connection closes. With zone empty, it follows the first zone seen.
+func readTuneFeed(conn *websocket.Conn, zone string, out chan<- tuneSample) {
+	defer close(out)
+	for {
+		var env struct {
+			Type string          `json:"type"`
+			Zone string          `json:"zone"`
+			Ts   *time.Time      `json:"ts"`
+			Data json.RawMessage `json:"data"`
+		}
+		if err := conn.ReadJSON(&env); err != nil {
+			return
+		}
+		if env.Zone != "" {
+			if zone == "" {
+				zone = env.Zone
+			} else if env.Zone != zone {
+				continue
+			}
+		}
+
+		at := time.Now()
+		if env.Ts != nil {
+			at = *env.Ts
+		}
+		switch env.Type {
+		case "state_init":
+			var snap StateSnapshot
+			if json.Unmarshal(env.Data, &snap) == nil && snap.VolumeKnown {
+				out <- tuneSample{At: at, DB: snap.VolumeDB}
+			}
+		case "volume_changed":
+			var d wsVolumeChangedData
+			if json.Unmarshal(env.Data, &d) == nil {
+				out <- tuneSample{At: at, DB: d.VolumeDB}
+			}
+		}
+	}
+}
+
+func drainTuneSamples(samples <-chan tuneSample) {
+	for {
+		select {
+		case <-samples:
+		default:
+			return
+		}
+	}
+}
+
+// recordMovement collects samples until none arrive for settle after the first one,
+// or maxWait passes.
+func recordMovement(samples <-chan tuneSample, maxWait, settle time.Duration) []tuneSample {
+	deadline := time.NewTimer(maxWait)
+	defer deadline.Stop()
+
+	var (
+		got   []tuneSample
+		quiet <-chan time.Time
+	)
+	for {
+		select {
+		case s, ok := <-samples:
+			if !ok {
+				return got
+			}
+			got = append(got, s)
+			quiet = time.After(settle)
+		case <-quiet:
+			return got
+		case <-deadline.C:
+			return got
+		}
+	}
+}
+
+// analyzeMovement computes the rates and coast distance of a recorded movement.
+func analyzeMovement(samples []tuneSample) tuneMovement {
+	var m tuneMovement
+	if len(samples) < 2 {
+		return m
+	}
+	first, last := samples[0], samples[len(samples)-1]
+	m.TotalDB = math.Abs(last.DB - first.DB)
+
+	// rates[i] is the rate over the latest span ending at samples[i] that is at
+	// least tuneRateWindow long (0 if there is none yet).
+	rates := make([]float64, len(samples))
+	j := 0
+	for i := 1; i < len(samples); i++ {
+		for j+1 < i && samples[i].At.Sub(samples[j+1].At) >= tuneRateWindow {
+			j++
+		}
+		dt := samples[i].At.Sub(samples[j].At)
+		if dt < tuneRateWindow {
+			continue
+		}
+		rates[i] = math.Abs(samples[i].DB-samples[j].DB) / dt.Seconds()
+		m.PeakDBPerSec = max(m.PeakDBPerSec, rates[i])
+	}
+	if m.PeakDBPerSec == 0 {
+		return m
+	}
+
+	lastFast := -1
+	for i, r := range rates {
+		if r < 0.9*m.PeakDBPerSec {
+			continue
+		}
+		if lastFast < 0 {
+			m.TimeToPeak = samples[i].At.Sub(first.At)
+		}
+		lastFast = i
+	}
+	m.CoastDB = math.Abs(last.DB - samples[lastFast].DB)
+	return m
+}
+
+// suggestTuning applies the measurements and answers to cur.
+func suggestTuning(cur ConfigUpdated, res tuneResults) ConfigUpdated {
+	next := cur.clone()
+	v := &next.Velocity
+	r := &next.Rotary
+
+	if v.Mode == "" || v.Mode == string(VelocityModeAccelerating) {
+		// A short press only gets part way up the acceleration ramp, so taps grow
+		// as accel_time_sec shrinks.
+		if res.TapFactor > 0 && v.AccelTimeSec > 0 {
+			v.AccelTimeSec = max(tuneRound(v.AccelTimeSec/res.TapFactor), 0.05)
+		}
+		// Release decays exponentially; the coast distance scales with decay_tau_sec.
+		if res.Hold.CoastDB > tuneMaxCoastDB && v.DecayTauSec > 0 {
+			v.DecayTauSec = max(tuneRound(v.DecayTauSec*tuneMaxCoastDB/res.Hold.CoastDB), 0.02)
+		}
+	}
+	v.MaxDBPerSec = tuneScale(v.MaxDBPerSec, res.HoldFactor)
+
+	if len(r.Curve) == 0 {
+		r.DbPerStep = tuneScale(r.DbPerStep, res.SlowSpinFactor)
+		r.VelocityMultiplier = tuneScale(r.VelocityMultiplier, res.FastSpinFactor)
+	} else {
+		for i := range r.Curve {
+			f := res.SlowSpinFactor
+			if r.Curve[i].Rate >= tuneFastSpinRate {
+				f = res.FastSpinFactor
+			}
+			r.Curve[i].DbPerStep = tuneScale(r.Curve[i].DbPerStep, f)
+		}
+	}
+	return next
+}
+
+func tuneScale(v, factor float64) float64 {
+	if factor <= 0 || factor == 1 {
+		return v
+	}
+	return tuneRound(v * factor)
+}
+
+func tuneRound(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// diffTuning lists the velocity/rotary keys that differ between cur and next, in
+// config key order per section.
+func diffTuning(cur, next ConfigUpdated) ([]tuneChange, error) {
+	var changes []tuneChange
+	for _, sec := range []struct {
+		name      string
+		cur, next any
+	}{
+		{"velocity", cur.Velocity, next.Velocity},
+		{"rotary", cur.Rotary, next.Rotary},
+	} {
+		a, err := tuneFields(sec.cur)
+		if err != nil {
+			return nil, err
+		}
+		b, err := tuneFields(sec.next)
+		if err != nil {
+			return nil, err
+		}
+		keys := make([]string, 0, len(b))
+		for k := range b {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if !reflect.DeepEqual(a[k], b[k]) {
+				changes = append(changes, tuneChange{Section: sec.name, Key: k, Old: a[k], New: b[k]})
+			}
+		}
+	}
+	return changes, nil
+}
+
+// tuneFields flattens a config section to its keys via its JSON tags (which match
+// the YAML keys).
+func tuneFields(v any) (map[string]any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// tunePatch builds the PUT /api/v1/tuning document setting just the changed keys
+// (the daemon refuses keys it does not allow at runtime).
+func tunePatch(changes []tuneChange) map[string]map[string]any {
+	patch := make(map[string]map[string]any)
+	for _, c := range changes {
+		if patch[c.Section] == nil {
+			patch[c.Section] = make(map[string]any)
+		}
+		patch[c.Section][c.Key] = c.New
+	}
+	return patch
+}
+
+func tuneFormat(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// writeTuningToConfig sets the changed keys in the config file, keeping the rest
+// of the document (including comments) as is.
+func writeTuningToConfig(path string, changes []tuneChange) error {
+	doc, err := readConfigNode(path)
+	if err != nil {
+		return err
+	}
+	root := doc.Content[0]
+
+	for _, c := range changes {
+		sec := mappingValue(root, c.Section)
+		if sec == nil || sec.Kind != yaml.MappingNode {
+			sec = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			setMappingValue(root, c.Section, sec)
+		}
+		var value yaml.Node
+		if err := value.Encode(c.New); err != nil {
+			return fmt.Errorf("encode %s.%s: %w", c.Section, c.Key, err)
+		}
+		setMappingValue(sec, c.Key, &value)
+	}
+	return writeConfigNode(path, doc, nil)
+}
+
+// readConfigNode parses the config file at path as a YAML tree whose top level is
+// a mapping (an empty file yields an empty mapping).
+func readConfigNode(path string) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("parse config: top level is not a mapping")
+	}
+	return &doc, nil
+}
+
+// writeConfigNode encodes doc and replaces the file at path with it (keeping its
+// mode) through a temporary file. If validate is set it is called with the
+// temporary file's path first, and an error leaves path untouched.
+func writeConfigNode(path string, doc *yaml.Node, validate func(tmp string) error) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("encode config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("encode config: %w", err)
+	}
+
+	mode := os.FileMode(0o644)
+	if st, err := os.Stat(path); err == nil {
+		mode = st.Mode().Perm()
+	}
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err := os.WriteFile(tmp, buf.Bytes(), mode); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+	if validate != nil {
+		if err := validate(tmp); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write config: %w", err)
+	}
+	return nil
+}
+
+// setMappingValue replaces the value for key in a mapping node, appending the key
+// if it is absent.
+func setMappingValue(m *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = value
+			return
+		}
+	}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+func tuneGetJSON(client *http.Client, url string, out any) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return tuneHTTPError(resp)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func tunePutJSON(client *http.Client, url string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), tuneHTTPTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return tuneHTTPError(resp)
+	}
+	return nil
+}
+
+// tuneSetVolume sets the volume through the daemon's JSON-RPC endpoint.
+func tuneSetVolume(client *http.Client, baseURL, zone string, db float64) error {
+	params := map[string]any{"db": db}
+	if zone != "" {
+		params["zone"] = zone
+	}
+	body, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "volume.set", "params": params})
+	resp, err := client.Post(baseURL+"/jsonrpc", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return tuneHTTPError(resp)
+	}
+	var rpc struct {
+		Error *jsonRPCError `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpc); err != nil {
+		return err
+	}
+	if rpc.Error != nil {
+		return errors.New(rpc.Error.Message)
+	}
+	return nil
+}
+
+func tuneHTTPError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnalyzeMovement_HoldWithCoast(t *testing.T) {
+	t0 := time.Unix(1000, 0)
+	var samples []tuneSample
+	db := -20.0
+	// Ramp up to 20 dB/s over 0.5s, hold it for 1s, then coast down to a stop.
+	for i, rate := range []float64{5, 10, 15, 20, 20, 20, 20, 20, 20, 20, 20, 20, 20, 20, 10, 5, 2, 1} {
+		samples = append(samples, tuneSample{At: t0.Add(time.Duration(i) * 100 * time.Millisecond), DB: db})
+		db -= rate * 0.1
+	}
+
+	m := analyzeMovement(samples)
+	if math.Abs(m.PeakDBPerSec-20) > 0.01 {
+		t.Fatalf("expected peak 20 dB/s, got %v", m.PeakDBPerSec)
+	}
+	if m.TimeToPeak < 300*time.Millisecond || m.TimeToPeak > 600*time.Millisecond {
+		t.Fatalf("unexpected time to peak %v", m.TimeToPeak)
+	}
+	if m.CoastDB < 1 || m.CoastDB > 3 {
+		t.Fatalf("unexpected coast %v dB", m.CoastDB)
+	}
+	if want := math.Abs(samples[len(samples)-1].DB - samples[0].DB); m.TotalDB != want {
+		t.Fatalf("expected total %v, got %v", want, m.TotalDB)
+	}
+}
+
+func TestSuggestTuning(t *testing.T) {
+	cfg := DefaultConfig()
+	cur := ConfigUpdated{Velocity: cfg.Velocity, Rotary: cfg.Rotary}
+	cur.Velocity.Mode = string(VelocityModeAccelerating)
+	cur.Velocity.AccelTimeSec = 1.2
+	cur.Velocity.DecayTauSec = 0.4
+	cur.Velocity.MaxDBPerSec = 20
+
+	next := suggestTuning(cur, tuneResults{
+		Hold:           tuneMovement{CoastDB: 4},
+		TapFactor:      1.5,
+		HoldFactor:     2.0 / 3,
+		SlowSpinFactor: 1,
+		FastSpinFactor: 1.5,
+	})
+	if next.Velocity.AccelTimeSec != 0.8 {
+		t.Fatalf("expected accel_time_sec 0.8, got %v", next.Velocity.AccelTimeSec)
+	}
+	if next.Velocity.DecayTauSec != 0.2 {
+		t.Fatalf("expected decay_tau_sec 0.2, got %v", next.Velocity.DecayTauSec)
+	}
+	if next.Velocity.MaxDBPerSec != 13.33 {
+		t.Fatalf("expected max_db_per_sec 13.33, got %v", next.Velocity.MaxDBPerSec)
+	}
+	for i, p := range next.Rotary.Curve {
+		want := cur.Rotary.Curve[i].DbPerStep
+		if p.Rate >= tuneFastSpinRate {
+			want = tuneRound(want * 1.5)
+		}
+		if p.DbPerStep != want {
+			t.Fatalf("curve[%d]: expected %v, got %v", i, want, p.DbPerStep)
+		}
+	}
+	if cur.Rotary.Curve[len(cur.Rotary.Curve)-1].DbPerStep == next.Rotary.Curve[len(next.Rotary.Curve)-1].DbPerStep {
+		t.Fatalf("suggestTuning modified the current curve in place")
+	}
+
+	changes, err := diffTuning(cur, next)
+	if err != nil {
+		t.Fatalf("diffTuning: %v", err)
+	}
+	var keys []string
+	for _, c := range changes {
+		keys = append(keys, c.Section+"."+c.Key)
+	}
+	if got := strings.Join(keys, ","); got != "velocity.accel_time_sec,velocity.decay_tau_sec,velocity.max_db_per_sec,rotary.curve" {
+		t.Fatalf("unexpected changes %s", got)
+	}
+
+	// The daemon accepts the patch and ends up with the suggestion.
+	body, _ := json.Marshal(tunePatch(changes))
+	applied, err := mergeTuning(cur, body)
+	if err != nil {
+		t.Fatalf("mergeTuning(patch): %v", err)
+	}
+	if applied.Velocity.AccelTimeSec != 0.8 || applied.Rotary.Curve[len(applied.Rotary.Curve)-1] != next.Rotary.Curve[len(next.Rotary.Curve)-1] {
+		t.Fatalf("patch applied as %#v", applied)
+	}
+}
+
+func TestWriteTuningToConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	orig := "# my setup\ncamilladsp:\n  ws_url: ws://dsp:1234 # the DSP box\nvelocity:\n  mode: accelerating\n  max_db_per_sec: 20\n"
+	if err := os.WriteFile(path, []byte(orig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := []tuneChange{
+		{Section: "velocity", Key: "max_db_per_sec", New: 13.5},
+		{Section: "rotary", Key: "curve", New: []any{map[string]any{"rate": 0.0, "db_per_step": 0.5}}},
+	}
+	if err := writeTuningToConfig(path, changes); err != nil {
+		t.Fatalf("writeTuningToConfig: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "# my setup") || !strings.Contains(string(data), "# the DSP box") {
+		t.Fatalf("comments were lost:\n%s", data)
+	}
+	if st, _ := os.Stat(path); st.Mode().Perm() != 0o600 {
+		t.Fatalf("expected mode 0600, got %v", st.Mode().Perm())
+	}
+
+	cfg, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("written config does not load: %v\n%s", err, data)
+	}
+	if cfg.Velocity.MaxDBPerSec != 13.5 || cfg.Velocity.Mode != "accelerating" {
+		t.Fatalf("unexpected velocity %#v", cfg.Velocity)
+	}
+	if len(cfg.Rotary.Curve) != 1 || cfg.Rotary.Curve[0].DbPerStep != 0.5 {
+		t.Fatalf("unexpected rotary curve %#v", cfg.Rotary.Curve)
+	}
+	if cfg.CamillaDSP.WsURL != "ws://dsp:1234" {
+		t.Fatalf("unrelated key changed: %q", cfg.CamillaDSP.WsURL)
+	}
+}
+
+func TestTuneBaseURL(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Webhooks.Port = 3001
+	if got := tuneBaseURL(cfg); got != "http://127.0.0.1:3001" {
+		t.Fatalf("expected loopback webhooks URL, got %q", got)
+	}
+	cfg.API = APIConfig{Port: 3002, BindAddress: "192.168.1.10"}
+	if got := tuneBaseURL(cfg); got != "http://192.168.1.10:3002" {
+		t.Fatalf("expected api URL, got %q", got)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// Runtime tuning (velocity / rotary)
+// ============================================================================
+// GET  /api/v1/tuning returns the active velocity and rotary settings
+//      ({"velocity": {...}, "rotary": {...}}, same keys as the config file).
+// PUT  /api/v1/tuning merges a partial document of the same shape over them,
+//      validates the result like the config file and applies it live: the zone
+//      router delivers a ConfigUpdated event to every zone's reducer, which uses
+//      the tuning in place of the startup config until the daemon restarts.
+//
+// Only the feel knobs are writable (tunableKeys): hold rate, acceleration,
+// decay, turbo and the rotary step settings. Safety limits (danger zone, hold
+// checkpoint) and behavior switches only change through the config file. Both
+// methods need the control API token (see api_auth.go).
+//
+// Changes are not written back to the config file; copy the final values from
+// GET once they feel right.
+// ============================================================================
+
+// ConfigUpdated replaces the tunable velocity and rotary settings at runtime.
+type ConfigUpdated struct {
+	Velocity VelocityFileConfig `json:"velocity"`
+	Rotary   RotaryConfig       `json:"rotary"`
+}
+
+func (ConfigUpdated) eventMarker() {}
+
+// clone returns a copy that shares no slices with c.
+func (c ConfigUpdated) clone() ConfigUpdated {
+	c.Rotary.Curve = slices.Clone(c.Rotary.Curve)
+	c.Rotary.BalanceFaders = slices.Clone(c.Rotary.BalanceFaders)
+	if c.Velocity.HoldCheckpointDB != nil {
+		cp := *c.Velocity.HoldCheckpointDB
+		c.Velocity.HoldCheckpointDB = &cp
+	}
+	return c
+}
+
+// BroadcastTuningChanged is emitted by the zone router when the tuning changes.
+type BroadcastTuningChanged struct {
+	Tuning ConfigUpdated `json:"tuning"`
+	At     time.Time     `json:"at"`
+}
+
+func (BroadcastTuningChanged) stateBroadcastMarker() {}
+
+// tunedConfig applies the runtime tuning (if any) over the startup configs.
+func tunedConfig(s *DaemonState, cfg VelocityConfig, rotaryCfg RotaryConfig) (VelocityConfig, RotaryConfig) {
+	if s.Tuning == nil {
+		return cfg, rotaryCfg
+	}
+	s.Tuning.Velocity.applyTo(&cfg)
+	return cfg, s.Tuning.Rotary
+}
+
+// tunableKeys lists the keys PUT /api/v1/tuning may set, per section.
+var tunableKeys = map[string][]string{
+	"velocity": {"max_db_per_sec", "accel_time_sec", "decay_tau_sec", "turbo_mult", "turbo_delay_sec"},
+	"rotary":   {"db_per_step", "velocity_window_ms", "velocity_multiplier", "velocity_threshold", "debounce_ms", "curve"},
+}
+
+// maxTuningBody bounds PUT /api/v1/tuning request bodies.
+const maxTuningBody = 64 << 10
+
+// tuningAPI serves /api/v1/tuning. It holds the active tuning so GET needs no round
+// trip through the reducers.
+type tuningAPI struct {
+	events chan<- Event
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	current ConfigUpdated
+}
+
+func newTuningAPI(cfg *Config, events chan<- Event, logger *slog.Logger) *tuningAPI {
+	return &tuningAPI{
+		events:  events,
+		logger:  logger,
+		current: ConfigUpdated{Velocity: cfg.Velocity, Rotary: cfg.Rotary}.clone(),
+	}
+}
+
+// Register registers the tuning handler on mux behind auth.
+func (t *tuningAPI) Register(mux *http.ServeMux, path string, auth apiAuth) {
+	if mux == nil {
+		return
+	}
+	mux.Handle(path, auth.require(http.HandlerFunc(t.handle)))
+}
+
+func (t *tuningAPI) handle(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		t.mu.Lock()
+		cur := t.current.clone()
+		t.mu.Unlock()
+		writeTuningResponse(w, http.StatusOK, cur)
+
+	case http.MethodPut:
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxTuningBody+1))
+		if err != nil {
+			writeEventWebhookResponse(w, http.StatusBadRequest, fmt.Sprintf("read body: %v", err))
+			return
+		}
+		if len(body) > maxTuningBody {
+			writeEventWebhookResponse(w, http.StatusRequestEntityTooLarge, "body too large")
+			return
+		}
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		next, err := mergeTuning(t.current, body)
+		if err != nil {
+			writeEventWebhookResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		select {
+		case t.events <- next.clone():
+		default:
+			writeEventWebhookResponse(w, http.StatusServiceUnavailable, "event queue full")
+			return
+		}
+		t.current = next
+		t.logger.Info("tuning updated", "remote_addr", r.RemoteAddr)
+		writeTuningResponse(w, http.StatusOK, next.clone())
+
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeEventWebhookResponse(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// mergeTuning decodes a partial tuning document over cur and validates the result.
+func mergeTuning(cur ConfigUpdated, body []byte) (ConfigUpdated, error) {
+	if err := checkTunableKeys(body); err != nil {
+		return ConfigUpdated{}, err
+	}
+	next := cur.clone()
+	// Unknown fields are rejected: a typo would otherwise be silently ignored.
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&next); err != nil {
+		return ConfigUpdated{}, fmt.Errorf("parse tuning: %w", err)
+	}
+	if err := next.Velocity.validate(); err != nil {
+		return ConfigUpdated{}, err
+	}
+	if err := next.Rotary.validate(); err != nil {
+		return ConfigUpdated{}, err
+	}
+	return next, nil
+}
+
+// checkTunableKeys rejects a tuning document that sets anything outside tunableKeys.
+func checkTunableKeys(body []byte) error {
+	var doc map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return fmt.Errorf("parse tuning: %w", err)
+	}
+	for section, fields := range doc {
+		allowed, ok := tunableKeys[section]
+		if !ok {
+			return fmt.Errorf("parse tuning: unknown section %q", section)
+		}
+		for key := range fields {
+			if !slices.Contains(allowed, key) {
+				return fmt.Errorf("%s.%s cannot be changed at runtime; set it in the config file", section, key)
+			}
+		}
+	}
+	return nil
+}
+
+func writeTuningResponse(w http.ResponseWriter, status int, tuning ConfigUpdated) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(tuning)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMergeTuning(t *testing.T) {
+	cfg := DefaultConfig()
+	cur := ConfigUpdated{Velocity: cfg.Velocity, Rotary: cfg.Rotary}
+
+	next, err := mergeTuning(cur, []byte(`{"rotary":{"db_per_step":2.5},"velocity":{"max_db_per_sec":20}}`))
+	if err != nil {
+		t.Fatalf("mergeTuning: %v", err)
+	}
+	if next.Rotary.DbPerStep != 2.5 || next.Velocity.MaxDBPerSec != 20 {
+		t.Fatalf("partial update not applied: %#v", next)
+	}
+	if next.Velocity.Mode != cur.Velocity.Mode || len(next.Rotary.Curve) != len(cur.Rotary.Curve) {
+		t.Fatalf("unrelated fields changed: %#v", next)
+	}
+
+	for name, body := range map[string]string{
+		"unknown field": `{"rotary":{"db_per_stp":2}}`,
+		"invalid value": `{"velocity":{"max_db_per_sec":-1}}`,
+		"malformed":     `{"rotary":`,
+		"danger zone":   `{"velocity":{"danger_zone_db":0}}`,
+		"checkpoint":    `{"velocity":{"hold_checkpoint_db":0}}`,
+		"mode":          `{"velocity":{"mode":"constant"}}`,
+		"button action": `{"rotary":{"button_action":"none"}}`,
+		"section":       `{"outputs":{}}`,
+	} {
+		if _, err := mergeTuning(cur, []byte(body)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestTuningAPI_GetAndPut(t *testing.T) {
+	cfg := DefaultConfig()
+	events := make(chan Event, 1)
+	api := newTuningAPI(&cfg, events, slog.New(slog.DiscardHandler))
+
+	rec := httptest.NewRecorder()
+	api.handle(rec, httptest.NewRequest(http.MethodPut, "/api/v1/tuning", strings.NewReader(`{"rotary":{"db_per_step":3}}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	select {
+	case ev := <-events:
+		if cu, ok := ev.(ConfigUpdated); !ok || cu.Rotary.DbPerStep != 3 {
+			t.Fatalf("unexpected event %#v", ev)
+		}
+	default:
+		t.Fatalf("expected a ConfigUpdated event")
+	}
+
+	rec = httptest.NewRecorder()
+	api.handle(rec, httptest.NewRequest(http.MethodGet, "/api/v1/tuning", nil))
+	var got ConfigUpdated
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("decode GET: %v", err)
+	}
+	if got.Rotary.DbPerStep != 3 {
+		t.Fatalf("GET does not reflect the update: %#v", got.Rotary)
+	}
+
+	// A rejected update is not sent and leaves the tuning unchanged.
+	rec = httptest.NewRecorder()
+	api.handle(rec, httptest.NewRequest(http.MethodPut, "/api/v1/tuning", strings.NewReader(`{"rotary":{"db_per_step":-1}}`)))
+	if rec.Code != http.StatusBadRequest || len(events) != 0 {
+		t.Fatalf("expected 400 and no event, got %d (%d queued)", rec.Code, len(events))
+	}
+
+	rec = httptest.NewRecorder()
+	api.handle(rec, httptest.NewRequest(http.MethodDelete, "/api/v1/tuning", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("DELETE: expected 405, got %d", rec.Code)
+	}
+}
+
+func TestReduce_ConfigUpdatedOverridesRotaryStep(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0}
+	rotaryCfg := RotaryConfig{DbPerStep: 1, VelocityThreshold: 100}
+	t0 := time.Unix(1000, 0).UTC()
+
+	s := &DaemonState{}
+	s.SetObservedVolume(-30, t0)
+
+	tuning := ConfigUpdated{Rotary: rotaryCfg}
+	tuning.Rotary.DbPerStep = 3
+	rr := Reduce(s, tuning, cfg, rotaryCfg, ReducerPolicy{})
+	rr = Reduce(rr.State, TimedEvent{Event: RotaryTurn{Steps: 1}, At: t0}, cfg, rotaryCfg, ReducerPolicy{})
+	if v, ok := rr.State.GetDesiredVolume(); !ok || v != -27 {
+		t.Fatalf("expected -27 with tuned db_per_step, got %v (ok=%v)", v, ok)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import "time"
+
+// ============================================================================
+// UI hints
+// ============================================================================
+// Displays (the web UI, OLED/LED modules, Home Assistant cards) all want the
+// same feedback: show a volume overlay for a moment when the volume changes,
+// flash the mute icon when mute toggles. Rather than each client choosing its
+// own timing, the reducer emits ui_hint broadcasts saying what to show and for
+// how long:
+//
+//	{"hint": "volume_overlay", "duration_ms": 2000, "volume_db": -32.5}
+//	{"hint": "mute_flash", "duration_ms": 1000, "muted": true}
+//
+// A volume overlay is hinted for attributed volume changes (not for values
+// merely learned at startup or on resync) and, while it is showing, renewed
+// once half of it has elapsed, so a hold yields about one hint per second
+// rather than one per step. ui_hints.volume_overlay_ms / mute_flash_ms set the
+// durations (0 disables that hint).
+// ============================================================================
+
+// UI hint kinds.
+const (
+	uiHintVolumeOverlay = "volume_overlay"
+	uiHintMuteFlash     = "mute_flash"
+)
+
+// BroadcastUIHint asks displays to show something for Duration.
+type BroadcastUIHint struct {
+	Hint     string
+	Duration time.Duration
+	VolumeDB *float64 // volume_overlay
+	Muted    *bool    // mute_flash
+	At       time.Time
+}
+
+func (BroadcastUIHint) stateBroadcastMarker() {}
+
+// UIHintState is the reducer-owned state of hints in progress.
+type UIHintState struct {
+	// OverlayUntil is when the last volume overlay hint runs out.
+	OverlayUntil time.Time
+}
+
+// volumeOverlayHint returns the overlay hint owed for an attributed volume change.
+func volumeOverlayHint(s *DaemonState, b BroadcastVolumeChanged, policy ReducerPolicy) []StateBroadcast {
+	d := policy.VolumeOverlay
+	if d <= 0 || b.Origin == "" {
+		return nil
+	}
+	if b.At.Before(s.UIHints.OverlayUntil.Add(-d / 2)) {
+		return nil // still showing; the last hint covers this change
+	}
+	s.UIHints.OverlayUntil = b.At.Add(d)
+	v := b.VolumeDB
+	return []StateBroadcast{BroadcastUIHint{Hint: uiHintVolumeOverlay, Duration: d, VolumeDB: &v, At: b.At}}
+}
+
+// muteFlashHint returns the flash hint for an observed mute toggle.
+func muteFlashHint(muted bool, at time.Time, policy ReducerPolicy) []StateBroadcast {
+	if policy.MuteFlash <= 0 {
+		return nil
+	}
+	return []StateBroadcast{BroadcastUIHint{Hint: uiHintMuteFlash, Duration: policy.MuteFlash, Muted: &muted, At: at}}
+}
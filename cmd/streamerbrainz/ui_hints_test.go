@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// uiHints returns the ui_hint broadcasts of a reduce.
+func uiHints(rr ReduceResult) []BroadcastUIHint {
+	var out []BroadcastUIHint
+	for _, b := range rr.Broadcasts {
+		if h, ok := b.(BroadcastUIHint); ok {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+func TestReduce_UIHints_VolumeOverlay(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0}
+	policy := ReducerPolicy{VolumeOverlay: 2 * time.Second}
+	rotaryCfg := RotaryConfig{DbPerStep: 1}
+	t0 := time.Unix(1000, 0).UTC()
+	observe := func(s *DaemonState, db float64, at time.Time) ReduceResult {
+		return Reduce(s, CamillaVolumeObserved{VolumeDB: db, At: at}, cfg, rotaryCfg, policy)
+	}
+
+	// Learning the volume at startup is no reason to show it.
+	rr := observe(&DaemonState{}, -30, t0)
+	if h := uiHints(rr); len(h) != 0 {
+		t.Fatalf("first observation: got %+v", h)
+	}
+
+	rr = Reduce(rr.State, TimedEvent{Event: VolumeStep{Steps: 1}, At: t0.Add(time.Second)}, cfg, rotaryCfg, policy)
+	rr = observe(rr.State, -29, t0.Add(time.Second))
+	h := uiHints(rr)
+	if len(h) != 1 || h[0].Hint != uiHintVolumeOverlay || h[0].Duration != 2*time.Second || *h[0].VolumeDB != -29 {
+		t.Fatalf("volume change: got %+v", h)
+	}
+
+	// Changes while the overlay shows renew it only after half its duration.
+	rr = observe(rr.State, -28, t0.Add(1500*time.Millisecond))
+	if h := uiHints(rr); len(h) != 0 {
+		t.Fatalf("change while showing: got %+v", h)
+	}
+	rr = observe(rr.State, -27, t0.Add(2*time.Second))
+	if h := uiHints(rr); len(h) != 1 || *h[0].VolumeDB != -27 {
+		t.Fatalf("renewal: got %+v", h)
+	}
+
+	// Disabled.
+	policy.VolumeOverlay = 0
+	rr = observe(rr.State, -20, t0.Add(10*time.Second))
+	if h := uiHints(rr); len(h) != 0 {
+		t.Fatalf("disabled: got %+v", h)
+	}
+}
+
+func TestReduce_UIHints_MuteFlash(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0}
+	policy := ReducerPolicy{MuteFlash: time.Second}
+	rotaryCfg := RotaryConfig{}
+	t0 := time.Unix(1000, 0).UTC()
+
+	rr := Reduce(&DaemonState{}, CamillaMuteObserved{Muted: false, At: t0}, cfg, rotaryCfg, policy)
+	if h := uiHints(rr); len(h) != 0 {
+		t.Fatalf("first observation: got %+v", h)
+	}
+	rr = Reduce(rr.State, CamillaMuteObserved{Muted: true, At: t0.Add(time.Second)}, cfg, rotaryCfg, policy)
+	h := uiHints(rr)
+	if len(h) != 1 || h[0].Hint != uiHintMuteFlash || h[0].Duration != time.Second || !*h[0].Muted {
+		t.Fatalf("mute: got %+v", h)
+	}
+
+	// A resync re-broadcasts the mute state but doesn't flash.
+	rr = Reduce(rr.State, ResyncState{}, cfg, rotaryCfg, policy)
+	rr = Reduce(rr.State, CamillaMuteObserved{Muted: true, At: t0.Add(2 * time.Second)}, cfg, rotaryCfg, policy)
+	if h := uiHints(rr); len(h) != 0 {
+		t.Fatalf("resync: got %+v", h)
+	}
+}
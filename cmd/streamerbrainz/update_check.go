@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// Release check (update_check)
+// ============================================================================
+// Opt-in: when update_check.enabled is set, the latest GitHub release of
+// update_check.repository is fetched shortly after startup and then every
+// interval_hours. A release newer than the running version is logged and sent as
+// an UpdateAvailable event, which the zone router publishes as
+// "update_available" (once per release), so headless installs notice updates
+// through the UI or outbound webhooks. Nothing is downloaded or installed. The
+// last result is included in GET /api/v1/version.
+// ============================================================================
+
+// githubAPIURL is a variable so tests can point the check at a fake server.
+var githubAPIURL = "https://api.github.com"
+
+const (
+	updateCheckInitialDelay = time.Minute
+	updateCheckHTTPTimeout  = 15 * time.Second
+)
+
+// UpdateAvailable reports a release newer than the running build.
+type UpdateAvailable struct {
+	Current string `json:"current"`
+	Latest  string `json:"latest"`
+	URL     string `json:"url"`
+}
+
+func (UpdateAvailable) eventMarker() {}
+
+// BroadcastUpdateAvailable is emitted by the zone router for UpdateAvailable.
+type BroadcastUpdateAvailable struct {
+	Current string    `json:"current"`
+	Latest  string    `json:"latest"`
+	URL     string    `json:"url"`
+	At      time.Time `json:"at"`
+}
+
+func (BroadcastUpdateAvailable) stateBroadcastMarker() {}
+
+// updateStatus is the last check result, as reported by GET /api/v1/version.
+type updateStatus struct {
+	Latest          string    `json:"latest,omitempty"`
+	URL             string    `json:"url,omitempty"`
+	UpdateAvailable bool      `json:"update_available"`
+	CheckedAt       time.Time `json:"checked_at,omitzero"`
+	Error           string    `json:"error,omitempty"`
+}
+
+// githubRelease is the subset of the GitHub releases API response we use.
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+	HTMLURL string `json:"html_url"`
+}
+
+type updateChecker struct {
+	cfg    UpdateCheckConfig
+	client *http.Client
+	events chan<- Event
+	logger *slog.Logger
+
+	mu        sync.Mutex
+	last      updateStatus
+	announced string // latest release already sent as UpdateAvailable
+}
+
+func newUpdateChecker(cfg UpdateCheckConfig, events chan<- Event, logger *slog.Logger) *updateChecker {
+	return &updateChecker{
+		cfg:    cfg,
+		client: &http.Client{Timeout: updateCheckHTTPTimeout},
+		events: events,
+		logger: logger,
+	}
+}
+
+// Run checks after updateCheckInitialDelay and then every interval until ctx is done.
+func (u *updateChecker) Run(ctx context.Context) {
+	timer := time.NewTimer(updateCheckInitialDelay)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		if err := u.check(ctx); err != nil && ctx.Err() == nil {
+			u.logger.Warn("update check failed", "error", err)
+		}
+		timer.Reset(time.Duration(u.cfg.IntervalHours) * time.Hour)
+	}
+}
+
+func (u *updateChecker) status() updateStatus {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.last
+}
+
+// check fetches the latest release and announces it if it is newer than version.
+func (u *updateChecker) check(ctx context.Context) error {
+	rel, err := u.fetchLatest(ctx)
+
+	u.mu.Lock()
+	u.last = updateStatus{CheckedAt: time.Now().UTC()}
+	if err != nil {
+		u.last.Error = err.Error()
+		u.mu.Unlock()
+		return err
+	}
+	u.last.Latest = strings.TrimPrefix(rel.TagName, "v")
+	u.last.URL = rel.HTMLURL
+	newer, ok := versionNewer(rel.TagName, version)
+	u.last.UpdateAvailable = ok && newer
+	announce := u.last.UpdateAvailable && u.announced != u.last.Latest
+	if announce {
+		u.announced = u.last.Latest
+	}
+	st := u.last
+	u.mu.Unlock()
+
+	if !ok {
+		u.logger.Debug("update check: unrecognized version", "tag", rel.TagName, "current", version)
+	}
+	if !announce {
+		return nil
+	}
+	u.logger.Info("newer release available", "current", version, "latest", st.Latest, "url", st.URL)
+	select {
+	case u.events <- UpdateAvailable{Current: version, Latest: st.Latest, URL: st.URL}:
+	default:
+		u.logger.Warn("event queue full, dropping update notification")
+	}
+	return nil
+}
+
+func (u *updateChecker) fetchLatest(ctx context.Context) (githubRelease, error) {
+	var rel githubRelease
+	url := fmt.Sprintf("%s/repos/%s/releases/latest", githubAPIURL, u.cfg.Repository)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return rel, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "streamerbrainz/"+version)
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return rel, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return rel, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
+		return rel, fmt.Errorf("decode release: %w", err)
+	}
+	if rel.TagName == "" {
+		return rel, errors.New("release has no tag")
+	}
+	return rel, nil
+}
+
+// versionNewer reports whether release is a newer version than current. Both are
+// dotted numbers with an optional "v" prefix; a pre-release suffix ("-rc1") is
+// ignored. ok is false if either does not parse.
+func versionNewer(release, current string) (newer, ok bool) {
+	a, okA := parseVersion(release)
+	b, okB := parseVersion(current)
+	if !okA || !okB {
+		return false, false
+	}
+	for i := range max(len(a), len(b)) {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			return x > y, true
+		}
+	}
+	return false, true
+}
+
+func parseVersion(v string) ([]int, bool) {
+	v = strings.TrimPrefix(v, "v")
+	v, _, _ = strings.Cut(v, "-")
+	v, _, _ = strings.Cut(v, "+")
+	if v == "" {
+		return nil, false
+	}
+	var out []int
+	for _, p := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		out = append(out, n)
+	}
+	return out, true
+}
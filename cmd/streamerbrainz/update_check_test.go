@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVersionNewer(t *testing.T) {
+	for _, tc := range []struct {
+		release, current string
+		newer, ok        bool
+	}{
+		{"v1.1.0", "1.0.0", true, true},
+		{"1.0.0", "1.0.0", false, true},
+		{"v1.0", "1.0.0", false, true},
+		{"v1.0.1", "1.0", true, true},
+		{"v0.9.9", "1.0.0", false, true},
+		{"v1.10.0", "1.9.3", true, true},
+		{"v1.1.0-rc1", "1.0.0", true, true},
+		{"nightly", "1.0.0", false, false},
+		{"v1.1.0", "dev", false, false},
+	} {
+		newer, ok := versionNewer(tc.release, tc.current)
+		if newer != tc.newer || ok != tc.ok {
+			t.Errorf("versionNewer(%q, %q) = %v, %v; want %v, %v", tc.release, tc.current, newer, ok, tc.newer, tc.ok)
+		}
+	}
+}
+
+func TestUpdateChecker_AnnouncesNewerReleaseOnce(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/releases/latest" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(githubRelease{TagName: "v99.0.0", HTMLURL: "https://example.com/release"})
+	}))
+	defer srv.Close()
+	defer func(orig string) { githubAPIURL = orig }(githubAPIURL)
+	githubAPIURL = srv.URL
+
+	events := make(chan Event, 4)
+	u := newUpdateChecker(UpdateCheckConfig{Enabled: true, IntervalHours: 24, Repository: "owner/repo"}, events, slog.New(slog.DiscardHandler))
+
+	for range 2 {
+		if err := u.check(context.Background()); err != nil {
+			t.Fatalf("check: %v", err)
+		}
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected one UpdateAvailable, got %d", len(events))
+	}
+	if ev := (<-events).(UpdateAvailable); ev.Latest != "99.0.0" || ev.Current != version || ev.URL != "https://example.com/release" {
+		t.Fatalf("unexpected event %#v", ev)
+	}
+
+	rec := httptest.NewRecorder()
+	(&versionHandler{updates: u}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/version", nil))
+	var resp versionResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode version response: %v", err)
+	}
+	if resp.Version != version || resp.Commit == "" || resp.GoVersion == "" {
+		t.Fatalf("unexpected build info %#v", resp.VersionInfo)
+	}
+	if resp.UpdateCheck == nil || !resp.UpdateCheck.UpdateAvailable || resp.UpdateCheck.Latest != "99.0.0" || resp.UpdateCheck.CheckedAt.IsZero() {
+		t.Fatalf("unexpected update status %#v", resp.UpdateCheck)
+	}
+}
+
+func TestUpdateChecker_RecordsErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rate limited", http.StatusForbidden)
+	}))
+	defer srv.Close()
+	defer func(orig string) { githubAPIURL = orig }(githubAPIURL)
+	githubAPIURL = srv.URL
+
+	events := make(chan Event, 1)
+	u := newUpdateChecker(UpdateCheckConfig{Enabled: true, IntervalHours: 24, Repository: "owner/repo"}, events, slog.New(slog.DiscardHandler))
+	if err := u.check(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+	if st := u.status(); st.Error == "" || st.UpdateAvailable || len(events) != 0 {
+		t.Fatalf("unexpected status %#v (%d events)", st, len(events))
+	}
+}
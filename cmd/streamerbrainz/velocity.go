@@ -43,10 +43,32 @@ type VelocityConfig struct {
 	// MaxDt clamps very large dt steps (seconds). 0 disables clamping.
 	MaxDt float64
 
+	// RampDBPerS is the rate used to fade absolute volume sets (SetVolumeAbsolute).
+	// 0 applies absolute sets immediately.
+	RampDBPerS float64
+
+	// ExternalChangeDB (0 = off) cancels a hold or ramp when a read-back volume is this
+	// far from what was set (see external_change.go).
+	ExternalChangeDB float64
+
 	// Danger zone (near max volume), ramp-up only
 	DangerZoneDB            float64 // Size of danger zone below MaxDB (dB)
 	DangerVelMaxDBPerS      float64 // Hard cap for ramp-up velocity inside danger zone (dB/s)
 	DangerVelMinNear0DBPerS float64 // Minimum ramp-up velocity near MaxDB (dB/s)
+	DangerRotaryDBPerStep   float64 // Max dB per upward rotary detent inside the zone (0 = unlimited)
+	DangerRampAbsolute      bool    // Absolute sets into the zone fade at the danger velocity
+
+	// HoldCheckpointDB (nil = off) stops upward holds that start below it (see hold_checkpoint.go).
+	HoldCheckpointDB *float64
+}
+
+// holdTimeout is how long the current hold survives without a repeat: the hold's
+// own timeout if longer than cfg.HoldTimeout (0 disables the timeout altogether).
+func (ctrl VolumeControllerState) holdTimeout(cfg VelocityConfig) time.Duration {
+	if cfg.HoldTimeout == 0 {
+		return 0
+	}
+	return max(cfg.HoldTimeout, ctrl.HoldTimeout)
 }
 
 // StepVolumeController advances the reducer-owned volume controller state by one tick.
@@ -80,8 +102,8 @@ func StepVolumeController(ctrl VolumeControllerState, baselineTarget float64, dt
 
 	// Hold-timeout behavior: if we haven't observed a hold event recently, treat as released.
 	// This is a robustness fallback for inputs that emit repeats but may miss releases.
-	if ctrl.HeldDirection != 0 && cfg.HoldTimeout > 0 && !ctrl.LastHeldAt.IsZero() {
-		if now.Sub(ctrl.LastHeldAt) > cfg.HoldTimeout {
+	if timeout := ctrl.holdTimeout(cfg); ctrl.HeldDirection != 0 && timeout > 0 && !ctrl.LastHeldAt.IsZero() {
+		if now.Sub(ctrl.LastHeldAt) > timeout {
 			ctrl.HeldDirection = 0
 			ctrl.HoldBeganAt = time.Time{}
 		}
@@ -92,35 +114,29 @@ func StepVolumeController(ctrl VolumeControllerState, baselineTarget float64, dt
 
 	// Compute per-tick velMax with danger-zone behavior for ramp-up (UP only).
 	velMax := cfg.VelMaxDBPerS
-	if ctrl.HeldDirection == 1 && cfg.DangerZoneDB > 0 {
-		estVol := ctrl.TargetDB
-		dangerThreshold := cfg.MaxDB - cfg.DangerZoneDB
-		if estVol > dangerThreshold {
-			den := cfg.MaxDB - dangerThreshold
-			x := 1.0
-			if den > 0 {
-				x = (estVol - dangerThreshold) / den
-			}
-			if x < 0 {
-				x = 0
-			}
-			if x > 1 {
-				x = 1
-			}
-			extra := 1.0 - (x * x * x)
-			if extra < 0 {
-				extra = 0
-			}
-			if extra > 1 {
-				extra = 1
-			}
-			// velMax transitions from DangerVelMaxDBPerS at zone entry down to DangerVelMinNear0DBPerS near MaxDB.
-			velMax = cfg.DangerVelMinNear0DBPerS + (cfg.DangerVelMaxDBPerS-cfg.DangerVelMinNear0DBPerS)*extra
+	if ctrl.HeldDirection == 1 {
+		if v, ok := dangerVelMax(ctrl.TargetDB, cfg); ok {
+			velMax = v
 		}
 	}
 
-	switch cfg.Mode {
-	case VelocityModeConstant:
+	switch {
+	case ctrl.Ramping && ctrl.HeldDirection == 0:
+		// Absolute-set ramp: move toward RampTarget at a constant rate, then stop.
+		// (slowed through the danger zone, see danger_zone.go).
+		ctrl.VelocityDBPerS = 0
+		target := ctrl.RampTarget.DB()
+		rampCfg := cfg
+		if ctrl.RampRateDBPerS > 0 {
+			rampCfg.RampDBPerS = ctrl.RampRateDBPerS
+		}
+		ctrl.TargetDB = rampStep(ctrl.TargetDB, target, dt, rampCfg)
+		if ctrl.TargetDB == target {
+			ctrl.Ramping = false
+			ctrl.RampRateDBPerS = 0
+		}
+
+	case cfg.Mode == VelocityModeConstant:
 		// Constant-rate hold with optional turbo.
 		rate := 0.0
 		if ctrl.HeldDirection == 1 {
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+)
+
+// Build metadata, set at build time (see Makefile / Dockerfile.builder):
+//
+//	-ldflags "-X main.version=1.2.0 -X main.commit=abc1234 -X main.buildDate=2026-01-01T00:00:00Z"
+//
+// An unset commit or buildDate falls back to the VCS stamp `go build` embeds
+// when building from a git checkout.
+var (
+	version   = "1.0.0"
+	commit    = ""
+	buildDate = ""
+)
+
+// VersionInfo describes the running build.
+type VersionInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+}
+
+func buildVersionInfo() VersionInfo {
+	info := VersionInfo{Version: version, Commit: commit, BuildDate: buildDate, GoVersion: runtime.Version()}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = s.Value
+				if len(info.Commit) > 12 {
+					info.Commit = info.Commit[:12]
+				}
+			case s.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = s.Value
+			}
+		}
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildDate == "" {
+		info.BuildDate = "unknown"
+	}
+	return info
+}
+
+// versionHandler serves GET /api/v1/version: the build metadata plus, when
+// update_check is enabled, the result of the latest release check.
+type versionHandler struct {
+	updates *updateChecker // nil when update_check is disabled
+}
+
+type versionResponse struct {
+	VersionInfo
+	UpdateCheck *updateStatus `json:"update_check,omitempty"`
+}
+
+func (h *versionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeEventWebhookResponse(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	resp := versionResponse{VersionInfo: buildVersionInfo()}
+	if h.updates != nil {
+		st := h.updates.status()
+		resp.UpdateCheck = &st
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
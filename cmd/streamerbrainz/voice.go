@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// Voice assistant targets
+// ============================================================================
+// The voice control protocols (alexa, hue) expose the daemon as one or more
+// devices a voice assistant can address by name: the default target (the
+// selected zone, named by options.name) plus one per entry in options.zones
+// (zone id -> spoken name). Assistants speak percent and on/off, which map onto
+// set_volume_percent and set_mute.
+// ============================================================================
+
+const (
+	defaultVoiceName = "Hi-Fi"
+
+	// voiceSnapshotTimeout bounds the wait for the daemon's state when answering.
+	voiceSnapshotTimeout = 2 * time.Second
+)
+
+// voiceOptions are the options shared by the voice control protocols.
+type voiceOptions struct {
+	Name  string            `yaml:"name"`  // spoken name of the default target (default "Hi-Fi")
+	Zones map[string]string `yaml:"zones"` // zone id -> spoken name
+}
+
+// voiceTarget is one addressable device.
+type voiceTarget struct {
+	ID   string // stable id: "streamerbrainz" or "streamerbrainz-<zone>"
+	Zone string // empty = the selected zone
+	Name string
+}
+
+// validate checks the names, reporting errors against protocol typ.
+func (o voiceOptions) validate(typ string) error {
+	seen := map[string]bool{strings.ToLower(o.name()): true}
+	for zone, name := range o.Zones {
+		if zone == "" || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("%s options.zones: zone ids and names must be non-empty", typ)
+		}
+		if seen[strings.ToLower(name)] {
+			return fmt.Errorf("%s options.zones: name %q is used twice", typ, name)
+		}
+		seen[strings.ToLower(name)] = true
+	}
+	return nil
+}
+
+func (o voiceOptions) name() string {
+	if strings.TrimSpace(o.Name) == "" {
+		return defaultVoiceName
+	}
+	return o.Name
+}
+
+// targets lists the default target followed by the zones, sorted by zone id.
+func (o voiceOptions) targets() []voiceTarget {
+	out := []voiceTarget{{ID: "streamerbrainz", Name: o.name()}}
+	zones := make([]string, 0, len(o.Zones))
+	for z := range o.Zones {
+		zones = append(zones, z)
+	}
+	sort.Strings(zones)
+	for _, z := range zones {
+		out = append(out, voiceTarget{ID: "streamerbrainz-" + z, Zone: z, Name: o.Zones[z]})
+	}
+	return out
+}
+
+// event addresses ev to the target's zone.
+func (t voiceTarget) event(ev Event) Event {
+	if t.Zone == "" {
+		return ev
+	}
+	return ZonedEvent{Zone: t.Zone, Event: ev}
+}
+
+// voiceState is what an assistant is told about a target.
+type voiceState struct {
+	Percent int
+	Muted   bool
+}
+
+// voiceTargetState reads the target's state from a daemon snapshot.
+func voiceTargetState(snap StateSnapshot, t voiceTarget) (voiceState, error) {
+	snap, ok := zoneSnapshot(snap, t.Zone)
+	if !ok {
+		return voiceState{}, fmt.Errorf("zone %q not found", t.Zone)
+	}
+	if !snap.VolumeKnown {
+		return voiceState{}, errors.New("volume not known yet")
+	}
+	return voiceState{Percent: int(math.Round(snap.VolumePercent)), Muted: snap.Muted}, nil
+}
+
+// currentVoiceState asks the daemon for the target's state.
+func currentVoiceState(ctx context.Context, events chan<- Event, t voiceTarget) (voiceState, error) {
+	snap, err := requestStateSnapshot(ctx, events, voiceSnapshotTimeout)
+	if err != nil {
+		return voiceState{}, fmt.Errorf("state snapshot: %w", err)
+	}
+	return voiceTargetState(snap, t)
+}
+
+// sendVoiceEvent queues ev for the daemon without blocking.
+func sendVoiceEvent(events chan<- Event, ev Event) error {
+	select {
+	case events <- ev:
+		return nil
+	default:
+		return errors.New("event queue full")
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import "math"
+
+// ============================================================================
+// Fixed-point volume
+// ============================================================================
+// Volume intents, observations and ramp targets are kept as integer millibels
+// (1/100 dB), converting from/to dB only at the edges: config, CamillaDSP
+// commands/observations and broadcasts. Equal levels then compare equal no
+// matter how they were reached, and rounding to step_db/display_step_db is
+// exact, so the controller target, intent and observed value can't drift
+// apart through repeated float integration and rounding.
+//
+// The hold/velocity controller itself still integrates in float dB (its
+// per-tick movement can be well below 1 mB); it is re-based on the millibel
+// intent or observation every tick.
+// ============================================================================
+
+// Millibel is a volume level or difference in hundredths of a dB.
+type Millibel int32
+
+// mbFromDB converts dB to the nearest millibel.
+func mbFromDB(db float64) Millibel {
+	return Millibel(math.Round(db * 100))
+}
+
+// DB converts m to dB.
+func (m Millibel) DB() float64 {
+	return float64(m) / 100
+}
+
+// roundTo rounds m to the nearest multiple of step, halves away from zero
+// (step <= 1 returns m unchanged).
+func (m Millibel) roundTo(step Millibel) Millibel {
+	if step <= 1 {
+		return m
+	}
+	if m < 0 {
+		return -(-m).roundTo(step)
+	}
+	return (m + step/2) / step * step
+}
+
+// floorTo returns the largest multiple of step <= m.
+func (m Millibel) floorTo(step Millibel) Millibel {
+	q := m / step * step
+	if q > m {
+		q -= step
+	}
+	return q
+}
+
+// ceilTo returns the smallest multiple of step >= m.
+func (m Millibel) ceilTo(step Millibel) Millibel {
+	q := m / step * step
+	if q < m {
+		q += step
+	}
+	return q
+}
+
+// absMB returns |m|.
+func absMB(m Millibel) Millibel {
+	if m < 0 {
+		return -m
+	}
+	return m
+}
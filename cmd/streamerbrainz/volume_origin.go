@@ -0,0 +1,112 @@
+package main
+
+import (
+	"cmp"
+	"time"
+)
+
+// ============================================================================
+// Volume change origin
+// ============================================================================
+// Volume intents (VolumeHeld, VolumeStep, RotaryTurn, RotaryTurnHiRes,
+// SetVolumeAbsolute) carry an optional Origin naming who or what asked for the
+// change ("ir", "rotary", "ipc:argon-ctl", "webui", "osc", ...). The reducer
+// remembers the origin of the latest intent and attributes observed volume
+// changes to it in BroadcastVolumeChanged; the daemon loop logs every
+// attributed change at Info as the volume audit trail.
+//
+// Observed changes with no intent in the preceding volumeOriginWindow were made
+// behind the daemon's back (another CamillaDSP client) and are attributed to
+// "external".
+//
+// Ingress labels what it can't know from the payload: IPC events become
+// "ipc:<origin>" (or "ipc"), event webhook posts default to "webhook". Local
+// devices leave Origin empty and get the defaults from volumeEventOrigin.
+// ============================================================================
+
+// volumeOriginWindow is how long after the latest volume intent (or controller
+// movement) an observed change is still attributed to it.
+const volumeOriginWindow = 2 * time.Second
+
+// volumeOriginExternal attributes changes no intent accounts for.
+const volumeOriginExternal = "external"
+
+// VolumeOriginState is the reducer-owned origin of the latest volume intent.
+type VolumeOriginState struct {
+	Origin string
+	At     time.Time
+}
+
+// volumeEventOrigin returns the origin of a volume intent, applying the
+// default for its event type when the producer didn't name one. ok is false for
+// events that don't change the volume.
+func volumeEventOrigin(ev Event) (origin string, ok bool) {
+	switch e := ev.(type) {
+	case VolumeHeld:
+		return cmp.Or(e.Origin, "ir"), true
+	case VolumeStep:
+		return cmp.Or(e.Origin, "rotary"), true
+	case RotaryTurn:
+		return cmp.Or(e.Origin, "rotary"), true
+	case RotaryTurnHiRes:
+		return cmp.Or(e.Origin, "rotary"), true
+	case SetVolumeAbsolute:
+		return cmp.Or(e.Origin, "input"), true
+	case SetVolumePercent:
+		return cmp.Or(e.Origin, "input"), true
+	}
+	return "", false
+}
+
+// withVolumeOrigin returns ev with its Origin set by an ingress: an origin
+// named by the client is prefixed with prefix+":", an empty one becomes
+// fallback. Zoned events are labelled inside; other events pass through.
+func withVolumeOrigin(ev Event, prefix, fallback string) Event {
+	label := func(origin string) string {
+		switch {
+		case origin == "":
+			return fallback
+		case prefix == "":
+			return origin
+		}
+		return prefix + ":" + origin
+	}
+	switch e := ev.(type) {
+	case ZonedEvent:
+		e.Event = withVolumeOrigin(e.Event, prefix, fallback)
+		return e
+	case VolumeHeld:
+		e.Origin = label(e.Origin)
+		return e
+	case VolumeStep:
+		e.Origin = label(e.Origin)
+		return e
+	case RotaryTurn:
+		e.Origin = label(e.Origin)
+		return e
+	case RotaryTurnHiRes:
+		e.Origin = label(e.Origin)
+		return e
+	case SetVolumeAbsolute:
+		e.Origin = label(e.Origin)
+		return e
+	case SetVolumePercent:
+		e.Origin = label(e.Origin)
+		return e
+	}
+	return ev
+}
+
+// noteVolumeOrigin records origin as the source of the current volume intent.
+func (s *DaemonState) noteVolumeOrigin(origin string, at time.Time) {
+	s.VolumeOrigin = VolumeOriginState{Origin: origin, At: at}
+}
+
+// observedVolumeOrigin attributes a volume change observed at `at`.
+func (s *DaemonState) observedVolumeOrigin(at time.Time) string {
+	o := s.VolumeOrigin
+	if o.Origin == "" || o.At.IsZero() || at.Sub(o.At) > volumeOriginWindow {
+		return volumeOriginExternal
+	}
+	return o.Origin
+}
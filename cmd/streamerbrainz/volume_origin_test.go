@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReduce_VolumeChangeOrigin(t *testing.T) {
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0}
+	t0 := time.Unix(1000, 0).UTC()
+	observe := func(s *DaemonState, db float64, at time.Time) BroadcastVolumeChanged {
+		t.Helper()
+		rr := Reduce(s, CamillaVolumeObserved{VolumeDB: db, At: at}, cfg, RotaryConfig{}, ReducerPolicy{})
+		if len(rr.Broadcasts) != 1 {
+			t.Fatalf("broadcasts = %v", rr.Broadcasts)
+		}
+		return rr.Broadcasts[0].(BroadcastVolumeChanged)
+	}
+
+	s := &DaemonState{}
+	if b := observe(s, -30, t0); b.Origin != "" {
+		t.Fatalf("first observation origin = %q", b.Origin)
+	}
+
+	Reduce(s, TimedEvent{At: t0, Event: SetVolumeAbsolute{Db: -20, Origin: "ipc:argon-ctl"}}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if b := observe(s, -20, t0.Add(100*time.Millisecond)); b.Origin != "ipc:argon-ctl" {
+		t.Fatalf("origin = %q", b.Origin)
+	}
+
+	Reduce(s, TimedEvent{At: t0.Add(time.Second), Event: RotaryTurn{Steps: 1}}, cfg, RotaryConfig{}, ReducerPolicy{})
+	if b := observe(s, -19, t0.Add(1100*time.Millisecond)); b.Origin != "rotary" {
+		t.Fatalf("default rotary origin = %q", b.Origin)
+	}
+
+	// Long after the last intent: someone else changed CamillaDSP.
+	if b := observe(s, -40, t0.Add(time.Minute)); b.Origin != volumeOriginExternal {
+		t.Fatalf("unattributed origin = %q", b.Origin)
+	}
+}
+
+func TestWithVolumeOrigin(t *testing.T) {
+	if ev := withVolumeOrigin(VolumeStep{Steps: 1, Origin: "argon-ctl"}, "ipc", "ipc"); ev.(VolumeStep).Origin != "ipc:argon-ctl" {
+		t.Fatalf("prefixed = %#v", ev)
+	}
+	if ev := withVolumeOrigin(ZonedEvent{Zone: "phones", Event: VolumeHeld{Direction: 1}}, "ipc", "ipc"); ev.(ZonedEvent).Event.(VolumeHeld).Origin != "ipc" {
+		t.Fatalf("zoned = %#v", ev)
+	}
+	if ev := withVolumeOrigin(SetVolumeAbsolute{Db: -10, Origin: "webui"}, "", "webhook"); ev.(SetVolumeAbsolute).Origin != "webui" {
+		t.Fatalf("unprefixed = %#v", ev)
+	}
+	if ev := withVolumeOrigin(ToggleMute{}, "ipc", "ipc"); ev != (ToggleMute{}) {
+		t.Fatalf("non-volume event changed: %#v", ev)
+	}
+}
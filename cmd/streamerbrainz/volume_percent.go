@@ -0,0 +1,43 @@
+package main
+
+import "math"
+
+// ============================================================================
+// Volume as a percentage
+// ============================================================================
+// Voice assistants and simple controllers speak in percent ("set the hi-fi to
+// 40 percent"). A percentage maps onto the zone's volume range (min_db..max_db,
+// narrowed by user_min_db/user_max_db) with the same logarithmic curve as
+// Spotify's volume slider, so the lower percentages aren't all inaudible:
+//
+//	db = min + (max-min) * log10(1 + 9*p/100)
+//
+// set_volume_percent is turned into an absolute set by the reducer, so ramps,
+// arbitration and limits apply as to set_volume_absolute. Snapshots report the
+// current volume_percent on the same curve.
+// ============================================================================
+
+// volumeFractionToDB maps a volume fraction (0..1) onto minDB..maxDB.
+func volumeFractionToDB(fraction, minDB, maxDB float64) float64 {
+	fraction = math.Max(0, math.Min(1, fraction))
+	return minDB + (maxDB-minDB)*math.Log10(1+9*fraction)
+}
+
+// volumeDBToFraction is the inverse of volumeFractionToDB.
+func volumeDBToFraction(db, minDB, maxDB float64) float64 {
+	if maxDB <= minDB {
+		return 1
+	}
+	pos := math.Max(0, math.Min(1, (db-minDB)/(maxDB-minDB)))
+	return (math.Pow(10, pos) - 1) / 9
+}
+
+// absolute converts a percent set to an absolute set within cfg's volume range.
+func (p SetVolumePercent) absolute(cfg VelocityConfig) SetVolumeAbsolute {
+	return SetVolumeAbsolute{Db: volumeFractionToDB(p.Percent/100, cfg.MinDB, cfg.MaxDB), Origin: p.Origin}
+}
+
+// volumePercent reports db as a percentage of cfg's volume range (one decimal).
+func volumePercent(db float64, cfg VelocityConfig) float64 {
+	return math.Round(volumeDBToFraction(db, cfg.MinDB, cfg.MaxDB)*1000) / 10
+}
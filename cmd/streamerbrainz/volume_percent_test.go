@@ -0,0 +1,63 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestVolumeFractionToDB_RoundTrip(t *testing.T) {
+	for _, f := range []float64{0, 0.1, 0.4, 0.75, 1} {
+		db := volumeFractionToDB(f, -60, 0)
+		if got := volumeDBToFraction(db, -60, 0); math.Abs(got-f) > 1e-9 {
+			t.Errorf("fraction %v: %v dB maps back to %v", f, db, got)
+		}
+	}
+	if db := volumeFractionToDB(2, -60, 0); db != 0 {
+		t.Errorf("fractions are clamped: got %v", db)
+	}
+	if got, want := mapSpotifyVolumeToDB(32768, -60, 0), volumeFractionToDB(32768/spotifyVolumeMax, -60, 0); got != want {
+		t.Errorf("spotify mapping = %v, want %v", got, want)
+	}
+}
+
+func TestReduce_SetVolumePercent(t *testing.T) {
+	maxDB := -20.0
+	cfg := VelocityConfig{MinDB: -80, MaxDB: 0}
+	policy := ReducerPolicy{UserMaxDB: &maxDB}
+	rotaryCfg := RotaryConfig{}
+	t0 := time.Unix(1000, 0).UTC()
+
+	s := &DaemonState{}
+	s.SetObservedVolume(-50, t0)
+
+	// The percentage covers the limited range, -80..-20.
+	rr := Reduce(s, SetVolumePercent{Percent: 100, Origin: "alexa"}, cfg, rotaryCfg, policy)
+	if v, ok := rr.State.GetDesiredVolume(); !ok || v != -20 {
+		t.Fatalf("100%%: desired %v (%v)", v, ok)
+	}
+	if rr.State.VolumeOrigin.Origin != "alexa" {
+		t.Fatalf("origin = %q", rr.State.VolumeOrigin.Origin)
+	}
+	rr = Reduce(rr.State, SetVolumePercent{Percent: 40}, cfg, rotaryCfg, policy)
+	want := volumeFractionToDB(0.4, -80, -20)
+	if v, _ := rr.State.GetDesiredVolume(); math.Abs(v-want) > 0.01 {
+		t.Fatalf("40%%: desired %v, want %v", v, want)
+	}
+
+	rr = Reduce(rr.State, CamillaVolumeObserved{VolumeDB: want, At: t0}, cfg, rotaryCfg, policy)
+	rr = Reduce(rr.State, RequestStateSnapshot{}, cfg, rotaryCfg, policy)
+	if snap := rr.Commands[0].(CmdPublishStateSnapshot).Snapshot; math.Abs(snap.VolumePercent-40) > 0.1 {
+		t.Fatalf("snapshot volume_percent = %v", snap.VolumePercent)
+	}
+}
+
+func TestReduce_SetMute(t *testing.T) {
+	s := &DaemonState{}
+	s.SetObservedMute(true, time.Unix(1000, 0))
+	s.RequestToggleMute(time.Unix(1000, 0))
+	rr := Reduce(s, SetMute{Muted: true}, VelocityConfig{}, RotaryConfig{}, ReducerPolicy{})
+	if rr.State.Intent.DesiredMute == nil || !*rr.State.Intent.DesiredMute {
+		t.Fatalf("intent = %+v", rr.State.Intent)
+	}
+}
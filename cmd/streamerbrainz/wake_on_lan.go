@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+)
+
+// ============================================================================
+// Wake-on-LAN for the CamillaDSP host
+// ============================================================================
+// When CamillaDSP runs on another machine that sleeps or powers off, set
+// camilladsp.wake_on_lan.mac (zones may set their own) and the daemon sends a
+// magic packet to wake it:
+//
+//   - when a volume or mute change is made while CamillaDSP is unreachable, so
+//     pressing volume on the remote wakes the DSP box
+//   - with on_startup, also when the first connect after startup fails
+//
+// Probes then run every wakeProbeInterval instead of every
+// camillaProbeInterval for wake_timeout_sec, so the host is noticed as soon as
+// it is up; another change after that window sends another packet. Probes
+// alone never wake the host, so one switched off on purpose stays off until
+// someone asks for sound.
+//
+// With a MAC set, snapshots carry power_state: "on" (CamillaDSP answers),
+// "waking" (packet sent, waiting) or "off".
+//
+// The packet is sent from the daemon loop, so the address must be an IP
+// literal (validated at config load): a DNS lookup there could stall the loop.
+// ============================================================================
+
+const (
+	defaultWakeOnLANAddress    = "255.255.255.255:9"
+	defaultWakeOnLANTimeoutSec = 90
+	wakeProbeInterval          = 2 * time.Second
+)
+
+// Snapshot power states (StateSnapshot.PowerState).
+const (
+	powerStateOn     = "on"
+	powerStateWaking = "waking"
+	powerStateOff    = "off"
+)
+
+// WakeOnLANConfig wakes the CamillaDSP host (see wake_on_lan.go).
+type WakeOnLANConfig struct {
+	// MAC is the host's network card address (empty = off).
+	MAC string `yaml:"mac,omitempty"`
+
+	// Address is where the magic packet is sent, ip:port (default 255.255.255.255:9);
+	// use the subnet's broadcast address if the host is on another interface.
+	Address string `yaml:"address,omitempty"`
+
+	// OnStartup also wakes the host when the first connect after startup fails.
+	OnStartup bool `yaml:"on_startup,omitempty"`
+
+	// WakeTimeoutSec is how long after a packet the host counts as waking
+	// (probed quickly, no further packets; default 90).
+	WakeTimeoutSec int `yaml:"wake_timeout_sec,omitempty"`
+}
+
+// enabled reports whether a MAC is configured.
+func (w WakeOnLANConfig) enabled() bool { return w.MAC != "" }
+
+// address is Address or the default.
+func (w WakeOnLANConfig) address() string {
+	if w.Address == "" {
+		return defaultWakeOnLANAddress
+	}
+	return w.Address
+}
+
+// udpAddr parses address (an IP literal; no lookup). It is nil if invalid,
+// which validate rules out.
+func (w WakeOnLANConfig) udpAddr() *net.UDPAddr {
+	ap, err := netip.ParseAddrPort(w.address())
+	if err != nil {
+		return nil
+	}
+	return net.UDPAddrFromAddrPort(ap)
+}
+
+// timeout is WakeTimeoutSec or the default.
+func (w WakeOnLANConfig) timeout() time.Duration {
+	if w.WakeTimeoutSec <= 0 {
+		return defaultWakeOnLANTimeoutSec * time.Second
+	}
+	return time.Duration(w.WakeTimeoutSec) * time.Second
+}
+
+func (w WakeOnLANConfig) validate(prefix string) error {
+	if !w.enabled() {
+		return nil
+	}
+	if mac, err := net.ParseMAC(w.MAC); err != nil || len(mac) != 6 {
+		return fmt.Errorf("%s.wake_on_lan.mac must be a 6-byte MAC address (e.g. 00:11:22:33:44:55)", prefix)
+	}
+	if w.udpAddr() == nil {
+		return fmt.Errorf("%s.wake_on_lan.address must be ip:port (e.g. 192.168.1.255:9)", prefix)
+	}
+	if w.WakeTimeoutSec < 0 {
+		return fmt.Errorf("%s.wake_on_lan.wake_timeout_sec must be >= 0", prefix)
+	}
+	return nil
+}
+
+// CmdWakeDSP sends a Wake-on-LAN magic packet for the CamillaDSP host. The
+// daemon loop sends it directly (one datagram) instead of queuing it behind
+// CamillaDSP commands that are failing.
+type CmdWakeDSP struct {
+	MAC     string
+	Address *net.UDPAddr
+}
+
+func (CmdWakeDSP) commandMarker() {}
+func (c CmdWakeDSP) String() string {
+	return fmt.Sprintf("CmdWakeDSP(mac=%s, address=%s)", c.MAC, c.Address)
+}
+
+// waking reports whether a wake packet was sent less than the wake timeout ago.
+func (s *DaemonState) waking(now time.Time, cfg WakeOnLANConfig) bool {
+	return !s.Camilla.WakeAt.IsZero() && now.Sub(s.Camilla.WakeAt) < cfg.timeout()
+}
+
+// wakeDSP wakes the CamillaDSP host when it is unreachable and a volume or
+// mute change is pending (or, with on_startup, it was never reached).
+func wakeDSP(s *DaemonState, now time.Time, cfg WakeOnLANConfig) []Command {
+	if !cfg.enabled() || !s.Camilla.Unreachable || s.waking(now, cfg) {
+		return nil
+	}
+	intent := s.Intent.DesiredVolume != nil || s.Intent.DesiredMute != nil
+	startup := cfg.OnStartup && s.Camilla.WakeAt.IsZero() && !s.Camilla.VolumeKnown && !s.Camilla.MuteKnown
+	if !intent && !startup {
+		return nil
+	}
+	s.Camilla.WakeAt = now
+	// Probe right away: the host may only have been asleep.
+	s.Camilla.ProbeAt = time.Time{}
+	return []Command{CmdWakeDSP{MAC: cfg.MAC, Address: cfg.udpAddr()}}
+}
+
+// probeInterval is how often an unreachable CamillaDSP is probed.
+func probeInterval(s *DaemonState, now time.Time, cfg WakeOnLANConfig) time.Duration {
+	if cfg.enabled() && s.waking(now, cfg) {
+		return wakeProbeInterval
+	}
+	return camillaProbeInterval
+}
+
+// powerState is the snapshot's power_state ("" without Wake-on-LAN).
+func powerState(s *DaemonState, now time.Time, cfg WakeOnLANConfig) string {
+	switch {
+	case !cfg.enabled():
+		return ""
+	case !s.Camilla.Unreachable:
+		return powerStateOn
+	case s.waking(now, cfg):
+		return powerStateWaking
+	}
+	return powerStateOff
+}
+
+// magicPacket builds the Wake-on-LAN payload for mac: 6 x 0xff, then mac 16 times.
+func magicPacket(mac net.HardwareAddr) []byte {
+	var b bytes.Buffer
+	b.Write(bytes.Repeat([]byte{0xff}, 6))
+	for range 16 {
+		b.Write(mac)
+	}
+	return b.Bytes()
+}
+
+// sendWakeOnLAN sends a magic packet for mac to addr (usually a broadcast
+// address). It does no name lookups, so it is safe to call from the daemon loop.
+func sendWakeOnLAN(mac string, addr *net.UDPAddr) error {
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write(magicPacket(hw))
+	return err
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestMagicPacket(t *testing.T) {
+	mac, _ := net.ParseMAC("00:11:22:33:44:55")
+	p := magicPacket(mac)
+	if len(p) != 102 {
+		t.Fatalf("len = %d, want 102", len(p))
+	}
+	if !bytes.Equal(p[:6], bytes.Repeat([]byte{0xff}, 6)) {
+		t.Fatalf("header = % x", p[:6])
+	}
+	for i := range 16 {
+		if got := p[6+6*i : 12+6*i]; !bytes.Equal(got, mac) {
+			t.Fatalf("repeat %d = % x", i, got)
+		}
+	}
+}
+
+func TestReduce_WakeOnLAN(t *testing.T) {
+	wol := WakeOnLANConfig{MAC: "00:11:22:33:44:55", WakeTimeoutSec: 30}
+	cfg := VelocityConfig{Mode: VelocityModeConstant, VelMaxDBPerS: 5, AccelTime: 1, MinDB: -80, MaxDB: 0}
+	policy := ReducerPolicy{WakeOnLAN: wol}
+	t0 := time.Unix(1000, 0).UTC()
+	s := &DaemonState{}
+	s.SetObservedVolume(-40, t0)
+	s.Camilla.Unreachable = true
+	s.Camilla.ProbeAt = t0
+
+	// Probes alone never wake the host.
+	rr := Reduce(s, Tick{Now: t0.Add(time.Minute), Dt: 0.1}, cfg, RotaryConfig{}, policy)
+	if n := countCommands[CmdWakeDSP](rr.Commands); n != 0 {
+		t.Fatalf("woke without a change: %v", rr.Commands)
+	}
+	if got := powerState(s, t0.Add(time.Minute), wol); got != powerStateOff {
+		t.Fatalf("power_state = %q, want off", got)
+	}
+
+	now := t0.Add(2 * time.Minute)
+	Reduce(s, TimedEvent{At: now, Event: SetMute{Muted: true}}, cfg, RotaryConfig{}, policy)
+	rr = Reduce(s, Tick{Now: now, Dt: 0.1}, cfg, RotaryConfig{}, policy)
+	var wake *CmdWakeDSP
+	for _, c := range rr.Commands {
+		if w, ok := c.(CmdWakeDSP); ok {
+			wake = &w
+		}
+	}
+	if wake == nil || wake.MAC != wol.MAC || wake.Address.String() != defaultWakeOnLANAddress {
+		t.Fatalf("wake = %+v in %v", wake, rr.Commands)
+	}
+	if countCommands[CmdGetVolume](rr.Commands) != 1 {
+		t.Fatalf("no probe right after the wake: %v", rr.Commands)
+	}
+	if got := powerState(s, now, wol); got != powerStateWaking {
+		t.Fatalf("power_state = %q, want waking", got)
+	}
+
+	// While waking: probed quickly, no second packet.
+	Reduce(s, TimedEvent{At: now.Add(time.Second), Event: SetMute{Muted: false}}, cfg, RotaryConfig{}, policy)
+	rr = Reduce(s, Tick{Now: now.Add(wakeProbeInterval), Dt: 0.1}, cfg, RotaryConfig{}, policy)
+	if countCommands[CmdWakeDSP](rr.Commands) != 0 || countCommands[CmdGetVolume](rr.Commands) != 1 {
+		t.Fatalf("while waking: %v", rr.Commands)
+	}
+
+	// After the window, a pending change wakes it again.
+	later := now.Add(31 * time.Second)
+	Reduce(s, TimedEvent{At: later, Event: SetMute{Muted: true}}, cfg, RotaryConfig{}, policy)
+	rr = Reduce(s, Tick{Now: later, Dt: 0.1}, cfg, RotaryConfig{}, policy)
+	if countCommands[CmdWakeDSP](rr.Commands) != 1 {
+		t.Fatalf("no wake after the window: %v", rr.Commands)
+	}
+
+	s.Camilla.Unreachable = false
+	if got := powerState(s, later, wol); got != powerStateOn {
+		t.Fatalf("power_state = %q, want on", got)
+	}
+	if got := powerState(s, later, WakeOnLANConfig{}); got != "" {
+		t.Fatalf("power_state without a MAC = %q", got)
+	}
+}
+
+func TestWakeDSP_OnStartup(t *testing.T) {
+	t0 := time.Unix(1000, 0).UTC()
+	wol := WakeOnLANConfig{MAC: "00:11:22:33:44:55", OnStartup: true}
+	s := &DaemonState{}
+	s.Camilla.Unreachable = true
+	if cmds := wakeDSP(s, t0, wol); len(cmds) != 1 {
+		t.Fatalf("startup wake = %v", cmds)
+	}
+	// Once only: after the window nothing pending means no packet.
+	if cmds := wakeDSP(s, t0.Add(time.Hour), wol); len(cmds) != 0 {
+		t.Fatalf("woke again = %v", cmds)
+	}
+
+	wol.OnStartup = false
+	if cmds := wakeDSP(&DaemonState{Camilla: s.Camilla}, t0, wol); len(cmds) != 0 {
+		t.Fatalf("woke without on_startup: %v", cmds)
+	}
+}
+
+func TestSendWakeOnLAN(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	if err := sendWakeOnLAN("00:11:22:33:44:55", pc.LocalAddr().(*net.UDPAddr)); err != nil {
+		t.Fatal(err)
+	}
+	pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 200)
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 102 || buf[6] != 0x00 || buf[7] != 0x11 {
+		t.Fatalf("received % x", buf[:n])
+	}
+}
+
+func TestWakeOnLANConfig_Validate(t *testing.T) {
+	for _, tc := range []struct {
+		cfg WakeOnLANConfig
+		ok  bool
+	}{
+		{WakeOnLANConfig{}, true},
+		{WakeOnLANConfig{MAC: "00:11:22:33:44:55"}, true},
+		{WakeOnLANConfig{MAC: "00-11-22-33-44-55", Address: "192.168.1.255:7"}, true},
+		{WakeOnLANConfig{MAC: "nope"}, false},
+		{WakeOnLANConfig{MAC: "00:00:5e:00:53:01:00:00"}, false},
+		{WakeOnLANConfig{MAC: "00:11:22:33:44:55", Address: "192.168.1.255"}, false},
+		{WakeOnLANConfig{MAC: "00:11:22:33:44:55", Address: "dsp.lan:9"}, false},
+		{WakeOnLANConfig{MAC: "00:11:22:33:44:55", WakeTimeoutSec: -1}, false},
+	} {
+		if err := tc.cfg.validate("camilladsp"); (err == nil) != tc.ok {
+			t.Errorf("%+v: err = %v", tc.cfg, err)
+		}
+	}
+}
@@ -4,44 +4,79 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
+	"net"
 	"net/http"
+	"os"
 	"time"
 )
 
 // ============================================================================
-// Webhooks Server
+// HTTP Servers
 // ============================================================================
-// Generic HTTP server for receiving webhook events from external services.
+// Generic HTTP server used for both the webhook receiver (public, e.g. Plex on
+// the LAN) and the control API (state WS/SSE, event injection). They can share
+// one listener or run on separate addresses (see api.port / bind_address); the
+// control API can additionally be served on a Unix socket (api.socket).
 // Individual integrations register their own endpoints.
 // ============================================================================
 
-// runWebhooksServer starts the HTTP server on the specified port and shuts it down
-// gracefully when ctx is canceled.
+// runHTTPServer starts an HTTP server on listenAddr and shuts it down gracefully
+// when ctx is canceled. name is used for logging only ("webhooks", "api").
 //
 // This replaces http.ListenAndServe so we can call Server.Shutdown during program shutdown.
 //
-// NOTE: This function now accepts an explicit handler (mux) so the program can host
+// NOTE: This function accepts an explicit handler (mux) so the program can host
 // multiple endpoints (webhooks, websocket, etc.) on a single HTTP server.
-func runWebhooksServer(ctx context.Context, port int, handler http.Handler, logger *slog.Logger) error {
-	listenAddr := fmt.Sprintf(":%d", port)
-	logger.Info("webhooks server listening", "port", port)
+func runHTTPServer(ctx context.Context, name string, listenAddr string, handler http.Handler, logger *slog.Logger) error {
+	if handler == nil {
+		return fmt.Errorf("nil http handler")
+	}
+	ln, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return fmt.Errorf("%s HTTP server: %w", name, err)
+	}
+	logger.Info(name+" server listening", "addr", listenAddr)
+	return serveHTTP(ctx, name, ln, handler)
+}
 
+// runHTTPServerUnix is runHTTPServer on a Unix domain socket at socketPath, created
+// with file mode perm (access is controlled by filesystem permissions, e.g. for a
+// reverse proxy in the same group). A stale socket file is replaced; the socket is
+// removed on shutdown.
+func runHTTPServerUnix(ctx context.Context, name string, socketPath string, perm os.FileMode, handler http.Handler, logger *slog.Logger) error {
 	if handler == nil {
 		return fmt.Errorf("nil http handler")
 	}
+	if err := os.Remove(socketPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s HTTP server: remove stale socket: %w", name, err)
+	}
+	ln, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("%s HTTP server: %w", name, err)
+	}
+	defer os.Remove(socketPath)
+	if err := os.Chmod(socketPath, perm); err != nil {
+		ln.Close()
+		return fmt.Errorf("%s HTTP server: chmod socket: %w", name, err)
+	}
+	logger.Info(name+" server listening", "socket", socketPath, "mode", fmt.Sprintf("%#o", perm))
+	return serveHTTP(ctx, name, ln, handler)
+}
 
+// serveHTTP serves handler on ln until ctx is canceled, then shuts down gracefully.
+func serveHTTP(ctx context.Context, name string, ln net.Listener, handler http.Handler) error {
 	srv := &http.Server{
-		Addr:    listenAddr,
 		Handler: handler,
 	}
 
 	errCh := make(chan error, 1)
 
 	go func() {
-		// ListenAndServe returns http.ErrServerClosed on Shutdown; treat that as clean exit.
-		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			errCh <- fmt.Errorf("HTTP server: %w", err)
+		// Serve returns http.ErrServerClosed on Shutdown; treat that as clean exit.
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- fmt.Errorf("%s HTTP server: %w", name, err)
 			return
 		}
 		errCh <- nil
@@ -54,9 +89,9 @@ func runWebhooksServer(ctx context.Context, port int, handler http.Handler, logg
 		defer cancel()
 
 		if err := srv.Shutdown(shutdownCtx); err != nil {
-			return fmt.Errorf("HTTP server shutdown: %w", err)
+			return fmt.Errorf("%s HTTP server shutdown: %w", name, err)
 		}
-		// Wait for the ListenAndServe goroutine to return.
+		// Wait for the Serve goroutine to return.
 		_ = <-errCh
 		return nil
 
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// ============================================================================
+// Generic Event Webhook
+// ============================================================================
+// POST /webhooks/event accepts the standard event envelope used by the IPC socket:
+//
+//   {"type": "set_volume_absolute", "data": {"db": -30, "origin": "shortcuts"}}
+//
+// This lets services that can only make HTTP requests (iOS Shortcuts, Tasker, Home
+// Assistant rest_command, ...) inject events without speaking the Unix socket.
+//
+// Authentication: a shared token, sent as either
+//   Authorization: Bearer <token>
+// or
+//   X-StreamerBrainz-Token: <token>
+//
+// Responses mirror IPCResponse: {"status":"ok"} or {"status":"error","error":"..."}.
+// ============================================================================
+
+// maxEventWebhookBody bounds the request body size for injected events.
+const maxEventWebhookBody = 64 << 10
+
+// handleEventWebhook returns the handler for POST /webhooks/event.
+func handleEventWebhook(token string, events chan<- Event, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeEventWebhookResponse(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		if !eventWebhookAuthorized(r, token) {
+			logger.Warn("event webhook unauthorized", "remote_addr", r.RemoteAddr)
+			writeEventWebhookResponse(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxEventWebhookBody+1))
+		if err != nil {
+			writeEventWebhookResponse(w, http.StatusBadRequest, fmt.Sprintf("read body: %v", err))
+			return
+		}
+		if len(body) > maxEventWebhookBody {
+			writeEventWebhookResponse(w, http.StatusRequestEntityTooLarge, "body too large")
+			return
+		}
+
+		ev, err := UnmarshalEvent(body)
+		if err != nil {
+			writeEventWebhookResponse(w, http.StatusBadRequest, fmt.Sprintf("parse event: %v", err))
+			return
+		}
+		ev = withVolumeOrigin(ev, "", "webhook")
+
+		ack, queued := sendAcked(events, ev)
+		if !queued {
+			writeEventWebhookResponse(w, http.StatusServiceUnavailable, "event queue full")
+			return
+		}
+		logger.Debug("event webhook handled", "remote_addr", r.RemoteAddr, "event", fmt.Sprintf("%T", ev), "error", ack.Err)
+		status := http.StatusOK
+		switch {
+		case ack.DSP:
+			status = http.StatusBadGateway
+		case ack.Err != "":
+			status = http.StatusConflict
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(ackResponse(ack))
+	}
+}
+
+// eventWebhookAuthorized checks the request token in constant time.
+func eventWebhookAuthorized(r *http.Request, token string) bool {
+	got := r.Header.Get(apiTokenHeader)
+	if auth := r.Header.Get("Authorization"); got == "" && strings.HasPrefix(auth, "Bearer ") {
+		got = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if got == "" || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+func writeEventWebhookResponse(w http.ResponseWriter, status int, errMsg string) {
+	resp := IPCResponse{Status: "ok"}
+	if errMsg != "" {
+		resp = IPCResponse{Status: "error", Error: errMsg}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// setupEventWebhook registers the generic event injection endpoint.
+func setupEventWebhook(tokenFile string, mux *http.ServeMux, events chan<- Event, logger *slog.Logger) error {
+	token, err := readSecret(tokenFile)
+	if err != nil {
+		return fmt.Errorf("failed to read event webhook token: %w", err)
+	}
+
+	if mux == nil {
+		return fmt.Errorf("nil http mux")
+	}
+
+	mux.HandleFunc("/webhooks/event", handleEventWebhook(token, events, logger))
+	logger.Info("event webhook enabled", "endpoint", "/webhooks/event")
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEventWebhook_AuthAndInjection(t *testing.T) {
+	events := make(chan Event, 1)
+	h := handleEventWebhook("tok", events, slog.Default())
+
+	body := `{"type":"set_volume_absolute","data":{"db":-30,"origin":"shortcuts"}}`
+
+	// Missing token.
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, "/webhooks/event", strings.NewReader(body)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rec.Code)
+	}
+
+	// Malformed envelope.
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/event", strings.NewReader(`{"type":"nope"}`))
+	req.Header.Set("Authorization", "Bearer tok")
+	rec = httptest.NewRecorder()
+	h(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+
+	// Valid; the response carries the outcome.
+	for _, tc := range []struct {
+		ack  EventAck
+		code int
+	}{
+		{EventAck{Message: "clamped to -40.0 dB"}, http.StatusOK},
+		{EventAck{Err: "volume is locked"}, http.StatusConflict},
+		{EventAck{Err: "camilladsp unreachable", DSP: true}, http.StatusBadGateway},
+	} {
+		got := make(chan Event, 1)
+		go func() {
+			ev, reply := splitAck(<-events)
+			got <- ev
+			reply <- tc.ack
+		}()
+		req = httptest.NewRequest(http.MethodPost, "/webhooks/event", strings.NewReader(body))
+		req.Header.Set("X-StreamerBrainz-Token", "tok")
+		rec = httptest.NewRecorder()
+		h(rec, req)
+		if rec.Code != tc.code || !strings.Contains(rec.Body.String(), tc.ack.Err+tc.ack.Message) {
+			t.Fatalf("ack %+v: got %d (%s)", tc.ack, rec.Code, rec.Body.String())
+		}
+
+		ev := <-got
+		sv, ok := ev.(SetVolumeAbsolute)
+		if !ok || sv.Db != -30 || sv.Origin != "shortcuts" {
+			t.Fatalf("unexpected event %#v", ev)
+		}
+	}
+}
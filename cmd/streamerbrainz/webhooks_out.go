@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// ============================================================================
+// Outbound Webhooks (state change fan-out)
+// ============================================================================
+// POSTs reducer-emitted state broadcasts (volume/mute/player changes) as JSON to
+// user-configured URLs. This lets users drive Node-RED / Home Assistant / IFTTT-style
+// automations without running MQTT.
+//
+// Wire format: the same envelope used by the state WebSocket:
+//   {"type": "volume_changed", "ts": "...", "data": {...}}
+//
+// Signing: if a target has a secret, the request carries
+//   X-StreamerBrainz-Signature: sha256=<hex(HMAC-SHA256(secret, body))>
+//
+// Delivery:
+//   - Each target has its own queue and worker, so a slow/down target never delays others.
+//   - Bursty volume_changed updates are coalesced (latest-wins) per target.
+//   - Failed deliveries (network errors, 429, 5xx) are retried with exponential backoff.
+// ============================================================================
+
+const (
+	outboundWebhookQueueSize   = 32
+	outboundWebhookTimeout     = 3 * time.Second
+	outboundWebhookBaseBackoff = 500 * time.Millisecond
+	outboundWebhookSignature   = "X-StreamerBrainz-Signature"
+)
+
+// outboundWebhookTarget is one configured destination plus its delivery queue.
+type outboundWebhookTarget struct {
+	cfg    OutboundWebhookConfig
+	events map[string]bool // nil means all event types
+	queue  chan wsOutboundEvent
+	client *http.Client
+}
+
+// resolveOutboundWebhookSecrets returns a copy of targets with each secret
+// source replaced by the secret it names.
+func resolveOutboundWebhookSecrets(targets []OutboundWebhookConfig) ([]OutboundWebhookConfig, error) {
+	out := slices.Clone(targets)
+	for i := range out {
+		if out[i].Secret == "" {
+			continue
+		}
+		secret, err := readSecret(out[i].Secret)
+		if err != nil {
+			return nil, fmt.Errorf("outbound_webhooks[%d].secret: %w", i, err)
+		}
+		out[i].Secret = secret
+	}
+	return out, nil
+}
+
+// RunOutboundWebhooks consumes StateBroadcasts from src and delivers them to all
+// configured targets until ctx is canceled or src is closed.
+func RunOutboundWebhooks(ctx context.Context, targets []OutboundWebhookConfig, src <-chan StateBroadcast, logger *slog.Logger) {
+	if len(targets) == 0 || src == nil {
+		return
+	}
+
+	var ts []*outboundWebhookTarget
+	for _, cfg := range targets {
+		timeout := outboundWebhookTimeout
+		if cfg.TimeoutMS > 0 {
+			timeout = time.Duration(cfg.TimeoutMS) * time.Millisecond
+		}
+		t := &outboundWebhookTarget{
+			cfg:    cfg,
+			queue:  make(chan wsOutboundEvent, outboundWebhookQueueSize),
+			client: &http.Client{Timeout: timeout},
+		}
+		if len(cfg.Events) > 0 {
+			t.events = make(map[string]bool, len(cfg.Events))
+			for _, e := range cfg.Events {
+				t.events[e] = true
+			}
+		}
+		ts = append(ts, t)
+		go t.run(ctx, logger)
+	}
+
+	logger.Info("outbound webhooks enabled", "targets", len(ts))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case b, ok := <-src:
+			if !ok {
+				return
+			}
+			ev, ok := convertBroadcast(b)
+			if !ok {
+				continue
+			}
+			if ev.Type == "controller_changed" || ev.Type == "camilladsp_frame" {
+				// Progress updates for live UIs (up to 10/s during a hold) and
+				// protocol tap frames, not notifications.
+				continue
+			}
+			for _, t := range ts {
+				if t.events != nil && !t.events[ev.Type] {
+					continue
+				}
+				select {
+				case t.queue <- ev:
+				default:
+					logger.Warn("outbound webhook queue full, dropping event", "url", t.cfg.URL, "type", ev.Type)
+				}
+			}
+		}
+	}
+}
+
+// run is the per-target delivery worker.
+func (t *outboundWebhookTarget) run(ctx context.Context, logger *slog.Logger) {
+	for {
+		var batch []wsOutboundEvent
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-t.queue:
+			batch = append(batch, ev)
+		}
+
+		// Drain whatever else is queued so superseded volume updates can be dropped.
+	drain:
+		for {
+			select {
+			case ev := <-t.queue:
+				batch = append(batch, ev)
+			default:
+				break drain
+			}
+		}
+
+		for _, ev := range coalesceOutbound(batch) {
+			if err := t.deliver(ctx, ev); err != nil {
+				logger.Warn("outbound webhook delivery failed", "url", t.cfg.URL, "type", ev.Type, "error", err)
+			}
+		}
+	}
+}
+
+// coalesceOutbound keeps only the last volume_changed per zone in a batch while
+// preserving the relative order of all other events.
+func coalesceOutbound(batch []wsOutboundEvent) []wsOutboundEvent {
+	lastVol := make(map[string]int)
+	for i, ev := range batch {
+		if ev.Type == "volume_changed" {
+			lastVol[ev.Zone] = i
+		}
+	}
+	out := batch[:0]
+	for i, ev := range batch {
+		if ev.Type == "volume_changed" && i != lastVol[ev.Zone] {
+			continue
+		}
+		out = append(out, ev)
+	}
+	return out
+}
+
+// deliver POSTs one event, retrying transient failures with exponential backoff.
+func (t *outboundWebhookTarget) deliver(ctx context.Context, ev wsOutboundEvent) error {
+	body, err := marshalOutbound(ev)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= t.cfg.MaxRetries; attempt++ {
+		if attempt > 0 {
+			backoff := outboundWebhookBaseBackoff << (attempt - 1)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+		}
+
+		retry, err := t.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+	return lastErr
+}
+
+// post performs a single delivery attempt. It reports whether a failure is retryable.
+func (t *outboundWebhookTarget) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "streamerbrainz/"+version)
+	if t.cfg.Secret != "" {
+		req.Header.Set(outboundWebhookSignature, signWebhookBody(t.cfg.Secret, body))
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("HTTP %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+}
+
+// signWebhookBody returns the signature header value for body.
+func signWebhookBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
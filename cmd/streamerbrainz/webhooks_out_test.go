@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOutboundWebhooks_SignsFiltersAndRetries(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var attempts atomic.Int32
+	got := make(chan string, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(outboundWebhookSignature) != signWebhookBody("s3cret", body) {
+			t.Errorf("bad signature header %q", r.Header.Get(outboundWebhookSignature))
+		}
+		// First attempt fails transiently to exercise retry.
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		got <- string(body)
+	}))
+	defer srv.Close()
+
+	t.Setenv("SB_TEST_WEBHOOK_SECRET", "s3cret")
+	targets, err := resolveOutboundWebhookSecrets([]OutboundWebhookConfig{{
+		URL:        srv.URL,
+		Events:     []string{"mute_changed"},
+		Secret:     "env:SB_TEST_WEBHOOK_SECRET",
+		MaxRetries: 2,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := make(chan StateBroadcast, 4)
+	go RunOutboundWebhooks(ctx, targets, src, slog.Default())
+
+	at := time.Unix(1000, 0).UTC()
+	src <- BroadcastVolumeChanged{VolumeDB: -20, At: at} // filtered out
+	src <- BroadcastMuteChanged{Muted: true, At: at}
+
+	select {
+	case body := <-got:
+		want := `{"type":"mute_changed","ts":"1970-01-01T00:16:40Z","data":{"muted":true}}`
+		if body != want {
+			t.Fatalf("got %s, want %s", body, want)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("timeout waiting for delivery")
+	}
+
+	if n := attempts.Load(); n != 2 {
+		t.Fatalf("expected 2 attempts (1 retry), got %d", n)
+	}
+}
+
+func TestResolveOutboundWebhookSecrets(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "hook"), []byte("from-credential\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CREDENTIALS_DIRECTORY", dir)
+
+	in := []OutboundWebhookConfig{{URL: "http://a"}, {URL: "http://b", Secret: "credential:hook"}}
+	out, err := resolveOutboundWebhookSecrets(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out[0].Secret != "" || out[1].Secret != "from-credential" || in[1].Secret != "credential:hook" {
+		t.Fatalf("out = %+v, in = %+v", out, in)
+	}
+	if _, err := resolveOutboundWebhookSecrets([]OutboundWebhookConfig{{Secret: "credential:missing"}}); err == nil {
+		t.Fatal("missing credential accepted")
+	}
+}
+
+func TestCoalesceOutbound_KeepsLatestVolume(t *testing.T) {
+	batch := []wsOutboundEvent{
+		{Type: "volume_changed", Data: 1},
+		{Type: "mute_changed", Data: 2},
+		{Type: "volume_changed", Data: 3},
+	}
+	out := coalesceOutbound(batch)
+	if len(out) != 2 || out[0].Type != "mute_changed" || out[1].Data != 3 {
+		t.Fatalf("unexpected coalesced batch: %+v", out)
+	}
+}
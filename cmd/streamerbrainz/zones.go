@@ -0,0 +1,395 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// Zones (multiple CamillaDSP instances)
+// ============================================================================
+//
+// Each zone is an independent CamillaDSP instance (e.g. speakers + headphone amp)
+// with its own daemon loop, DaemonState, client, bounds and update rate.
+//
+// The zone router sits between producers (inputs, IPC, HTTP) and the per-zone
+// daemon loops:
+//   - ZonedEvent{Zone, Event} is delivered to that zone.
+//   - SelectZone switches the "current zone" used for unzoned events.
+//   - RequestStateSnapshot is answered with an aggregated snapshot: the current
+//     zone's fields at the top level (backward compatible) plus Zones[].
+//   - ConfigUpdated and ResyncState go to every zone.
+//   - Every other event goes to the current zone (IR/rotary bind to it).
+//   - AckedEvent is answered here for events the router handles itself; events
+//     for a zone carry the reply on to that zone's reducer (see ack.go).
+//
+// Volume linking:
+//   - Zones in the link group mirror each other's volume changes. Relative changes
+//     (hold, release, rotary, step) are forwarded unchanged to every linked zone, so
+//     per-zone offsets are preserved; absolute sets are translated per zone as
+//     db - offset(source) + offset(target). Each zone's reducer still clamps to its
+//     own bounds.
+//   - Membership is changed at runtime with LinkZone / UnlinkZone.
+//
+// ============================================================================
+
+// ZoneBroadcast tags a reducer-emitted broadcast with the zone it came from.
+// Daemon loops wrap every broadcast they publish.
+type ZoneBroadcast struct {
+	Zone      string
+	Broadcast StateBroadcast
+}
+
+func (ZoneBroadcast) stateBroadcastMarker() {}
+
+// BroadcastZoneSelected is emitted by the zone router when the current zone changes.
+type BroadcastZoneSelected struct {
+	Zone string    `json:"zone"`
+	At   time.Time `json:"at"`
+}
+
+func (BroadcastZoneSelected) stateBroadcastMarker() {}
+
+// BroadcastZoneLinkChanged is emitted by the zone router when link group membership changes.
+type BroadcastZoneLinkChanged struct {
+	// Offsets maps each linked zone to its offset (dB). Empty means no zones are linked.
+	Offsets map[string]float64 `json:"offsets"`
+	At      time.Time          `json:"at"`
+}
+
+func (BroadcastZoneLinkChanged) stateBroadcastMarker() {}
+
+// zoneSnapshotTimeout bounds how long the router waits for per-zone snapshots.
+const zoneSnapshotTimeout = 500 * time.Millisecond
+
+// zoneRoute is one zone's input channel, kept in config order.
+type zoneRoute struct {
+	ID     string
+	Events chan<- Event
+	// Senders tracks producers other than the router that send to Events (nil
+	// if none); the router waits for them before closing it. Each must stop
+	// sending once ctx is canceled.
+	Senders *sync.WaitGroup
+}
+
+// runZoneRouter dispatches events to per-zone daemon loops until ctx is canceled or
+// events is closed. On exit it closes every zone channel so the daemon loops stop,
+// after the snapshot requests it started and the zone's Senders have stopped
+// sending to them.
+func runZoneRouter(
+	ctx context.Context,
+	events <-chan Event,
+	zones []zoneRoute,
+	current string,
+	linked map[string]float64,
+	broadcasts chan<- StateBroadcast,
+	logger *slog.Logger,
+) {
+	// Snapshot collectors send to zone channels; they must finish before those close.
+	var collectors sync.WaitGroup
+	defer func() {
+		collectors.Wait()
+		for _, z := range zones {
+			if z.Senders != nil {
+				z.Senders.Wait()
+			}
+			close(z.Events)
+		}
+	}()
+
+	byID := make(map[string]chan<- Event, len(zones))
+	for _, z := range zones {
+		byID[z.ID] = z.Events
+	}
+
+	// Copy so runtime link changes never mutate the caller's config.
+	links := make(map[string]float64, len(linked))
+	for z, off := range linked {
+		links[z] = off
+	}
+
+	// Input device status, keyed by path (devices are shared by all zones).
+	devices := make(map[string]InputDeviceStatus)
+
+	publish := func(b StateBroadcast) {
+		if broadcasts == nil {
+			return
+		}
+		select {
+		case broadcasts <- b:
+		default:
+			logger.Warn("state broadcast queue full, dropping broadcast")
+		}
+	}
+
+	send := func(zone string, ev Event) bool {
+		ch, ok := byID[zone]
+		if !ok {
+			logger.Warn("event for unknown zone dropped", "zone", zone)
+			return false
+		}
+		select {
+		case ch <- ev:
+		case <-ctx.Done():
+		}
+		return true
+	}
+
+	// forward delivers ev to zone (with the acknowledgement request, if any) and
+	// mirrors volume changes to linked zones.
+	forward := func(zone string, ev Event, reply chan<- EventAck) {
+		if reply == nil {
+			send(zone, ev)
+		} else if !send(zone, AckedEvent{Event: ev, Reply: reply}) {
+			deliverAck(reply, EventAck{Err: "unknown zone " + zone}, logger)
+		}
+
+		srcOffset, isLinked := links[zone]
+		if !isLinked {
+			return
+		}
+		for _, z := range zones {
+			dstOffset, ok := links[z.ID]
+			if !ok || z.ID == zone {
+				continue
+			}
+			if mirrored, ok := mirrorLinkedEvent(ev, dstOffset-srcOffset); ok {
+				send(z.ID, mirrored)
+			}
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			// reply is answered once the router is done with the event, unless
+			// it was forwarded to a zone.
+			ev, reply := splitAck(ev)
+			ack := EventAck{}
+
+			switch e := ev.(type) {
+			case ZonedEvent:
+				forward(e.Zone, e.Event, reply)
+				reply = nil
+
+			case SelectZone:
+				next := e.Zone
+				if next == "" {
+					next = nextZoneID(zones, current)
+				}
+				switch _, ok := byID[next]; {
+				case !ok:
+					logger.Warn("select zone: unknown zone", "zone", next)
+					ack.Err = "unknown zone " + next
+				case next != current:
+					current = next
+					logger.Info("zone selected", "zone", current)
+					publish(BroadcastZoneSelected{Zone: current, At: time.Now()})
+				}
+
+			case LinkZone:
+				if _, ok := byID[e.Zone]; !ok {
+					logger.Warn("link zone: unknown zone", "zone", e.Zone)
+					ack.Err = "unknown zone " + e.Zone
+					break
+				}
+				links[e.Zone] = e.OffsetDB
+				logger.Info("zone linked", "zone", e.Zone, "offset_db", e.OffsetDB)
+				publish(BroadcastZoneLinkChanged{Offsets: copyOffsets(links), At: time.Now()})
+
+			case UnlinkZone:
+				if _, ok := links[e.Zone]; !ok {
+					break
+				}
+				delete(links, e.Zone)
+				logger.Info("zone unlinked", "zone", e.Zone)
+				publish(BroadcastZoneLinkChanged{Offsets: copyOffsets(links), At: time.Now()})
+
+			case DeviceUp:
+				prev, known := devices[e.Device]
+				now := time.Now()
+				devices[e.Device] = InputDeviceStatus{Device: e.Device, Up: true, Since: now}
+				if known && !prev.Up {
+					logger.Info("input device up", "device", e.Device)
+					publish(BroadcastDeviceUp{Device: e.Device, At: now})
+				}
+
+			case DeviceDown:
+				// Input devices are shared by all zones.
+				now := time.Now()
+				since := now
+				if prev, ok := devices[e.Device]; ok && !prev.Up {
+					since = prev.Since
+				}
+				devices[e.Device] = InputDeviceStatus{Device: e.Device, Reason: e.Reason, Since: since}
+				logger.Warn("input device down", "device", e.Device, "reason", e.Reason)
+				publish(BroadcastDeviceDown{Device: e.Device, Reason: e.Reason, At: now})
+
+			case ConfigUpdated:
+				// Velocity/rotary tuning is global: every zone's reducer applies it.
+				for _, z := range zones {
+					send(z.ID, e.clone())
+				}
+				logger.Info("tuning applied to all zones")
+				publish(BroadcastTuningChanged{Tuning: e.clone(), At: time.Now()})
+
+			case ResyncState:
+				for _, z := range zones {
+					send(z.ID, e)
+				}
+				logger.Info("state resync requested for all zones")
+
+			case UpdateAvailable:
+				publish(BroadcastUpdateAvailable{Current: e.Current, Latest: e.Latest, URL: e.URL, At: time.Now()})
+
+			case ExposureWarning:
+				publish(BroadcastExposureWarning{Warning: e, At: time.Now()})
+
+			case IRSend:
+				// The amp is shared by all zones; the ir_tx worker consumes the broadcast.
+				logger.Debug("ir send requested", "command", e.Command)
+				publish(BroadcastIRSend{Command: e.Command, At: time.Now()})
+
+			case RequestStateSnapshot:
+				collectors.Add(1)
+				go func(current string, links map[string]float64, inputs []InputDeviceStatus) {
+					defer collectors.Done()
+					collectZoneSnapshots(ctx, zones, current, links, inputs, e.Reply, logger)
+				}(current, copyOffsets(links), inputStatuses(devices))
+
+			default:
+				forward(current, ev, reply)
+				reply = nil
+			}
+			if reply != nil {
+				deliverAck(reply, ack, logger)
+			}
+		}
+	}
+}
+
+// mirrorLinkedEvent returns the event to forward to a linked zone whose offset differs
+// from the source zone's by delta dB. Only volume changes are mirrored.
+func mirrorLinkedEvent(ev Event, delta float64) (Event, bool) {
+	switch e := ev.(type) {
+	case VolumeHeld, VolumeRelease, RotaryTurn, RotaryTurnHiRes, VolumeStep:
+		return ev, true
+	case SetVolumePercent:
+		// Percentages are relative to each zone's own range; the offset doesn't apply.
+		return ev, true
+	case SetVolumeAbsolute:
+		e.Db += delta
+		return e, true
+	default:
+		return nil, false
+	}
+}
+
+// zoneSnapshot picks zone's snapshot out of an aggregated snapshot (empty = the
+// selected zone).
+func zoneSnapshot(snap StateSnapshot, zone string) (StateSnapshot, bool) {
+	if zone == "" || zone == snap.Zone {
+		return snap, true
+	}
+	for _, z := range snap.Zones {
+		if z.Zone == zone {
+			return z, true
+		}
+	}
+	return StateSnapshot{}, false
+}
+
+// inputStatuses returns device statuses sorted by path (nil if none are known).
+func inputStatuses(m map[string]InputDeviceStatus) []InputDeviceStatus {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make([]InputDeviceStatus, 0, len(m))
+	for _, st := range m {
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Device < out[j].Device })
+	return out
+}
+
+func copyOffsets(m map[string]float64) map[string]float64 {
+	out := make(map[string]float64, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// nextZoneID returns the zone after current in config order (wrapping around).
+func nextZoneID(zones []zoneRoute, current string) string {
+	for i, z := range zones {
+		if z.ID == current {
+			return zones[(i+1)%len(zones)].ID
+		}
+	}
+	if len(zones) > 0 {
+		return zones[0].ID
+	}
+	return current
+}
+
+// collectZoneSnapshots requests a snapshot from every zone and replies with an aggregate.
+// It runs in its own goroutine so a slow zone never stalls event routing.
+func collectZoneSnapshots(ctx context.Context, zones []zoneRoute, current string, links map[string]float64, inputs []InputDeviceStatus, reply chan<- StateSnapshot, logger *slog.Logger) {
+	if reply == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, zoneSnapshotTimeout)
+	defer cancel()
+
+	replies := make([]chan StateSnapshot, len(zones))
+	for i, z := range zones {
+		replies[i] = make(chan StateSnapshot, 1)
+		select {
+		case z.Events <- RequestStateSnapshot{Reply: replies[i]}:
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	snaps := make([]StateSnapshot, len(zones))
+	for i, z := range zones {
+		select {
+		case snaps[i] = <-replies[i]:
+		case <-ctx.Done():
+			logger.Warn("zone snapshot request timed out", "zone", z.ID)
+			snaps[i] = StateSnapshot{Zone: z.ID}
+		}
+	}
+
+	// Top-level fields describe the current zone; Zones lists all of them.
+	var agg StateSnapshot
+	for _, snap := range snaps {
+		if snap.Zone == current {
+			agg = snap
+		}
+	}
+	if len(zones) > 1 {
+		agg.Zones = snaps
+		if len(links) > 0 {
+			agg.LinkedZones = links
+		}
+	}
+	agg.Inputs = inputs
+
+	select {
+	case reply <- agg:
+	default:
+		logger.Warn("state snapshot reply channel not ready; dropping snapshot")
+	}
+}
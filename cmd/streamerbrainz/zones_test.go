@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestZoneRouter_RoutesBySelectionAndZone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan Event, 8)
+	speakers := make(chan Event, 8)
+	phones := make(chan Event, 8)
+	broadcasts := make(chan StateBroadcast, 8)
+
+	go runZoneRouter(ctx, events, []zoneRoute{
+		{ID: "speakers", Events: speakers},
+		{ID: "phones", Events: phones},
+	}, "speakers", nil, broadcasts, slog.Default())
+
+	recv := func(ch <-chan Event, name string) Event {
+		t.Helper()
+		select {
+		case ev := <-ch:
+			return ev
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for event on %s", name)
+			return nil
+		}
+	}
+
+	events <- ToggleMute{}
+	if _, ok := recv(speakers, "speakers").(ToggleMute); !ok {
+		t.Fatalf("expected unzoned event routed to current zone")
+	}
+
+	events <- ZonedEvent{Zone: "phones", Event: VolumeStep{Steps: 1}}
+	if _, ok := recv(phones, "phones").(VolumeStep); !ok {
+		t.Fatalf("expected zoned event routed to its zone")
+	}
+
+	// Empty SelectZone cycles to the next zone.
+	events <- SelectZone{}
+	select {
+	case b := <-broadcasts:
+		if zs, ok := b.(BroadcastZoneSelected); !ok || zs.Zone != "phones" {
+			t.Fatalf("unexpected broadcast %#v", b)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timeout waiting for zone_selected broadcast")
+	}
+
+	events <- RotaryTurn{Steps: 2}
+	if _, ok := recv(phones, "phones").(RotaryTurn); !ok {
+		t.Fatalf("expected unzoned event routed to newly selected zone")
+	}
+}
+
+func TestZoneRouter_AggregatesSnapshots(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan Event, 8)
+	a := make(chan Event, 8)
+	b := make(chan Event, 8)
+
+	// Fake zone daemons answer snapshot requests.
+	serve := func(id string, vol float64, ch <-chan Event) {
+		for ev := range ch {
+			if req, ok := ev.(RequestStateSnapshot); ok {
+				req.Reply <- StateSnapshot{Zone: id, VolumeDB: vol, VolumeKnown: true}
+			}
+		}
+	}
+	go serve("a", -10, a)
+	go serve("b", -20, b)
+
+	go runZoneRouter(ctx, events, []zoneRoute{{ID: "a", Events: a}, {ID: "b", Events: b}}, "b", nil, nil, slog.Default())
+
+	reply := make(chan StateSnapshot, 1)
+	events <- RequestStateSnapshot{Reply: reply}
+
+	select {
+	case snap := <-reply:
+		if snap.Zone != "b" || snap.VolumeDB != -20 {
+			t.Fatalf("expected current zone at top level, got %+v", snap)
+		}
+		if len(snap.Zones) != 2 || snap.Zones[0].Zone != "a" || snap.Zones[1].Zone != "b" {
+			t.Fatalf("unexpected zones: %+v", snap.Zones)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timeout waiting for aggregated snapshot")
+	}
+}
+
+func TestZoneRouter_ShutdownDuringSnapshot(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan Event, 8)
+	zone := make(chan Event) // nobody reads: the snapshot request stays pending
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runZoneRouter(ctx, events, []zoneRoute{{ID: "a", Events: zone}}, "a", nil, nil, slog.Default())
+	}()
+	events <- RequestStateSnapshot{Reply: make(chan StateSnapshot, 1)}
+	time.Sleep(20 * time.Millisecond)
+	close(events) // shutdown: the router exits while the collector still waits
+
+	// The router closes the zone channel only after the collector gave up
+	// sending to it (a send on the closed channel would panic).
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("router didn't exit")
+	}
+	if _, ok := <-zone; ok {
+		t.Fatal("zone channel not closed")
+	}
+}
+
+func TestZoneRouter_WaitsForSenders(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan Event, 8)
+	zone := make(chan Event)
+	var senders sync.WaitGroup
+	senders.Add(1)
+	go func() {
+		defer senders.Done()
+		select {
+		case zone <- ResyncState{}:
+		case <-ctx.Done():
+		}
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runZoneRouter(ctx, events, []zoneRoute{{ID: "a", Events: zone, Senders: &senders}}, "a", nil, nil, slog.Default())
+	}()
+	close(events)
+	select {
+	case <-done:
+		t.Fatal("router closed the zone channel while a sender was active")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("router didn't exit")
+	}
+}
+
+func TestZoneRouter_MirrorsLinkedVolumeChanges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan Event, 8)
+	speakers := make(chan Event, 8)
+	phones := make(chan Event, 8)
+
+	go runZoneRouter(ctx, events, []zoneRoute{
+		{ID: "speakers", Events: speakers},
+		{ID: "phones", Events: phones},
+	}, "speakers", map[string]float64{"speakers": 0}, nil, slog.Default())
+
+	recv := func(ch <-chan Event) Event {
+		t.Helper()
+		select {
+		case ev := <-ch:
+			return ev
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for event")
+			return nil
+		}
+	}
+
+	events <- LinkZone{Zone: "phones", OffsetDB: -6}
+	events <- SetVolumeAbsolute{Db: -20}
+
+	if ev := recv(speakers).(SetVolumeAbsolute); ev.Db != -20 {
+		t.Fatalf("speakers got %v, want -20", ev.Db)
+	}
+	if ev := recv(phones).(SetVolumeAbsolute); ev.Db != -26 {
+		t.Fatalf("phones got %v, want -26 (offset applied)", ev.Db)
+	}
+
+	// Relative changes are mirrored unchanged; non-volume events are not.
+	events <- RotaryTurn{Steps: 1}
+	events <- ToggleMute{}
+	recv(speakers)
+	if _, ok := recv(phones).(RotaryTurn); !ok {
+		t.Fatalf("expected rotary turn mirrored to linked zone")
+	}
+	if _, ok := recv(speakers).(ToggleMute); !ok {
+		t.Fatalf("expected mute on current zone")
+	}
+	select {
+	case ev := <-phones:
+		t.Fatalf("unexpected mirrored event %T", ev)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	events <- UnlinkZone{Zone: "phones"}
+	events <- VolumeStep{Steps: 1}
+	recv(speakers)
+	select {
+	case ev := <-phones:
+		t.Fatalf("unexpected event after unlink %T", ev)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestZoneRouter_TracksInputDeviceStatus(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan Event, 8)
+	zone := make(chan Event, 8)
+	broadcasts := make(chan StateBroadcast, 8)
+	go func() {
+		for ev := range zone {
+			if req, ok := ev.(RequestStateSnapshot); ok {
+				req.Reply <- StateSnapshot{Zone: "main"}
+			}
+		}
+	}()
+	go runZoneRouter(ctx, events, []zoneRoute{{ID: "main", Events: zone}}, "main", nil, broadcasts, slog.Default())
+
+	events <- DeviceUp{Device: "/dev/input/event1"}
+	events <- DeviceUp{Device: "/dev/input/event0"}
+	events <- DeviceDown{Device: "/dev/input/event1", Reason: "EOF"}
+	events <- DeviceUp{Device: "/dev/input/event1"}
+
+	for _, want := range []string{"down", "up"} {
+		select {
+		case b := <-broadcasts:
+			switch b.(type) {
+			case BroadcastDeviceDown:
+				if want != "down" {
+					t.Fatalf("unexpected %#v", b)
+				}
+			case BroadcastDeviceUp:
+				if want != "up" {
+					t.Fatalf("unexpected %#v", b)
+				}
+			default:
+				t.Fatalf("unexpected broadcast %#v", b)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for device_%s broadcast", want)
+		}
+	}
+
+	events <- DeviceDown{Device: "/dev/input/event0", Reason: "no such device"}
+	reply := make(chan StateSnapshot, 1)
+	events <- RequestStateSnapshot{Reply: reply}
+	select {
+	case snap := <-reply:
+		if len(snap.Inputs) != 2 {
+			t.Fatalf("expected 2 inputs, got %+v", snap.Inputs)
+		}
+		if in := snap.Inputs[0]; in.Device != "/dev/input/event0" || in.Up || in.Reason != "no such device" {
+			t.Fatalf("unexpected status %+v", in)
+		}
+		if in := snap.Inputs[1]; in.Device != "/dev/input/event1" || !in.Up {
+			t.Fatalf("unexpected status %+v", in)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timeout waiting for snapshot")
+	}
+}
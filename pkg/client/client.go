@@ -0,0 +1,322 @@
+// Package client is a Go client for the streamerbrainz daemon.
+//
+// Commands are sent as event envelopes over the IPC socket (a named pipe on
+// Windows), the same protocol the librespot hook uses. State changes are
+// received from the control API's WebSocket (/ws/state):
+//
+//	c, err := client.Connect(ctx, client.Options{})
+//	if err != nil { ... }
+//	_ = c.SetVolume(ctx, -30)
+//	events, err := c.Subscribe(ctx)
+//	for ev := range events {
+//		var v client.VolumeChanged
+//		if ev.Type == client.EventVolumeChanged && ev.Decode(&v) == nil { ... }
+//	}
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Defaults match the daemon's default configuration.
+const (
+	DefaultSocketPath = "/tmp/streamerbrainz.sock"
+	DefaultURL        = "http://127.0.0.1:3001"
+)
+
+const (
+	defaultTimeout    = 5 * time.Second
+	reconnectMin      = 500 * time.Millisecond
+	reconnectMax      = 30 * time.Second
+	subscribeChanSize = 64
+)
+
+// Options configures a Client.
+type Options struct {
+	// SocketPath is the daemon's ipc.socket_path (default DefaultSocketPath).
+	SocketPath string
+
+	// URL is the control API base URL, including api.base_path if set
+	// (default DefaultURL).
+	URL string
+
+	// Zone is the zone commands target; empty targets the daemon's current zone.
+	Zone string
+
+	// Origin tags volume changes (default "client"); the daemon reports them
+	// as "ipc:<Origin>" in volume_changed.
+	Origin string
+
+	// Timeout bounds each command round trip when ctx has no deadline (default 5s).
+	Timeout time.Duration
+}
+
+// Client sends commands to and receives state from one daemon. It is safe for
+// concurrent use; each command uses its own IPC connection.
+type Client struct {
+	opts  Options
+	wsURL string
+}
+
+// Event is a command envelope: Type is the event name (e.g. "toggle_mute") and
+// Data its payload, marshaled as JSON.
+type Event struct {
+	Type string
+	Zone string // overrides Options.Zone
+	Data any
+}
+
+type envelope struct {
+	Type string `json:"type"`
+	Zone string `json:"zone,omitempty"`
+	Data any    `json:"data,omitempty"`
+}
+
+type ipcResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Connect validates opts and checks that the daemon's IPC socket accepts connections.
+func Connect(ctx context.Context, opts Options) (*Client, error) {
+	if opts.SocketPath == "" {
+		opts.SocketPath = DefaultSocketPath
+	}
+	if opts.URL == "" {
+		opts.URL = DefaultURL
+	}
+	if opts.Origin == "" {
+		opts.Origin = "client"
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+
+	u, err := url.Parse(opts.URL)
+	if err != nil {
+		return nil, fmt.Errorf("parse URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	default:
+		return nil, fmt.Errorf("URL %q must be http(s)", opts.URL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/ws/state"
+
+	c := &Client{opts: opts, wsURL: u.String()}
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn.Close()
+	return c, nil
+}
+
+func (c *Client) dial(ctx context.Context) (net.Conn, error) {
+	conn, err := dialIPC(ctx, c.opts.SocketPath)
+	if err != nil {
+		return nil, fmt.Errorf("connect to %s: %w", c.opts.SocketPath, err)
+	}
+	return conn, nil
+}
+
+// Send delivers ev to the daemon and waits for it to be queued.
+func (c *Client) Send(ctx context.Context, ev Event) error {
+	env := envelope{Type: ev.Type, Zone: ev.Zone, Data: ev.Data}
+	if env.Zone == "" {
+		env.Zone = c.opts.Zone
+	}
+	return c.send(ctx, env)
+}
+
+func (c *Client) send(ctx context.Context, env envelope) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.Timeout)
+		defer cancel()
+	}
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	line, err := json.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+	if _, err := conn.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("send event: %w", err)
+	}
+
+	var resp ipcResponse
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&resp); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("read response: %w", err)
+	}
+	if resp.Status != "ok" {
+		return fmt.Errorf("daemon: %s", resp.Error)
+	}
+	return nil
+}
+
+// SetVolume sets the volume to db (dBFS; clamped by the daemon's limits).
+func (c *Client) SetVolume(ctx context.Context, db float64) error {
+	return c.Send(ctx, Event{Type: "set_volume_absolute", Data: map[string]any{"db": db, "origin": c.opts.Origin}})
+}
+
+// StepVolume changes the volume by steps rotary detents (negative = down).
+func (c *Client) StepVolume(ctx context.Context, steps int) error {
+	return c.Send(ctx, Event{Type: "volume_step", Data: map[string]any{"steps": steps, "origin": c.opts.Origin}})
+}
+
+// SetVolumePercent sets the volume to percent (0-100) of the daemon's volume range.
+func (c *Client) SetVolumePercent(ctx context.Context, percent float64) error {
+	return c.Send(ctx, Event{Type: "set_volume_percent", Data: map[string]any{"percent": percent, "origin": c.opts.Origin}})
+}
+
+// ToggleMute toggles mute.
+func (c *Client) ToggleMute(ctx context.Context) error {
+	return c.Send(ctx, Event{Type: "toggle_mute"})
+}
+
+// SetMute mutes or unmutes.
+func (c *Client) SetMute(ctx context.Context, muted bool) error {
+	return c.Send(ctx, Event{Type: "set_mute", Data: map[string]any{"muted": muted}})
+}
+
+// Resync makes the daemon re-read its state from CamillaDSP and re-broadcast it.
+func (c *Client) Resync(ctx context.Context) error {
+	return c.Send(ctx, Event{Type: "resync_state"})
+}
+
+// SelectOutput switches to output; empty cycles to the next one.
+func (c *Client) SelectOutput(ctx context.Context, output string) error {
+	return c.Send(ctx, Event{Type: "output_select", Data: map[string]any{"output": output}})
+}
+
+// SelectZone makes zone the daemon's current zone; empty cycles to the next one.
+// It is not scoped to Options.Zone.
+func (c *Client) SelectZone(ctx context.Context, zone string) error {
+	return c.send(ctx, envelope{Type: "select_zone", Data: map[string]any{"zone": zone}})
+}
+
+// Subscribe streams state events until ctx is canceled, when the channel is
+// closed. The first connection starts with an EventStateInit snapshot and an
+// EventHello. Dropped connections are retried with backoff and resume where
+// they left off: the missed events followed by EventResumed, or a new
+// EventStateInit if the daemon restarted or too much was missed. Subscribe
+// fails only if the first connection does.
+func (c *Client) Subscribe(ctx context.Context) (<-chan StateEvent, error) {
+	var pos streamPos
+	conn, err := c.dialState(ctx, pos)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan StateEvent, subscribeChanSize)
+	go func() {
+		defer close(out)
+		backoff := reconnectMin
+		for {
+			if err := readStateEvents(ctx, conn, out, &pos); err == nil || ctx.Err() != nil {
+				return
+			}
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(backoff):
+				}
+				backoff = min(backoff*2, reconnectMax)
+				if conn, err = c.dialState(ctx, pos); err == nil {
+					backoff = reconnectMin
+					break
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+// streamPos is the last position seen on the daemon's broadcast stream.
+type streamPos struct {
+	stream string
+	seq    uint64
+}
+
+// dialState connects to /ws/state, resuming from pos if known, and announces
+// the protocol versions this package understands.
+func (c *Client) dialState(ctx context.Context, pos streamPos) (*websocket.Conn, error) {
+	wsURL := c.wsURL
+	if pos.stream != "" {
+		wsURL += "?" + url.Values{
+			"stream":   {pos.stream},
+			"last_seq": {strconv.FormatUint(pos.seq, 10)},
+		}.Encode()
+	}
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, wsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("connect to %s: %w", c.wsURL, err)
+	}
+	hello := envelope{Type: "hello", Data: map[string]any{"versions": []int{ProtocolVersion}}}
+	if err := conn.WriteJSON(hello); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("send hello: %w", err)
+	}
+	return conn, nil
+}
+
+// readStateEvents forwards frames from conn to out, tracking pos, until the
+// connection fails or ctx is canceled (then it returns nil).
+func readStateEvents(ctx context.Context, conn *websocket.Conn, out chan<- StateEvent, pos *streamPos) error {
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+	defer conn.Close()
+	for {
+		var ev StateEvent
+		if err := conn.ReadJSON(&ev); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				continue
+			}
+			return err
+		}
+		if ev.Type == EventStateInit {
+			// A snapshot restarts tracking (possibly on a new stream).
+			var snap Snapshot
+			_ = ev.Decode(&snap)
+			*pos = streamPos{stream: snap.Stream, seq: ev.Seq}
+		} else {
+			pos.seq = max(pos.seq, ev.Seq)
+		}
+		select {
+		case out <- ev:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
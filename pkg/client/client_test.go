@@ -0,0 +1,146 @@
+//go:build unix
+
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeIPC accepts one line per connection, reports it on got and answers with resp.
+func fakeIPC(t *testing.T, resp string) (string, <-chan map[string]any) {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "sb.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	got := make(chan map[string]any, 8)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			line, err := bufio.NewReader(conn).ReadBytes('\n')
+			if err == nil {
+				var m map[string]any
+				if json.Unmarshal(line, &m) == nil {
+					got <- m
+				}
+				_, _ = conn.Write([]byte(resp + "\n"))
+			}
+			conn.Close()
+		}
+	}()
+	return socket, got
+}
+
+func TestClient_SetVolume(t *testing.T) {
+	socket, got := fakeIPC(t, `{"status":"ok"}`)
+	ctx := context.Background()
+	c, err := Connect(ctx, Options{SocketPath: socket, Zone: "kitchen"})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if err := c.SetVolume(ctx, -30); err != nil {
+		t.Fatalf("set volume: %v", err)
+	}
+	m := <-got
+	if m["type"] != "set_volume_absolute" || m["zone"] != "kitchen" {
+		t.Fatalf("envelope = %v", m)
+	}
+	data, _ := m["data"].(map[string]any)
+	if data["db"] != -30.0 || data["origin"] != "client" {
+		t.Fatalf("data = %v", data)
+	}
+
+	if err := c.SelectZone(ctx, "office"); err != nil {
+		t.Fatalf("select zone: %v", err)
+	}
+	if m := <-got; m["type"] != "select_zone" || m["zone"] != nil {
+		t.Fatalf("select_zone envelope = %v", m)
+	}
+}
+
+func TestClient_SendError(t *testing.T) {
+	socket, _ := fakeIPC(t, `{"status":"error","error":"unknown event type"}`)
+	c, err := Connect(context.Background(), Options{SocketPath: socket})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if err := c.Send(context.Background(), Event{Type: "bogus"}); err == nil {
+		t.Fatal("expected daemon error")
+	}
+}
+
+func TestConnect_NoDaemon(t *testing.T) {
+	if _, err := Connect(context.Background(), Options{SocketPath: filepath.Join(t.TempDir(), "none.sock")}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestClient_Subscribe(t *testing.T) {
+	socket, _ := fakeIPC(t, `{"status":"ok"}`)
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sb/ws/state" {
+			http.NotFound(w, r)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"state_init","ts":"2026-01-02T03:04:05Z","data":{"volume_db":-40,"volume_known":true,"muted":false}}`))
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"volume_changed","zone":"kitchen","data":{"volume_db":-35.5}}`))
+		_, _, _ = conn.ReadMessage()
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, err := Connect(ctx, Options{SocketPath: socket, URL: srv.URL + "/sb/"})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	events, err := c.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	ev := <-events
+	var snap Snapshot
+	if ev.Type != EventStateInit || ev.Decode(&snap) != nil || snap.VolumeDB != -40 || !snap.VolumeKnown {
+		t.Fatalf("init = %+v (%+v)", ev, snap)
+	}
+	if want := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC); !ev.Time.Equal(want) {
+		t.Fatalf("ts = %v", ev.Time)
+	}
+	ev = <-events
+	var vol VolumeChanged
+	if ev.Type != EventVolumeChanged || ev.Zone != "kitchen" || ev.Decode(&vol) != nil || vol.VolumeDB != -35.5 {
+		t.Fatalf("volume = %+v (%+v)", ev, vol)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-events:
+		for ok {
+			_, ok = <-events
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("channel not closed after cancel")
+	}
+}
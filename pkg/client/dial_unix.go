@@ -0,0 +1,14 @@
+//go:build !windows
+
+package client
+
+import (
+	"context"
+	"net"
+)
+
+// dialIPC connects to the daemon's Unix socket.
+func dialIPC(ctx context.Context, socketPath string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "unix", socketPath)
+}
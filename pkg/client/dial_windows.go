@@ -0,0 +1,63 @@
+//go:build windows
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+const pipePrefix = `\\.\pipe\`
+
+// pipeName maps a socket path to the daemon's named pipe, like the daemon does:
+// a \\.\pipe\ path is used as is, anything else becomes \\.\pipe\<base name>.
+func pipeName(socketPath string) string {
+	if strings.HasPrefix(strings.ToLower(socketPath), pipePrefix) {
+		return socketPath
+	}
+	base := path.Base(strings.ReplaceAll(socketPath, `\`, "/"))
+	base = strings.TrimSuffix(base, path.Ext(base))
+	if base == "" || base == "." || base == "/" {
+		base = "streamerbrainz"
+	}
+	return pipePrefix + base
+}
+
+type pipeAddr string
+
+func (a pipeAddr) Network() string { return "pipe" }
+func (a pipeAddr) String() string  { return string(a) }
+
+type pipeConn struct {
+	*os.File
+	addr pipeAddr
+}
+
+func (c *pipeConn) LocalAddr() net.Addr  { return c.addr }
+func (c *pipeConn) RemoteAddr() net.Addr { return c.addr }
+
+// dialIPC opens the daemon's named pipe, retrying while all instances are busy.
+func dialIPC(ctx context.Context, socketPath string) (net.Conn, error) {
+	name := pipeName(socketPath)
+	for {
+		f, err := os.OpenFile(name, os.O_RDWR, 0)
+		if err == nil {
+			return &pipeConn{File: f, addr: pipeAddr(name)}, nil
+		}
+		if !errors.Is(err, windows.ERROR_PIPE_BUSY) {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// State event types sent on /ws/state. Other types (device_down, tuning_changed,
+// ...) are passed through as well; decode their Data as needed.
+const (
+	EventHello         = "hello"
+	EventResumed       = "resumed"
+	EventStateInit     = "state_init"
+	EventVolumeChanged = "volume_changed"
+	EventMuteChanged   = "mute_changed"
+	EventPlayerChanged = "player_changed"
+	EventOutputChanged = "output_changed"
+	EventZoneSelected  = "zone_selected"
+
+	EventControlRejected   = "control_rejected"
+	EventSignalLevels      = "signal_levels"
+	EventControllerChanged = "controller_changed"
+	EventUIHint            = "ui_hint"
+	EventExposureWarning   = "exposure_warning"
+	EventCamillaFrame      = "camilladsp_frame"
+)
+
+// StateEvent is one frame from the state WebSocket.
+type StateEvent struct {
+	Seq  uint64          `json:"seq,omitempty"`
+	Type string          `json:"type"`
+	Zone string          `json:"zone,omitempty"`
+	Time time.Time       `json:"ts"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Decode unmarshals the event's data into v (e.g. *Snapshot for EventStateInit).
+func (e StateEvent) Decode(v any) error {
+	if len(e.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(e.Data, v)
+}
+
+// ProtocolVersion is the state snapshot schema this package decodes; Subscribe
+// announces it in a hello.
+const ProtocolVersion = 1
+
+// Snapshot is the data of EventStateInit.
+type Snapshot struct {
+	// ProtocolVersion and Capabilities describe the stream (top-level snapshot only).
+	ProtocolVersion int      `json:"protocol_version,omitempty"`
+	Capabilities    []string `json:"capabilities,omitempty"`
+	Stream          string   `json:"stream,omitempty"`
+
+	Zone string `json:"zone,omitempty"`
+
+	VolumeDB    float64   `json:"volume_db"`
+	VolumeKnown bool      `json:"volume_known"`
+	VolumeAt    time.Time `json:"volume_at"`
+
+	VolumePercent float64 `json:"volume_percent"`
+
+	Muted     bool      `json:"muted"`
+	MuteKnown bool      `json:"mute_known"`
+	MuteAt    time.Time `json:"mute_at"`
+
+	Output string `json:"output,omitempty"`
+
+	EncoderMode string  `json:"encoder_mode"`
+	BalanceDB   float64 `json:"balance_db"`
+	SubDB       float64 `json:"sub_db"`
+
+	Player *Player `json:"player,omitempty"`
+
+	// NormalizationDB is the per-track loudness offset in effect.
+	NormalizationDB float64 `json:"normalization_db,omitempty"`
+
+	// Hold/ramp in progress (omitted while idle), as in ControllerChanged.
+	HeldDirection  int      `json:"held_direction,omitempty"`
+	Ramping        bool     `json:"ramping,omitempty"`
+	VelocityDBPerS float64  `json:"velocity_db_per_s,omitempty"`
+	TargetDB       *float64 `json:"target_db,omitempty"`
+
+	// Zones lists every zone's snapshot when multiple zones are configured.
+	Zones []Snapshot `json:"zones,omitempty"`
+
+	// LinkedZones maps linked zone ids to their volume offsets (dB).
+	LinkedZones map[string]float64 `json:"linked_zones,omitempty"`
+}
+
+// Player is the active player's state (Snapshot.Player and EventPlayerChanged).
+type Player struct {
+	Source     string    `json:"source"`
+	State      string    `json:"state"`
+	Title      string    `json:"title,omitempty"`
+	Artist     string    `json:"artist,omitempty"`
+	Album      string    `json:"album,omitempty"`
+	DurationMs int64     `json:"duration_ms,omitempty"`
+	At         time.Time `json:"at,omitzero"`
+}
+
+// Hello is the data of EventHello, the daemon's answer to Subscribe's hello.
+type Hello struct {
+	ProtocolVersion  int      `json:"protocol_version"`
+	ProtocolVersions []int    `json:"protocol_versions"`
+	Capabilities     []string `json:"capabilities"`
+}
+
+// VolumeChanged is the data of EventVolumeChanged.
+type VolumeChanged struct {
+	VolumeDB float64 `json:"volume_db"`
+	// Origin names who or what changed the volume ("ir", "rotary",
+	// "ipc:argon-ctl", "webui", "external", ...). Empty when the value was only
+	// (re)learned, e.g. right after startup or a resync.
+	Origin string `json:"origin,omitempty"`
+	// ExternalChange is set when the change (Origin "external") interrupted a
+	// hold or ramp, which the daemon then abandoned.
+	ExternalChange bool `json:"external_change,omitempty"`
+}
+
+// ControlRejected is the data of EventControlRejected: the daemon's
+// arbitration dropped a volume change from Origin because Holder controls the
+// volume until Until.
+type ControlRejected struct {
+	Origin string    `json:"origin"`
+	Holder string    `json:"holder"`
+	Until  time.Time `json:"until"`
+}
+
+// ControllerChanged is the data of EventControllerChanged: a volume hold or
+// ramp started, ended or progressed. HeldDirection is -1, 0 or +1; TargetDB is
+// the ramp's destination or the hold's current position (nil when idle), so a
+// UI can animate towards it before the next VolumeChanged.
+type ControllerChanged struct {
+	HeldDirection  int      `json:"held_direction"`
+	Ramping        bool     `json:"ramping"`
+	VelocityDBPerS float64  `json:"velocity_db_per_s"`
+	TargetDB       *float64 `json:"target_db,omitempty"`
+}
+
+// UIHint is the data of EventUIHint: show Hint ("volume_overlay" with VolumeDB,
+// or "mute_flash" with Muted) for DurationMS.
+type UIHint struct {
+	Hint       string   `json:"hint"`
+	DurationMS int      `json:"duration_ms"`
+	VolumeDB   *float64 `json:"volume_db,omitempty"`
+	Muted      *bool    `json:"muted,omitempty"`
+}
+
+// ExposureWarning is the data of EventExposureWarning: today's listening at or
+// above ThresholdDB reached Level ("approaching" or "exceeded") of the daily
+// budget. DimToDB is set when loud zones are being dimmed.
+type ExposureWarning struct {
+	Level       string   `json:"level"`
+	LoudSec     float64  `json:"loud_sec"`
+	BudgetSec   float64  `json:"budget_sec"`
+	ThresholdDB float64  `json:"threshold_db"`
+	DimToDB     *float64 `json:"dim_to_db,omitempty"`
+}
+
+// CamillaFrame is the data of EventCamillaFrame: one raw CamillaDSP protocol
+// frame captured by the daemon's protocol tap, Direction "out" (sent) or "in"
+// (received). Truncated is set when Frame was cut at 4 KiB.
+type CamillaFrame struct {
+	Direction string `json:"direction"`
+	Frame     string `json:"frame"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// SignalLevels is the data of EventSignalLevels: one meter poll of the zone's
+// CamillaDSP (sent only with camilladsp.monitor_hz set). Levels are dBFS per
+// channel; Faders[0] is the Main fader.
+type SignalLevels struct {
+	PlaybackRMS  []float64 `json:"playback_rms"`
+	PlaybackPeak []float64 `json:"playback_peak"`
+	CaptureRMS   []float64 `json:"capture_rms"`
+	CapturePeak  []float64 `json:"capture_peak"`
+	Faders       []Fader   `json:"faders"`
+}
+
+// Fader is one CamillaDSP fader in SignalLevels.
+type Fader struct {
+	Volume float64 `json:"volume"`
+	Mute   bool    `json:"mute"`
+}
+
+// MuteChanged is the data of EventMuteChanged.
+type MuteChanged struct {
+	Muted bool `json:"muted"`
+}
+
+// OutputChanged is the data of EventOutputChanged.
+type OutputChanged struct {
+	Output string `json:"output"`
+}
+
+// ZoneSelected is the data of EventZoneSelected.
+type ZoneSelected struct {
+	Zone string `json:"zone"`
+}